@@ -3,28 +3,82 @@ package easygo
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/bdlilley/easygo/pkg/breaker"
 	"github.com/bdlilley/easygo/pkg/logging"
-	"github.com/rotisserie/eris"
+	"github.com/bdlilley/easygo/pkg/metrics"
+	"github.com/bdlilley/easygo/pkg/runtimeinfo"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-sdk-go-v2/otelaws"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type EGAwsClient struct {
 	cfg           aws.Config
+	endpoints     ServiceEndpoints
+	logger        logging.Logger
+	credCache     *fileCredentialCache
 	stsClient     *sts.Client
 	secretsClient *secretsmanager.Client
+	eventsClient  *eventbridge.Client
+	lambdaClient  *lambda.Client
+	ecrClient     *ecr.Client
+	ssmClient     *ssm.Client
+	route53Client *route53.Client
+	sesClient     *sesv2.Client
+	kinesisClient *kinesis.Client
+	dynamoClient  *dynamodb.Client
+	sqsClient     *sqs.Client
+	snsClient     *sns.Client
+	kmsClient     *kms.Client
+	s3Client      *s3.Client
+	s3PathStyle   bool
+	ecrAuth       ecrAuthCache
 }
 
 type NewEGAwsClientArgs struct {
-	Logger        logging.Logger
+	// Logger receives client logs (default: logging.Noop)
+	Logger logging.Logger
+	// Region is the AWS region (default: AWS_REGION, AWS_DEFAULT_REGION or the
+	// shared config, then the region of the EC2 instance, ECS task or Lambda
+	// function the process runs in, which is not probed with LazyInit; see
+	// runtimeinfo)
 	Region        string
 	AssumeRoleArn string
+	// AssumeRoleChain is a list of roles assumed in order before AssumeRoleArn, each hop
+	// using the credentials of the previous one, e.g. to reach a role in another account
+	AssumeRoleChain []string
+	// AssumeRoleSessionName is the session name used when assuming AssumeRoleArn (default: easygo)
+	AssumeRoleSessionName string
+	// CredentialCacheDir enables a file-backed cache of assumed-role credentials in this
+	// directory so short-lived processes can reuse valid credentials across restarts.
+	// See DefaultCredentialCacheDir.
+	CredentialCacheDir string
+	// Profile selects a named profile from the shared config and credentials files
+	Profile string
+	// SharedConfigFiles overrides the shared config file paths (default: ~/.aws/config)
+	SharedConfigFiles []string
+	// SharedCredentialsFiles overrides the shared credentials file paths (default: ~/.aws/credentials)
+	SharedCredentialsFiles []string
 	// RetryMaxAttempts sets the maximum number of attempts (default: 3)
 	// Set to 0 to use AWS default behavior
 	RetryMaxAttempts int
@@ -34,14 +88,80 @@ type NewEGAwsClientArgs struct {
 	// HTTPClient allows providing a custom HTTP client with custom timeout/retry logic
 	// If nil, the default HTTP client will be used
 	HTTPClient *http.Client
+	// Credentials replaces the default credentials chain, e.g.
+	// credentials.NewStaticCredentialsProvider for LocalStack
+	Credentials aws.CredentialsProvider
+	// EndpointURL overrides the endpoint for all services, e.g. http://localhost:4566 for LocalStack
+	EndpointURL string
+	// ServiceEndpoints overrides the endpoint for individual services and takes precedence over EndpointURL
+	ServiceEndpoints ServiceEndpoints
+	// S3UsePathStyle addresses buckets in the URL path instead of the host name,
+	// as LocalStack and MinIO endpoints need
+	S3UsePathStyle bool
+	// LogAPICalls logs every AWS API call (service, operation, duration, status, retries)
+	// through Logger at debug level
+	LogAPICalls bool
+	// EnableTracing wires OpenTelemetry middleware into every AWS API call so each
+	// call produces a span with service, operation, and request ID attributes
+	EnableTracing bool
+	// TracerProvider is used when EnableTracing is set (default: the global provider)
+	TracerProvider trace.TracerProvider
+	// CircuitBreaker guards every AWS API call made through the client, failing
+	// fast with breaker.ErrOpen while AWS is failing; see breaker.AWSMiddleware
+	CircuitBreaker *breaker.Breaker
+	// Metrics records call counts, latency and throttles for every AWS API
+	// call made through the client; see metrics.AWSMiddleware
+	Metrics metrics.Provider
+	// SkipCallerIdentityCheck skips the GetCallerIdentity call made during construction
+	SkipCallerIdentityCheck bool
+	// LazyInit skips every AWS call during construction, including assuming roles and
+	// probing instance metadata for a region, so startup does not depend on STS being
+	// reachable; set Region or AWS_REGION where metadata would provide it. Credential
+	// errors surface on the first API call; call Validate to check explicitly.
+	LazyInit bool
+}
+
+// ServiceEndpoints holds per-service endpoint overrides; empty values use the default endpoint
+type ServiceEndpoints struct {
+	STS            string
+	SecretsManager string
+	EventBridge    string
+	Lambda         string
+	ECR            string
+	SSM            string
+	Route53        string
+	SES            string
+	Kinesis        string
+	DynamoDB       string
+	SQS            string
+	SNS            string
+	KMS            string
+	S3             string
 }
 
 func NewAwsClient(ctx context.Context, args *NewEGAwsClientArgs) (*EGAwsClient, error) {
+	if args.Logger == nil {
+		args.Logger = logging.Noop()
+	}
+
 	// Build config options
 	configOpts := []func(*config.LoadOptions) error{
 		config.WithRegion(args.Region),
 	}
 
+	if args.Profile != "" {
+		configOpts = append(configOpts, config.WithSharedConfigProfile(args.Profile))
+		args.Logger.WithField("profile", args.Profile).Debug("configured shared config profile")
+	}
+
+	if len(args.SharedConfigFiles) > 0 {
+		configOpts = append(configOpts, config.WithSharedConfigFiles(args.SharedConfigFiles))
+	}
+
+	if len(args.SharedCredentialsFiles) > 0 {
+		configOpts = append(configOpts, config.WithSharedCredentialsFiles(args.SharedCredentialsFiles))
+	}
+
 	// Configure retry behavior
 	if args.RetryMaxAttempts > 0 {
 		configOpts = append(configOpts, config.WithRetryMaxAttempts(args.RetryMaxAttempts))
@@ -59,47 +179,188 @@ func NewAwsClient(ctx context.Context, args *NewEGAwsClientArgs) (*EGAwsClient,
 		args.Logger.Debug("using custom HTTP client")
 	}
 
+	if args.Credentials != nil {
+		configOpts = append(configOpts, config.WithCredentialsProvider(args.Credentials))
+		args.Logger.Debug("using provided credentials")
+	}
+
+	if args.EndpointURL != "" {
+		configOpts = append(configOpts, config.WithBaseEndpoint(args.EndpointURL))
+		args.Logger.WithField("endpoint", args.EndpointURL).Debug("configured endpoint override")
+	}
+
 	cfg, err := config.LoadDefaultConfig(ctx, configOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 	args.Logger.Debug("loaded AWS config from default credentials chain")
 
-	stsClient := sts.NewFromConfig(cfg)
+	if cfg.Region == "" && !args.LazyInit {
+		// nothing in the arguments, environment or shared config set a region;
+		// use the instance, task or function's
+		cfg.Region = runtimeinfo.Get().Region
+		args.Logger.WithField("region", cfg.Region).Debug("defaulted region from the runtime environment")
+	}
+
+	if args.EnableTracing || args.TracerProvider != nil {
+		var otelOpts []otelaws.Option
+		if args.TracerProvider != nil {
+			otelOpts = append(otelOpts, otelaws.WithTracerProvider(args.TracerProvider))
+		}
+		otelaws.AppendMiddlewares(&cfg.APIOptions, otelOpts...)
+		args.Logger.Debug("enabled AWS API call tracing")
+	}
+
+	if args.CircuitBreaker != nil {
+		cfg.APIOptions = append(cfg.APIOptions, breaker.AWSMiddleware(args.CircuitBreaker))
+		args.Logger.WithField("breaker", args.CircuitBreaker.Name()).Debug("enabled AWS API circuit breaker")
+	}
+
+	if args.Metrics != nil {
+		cfg.APIOptions = append(cfg.APIOptions, metrics.AWSMiddleware(args.Metrics))
+		args.Logger.Debug("enabled AWS API call metrics")
+	}
 
+	if args.LogAPICalls {
+		cfg.APIOptions = append(cfg.APIOptions, apiCallLoggingMiddleware(args.Logger))
+		args.Logger.Debug("enabled AWS API call logging")
+	}
+
+	c := &EGAwsClient{
+		cfg:         cfg,
+		endpoints:   args.ServiceEndpoints,
+		logger:      args.Logger,
+		s3PathStyle: args.S3UsePathStyle,
+	}
+	c.initClients()
+
+	roleChain := args.AssumeRoleChain
 	if args.AssumeRoleArn != "" {
-		args.Logger.WithField("roleArn", args.AssumeRoleArn).Debug("AssumeRoleArn is set; assuming role")
-		result, err := stsClient.AssumeRole(ctx, &sts.AssumeRoleInput{
-			RoleArn: aws.String(args.AssumeRoleArn),
-		})
-		if err != nil {
-			return nil, fmt.Errorf("failed to assume role: %w", err)
+		roleChain = append(append([]string{}, roleChain...), args.AssumeRoleArn)
+	}
+
+	var credCache *fileCredentialCache
+	if len(roleChain) > 0 {
+		sessionName := args.AssumeRoleSessionName
+		if sessionName == "" {
+			sessionName = defaultRoleSessionName
 		}
-		cfg.Credentials = aws.NewCredentialsCache(aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
-			return aws.Credentials{
-				AccessKeyID:     *result.Credentials.AccessKeyId,
-				SecretAccessKey: *result.Credentials.SecretAccessKey,
-				SessionToken:    *result.Credentials.SessionToken,
-				Expires:         *result.Credentials.Expiration,
-			}, nil
-		}))
-		args.Logger.WithField("roleArn", args.AssumeRoleArn).Debug("assume role successful")
-		stsClient = sts.NewFromConfig(cfg)
-	}
-
-	id, err := stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+
+		if args.CredentialCacheDir != "" {
+			credCache = newFileCredentialCache(args.CredentialCacheDir, strings.Join(roleChain, ","), sessionName)
+		}
+
+		// each hop assumes the next role using the refreshing credentials of the previous hop
+		for i, roleArn := range roleChain {
+			args.Logger.WithField("roleArn", roleArn).Debug("assuming role")
+			var provider aws.CredentialsProvider = stscreds.NewAssumeRoleProvider(c.stsClient, roleArn, func(o *stscreds.AssumeRoleOptions) {
+				o.RoleSessionName = sessionName
+			})
+			if i == len(roleChain)-1 && credCache != nil {
+				provider = &fileCachedCredentialsProvider{
+					cache:    credCache,
+					provider: provider,
+					logger:   args.Logger,
+				}
+			}
+			c.cfg.Credentials = aws.NewCredentialsCache(provider)
+			c.initClients()
+		}
+
+		if !args.LazyInit {
+			if _, err := c.cfg.Credentials.Retrieve(ctx); err != nil {
+				return nil, fmt.Errorf("failed to assume role: %w", err)
+			}
+			args.Logger.WithField("roleArn", roleChain[len(roleChain)-1]).Debug("assume role successful")
+		}
+	}
+	c.credCache = credCache
+
+	if args.LazyInit {
+		args.Logger.Debug("lazy init; deferring credential validation")
+		return c, nil
+	}
+
+	if args.SkipCallerIdentityCheck {
+		args.Logger.Debug("skipping caller identity check")
+		return c, nil
+	}
+
+	if err := c.Validate(ctx); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Validate checks the client's credentials with a GetCallerIdentity call. It is run
+// during construction unless LazyInit or SkipCallerIdentityCheck is set.
+func (c *EGAwsClient) Validate(ctx context.Context) error {
+	id, err := c.stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get caller identity: %w", err)
+		// cached credentials may have been revoked; don't reuse them on the next run
+		if clearErr := c.credCache.clear(); clearErr != nil {
+			c.logger.WithError(clearErr).Warn("failed to clear credential cache")
+		}
+		return fmt.Errorf("failed to get caller identity: %w", err)
 	}
-	args.Logger.WithField("identity", id).Debug("caller identity")
+	c.logger.WithField("identity", id).Debug("caller identity")
+
+	return nil
+}
 
-	secretsClient := secretsmanager.NewFromConfig(cfg)
+// initClients (re)creates the service clients from the current config
+func (c *EGAwsClient) initClients() {
+	c.stsClient = sts.NewFromConfig(c.cfg, func(o *sts.Options) {
+		overrideEndpoint(&o.BaseEndpoint, c.endpoints.STS)
+	})
+	c.secretsClient = secretsmanager.NewFromConfig(c.cfg, func(o *secretsmanager.Options) {
+		overrideEndpoint(&o.BaseEndpoint, c.endpoints.SecretsManager)
+	})
+	c.eventsClient = eventbridge.NewFromConfig(c.cfg, func(o *eventbridge.Options) {
+		overrideEndpoint(&o.BaseEndpoint, c.endpoints.EventBridge)
+	})
+	c.lambdaClient = lambda.NewFromConfig(c.cfg, func(o *lambda.Options) {
+		overrideEndpoint(&o.BaseEndpoint, c.endpoints.Lambda)
+	})
+	c.ecrClient = ecr.NewFromConfig(c.cfg, func(o *ecr.Options) {
+		overrideEndpoint(&o.BaseEndpoint, c.endpoints.ECR)
+	})
+	c.ssmClient = ssm.NewFromConfig(c.cfg, func(o *ssm.Options) {
+		overrideEndpoint(&o.BaseEndpoint, c.endpoints.SSM)
+	})
+	c.route53Client = route53.NewFromConfig(c.cfg, func(o *route53.Options) {
+		overrideEndpoint(&o.BaseEndpoint, c.endpoints.Route53)
+	})
+	c.sesClient = sesv2.NewFromConfig(c.cfg, func(o *sesv2.Options) {
+		overrideEndpoint(&o.BaseEndpoint, c.endpoints.SES)
+	})
+	c.kinesisClient = kinesis.NewFromConfig(c.cfg, func(o *kinesis.Options) {
+		overrideEndpoint(&o.BaseEndpoint, c.endpoints.Kinesis)
+	})
+	c.dynamoClient = dynamodb.NewFromConfig(c.cfg, func(o *dynamodb.Options) {
+		overrideEndpoint(&o.BaseEndpoint, c.endpoints.DynamoDB)
+	})
+	c.sqsClient = sqs.NewFromConfig(c.cfg, func(o *sqs.Options) {
+		overrideEndpoint(&o.BaseEndpoint, c.endpoints.SQS)
+	})
+	c.snsClient = sns.NewFromConfig(c.cfg, func(o *sns.Options) {
+		overrideEndpoint(&o.BaseEndpoint, c.endpoints.SNS)
+	})
+	c.kmsClient = kms.NewFromConfig(c.cfg, func(o *kms.Options) {
+		overrideEndpoint(&o.BaseEndpoint, c.endpoints.KMS)
+	})
+	c.s3Client = s3.NewFromConfig(c.cfg, func(o *s3.Options) {
+		overrideEndpoint(&o.BaseEndpoint, c.endpoints.S3)
+		o.UsePathStyle = c.s3PathStyle
+	})
+}
 
-	return &EGAwsClient{
-		cfg:           cfg,
-		stsClient:     stsClient,
-		secretsClient: secretsClient,
-	}, nil
+// overrideEndpoint sets dst to endpoint when endpoint is not empty
+func overrideEndpoint(dst **string, endpoint string) {
+	if endpoint != "" {
+		*dst = aws.String(endpoint)
+	}
 }
 
 // GetCallerIdentity retrieves information about the current AWS identity
@@ -123,36 +384,62 @@ func (c *EGAwsClient) GetSecretsClient() *secretsmanager.Client {
 	return c.secretsClient
 }
 
-type GetLatestSecretValueResult struct {
-	ByteValue []byte
+// GetEventBridgeClient returns the EventBridge client
+func (c *EGAwsClient) GetEventBridgeClient() *eventbridge.Client {
+	return c.eventsClient
 }
 
-type ByteTransformer[T any] struct {
-	ByteValue []byte
+// GetLambdaClient returns the Lambda client
+func (c *EGAwsClient) GetLambdaClient() *lambda.Client {
+	return c.lambdaClient
 }
 
-// Gets the latest value of secretNameOrArn and unmarshals it into result
-func (c *EGAwsClient) GetLatestJsonSecretValue(ctx context.Context, secretNameOrArn string, result any) error {
-	output, err := c.secretsClient.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
-		SecretId: aws.String(secretNameOrArn),
-	})
-	if err != nil {
-		return eris.Wrap(err, "failed to get secret value")
-	}
+// GetECRClient returns the ECR client
+func (c *EGAwsClient) GetECRClient() *ecr.Client {
+	return c.ecrClient
+}
 
-	var byteValue []byte
-	if output.SecretString != nil {
-		byteValue = []byte(*output.SecretString)
-	} else if output.SecretBinary != nil {
-		byteValue = output.SecretBinary
-	} else {
-		return eris.New("secret found but value is empty")
-	}
+// GetSSMClient returns the SSM client
+func (c *EGAwsClient) GetSSMClient() *ssm.Client {
+	return c.ssmClient
+}
 
-	err = json.Unmarshal(byteValue, result)
-	if err != nil {
-		return eris.Wrap(err, "failed to unmarshal byte value")
-	}
+// GetRoute53Client returns the Route53 client
+func (c *EGAwsClient) GetRoute53Client() *route53.Client {
+	return c.route53Client
+}
 
-	return nil
+// GetSESClient returns the SES v2 client
+func (c *EGAwsClient) GetSESClient() *sesv2.Client {
+	return c.sesClient
+}
+
+// GetKinesisClient returns the Kinesis client
+func (c *EGAwsClient) GetKinesisClient() *kinesis.Client {
+	return c.kinesisClient
+}
+
+// GetDynamoDBClient returns the DynamoDB client
+func (c *EGAwsClient) GetDynamoDBClient() *dynamodb.Client {
+	return c.dynamoClient
+}
+
+// GetSQSClient returns the SQS client
+func (c *EGAwsClient) GetSQSClient() *sqs.Client {
+	return c.sqsClient
+}
+
+// GetSNSClient returns the SNS client
+func (c *EGAwsClient) GetSNSClient() *sns.Client {
+	return c.snsClient
+}
+
+// GetKMSClient returns the KMS client
+func (c *EGAwsClient) GetKMSClient() *kms.Client {
+	return c.kmsClient
+}
+
+// GetS3Client returns the S3 client
+func (c *EGAwsClient) GetS3Client() *s3.Client {
+	return c.s3Client
 }
@@ -0,0 +1,132 @@
+package easygo
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/cloudfront/sign"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// CloudFrontPolicyArgs describes a custom policy for signed URLs and cookies
+type CloudFrontPolicyArgs struct {
+	// Resource is the URL the policy applies to; it may contain * wildcards
+	Resource string
+	// Expires is required
+	Expires time.Time
+	// NotBefore optionally rejects requests made before this time
+	NotBefore time.Time
+	// SourceIP optionally restricts requests to an IP or CIDR, e.g. 192.0.2.0/24
+	SourceIP string
+}
+
+// CloudFrontSigner produces CloudFront signed URLs and signed cookies for a key pair
+type CloudFrontSigner struct {
+	urlSigner    *sign.URLSigner
+	cookieSigner *sign.CookieSigner
+}
+
+// NewCloudFrontSigner creates a signer from a key pair ID and a PEM encoded RSA private
+// key in PKCS#1 or PKCS#8 form. cookieOpts sets defaults for signed cookies.
+func NewCloudFrontSigner(keyPairID string, privateKeyPEM []byte, cookieOpts ...func(*sign.CookieOptions)) (*CloudFrontSigner, error) {
+	key, err := parseRSAPrivateKey(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CloudFrontSigner{
+		urlSigner:    sign.NewURLSigner(keyPairID, key),
+		cookieSigner: sign.NewCookieSigner(keyPairID, key, cookieOpts...),
+	}, nil
+}
+
+// NewCloudFrontSignerFromSecret creates a signer using a private key stored as a
+// plain PEM string in Secrets Manager
+func (c *EGAwsClient) NewCloudFrontSignerFromSecret(ctx context.Context, keyPairID, secretNameOrArn string, opts ...SecretValueOption) (*CloudFrontSigner, error) {
+	privateKeyPEM, err := c.GetLatestSecretBytes(ctx, secretNameOrArn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewCloudFrontSigner(keyPairID, privateKeyPEM)
+}
+
+// NewCloudFrontPolicy builds a custom policy from args
+func NewCloudFrontPolicy(args *CloudFrontPolicyArgs) *sign.Policy {
+	condition := sign.Condition{
+		DateLessThan: sign.NewAWSEpochTime(args.Expires),
+	}
+	if !args.NotBefore.IsZero() {
+		condition.DateGreaterThan = sign.NewAWSEpochTime(args.NotBefore)
+	}
+	if args.SourceIP != "" {
+		condition.IPAddress = &sign.IPAddress{SourceIP: args.SourceIP}
+	}
+
+	return &sign.Policy{
+		Statements: []sign.Statement{{
+			Resource:  args.Resource,
+			Condition: condition,
+		}},
+	}
+}
+
+// SignURL signs rawURL with a canned policy that expires at expires
+func (s *CloudFrontSigner) SignURL(rawURL string, expires time.Time) (string, error) {
+	signed, err := s.urlSigner.Sign(rawURL, expires)
+	if err != nil {
+		return "", errs.Errorf("failed to sign url: %w", err)
+	}
+	return signed, nil
+}
+
+// SignURLWithPolicy signs rawURL with a custom policy
+func (s *CloudFrontSigner) SignURLWithPolicy(rawURL string, policy *CloudFrontPolicyArgs) (string, error) {
+	signed, err := s.urlSigner.SignWithPolicy(rawURL, NewCloudFrontPolicy(policy))
+	if err != nil {
+		return "", errs.Errorf("failed to sign url: %w", err)
+	}
+	return signed, nil
+}
+
+// SignCookies returns signed cookies granting access to resource with a canned policy
+func (s *CloudFrontSigner) SignCookies(resource string, expires time.Time, opts ...func(*sign.CookieOptions)) ([]*http.Cookie, error) {
+	cookies, err := s.cookieSigner.Sign(resource, expires, opts...)
+	if err != nil {
+		return nil, errs.Errorf("failed to sign cookies: %w", err)
+	}
+	return cookies, nil
+}
+
+// SignCookiesWithPolicy returns signed cookies for a custom policy
+func (s *CloudFrontSigner) SignCookiesWithPolicy(policy *CloudFrontPolicyArgs, opts ...func(*sign.CookieOptions)) ([]*http.Cookie, error) {
+	cookies, err := s.cookieSigner.SignWithPolicy(NewCloudFrontPolicy(policy), opts...)
+	if err != nil {
+		return nil, errs.Errorf("failed to sign cookies: %w", err)
+	}
+	return cookies, nil
+}
+
+func parseRSAPrivateKey(privateKeyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, errs.New(errs.Internal, "failed to decode PEM private key")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errs.Errorf("failed to parse private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errs.New(errs.Internal, "private key is not an RSA key")
+	}
+	return key, nil
+}
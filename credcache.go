@@ -0,0 +1,169 @@
+package easygo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+)
+
+const (
+	defaultRoleSessionName = "easygo"
+	// credentialCacheExpiryWindow is how long before expiry cached credentials are considered stale
+	credentialCacheExpiryWindow = 5 * time.Minute
+)
+
+// DefaultCredentialCacheDir returns the per-user directory used for cached credentials,
+// e.g. ~/.cache/easygo/credentials on Linux
+func DefaultCredentialCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", errs.Errorf("failed to find user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "easygo", "credentials"), nil
+}
+
+type cachedCredentials struct {
+	// RoleArn is the assumed role, or the comma separated chain of roles
+	RoleArn         string    `json:"roleArn"`
+	SessionName     string    `json:"sessionName"`
+	AccessKeyID     string    `json:"accessKeyId"`
+	SecretAccessKey string    `json:"secretAccessKey"`
+	SessionToken    string    `json:"sessionToken"`
+	Expires         time.Time `json:"expires"`
+}
+
+// fileCredentialCache stores assumed-role credentials in a file keyed by role ARN and session name.
+// A nil *fileCredentialCache is a valid, disabled cache.
+type fileCredentialCache struct {
+	path        string
+	roleArn     string
+	sessionName string
+}
+
+func newFileCredentialCache(dir, roleArn, sessionName string) *fileCredentialCache {
+	sum := sha256.Sum256([]byte(roleArn + "|" + sessionName))
+	return &fileCredentialCache{
+		path:        filepath.Join(dir, hex.EncodeToString(sum[:])+".json"),
+		roleArn:     roleArn,
+		sessionName: sessionName,
+	}
+}
+
+// load returns cached credentials if they exist and are not close to expiry
+func (c *fileCredentialCache) load() (aws.Credentials, bool) {
+	if c == nil {
+		return aws.Credentials{}, false
+	}
+
+	b, err := os.ReadFile(c.path)
+	if err != nil {
+		return aws.Credentials{}, false
+	}
+
+	var cached cachedCredentials
+	if err := json.Unmarshal(b, &cached); err != nil {
+		return aws.Credentials{}, false
+	}
+	if cached.RoleArn != c.roleArn || cached.SessionName != c.sessionName {
+		return aws.Credentials{}, false
+	}
+	if time.Until(cached.Expires) < credentialCacheExpiryWindow {
+		return aws.Credentials{}, false
+	}
+
+	return aws.Credentials{
+		AccessKeyID:     cached.AccessKeyID,
+		SecretAccessKey: cached.SecretAccessKey,
+		SessionToken:    cached.SessionToken,
+		Source:          "easygo file cache",
+		CanExpire:       true,
+		Expires:         cached.Expires,
+	}, true
+}
+
+// store writes credentials to the cache file, readable only by the current user
+func (c *fileCredentialCache) store(creds aws.Credentials) error {
+	if c == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(cachedCredentials{
+		RoleArn:         c.roleArn,
+		SessionName:     c.sessionName,
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		Expires:         creds.Expires,
+	})
+	if err != nil {
+		return errs.Errorf("failed to marshal cached credentials: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return errs.Errorf("failed to create credential cache directory: %w", err)
+	}
+
+	// write to a temp file and rename so concurrent processes never read a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".creds-*")
+	if err != nil {
+		return errs.Errorf("failed to create credential cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return errs.Errorf("failed to write credential cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errs.Errorf("failed to write credential cache file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return errs.Errorf("failed to write credential cache file: %w", err)
+	}
+
+	return nil
+}
+
+// fileCachedCredentialsProvider serves credentials from a fileCredentialCache,
+// falling back to provider and storing its result when the cache is empty or stale
+type fileCachedCredentialsProvider struct {
+	cache    *fileCredentialCache
+	provider aws.CredentialsProvider
+	logger   logging.Logger
+}
+
+func (p *fileCachedCredentialsProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	if creds, ok := p.cache.load(); ok {
+		p.logger.WithField("roleArn", p.cache.roleArn).Debug("using cached assume role credentials")
+		return creds, nil
+	}
+
+	creds, err := p.provider.Retrieve(ctx)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	if err := p.cache.store(creds); err != nil {
+		p.logger.WithError(err).Warn("failed to write credential cache")
+	}
+	return creds, nil
+}
+
+// clear removes the cache file
+func (c *fileCredentialCache) clear() error {
+	if c == nil {
+		return nil
+	}
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errs.Errorf("failed to remove credential cache file: %w", err)
+	}
+	return nil
+}
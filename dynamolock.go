@@ -0,0 +1,306 @@
+package easygo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/bdlilley/easygo/pkg/clock"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// ErrLockHeld is returned by TryAcquire when another owner holds an unexpired lease
+var ErrLockHeld = errs.New(errs.Conflict, "lock is held by another owner")
+
+// DynamoDBLockAPI is the subset of the DynamoDB client used by DynamoDBLocker
+type DynamoDBLockAPI interface {
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+}
+
+type DynamoDBLockerOptions struct {
+	// KeyAttribute is the table's partition key, a string (default: lockKey)
+	KeyAttribute string
+	// LeaseDuration is how long a lock is held without renewal (default: 30s)
+	LeaseDuration time.Duration
+	// HeartbeatInterval is how often held locks are renewed (default: LeaseDuration/3)
+	HeartbeatInterval time.Duration
+	// RetryInterval is how often Acquire retries a held lock (default: 1s)
+	RetryInterval time.Duration
+	// Owner identifies this process in lock items (default: hostname plus a random suffix)
+	Owner string
+	// Clock times leases, renewals and retries (default: clock.Real)
+	Clock clock.Clock
+}
+
+// DynamoDBLocker acquires leased locks stored as items in a DynamoDB table.
+// Items keep the owner, lease expiry, and a fencing token incremented on every
+// acquisition. Release and expired leases leave the item in place so the token
+// keeps increasing; do not expire lock items with the table's TTL, as a
+// recreated item restarts the token at 1.
+type DynamoDBLocker struct {
+	client DynamoDBLockAPI
+	table  string
+	opts   DynamoDBLockerOptions
+}
+
+// NewDynamoDBLocker creates a locker for locks in table
+func NewDynamoDBLocker(client DynamoDBLockAPI, table string, opts *DynamoDBLockerOptions) *DynamoDBLocker {
+	o := DynamoDBLockerOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.KeyAttribute == "" {
+		o.KeyAttribute = "lockKey"
+	}
+	if o.LeaseDuration <= 0 {
+		o.LeaseDuration = 30 * time.Second
+	}
+	if o.HeartbeatInterval <= 0 || o.HeartbeatInterval >= o.LeaseDuration {
+		o.HeartbeatInterval = o.LeaseDuration / 3
+	}
+	if o.RetryInterval <= 0 {
+		o.RetryInterval = time.Second
+	}
+	if o.Owner == "" {
+		host, _ := os.Hostname()
+		suffix := make([]byte, 4)
+		_, _ = rand.Read(suffix)
+		o.Owner = host + "-" + hex.EncodeToString(suffix)
+	}
+	if o.Clock == nil {
+		o.Clock = clock.Real()
+	}
+	return &DynamoDBLocker{client: client, table: table, opts: o}
+}
+
+// NewDynamoDBLocker creates a locker for locks in table using the client's DynamoDB client
+func (c *EGAwsClient) NewDynamoDBLocker(table string, opts *DynamoDBLockerOptions) *DynamoDBLocker {
+	return NewDynamoDBLocker(c.dynamoClient, table, opts)
+}
+
+// DynamoDBLock is a held lock. Its lease is renewed in the background until
+// Release or until renewal fails, after which Lost is closed.
+type DynamoDBLock struct {
+	locker *DynamoDBLocker
+	key    string
+	fence  int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	lost   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+
+	mu      sync.Mutex
+	expires time.Time
+}
+
+// TryAcquire takes the lock for key if it is free or its lease has expired,
+// and returns ErrLockHeld otherwise
+func (l *DynamoDBLocker) TryAcquire(ctx context.Context, key string) (*DynamoDBLock, error) {
+	now := l.opts.Clock.Now()
+	expires := now.Add(l.opts.LeaseDuration)
+	output, err := l.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(l.table),
+		Key:                 map[string]types.AttributeValue{l.opts.KeyAttribute: &types.AttributeValueMemberS{Value: key}},
+		UpdateExpression:    aws.String("SET #owner = :owner, #expires = :expires ADD #fence :one"),
+		ConditionExpression: aws.String("attribute_not_exists(#owner) OR #expires < :now"),
+		ExpressionAttributeNames: map[string]string{
+			"#owner":   "owner",
+			"#expires": "expiresAt",
+			"#fence":   "fence",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner":   &types.AttributeValueMemberS{Value: l.opts.Owner},
+			":expires": epochMillis(expires),
+			":now":     epochMillis(now),
+			":one":     &types.AttributeValueMemberN{Value: "1"},
+		},
+		ReturnValues: types.ReturnValueUpdatedNew,
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return nil, ErrLockHeld
+		}
+		return nil, errs.Errorf("failed to acquire lock %s: %w", key, err)
+	}
+
+	fence, err := numberAttribute(output.Attributes["fence"])
+	if err != nil {
+		return nil, errs.Errorf("invalid fencing token for lock %s: %w", key, err)
+	}
+
+	lockCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	lock := &DynamoDBLock{
+		locker:  l,
+		key:     key,
+		fence:   fence,
+		ctx:     lockCtx,
+		cancel:  cancel,
+		lost:    make(chan struct{}),
+		done:    make(chan struct{}),
+		expires: expires,
+	}
+	go lock.heartbeat()
+	return lock, nil
+}
+
+// Acquire waits until the lock for key is taken or ctx is done
+func (l *DynamoDBLocker) Acquire(ctx context.Context, key string) (*DynamoDBLock, error) {
+	for {
+		lock, err := l.TryAcquire(ctx, key)
+		if !errors.Is(err, ErrLockHeld) {
+			return lock, err
+		}
+		select {
+		case <-l.opts.Clock.After(l.opts.RetryInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Key returns the lock's key
+func (k *DynamoDBLock) Key() string {
+	return k.key
+}
+
+// Fence returns the fencing token, which increases with every acquisition of
+// the key. Pass it to downstream writes so stale holders can be rejected.
+func (k *DynamoDBLock) Fence() int64 {
+	return k.fence
+}
+
+// Context is canceled when the lock is released or lost; run guarded work with it
+func (k *DynamoDBLock) Context() context.Context {
+	return k.ctx
+}
+
+// Lost is closed when the lease could not be renewed
+func (k *DynamoDBLock) Lost() <-chan struct{} {
+	return k.lost
+}
+
+// Release stops renewal and frees the lock if this lock still holds it
+func (k *DynamoDBLock) Release(ctx context.Context) error {
+	k.stop(false)
+	<-k.done
+
+	l := k.locker
+	_, err := l.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(l.table),
+		Key:                 map[string]types.AttributeValue{l.opts.KeyAttribute: &types.AttributeValueMemberS{Value: k.key}},
+		UpdateExpression:    aws.String("REMOVE #owner SET #expires = :zero"),
+		ConditionExpression: aws.String("#owner = :owner AND #fence = :fence"),
+		ExpressionAttributeNames: map[string]string{
+			"#owner":   "owner",
+			"#expires": "expiresAt",
+			"#fence":   "fence",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner": &types.AttributeValueMemberS{Value: l.opts.Owner},
+			":fence": &types.AttributeValueMemberN{Value: strconv.FormatInt(k.fence, 10)},
+			":zero":  &types.AttributeValueMemberN{Value: "0"},
+		},
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			// the lease expired and someone else holds the lock; nothing to release
+			return nil
+		}
+		return errs.Errorf("failed to release lock %s: %w", k.key, err)
+	}
+	return nil
+}
+
+func (k *DynamoDBLock) stop(lost bool) {
+	k.once.Do(func() {
+		if lost {
+			close(k.lost)
+		}
+		k.cancel()
+	})
+}
+
+// heartbeat renews the lease until the lock is released. Failed renewals are
+// retried until the lease expires; a lease taken over by another owner is lost
+// immediately.
+func (k *DynamoDBLock) heartbeat() {
+	defer close(k.done)
+	l := k.locker
+	ticker := l.opts.Clock.NewTicker(l.opts.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-k.ctx.Done():
+			return
+		case <-ticker.C():
+		}
+
+		err := k.renew()
+		if err == nil {
+			continue
+		}
+		var condErr *types.ConditionalCheckFailedException
+		k.mu.Lock()
+		expired := !l.opts.Clock.Now().Before(k.expires)
+		k.mu.Unlock()
+		if errors.As(err, &condErr) || expired {
+			k.stop(true)
+			return
+		}
+	}
+}
+
+func (k *DynamoDBLock) renew() error {
+	l := k.locker
+	expires := l.opts.Clock.Now().Add(l.opts.LeaseDuration)
+	ctx, cancel := context.WithTimeout(k.ctx, l.opts.HeartbeatInterval)
+	defer cancel()
+	_, err := l.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(l.table),
+		Key:                 map[string]types.AttributeValue{l.opts.KeyAttribute: &types.AttributeValueMemberS{Value: k.key}},
+		UpdateExpression:    aws.String("SET #expires = :expires"),
+		ConditionExpression: aws.String("#owner = :owner AND #fence = :fence"),
+		ExpressionAttributeNames: map[string]string{
+			"#owner":   "owner",
+			"#expires": "expiresAt",
+			"#fence":   "fence",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner":   &types.AttributeValueMemberS{Value: l.opts.Owner},
+			":fence":   &types.AttributeValueMemberN{Value: strconv.FormatInt(k.fence, 10)},
+			":expires": epochMillis(expires),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	k.mu.Lock()
+	k.expires = expires
+	k.mu.Unlock()
+	return nil
+}
+
+func epochMillis(t time.Time) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(t.UnixMilli(), 10)}
+}
+
+func numberAttribute(v types.AttributeValue) (int64, error) {
+	n, ok := v.(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, fmt.Errorf("expected a number attribute, got %T", v)
+	}
+	return strconv.ParseInt(n.Value, 10, 64)
+}
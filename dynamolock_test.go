@@ -0,0 +1,146 @@
+package easygo
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/bdlilley/easygo/pkg/clock"
+)
+
+// fakeLockTable evaluates the locker's three update expressions against one item
+type fakeLockTable struct {
+	mu      sync.Mutex
+	owner   string
+	expires int64
+	fence   int64
+}
+
+func (f *fakeLockTable) UpdateItem(ctx context.Context, in *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := in.ExpressionAttributeNames["#ttl"]; ok {
+		// an expiring item would restart the fencing token
+		return nil, errors.New("lock items must not set a ttl")
+	}
+	str := func(k string) string { return in.ExpressionAttributeValues[k].(*types.AttributeValueMemberS).Value }
+	num := func(k string) int64 {
+		n, _ := strconv.ParseInt(in.ExpressionAttributeValues[k].(*types.AttributeValueMemberN).Value, 10, 64)
+		return n
+	}
+	failed := &types.ConditionalCheckFailedException{Message: aws.String("condition failed")}
+
+	switch aws.ToString(in.ConditionExpression) {
+	case "attribute_not_exists(#owner) OR #expires < :now":
+		if f.owner != "" && f.expires >= num(":now") {
+			return nil, failed
+		}
+		f.owner, f.expires = str(":owner"), num(":expires")
+		f.fence++
+		return &dynamodb.UpdateItemOutput{Attributes: map[string]types.AttributeValue{
+			"fence": &types.AttributeValueMemberN{Value: strconv.FormatInt(f.fence, 10)},
+		}}, nil
+	default:
+		if f.owner != str(":owner") || f.fence != num(":fence") {
+			return nil, failed
+		}
+		if _, ok := in.ExpressionAttributeValues[":zero"]; ok {
+			f.owner, f.expires = "", 0
+		} else {
+			f.expires = num(":expires")
+		}
+		return &dynamodb.UpdateItemOutput{}, nil
+	}
+}
+
+func TestDynamoDBLockFencing(t *testing.T) {
+	ctx := context.Background()
+	table := &fakeLockTable{}
+	a := NewDynamoDBLocker(table, "locks", &DynamoDBLockerOptions{Owner: "a", LeaseDuration: time.Minute})
+	b := NewDynamoDBLocker(table, "locks", &DynamoDBLockerOptions{Owner: "b", LeaseDuration: time.Minute})
+
+	lockA, err := a.TryAcquire(ctx, "job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.TryAcquire(ctx, "job"); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("expected ErrLockHeld, got %v", err)
+	}
+	if err := lockA.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if lockA.Context().Err() == nil {
+		t.Fatal("expected lock context to be canceled after release")
+	}
+
+	lockB, err := b.TryAcquire(ctx, "job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lockB.Release(ctx)
+	if lockB.Fence() <= lockA.Fence() {
+		t.Fatalf("expected fence to increase, got %d after %d", lockB.Fence(), lockA.Fence())
+	}
+}
+
+func TestDynamoDBLockLost(t *testing.T) {
+	table := &fakeLockTable{}
+	l := NewDynamoDBLocker(table, "locks", &DynamoDBLockerOptions{Owner: "a", LeaseDuration: 30 * time.Millisecond})
+	lock, err := l.TryAcquire(context.Background(), "job")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// another owner takes over the item
+	table.mu.Lock()
+	table.owner = "b"
+	table.fence++
+	table.mu.Unlock()
+
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("expected lock to be lost")
+	}
+	if lock.Context().Err() == nil {
+		t.Fatal("expected lock context to be canceled")
+	}
+}
+
+func TestDynamoDBLockExpiredLease(t *testing.T) {
+	ctx := context.Background()
+	table := &fakeLockTable{}
+	start := time.Unix(1000, 0)
+	clkA, clkB := clock.NewFake(start), clock.NewFake(start)
+	a := NewDynamoDBLocker(table, "locks", &DynamoDBLockerOptions{Owner: "a", LeaseDuration: time.Minute, Clock: clkA})
+	b := NewDynamoDBLocker(table, "locks", &DynamoDBLockerOptions{Owner: "b", LeaseDuration: time.Minute, Clock: clkB})
+
+	lockA, err := a.TryAcquire(ctx, "job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a is stalled and stops renewing; b sees the lease expire
+	clkB.Advance(2 * time.Minute)
+	lockB, err := b.TryAcquire(ctx, "job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lockB.Release(ctx)
+	if lockB.Fence() <= lockA.Fence() {
+		t.Fatalf("expected fence to increase, got %d after %d", lockB.Fence(), lockA.Fence())
+	}
+
+	// the stale holder's release leaves b's lock in place
+	if err := lockA.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.TryAcquire(ctx, "job"); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("expected ErrLockHeld, got %v", err)
+	}
+}
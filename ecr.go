@@ -0,0 +1,109 @@
+package easygo
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// ecrTokenRefreshWindow is how long before expiry a cached ECR token is refreshed
+const ecrTokenRefreshWindow = 5 * time.Minute
+
+// ECRAuthorization is a decoded ECR authorization token
+type ECRAuthorization struct {
+	Username string
+	Password string
+	// ProxyEndpoint is the registry URL, e.g. https://123456789012.dkr.ecr.us-east-1.amazonaws.com
+	ProxyEndpoint string
+	ExpiresAt     time.Time
+	// Token is the raw base64 "username:password" token
+	Token string
+}
+
+// Registry returns the registry host without the URL scheme
+func (a *ECRAuthorization) Registry() string {
+	return strings.TrimPrefix(strings.TrimPrefix(a.ProxyEndpoint, "https://"), "http://")
+}
+
+// DockerAuthEntry is a single registry entry in a docker config.json
+type DockerAuthEntry struct {
+	Auth string `json:"auth"`
+}
+
+// DockerConfig is the docker config.json format understood by docker, containerd,
+// and kubernetes dockerconfigjson image pull secrets
+type DockerConfig struct {
+	Auths map[string]DockerAuthEntry `json:"auths"`
+}
+
+// DockerConfig returns a docker-compatible auth config for the registry
+func (a *ECRAuthorization) DockerConfig() *DockerConfig {
+	return &DockerConfig{
+		Auths: map[string]DockerAuthEntry{
+			a.Registry(): {Auth: a.Token},
+		},
+	}
+}
+
+type ecrAuthCache struct {
+	mu   sync.Mutex
+	auth *ECRAuthorization
+}
+
+// GetECRAuthorization returns an ECR authorization token for the default registry
+// of the caller's account. The token is cached and refreshed shortly before it expires.
+func (c *EGAwsClient) GetECRAuthorization(ctx context.Context) (*ECRAuthorization, error) {
+	c.ecrAuth.mu.Lock()
+	defer c.ecrAuth.mu.Unlock()
+
+	if c.ecrAuth.auth != nil && time.Until(c.ecrAuth.auth.ExpiresAt) > ecrTokenRefreshWindow {
+		return c.ecrAuth.auth, nil
+	}
+
+	output, err := c.ecrClient.GetAuthorizationToken(ctx, &ecr.GetAuthorizationTokenInput{})
+	if err != nil {
+		return nil, errs.Errorf("failed to get ECR authorization token: %w", err)
+	}
+	if len(output.AuthorizationData) == 0 {
+		return nil, errs.New(errs.Internal, "ECR returned no authorization data")
+	}
+
+	data := output.AuthorizationData[0]
+	token := aws.ToString(data.AuthorizationToken)
+	decoded, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errs.Errorf("failed to decode ECR authorization token: %w", err)
+	}
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return nil, errs.New(errs.Internal, "ECR authorization token is not in username:password format")
+	}
+
+	auth := &ECRAuthorization{
+		Username:      username,
+		Password:      password,
+		ProxyEndpoint: aws.ToString(data.ProxyEndpoint),
+		Token:         token,
+	}
+	if data.ExpiresAt != nil {
+		auth.ExpiresAt = *data.ExpiresAt
+	}
+	c.ecrAuth.auth = auth
+
+	return auth, nil
+}
+
+// GetECRDockerConfig returns a docker-compatible auth config for the default ECR registry
+func (c *EGAwsClient) GetECRDockerConfig(ctx context.Context) (*DockerConfig, error) {
+	auth, err := c.GetECRAuthorization(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return auth.DockerConfig(), nil
+}
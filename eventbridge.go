@@ -0,0 +1,151 @@
+package easygo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// EventBridge PutEvents limits
+const (
+	eventBridgeMaxBatchEntries = 10
+	eventBridgeMaxRequestBytes = 262144
+	eventBridgeTimeBytes       = 14
+)
+
+// eventBridgePutEventsAPI is the subset of the EventBridge client used by PutEvents
+type eventBridgePutEventsAPI interface {
+	PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error)
+}
+
+// EventBridgeEvent is a single event to publish; Detail is marshaled to JSON
+type EventBridgeEvent struct {
+	BusName    string
+	Source     string
+	DetailType string
+	Detail     any
+	Resources  []string
+	// Time defaults to the time the event is received by EventBridge
+	Time *time.Time
+}
+
+// EventBridgeFailedEntry describes an event that EventBridge rejected
+type EventBridgeFailedEntry struct {
+	// Index is the position of the event in the slice passed to PutEvents
+	Index        int
+	ErrorCode    string
+	ErrorMessage string
+}
+
+// EventBridgePutError is returned by PutEvents when some events were rejected
+type EventBridgePutError struct {
+	Failed []EventBridgeFailedEntry
+}
+
+func (e *EventBridgePutError) Error() string {
+	if len(e.Failed) == 0 {
+		return "failed to put events"
+	}
+	first := e.Failed[0]
+	return fmt.Sprintf("failed to put %d event(s); first failure at index %d: %s: %s",
+		len(e.Failed), first.Index, first.ErrorCode, first.ErrorMessage)
+}
+
+// PutEvent marshals detail to JSON and publishes it to busName
+func (c *EGAwsClient) PutEvent(ctx context.Context, busName, source, detailType string, detail any) error {
+	_, err := c.PutEvents(ctx, []EventBridgeEvent{{
+		BusName:    busName,
+		Source:     source,
+		DetailType: detailType,
+		Detail:     detail,
+	}})
+	return err
+}
+
+// PutEvents publishes events, splitting them into requests that respect the
+// 10-entry and 256KB PutEvents limits. It returns the event IDs in input order;
+// rejected events have an empty ID and are reported by an *EventBridgePutError.
+func (c *EGAwsClient) PutEvents(ctx context.Context, events []EventBridgeEvent) ([]string, error) {
+	return putEvents(ctx, c.eventsClient, events)
+}
+
+func putEvents(ctx context.Context, client eventBridgePutEventsAPI, events []EventBridgeEvent) ([]string, error) {
+	entries := make([]types.PutEventsRequestEntry, len(events))
+	sizes := make([]int, len(events))
+	for i, ev := range events {
+		detail, err := json.Marshal(ev.Detail)
+		if err != nil {
+			return nil, errs.Errorf("failed to marshal detail for event %d: %w", i, err)
+		}
+		entries[i] = types.PutEventsRequestEntry{
+			Detail:     aws.String(string(detail)),
+			DetailType: aws.String(ev.DetailType),
+			Source:     aws.String(ev.Source),
+			Resources:  ev.Resources,
+			Time:       ev.Time,
+		}
+		if ev.BusName != "" {
+			entries[i].EventBusName = aws.String(ev.BusName)
+		}
+		sizes[i] = eventBridgeEntrySize(entries[i])
+		if sizes[i] > eventBridgeMaxRequestBytes {
+			return nil, errs.Newf(errs.Internal, "event %d is %d bytes, exceeding the %d byte limit", i, sizes[i], eventBridgeMaxRequestBytes)
+		}
+	}
+
+	ids := make([]string, len(events))
+	putErr := &EventBridgePutError{}
+
+	start := 0
+	for start < len(entries) {
+		end := start
+		size := 0
+		for end < len(entries) && end-start < eventBridgeMaxBatchEntries && size+sizes[end] <= eventBridgeMaxRequestBytes {
+			size += sizes[end]
+			end++
+		}
+
+		output, err := client.PutEvents(ctx, &eventbridge.PutEventsInput{
+			Entries: entries[start:end],
+		})
+		if err != nil {
+			return ids, errs.Errorf("failed to put events: %w", err)
+		}
+		for i, result := range output.Entries {
+			if result.ErrorCode != nil {
+				putErr.Failed = append(putErr.Failed, EventBridgeFailedEntry{
+					Index:        start + i,
+					ErrorCode:    aws.ToString(result.ErrorCode),
+					ErrorMessage: aws.ToString(result.ErrorMessage),
+				})
+				continue
+			}
+			ids[start+i] = aws.ToString(result.EventId)
+		}
+
+		start = end
+	}
+
+	if len(putErr.Failed) > 0 {
+		return ids, putErr
+	}
+	return ids, nil
+}
+
+// eventBridgeEntrySize calculates the entry size the way EventBridge does for the request limit
+func eventBridgeEntrySize(entry types.PutEventsRequestEntry) int {
+	size := len(aws.ToString(entry.Source)) + len(aws.ToString(entry.DetailType)) + len(aws.ToString(entry.Detail))
+	if entry.Time != nil {
+		size += eventBridgeTimeBytes
+	}
+	for _, r := range entry.Resources {
+		size += len(r)
+	}
+	return size
+}
@@ -0,0 +1,87 @@
+package easygo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+)
+
+// fakeEventBridge records PutEvents requests and rejects the entries fail picks
+type fakeEventBridge struct {
+	calls [][]types.PutEventsRequestEntry
+	fail  func(call, i int) bool
+}
+
+func (f *fakeEventBridge) PutEvents(_ context.Context, in *eventbridge.PutEventsInput, _ ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error) {
+	call := len(f.calls)
+	f.calls = append(f.calls, in.Entries)
+	out := &eventbridge.PutEventsOutput{Entries: make([]types.PutEventsResultEntry, len(in.Entries))}
+	for i := range in.Entries {
+		if f.fail != nil && f.fail(call, i) {
+			out.Entries[i] = types.PutEventsResultEntry{ErrorCode: aws.String("ThrottlingException"), ErrorMessage: aws.String("slow down")}
+			out.FailedEntryCount++
+			continue
+		}
+		out.Entries[i] = types.PutEventsResultEntry{EventId: aws.String(fmt.Sprint("id-", call, "-", i))}
+	}
+	return out, nil
+}
+
+func TestPutEventsSplitsRequests(t *testing.T) {
+	client := &fakeEventBridge{}
+	events := make([]EventBridgeEvent, 25)
+	for i := range events {
+		events[i] = EventBridgeEvent{Source: "orders", DetailType: "created", Detail: map[string]int{"n": i}}
+	}
+	// 100KB events, so only two fit in a 256KB request
+	for i := 20; i < 25; i++ {
+		events[i].Detail = strings.Repeat("x", 100*1024)
+	}
+	ids, err := putEvents(context.Background(), client, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var counts []int
+	for _, call := range client.calls {
+		counts = append(counts, len(call))
+	}
+	if fmt.Sprint(counts) != "[10 10 2 2 1]" {
+		t.Fatalf("got requests of %v entries, want [10 10 2 2 1]", counts)
+	}
+	if len(ids) != 25 || ids[0] != "id-0-0" || ids[12] != "id-1-2" || ids[24] != "id-4-0" {
+		t.Fatalf("got ids %v", ids)
+	}
+
+	events[0].Detail = strings.Repeat("x", eventBridgeMaxRequestBytes)
+	if _, err := putEvents(context.Background(), client, events[:1]); err == nil {
+		t.Fatal("expected an error for an event over the request limit")
+	}
+}
+
+func TestPutEventsFailedEntries(t *testing.T) {
+	// the second request rejects its second entry, event 11 of the input
+	client := &fakeEventBridge{fail: func(call, i int) bool { return call == 1 && i == 1 }}
+	events := make([]EventBridgeEvent, 15)
+	for i := range events {
+		events[i] = EventBridgeEvent{Source: "orders", DetailType: "created", Detail: i}
+	}
+	ids, err := putEvents(context.Background(), client, events)
+
+	var putErr *EventBridgePutError
+	if !errors.As(err, &putErr) {
+		t.Fatalf("expected an *EventBridgePutError, got %v", err)
+	}
+	if len(putErr.Failed) != 1 || putErr.Failed[0].Index != 11 || putErr.Failed[0].ErrorCode != "ThrottlingException" {
+		t.Fatalf("got failed entries %+v, want index 11", putErr.Failed)
+	}
+	if ids[11] != "" || ids[10] != "id-1-0" || ids[14] != "id-1-4" {
+		t.Fatalf("got ids %v", ids)
+	}
+}
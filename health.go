@@ -0,0 +1,74 @@
+package easygo
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// healthCredentialExpiryWindow is the minimum remaining credential lifetime considered healthy
+const healthCredentialExpiryWindow = time.Minute
+
+// AwsHealthStatus is the result of an AWS connectivity check
+type AwsHealthStatus struct {
+	Healthy bool   `json:"healthy"`
+	Account string `json:"account,omitempty"`
+	Arn     string `json:"arn,omitempty"`
+	// CredentialsExpire is false for long-lived credentials
+	CredentialsExpire    bool          `json:"credentialsExpire"`
+	CredentialsExpiresAt time.Time     `json:"credentialsExpiresAt,omitempty"`
+	Latency              time.Duration `json:"latency"`
+	Error                string        `json:"error,omitempty"`
+}
+
+// HealthStatus checks that credentials can be retrieved and are not about to expire,
+// then makes a GetCallerIdentity call to verify STS is reachable
+func (c *EGAwsClient) HealthStatus(ctx context.Context) *AwsHealthStatus {
+	status := &AwsHealthStatus{}
+	start := time.Now()
+	defer func() {
+		status.Latency = time.Since(start)
+	}()
+
+	if c.cfg.Credentials == nil {
+		status.Error = "no credentials configured"
+		return status
+	}
+	creds, err := c.cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		status.Error = errs.Errorf("failed to retrieve credentials: %w", err).Error()
+		return status
+	}
+	if creds.CanExpire {
+		status.CredentialsExpire = true
+		status.CredentialsExpiresAt = creds.Expires
+		if time.Until(creds.Expires) < healthCredentialExpiryWindow {
+			status.Error = "credentials are expired or about to expire"
+			return status
+		}
+	}
+
+	id, err := c.stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		status.Error = errs.Errorf("failed to get caller identity: %w", err).Error()
+		return status
+	}
+	status.Account = aws.ToString(id.Account)
+	status.Arn = aws.ToString(id.Arn)
+	status.Healthy = true
+
+	return status
+}
+
+// HealthCheck returns an error when HealthStatus reports the client unhealthy;
+// it matches the func(ctx) error shape used by readiness checks
+func (c *EGAwsClient) HealthCheck(ctx context.Context) error {
+	status := c.HealthStatus(ctx)
+	if !status.Healthy {
+		return errs.New(errs.Internal, status.Error)
+	}
+	return nil
+}
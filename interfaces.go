@@ -0,0 +1,58 @@
+package easygo
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// Small interfaces implemented by EGAwsClient. Depend on these instead of the
+// concrete client so code can be unit tested with the fakes in pkg/egaws/egawstest.
+
+// SecretsReader reads Secrets Manager secret values
+type SecretsReader interface {
+	GetLatestJsonSecretValue(ctx context.Context, secretNameOrArn string, result any, opts ...SecretValueOption) error
+	GetLatestSecretString(ctx context.Context, secretNameOrArn string, opts ...SecretValueOption) (string, error)
+	GetLatestSecretBytes(ctx context.Context, secretNameOrArn string, opts ...SecretValueOption) ([]byte, error)
+}
+
+// SecretsWriter writes new Secrets Manager secret versions
+type SecretsWriter interface {
+	PutJsonSecretValue(ctx context.Context, secretNameOrArn string, value any, opts ...PutSecretOption) (string, error)
+	PutSecretString(ctx context.Context, secretNameOrArn string, value string, opts ...PutSecretOption) (string, error)
+}
+
+// SecretsReadWriter reads and writes secrets
+type SecretsReadWriter interface {
+	SecretsReader
+	SecretsWriter
+}
+
+// ParameterReader reads SSM Parameter Store values
+type ParameterReader interface {
+	GetParameterValue(ctx context.Context, name string) (string, error)
+}
+
+// IdentityProvider returns the caller's AWS identity
+type IdentityProvider interface {
+	GetCallerIdentity(ctx context.Context) (*sts.GetCallerIdentityOutput, error)
+}
+
+// EventPublisher publishes EventBridge events
+type EventPublisher interface {
+	PutEvent(ctx context.Context, busName, source, detailType string, detail any) error
+	PutEvents(ctx context.Context, events []EventBridgeEvent) ([]string, error)
+}
+
+// HealthChecker reports AWS connectivity
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+var (
+	_ SecretsReadWriter = (*EGAwsClient)(nil)
+	_ ParameterReader   = (*EGAwsClient)(nil)
+	_ IdentityProvider  = (*EGAwsClient)(nil)
+	_ EventPublisher    = (*EGAwsClient)(nil)
+	_ HealthChecker     = (*EGAwsClient)(nil)
+)
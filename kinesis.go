@@ -0,0 +1,308 @@
+package easygo
+
+import (
+	"context"
+	"crypto/md5"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"github.com/bdlilley/easygo/pkg/batcher"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Kinesis PutRecords limits
+const (
+	kinesisMaxBatchRecords = 500
+	kinesisMaxBatchBytes   = 5 * 1024 * 1024
+	kinesisMaxRecordBytes  = 1024 * 1024
+)
+
+// kinesisAggregationMagic starts records in the KPL aggregated record format
+var kinesisAggregationMagic = []byte{0xF3, 0x89, 0x9A, 0xC2}
+
+// ErrKinesisProducerClosed is returned by Put after Close has been called
+var ErrKinesisProducerClosed = errs.New(errs.Unavailable, "kinesis producer is closed")
+
+// KinesisPutRecordsAPI is the subset of the Kinesis client used by KinesisProducer
+type KinesisPutRecordsAPI interface {
+	PutRecords(ctx context.Context, params *kinesis.PutRecordsInput, optFns ...func(*kinesis.Options)) (*kinesis.PutRecordsOutput, error)
+}
+
+type KinesisProducerOptions struct {
+	// FlushInterval is the maximum time a record waits before being sent (default: 1s)
+	FlushInterval time.Duration
+	// MaxBatchRecords is the number of records that triggers a flush (default and max: 500)
+	MaxBatchRecords int
+	// MaxBatchBytes is the batch size that triggers a flush (default and max: 5MB)
+	MaxBatchBytes int
+	// MaxBufferedRecords bounds the records waiting to be batched; Put blocks when full (default: 10000)
+	MaxBufferedRecords int
+	// MaxRetries is how many times records rejected in a PutRecords response are retried (default: 3)
+	MaxRetries int
+	// RetryBackoff is the initial delay between retries, doubled per attempt (default: 100ms)
+	RetryBackoff time.Duration
+	// ErrorHandler receives errors from background flushes (default: log them to Logger)
+	ErrorHandler func(error)
+	// Logger logs failed flushes at error without an ErrorHandler (default: logging.Noop)
+	Logger logging.Logger
+	// Aggregate packs the records of a batch into Kinesis records of up to 1MB
+	// in the KPL aggregated record format, which the KCL and the Lambda event
+	// source deaggregate. It cuts the request count and shard throughput
+	// used by small records, but an aggregated record is routed by its first
+	// record's partition key, so records with different keys may share a shard.
+	// MaxBatchRecords and MaxBatchBytes count records before aggregation.
+	Aggregate bool
+}
+
+type kinesisRecord struct {
+	partitionKey string
+	data         []byte
+}
+
+func (r kinesisRecord) size() int {
+	return len(r.partitionKey) + len(r.data)
+}
+
+// KinesisProducer buffers records and writes them to a stream with PutRecords,
+// respecting the 500-record/5MB request limits and retrying partial failures.
+// Records are optionally aggregated; see KinesisProducerOptions.Aggregate.
+type KinesisProducer struct {
+	client     KinesisPutRecordsAPI
+	streamName string
+	opts       KinesisProducerOptions
+	batcher    *batcher.Batcher[kinesisRecord]
+}
+
+// NewKinesisProducer creates a producer for streamName and starts its background
+// batching loop. Call Close to flush buffered records and stop it.
+func NewKinesisProducer(client KinesisPutRecordsAPI, streamName string, opts *KinesisProducerOptions) *KinesisProducer {
+	o := KinesisProducerOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = time.Second
+	}
+	if o.MaxBatchRecords <= 0 || o.MaxBatchRecords > kinesisMaxBatchRecords {
+		o.MaxBatchRecords = kinesisMaxBatchRecords
+	}
+	if o.MaxBatchBytes <= 0 || o.MaxBatchBytes > kinesisMaxBatchBytes {
+		o.MaxBatchBytes = kinesisMaxBatchBytes
+	}
+	if o.MaxBufferedRecords <= 0 {
+		o.MaxBufferedRecords = 10000
+	}
+	if o.MaxRetries <= 0 {
+		o.MaxRetries = 3
+	}
+	if o.RetryBackoff <= 0 {
+		o.RetryBackoff = 100 * time.Millisecond
+	}
+	if o.Logger == nil {
+		o.Logger = logging.Noop()
+	}
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = func(err error) {
+			o.Logger.WithError(err).WithField("stream", streamName).Error("kinesis producer flush failed")
+		}
+	}
+
+	p := &KinesisProducer{client: client, streamName: streamName, opts: o}
+	p.batcher = batcher.New(p.flush, &batcher.Options[kinesisRecord]{
+		MaxItems:     o.MaxBatchRecords,
+		MaxBytes:     o.MaxBatchBytes,
+		Size:         kinesisRecord.size,
+		MaxAge:       o.FlushInterval,
+		QueueSize:    o.MaxBufferedRecords,
+		ErrorHandler: func(_ []kinesisRecord, err error) { o.ErrorHandler(err) },
+	})
+	return p
+}
+
+// NewKinesisProducer creates a producer for streamName using the client's Kinesis client
+func (c *EGAwsClient) NewKinesisProducer(streamName string, opts *KinesisProducerOptions) *KinesisProducer {
+	return NewKinesisProducer(c.kinesisClient, streamName, opts)
+}
+
+// Put buffers a record, blocking while the buffer is full until ctx is done
+func (p *KinesisProducer) Put(ctx context.Context, partitionKey string, data []byte) error {
+	r := kinesisRecord{partitionKey: partitionKey, data: data}
+	if r.size() > kinesisMaxRecordBytes {
+		return errs.Newf(errs.Internal, "record is %d bytes, exceeding the %d byte limit", r.size(), kinesisMaxRecordBytes)
+	}
+	return kinesisProducerError(p.batcher.Add(ctx, r))
+}
+
+// Flush sends all records buffered so far
+func (p *KinesisProducer) Flush(ctx context.Context) error {
+	return kinesisProducerError(p.batcher.Flush(ctx))
+}
+
+// Close stops accepting records, flushes everything buffered, and waits for the
+// flushes or ctx to be done
+func (p *KinesisProducer) Close(ctx context.Context) error {
+	return p.batcher.Close(ctx)
+}
+
+func kinesisProducerError(err error) error {
+	if errors.Is(err, batcher.ErrClosed) {
+		return ErrKinesisProducerClosed
+	}
+	return err
+}
+
+// flush aggregates batch when enabled and sends it in PutRecords requests
+// within the request limits
+func (p *KinesisProducer) flush(ctx context.Context, batch []kinesisRecord) error {
+	if p.opts.Aggregate {
+		batch = aggregateKinesisRecords(batch)
+	}
+	for len(batch) > 0 {
+		n, size := 0, 0
+		for n < len(batch) && n < kinesisMaxBatchRecords && size+batch[n].size() <= kinesisMaxBatchBytes {
+			size += batch[n].size()
+			n++
+		}
+		if err := p.putWithRetry(ctx, batch[:n]); err != nil {
+			return err
+		}
+		batch = batch[n:]
+	}
+	return nil
+}
+
+// aggregateKinesisRecords packs records, in order, into aggregated records of
+// at most kinesisMaxRecordBytes. A record too large to aggregate is kept as is.
+func aggregateKinesisRecords(records []kinesisRecord) []kinesisRecord {
+	var out, pending []kinesisRecord
+	keys := map[string]uint64{}
+	var keyTable []string
+	size := 0
+	emit := func() {
+		switch len(pending) {
+		case 0:
+		case 1:
+			out = append(out, pending[0])
+		default:
+			out = append(out, kinesisRecord{partitionKey: pending[0].partitionKey, data: encodeKinesisAggregate(keyTable, keys, pending)})
+		}
+		pending, keyTable, size = nil, nil, 0
+		clear(keys)
+	}
+	// the aggregate's own overhead: magic, MD5 digest and partition key
+	overhead := func(first kinesisRecord) int {
+		return len(kinesisAggregationMagic) + md5.Size + len(first.partitionKey)
+	}
+	for _, r := range records {
+		add := aggregatedRecordSize(r, uint64(len(keyTable)))
+		if _, ok := keys[r.partitionKey]; !ok {
+			add += aggregatedKeySize(r.partitionKey)
+		}
+		if len(pending) > 0 && overhead(pending[0])+size+add > kinesisMaxRecordBytes {
+			emit()
+			add = aggregatedRecordSize(r, 0) + aggregatedKeySize(r.partitionKey)
+		}
+		if overhead(r)+add > kinesisMaxRecordBytes {
+			emit()
+			out = append(out, r)
+			continue
+		}
+		if _, ok := keys[r.partitionKey]; !ok {
+			keys[r.partitionKey] = uint64(len(keyTable))
+			keyTable = append(keyTable, r.partitionKey)
+		}
+		pending = append(pending, r)
+		size += add
+	}
+	emit()
+	return out
+}
+
+// aggregatedKeySize is the encoded size of a partition_key_table entry
+func aggregatedKeySize(key string) int {
+	return protowire.SizeTag(1) + protowire.SizeBytes(len(key))
+}
+
+// aggregatedRecordSize is the encoded size of r in the records field, with a
+// partition key index of at most keyIndex
+func aggregatedRecordSize(r kinesisRecord, keyIndex uint64) int {
+	n := protowire.SizeTag(1) + protowire.SizeVarint(keyIndex) + protowire.SizeTag(3) + protowire.SizeBytes(len(r.data))
+	return protowire.SizeTag(3) + protowire.SizeBytes(n)
+}
+
+// encodeKinesisAggregate encodes the KPL AggregatedRecord message:
+// repeated string partition_key_table = 1 and repeated Record records = 3,
+// where Record has uint64 partition_key_index = 1 and bytes data = 3,
+// followed by the message's MD5 digest
+func encodeKinesisAggregate(keyTable []string, keys map[string]uint64, records []kinesisRecord) []byte {
+	var msg []byte
+	for _, key := range keyTable {
+		msg = protowire.AppendTag(msg, 1, protowire.BytesType)
+		msg = protowire.AppendString(msg, key)
+	}
+	var rec []byte
+	for _, r := range records {
+		rec = protowire.AppendTag(rec[:0], 1, protowire.VarintType)
+		rec = protowire.AppendVarint(rec, keys[r.partitionKey])
+		rec = protowire.AppendTag(rec, 3, protowire.BytesType)
+		rec = protowire.AppendBytes(rec, r.data)
+		msg = protowire.AppendTag(msg, 3, protowire.BytesType)
+		msg = protowire.AppendBytes(msg, rec)
+	}
+	sum := md5.Sum(msg)
+	data := make([]byte, 0, len(kinesisAggregationMagic)+len(msg)+md5.Size)
+	data = append(data, kinesisAggregationMagic...)
+	data = append(data, msg...)
+	return append(data, sum[:]...)
+}
+
+// putWithRetry sends batch and retries the records rejected in the response
+func (p *KinesisProducer) putWithRetry(ctx context.Context, batch []kinesisRecord) error {
+	backoff := p.opts.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		entries := make([]types.PutRecordsRequestEntry, len(batch))
+		for i, r := range batch {
+			entries[i] = types.PutRecordsRequestEntry{
+				PartitionKey: aws.String(r.partitionKey),
+				Data:         r.data,
+			}
+		}
+
+		output, err := p.client.PutRecords(ctx, &kinesis.PutRecordsInput{
+			StreamName: aws.String(p.streamName),
+			Records:    entries,
+		})
+		if err != nil {
+			return errs.Errorf("failed to put %d records: %w", len(batch), err)
+		}
+		if aws.ToInt32(output.FailedRecordCount) == 0 {
+			return nil
+		}
+
+		var failed []kinesisRecord
+		var lastCode, lastMessage string
+		for i, result := range output.Records {
+			if result.ErrorCode != nil {
+				failed = append(failed, batch[i])
+				lastCode = aws.ToString(result.ErrorCode)
+				lastMessage = aws.ToString(result.ErrorMessage)
+			}
+		}
+		if attempt >= p.opts.MaxRetries {
+			return errs.Newf(errs.Internal, "failed to put %d records after %d retries: %s: %s", len(failed), attempt, lastCode, lastMessage)
+		}
+
+		batch = failed
+		select {
+		case <-ctx.Done():
+			return errs.Errorf("failed to put %d records: %w", len(failed), ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,193 @@
+package easygo
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// fakeKinesis records PutRecords requests and rejects the records fail picks
+type fakeKinesis struct {
+	mu    sync.Mutex
+	calls [][]types.PutRecordsRequestEntry
+	fail  func(call, i int) bool
+}
+
+func (f *fakeKinesis) PutRecords(_ context.Context, in *kinesis.PutRecordsInput, _ ...func(*kinesis.Options)) (*kinesis.PutRecordsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	call := len(f.calls)
+	f.calls = append(f.calls, in.Records)
+	out := &kinesis.PutRecordsOutput{Records: make([]types.PutRecordsResultEntry, len(in.Records))}
+	failed := int32(0)
+	for i := range in.Records {
+		if f.fail != nil && f.fail(call, i) {
+			out.Records[i] = types.PutRecordsResultEntry{ErrorCode: aws.String("ProvisionedThroughputExceededException"), ErrorMessage: aws.String("slow down")}
+			failed++
+		}
+	}
+	out.FailedRecordCount = aws.Int32(failed)
+	return out, nil
+}
+
+func TestKinesisProducerBatchLimits(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeKinesis{}
+	p := NewKinesisProducer(client, "events", &KinesisProducerOptions{FlushInterval: time.Hour, MaxBufferedRecords: 2000})
+
+	for i := range 1200 {
+		if err := p.Put(ctx, fmt.Sprint(i), []byte("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 999KB records, so five and a bit fill a 5MB request
+	big := bytes.Repeat([]byte("y"), 999*1024)
+	for range 6 {
+		if err := p.Put(ctx, "big", big); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var counts []int
+	for _, call := range client.calls {
+		size := 0
+		for _, e := range call {
+			size += len(aws.ToString(e.PartitionKey)) + len(e.Data)
+		}
+		if len(call) > kinesisMaxBatchRecords || size > kinesisMaxBatchBytes {
+			t.Fatalf("request of %d records and %d bytes exceeds the limits", len(call), size)
+		}
+		counts = append(counts, len(call))
+	}
+	// the last 200 small records and five big ones fill a request to 5MB
+	if fmt.Sprint(counts) != "[500 500 205 1]" {
+		t.Fatalf("got requests of %v records, want [500 500 205 1]", counts)
+	}
+}
+
+func TestKinesisProducerRetriesFailedRecords(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeKinesis{fail: func(call, i int) bool { return call == 0 && (i == 1 || i == 3) }}
+	p := NewKinesisProducer(client, "events", &KinesisProducerOptions{FlushInterval: time.Hour, RetryBackoff: time.Millisecond})
+	for i := range 5 {
+		if err := p.Put(ctx, fmt.Sprint(i), []byte(fmt.Sprint("record ", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close(ctx)
+
+	if len(client.calls) != 2 {
+		t.Fatalf("got %d requests, want the batch and one retry", len(client.calls))
+	}
+	retried := client.calls[1]
+	if len(retried) != 2 || aws.ToString(retried[0].PartitionKey) != "1" || aws.ToString(retried[1].PartitionKey) != "3" {
+		t.Fatalf("retried %v, want only records 1 and 3", retried)
+	}
+
+	// records still rejected after MaxRetries are reported
+	client.fail = func(_, i int) bool { return i == 0 }
+	if err := p.Put(ctx, "a", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Flush(ctx); err == nil {
+		t.Fatal("expected an error after the retries are exhausted")
+	}
+}
+
+func TestKinesisProducerAggregate(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeKinesis{}
+	p := NewKinesisProducer(client, "events", &KinesisProducerOptions{FlushInterval: time.Hour, Aggregate: true})
+	want := map[string]string{}
+	for i := range 300 {
+		key, data := fmt.Sprint("key-", i%3), fmt.Sprint("record ", i)
+		want[data] = key
+		if err := p.Put(ctx, key, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.calls) != 1 || len(client.calls[0]) != 1 {
+		t.Fatalf("got requests %v, want one aggregated record", client.calls)
+	}
+
+	got := decodeKinesisAggregate(t, client.calls[0][0].Data)
+	if len(got) != len(want) {
+		t.Fatalf("got %d records, want %d", len(got), len(want))
+	}
+	for data, key := range want {
+		if got[data] != key {
+			t.Fatalf("record %q has key %q, want %q", data, got[data], key)
+		}
+	}
+}
+
+// decodeKinesisAggregate deaggregates a KPL aggregated record into data to
+// partition key, the way the KCL does
+func decodeKinesisAggregate(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	if !bytes.HasPrefix(data, kinesisAggregationMagic) {
+		t.Fatal("missing aggregation magic")
+	}
+	msg := data[len(kinesisAggregationMagic) : len(data)-md5.Size]
+	if sum := md5.Sum(msg); !bytes.Equal(sum[:], data[len(data)-md5.Size:]) {
+		t.Fatal("digest mismatch")
+	}
+	var keys []string
+	type record struct {
+		key  uint64
+		data string
+	}
+	var records []record
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		msg = msg[n:]
+		if typ != protowire.BytesType {
+			t.Fatalf("field %d has wire type %d", num, typ)
+		}
+		v, n := protowire.ConsumeBytes(msg)
+		msg = msg[n:]
+		switch num {
+		case 1:
+			keys = append(keys, string(v))
+		case 3:
+			var r record
+			for len(v) > 0 {
+				num, _, n := protowire.ConsumeTag(v)
+				v = v[n:]
+				switch num {
+				case 1:
+					r.key, n = protowire.ConsumeVarint(v)
+				case 3:
+					var b []byte
+					b, n = protowire.ConsumeBytes(v)
+					r.data = string(b)
+				}
+				v = v[n:]
+			}
+			records = append(records, r)
+		}
+	}
+	out := map[string]string{}
+	for _, r := range records {
+		out[r.data] = keys[r.key]
+	}
+	return out
+}
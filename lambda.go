@@ -0,0 +1,93 @@
+package easygo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// LambdaFunctionError is returned when the invoked function itself failed;
+// the fields are decoded from the standard Lambda error payload
+type LambdaFunctionError struct {
+	// FunctionError is the value of the X-Amz-Function-Error header (e.g. "Unhandled")
+	FunctionError string   `json:"-"`
+	ErrorMessage  string   `json:"errorMessage"`
+	ErrorType     string   `json:"errorType"`
+	StackTrace    []string `json:"stackTrace"`
+	// Payload is the raw error payload returned by the function
+	Payload []byte `json:"-"`
+}
+
+func (e *LambdaFunctionError) Error() string {
+	if e.ErrorType != "" {
+		return fmt.Sprintf("lambda function error (%s): %s: %s", e.FunctionError, e.ErrorType, e.ErrorMessage)
+	}
+	return fmt.Sprintf("lambda function error (%s): %s", e.FunctionError, e.ErrorMessage)
+}
+
+// InvokeLambdaJSON synchronously invokes functionName with req marshaled as the
+// payload and unmarshals the response payload into Resp. A failure inside the
+// function is returned as a *LambdaFunctionError.
+func InvokeLambdaJSON[Req, Resp any](ctx context.Context, c *EGAwsClient, functionName string, req Req) (Resp, error) {
+	var resp Resp
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return resp, errs.Errorf("failed to marshal lambda request: %w", err)
+	}
+
+	output, err := c.lambdaClient.Invoke(ctx, &lambda.InvokeInput{
+		FunctionName:   aws.String(functionName),
+		InvocationType: types.InvocationTypeRequestResponse,
+		Payload:        payload,
+	})
+	if err != nil {
+		return resp, errs.Errorf("failed to invoke lambda: %w", err)
+	}
+
+	if output.FunctionError != nil {
+		fnErr := &LambdaFunctionError{
+			FunctionError: aws.ToString(output.FunctionError),
+			Payload:       output.Payload,
+		}
+		if err := json.Unmarshal(output.Payload, fnErr); err != nil {
+			fnErr.ErrorMessage = string(output.Payload)
+		}
+		return resp, fnErr
+	}
+
+	if len(output.Payload) == 0 {
+		return resp, nil
+	}
+	if err := json.Unmarshal(output.Payload, &resp); err != nil {
+		return resp, errs.Errorf("failed to unmarshal lambda response: %w", err)
+	}
+
+	return resp, nil
+}
+
+// InvokeLambdaJSONAsync invokes functionName asynchronously (Event invocation
+// type) with req marshaled as the payload. Lambda only acknowledges that the
+// event was queued; function errors are not reported to the caller.
+func InvokeLambdaJSONAsync[Req any](ctx context.Context, c *EGAwsClient, functionName string, req Req) error {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return errs.Errorf("failed to marshal lambda request: %w", err)
+	}
+
+	_, err = c.lambdaClient.Invoke(ctx, &lambda.InvokeInput{
+		FunctionName:   aws.String(functionName),
+		InvocationType: types.InvocationTypeEvent,
+		Payload:        payload,
+	})
+	if err != nil {
+		return errs.Errorf("failed to invoke lambda: %w", err)
+	}
+
+	return nil
+}
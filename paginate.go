@@ -0,0 +1,67 @@
+package easygo
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// Paginator matches the paginators generated by the AWS SDK, e.g. secretsmanager.ListSecretsPaginator
+type Paginator[Page, Options any] interface {
+	HasMorePages() bool
+	NextPage(ctx context.Context, optFns ...func(Options)) (Page, error)
+}
+
+// CollectPages reads every page from paginator and returns the items selected by extract
+//
+//	secrets, err := easygo.CollectPages(ctx,
+//		secretsmanager.NewListSecretsPaginator(client, &secretsmanager.ListSecretsInput{}),
+//		func(page *secretsmanager.ListSecretsOutput) []types.SecretListEntry { return page.SecretList },
+//	)
+func CollectPages[Page, Options, T any](ctx context.Context, paginator Paginator[Page, Options], extract func(Page) []T) ([]T, error) {
+	var items []T
+	err := EachPage(ctx, paginator, func(page Page) error {
+		items = append(items, extract(page)...)
+		return nil
+	})
+	return items, err
+}
+
+// EachPage calls fn for every page from paginator, stopping at the first error
+func EachPage[Page, Options any](ctx context.Context, paginator Paginator[Page, Options], fn func(Page) error) error {
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return errs.Errorf("failed to get next page: %w", err)
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListAllSecrets returns metadata for every secret visible to the caller
+func (c *EGAwsClient) ListAllSecrets(ctx context.Context) ([]smtypes.SecretListEntry, error) {
+	paginator := secretsmanager.NewListSecretsPaginator(c.secretsClient, &secretsmanager.ListSecretsInput{})
+	return CollectPages(ctx, paginator, func(page *secretsmanager.ListSecretsOutput) []smtypes.SecretListEntry {
+		return page.SecretList
+	})
+}
+
+// GetAllParametersByPath returns every SSM parameter under path, recursively, with SecureString values decrypted
+func (c *EGAwsClient) GetAllParametersByPath(ctx context.Context, path string) ([]ssmtypes.Parameter, error) {
+	paginator := ssm.NewGetParametersByPathPaginator(c.ssmClient, &ssm.GetParametersByPathInput{
+		Path:           aws.String(path),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+	})
+	return CollectPages(ctx, paginator, func(page *ssm.GetParametersByPathOutput) []ssmtypes.Parameter {
+		return page.Parameters
+	})
+}
@@ -0,0 +1,50 @@
+package easygo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type fakeOptions struct{}
+
+type fakePaginator struct {
+	pages [][]int
+	err   error
+}
+
+func (p *fakePaginator) HasMorePages() bool {
+	return len(p.pages) > 0 || p.err != nil
+}
+
+func (p *fakePaginator) NextPage(ctx context.Context, optFns ...func(*fakeOptions)) ([]int, error) {
+	if len(p.pages) == 0 {
+		err := p.err
+		p.err = nil
+		return nil, err
+	}
+	page := p.pages[0]
+	p.pages = p.pages[1:]
+	return page, nil
+}
+
+func TestCollectPages(t *testing.T) {
+	p := &fakePaginator{pages: [][]int{{1, 2}, {}, {3}}}
+	items, err := CollectPages(context.Background(), p, func(page []int) []int { return page })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(items, want) {
+		t.Fatalf("got %v, want %v", items, want)
+	}
+}
+
+func TestCollectPagesError(t *testing.T) {
+	pageErr := errors.New("throttled")
+	p := &fakePaginator{pages: [][]int{{1}}, err: pageErr}
+	_, err := CollectPages(context.Background(), p, func(page []int) []int { return page })
+	if !errors.Is(err, pageErr) {
+		t.Fatalf("got %v, want %v", err, pageErr)
+	}
+}
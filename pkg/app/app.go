@@ -0,0 +1,174 @@
+// Package app runs a process's long-lived components, such as HTTP and gRPC
+// servers, consumers and schedulers, under one lifecycle: they start
+// together, a failure in one stops them all, and SIGINT or SIGTERM stops them
+// in reverse registration order within a shutdown timeout.
+package app
+
+import (
+	"context"
+	"errors"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"golang.org/x/sync/errgroup"
+)
+
+// Component is a long-lived part of the application. Start may block while
+// the component runs, returning nil once Stop has stopped it; a Start error
+// stops the application. Stop must be safe to call when Start failed or has
+// not returned yet.
+type Component interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+type NewAppArgs struct {
+	// Logger logs component start, stop and failures (default: logging.Noop)
+	Logger logging.Logger
+	// ShutdownTimeout bounds stopping every component (default: 30s)
+	ShutdownTimeout time.Duration
+	// StopTimeout bounds each component's Stop within ShutdownTimeout
+	// (default: the remaining shutdown time)
+	StopTimeout time.Duration
+}
+
+type namedComponent struct {
+	name string
+	Component
+}
+
+// App runs components until a stop signal or a component failure
+type App struct {
+	logger          logging.Logger
+	shutdownTimeout time.Duration
+	stopTimeout     time.Duration
+
+	mu         sync.Mutex
+	components []namedComponent
+	running    bool
+}
+
+// New creates an App; args may be nil
+func New(args *NewAppArgs) *App {
+	if args == nil {
+		args = &NewAppArgs{}
+	}
+	a := &App{
+		logger:          args.Logger,
+		shutdownTimeout: args.ShutdownTimeout,
+		stopTimeout:     args.StopTimeout,
+	}
+	if a.logger == nil {
+		a.logger = logging.Noop()
+	}
+	if a.shutdownTimeout <= 0 {
+		a.shutdownTimeout = 30 * time.Second
+	}
+	return a
+}
+
+// Add registers a component. Components are stopped in reverse order, so add
+// dependencies (e.g. a database pool) before the components that use them.
+func (a *App) Add(name string, c Component) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.running {
+		panic("app: Add called after Run")
+	}
+	a.components = append(a.components, namedComponent{name: name, Component: c})
+}
+
+// Run starts every component and waits until ctx is cancelled, the process
+// receives SIGINT or SIGTERM, or a component's Start fails. It then stops
+// the components in reverse order and returns the start failure and any stop
+// errors, or nil after a clean shutdown.
+//
+// Components start with a context that stays valid until they are stopped,
+// so in-flight work is not cancelled before its component's Stop runs.
+func (a *App) Run(ctx context.Context) error {
+	a.mu.Lock()
+	if a.running {
+		a.mu.Unlock()
+		return errs.New(errs.Internal, "app is already running")
+	}
+	a.running = true
+	components := append([]namedComponent{}, a.components...)
+	a.mu.Unlock()
+
+	sigCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	startCtx, cancelStart := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelStart()
+
+	failed := make(chan error, len(components))
+	var g errgroup.Group
+	for _, c := range components {
+		a.logger.WithField("component", c.name).Info("starting component")
+		g.Go(func() error {
+			if err := c.Start(startCtx); err != nil {
+				err = errs.Errorf("%s failed: %w", c.name, err)
+				failed <- err
+				return err
+			}
+			return nil
+		})
+	}
+
+	var startErr error
+	select {
+	case <-sigCtx.Done():
+		a.logger.Info("stopping components")
+	case startErr = <-failed:
+		a.logger.WithError(startErr).Error("component failed, stopping components")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout)
+	defer cancel()
+	failures := []error{startErr}
+	for i := len(components) - 1; i >= 0; i-- {
+		if err := a.stopComponent(shutdownCtx, components[i]); err != nil {
+			failures = append(failures, err)
+		}
+	}
+	cancelStart()
+
+	done := make(chan struct{})
+	go func() {
+		_ = g.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-shutdownCtx.Done():
+		failures = append(failures, errs.New(errs.Internal, "components did not return from Start before the shutdown timeout"))
+	}
+
+	// components that failed while stopping
+	for len(failed) > 0 {
+		if err := <-failed; err != startErr {
+			failures = append(failures, err)
+		}
+	}
+	return errors.Join(failures...)
+}
+
+func (a *App) stopComponent(ctx context.Context, c namedComponent) error {
+	if a.stopTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.stopTimeout)
+		defer cancel()
+	}
+	start := time.Now()
+	logger := a.logger.WithField("component", c.name)
+	if err := c.Stop(ctx); err != nil {
+		logger.WithError(err).Error("failed to stop component")
+		return errs.Errorf("failed to stop %s: %w", c.name, err)
+	}
+	logger.WithField("elapsed", time.Since(start).String()).Info("stopped component")
+	return nil
+}
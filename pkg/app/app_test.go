@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) component(name string, startErr error) Component {
+	return Runner(func(ctx context.Context) error {
+		if startErr != nil {
+			return startErr
+		}
+		<-ctx.Done()
+		r.add("stop " + name)
+		return nil
+	})
+}
+
+func TestShutdownOrder(t *testing.T) {
+	rec := &recorder{}
+	a := New(nil)
+	a.Add("db", rec.component("db", nil))
+	a.Add("http", rec.component("http", nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Run(ctx) }()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"stop http", "stop db"}; !slices.Equal(rec.events, want) {
+		t.Fatalf("expected %v, got %v", want, rec.events)
+	}
+}
+
+func TestStartFailureStopsOthers(t *testing.T) {
+	rec := &recorder{}
+	boom := errors.New("bind: address in use")
+	a := New(&NewAppArgs{ShutdownTimeout: time.Second})
+	a.Add("worker", rec.component("worker", nil))
+	a.Add("http", rec.component("http", boom))
+
+	err := a.Run(context.Background())
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected start failure, got %v", err)
+	}
+	if !slices.Equal(rec.events, []string{"stop worker"}) {
+		t.Fatalf("expected worker to be stopped, got %v", rec.events)
+	}
+}
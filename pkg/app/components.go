@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bdlilley/easygo/pkg/grpcserver"
+	"github.com/bdlilley/easygo/pkg/httpserver"
+	"github.com/bdlilley/easygo/pkg/scheduler"
+)
+
+// funcComponent adapts a pair of functions
+type funcComponent struct {
+	start func(ctx context.Context) error
+	stop  func(ctx context.Context) error
+}
+
+func (f *funcComponent) Start(ctx context.Context) error {
+	if f.start == nil {
+		return nil
+	}
+	return f.start(ctx)
+}
+
+func (f *funcComponent) Stop(ctx context.Context) error {
+	if f.stop == nil {
+		return nil
+	}
+	return f.stop(ctx)
+}
+
+// Func adapts start and stop functions; either may be nil
+func Func(start, stop func(ctx context.Context) error) Component {
+	return &funcComponent{start: start, stop: stop}
+}
+
+// Runner adapts a function that runs until its context is cancelled, such
+// as kafka.Consumer.Run. Stop cancels the context and waits for fn to return.
+func Runner(fn func(ctx context.Context) error) Component {
+	return &runner{fn: fn, done: make(chan struct{})}
+}
+
+type runner struct {
+	fn      func(ctx context.Context) error
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	started bool
+	done    chan struct{}
+}
+
+func (r *runner) Start(ctx context.Context) error {
+	r.mu.Lock()
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.started = true
+	r.mu.Unlock()
+	defer close(r.done)
+	err := r.fn(ctx)
+	if ctx.Err() != nil {
+		// stopped by Stop
+		return nil
+	}
+	return err
+}
+
+func (r *runner) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	cancel, started := r.cancel, r.started
+	r.mu.Unlock()
+	if !started {
+		return nil
+	}
+	cancel()
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// HTTPServer adapts an httpserver server: Start serves and runs its OnStart
+// hooks, Stop drains it and runs its OnShutdown hooks
+func HTTPServer(s *httpserver.EasyGoHTTPServer) Component {
+	return Func(s.Start, s.Shutdown)
+}
+
+// GRPCServer adapts a grpcserver server; Stop shuts it down gracefully
+func GRPCServer(s *grpcserver.EasyGoGRPCServer) Component {
+	return Func(func(ctx context.Context) error {
+		return s.ListenAndServe()
+	}, s.Shutdown)
+}
+
+// Scheduler adapts a scheduler; Stop waits for running jobs
+func Scheduler(s *scheduler.Scheduler) Component {
+	return Func(func(ctx context.Context) error {
+		s.Start(ctx)
+		return nil
+	}, s.Stop)
+}
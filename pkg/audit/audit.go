@@ -0,0 +1,156 @@
+// Package audit records who did what to which resource, with a fixed schema
+// and sinks kept separate from application logs.
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+)
+
+// Outcome is the result of an audited action
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+	// OutcomeDenied is an action rejected by authentication or authorization
+	OutcomeDenied Outcome = "denied"
+)
+
+// Event is one audit record. Actor, Action, Resource and Outcome are always set.
+type Event struct {
+	ID       string         `json:"id"`
+	Time     time.Time      `json:"time"`
+	Actor    string         `json:"actor"`
+	Action   string         `json:"action"`
+	Resource string         `json:"resource"`
+	Outcome  Outcome        `json:"outcome"`
+	TraceID  string         `json:"traceId,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// Validate reports missing required fields
+func (e *Event) Validate() error {
+	var missing []error
+	if e.Actor == "" {
+		missing = append(missing, errs.New(errs.Internal, "actor is required"))
+	}
+	if e.Action == "" {
+		missing = append(missing, errs.New(errs.Internal, "action is required"))
+	}
+	if e.Resource == "" {
+		missing = append(missing, errs.New(errs.Internal, "resource is required"))
+	}
+	switch e.Outcome {
+	case OutcomeSuccess, OutcomeFailure, OutcomeDenied:
+	default:
+		missing = append(missing, errs.Newf(errs.Internal, "invalid outcome %q", e.Outcome))
+	}
+	return errors.Join(missing...)
+}
+
+// Sink stores audit events
+type Sink interface {
+	Write(ctx context.Context, e *Event) error
+}
+
+type NewLoggerArgs struct {
+	// Sinks receive every event (required)
+	Sinks []Sink
+	// ErrorLogger logs sink failures (default: logging.Noop)
+	ErrorLogger logging.Logger
+}
+
+// Logger writes audit events to its sinks
+type Logger struct {
+	sinks  []Sink
+	errLog logging.Logger
+	now    func() time.Time
+}
+
+// NewLogger creates an audit Logger
+func NewLogger(args *NewLoggerArgs) (*Logger, error) {
+	if args == nil || len(args.Sinks) == 0 {
+		return nil, errs.New(errs.Internal, "at least one sink is required")
+	}
+	l := &Logger{sinks: args.Sinks, errLog: args.ErrorLogger, now: time.Now}
+	if l.errLog == nil {
+		l.errLog = logging.Noop()
+	}
+	return l, nil
+}
+
+// Log records an event. The trace ID is taken from ctx. An error is returned
+// when a field is missing or any sink fails; the other sinks are still written.
+func (l *Logger) Log(ctx context.Context, actor, action, resource string, outcome Outcome, metadata map[string]any) error {
+	e := &Event{
+		ID:       newEventID(),
+		Time:     l.now().UTC(),
+		Actor:    actor,
+		Action:   action,
+		Resource: resource,
+		Outcome:  outcome,
+		Metadata: metadata,
+	}
+	if id, ok := logging.TraceFields(ctx)[logging.TraceIDField].(string); ok {
+		e.TraceID = id
+	}
+	return l.Write(ctx, e)
+}
+
+// Write validates e and writes it to every sink
+func (l *Logger) Write(ctx context.Context, e *Event) error {
+	if err := e.Validate(); err != nil {
+		return errs.Errorf("invalid audit event: %w", err)
+	}
+	var errs []error
+	for _, s := range l.sinks {
+		if err := s.Write(ctx, e); err != nil {
+			l.errLog.WithError(err).WithField("action", e.Action).Error("failed to write audit event")
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultLogger *Logger
+)
+
+// SetDefault sets the Logger used by the package-level Log
+func SetDefault(l *Logger) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultLogger = l
+}
+
+// Default returns the Logger set by SetDefault, or nil
+func Default() *Logger {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultLogger
+}
+
+// Log records an event with the default Logger. It fails when SetDefault has
+// not been called, so audit events are never dropped silently.
+func Log(ctx context.Context, actor, action, resource string, outcome Outcome, metadata map[string]any) error {
+	l := Default()
+	if l == nil {
+		return errs.New(errs.Internal, "audit: no default logger; call audit.SetDefault")
+	}
+	return l.Log(ctx, actor, action, resource, outcome, metadata)
+}
@@ -0,0 +1,32 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestLoggerLog(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLogger(&NewLoggerArgs{Sinks: []Sink{NewWriterSink(&buf)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := l.Log(ctx, "alice", "delete", "orders/1", OutcomeSuccess, map[string]any{"reason": "dup"}); err != nil {
+		t.Fatal(err)
+	}
+	e := Event{}
+	if err := json.Unmarshal(buf.Bytes(), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Actor != "alice" || e.Action != "delete" || e.Outcome != OutcomeSuccess || e.ID == "" || e.Time.IsZero() {
+		t.Fatalf("event = %+v", e)
+	}
+
+	if err := l.Log(ctx, "", "delete", "orders/1", "maybe", nil); err == nil {
+		t.Fatal("expected validation error")
+	}
+}
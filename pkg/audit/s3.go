@@ -0,0 +1,190 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+)
+
+// S3PutObjectAPI is the subset of the S3 client used by S3Sink
+type S3PutObjectAPI interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+type NewS3SinkArgs struct {
+	// Config is the AWS config used to build the S3 client, typically
+	// EGAwsClient.GetConfig()
+	Config aws.Config
+	// Client overrides the S3 client built from Config
+	Client S3PutObjectAPI
+	Bucket string
+	// Prefix is prepended to object keys, which are
+	// <prefix>/YYYY/MM/DD/<timestamp>-<id>.jsonl
+	Prefix string
+	// FlushInterval is the maximum time events wait before being uploaded (default: 1m)
+	FlushInterval time.Duration
+	// MaxEvents is the number of buffered events that triggers an upload (default: 1000)
+	MaxEvents int
+	// ErrorHandler receives errors from background uploads (default: log them to Logger)
+	ErrorHandler func(error)
+	// Logger logs failed uploads at error without an ErrorHandler (default: logging.Noop)
+	Logger logging.Logger
+}
+
+// S3Sink batches events into JSON lines objects in S3. Failed uploads are
+// kept and retried with the next batch. Call Close to upload buffered events.
+type S3Sink struct {
+	client        S3PutObjectAPI
+	bucket        string
+	prefix        string
+	maxEvents     int
+	errorHandler  func(error)
+	flushInterval time.Duration
+
+	mu     sync.Mutex
+	buffer []*Event
+
+	// uploadMu serializes uploads so retried events keep their order
+	uploadMu  sync.Mutex
+	wake      chan struct{}
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// NewS3Sink creates an S3Sink and starts its background uploader
+func NewS3Sink(args *NewS3SinkArgs) (*S3Sink, error) {
+	if args == nil || args.Bucket == "" {
+		return nil, errs.New(errs.Internal, "Bucket is required")
+	}
+	s := &S3Sink{
+		client:        args.Client,
+		bucket:        args.Bucket,
+		prefix:        args.Prefix,
+		maxEvents:     args.MaxEvents,
+		errorHandler:  args.ErrorHandler,
+		flushInterval: args.FlushInterval,
+		wake:          make(chan struct{}, 1),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	if s.client == nil {
+		s.client = s3.NewFromConfig(args.Config)
+	}
+	if s.maxEvents <= 0 {
+		s.maxEvents = 1000
+	}
+	if s.flushInterval <= 0 {
+		s.flushInterval = time.Minute
+	}
+	if s.errorHandler == nil {
+		logger := args.Logger
+		if logger == nil {
+			logger = logging.Noop()
+		}
+		s.errorHandler = func(err error) {
+			logger.WithError(err).Error("audit S3 upload failed")
+		}
+	}
+	go s.run()
+	return s, nil
+}
+
+// Write implements Sink by buffering e for the next upload
+func (s *S3Sink) Write(_ context.Context, e *Event) error {
+	s.mu.Lock()
+	s.buffer = append(s.buffer, e)
+	full := len(s.buffer) >= s.maxEvents
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Flush uploads buffered events
+func (s *S3Sink) Flush(ctx context.Context) error {
+	s.uploadMu.Lock()
+	defer s.uploadMu.Unlock()
+
+	s.mu.Lock()
+	batch := s.buffer
+	s.buffer = nil
+	s.mu.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	if err := s.upload(ctx, batch); err != nil {
+		s.mu.Lock()
+		s.buffer = append(batch, s.buffer...)
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Close stops the background uploader and uploads buffered events
+func (s *S3Sink) Close(ctx context.Context) error {
+	s.closeOnce.Do(func() { close(s.done) })
+	select {
+	case <-s.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return s.Flush(ctx)
+}
+
+func (s *S3Sink) run() {
+	defer close(s.stopped)
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+		if err := s.Flush(context.Background()); err != nil {
+			s.errorHandler(err)
+		}
+	}
+}
+
+func (s *S3Sink) upload(ctx context.Context, batch []*Event) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, e := range batch {
+		if err := enc.Encode(e); err != nil {
+			return errs.Errorf("failed to marshal audit event: %w", err)
+		}
+	}
+
+	first := batch[0]
+	key := path.Join(s.prefix, first.Time.Format("2006/01/02"),
+		fmt.Sprintf("%s-%s.jsonl", first.Time.Format("20060102T150405.000Z"), first.ID))
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body.Bytes()),
+		ContentType: aws.String("application/x-ndjson"),
+	})
+	if err != nil {
+		return errs.Errorf("failed to upload %d audit events to s3://%s/%s: %w", len(batch), s.bucket, key, err)
+	}
+	return nil
+}
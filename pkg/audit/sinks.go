@@ -0,0 +1,38 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// WriterSink writes events as JSON lines to an io.Writer, such as a file, a
+// logging.RotatingFile or a logging.CloudWatchHook
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink creates a WriterSink for w
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// Write implements Sink
+func (s *WriterSink) Write(_ context.Context, e *Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errs.Errorf("failed to marshal audit event: %w", err)
+	}
+	b = append(b, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(b); err != nil {
+		return errs.Errorf("failed to write audit event: %w", err)
+	}
+	return nil
+}
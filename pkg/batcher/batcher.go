@@ -0,0 +1,339 @@
+// Package batcher accumulates items into batches that are flushed when they
+// reach a maximum number of items or bytes, or a maximum age. It is the
+// building block for batch publishers such as Kinesis PutRecords, CloudWatch
+// Logs PutLogEvents and SQS SendMessageBatch.
+package batcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrClosed is returned by Add, TryAdd and Flush after Close has been called
+var ErrClosed = errs.New(errs.Unavailable, "batcher is closed")
+
+// ErrFull is returned by TryAdd when the queue is full
+var ErrFull = errs.New(errs.Unavailable, "batcher queue is full")
+
+// ErrItemTooLarge is returned by Add for items larger than MaxBytes
+var ErrItemTooLarge = errs.New(errs.InvalidArgument, "item exceeds the batch size limit")
+
+// FlushFunc writes a batch. Batches are never empty, and the slice is not
+// reused after FlushFunc returns.
+type FlushFunc[T any] func(ctx context.Context, batch []T) error
+
+type Options[T any] struct {
+	// MaxItems flushes a batch when it has this many items (default: 100)
+	MaxItems int
+	// MaxBytes flushes a batch before an item would make it larger (default:
+	// no limit); Size must be set with it
+	MaxBytes int
+	// Size returns an item's size in bytes for MaxBytes
+	Size func(item T) int
+	// MaxAge flushes a batch this long after its first item was added (default: 1s)
+	MaxAge time.Duration
+	// QueueSize bounds the items waiting to be batched; Add blocks while it
+	// is full (default: MaxItems)
+	QueueSize int
+	// Concurrency is the number of flushes run at once; while all are busy new
+	// batches wait, the queue fills and Add blocks (default: 1)
+	Concurrency int
+	// FlushTimeout bounds each flush's context (default: none)
+	FlushTimeout time.Duration
+	// ErrorHandler receives failed flushes (default: log them to Logger)
+	ErrorHandler func(batch []T, err error)
+	// Logger logs failed flushes at error without an ErrorHandler; it is a
+	// logging.Logger, which this package cannot import as logging batches
+	// with it (default: none, background failures are dropped)
+	Logger logrus.FieldLogger
+}
+
+// Batcher batches items for a FlushFunc. It is safe for concurrent use.
+type Batcher[T any] struct {
+	flush FlushFunc[T]
+	opts  Options[T]
+
+	// mu guards closed and is held only briefly, never while waiting on the
+	// queue, so Close is never stuck behind a blocked Add
+	mu     sync.RWMutex
+	closed bool
+	// adders counts Add and TryAdd calls past the closed check; the loop waits
+	// for them after closing so no accepted item is left in the queue
+	adders   sync.WaitGroup
+	closing  chan struct{}
+	items    chan T
+	flushReq chan chan error
+	stopped  chan struct{}
+
+	sem      chan struct{}
+	inflight sync.WaitGroup
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// New creates a Batcher and starts its batching loop. Call Close to flush the
+// remaining items and stop it.
+func New[T any](flush FlushFunc[T], opts *Options[T]) *Batcher[T] {
+	o := Options[T]{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.MaxItems <= 0 {
+		o.MaxItems = 100
+	}
+	if o.Size == nil {
+		o.MaxBytes = 0
+	}
+	if o.MaxAge <= 0 {
+		o.MaxAge = time.Second
+	}
+	if o.QueueSize <= 0 {
+		o.QueueSize = o.MaxItems
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 1
+	}
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = func(batch []T, err error) {
+			if o.Logger != nil {
+				o.Logger.WithError(err).WithField("items", len(batch)).Error("batcher flush failed")
+			}
+		}
+	}
+
+	b := &Batcher[T]{
+		flush:    flush,
+		opts:     o,
+		closing:  make(chan struct{}),
+		items:    make(chan T, o.QueueSize),
+		flushReq: make(chan chan error),
+		stopped:  make(chan struct{}),
+		sem:      make(chan struct{}, o.Concurrency),
+	}
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+	go b.run()
+	return b
+}
+
+// Add queues item, blocking while the queue is full until ctx is done
+func (b *Batcher[T]) Add(ctx context.Context, item T) error {
+	if err := b.check(item); err != nil {
+		return err
+	}
+	if !b.enter() {
+		return ErrClosed
+	}
+	defer b.adders.Done()
+	select {
+	case b.items <- item:
+		return nil
+	case <-b.closing:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TryAdd queues item without blocking, returning ErrFull when the queue is full
+func (b *Batcher[T]) TryAdd(item T) error {
+	if err := b.check(item); err != nil {
+		return err
+	}
+	if !b.enter() {
+		return ErrClosed
+	}
+	defer b.adders.Done()
+	select {
+	case b.items <- item:
+		return nil
+	default:
+		return ErrFull
+	}
+}
+
+// enter registers an adder, reporting false once the batcher is closed
+func (b *Batcher[T]) enter() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return false
+	}
+	b.adders.Add(1)
+	return true
+}
+
+func (b *Batcher[T]) check(item T) error {
+	if b.opts.MaxBytes > 0 {
+		if n := b.opts.Size(item); n > b.opts.MaxBytes {
+			return errs.Errorf("item is %d bytes, limit is %d: %w", n, b.opts.MaxBytes, ErrItemTooLarge)
+		}
+	}
+	return nil
+}
+
+// Flush flushes the items queued so far and returns the errors of those
+// flushes
+func (b *Batcher[T]) Flush(ctx context.Context) error {
+	reply := make(chan error, 1)
+	select {
+	case b.flushReq <- reply:
+	case <-b.stopped:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting items, flushes everything queued and waits for the
+// flushes. When ctx is done first, running flushes are canceled and Close
+// returns ctx's error.
+func (b *Batcher[T]) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.closing)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.stopped:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	}
+}
+
+// flushGroup collects the errors of the flushes a Flush call waits for
+type flushGroup struct {
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	errs []error
+}
+
+func (g *flushGroup) wait() error {
+	g.wg.Wait()
+	return errors.Join(g.errs...)
+}
+
+func (b *Batcher[T]) run() {
+	defer close(b.stopped)
+	defer b.cancel()
+
+	var batch []T
+	size := 0
+	timer := time.NewTimer(b.opts.MaxAge)
+	timer.Stop()
+	defer timer.Stop()
+
+	dispatch := func(g *flushGroup) {
+		if len(batch) == 0 {
+			return
+		}
+		items := batch
+		batch, size = nil, 0
+		timer.Stop()
+
+		// waiting for a free slot stops the loop from taking items, which
+		// blocks Add once the queue fills
+		b.sem <- struct{}{}
+		b.inflight.Add(1)
+		if g != nil {
+			g.wg.Add(1)
+		}
+		go func() {
+			defer b.inflight.Done()
+			err := b.flushBatch(items)
+			if g != nil {
+				if err != nil {
+					g.mu.Lock()
+					g.errs = append(g.errs, err)
+					g.mu.Unlock()
+				}
+				g.wg.Done()
+			}
+			<-b.sem
+		}()
+	}
+	add := func(item T, g *flushGroup) {
+		n := 0
+		if b.opts.MaxBytes > 0 {
+			n = b.opts.Size(item)
+			if len(batch) > 0 && size+n > b.opts.MaxBytes {
+				dispatch(g)
+			}
+		}
+		batch = append(batch, item)
+		size += n
+		if len(batch) == 1 {
+			timer.Reset(b.opts.MaxAge)
+		}
+		if len(batch) >= b.opts.MaxItems {
+			dispatch(g)
+		}
+	}
+
+	for {
+		select {
+		case item := <-b.items:
+			add(item, nil)
+		case <-b.closing:
+			// keep taking items until adders blocked on a full queue have
+			// returned, then flush what is left
+			added := make(chan struct{})
+			go func() {
+				b.adders.Wait()
+				close(added)
+			}()
+			for done := false; !done; {
+				select {
+				case item := <-b.items:
+					add(item, nil)
+				case <-added:
+					done = true
+				}
+			}
+			for n := len(b.items); n > 0; n-- {
+				add(<-b.items, nil)
+			}
+			dispatch(nil)
+			b.inflight.Wait()
+			return
+		case <-timer.C:
+			dispatch(nil)
+		case reply := <-b.flushReq:
+			g := &flushGroup{}
+			for n := len(b.items); n > 0; n-- {
+				add(<-b.items, g)
+			}
+			dispatch(g)
+			go func() { reply <- g.wait() }()
+		}
+	}
+}
+
+// flushBatch calls the FlushFunc, reporting errors to the ErrorHandler
+func (b *Batcher[T]) flushBatch(items []T) error {
+	ctx := b.ctx
+	if b.opts.FlushTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.opts.FlushTimeout)
+		defer cancel()
+	}
+	err := b.flush(ctx, items)
+	if err != nil {
+		b.opts.ErrorHandler(items, err)
+	}
+	return err
+}
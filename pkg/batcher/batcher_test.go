@@ -0,0 +1,170 @@
+package batcher
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder collects flushed batches
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (r *recorder) flush(_ context.Context, batch []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, batch)
+	return nil
+}
+
+func (r *recorder) sizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sizes []int
+	for _, b := range r.batches {
+		sizes = append(sizes, len(b))
+	}
+	return sizes
+}
+
+func TestLimits(t *testing.T) {
+	ctx := context.Background()
+	rec := &recorder{}
+	b := New(rec.flush, &Options[string]{MaxItems: 3, MaxBytes: 10, Size: func(s string) int { return len(s) }, MaxAge: time.Hour})
+	// two batches by count, then one by bytes
+	for _, s := range []string{"a", "b", "c", "d", "e", "f", "gggg", "hhhh", "iiii"} {
+		if err := b.Add(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Add(ctx, "way too large"); !errors.Is(err, ErrItemTooLarge) {
+		t.Fatalf("got %v for an oversized item", err)
+	}
+	if err := b.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.sizes(); !slices.Equal(got, []int{3, 3, 2, 1}) {
+		t.Fatalf("got batch sizes %v", got)
+	}
+
+	// the final partial batch is flushed on Close
+	_ = b.Add(ctx, "j")
+	if err := b.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.sizes(); !slices.Equal(got, []int{3, 3, 2, 1, 1}) {
+		t.Fatalf("got batch sizes %v after Close", got)
+	}
+	if err := b.Add(ctx, "k"); err != ErrClosed {
+		t.Fatalf("got %v after Close", err)
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	flushed := make(chan []int, 1)
+	b := New(func(_ context.Context, batch []int) error {
+		flushed <- batch
+		return nil
+	}, &Options[int]{MaxAge: 10 * time.Millisecond})
+	defer b.Close(context.Background())
+
+	_ = b.Add(context.Background(), 1)
+	_ = b.Add(context.Background(), 2)
+	select {
+	case got := <-flushed:
+		if !slices.Equal(got, []int{1, 2}) {
+			t.Fatalf("got %v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("batch was not flushed after MaxAge")
+	}
+}
+
+func TestBackpressure(t *testing.T) {
+	release := make(chan struct{})
+	var failed [][]int
+	b := New(func(ctx context.Context, batch []int) error {
+		<-release
+		return errors.New("unavailable")
+	}, &Options[int]{MaxItems: 1, QueueSize: 2, ErrorHandler: func(batch []int, _ error) { failed = append(failed, batch) }})
+
+	// one item is being flushed, one waits for the flush slot, two fill the queue
+	added := 0
+	deadline := time.Now().Add(time.Second)
+	for added < 4 && time.Now().Before(deadline) {
+		if b.TryAdd(added) == nil {
+			added++
+		}
+	}
+	if added != 4 {
+		t.Fatalf("added %d items", added)
+	}
+	// wait for the loop to block on the busy flush slot with the queue full
+	for len(b.items) < cap(b.items) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := b.TryAdd(4); err != ErrFull {
+		t.Fatalf("got %v with a full queue", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.Add(ctx, 4); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want Add to block", err)
+	}
+
+	close(release)
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 4 {
+		t.Fatalf("got %d failed batches, want 4", len(failed))
+	}
+}
+
+func TestCloseWithAddBlocked(t *testing.T) {
+	b := New(func(ctx context.Context, batch []int) error {
+		// a stuck downstream that only gives up when canceled
+		<-ctx.Done()
+		return ctx.Err()
+	}, &Options[int]{MaxItems: 1, QueueSize: 1, ErrorHandler: func([]int, error) {}})
+
+	blocked := make(chan error)
+	go func() {
+		for i := 0; ; i++ {
+			if err := b.Add(context.Background(), i); err != nil {
+				blocked <- err
+				return
+			}
+		}
+	}()
+	deadline := time.Now().Add(time.Second)
+	for len(b.items) < cap(b.items) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := b.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Close = %v", err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Fatalf("Close took %s with a 50ms deadline", d)
+	}
+	if err := <-blocked; err != ErrClosed {
+		t.Fatalf("blocked Add = %v, want ErrClosed", err)
+	}
+	if err := b.TryAdd(1); err != ErrClosed {
+		t.Fatalf("TryAdd after Close = %v", err)
+	}
+	select {
+	case <-b.stopped:
+	case <-time.After(time.Second):
+		t.Fatal("canceled flushes did not stop the batcher")
+	}
+}
@@ -0,0 +1,148 @@
+// Package blob stores objects by key behind a Store interface with S3 and
+// local filesystem implementations, so services run the same code against a
+// bucket in AWS and a directory locally, and tests need no bucket.
+package blob
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// ErrNotFound is returned by Get when the key does not exist
+var ErrNotFound = errs.New(errs.NotFound, "blob: object not found")
+
+// ErrInvalidKey is returned for keys that are empty, absolute or contain
+// . or .. elements
+var ErrInvalidKey = errs.New(errs.InvalidArgument, "blob: invalid key")
+
+// Object describes a stored object
+type Object struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	CacheControl string
+	ETag         string
+	LastModified time.Time
+	// CacheControl and Metadata are set by Get; List leaves them empty
+	Metadata map[string]string
+}
+
+type PutOptions struct {
+	// ContentType is stored with the object (default: application/octet-stream)
+	ContentType string
+	// CacheControl is returned when the object is served
+	CacheControl string
+	// Metadata is user metadata stored with the object
+	Metadata map[string]string
+}
+
+type ListOptions struct {
+	// Prefix limits the listing to keys starting with it
+	Prefix string
+	// PageToken continues a listing from ListPage.NextPageToken
+	PageToken string
+	// Limit is the maximum number of objects per page (default and max: 1000)
+	Limit int
+}
+
+// ListPage is one page of a listing in key order
+type ListPage struct {
+	Objects []Object
+	// NextPageToken continues the listing; empty on the last page
+	NextPageToken string
+}
+
+type SignedURLOptions struct {
+	// Method is http.MethodGet to download or http.MethodPut to upload
+	// (default: GET)
+	Method string
+	// Expires is how long the URL is valid (default: 15m)
+	Expires time.Duration
+	// ContentType must be sent with signed uploads
+	ContentType string
+}
+
+func (o *SignedURLOptions) withDefaults() SignedURLOptions {
+	c := SignedURLOptions{}
+	if o != nil {
+		c = *o
+	}
+	if c.Method == "" {
+		c.Method = http.MethodGet
+	}
+	if c.Expires <= 0 {
+		c.Expires = 15 * time.Minute
+	}
+	return c
+}
+
+// Store stores objects by key. Keys are slash separated paths.
+type Store interface {
+	// Put stores the contents of r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, opts *PutOptions) error
+	// Get opens the object at key; the caller must close the reader. It
+	// returns ErrNotFound when key does not exist.
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	// Delete removes the object at key; deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+	// List returns one page of objects
+	List(ctx context.Context, opts *ListOptions) (*ListPage, error)
+	// SignedURL returns a URL that grants temporary access to key without
+	// credentials, e.g. for browser uploads and downloads
+	SignedURL(ctx context.Context, key string, opts *SignedURLOptions) (string, error)
+}
+
+// Walk calls fn for every object under prefix, page by page, stopping at the
+// first error
+func Walk(ctx context.Context, s Store, prefix string, fn func(Object) error) error {
+	opts := &ListOptions{Prefix: prefix}
+	for {
+		page, err := s.List(ctx, opts)
+		if err != nil {
+			return err
+		}
+		for _, obj := range page.Objects {
+			if err := fn(obj); err != nil {
+				return err
+			}
+		}
+		if page.NextPageToken == "" {
+			return nil
+		}
+		opts.PageToken = page.NextPageToken
+	}
+}
+
+// ReadAll reads the whole object at key
+func ReadAll(ctx context.Context, s Store, key string) ([]byte, error) {
+	r, _, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errs.Errorf("failed to read %s: %w", key, err)
+	}
+	return b, nil
+}
+
+// validKey rejects keys that could escape a directory or prefix
+func validKey(key string) error {
+	if key == "." || !fs.ValidPath(key) {
+		return errs.Errorf("%q: %w", key, ErrInvalidKey)
+	}
+	return nil
+}
+
+func listLimit(opts *ListOptions) int {
+	if opts == nil || opts.Limit <= 0 || opts.Limit > 1000 {
+		return 1000
+	}
+	return opts.Limit
+}
@@ -0,0 +1,181 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewLocalStore(&NewLocalStoreArgs{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, key := range []string{"a/b", "a-b", "a/c/d", "z"} {
+		if err := s.Put(ctx, key, strings.NewReader("data "+key), &PutOptions{ContentType: "text/plain", Metadata: map[string]string{"k": key}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r, obj, err := s.Get(ctx, "a/c/d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(r)
+	r.Close()
+	if string(b) != "data a/c/d" || obj.ContentType != "text/plain" || obj.Metadata["k"] != "a/c/d" || obj.ETag == "" {
+		t.Fatalf("got %q %+v", b, obj)
+	}
+
+	var keys []string
+	if err := Walk(ctx, s, "a", func(o Object) error { keys = append(keys, o.Key); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(keys, ",") != "a-b,a/b,a/c/d" {
+		t.Fatalf("got keys %v", keys)
+	}
+	page, err := s.List(ctx, &ListOptions{Limit: 2})
+	if err != nil || len(page.Objects) != 2 || page.NextPageToken == "" {
+		t.Fatalf("got %+v %v", page, err)
+	}
+	page, _ = s.List(ctx, &ListOptions{Limit: 2, PageToken: page.NextPageToken})
+	if len(page.Objects) != 2 || page.Objects[1].Key != "z" || page.NextPageToken != "" {
+		t.Fatalf("got second page %+v", page)
+	}
+
+	if err := s.Delete(ctx, "z"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, "z"); err != nil {
+		t.Fatalf("deleting a missing key: %v", err)
+	}
+	if _, _, err := s.Get(ctx, "z"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want ErrNotFound", err)
+	}
+	for _, key := range []string{"../x", "/x", "a//b", ".blob/meta/a.json"} {
+		if err := s.Put(ctx, key, strings.NewReader(""), nil); !errors.Is(err, ErrInvalidKey) {
+			t.Fatalf("%s: got %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
+
+func TestLocalSignedURLs(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	s, err := NewLocalStore(&NewLocalStoreArgs{Dir: t.TempDir(), Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	mux := http.NewServeMux()
+	mux.Handle("/blobs/", http.StripPrefix("/blobs", s.Handler()))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	s.baseURL = srv.URL + "/blobs"
+
+	upload, err := s.SignedURL(ctx, "docs/report.txt", &SignedURLOptions{Method: http.MethodPut, ContentType: "text/plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodPut, upload, strings.NewReader("report"))
+	req.Header.Set("Content-Type", "text/html")
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("got %v %v, want 403 for the wrong content type", resp, err)
+	}
+	req, _ = http.NewRequest(http.MethodPut, upload, strings.NewReader("report"))
+	req.Header.Set("Content-Type", "text/plain")
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("upload: %v %v", resp, err)
+	}
+
+	download, _ := s.SignedURL(ctx, "docs/report.txt", nil)
+	resp, err := http.Get(download)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(b) != "report" || resp.Header.Get("Content-Type") != "text/plain" {
+		t.Fatalf("download: %d %q", resp.StatusCode, b)
+	}
+	if resp, _ := http.Get(strings.Replace(download, "report.txt", "other.txt", 1)); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("got %d for a URL signed for another key", resp.StatusCode)
+	}
+	now = now.Add(time.Hour)
+	if resp, _ := http.Get(download); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("got %d for an expired URL", resp.StatusCode)
+	}
+}
+
+// fakeS3 records calls made by S3Store
+type fakeS3 struct {
+	S3API
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func (f *fakeS3) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	b, _ := io.ReadAll(in.Body)
+	f.objects[aws.ToString(in.Key)] = b
+	f.types[aws.ToString(in.Key)] = aws.ToString(in.ContentType)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	b, ok := f.objects[aws.ToString(in.Key)]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(b)), ContentLength: aws.Int64(int64(len(b))), ETag: aws.String(`"abc"`)}, nil
+}
+
+func (f *fakeS3) ListObjectsV2(_ context.Context, in *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	out := &s3.ListObjectsV2Output{}
+	for key := range f.objects {
+		if strings.HasPrefix(key, aws.ToString(in.Prefix)) {
+			out.Contents = append(out.Contents, types.Object{Key: aws.String(key)})
+		}
+	}
+	return out, nil
+}
+
+func TestS3Store(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeS3{objects: map[string][]byte{}, types: map[string]string{}}
+	s, err := NewS3Store(&NewS3StoreArgs{Client: client, Bucket: "bucket", Prefix: "uploads"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a reader that is not a ReadSeeker is buffered
+	if err := s.Put(ctx, "a.txt", io.MultiReader(strings.NewReader("hi")), nil); err != nil {
+		t.Fatal(err)
+	}
+	if string(client.objects["uploads/a.txt"]) != "hi" || client.types["uploads/a.txt"] != "application/octet-stream" {
+		t.Fatalf("got objects %v", client.objects)
+	}
+	if b, err := ReadAll(ctx, s, "a.txt"); err != nil || string(b) != "hi" {
+		t.Fatalf("got %q %v", b, err)
+	}
+	if _, _, err := s.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want ErrNotFound", err)
+	}
+	page, err := s.List(ctx, nil)
+	if err != nil || len(page.Objects) != 1 || page.Objects[0].Key != "a.txt" {
+		t.Fatalf("got %+v %v, want keys without the store prefix", page, err)
+	}
+	if _, err := s.SignedURL(ctx, "a.txt", nil); err == nil {
+		t.Fatal("expected an error without a presigner")
+	}
+}
@@ -0,0 +1,335 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/httpserver"
+)
+
+// localMetaDir holds content types, metadata and in-progress writes beside
+// the objects; keys under it are rejected
+const localMetaDir = ".blob"
+
+type NewLocalStoreArgs struct {
+	// Dir holds the objects as files, created if missing (required)
+	Dir string
+	// BaseURL is where Handler is mounted, used to build signed URLs, e.g.
+	// http://localhost:8080/blobs
+	BaseURL string
+	// SigningKey signs URLs (default: random per process)
+	SigningKey []byte
+	// Now is the clock for signed URL expiry (default: time.Now)
+	Now func() time.Time
+}
+
+// LocalStore is a Store in a local directory for development and tests.
+// Signed URLs point at its Handler, which serves downloads and accepts
+// uploads like presigned S3 URLs.
+type LocalStore struct {
+	root    *os.Root
+	baseURL string
+	key     []byte
+	now     func() time.Time
+}
+
+var _ Store = (*LocalStore)(nil)
+
+// localMeta is the sidecar stored for each object
+type localMeta struct {
+	ContentType  string            `json:"contentType"`
+	CacheControl string            `json:"cacheControl,omitempty"`
+	ETag         string            `json:"etag"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+// NewLocalStore creates a LocalStore; call Close to release the directory
+func NewLocalStore(args *NewLocalStoreArgs) (*LocalStore, error) {
+	if args == nil || args.Dir == "" {
+		return nil, errs.New(errs.Internal, "blob: Dir is required")
+	}
+	if err := os.MkdirAll(args.Dir, 0o755); err != nil {
+		return nil, errs.Errorf("failed to create %s: %w", args.Dir, err)
+	}
+	root, err := os.OpenRoot(args.Dir)
+	if err != nil {
+		return nil, errs.Errorf("failed to open %s: %w", args.Dir, err)
+	}
+	s := &LocalStore{root: root, baseURL: strings.TrimRight(args.BaseURL, "/"), key: args.SigningKey, now: args.Now}
+	if len(s.key) == 0 {
+		s.key = make([]byte, 32)
+		_, _ = rand.Read(s.key)
+	}
+	if s.now == nil {
+		s.now = time.Now
+	}
+	return s, nil
+}
+
+// Close releases the directory
+func (s *LocalStore) Close() error {
+	return s.root.Close()
+}
+
+func (s *LocalStore) Put(_ context.Context, key string, r io.Reader, opts *PutOptions) error {
+	if err := localKey(key); err != nil {
+		return err
+	}
+	o := PutOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.ContentType == "" {
+		o.ContentType = "application/octet-stream"
+	}
+
+	// write aside and rename, so readers never see a partial object
+	if err := s.root.MkdirAll(path.Join(localMetaDir, "tmp"), 0o755); err != nil {
+		return errs.Errorf("failed to create temporary directory: %w", err)
+	}
+	suffix := make([]byte, 8)
+	_, _ = rand.Read(suffix)
+	tmp := path.Join(localMetaDir, "tmp", hex.EncodeToString(suffix))
+	f, err := s.root.Create(tmp)
+	if err != nil {
+		return errs.Errorf("failed to create %s: %w", key, err)
+	}
+	defer s.root.Remove(tmp)
+	h := md5.New()
+	_, err = io.Copy(io.MultiWriter(f, h), r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errs.Errorf("failed to write %s: %w", key, err)
+	}
+
+	meta, err := json.Marshal(localMeta{
+		ContentType:  o.ContentType,
+		CacheControl: o.CacheControl,
+		ETag:         hex.EncodeToString(h.Sum(nil)),
+		Metadata:     o.Metadata,
+	})
+	if err != nil {
+		return errs.Errorf("failed to marshal metadata of %s: %w", key, err)
+	}
+	for _, dir := range []string{path.Dir(key), path.Dir(metaPath(key))} {
+		if err := s.root.MkdirAll(dir, 0o755); err != nil {
+			return errs.Errorf("failed to create directory for %s: %w", key, err)
+		}
+	}
+	if err := s.root.WriteFile(metaPath(key), meta, 0o644); err != nil {
+		return errs.Errorf("failed to write metadata of %s: %w", key, err)
+	}
+	if err := s.root.Rename(tmp, key); err != nil {
+		return errs.Errorf("failed to store %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *LocalStore) Get(_ context.Context, key string) (io.ReadCloser, *Object, error) {
+	if err := localKey(key); err != nil {
+		return nil, nil, err
+	}
+	f, err := s.root.Open(key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, errs.Errorf("%s: %w", key, ErrNotFound)
+	}
+	if err != nil {
+		return nil, nil, errs.Errorf("failed to open %s: %w", key, err)
+	}
+	info, err := f.Stat()
+	if err == nil && info.IsDir() {
+		f.Close()
+		return nil, nil, errs.Errorf("%s: %w", key, ErrNotFound)
+	}
+	if err != nil {
+		f.Close()
+		return nil, nil, errs.Errorf("failed to stat %s: %w", key, err)
+	}
+	obj := s.object(key, info)
+	return f, &obj, nil
+}
+
+// object describes the file at key, filling in its sidecar's fields
+func (s *LocalStore) object(key string, info fs.FileInfo) Object {
+	obj := Object{Key: key, Size: info.Size(), LastModified: info.ModTime(), ContentType: "application/octet-stream"}
+	var meta localMeta
+	if b, err := s.root.ReadFile(metaPath(key)); err == nil && json.Unmarshal(b, &meta) == nil {
+		obj.ContentType, obj.CacheControl, obj.ETag, obj.Metadata = meta.ContentType, meta.CacheControl, meta.ETag, meta.Metadata
+	}
+	return obj
+}
+
+func (s *LocalStore) Delete(_ context.Context, key string) error {
+	if err := localKey(key); err != nil {
+		return err
+	}
+	if err := s.root.Remove(key); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return errs.Errorf("failed to delete %s: %w", key, err)
+	}
+	_ = s.root.Remove(metaPath(key))
+	return nil
+}
+
+// List walks the directory under the prefix, so it suits development-sized
+// trees. Page tokens are the last key of the previous page.
+func (s *LocalStore) List(_ context.Context, opts *ListOptions) (*ListPage, error) {
+	o := ListOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	dir := "."
+	if i := strings.LastIndex(o.Prefix, "/"); i > 0 {
+		dir = o.Prefix[:i]
+	}
+	var keys []string
+	err := fs.WalkDir(s.root.FS(), dir, func(p string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fs.SkipAll
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p == localMetaDir {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(p, o.Prefix) && p > o.PageToken && !strings.HasPrefix(p, localMetaDir+"/") {
+			keys = append(keys, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errs.Errorf("failed to list %s: %w", o.Prefix, err)
+	}
+	// directory order differs from key order, e.g. a/b sorts after a-b
+	slices.Sort(keys)
+
+	page := &ListPage{}
+	if limit := listLimit(opts); len(keys) > limit {
+		keys = keys[:limit]
+		page.NextPageToken = keys[limit-1]
+	}
+	for _, key := range keys {
+		info, err := s.root.Stat(key)
+		if err != nil {
+			continue
+		}
+		obj := s.object(key, info)
+		obj.CacheControl, obj.Metadata = "", nil
+		page.Objects = append(page.Objects, obj)
+	}
+	return page, nil
+}
+
+// SignedURL returns a URL for Handler, so BaseURL must be set
+func (s *LocalStore) SignedURL(_ context.Context, key string, opts *SignedURLOptions) (string, error) {
+	if err := localKey(key); err != nil {
+		return "", err
+	}
+	if s.baseURL == "" {
+		return "", errs.New(errs.Internal, "blob: LocalStore needs a BaseURL to sign URLs")
+	}
+	o := opts.withDefaults()
+	if o.Method != http.MethodGet && o.Method != http.MethodPut {
+		return "", errs.Errorf("blob: cannot sign %s URLs", o.Method)
+	}
+	expires := strconv.FormatInt(s.now().Add(o.Expires).Unix(), 10)
+	q := url.Values{"expires": {expires}, "signature": {s.sign(o.Method, key, expires, o.ContentType)}}
+	if o.ContentType != "" {
+		q.Set("content-type", o.ContentType)
+	}
+	return s.baseURL + "/" + (&url.URL{Path: key}).EscapedPath() + "?" + q.Encode(), nil
+}
+
+func (s *LocalStore) sign(method, key, expires, contentType string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(method + "\n" + key + "\n" + expires + "\n" + contentType))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Handler serves signed URLs: GET downloads and PUT uploads the object named
+// by the request path, which is relative to where the handler is mounted,
+// e.g. with http.StripPrefix
+func (s *LocalStore) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		q := r.URL.Query()
+		expires, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+		valid := err == nil && s.now().Unix() <= expires &&
+			hmac.Equal([]byte(q.Get("signature")), []byte(s.sign(r.Method, key, q.Get("expires"), q.Get("content-type"))))
+		if !valid {
+			httpserver.WriteProblem(w, r, httpserver.NewProblem(http.StatusForbidden, "invalid or expired signature"))
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPut:
+			contentType := r.Header.Get("Content-Type")
+			if want := q.Get("content-type"); want != "" && contentType != want {
+				httpserver.WriteProblem(w, r, httpserver.NewProblem(http.StatusForbidden, "Content-Type must be "+want))
+				return
+			}
+			if err := s.Put(r.Context(), key, r.Body, &PutOptions{ContentType: contentType}); err != nil {
+				httpserver.WriteProblem(w, r, httpserver.NewProblem(http.StatusInternalServerError, "failed to store object"))
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			body, obj, err := s.Get(r.Context(), key)
+			if errors.Is(err, ErrNotFound) {
+				httpserver.WriteProblem(w, r, httpserver.NewProblem(http.StatusNotFound, "object not found"))
+				return
+			}
+			if err != nil {
+				httpserver.WriteProblem(w, r, httpserver.NewProblem(http.StatusInternalServerError, "failed to open object"))
+				return
+			}
+			defer body.Close()
+			w.Header().Set("Content-Type", obj.ContentType)
+			if obj.ETag != "" {
+				w.Header().Set("ETag", `"`+obj.ETag+`"`)
+			}
+			if obj.CacheControl != "" {
+				w.Header().Set("Cache-Control", obj.CacheControl)
+			}
+			http.ServeContent(w, r, "", obj.LastModified, body.(io.ReadSeeker))
+		}
+	})
+}
+
+// localKey validates key and keeps it out of the sidecar directory
+func localKey(key string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	if key == localMetaDir || strings.HasPrefix(key, localMetaDir+"/") {
+		return errs.Errorf("%q is reserved: %w", key, ErrInvalidKey)
+	}
+	return nil
+}
+
+func metaPath(key string) string {
+	return path.Join(localMetaDir, "meta", key+".json")
+}
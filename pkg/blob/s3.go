@@ -0,0 +1,200 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	egerrors "github.com/bdlilley/easygo/pkg/egaws/errors"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// S3API is the subset of the S3 client used by S3Store
+type S3API interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+// S3PresignAPI is the subset of the S3 presign client used by S3Store
+type S3PresignAPI interface {
+	PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+	PresignPutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+}
+
+type NewS3StoreArgs struct {
+	// Client is typically EGAwsClient.GetS3Client() (required)
+	Client S3API
+	// Presigner signs URLs (default: s3.NewPresignClient of Client when it is
+	// an *s3.Client)
+	Presigner S3PresignAPI
+	// Bucket holds the objects (required)
+	Bucket string
+	// Prefix is prepended to keys, so several stores can share a bucket
+	Prefix string
+}
+
+// S3Store is a Store in an S3 bucket. Bodies passed to Put that are not
+// io.ReadSeeker are buffered in memory, since S3 needs the content length.
+type S3Store struct {
+	client    S3API
+	presigner S3PresignAPI
+	bucket    string
+	prefix    string
+}
+
+var _ Store = (*S3Store)(nil)
+
+// NewS3Store creates an S3Store
+func NewS3Store(args *NewS3StoreArgs) (*S3Store, error) {
+	if args == nil || args.Client == nil || args.Bucket == "" {
+		return nil, errs.New(errs.Internal, "blob: S3 Client and Bucket are required")
+	}
+	s := &S3Store{client: args.Client, presigner: args.Presigner, bucket: args.Bucket, prefix: args.Prefix}
+	if s.presigner == nil {
+		if c, ok := args.Client.(*s3.Client); ok {
+			s.presigner = s3.NewPresignClient(c)
+		}
+	}
+	if s.prefix != "" && !strings.HasSuffix(s.prefix, "/") {
+		s.prefix += "/"
+	}
+	return s, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, opts *PutOptions) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	o := PutOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.ContentType == "" {
+		o.ContentType = "application/octet-stream"
+	}
+	if _, ok := r.(io.ReadSeeker); !ok {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return errs.Errorf("failed to read body of %s: %w", key, err)
+		}
+		r = bytes.NewReader(b)
+	}
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(s.prefix + key),
+		Body:        r,
+		ContentType: aws.String(o.ContentType),
+		Metadata:    o.Metadata,
+	}
+	if o.CacheControl != "" {
+		input.CacheControl = aws.String(o.CacheControl)
+	}
+	if _, err := s.client.PutObject(ctx, input); err != nil {
+		return errs.Errorf("failed to put %s: %w", s.url(key), err)
+	}
+	return nil
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	if err := validKey(key); err != nil {
+		return nil, nil, err
+	}
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(s.prefix + key)})
+	if egerrors.IsNotFound(err) {
+		return nil, nil, errs.Errorf("%s: %w", s.url(key), ErrNotFound)
+	}
+	if err != nil {
+		return nil, nil, errs.Errorf("failed to get %s: %w", s.url(key), err)
+	}
+	return out.Body, &Object{
+		Key:          key,
+		Size:         aws.ToInt64(out.ContentLength),
+		ContentType:  aws.ToString(out.ContentType),
+		CacheControl: aws.ToString(out.CacheControl),
+		ETag:         strings.Trim(aws.ToString(out.ETag), `"`),
+		LastModified: aws.ToTime(out.LastModified),
+		Metadata:     out.Metadata,
+	}, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	if _, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(s.prefix + key)}); err != nil {
+		return errs.Errorf("failed to delete %s: %w", s.url(key), err)
+	}
+	return nil
+}
+
+func (s *S3Store) List(ctx context.Context, opts *ListOptions) (*ListPage, error) {
+	o := ListOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	input := &s3.ListObjectsV2Input{
+		Bucket:  aws.String(s.bucket),
+		Prefix:  aws.String(s.prefix + o.Prefix),
+		MaxKeys: aws.Int32(int32(listLimit(opts))),
+	}
+	if o.PageToken != "" {
+		input.ContinuationToken = aws.String(o.PageToken)
+	}
+	out, err := s.client.ListObjectsV2(ctx, input)
+	if err != nil {
+		return nil, errs.Errorf("failed to list s3://%s/%s: %w", s.bucket, s.prefix+o.Prefix, err)
+	}
+	page := &ListPage{Objects: make([]Object, 0, len(out.Contents))}
+	for _, obj := range out.Contents {
+		page.Objects = append(page.Objects, Object{
+			Key:          strings.TrimPrefix(aws.ToString(obj.Key), s.prefix),
+			Size:         aws.ToInt64(obj.Size),
+			ETag:         strings.Trim(aws.ToString(obj.ETag), `"`),
+			LastModified: aws.ToTime(obj.LastModified),
+		})
+	}
+	if aws.ToBool(out.IsTruncated) {
+		page.NextPageToken = aws.ToString(out.NextContinuationToken)
+	}
+	return page, nil
+}
+
+func (s *S3Store) SignedURL(ctx context.Context, key string, opts *SignedURLOptions) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	if s.presigner == nil {
+		return "", errs.New(errs.Internal, "blob: S3Store has no Presigner")
+	}
+	o := opts.withDefaults()
+	expires := func(po *s3.PresignOptions) { po.Expires = o.Expires }
+	var req *v4.PresignedHTTPRequest
+	var err error
+	switch o.Method {
+	case http.MethodGet:
+		req, err = s.presigner.PresignGetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(s.prefix + key)}, expires)
+	case http.MethodPut:
+		input := &s3.PutObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(s.prefix + key)}
+		if o.ContentType != "" {
+			input.ContentType = aws.String(o.ContentType)
+		}
+		req, err = s.presigner.PresignPutObject(ctx, input, expires)
+	default:
+		return "", errs.Errorf("blob: cannot sign %s URLs", o.Method)
+	}
+	if err != nil {
+		return "", errs.Errorf("failed to sign %s: %w", s.url(key), err)
+	}
+	return req.URL, nil
+}
+
+func (s *S3Store) url(key string) string {
+	return "s3://" + s.bucket + "/" + s.prefix + key
+}
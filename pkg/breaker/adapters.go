@@ -0,0 +1,91 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// Transport wraps next so requests fail fast with ErrOpen while b is open.
+// Transport errors and 5xx responses count as failures. Use it as the
+// httpclient Transport or with any http.Client; next defaults to
+// http.DefaultTransport.
+func Transport(b *Breaker, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{breaker: b, next: next}
+}
+
+type transport struct {
+	breaker *Breaker
+	next    http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	done, err := t.breaker.allow()
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, errs.Errorf("%s: %w", t.breaker.name, err)
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		done(classify(err, DefaultIsFailure))
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		done(failure)
+	} else {
+		done(success)
+	}
+	return resp, nil
+}
+
+// AWSMiddleware returns an APIOptions entry that guards every operation of
+// the clients it is applied to with b, around the SDK's own retries. Append it
+// to aws.Config.APIOptions or set NewEGAwsClientArgs.CircuitBreaker. Results
+// are classified with AWSIsFailure.
+func AWSMiddleware(b *Breaker) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("EasyGoCircuitBreaker",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				done, err := b.allow()
+				if err != nil {
+					return middleware.InitializeOutput{}, middleware.Metadata{}, errs.Errorf("%s: %w", b.name, err)
+				}
+				out, metadata, err := next.HandleInitialize(ctx, in)
+				done(classify(err, AWSIsFailure))
+				return out, metadata, err
+			}), middleware.Before)
+	}
+}
+
+// AWSIsFailure reports whether an AWS API error indicates an unhealthy
+// service: failures to send the request, throttling, and 5xx responses.
+// Client errors such as validation or access denied are not failures.
+func AWSIsFailure(err error) bool {
+	if !DefaultIsFailure(err) {
+		return false
+	}
+	var sendErr *smithyhttp.RequestSendError
+	if errors.As(err, &sendErr) {
+		return true
+	}
+	if retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary {
+		return true
+	}
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.HTTPStatusCode() >= http.StatusInternalServerError
+	}
+	return false
+}
@@ -0,0 +1,408 @@
+// Package breaker implements circuit breakers that stop calling a failing
+// dependency for a while and probe it before resuming, with Prometheus
+// metrics, state-change logging, and adapters for HTTP transports and AWS SDK clients.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/bdlilley/easygo/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrOpen is returned without calling the dependency while the breaker is
+// open, or half-open with all probes in flight
+var ErrOpen = errs.New(errs.Unavailable, "circuit breaker is open")
+
+// State is a breaker state
+type State int
+
+const (
+	// Closed lets every call through and counts failures
+	Closed State = iota
+	// HalfOpen lets a limited number of probe calls through after OpenTimeout
+	HalfOpen
+	// Open rejects calls with ErrOpen
+	Open
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case HalfOpen:
+		return "half-open"
+	case Open:
+		return "open"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+type Options struct {
+	// ConsecutiveFailures opens the breaker after this many failures in a row (default: 5)
+	ConsecutiveFailures int
+	// FailureRatio also opens the breaker when at least this fraction of calls
+	// in the current Interval failed, once MinRequests were made (default: disabled)
+	FailureRatio float64
+	// MinRequests is the number of calls in an Interval before FailureRatio applies (default: 10)
+	MinRequests int
+	// Interval is the window over which FailureRatio is measured (default: 60s)
+	Interval time.Duration
+	// OpenTimeout is how long the breaker stays open before probing (default: 30s)
+	OpenTimeout time.Duration
+	// HalfOpenRequests is the number of probes let through while half-open; the
+	// breaker closes once they all succeed and reopens on the first failure (default: 1)
+	HalfOpenRequests int
+	// IsFailure classifies the result of Execute and Do (default:
+	// DefaultIsFailure). Calls ending in context.Canceled are not counted
+	// either way.
+	IsFailure func(err error) bool
+	// OnStateChange is called after every state change
+	OnStateChange func(name string, from, to State)
+	// Registerer registers the breaker metrics (default: prometheus.DefaultRegisterer)
+	Registerer prometheus.Registerer
+	// Namespace prefixes metric names, e.g. "myapp" gives myapp_circuit_breaker_state
+	Namespace string
+	// Logger logs state changes at warn when opening and info otherwise (default: logging.Noop)
+	Logger logging.Logger
+}
+
+// Breaker tracks the health of one dependency. It is safe for concurrent use.
+type Breaker struct {
+	name    string
+	opts    Options
+	metrics *breakerMetrics
+	now     func() time.Time
+
+	mu    sync.Mutex
+	state State
+	// generation changes with every state change and interval reset so results
+	// of calls admitted earlier are ignored
+	generation  uint64
+	consecutive int
+	requests    int
+	failures    int
+	windowStart time.Time
+	openedAt    time.Time
+	probes      int
+	successes   int
+}
+
+// New creates a closed breaker; name labels its metrics and logs
+func New(name string, opts *Options) *Breaker {
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.ConsecutiveFailures <= 0 {
+		o.ConsecutiveFailures = 5
+	}
+	if o.MinRequests <= 0 {
+		o.MinRequests = 10
+	}
+	if o.Interval <= 0 {
+		o.Interval = time.Minute
+	}
+	if o.OpenTimeout <= 0 {
+		o.OpenTimeout = 30 * time.Second
+	}
+	if o.HalfOpenRequests <= 0 {
+		o.HalfOpenRequests = 1
+	}
+	if o.IsFailure == nil {
+		o.IsFailure = DefaultIsFailure
+	}
+	if o.Logger == nil {
+		o.Logger = logging.Noop()
+	}
+	reg := o.Registerer
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	b := &Breaker{
+		name:    name,
+		opts:    o,
+		metrics: newBreakerMetrics(reg, o.Namespace),
+		now:     time.Now,
+	}
+	b.windowStart = b.now()
+	b.metrics.state.WithLabelValues(name).Set(float64(Closed))
+	return b
+}
+
+// DefaultIsFailure treats every error except context.Canceled as a failure;
+// a caller giving up says nothing about the dependency's health
+func DefaultIsFailure(err error) bool {
+	return err != nil && !errors.Is(err, context.Canceled)
+}
+
+// outcome is the recorded result of an admitted call
+type outcome int
+
+const (
+	success outcome = iota
+	failure
+	// canceled calls are not counted; a canceled probe frees its slot and the
+	// breaker stays half-open
+	canceled
+)
+
+func (o outcome) String() string {
+	switch o {
+	case failure:
+		return "failure"
+	case canceled:
+		return "canceled"
+	}
+	return "success"
+}
+
+// classify returns the outcome of a call that returned err
+func classify(err error, isFailure func(error) bool) outcome {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return canceled
+	case isFailure(err):
+		return failure
+	}
+	return success
+}
+
+// Name returns the breaker's name
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns the current state
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	changed := b.refresh(b.now())
+	state := b.state
+	b.mu.Unlock()
+	b.notify(changed)
+	return state
+}
+
+// Allow admits a call, returning ErrOpen if the breaker rejects it. Report the
+// outcome by calling done exactly once. Execute and Do wrap Allow for
+// function calls; use it directly to classify results such as HTTP statuses.
+func (b *Breaker) Allow() (done func(failed bool), err error) {
+	record, err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+	return func(failed bool) {
+		if failed {
+			record(failure)
+		} else {
+			record(success)
+		}
+	}, nil
+}
+
+// allow is Allow reporting an outcome, so callers can leave canceled calls
+// uncounted
+func (b *Breaker) allow() (done func(outcome), err error) {
+	b.mu.Lock()
+	changed := b.refresh(b.now())
+	rejected := b.state == Open || (b.state == HalfOpen && b.probes >= b.opts.HalfOpenRequests)
+	if !rejected && b.state == HalfOpen {
+		b.probes++
+	}
+	generation := b.generation
+	b.mu.Unlock()
+	b.notify(changed)
+
+	if rejected {
+		b.metrics.requests.WithLabelValues(b.name, "rejected").Inc()
+		return nil, ErrOpen
+	}
+	var once sync.Once
+	return func(o outcome) {
+		once.Do(func() { b.record(generation, o) })
+	}, nil
+}
+
+// Do calls fn if the breaker admits it and records the result
+func (b *Breaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	_, err := Execute(ctx, b, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// Execute calls fn if b admits it and records the result. A panic in fn is
+// recorded as a failure and re-raised.
+func Execute[T any](ctx context.Context, b *Breaker, fn func(ctx context.Context) (T, error)) (T, error) {
+	done, err := b.allow()
+	if err != nil {
+		var zero T
+		return zero, errs.Errorf("%s: %w", b.name, err)
+	}
+	completed := false
+	defer func() {
+		if !completed {
+			done(failure)
+		}
+	}()
+	result, err := fn(ctx)
+	completed = true
+	done(classify(err, b.opts.IsFailure))
+	return result, err
+}
+
+type transition struct {
+	from, to State
+}
+
+// refresh moves an open breaker to half-open once OpenTimeout has passed and
+// starts a new failure ratio window; b.mu must be held
+func (b *Breaker) refresh(now time.Time) *transition {
+	switch b.state {
+	case Open:
+		if now.Sub(b.openedAt) >= b.opts.OpenTimeout {
+			return b.setState(HalfOpen, now)
+		}
+	case Closed:
+		if now.Sub(b.windowStart) >= b.opts.Interval {
+			b.generation++
+			b.requests, b.failures = 0, 0
+			b.windowStart = now
+		}
+	}
+	return nil
+}
+
+func (b *Breaker) record(generation uint64, o outcome) {
+	b.metrics.requests.WithLabelValues(b.name, o.String()).Inc()
+
+	b.mu.Lock()
+	now := b.now()
+	var changed *transition
+	if generation == b.generation {
+		changed = b.recordLocked(o, now)
+	}
+	b.mu.Unlock()
+	b.notify(changed)
+}
+
+func (b *Breaker) recordLocked(o outcome, now time.Time) *transition {
+	if o == canceled {
+		if b.state == HalfOpen {
+			b.probes--
+		}
+		return nil
+	}
+	switch b.state {
+	case Closed:
+		b.requests++
+		if o == success {
+			b.consecutive = 0
+			return nil
+		}
+		b.consecutive++
+		b.failures++
+		if b.consecutive >= b.opts.ConsecutiveFailures {
+			return b.setState(Open, now)
+		}
+		if b.opts.FailureRatio > 0 && b.requests >= b.opts.MinRequests &&
+			float64(b.failures)/float64(b.requests) >= b.opts.FailureRatio {
+			return b.setState(Open, now)
+		}
+	case HalfOpen:
+		b.probes--
+		if o == failure {
+			return b.setState(Open, now)
+		}
+		b.successes++
+		if b.successes >= b.opts.HalfOpenRequests {
+			return b.setState(Closed, now)
+		}
+	}
+	return nil
+}
+
+// setState resets the counters for the new state; b.mu must be held
+func (b *Breaker) setState(to State, now time.Time) *transition {
+	from := b.state
+	b.state = to
+	b.generation++
+	b.consecutive, b.requests, b.failures = 0, 0, 0
+	b.probes, b.successes = 0, 0
+	b.windowStart = now
+	if to == Open {
+		b.openedAt = now
+	}
+	return &transition{from: from, to: to}
+}
+
+// notify reports a state change; it is called without b.mu held
+func (b *Breaker) notify(t *transition) {
+	if t == nil {
+		return
+	}
+	b.metrics.state.WithLabelValues(b.name).Set(float64(t.to))
+	b.metrics.transitions.WithLabelValues(b.name, t.to.String()).Inc()
+
+	log := b.opts.Logger.WithFields(logrus.Fields{
+		"breaker": b.name,
+		"from":    t.from.String(),
+		"to":      t.to.String(),
+	})
+	if t.to == Open {
+		log.WithField("retry_in", b.opts.OpenTimeout).Warn("circuit breaker opened")
+	} else {
+		log.Info("circuit breaker state changed")
+	}
+	if b.opts.OnStateChange != nil {
+		b.opts.OnStateChange(b.name, t.from, t.to)
+	}
+}
+
+type breakerMetrics struct {
+	state       *prometheus.GaugeVec
+	requests    *prometheus.CounterVec
+	transitions *prometheus.CounterVec
+}
+
+func newBreakerMetrics(reg prometheus.Registerer, namespace string) *breakerMetrics {
+	return &breakerMetrics{
+		state: metrics.RegisterCollector(reg, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "circuit_breaker_state",
+			Help:      "Circuit breaker state: 0 closed, 1 half-open, 2 open.",
+		}, []string{"breaker"})),
+		requests: metrics.RegisterCollector(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "circuit_breaker_requests_total",
+			Help:      "Calls through circuit breakers by result: success, failure, canceled or rejected.",
+		}, []string{"breaker", "result"})),
+		transitions: metrics.RegisterCollector(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "circuit_breaker_transitions_total",
+			Help:      "Circuit breaker state changes by new state.",
+		}, []string{"breaker", "state"})),
+	}
+}
+
+// registerCollector registers c, or returns the collector already registered
+// under the same name so multiple breakers can share a registry
+func registerCollector[C prometheus.Collector](reg prometheus.Registerer, c C) C {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(C); ok {
+				return existing
+			}
+		}
+		panic(fmt.Sprintf("failed to register circuit breaker metrics: %v", err))
+	}
+	return c
+}
@@ -0,0 +1,188 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var errBoom = errors.New("boom")
+
+func newTestBreaker(opts *Options) (*Breaker, *time.Time) {
+	opts.Registerer = prometheus.NewRegistry()
+	b := New("test", opts)
+	now := time.Unix(0, 0)
+	b.now = func() time.Time { return now }
+	b.windowStart = now
+	return b, &now
+}
+
+func TestBreakerStates(t *testing.T) {
+	var changes []State
+	b, now := newTestBreaker(&Options{
+		ConsecutiveFailures: 3,
+		OpenTimeout:         time.Second,
+		OnStateChange:       func(name string, from, to State) { changes = append(changes, to) },
+	})
+	ctx := context.Background()
+	fail := func(ctx context.Context) (int, error) { return 0, errBoom }
+	ok := func(ctx context.Context) (int, error) { return 1, nil }
+
+	for range 2 {
+		_, _ = Execute(ctx, b, fail)
+	}
+	_, _ = Execute(ctx, b, ok)
+	for range 2 {
+		_, _ = Execute(ctx, b, fail)
+	}
+	if b.State() != Closed {
+		t.Fatal("a success should reset consecutive failures")
+	}
+	if _, err := Execute(ctx, b, fail); !errors.Is(err, errBoom) {
+		t.Fatalf("expected fn's error, got %v", err)
+	}
+	if b.State() != Open {
+		t.Fatal("expected breaker to open")
+	}
+	if _, err := Execute(ctx, b, ok); !errors.Is(err, ErrOpen) {
+		t.Fatalf("expected ErrOpen, got %v", err)
+	}
+
+	*now = now.Add(time.Second)
+	if b.State() != HalfOpen {
+		t.Fatal("expected half-open after OpenTimeout")
+	}
+	// a probe failure reopens
+	_, _ = Execute(ctx, b, fail)
+	if b.State() != Open {
+		t.Fatal("expected failed probe to reopen")
+	}
+
+	*now = now.Add(time.Second)
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatal("expected only one probe while half-open")
+	}
+	done(false)
+	if b.State() != Closed {
+		t.Fatal("expected successful probe to close")
+	}
+
+	want := []State{Open, HalfOpen, Open, HalfOpen, Closed}
+	if len(changes) != len(want) {
+		t.Fatalf("unexpected state changes %v", changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Fatalf("unexpected state changes %v", changes)
+		}
+	}
+}
+
+func TestBreakerFailureRatio(t *testing.T) {
+	b, now := newTestBreaker(&Options{ConsecutiveFailures: 100, FailureRatio: 0.5, MinRequests: 4, Interval: time.Minute})
+	ctx := context.Background()
+	record := func(failed bool) {
+		_ = b.Do(ctx, func(ctx context.Context) error {
+			if failed {
+				return errBoom
+			}
+			return nil
+		})
+	}
+
+	record(true)
+	record(false)
+	*now = now.Add(time.Minute)
+	// the new interval starts from zero
+	record(true)
+	record(false)
+	record(false)
+	if b.State() != Closed {
+		t.Fatal("expected closed below MinRequests")
+	}
+	record(true)
+	if b.State() != Open {
+		t.Fatal("expected open at the failure ratio")
+	}
+}
+
+func TestBreakerIgnoresCanceled(t *testing.T) {
+	b, _ := newTestBreaker(&Options{ConsecutiveFailures: 1})
+	_ = b.Do(context.Background(), func(ctx context.Context) error { return context.Canceled })
+	if b.State() != Closed {
+		t.Fatal("context.Canceled should not count as a failure")
+	}
+
+	// a canceled probe frees its slot without closing the breaker
+	b, now := newTestBreaker(&Options{ConsecutiveFailures: 1, OpenTimeout: time.Second})
+	_ = b.Do(context.Background(), func(ctx context.Context) error { return errBoom })
+	*now = now.Add(time.Second)
+	if err := b.Do(context.Background(), func(ctx context.Context) error { return context.Canceled }); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the probe to run, got %v", err)
+	}
+	if b.State() != HalfOpen {
+		t.Fatalf("got %s after a canceled probe, want half-open", b.State())
+	}
+	if err := b.Do(context.Background(), func(ctx context.Context) error { return nil }); err != nil {
+		t.Fatalf("expected another probe to be admitted, got %v", err)
+	}
+	if b.State() != Closed {
+		t.Fatalf("got %s after a successful probe", b.State())
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	b, _ := newTestBreaker(&Options{ConsecutiveFailures: 2})
+	client := &http.Client{Transport: Transport(b, nil)}
+	for range 2 {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if _, err := client.Get(srv.URL); !errors.Is(err, ErrOpen) {
+		t.Fatalf("expected ErrOpen, got %v", err)
+	}
+}
+
+func TestAWSIsFailure(t *testing.T) {
+	response := func(status int) error {
+		return &awshttp.ResponseError{ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+			Err:      errBoom,
+		}}
+	}
+	cases := map[string]struct {
+		err  error
+		want bool
+	}{
+		"nil":          {nil, false},
+		"canceled":     {context.Canceled, false},
+		"send":         {&smithyhttp.RequestSendError{Err: errBoom}, true},
+		"server error": {response(http.StatusInternalServerError), true},
+		"client error": {response(http.StatusBadRequest), false},
+		"other":        {errBoom, false},
+	}
+	for name, tc := range cases {
+		if got := AWSIsFailure(tc.err); got != tc.want {
+			t.Errorf("%s: got %v, want %v", name, got, tc.want)
+		}
+	}
+}
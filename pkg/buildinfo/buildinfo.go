@@ -0,0 +1,158 @@
+// Package buildinfo reports the version, commit and build time of the
+// running binary, from linker flags when set and the module and VCS
+// information Go embeds otherwise:
+//
+//	go build -ldflags "\
+//	  -X github.com/bdlilley/easygo/pkg/buildinfo.version=1.4.2 \
+//	  -X github.com/bdlilley/easygo/pkg/buildinfo.commit=$(git rev-parse HEAD) \
+//	  -X github.com/bdlilley/easygo/pkg/buildinfo.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package buildinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+// set with -ldflags -X; empty values fall back to debug.ReadBuildInfo
+var (
+	version   string
+	commit    string
+	buildTime string
+)
+
+// Info describes the running binary
+type Info struct {
+	// Name is the main package's name, e.g. orders for example.com/orders/cmd/orders
+	Name string `json:"name"`
+	// Version is the release version, the module version, or the short
+	// commit when neither is known
+	Version string `json:"version,omitempty"`
+	Commit  string `json:"commit,omitempty"`
+	// Modified reports uncommitted changes in the build's working tree
+	Modified bool `json:"modified,omitempty"`
+	// BuildTime is when the binary was built, or the commit time when it is
+	// not set at build time
+	BuildTime time.Time `json:"buildTime,omitzero"`
+	// Module is the main module path
+	Module    string `json:"module,omitempty"`
+	GoVersion string `json:"goVersion"`
+}
+
+var get = sync.OnceValue(func() Info {
+	bi, _ := debug.ReadBuildInfo()
+	return read(bi, version, commit, buildTime)
+})
+
+// Get returns the running binary's build information
+func Get() Info {
+	return get()
+}
+
+// read combines linker-provided values with the embedded build info, which
+// may be nil
+func read(bi *debug.BuildInfo, version, commit, buildTime string) Info {
+	info := Info{
+		Name:      strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe"),
+		Version:   version,
+		Commit:    commit,
+		GoVersion: runtime.Version(),
+	}
+	if t, err := time.Parse(time.RFC3339, buildTime); err == nil {
+		info.BuildTime = t
+	}
+	if bi != nil {
+		info.Module = bi.Main.Path
+		if bi.Path != "" {
+			info.Name = path.Base(bi.Path)
+		}
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if t, err := time.Parse(time.RFC3339, s.Value); err == nil && info.BuildTime.IsZero() {
+					info.BuildTime = t
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+	if info.Version == "" && info.Commit != "" {
+		info.Version = info.Commit[:min(12, len(info.Commit))]
+	}
+	return info
+}
+
+// Fields returns the version and commit as log fields
+func (i Info) Fields() logrus.Fields {
+	fields := logrus.Fields{"version": i.Version}
+	if i.Commit != "" {
+		fields["commit"] = i.Commit
+	}
+	return fields
+}
+
+// Attributes returns OpenTelemetry resource attributes for the build: the
+// service version and VCS revision
+func (i Info) Attributes() []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if i.Version != "" {
+		attrs = append(attrs, semconv.ServiceVersion(i.Version))
+	}
+	if i.Commit != "" {
+		attrs = append(attrs, semconv.VCSRefHeadRevision(i.Commit))
+	}
+	return attrs
+}
+
+// fieldsHook adds build fields to entries that do not set them
+type fieldsHook logrus.Fields
+
+func (h fieldsHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h fieldsHook) Fire(entry *logrus.Entry) error {
+	for k, v := range h {
+		if _, ok := entry.Data[k]; !ok {
+			entry.Data[k] = v
+		}
+	}
+	return nil
+}
+
+// InstallFields adds Get().Fields() to every entry l logs
+func InstallFields(l *logrus.Logger) {
+	l.AddHook(fieldsHook(Get().Fields()))
+}
+
+// Handler serves Get() as JSON, e.g. at /version on the admin listener
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Get())
+	})
+}
@@ -0,0 +1,54 @@
+package buildinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime/debug"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestRead(t *testing.T) {
+	bi := &debug.BuildInfo{
+		Path: "example.com/orders/cmd/orders-api",
+		Main: debug.Module{Path: "example.com/orders", Version: "(devel)"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123456789abcdef0123"},
+			{Key: "vcs.time", Value: "2026-01-02T03:04:05Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+	info := read(bi, "", "", "")
+	if info.Name != "orders-api" || info.Version != "0123456789ab" || info.Commit != "0123456789abcdef0123" || !info.Modified || info.BuildTime.Year() != 2026 {
+		t.Fatalf("got %+v", info)
+	}
+
+	// linker flags take precedence
+	info = read(bi, "1.4.2", "feedface", "2026-05-06T07:08:09Z")
+	if info.Version != "1.4.2" || info.Commit != "feedface" || !info.BuildTime.Equal(time.Date(2026, 5, 6, 7, 8, 9, 0, time.UTC)) {
+		t.Fatalf("got %+v", info)
+	}
+	if len(info.Attributes()) != 2 {
+		t.Fatalf("got attributes %v", info.Attributes())
+	}
+}
+
+func TestHandlerAndFields(t *testing.T) {
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+	var info Info
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil || info.GoVersion == "" || info.Name == "" {
+		t.Fatalf("got %s %v", w.Body, err)
+	}
+
+	l, hook := test.NewNullLogger()
+	InstallFields(l)
+	l.WithField("version", "override").Info("a")
+	l.Info("b")
+	if hook.Entries[0].Data["version"] != "override" || hook.Entries[1].Data["version"] != Get().Version {
+		t.Fatalf("got %v and %v", hook.Entries[0].Data, hook.Entries[1].Data)
+	}
+}
@@ -0,0 +1,292 @@
+// Package cache is a generic in-memory cache with TTL expiry, LRU eviction
+// and deduplicated loading.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/clock"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+var errLoaderPanicked = errs.New(errs.Internal, "cache loader panicked")
+
+// EvictReason is why an entry left the cache
+type EvictReason int
+
+const (
+	// EvictExpired is an entry whose TTL passed
+	EvictExpired EvictReason = iota
+	// EvictCapacity is the least recently used entry removed to make room
+	EvictCapacity
+	// EvictDeleted is an entry removed by Delete or Purge
+	EvictDeleted
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case EvictExpired:
+		return "expired"
+	case EvictCapacity:
+		return "capacity"
+	default:
+		return "deleted"
+	}
+}
+
+// Metrics receives cache events, e.g. to update Prometheus counters. Methods
+// are called without the cache lock held.
+type Metrics interface {
+	Hit()
+	Miss()
+	Evicted(reason EvictReason)
+	// Loaded is called after each GetOrLoad loader call
+	Loaded(d time.Duration, err error)
+}
+
+type Options[K comparable, V any] struct {
+	// TTL is how long entries live (default: forever)
+	TTL time.Duration
+	// MaxSize bounds the number of entries, evicting the least recently used
+	// (default: unbounded)
+	MaxSize int
+	// OnEvict is called for every entry that leaves the cache, outside the lock
+	OnEvict func(key K, value V, reason EvictReason)
+	Metrics Metrics
+	// Clock expires entries and times loads (default: clock.Real)
+	Clock clock.Clock
+}
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+type eviction[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictReason
+}
+
+// Cache is safe for concurrent use
+type Cache[K comparable, V any] struct {
+	opts Options[K, V]
+
+	mu    sync.Mutex
+	items map[K]*list.Element
+	lru   *list.List // front is most recently used
+	calls map[K]*call[V]
+}
+
+// New creates a Cache; opts may be nil
+func New[K comparable, V any](opts *Options[K, V]) *Cache[K, V] {
+	c := &Cache[K, V]{
+		items: map[K]*list.Element{},
+		lru:   list.New(),
+		calls: map[K]*call[V]{},
+	}
+	if opts != nil {
+		c.opts = *opts
+	}
+	if c.opts.Clock == nil {
+		c.opts.Clock = clock.Real()
+	}
+	return c
+}
+
+// Get returns the value for key if present and not expired
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	v, ok, evicted := c.get(key)
+	c.mu.Unlock()
+
+	c.notify(evicted)
+	if c.opts.Metrics != nil {
+		if ok {
+			c.opts.Metrics.Hit()
+		} else {
+			c.opts.Metrics.Miss()
+		}
+	}
+	return v, ok
+}
+
+func (c *Cache[K, V]) get(key K) (V, bool, []eviction[K, V]) {
+	var zero V
+	el, ok := c.items[key]
+	if !ok {
+		return zero, false, nil
+	}
+	e := el.Value.(*entry[K, V])
+	if !e.expires.IsZero() && !c.opts.Clock.Now().Before(e.expires) {
+		c.removeElement(el)
+		return zero, false, []eviction[K, V]{{e.key, e.value, EvictExpired}}
+	}
+	c.lru.MoveToFront(el)
+	return e.value, true, nil
+}
+
+// Set stores value for key with the default TTL
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.opts.TTL)
+}
+
+// SetWithTTL stores value for key, expiring after ttl (zero never expires)
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	evicted := c.set(key, value, ttl)
+	c.mu.Unlock()
+	c.notify(evicted)
+}
+
+func (c *Cache[K, V]) set(key K, value V, ttl time.Duration) []eviction[K, V] {
+	var expires time.Time
+	if ttl > 0 {
+		expires = c.opts.Clock.Now().Add(ttl)
+	}
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry[K, V])
+		e.value, e.expires = value, expires
+		c.lru.MoveToFront(el)
+		return nil
+	}
+	c.items[key] = c.lru.PushFront(&entry[K, V]{key: key, value: value, expires: expires})
+
+	var evicted []eviction[K, V]
+	for c.opts.MaxSize > 0 && c.lru.Len() > c.opts.MaxSize {
+		el := c.lru.Back()
+		e := el.Value.(*entry[K, V])
+		c.removeElement(el)
+		evicted = append(evicted, eviction[K, V]{e.key, e.value, EvictCapacity})
+	}
+	return evicted
+}
+
+// Delete removes key
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	var evicted []eviction[K, V]
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry[K, V])
+		c.removeElement(el)
+		evicted = append(evicted, eviction[K, V]{e.key, e.value, EvictDeleted})
+	}
+	c.mu.Unlock()
+	c.notify(evicted)
+}
+
+// Purge removes every entry
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	var evicted []eviction[K, V]
+	for el := c.lru.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*entry[K, V])
+		evicted = append(evicted, eviction[K, V]{e.key, e.value, EvictDeleted})
+	}
+	c.items = map[K]*list.Element{}
+	c.lru.Init()
+	c.mu.Unlock()
+	c.notify(evicted)
+}
+
+// DeleteExpired removes expired entries, which are otherwise only removed
+// when read; run it periodically for caches with many one-off keys
+func (c *Cache[K, V]) DeleteExpired() {
+	c.mu.Lock()
+	now := c.opts.Clock.Now()
+	var evicted []eviction[K, V]
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		e := el.Value.(*entry[K, V])
+		if !e.expires.IsZero() && !now.Before(e.expires) {
+			c.removeElement(el)
+			evicted = append(evicted, eviction[K, V]{e.key, e.value, EvictExpired})
+		}
+		el = next
+	}
+	c.mu.Unlock()
+	c.notify(evicted)
+}
+
+// Len returns the number of entries, including expired ones not yet removed
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// GetOrLoad returns the cached value for key or calls load to fill it.
+// Concurrent calls for the same key share one load; callers whose ctx is done
+// stop waiting, but the load continues with the first caller's context.
+// Errors are returned to every waiter and not cached.
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, load func(ctx context.Context, key K) (V, error)) (V, error) {
+	if v, ok := c.Get(key); ok {
+		return v, nil
+	}
+
+	c.mu.Lock()
+	if cl, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-cl.done:
+			return cl.value, cl.err
+		case <-ctx.Done():
+			var zero V
+			return zero, ctx.Err()
+		}
+	}
+	cl := &call[V]{done: make(chan struct{})}
+	c.calls[key] = cl
+	c.mu.Unlock()
+
+	var evicted []eviction[K, V]
+	finished := false
+	defer func() {
+		if !finished {
+			// the loader panicked; release waiters before re-panicking
+			cl.err = errLoaderPanicked
+		}
+		c.mu.Lock()
+		delete(c.calls, key)
+		if cl.err == nil {
+			evicted = c.set(key, cl.value, c.opts.TTL)
+		}
+		c.mu.Unlock()
+		close(cl.done)
+		c.notify(evicted)
+	}()
+
+	start := c.opts.Clock.Now()
+	cl.value, cl.err = load(ctx, key)
+	finished = true
+	if c.opts.Metrics != nil {
+		c.opts.Metrics.Loaded(c.opts.Clock.Since(start), cl.err)
+	}
+	return cl.value, cl.err
+}
+
+func (c *Cache[K, V]) removeElement(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.items, el.Value.(*entry[K, V]).key)
+}
+
+func (c *Cache[K, V]) notify(evicted []eviction[K, V]) {
+	for _, e := range evicted {
+		if c.opts.Metrics != nil {
+			c.opts.Metrics.Evicted(e.reason)
+		}
+		if c.opts.OnEvict != nil {
+			c.opts.OnEvict(e.key, e.value, e.reason)
+		}
+	}
+}
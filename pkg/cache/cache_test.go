@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/clock"
+)
+
+func TestCacheTTLAndLRU(t *testing.T) {
+	clk := clock.NewFake(time.Unix(1000, 0))
+	var evicted []string
+	c := New(&Options[string, int]{
+		TTL:     time.Minute,
+		MaxSize: 2,
+		OnEvict: func(k string, _ int, r EvictReason) { evicted = append(evicted, k+":"+r.String()) },
+		Clock:   clk,
+	})
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3) // evicts b, the least recently used
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("expected b to be evicted")
+	}
+
+	clk.Advance(time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expected a to expire")
+	}
+	if len(evicted) != 2 || evicted[0] != "b:capacity" || evicted[1] != "a:expired" {
+		t.Fatalf("evicted = %v", evicted)
+	}
+}
+
+func TestGetOrLoadDeduplicates(t *testing.T) {
+	c := New[string, int](nil)
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func(context.Context, string) (int, error) {
+		loads.Add(1)
+		<-release
+		return 7, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := c.GetOrLoad(context.Background(), "k", load); err != nil || v != 7 {
+				t.Errorf("GetOrLoad = %d, %v", v, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if loads.Load() != 1 {
+		t.Fatalf("loader called %d times, want 1", loads.Load())
+	}
+}
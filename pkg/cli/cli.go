@@ -0,0 +1,176 @@
+// Package cli scaffolds cobra command-line tools with the standard easygo
+// flags: it builds the logger, configuration and EGAwsClient from them and
+// makes them available to every subcommand.
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/bdlilley/easygo"
+	"github.com/bdlilley/easygo/pkg/config"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/bdlilley/easygo/pkg/runtimeinfo"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type NewRootCommandArgs struct {
+	// Use, Short, Long and Version are passed to the cobra root command
+	Use     string
+	Short   string
+	Long    string
+	Version string
+	// Config, a pointer to a struct, is loaded with pkg/config from the
+	// --config files and the environment before any subcommand runs
+	Config any
+	// EnvPrefix prefixes the environment variables read into Config
+	EnvPrefix string
+	// AwsArgs are the base arguments for the AWS client; --region and
+	// --profile override Region and Profile (default: LazyInit)
+	AwsArgs *easygo.NewEGAwsClientArgs
+}
+
+// Env holds what the root command built from its flags
+type Env struct {
+	// Logger logs in --log-format at --log-level, tagged with runtimeinfo fields
+	Logger *logrus.Logger
+	// Config is NewRootCommandArgs.Config after loading
+	Config any
+
+	awsArgs easygo.NewEGAwsClientArgs
+	awsOnce sync.Once
+	aws     *easygo.EGAwsClient
+	awsErr  error
+}
+
+// AwsClient builds the AWS client on first use, so commands that do not call
+// AWS work without credentials
+func (e *Env) AwsClient(ctx context.Context) (*easygo.EGAwsClient, error) {
+	e.awsOnce.Do(func() {
+		args := e.awsArgs
+		args.Logger = e.Logger
+		e.aws, e.awsErr = easygo.NewAwsClient(ctx, &args)
+	})
+	return e.aws, e.awsErr
+}
+
+type envKey struct{}
+
+// FromContext returns the Env of the running command; use cmd.Context() in
+// RunE. It is nil outside a command created by NewRootCommand.
+func FromContext(ctx context.Context) *Env {
+	env, _ := ctx.Value(envKey{}).(*Env)
+	return env
+}
+
+// flags holds the standard flag values
+type flags struct {
+	logLevel    string
+	logFormat   string
+	configFiles []string
+	region      string
+	profile     string
+}
+
+// NewRootCommand creates a root command with the persistent flags
+//
+//	--log-level   logrus level (default: info)
+//	--log-format  json, text or ecs (default: text)
+//	--config      configuration file, repeatable; later files override earlier ones
+//	--region      AWS region
+//	--profile     AWS shared config profile
+//
+// Its PersistentPreRunE builds the Env before any subcommand runs. A
+// subcommand that sets its own PersistentPreRunE must call the root's first.
+func NewRootCommand(args *NewRootCommandArgs) *cobra.Command {
+	if args == nil {
+		args = &NewRootCommandArgs{}
+	}
+	f := &flags{}
+	cmd := &cobra.Command{
+		Use:           args.Use,
+		Short:         args.Short,
+		Long:          args.Long,
+		Version:       args.Version,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			env, err := newEnv(cmd.Context(), args, f)
+			if err != nil {
+				return err
+			}
+			ctx := context.WithValue(cmd.Context(), envKey{}, env)
+			cmd.SetContext(logging.WithContext(ctx, env.Logger))
+			return nil
+		},
+	}
+
+	pf := cmd.PersistentFlags()
+	pf.StringVar(&f.logLevel, "log-level", "info", "log level (trace, debug, info, warn, error)")
+	pf.StringVar(&f.logFormat, "log-format", "text", "log format (json, text, ecs)")
+	pf.StringArrayVar(&f.configFiles, "config", nil, "configuration file (repeatable)")
+	pf.StringVar(&f.region, "region", "", "AWS region")
+	pf.StringVar(&f.profile, "profile", "", "AWS shared config profile")
+	return cmd
+}
+
+func newEnv(ctx context.Context, args *NewRootCommandArgs, f *flags) (*Env, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+	switch f.logFormat {
+	case "json":
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	case "text":
+		logger.SetFormatter(&logrus.TextFormatter{})
+	case "ecs":
+		logger.SetFormatter(&logging.ECSFormatter{})
+	default:
+		return nil, errs.Newf(errs.Internal, "invalid --log-format %q", f.logFormat)
+	}
+	level, err := logrus.ParseLevel(f.logLevel)
+	if err != nil {
+		return nil, errs.Errorf("invalid --log-level: %w", err)
+	}
+	logger.SetLevel(level)
+	runtimeinfo.InstallFields(logger)
+
+	env := &Env{Logger: logger, Config: args.Config, awsArgs: easygo.NewEGAwsClientArgs{LazyInit: true}}
+	if args.AwsArgs != nil {
+		env.awsArgs = *args.AwsArgs
+	}
+	if f.region != "" {
+		env.awsArgs.Region = f.region
+	}
+	if f.profile != "" {
+		env.awsArgs.Profile = f.profile
+	}
+
+	if args.Config != nil {
+		err := config.LoadContext(ctx, args.Config, &config.LoadArgs{Files: f.configFiles, EnvPrefix: args.EnvPrefix})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return env, nil
+}
+
+// Execute runs cmd with a context cancelled on SIGINT or SIGTERM, printing
+// the error and exiting with status 1 when the command fails
+func Execute(cmd *cobra.Command) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	err := cmd.ExecuteContext(ctx)
+	stop()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
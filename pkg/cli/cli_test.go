@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func TestRootCommand(t *testing.T) {
+	type appConfig struct {
+		Bucket string `json:"bucket" env:"BUCKET"`
+	}
+	file := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(file, []byte(`{"bucket": "from-file"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &appConfig{}
+	root := NewRootCommand(&NewRootCommandArgs{Use: "tool", Config: cfg})
+	var env *Env
+	root.AddCommand(&cobra.Command{
+		Use: "sync",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			env = FromContext(cmd.Context())
+			return nil
+		},
+	})
+	root.SetArgs([]string{"sync", "--log-level", "debug", "--config", file, "--region", "eu-west-1"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if env == nil {
+		t.Fatal("expected env in subcommand context")
+	}
+	if env.Logger.GetLevel() != logrus.DebugLevel {
+		t.Fatalf("expected debug level, got %s", env.Logger.GetLevel())
+	}
+	if cfg.Bucket != "from-file" {
+		t.Fatalf("expected config from file, got %q", cfg.Bucket)
+	}
+	if env.awsArgs.Region != "eu-west-1" || !env.awsArgs.LazyInit {
+		t.Fatalf("unexpected aws args %+v", env.awsArgs)
+	}
+
+	root.SetArgs([]string{"sync", "--log-format", "xml"})
+	if err := root.Execute(); err == nil {
+		t.Fatal("expected invalid log format error")
+	}
+}
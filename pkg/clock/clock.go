@@ -0,0 +1,56 @@
+// Package clock abstracts time so code that waits, expires or schedules can
+// be tested without sleeping. Production code takes a Clock and defaults to
+// Real; tests pass a Fake and move it forward with Advance:
+//
+//	clk := clock.NewFake(time.Unix(0, 0))
+//	go s.Start(ctx)
+//	clk.BlockUntil(1) // the job loop is waiting
+//	clk.Advance(time.Minute)
+package clock
+
+import (
+	"time"
+)
+
+// Clock tells the time and waits
+type Clock interface {
+	Now() time.Time
+	// Since returns the time elapsed since t
+	Since(t time.Time) time.Duration
+	// After returns a channel that receives the time once d has elapsed
+	After(d time.Duration) <-chan time.Time
+	// NewTicker returns a Ticker firing every d, which must be positive
+	NewTicker(d time.Duration) Ticker
+	// Sleep blocks for d
+	Sleep(d time.Duration)
+}
+
+// Ticker delivers ticks at intervals, like time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	// Reset stops the ticker and restarts it with period d
+	Reset(d time.Duration)
+	Stop()
+}
+
+// Real returns the Clock backed by package time
+func Real() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
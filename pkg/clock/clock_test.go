@@ -0,0 +1,55 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Unix(1000, 0)
+	f := NewFake(start)
+	after := f.After(time.Minute)
+	ticker := f.NewTicker(30 * time.Second)
+	slept := make(chan time.Time)
+	go func() {
+		f.Sleep(2 * time.Minute)
+		slept <- f.Now()
+	}()
+	f.BlockUntil(3)
+
+	f.Advance(45 * time.Second)
+	if got := <-ticker.C(); !got.Equal(start.Add(30 * time.Second)) {
+		t.Fatalf("got tick at %s", got)
+	}
+	select {
+	case <-after:
+		t.Fatal("After fired early")
+	default:
+	}
+
+	// ticks the receiver missed are dropped, as with time.Ticker
+	f.Advance(75 * time.Second)
+	if got := <-after; !got.Equal(start.Add(time.Minute)) {
+		t.Fatalf("got After at %s", got)
+	}
+	if got := <-ticker.C(); !got.Equal(start.Add(time.Minute)) {
+		t.Fatalf("got tick at %s", got)
+	}
+	if got := <-slept; !got.Equal(start.Add(2 * time.Minute)) {
+		t.Fatalf("woke at %s", got)
+	}
+	if f.Since(start) != 2*time.Minute || f.Waiters() != 1 {
+		t.Fatalf("got %s since start with %d waiters", f.Since(start), f.Waiters())
+	}
+
+	ticker.Stop()
+	f.Advance(time.Hour)
+	select {
+	case <-ticker.C():
+		t.Fatal("stopped ticker fired")
+	default:
+	}
+	if f.Waiters() != 0 {
+		t.Fatalf("got %d waiters", f.Waiters())
+	}
+}
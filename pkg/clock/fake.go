@@ -0,0 +1,166 @@
+package clock
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Fake is a Clock that only moves when told to. Its waiters fire, in
+// deadline order, as Advance or Set moves it past their deadlines. It is
+// safe for concurrent use.
+type Fake struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*waiter
+}
+
+// waiter is a pending After or Sleep, or a running ticker when period is set
+type waiter struct {
+	when   time.Time
+	period time.Duration
+	c      chan time.Time
+}
+
+// NewFake returns a Fake set to t
+func NewFake(t time.Time) *Fake {
+	f := &Fake{now: t}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := &waiter{when: f.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.c <- f.now
+		return w.c
+	}
+	f.add(w)
+	return w.c
+}
+
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := &waiter{when: f.now.Add(d), period: d, c: make(chan time.Time, 1)}
+	f.add(w)
+	return &fakeTicker{f: f, w: w}
+}
+
+// Advance moves the clock forward by d, firing the waiters it passes
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	target := f.now.Add(d)
+	f.mu.Unlock()
+	f.Set(target)
+}
+
+// Set moves the clock to t, firing the waiters due by then. Setting it
+// backwards fires nothing.
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.waiters) > 0 && !f.waiters[0].when.After(t) {
+		w := f.waiters[0]
+		f.waiters = f.waiters[1:]
+		if w.when.After(f.now) {
+			f.now = w.when
+		}
+		// like time.Ticker, ticks are dropped while the receiver is behind
+		select {
+		case w.c <- f.now:
+		default:
+		}
+		if w.period > 0 {
+			w.when = w.when.Add(w.period)
+			f.add(w)
+		}
+	}
+	f.now = t
+}
+
+// Waiters returns the number of pending After and Sleep calls plus running
+// tickers
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+// BlockUntil waits until at least n After or Sleep calls or tickers are
+// waiting, so a test advances the clock only once the code under test is
+// waiting on it
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.waiters) < n {
+		f.cond.Wait()
+	}
+}
+
+// add inserts w in deadline order; f.mu must be held
+func (f *Fake) add(w *waiter) {
+	i, _ := slices.BinarySearchFunc(f.waiters, w.when, func(w *waiter, t time.Time) int {
+		if w.when.After(t) {
+			return 1
+		}
+		// waiters with equal deadlines fire in the order they were added
+		return -1
+	})
+	f.waiters = slices.Insert(f.waiters, i, w)
+	f.cond.Broadcast()
+}
+
+// remove drops w if it is pending; f.mu must be held
+func (f *Fake) remove(w *waiter) {
+	if i := slices.Index(f.waiters, w); i >= 0 {
+		f.waiters = slices.Delete(f.waiters, i, i+1)
+	}
+}
+
+type fakeTicker struct {
+	f *Fake
+	w *waiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.w.c
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Ticker.Reset")
+	}
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.f.remove(t.w)
+	t.w.period = d
+	t.w.when = t.f.now.Add(d)
+	t.f.add(t.w)
+}
+
+func (t *fakeTicker) Stop() {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.f.remove(t.w)
+}
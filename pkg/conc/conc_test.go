@@ -0,0 +1,136 @@
+package conc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKeyedMutex(t *testing.T) {
+	ctx := context.Background()
+	m := NewKeyedMutex[string]()
+
+	unlock, err := m.Lock(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.TryLock("a"); ok {
+		t.Fatal("expected a to be locked")
+	}
+	unlockB, ok := m.TryLock("b")
+	if !ok {
+		t.Fatal("expected b to be independent of a")
+	}
+	unlockB()
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := m.Lock(timeout, "a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want DeadlineExceeded", err)
+	}
+	unlock()
+	unlock()
+	if n := m.sem.Len(); n != 0 {
+		t.Fatalf("got %d keys after unlocking, want 0", n)
+	}
+}
+
+func TestKeyedSemaphore(t *testing.T) {
+	ctx := context.Background()
+	s := NewKeyedSemaphore[string](2)
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			release, err := s.Acquire(ctx, "tenant", 1)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer release()
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+		})
+	}
+	wg.Wait()
+	if peak.Load() != 2 {
+		t.Fatalf("got peak concurrency %d, want 2", peak.Load())
+	}
+	if _, err := s.Acquire(ctx, "tenant", 3); !errors.Is(err, ErrWeightExceedsSize) {
+		t.Fatalf("got %v, want ErrWeightExceedsSize", err)
+	}
+	if s.Len() != 0 {
+		t.Fatalf("got %d keys, want 0", s.Len())
+	}
+}
+
+func TestGroup(t *testing.T) {
+	ctx := context.Background()
+	var g Group[string, int]
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fn := func(context.Context) (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make(chan bool, 5)
+	for range 5 {
+		wg.Go(func() {
+			v, shared, err := g.Do(ctx, "k", fn)
+			if err != nil || v != 42 {
+				t.Errorf("got %d %v", v, err)
+			}
+			results <- shared
+		})
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+	if calls.Load() != 1 {
+		t.Fatalf("got %d calls, want 1", calls.Load())
+	}
+	for shared := range results {
+		if !shared {
+			t.Fatal("expected every result to be shared")
+		}
+	}
+
+	// the call is canceled once every caller gave up
+	canceled := make(chan struct{})
+	callerCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, _, err := g.Do(callerCtx, "slow", func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		close(canceled)
+		return 0, ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want Canceled", err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("expected the call to be canceled")
+	}
+
+	if _, _, err := g.Do(ctx, "panic", func(context.Context) (int, error) { panic("boom") }); err == nil {
+		t.Fatal("expected the panic as an error")
+	}
+}
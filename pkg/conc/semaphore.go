@@ -0,0 +1,154 @@
+// Package conc has concurrency primitives services keep rewriting: weighted
+// semaphores, mutexes and semaphores scoped to a key such as a tenant or a
+// secret name, and a generic singleflight Group.
+package conc
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"golang.org/x/sync/semaphore"
+)
+
+// ErrWeightExceedsSize is returned when acquiring more than a semaphore's
+// size, which could never succeed
+var ErrWeightExceedsSize = errs.New(errs.InvalidArgument, "conc: weight exceeds semaphore size")
+
+// Semaphore bounds the total weight of work in progress. Waiters are served
+// in order, so a large acquisition is not starved by smaller ones.
+type Semaphore struct {
+	size int64
+	sem  *semaphore.Weighted
+}
+
+// NewSemaphore creates a semaphore of size
+func NewSemaphore(size int64) *Semaphore {
+	return &Semaphore{size: size, sem: semaphore.NewWeighted(size)}
+}
+
+// Size returns the semaphore's size
+func (s *Semaphore) Size() int64 {
+	return s.size
+}
+
+// Acquire blocks until n is available or ctx is done
+func (s *Semaphore) Acquire(ctx context.Context, n int64) error {
+	if n > s.size {
+		return ErrWeightExceedsSize
+	}
+	return s.sem.Acquire(ctx, n)
+}
+
+// TryAcquire acquires n if it is available without blocking
+func (s *Semaphore) TryAcquire(n int64) bool {
+	return s.sem.TryAcquire(n)
+}
+
+// Release returns n acquired earlier
+func (s *Semaphore) Release(n int64) {
+	s.sem.Release(n)
+}
+
+// KeyedSemaphore is a semaphore of the same size per key, e.g. to limit
+// concurrent requests per tenant. Keys take no memory while nothing holds or
+// waits for them.
+type KeyedSemaphore[K comparable] struct {
+	size int64
+
+	mu   sync.Mutex
+	keys map[K]*keyedEntry
+}
+
+type keyedEntry struct {
+	sem *semaphore.Weighted
+	// refs counts holders and waiters, so the entry is dropped when unused
+	refs int
+}
+
+// NewKeyedSemaphore creates a KeyedSemaphore with size per key
+func NewKeyedSemaphore[K comparable](size int64) *KeyedSemaphore[K] {
+	return &KeyedSemaphore[K]{size: size, keys: map[K]*keyedEntry{}}
+}
+
+// Acquire blocks until n is available for key or ctx is done. The returned
+// release function may be called more than once.
+func (s *KeyedSemaphore[K]) Acquire(ctx context.Context, key K, n int64) (release func(), err error) {
+	if n > s.size {
+		return nil, ErrWeightExceedsSize
+	}
+	e := s.ref(key)
+	if err := e.sem.Acquire(ctx, n); err != nil {
+		s.unref(key, e)
+		return nil, err
+	}
+	return s.releaser(key, e, n), nil
+}
+
+// TryAcquire acquires n for key if it is available without blocking
+func (s *KeyedSemaphore[K]) TryAcquire(key K, n int64) (release func(), ok bool) {
+	e := s.ref(key)
+	if !e.sem.TryAcquire(n) {
+		s.unref(key, e)
+		return nil, false
+	}
+	return s.releaser(key, e, n), true
+}
+
+// Len returns the number of keys held or waited for
+func (s *KeyedSemaphore[K]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.keys)
+}
+
+func (s *KeyedSemaphore[K]) ref(key K) *keyedEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.keys[key]
+	if !ok {
+		e = &keyedEntry{sem: semaphore.NewWeighted(s.size)}
+		s.keys[key] = e
+	}
+	e.refs++
+	return e
+}
+
+func (s *KeyedSemaphore[K]) unref(key K, e *keyedEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e.refs--; e.refs == 0 {
+		delete(s.keys, key)
+	}
+}
+
+func (s *KeyedSemaphore[K]) releaser(key K, e *keyedEntry, n int64) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			e.sem.Release(n)
+			s.unref(key, e)
+		})
+	}
+}
+
+// KeyedMutex is a mutex per key, e.g. to serialize refreshes of each secret
+type KeyedMutex[K comparable] struct {
+	sem *KeyedSemaphore[K]
+}
+
+// NewKeyedMutex creates a KeyedMutex
+func NewKeyedMutex[K comparable]() *KeyedMutex[K] {
+	return &KeyedMutex[K]{sem: NewKeyedSemaphore[K](1)}
+}
+
+// Lock blocks until key is locked or ctx is done. The returned unlock
+// function may be called more than once.
+func (m *KeyedMutex[K]) Lock(ctx context.Context, key K) (unlock func(), err error) {
+	return m.sem.Acquire(ctx, key, 1)
+}
+
+// TryLock locks key if it is not locked
+func (m *KeyedMutex[K]) TryLock(key K) (unlock func(), ok bool) {
+	return m.sem.TryAcquire(key, 1)
+}
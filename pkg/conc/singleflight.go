@@ -0,0 +1,88 @@
+package conc
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// Group deduplicates concurrent calls by key: while a call for a key is in
+// flight, later callers wait for its result instead of starting their own.
+// The zero value is ready to use.
+type Group[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*flight[V]
+}
+
+type flight[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+	// waiters counts callers still waiting; the call's context is canceled
+	// when the last one gives up
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// Do calls fn once for all concurrent callers with the same key and returns
+// its result to each; shared reports whether the result went to more than
+// one caller. fn runs with the first caller's context values but is only
+// canceled once every waiting caller's ctx is done; a caller whose ctx is done
+// stops waiting with ctx's error.
+func (g *Group[K, V]) Do(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) (v V, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[K]*flight[V]{}
+	}
+	f, ok := g.calls[key]
+	if ok {
+		f.waiters++
+	} else {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight[V]{done: make(chan struct{}), waiters: 1, cancel: cancel}
+		g.calls[key] = f
+		go g.run(callCtx, key, f, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		g.mu.Lock()
+		shared = ok || f.waiters > 1
+		g.mu.Unlock()
+		return f.value, shared, f.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		if f.waiters--; f.waiters == 0 {
+			f.cancel()
+		}
+		g.mu.Unlock()
+		var zero V
+		return zero, ok, ctx.Err()
+	}
+}
+
+// Forget makes the next call for key start a new call even if one is in
+// flight
+func (g *Group[K, V]) Forget(key K) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.calls, key)
+}
+
+func (g *Group[K, V]) run(ctx context.Context, key K, f *flight[V], fn func(ctx context.Context) (V, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			f.err = errs.Newf(errs.Internal, "conc: panic in call for %v: %v", key, r)
+		}
+		g.mu.Lock()
+		if g.calls[key] == f {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+		f.cancel()
+		close(f.done)
+	}()
+	f.value, f.err = fn(ctx)
+}
@@ -0,0 +1,312 @@
+// Package config populates a struct from defaults, YAML or JSON files and
+// environment variables, in that order of precedence, using struct tags:
+//
+//	type Config struct {
+//		Port     int           `env:"PORT" default:"8080" yaml:"port"`
+//		Timeout  time.Duration `env:"TIMEOUT" default:"30s" yaml:"timeout"`
+//		Database struct {
+//			URL string `env:"URL" required:"true" yaml:"url"`
+//		} `envPrefix:"DB_" yaml:"database"`
+//	}
+//
+// String fields holding secretsmanager://name#key, ssm:///parameter/name or
+// secret://name#key references are replaced with the referenced value; see
+// LoadArgs.Secrets and LoadArgs.SecretProvider.
+//
+// Fields may also carry validate tags, such as validate:"url" or
+// validate:"aws_region", and the struct may implement validate.Validator;
+// see pkg/validate. They are checked after the required tags pass.
+//
+// Supported field types are strings, bools, integers, floats, time.Duration,
+// slices (comma separated), map[string]string (comma separated key=value
+// pairs), encoding.TextUnmarshaler implementations and pointers to these.
+package config
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bdlilley/easygo"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/secrets"
+	"github.com/bdlilley/easygo/pkg/validate"
+	"gopkg.in/yaml.v3"
+)
+
+type LoadArgs struct {
+	// Files are decoded in order, later files overriding earlier ones. The
+	// format follows the extension: .yaml, .yml or .json.
+	Files []string
+	// IgnoreMissingFiles skips Files that do not exist
+	IgnoreMissingFiles bool
+	// EnvPrefix is prepended to every env tag, e.g. "ORDERS_"
+	EnvPrefix string
+	// LookupEnv reads environment variables (default: os.LookupEnv)
+	LookupEnv func(key string) (string, bool)
+	// Secrets resolves secretsmanager://name-or-arn#jsonKey references; the
+	// key is optional and selects a field of a JSON secret. Typically an
+	// EGAwsClient.
+	Secrets easygo.SecretsReader
+	// Parameters resolves ssm:///parameter/name references, typically an EGAwsClient
+	Parameters easygo.ParameterReader
+	// SecretProvider resolves secret://name#key references, so the backend
+	// can differ per environment, e.g. secrets.NewFiles locally and
+	// secrets.NewSecretsManager in production
+	SecretProvider secrets.Provider
+}
+
+// Load populates dst, a pointer to a struct. Defaults apply only to fields
+// that are still zero, so values set before Load are kept unless a file or
+// the environment overrides them. Every invalid or missing field is reported
+// in the returned error.
+func Load(dst any, args *LoadArgs) error {
+	return LoadContext(context.Background(), dst, args)
+}
+
+// LoadContext is Load with a context for resolving secret references
+func LoadContext(ctx context.Context, dst any, args *LoadArgs) error {
+	if args == nil {
+		args = &LoadArgs{}
+	}
+	lookup := args.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+
+	root := reflect.ValueOf(dst)
+	if root.Kind() != reflect.Pointer || root.IsNil() || root.Elem().Kind() != reflect.Struct {
+		return errs.Newf(errs.Internal, "config: dst must be a non-nil pointer to a struct, got %T", dst)
+	}
+
+	var failures []error
+	walk(root.Elem(), "", args.EnvPrefix, func(f field) {
+		if def, ok := f.tag.Lookup("default"); ok && f.value.IsZero() {
+			if err := setValue(f.value, def); err != nil {
+				failures = append(failures, errs.Errorf("config: invalid default for %s: %w", f.path, err))
+			}
+		}
+	})
+
+	for _, file := range args.Files {
+		if err := decodeFile(file, dst); err != nil {
+			if args.IgnoreMissingFiles && errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			failures = append(failures, err)
+		}
+	}
+
+	walk(root.Elem(), "", args.EnvPrefix, func(f field) {
+		if f.env == "" {
+			return
+		}
+		if v, ok := lookup(f.env); ok {
+			if err := setValue(f.value, v); err != nil {
+				failures = append(failures, errs.Errorf("config: invalid %s for %s: %w", f.env, f.path, err))
+			}
+		}
+	})
+
+	res := &resolver{secrets: args.Secrets, parameters: args.Parameters, provider: args.SecretProvider, cache: map[string]string{}}
+	walk(root.Elem(), "", args.EnvPrefix, func(f field) {
+		if err := res.resolveField(ctx, f); err != nil {
+			failures = append(failures, errs.Errorf("config: failed to resolve %s: %w", f.path, err))
+		}
+	})
+
+	walk(root.Elem(), "", args.EnvPrefix, func(f field) {
+		if f.tag.Get("required") == "true" && f.value.IsZero() {
+			if f.env != "" {
+				failures = append(failures, errs.Newf(errs.Internal, "config: %s is required (set %s)", f.path, f.env))
+			} else {
+				failures = append(failures, errs.Newf(errs.Internal, "config: %s is required", f.path))
+			}
+		}
+	})
+
+	if len(failures) > 0 {
+		return errors.Join(failures...)
+	}
+	// validate tags and Validate methods run once every field is populated
+	if err := validate.Struct(dst); err != nil {
+		var verrs validate.Errors
+		if !errors.As(err, &verrs) {
+			return errs.Errorf("config: validation failed: %w", err)
+		}
+		for _, fe := range verrs {
+			failures = append(failures, errs.Newf(errs.Internal, "config: %s %s", fe.Field, fe.Message))
+		}
+	}
+	return errors.Join(failures...)
+}
+
+// field is a settable leaf field found by walk
+type field struct {
+	value reflect.Value
+	tag   reflect.StructTag
+	// path is the dotted Go field path, e.g. Database.URL
+	path string
+	// env is the prefixed variable name, or empty without an env tag
+	env string
+}
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// walk calls fn for every exported leaf field of v, recursing into nested
+// structs that are not TextUnmarshalers
+func walk(v reflect.Value, path, envPrefix string, fn func(field)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		name := sf.Name
+		if path != "" {
+			name = path + "." + sf.Name
+		}
+
+		if isNestedStruct(sf.Type) {
+			if sf.Type.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					fv.Set(reflect.New(sf.Type.Elem()))
+				}
+				fv = fv.Elem()
+			}
+			walk(fv, name, envPrefix+sf.Tag.Get("envPrefix"), fn)
+			continue
+		}
+
+		f := field{value: fv, tag: sf.Tag, path: name}
+		if env := sf.Tag.Get("env"); env != "" {
+			f.env = envPrefix + env
+		}
+		fn(f)
+	}
+}
+
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == reflect.TypeFor[time.Time]() {
+		return false
+	}
+	return !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// setValue parses s into v
+func setValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValue(v.Elem(), s)
+	}
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(s))
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == reflect.TypeFor[time.Duration]() {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return err
+			}
+			v.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		parts := splitList(s)
+		slice := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			if err := setValue(slice.Index(i), p); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.Type().Elem().Kind() != reflect.String {
+			return errs.Newf(errs.Internal, "unsupported map type %s", v.Type())
+		}
+		m := reflect.MakeMap(v.Type())
+		for _, p := range splitList(s) {
+			k, val, ok := strings.Cut(p, "=")
+			if !ok {
+				return errs.Newf(errs.Internal, "expected key=value, got %q", p)
+			}
+			m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(k)).Convert(v.Type().Key()),
+				reflect.ValueOf(strings.TrimSpace(val)).Convert(v.Type().Elem()))
+		}
+		v.Set(m)
+	default:
+		return errs.Newf(errs.Internal, "unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func decodeFile(path string, dst any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errs.Errorf("config: failed to read %s: %w", path, err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, dst)
+	case ".json":
+		err = json.Unmarshal(data, dst)
+	default:
+		return errs.Newf(errs.Internal, "config: unsupported file type %s", path)
+	}
+	if err != nil {
+		return errs.Errorf("config: failed to decode %s: %w", path, err)
+	}
+	return nil
+}
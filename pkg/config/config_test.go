@@ -0,0 +1,99 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/egaws/egawstest"
+	"github.com/bdlilley/easygo/pkg/secrets"
+)
+
+type testConfig struct {
+	Port    int               `env:"PORT" default:"8080" yaml:"port"`
+	Timeout time.Duration     `env:"TIMEOUT" default:"30s" yaml:"timeout"`
+	Hosts   []string          `env:"HOSTS" yaml:"hosts"`
+	Tags    map[string]string `env:"TAGS"`
+	DB      struct {
+		URL      string `env:"URL" required:"true" yaml:"url"`
+		Password string `env:"PASSWORD" required:"true" yaml:"password"`
+	} `envPrefix:"DB_" yaml:"db"`
+}
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte("port: 9000\ndb:\n  url: postgres://file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"APP_DB_URL": "postgres://env",
+		"APP_HOSTS":  "a, b",
+		"APP_TAGS":   "team=core,tier=1",
+	}
+	lookup := func(k string) (string, bool) { v, ok := env[k]; return v, ok }
+
+	cfg := testConfig{}
+	err := Load(&cfg, &LoadArgs{Files: []string{file}, EnvPrefix: "APP_", LookupEnv: lookup})
+	if err == nil || !strings.Contains(err.Error(), "DB.Password is required (set APP_DB_PASSWORD)") {
+		t.Fatalf("err = %v, want missing DB.Password", err)
+	}
+	if cfg.Port != 9000 || cfg.Timeout != 30*time.Second || cfg.DB.URL != "postgres://env" {
+		t.Errorf("cfg = %+v", cfg)
+	}
+	if len(cfg.Hosts) != 2 || cfg.Hosts[1] != "b" || cfg.Tags["tier"] != "1" {
+		t.Errorf("hosts = %v, tags = %v", cfg.Hosts, cfg.Tags)
+	}
+
+	env["APP_PORT"] = "nope"
+	env["APP_DB_PASSWORD"] = "x"
+	err = Load(&testConfig{}, &LoadArgs{EnvPrefix: "APP_", LookupEnv: lookup})
+	if err == nil || !strings.Contains(err.Error(), "invalid APP_PORT for Port") {
+		t.Fatalf("err = %v, want invalid APP_PORT", err)
+	}
+}
+
+func TestLoadValidates(t *testing.T) {
+	var cfg struct {
+		Region string `env:"REGION" default:"us-east-1" validate:"aws_region"`
+		Queue  string `env:"QUEUE_ARN" validate:"omitempty,arn"`
+	}
+	env := map[string]string{"QUEUE_ARN": "jobs"}
+	lookup := func(k string) (string, bool) { v, ok := env[k]; return v, ok }
+	err := Load(&cfg, &LoadArgs{LookupEnv: lookup})
+	if err == nil || err.Error() != "config: Queue must be a valid ARN" {
+		t.Fatalf("err = %v, want invalid Queue", err)
+	}
+}
+
+func TestLoadResolvesReferences(t *testing.T) {
+	store := egawstest.NewSecretsStore()
+	store.SetSecret("db", `{"password":"s3cret","port":5432}`)
+	params := &egawstest.ParameterStore{}
+	params.SetParameter("/app/api-key", "key-1")
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app"), []byte(`{"token":"t-1"}`+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := struct {
+		Password string `env:"DB_PASSWORD"`
+		Port     string `default:"secretsmanager://db#port"`
+		APIKey   string `env:"API_KEY" required:"true"`
+		Token    string `default:"secret://app#token"`
+	}{}
+	env := map[string]string{"DB_PASSWORD": "secretsmanager://db#password", "API_KEY": "ssm:///app/api-key"}
+	err := Load(&cfg, &LoadArgs{
+		LookupEnv:      func(k string) (string, bool) { v, ok := env[k]; return v, ok },
+		Secrets:        store,
+		Parameters:     params,
+		SecretProvider: secrets.NewFiles(dir, nil),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Password != "s3cret" || cfg.Port != "5432" || cfg.APIKey != "key-1" || cfg.Token != "t-1" {
+		t.Fatalf("cfg = %+v", cfg)
+	}
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/logging"
+)
+
+type NewReloaderArgs struct {
+	Load *LoadArgs
+	// Interval is how often the configuration is reloaded (default: 5m)
+	Interval time.Duration
+	// ErrorHandler receives failed reloads; the previous value is kept
+	// (default: log them to Logger)
+	ErrorHandler func(error)
+	// Logger logs failed reloads at error without an ErrorHandler (default: logging.Noop)
+	Logger logging.Logger
+}
+
+// Reloader loads a T and reloads it periodically, e.g. to pick up rotated
+// secrets. Each load builds a new value, so values returned by Get are never
+// modified and are safe to share.
+type Reloader[T any] struct {
+	args     *LoadArgs
+	errorFn  func(error)
+	current  atomic.Pointer[T]
+	mu       sync.Mutex
+	onChange []func(*T)
+
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// NewReloader loads the initial value, failing if it is invalid, and starts reloading
+func NewReloader[T any](ctx context.Context, args *NewReloaderArgs) (*Reloader[T], error) {
+	if args == nil {
+		args = &NewReloaderArgs{}
+	}
+	r := &Reloader[T]{
+		args:    args.Load,
+		errorFn: args.ErrorHandler,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if r.errorFn == nil {
+		logger := args.Logger
+		if logger == nil {
+			logger = logging.Noop()
+		}
+		r.errorFn = func(err error) {
+			logger.WithError(err).Error("config reload failed")
+		}
+	}
+	if _, err := r.Reload(ctx); err != nil {
+		return nil, err
+	}
+
+	interval := args.Interval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	go r.run(interval)
+	return r, nil
+}
+
+// Get returns the current value
+func (r *Reloader[T]) Get() *T {
+	return r.current.Load()
+}
+
+// OnChange registers fn to be called with the new value after a reload changes it
+func (r *Reloader[T]) OnChange(fn func(*T)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onChange = append(r.onChange, fn)
+}
+
+// Reload loads a new value now and reports whether it differs from the current one
+func (r *Reloader[T]) Reload(ctx context.Context) (bool, error) {
+	next := new(T)
+	if err := LoadContext(ctx, next, r.args); err != nil {
+		return false, err
+	}
+	prev := r.current.Swap(next)
+	if prev == nil || reflect.DeepEqual(prev, next) {
+		return false, nil
+	}
+
+	r.mu.Lock()
+	fns := append([]func(*T){}, r.onChange...)
+	r.mu.Unlock()
+	for _, fn := range fns {
+		fn(next)
+	}
+	return true, nil
+}
+
+// Close stops reloading
+func (r *Reloader[T]) Close() {
+	r.stopOnce.Do(func() { close(r.stop) })
+	<-r.stopped
+}
+
+func (r *Reloader[T]) run(interval time.Duration) {
+	defer close(r.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			if _, err := r.Reload(ctx); err != nil {
+				r.errorFn(err)
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/bdlilley/easygo"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/secrets"
+)
+
+// Reference schemes resolved by Load
+const (
+	SecretsManagerScheme = "secretsmanager://"
+	SSMScheme            = "ssm://"
+	SecretScheme         = "secret://"
+)
+
+// resolver replaces secret references, fetching each secret once per load
+type resolver struct {
+	secrets    easygo.SecretsReader
+	parameters easygo.ParameterReader
+	provider   secrets.Provider
+	cache      map[string]string
+}
+
+func (r *resolver) resolveField(ctx context.Context, f field) error {
+	v := f.value
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.String {
+		return nil
+	}
+	resolved, err := r.resolve(ctx, v.String())
+	if err != nil {
+		return err
+	}
+	v.SetString(resolved)
+	return nil
+}
+
+// resolve returns the value a reference points to, or s unchanged when it is
+// not a reference
+func (r *resolver) resolve(ctx context.Context, s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, SecretsManagerScheme):
+		name, key, _ := strings.Cut(strings.TrimPrefix(s, SecretsManagerScheme), "#")
+		if r.secrets == nil {
+			return "", errs.Newf(errs.Internal, "%s references Secrets Manager but LoadArgs.Secrets is not set", s)
+		}
+		value, err := r.cached(SecretsManagerScheme+name, func() (string, error) {
+			return r.secrets.GetLatestSecretString(ctx, name)
+		})
+		if err != nil || key == "" {
+			return value, err
+		}
+		return jsonKey(value, name, key)
+
+	case strings.HasPrefix(s, SSMScheme):
+		name := strings.TrimPrefix(s, SSMScheme)
+		if r.parameters == nil {
+			return "", errs.Newf(errs.Internal, "%s references SSM but LoadArgs.Parameters is not set", s)
+		}
+		return r.cached(s, func() (string, error) {
+			return r.parameters.GetParameterValue(ctx, name)
+		})
+
+	case strings.HasPrefix(s, SecretScheme):
+		name := strings.TrimPrefix(s, SecretScheme)
+		if r.provider == nil {
+			return "", errs.Newf(errs.Internal, "%s references a secret but LoadArgs.SecretProvider is not set", s)
+		}
+		return r.cached(s, func() (string, error) {
+			return r.provider.Get(ctx, name)
+		})
+	}
+	return s, nil
+}
+
+func (r *resolver) cached(key string, fetch func() (string, error)) (string, error) {
+	if v, ok := r.cache[key]; ok {
+		return v, nil
+	}
+	v, err := fetch()
+	if err != nil {
+		return "", err
+	}
+	r.cache[key] = v
+	return v, nil
+}
+
+// jsonKey returns the field key of the JSON object secret; non-string values
+// are returned as JSON
+func jsonKey(secret, name, key string) (string, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", errs.Errorf("secret %s is not a JSON object: %w", name, err)
+	}
+	raw, ok := fields[key]
+	if !ok {
+		return "", errs.Newf(errs.Internal, "secret %s has no key %q", name, key)
+	}
+	var str string
+	if err := json.Unmarshal(raw, &str); err == nil {
+		return str, nil
+	}
+	return string(raw), nil
+}
@@ -0,0 +1,237 @@
+// Package crypto implements envelope encryption with KMS: payloads are
+// encrypted locally with AES-256-GCM under a data key from KMS, and the data
+// key, encrypted by KMS, travels in the ciphertext's header. Data keys are
+// cached so most calls make no KMS request.
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/bdlilley/easygo/pkg/cache"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// ErrInvalidCiphertext is returned by Decrypt for input that was not produced
+// by Encrypt, was modified, or uses a different encryption context
+var ErrInvalidCiphertext = errs.New(errs.InvalidArgument, "invalid ciphertext")
+
+// formatV1 is the first byte of every ciphertext. Version 1 is
+//
+//	version (1) | wrapped key length (2, big endian) | wrapped key | nonce (12) | AES-GCM sealed payload
+//
+// The version, length and wrapped key are authenticated as additional data.
+// The wrapped key is a KMS ciphertext blob, which names the KMS key and key
+// material that produced it, so ciphertexts stay readable after KeyID
+// changes or the KMS key rotates.
+const formatV1 = 1
+
+const nonceSize = 12
+
+// KMSAPI is the subset of the KMS client used by Envelope
+type KMSAPI interface {
+	GenerateDataKey(ctx context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error)
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+type NewEnvelopeArgs struct {
+	// Client is typically EGAwsClient.GetKMSClient() (required)
+	Client KMSAPI
+	// KeyID is the ID, ARN or alias of the symmetric KMS key that new data
+	// keys are generated under (required)
+	KeyID string
+	// EncryptionContext is bound to every data key, so ciphertexts from one
+	// purpose, e.g. {"table": "orders"}, cannot be decrypted as another
+	EncryptionContext map[string]string
+	// DataKeyTTL is how long a data key encrypts new payloads and how long
+	// decrypted data keys are cached (default: 5m, negative disables caching
+	// and makes one KMS call per Encrypt and Decrypt)
+	DataKeyTTL time.Duration
+	// MaxDataKeyUses bounds the payloads encrypted under one data key
+	// (default: 1<<20, well within AES-GCM's limit for random nonces)
+	MaxDataKeyUses int
+	// DecryptCacheSize bounds the number of decrypted data keys held
+	// (default: 1000)
+	DecryptCacheSize int
+}
+
+// dataKey is the data key currently used for encryption
+type dataKey struct {
+	aead    cipher.AEAD
+	wrapped []byte
+	expires time.Time
+	uses    int
+}
+
+// Envelope encrypts and decrypts payloads. It is safe for concurrent use.
+// Plaintext data keys are held in memory while cached.
+type Envelope struct {
+	client         KMSAPI
+	keyID          string
+	encContext     map[string]string
+	ttl            time.Duration
+	maxUses        int
+	now            func() time.Time
+	decryptedCache *cache.Cache[string, cipher.AEAD]
+
+	mu      sync.Mutex
+	current *dataKey
+}
+
+// NewEnvelope creates an Envelope
+func NewEnvelope(args *NewEnvelopeArgs) (*Envelope, error) {
+	if args == nil || args.Client == nil || args.KeyID == "" {
+		return nil, errs.New(errs.Internal, "crypto: KMS Client and KeyID are required")
+	}
+	e := &Envelope{
+		client:     args.Client,
+		keyID:      args.KeyID,
+		encContext: args.EncryptionContext,
+		ttl:        args.DataKeyTTL,
+		maxUses:    args.MaxDataKeyUses,
+		now:        time.Now,
+	}
+	if e.ttl == 0 {
+		e.ttl = 5 * time.Minute
+	}
+	if e.maxUses <= 0 {
+		e.maxUses = 1 << 20
+	}
+	size := args.DecryptCacheSize
+	if size <= 0 {
+		size = 1000
+	}
+	if e.ttl > 0 {
+		e.decryptedCache = cache.New(&cache.Options[string, cipher.AEAD]{TTL: e.ttl, MaxSize: size})
+	}
+	return e, nil
+}
+
+// Encrypt encrypts plaintext under the current data key, generating a new
+// one when it has expired or reached MaxDataKeyUses
+func (e *Envelope) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	key, err := e.dataKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 3, 3+len(key.wrapped)+nonceSize+len(plaintext)+key.aead.Overhead())
+	header[0] = formatV1
+	binary.BigEndian.PutUint16(header[1:3], uint16(len(key.wrapped)))
+	header = append(header, key.wrapped...)
+	// Seal must not write over its additional data
+	aad := bytes.Clone(header)
+
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errs.Errorf("failed to generate nonce: %w", err)
+	}
+	out := append(header, nonce...)
+	return key.aead.Seal(out, nonce, plaintext, aad), nil
+}
+
+// dataKey returns the data key to encrypt with, counting this use
+func (e *Envelope) dataKey(ctx context.Context) (*dataKey, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if k := e.current; k != nil && k.uses < e.maxUses && e.now().Before(k.expires) {
+		k.uses++
+		return k, nil
+	}
+
+	out, err := e.client.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+		KeyId:             aws.String(e.keyID),
+		KeySpec:           types.DataKeySpecAes256,
+		EncryptionContext: e.encContext,
+	})
+	if err != nil {
+		return nil, errs.Errorf("failed to generate data key with %s: %w", e.keyID, err)
+	}
+	if len(out.CiphertextBlob) > 0xffff {
+		return nil, errs.Newf(errs.Internal, "crypto: encrypted data key of %d bytes is too large", len(out.CiphertextBlob))
+	}
+	aead, err := newAEAD(out.Plaintext)
+	clear(out.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+	k := &dataKey{aead: aead, wrapped: out.CiphertextBlob, expires: e.now().Add(e.ttl), uses: 1}
+	if e.decryptedCache != nil {
+		e.current = k
+		// payloads encrypted under this key decrypt without a KMS call
+		e.decryptedCache.Set(string(k.wrapped), aead)
+	}
+	return k, nil
+}
+
+// Decrypt decrypts a ciphertext from Encrypt, by an Envelope with the same
+// EncryptionContext and any KeyID the caller may decrypt with
+func (e *Envelope) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < 3 || ciphertext[0] != formatV1 {
+		return nil, errs.Errorf("unknown format: %w", ErrInvalidCiphertext)
+	}
+	n := int(binary.BigEndian.Uint16(ciphertext[1:3]))
+	if n == 0 || len(ciphertext) < 3+n+nonceSize {
+		return nil, errs.Errorf("truncated: %w", ErrInvalidCiphertext)
+	}
+	aad := ciphertext[:3+n]
+	wrapped := ciphertext[3 : 3+n]
+	nonce := ciphertext[3+n : 3+n+nonceSize]
+
+	var aead cipher.AEAD
+	var err error
+	if e.decryptedCache != nil {
+		aead, err = e.decryptedCache.GetOrLoad(ctx, string(wrapped), func(ctx context.Context, _ string) (cipher.AEAD, error) {
+			return e.unwrap(ctx, wrapped)
+		})
+	} else {
+		aead, err = e.unwrap(ctx, wrapped)
+	}
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext[3+n+nonceSize:], aad)
+	if err != nil {
+		return nil, errs.Errorf("authentication failed: %w", ErrInvalidCiphertext)
+	}
+	return plaintext, nil
+}
+
+// unwrap decrypts a wrapped data key with KMS
+func (e *Envelope) unwrap(ctx context.Context, wrapped []byte) (cipher.AEAD, error) {
+	out, err := e.client.Decrypt(ctx, &kms.DecryptInput{
+		CiphertextBlob:    wrapped,
+		EncryptionContext: e.encContext,
+	})
+	var invalid *types.InvalidCiphertextException
+	if errors.As(err, &invalid) {
+		return nil, errs.Errorf("%s: %w", invalid.ErrorMessage(), ErrInvalidCiphertext)
+	}
+	if err != nil {
+		return nil, errs.Errorf("failed to decrypt data key: %w", err)
+	}
+	aead, err := newAEAD(out.Plaintext)
+	clear(out.Plaintext)
+	return aead, err
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errs.Errorf("invalid data key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errs.Errorf("failed to create AES-GCM: %w", err)
+	}
+	return aead, nil
+}
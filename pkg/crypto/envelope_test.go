@@ -0,0 +1,104 @@
+package crypto
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// fakeKMS wraps data keys by recording them under an opaque blob
+type fakeKMS struct {
+	mu        sync.Mutex
+	keys      map[string]fakeKey
+	generated int
+	decrypted int
+}
+
+type fakeKey struct {
+	plaintext []byte
+	context   map[string]string
+}
+
+func (f *fakeKMS) GenerateDataKey(_ context.Context, in *kms.GenerateDataKeyInput, _ ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.generated++
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	blob := []byte(fmt.Sprintf("%s/%d", aws.ToString(in.KeyId), f.generated))
+	f.keys[string(blob)] = fakeKey{plaintext: append([]byte(nil), key...), context: in.EncryptionContext}
+	return &kms.GenerateDataKeyOutput{Plaintext: key, CiphertextBlob: blob, KeyId: in.KeyId}, nil
+}
+
+func (f *fakeKMS) Decrypt(_ context.Context, in *kms.DecryptInput, _ ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.decrypted++
+	k, ok := f.keys[string(in.CiphertextBlob)]
+	if !ok || !maps.Equal(k.context, in.EncryptionContext) {
+		return nil, &types.InvalidCiphertextException{Message: aws.String("bad ciphertext")}
+	}
+	return &kms.DecryptOutput{Plaintext: append([]byte(nil), k.plaintext...)}, nil
+}
+
+func TestEnvelope(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeKMS{keys: map[string]fakeKey{}}
+	orders := map[string]string{"table": "orders"}
+	e, err := NewEnvelope(&NewEnvelopeArgs{Client: client, KeyID: "alias/one", EncryptionContext: orders, MaxDataKeyUses: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ciphertexts [][]byte
+	for i := range 4 {
+		c, err := e.Encrypt(ctx, []byte(fmt.Sprintf("payload %d", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ciphertexts = append(ciphertexts, c)
+	}
+	if client.generated != 2 {
+		t.Fatalf("got %d data keys for 4 payloads with 3 uses each, want 2", client.generated)
+	}
+	for i, c := range ciphertexts {
+		if p, err := e.Decrypt(ctx, c); err != nil || string(p) != fmt.Sprintf("payload %d", i) {
+			t.Fatalf("got %q %v", p, err)
+		}
+	}
+	if client.decrypted != 0 {
+		t.Fatalf("got %d KMS decrypts, want data keys from Encrypt to be cached", client.decrypted)
+	}
+
+	// a reader configured with another key still decrypts, one KMS call per data key
+	reader, _ := NewEnvelope(&NewEnvelopeArgs{Client: client, KeyID: "alias/two", EncryptionContext: orders})
+	for _, c := range append(ciphertexts, ciphertexts...) {
+		if _, err := reader.Decrypt(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if client.decrypted != 2 {
+		t.Fatalf("got %d KMS decrypts, want 2", client.decrypted)
+	}
+
+	tampered := append([]byte(nil), ciphertexts[0]...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := reader.Decrypt(ctx, tampered); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("got %v for a modified ciphertext", err)
+	}
+	if _, err := reader.Decrypt(ctx, []byte("plain")); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("got %v for a foreign ciphertext", err)
+	}
+	other, _ := NewEnvelope(&NewEnvelopeArgs{Client: client, KeyID: "alias/one", EncryptionContext: map[string]string{"table": "users"}, DataKeyTTL: -1})
+	if _, err := other.Decrypt(ctx, ciphertexts[0]); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("got %v for another encryption context", err)
+	}
+}
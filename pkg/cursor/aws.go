@@ -0,0 +1,143 @@
+package cursor
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// KMSAPI is the subset of the KMS client used by KMSEncrypter
+type KMSAPI interface {
+	Encrypt(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error)
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// KMSEncrypter encrypts cursors with a KMS key. Each Encode and Decode makes
+// one KMS call, so use it where cursor contents must stay private.
+type KMSEncrypter struct {
+	client KMSAPI
+	keyID  string
+}
+
+var _ Encrypter = (*KMSEncrypter)(nil)
+
+// NewKMSEncrypter encrypts with keyID, e.g. with EGAwsClient.GetKMSClient()
+func NewKMSEncrypter(client KMSAPI, keyID string) *KMSEncrypter {
+	return &KMSEncrypter{client: client, keyID: keyID}
+}
+
+// encryptionContext binds ciphertexts to cursors so other KMS ciphertexts
+// under the same key are not accepted
+var encryptionContext = map[string]string{"purpose": "pagination-cursor"}
+
+func (e *KMSEncrypter) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	out, err := e.client.Encrypt(ctx, &kms.EncryptInput{
+		KeyId:             aws.String(e.keyID),
+		Plaintext:         plaintext,
+		EncryptionContext: encryptionContext,
+	})
+	if err != nil {
+		return nil, errs.Errorf("failed to encrypt with %s: %w", e.keyID, err)
+	}
+	return out.CiphertextBlob, nil
+}
+
+func (e *KMSEncrypter) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	out, err := e.client.Decrypt(ctx, &kms.DecryptInput{
+		KeyId:             aws.String(e.keyID),
+		CiphertextBlob:    ciphertext,
+		EncryptionContext: encryptionContext,
+	})
+	var invalid *kmstypes.InvalidCiphertextException
+	var incorrectKey *kmstypes.IncorrectKeyException
+	if errors.As(err, &invalid) || errors.As(err, &incorrectKey) {
+		return nil, errs.Errorf("failed to decrypt with %s: %w: %w", e.keyID, err, ErrInvalidCursor)
+	}
+	if err != nil {
+		return nil, errs.Errorf("failed to decrypt with %s: %w", e.keyID, err)
+	}
+	return out.Plaintext, nil
+}
+
+// dynamoKeyAttribute holds a key attribute; DynamoDB keys are strings,
+// numbers or binary
+type dynamoKeyAttribute struct {
+	S *string `json:"s,omitempty"`
+	N *string `json:"n,omitempty"`
+	B []byte  `json:"b,omitempty"`
+}
+
+// EncodeDynamoDBKey encodes a Query or Scan LastEvaluatedKey as a cursor,
+// returning an empty cursor for the last page
+func (c *Codec) EncodeDynamoDBKey(ctx context.Context, key map[string]types.AttributeValue) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+	attrs := make(map[string]dynamoKeyAttribute, len(key))
+	for name, v := range key {
+		switch v := v.(type) {
+		case *types.AttributeValueMemberS:
+			attrs[name] = dynamoKeyAttribute{S: aws.String(v.Value)}
+		case *types.AttributeValueMemberN:
+			attrs[name] = dynamoKeyAttribute{N: aws.String(v.Value)}
+		case *types.AttributeValueMemberB:
+			attrs[name] = dynamoKeyAttribute{B: v.Value}
+		default:
+			return "", errs.Errorf("cursor: unsupported key attribute type %T for %s", v, name)
+		}
+	}
+	return c.Encode(ctx, attrs)
+}
+
+// DecodeDynamoDBKey decodes a cursor from EncodeDynamoDBKey into an
+// ExclusiveStartKey; an empty cursor returns nil to start from the beginning
+func (c *Codec) DecodeDynamoDBKey(ctx context.Context, cursor string) (map[string]types.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	var attrs map[string]dynamoKeyAttribute
+	if err := c.Decode(ctx, cursor, &attrs); err != nil {
+		return nil, err
+	}
+	key := make(map[string]types.AttributeValue, len(attrs))
+	for name, a := range attrs {
+		switch {
+		case a.S != nil:
+			key[name] = &types.AttributeValueMemberS{Value: *a.S}
+		case a.N != nil:
+			key[name] = &types.AttributeValueMemberN{Value: *a.N}
+		case a.B != nil:
+			key[name] = &types.AttributeValueMemberB{Value: a.B}
+		default:
+			return nil, errs.Errorf("empty key attribute %s: %w", name, ErrInvalidCursor)
+		}
+	}
+	return key, nil
+}
+
+// EncodeS3Token encodes a ListObjectsV2 NextContinuationToken as a cursor,
+// returning an empty cursor for the last page
+func (c *Codec) EncodeS3Token(ctx context.Context, token *string) (string, error) {
+	if aws.ToString(token) == "" {
+		return "", nil
+	}
+	return c.Encode(ctx, aws.ToString(token))
+}
+
+// DecodeS3Token decodes a cursor from EncodeS3Token into a ContinuationToken;
+// an empty cursor returns nil to start from the beginning
+func (c *Codec) DecodeS3Token(ctx context.Context, cursor string) (*string, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	var token string
+	if err := c.Decode(ctx, cursor, &token); err != nil {
+		return nil, err
+	}
+	return aws.String(token), nil
+}
@@ -0,0 +1,156 @@
+// Package cursor encodes opaque pagination cursors and page envelopes for
+// list APIs. Cursors are HMAC signed so clients cannot forge them, and can be
+// encrypted with KMS so their contents stay private.
+package cursor
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// version is the first byte of every encoded cursor
+const version byte = 1
+
+// minKeyBytes is the shortest accepted HMAC key
+const minKeyBytes = 32
+
+// ErrInvalidCursor is returned for cursors that are malformed, tampered with
+// or expired
+var ErrInvalidCursor = errs.New(errs.InvalidArgument, "invalid cursor")
+
+// Encrypter encrypts cursor payloads; see NewKMSEncrypter. Decrypt returns an
+// error wrapping ErrInvalidCursor for ciphertexts it rejects; other errors
+// mean the encrypter is unavailable and are not the client's fault.
+type Encrypter interface {
+	Encrypt(ctx context.Context, plaintext []byte) ([]byte, error)
+	Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+type NewCodecArgs struct {
+	// Key signs cursors with HMAC-SHA256; at least 32 bytes, typically read
+	// from Secrets Manager (required)
+	Key []byte
+	// Encrypter encrypts cursors so clients cannot read their contents
+	// (default: cursors are signed but readable)
+	Encrypter Encrypter
+	// TTL rejects cursors older than this (default: cursors do not expire)
+	TTL time.Duration
+}
+
+// Codec encodes and decodes cursors. It is safe for concurrent use.
+type Codec struct {
+	key       []byte
+	encrypter Encrypter
+	ttl       time.Duration
+	now       func() time.Time
+}
+
+// NewCodec creates a Codec
+func NewCodec(args *NewCodecArgs) (*Codec, error) {
+	if args == nil || len(args.Key) < minKeyBytes {
+		return nil, errs.Errorf("cursor: Key must be at least %d bytes", minKeyBytes)
+	}
+	return &Codec{key: args.Key, encrypter: args.Encrypter, ttl: args.TTL, now: time.Now}, nil
+}
+
+// payload is the signed content of a cursor
+type payload struct {
+	Value    json.RawMessage `json:"v"`
+	IssuedAt int64           `json:"t"`
+}
+
+// Encode returns an opaque cursor holding v, which must marshal to JSON
+func (c *Codec) Encode(ctx context.Context, v any) (string, error) {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return "", errs.Errorf("failed to marshal cursor: %w", err)
+	}
+	body, err := json.Marshal(payload{Value: value, IssuedAt: c.now().Unix()})
+	if err != nil {
+		return "", errs.Errorf("failed to marshal cursor: %w", err)
+	}
+	if c.encrypter != nil {
+		if body, err = c.encrypter.Encrypt(ctx, body); err != nil {
+			return "", errs.Errorf("failed to encrypt cursor: %w", err)
+		}
+	}
+
+	token := make([]byte, 0, 1+len(body)+sha256.Size)
+	token = append(token, version)
+	token = append(token, body...)
+	token = append(token, c.sign(token)...)
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// Decode verifies cursor and unmarshals its value into v. Invalid cursors
+// return an error wrapping ErrInvalidCursor; failures to reach the Encrypter
+// return an Unavailable error.
+func (c *Codec) Decode(ctx context.Context, cursor string, v any) error {
+	token, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(token) < 1+sha256.Size || token[0] != version {
+		return errs.Errorf("malformed cursor: %w", ErrInvalidCursor)
+	}
+	signed, mac := token[:len(token)-sha256.Size], token[len(token)-sha256.Size:]
+	if !hmac.Equal(mac, c.sign(signed)) {
+		return errs.Errorf("cursor signature mismatch: %w", ErrInvalidCursor)
+	}
+
+	body := signed[1:]
+	if c.encrypter != nil {
+		if body, err = c.encrypter.Decrypt(ctx, body); err != nil {
+			kind := errs.Unavailable
+			if errors.Is(err, ErrInvalidCursor) || errs.KindOf(err) != errs.Unknown {
+				kind = errs.Unknown
+			}
+			return errs.Wrap(err, kind, "failed to decrypt cursor")
+		}
+	}
+	var p payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return errs.Errorf("malformed cursor payload: %w", ErrInvalidCursor)
+	}
+	if c.ttl > 0 && c.now().Sub(time.Unix(p.IssuedAt, 0)) > c.ttl {
+		return errs.Errorf("cursor expired: %w", ErrInvalidCursor)
+	}
+	if err := json.Unmarshal(p.Value, v); err != nil {
+		return errs.Errorf("cursor does not match the expected type: %w", ErrInvalidCursor)
+	}
+	return nil
+}
+
+func (c *Codec) sign(b []byte) []byte {
+	h := hmac.New(sha256.New, c.key)
+	h.Write(b)
+	return h.Sum(nil)
+}
+
+// Page is a list response envelope; render it with httpserver.JSON
+type Page[T any] struct {
+	Items []T `json:"items"`
+	// NextCursor fetches the following page; empty on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// NewPage builds a page of items, encoding next as its NextCursor. Pass a nil
+// next for the last page. Items is never null in JSON.
+func NewPage[T, C any](ctx context.Context, codec *Codec, items []T, next *C) (*Page[T], error) {
+	if items == nil {
+		items = []T{}
+	}
+	page := &Page[T]{Items: items}
+	if next != nil {
+		cursor, err := codec.Encode(ctx, next)
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = cursor
+	}
+	return page, nil
+}
@@ -0,0 +1,222 @@
+package cursor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/httpserver"
+)
+
+var testKey = bytes.Repeat([]byte("k"), 32)
+
+type position struct {
+	ID    string `json:"id"`
+	Score int    `json:"score"`
+}
+
+// xorEncrypter is a reversible stand-in for KMS
+type xorEncrypter struct{}
+
+func (xorEncrypter) Encrypt(_ context.Context, b []byte) ([]byte, error) { return xor(b), nil }
+func (xorEncrypter) Decrypt(_ context.Context, b []byte) ([]byte, error) { return xor(b), nil }
+
+func xor(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[i] = b[i] ^ 0x5a
+	}
+	return out
+}
+
+func newCodec(t *testing.T, args *NewCodecArgs) *Codec {
+	t.Helper()
+	args.Key = testKey
+	c, err := NewCodec(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	for name, args := range map[string]*NewCodecArgs{
+		"signed":    {},
+		"encrypted": {Encrypter: xorEncrypter{}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := newCodec(t, args)
+			cursor, err := c.Encode(ctx, position{ID: "a", Score: 7})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got position
+			if err := c.Decode(ctx, cursor, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got != (position{ID: "a", Score: 7}) {
+				t.Fatalf("got %+v", got)
+			}
+		})
+	}
+}
+
+func TestRejectsInvalidCursors(t *testing.T) {
+	ctx := context.Background()
+	c := newCodec(t, &NewCodecArgs{TTL: time.Minute})
+	now := time.Unix(1_700_000_000, 0)
+	c.now = func() time.Time { return now }
+
+	cursor, err := c.Encode(ctx, position{ID: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := []byte(cursor)
+	tampered[5] ^= 1
+
+	other, err := NewCodec(&NewCodecArgs{Key: bytes.Repeat([]byte("x"), 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, _ := other.Encode(ctx, position{ID: "a"})
+
+	for name, s := range map[string]string{
+		"garbage":   "not a cursor!",
+		"tampered":  string(tampered),
+		"wrong key": foreign,
+	} {
+		var p position
+		if err := c.Decode(ctx, s, &p); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: got %v, want ErrInvalidCursor", name, err)
+		}
+	}
+
+	now = now.Add(2 * time.Minute)
+	var p position
+	if err := c.Decode(ctx, cursor, &p); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expired: got %v, want ErrInvalidCursor", err)
+	}
+
+	if _, err := NewCodec(&NewCodecArgs{Key: []byte("short")}); err == nil {
+		t.Error("expected short key to be rejected")
+	}
+}
+
+// fakeKMS returns plaintexts as ciphertexts and fails Decrypt with err
+type fakeKMS struct {
+	err error
+}
+
+func (f *fakeKMS) Encrypt(_ context.Context, in *kms.EncryptInput, _ ...func(*kms.Options)) (*kms.EncryptOutput, error) {
+	return &kms.EncryptOutput{CiphertextBlob: in.Plaintext}, nil
+}
+
+func (f *fakeKMS) Decrypt(_ context.Context, in *kms.DecryptInput, _ ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &kms.DecryptOutput{Plaintext: in.CiphertextBlob}, nil
+}
+
+func TestKMSDecryptErrors(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeKMS{}
+	c := newCodec(t, &NewCodecArgs{Encrypter: NewKMSEncrypter(client, "key")})
+	cursor, err := c.Encode(ctx, position{ID: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tc := range map[string]struct {
+		err  error
+		want int
+	}{
+		"rejected ciphertext": {&kmstypes.InvalidCiphertextException{}, http.StatusBadRequest},
+		"kms unreachable":     {errors.New("dial tcp: connection refused"), http.StatusServiceUnavailable},
+	} {
+		client.err = tc.err
+		var p position
+		err := c.Decode(ctx, cursor, &p)
+		if got := errs.KindOf(err).HTTPStatus(); got != tc.want {
+			t.Errorf("%s: got %v with status %d, want %d", name, err, got, tc.want)
+		}
+		_, err = c.FromRequest(httptest.NewRequest("GET", "/items?cursor="+cursor, nil), &p)
+		if got := httpserver.ProblemFromError(err).Status; got != tc.want {
+			t.Errorf("%s: FromRequest got %v with status %d, want %d", name, err, got, tc.want)
+		}
+	}
+}
+
+func TestPageAndRequest(t *testing.T) {
+	ctx := context.Background()
+	c := newCodec(t, &NewCodecArgs{})
+
+	last, err := NewPage[string, position](ctx, c, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Items == nil || last.NextCursor != "" {
+		t.Fatalf("got %+v, want empty items and no cursor", last)
+	}
+
+	page, err := NewPage(ctx, c, []string{"a"}, &position{ID: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p position
+	ok, err := c.FromRequest(httptest.NewRequest("GET", "/items?cursor="+page.NextCursor, nil), &p)
+	if err != nil || !ok || p.ID != "a" {
+		t.Fatalf("got %v %v %+v", ok, err, p)
+	}
+	if ok, err := c.FromRequest(httptest.NewRequest("GET", "/items", nil), &p); ok || err != nil {
+		t.Fatalf("got %v %v for no cursor", ok, err)
+	}
+	_, err = c.FromRequest(httptest.NewRequest("GET", "/items?cursor=bogus", nil), &p)
+	var problem *httpserver.Problem
+	if !errors.As(err, &problem) || problem.Status != 400 {
+		t.Fatalf("got %v, want a 400 problem", err)
+	}
+
+	if n, err := Limit(httptest.NewRequest("GET", "/items", nil), 25, 100); n != 25 || err != nil {
+		t.Fatalf("got %d %v", n, err)
+	}
+	if _, err := Limit(httptest.NewRequest("GET", "/items?limit=500", nil), 25, 100); err == nil {
+		t.Fatal("expected limit above max to be rejected")
+	}
+}
+
+func TestDynamoDBKey(t *testing.T) {
+	ctx := context.Background()
+	c := newCodec(t, &NewCodecArgs{})
+	key := map[string]types.AttributeValue{
+		"pk":  &types.AttributeValueMemberS{Value: "tenant#1"},
+		"sk":  &types.AttributeValueMemberN{Value: "42"},
+		"bin": &types.AttributeValueMemberB{Value: []byte{1, 2}},
+	}
+	cursor, err := c.EncodeDynamoDBKey(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.DecodeDynamoDBKey(ctx, cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["pk"].(*types.AttributeValueMemberS).Value != "tenant#1" ||
+		got["sk"].(*types.AttributeValueMemberN).Value != "42" ||
+		!bytes.Equal(got["bin"].(*types.AttributeValueMemberB).Value, []byte{1, 2}) {
+		t.Fatalf("got %#v", got)
+	}
+
+	if cursor, err := c.EncodeDynamoDBKey(ctx, nil); cursor != "" || err != nil {
+		t.Fatalf("got %q %v for the last page", cursor, err)
+	}
+}
@@ -0,0 +1,41 @@
+package cursor
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/bdlilley/easygo/pkg/httpserver"
+)
+
+// FromRequest decodes the cursor query parameter into v and reports whether
+// one was sent. Invalid cursors return a 400 *httpserver.Problem, so handlers
+// wrapped by httpserver.ErrorHandler can return the error as is; Encrypter
+// failures are returned unchanged and served as 503.
+func (c *Codec) FromRequest(r *http.Request, v any) (bool, error) {
+	raw := r.URL.Query().Get("cursor")
+	if raw == "" {
+		return false, nil
+	}
+	if err := c.Decode(r.Context(), raw, v); err != nil {
+		if !errors.Is(err, ErrInvalidCursor) {
+			return false, err
+		}
+		return false, httpserver.NewProblem(http.StatusBadRequest, "invalid cursor").WithCode("invalid_cursor")
+	}
+	return true, nil
+}
+
+// Limit reads the limit query parameter, returning def when it is absent and
+// a 400 *httpserver.Problem unless it is between 1 and max
+func Limit(r *http.Request, def, max int) (int, error) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 || n > max {
+		return 0, httpserver.NewProblem(http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(max)).WithCode("invalid_limit")
+	}
+	return n, nil
+}
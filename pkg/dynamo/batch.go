@@ -0,0 +1,67 @@
+package dynamo
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// BatchPut writes items with BatchWriteItem, 25 per call, retrying items
+// DynamoDB leaves unprocessed. Batch writes take no conditions, so versions
+// are written as they are and not checked.
+func (r *Repository[T]) BatchPut(ctx context.Context, items []T) error {
+	reqs := make([]types.WriteRequest, 0, len(items))
+	for i := range items {
+		av, err := attributevalue.MarshalMap(&items[i])
+		if err != nil {
+			return errs.Errorf("dynamo: failed to marshal item %d for %s: %w", i, r.table, err)
+		}
+		reqs = append(reqs, types.WriteRequest{PutRequest: &types.PutRequest{Item: av}})
+	}
+	return r.batchWrite(ctx, reqs)
+}
+
+// BatchDelete deletes the items with keys with BatchWriteItem, 25 per call,
+// retrying keys DynamoDB leaves unprocessed
+func (r *Repository[T]) BatchDelete(ctx context.Context, keys []Key) error {
+	reqs := make([]types.WriteRequest, 0, len(keys))
+	for _, key := range keys {
+		k, err := r.key(key)
+		if err != nil {
+			return err
+		}
+		reqs = append(reqs, types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: k}})
+	}
+	return r.batchWrite(ctx, reqs)
+}
+
+func (r *Repository[T]) batchWrite(ctx context.Context, reqs []types.WriteRequest) error {
+	for start := 0; start < len(reqs); start += batchWriteMaxItems {
+		end := min(start+batchWriteMaxItems, len(reqs))
+		pending := reqs[start:end]
+		for attempt := 0; len(pending) > 0; attempt++ {
+			if attempt == r.opts.BatchAttempts {
+				// later batches are not attempted either
+				return errs.Newf(errs.Internal, "dynamo: %d of %d writes to %s unprocessed after %d attempts", len(pending)+len(reqs)-end, len(reqs), r.table, attempt)
+			}
+			if attempt > 0 {
+				select {
+				case <-ctx.Done():
+					return errs.Errorf("dynamo: %d writes to %s unprocessed: %w", len(pending), r.table, ctx.Err())
+				case <-r.opts.Clock.After(r.opts.BatchBackoff.Delay(attempt - 1)):
+				}
+			}
+			out, err := r.client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+				RequestItems: map[string][]types.WriteRequest{r.table: pending},
+			})
+			if err != nil {
+				return errs.Errorf("dynamo: failed to batch write to %s: %w", r.table, err)
+			}
+			pending = out.UnprocessedItems[r.table]
+		}
+	}
+	return nil
+}
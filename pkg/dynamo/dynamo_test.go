@@ -0,0 +1,405 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/bdlilley/easygo/pkg/clock"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+type order struct {
+	TenantID  string    `dynamodbav:"tenant_id" dynamo:"hash"`
+	OrderID   string    `dynamodbav:"order_id" dynamo:"range"`
+	Status    string    `dynamodbav:"status" dynamo:"hash=by-status"`
+	CreatedAt time.Time `dynamodbav:"created_at" dynamo:"range=by-status,range=by-created"`
+	Version   int64     `dynamodbav:"version" dynamo:"version"`
+	Note      string    `dynamodbav:"note,omitempty"`
+}
+
+// fakeTable evaluates the expressions Repository writes against items held
+// in memory
+type fakeTable struct {
+	hash, rng string
+
+	mu    sync.Mutex
+	items map[string]map[string]types.AttributeValue
+	// unprocessed is the number of requests the next BatchWriteItem leaves
+	// unprocessed
+	unprocessed int
+	batchCalls  int
+	lastQuery   *dynamodb.QueryInput
+}
+
+func newFakeTable() *fakeTable {
+	return &fakeTable{hash: "tenant_id", rng: "order_id", items: map[string]map[string]types.AttributeValue{}}
+}
+
+func str(v types.AttributeValue) string {
+	switch v := v.(type) {
+	case *types.AttributeValueMemberS:
+		return v.Value
+	case *types.AttributeValueMemberN:
+		return v.Value
+	}
+	return ""
+}
+
+func (f *fakeTable) id(item map[string]types.AttributeValue) string {
+	return str(item[f.hash]) + "|" + str(item[f.rng])
+}
+
+func (f *fakeTable) check(existing map[string]types.AttributeValue, cond *string, names map[string]string, values map[string]types.AttributeValue) error {
+	switch aws.ToString(cond) {
+	case "":
+		return nil
+	case "attribute_not_exists(#hash)":
+		if existing == nil {
+			return nil
+		}
+	case "#version = :version":
+		if existing != nil && str(existing[names["#version"]]) == str(values[":version"]) {
+			return nil
+		}
+	default:
+		return fmt.Errorf("unexpected condition %s", aws.ToString(cond))
+	}
+	return &types.ConditionalCheckFailedException{}
+}
+
+func (f *fakeTable) GetItem(_ context.Context, in *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &dynamodb.GetItemOutput{Item: f.items[f.id(in.Key)]}, nil
+}
+
+func (f *fakeTable) PutItem(_ context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.id(in.Item)
+	if err := f.check(f.items[id], in.ConditionExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues); err != nil {
+		return nil, err
+	}
+	f.items[id] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeTable) DeleteItem(_ context.Context, in *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.id(in.Key)
+	if err := f.check(f.items[id], in.ConditionExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues); err != nil {
+		return nil, err
+	}
+	delete(f.items, id)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func (f *fakeTable) Query(_ context.Context, in *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lastQuery = in
+	names, values := in.ExpressionAttributeNames, in.ExpressionAttributeValues
+	rangeExpr, _ := strings.CutPrefix(aws.ToString(in.KeyConditionExpression), "#hash = :hash")
+	rangeExpr = strings.TrimPrefix(rangeExpr, " AND ")
+
+	var matched []map[string]types.AttributeValue
+	for _, item := range f.items {
+		if str(item[names["#hash"]]) != str(values[":hash"]) {
+			continue
+		}
+		r := str(item[names["#range"]])
+		ok := true
+		switch rangeExpr {
+		case "":
+		case "#range = :r0":
+			ok = r == str(values[":r0"])
+		case "begins_with(#range, :r0)":
+			ok = strings.HasPrefix(r, str(values[":r0"]))
+		case "#range BETWEEN :r0 AND :r1":
+			ok = r >= str(values[":r0"]) && r <= str(values[":r1"])
+		default:
+			return nil, fmt.Errorf("unexpected key condition %s", rangeExpr)
+		}
+		if ok {
+			matched = append(matched, item)
+		}
+	}
+	slices.SortFunc(matched, func(a, b map[string]types.AttributeValue) int {
+		return strings.Compare(str(a[names["#range"]])+f.id(a), str(b[names["#range"]])+f.id(b))
+	})
+	if !aws.ToBool(in.ScanIndexForward) {
+		slices.Reverse(matched)
+	}
+	if in.ExclusiveStartKey != nil {
+		start := f.id(in.ExclusiveStartKey)
+		i := slices.IndexFunc(matched, func(item map[string]types.AttributeValue) bool { return f.id(item) == start })
+		matched = matched[i+1:]
+	}
+	out := &dynamodb.QueryOutput{Items: matched}
+	if limit := int(aws.ToInt32(in.Limit)); limit > 0 && len(matched) > limit {
+		out.Items = matched[:limit]
+		last := out.Items[limit-1]
+		out.LastEvaluatedKey = map[string]types.AttributeValue{f.hash: last[f.hash], f.rng: last[f.rng]}
+	}
+	return out, nil
+}
+
+func (f *fakeTable) BatchWriteItem(_ context.Context, in *dynamodb.BatchWriteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batchCalls++
+	out := &dynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]types.WriteRequest{}}
+	for table, reqs := range in.RequestItems {
+		if len(reqs) > batchWriteMaxItems {
+			return nil, fmt.Errorf("%d requests in one batch", len(reqs))
+		}
+		for _, req := range reqs {
+			if f.unprocessed > 0 {
+				f.unprocessed--
+				out.UnprocessedItems[table] = append(out.UnprocessedItems[table], req)
+				continue
+			}
+			if req.PutRequest != nil {
+				f.items[f.id(req.PutRequest.Item)] = req.PutRequest.Item
+			} else {
+				delete(f.items, f.id(req.DeleteRequest.Key))
+			}
+		}
+	}
+	return out, nil
+}
+
+func TestSchema(t *testing.T) {
+	r, err := NewRepository[order](newFakeTable(), "orders", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := r.schema
+	if s.hash != "tenant_id" || s.rng != "order_id" || s.versionName != "version" {
+		t.Fatalf("schema = %+v", s)
+	}
+	if h, rng, _ := s.keyNames("by-status"); h != "status" || rng != "created_at" {
+		t.Fatalf("by-status = %s, %s", h, rng)
+	}
+	if h, rng, _ := s.keyNames("by-created"); h != "tenant_id" || rng != "created_at" {
+		t.Fatalf("local index by-created = %s, %s", h, rng)
+	}
+	if _, _, err := s.keyNames("missing"); err == nil {
+		t.Fatal("expected an error for an unknown index")
+	}
+
+	type noHash struct {
+		ID string `dynamo:"range"`
+	}
+	type badVersion struct {
+		ID      string `dynamo:"hash"`
+		Version string `dynamo:"version"`
+	}
+	type badOption struct {
+		ID string `dynamo:"hash,primary"`
+	}
+	type Base struct {
+		ID string `dynamodbav:"id" dynamo:"hash"`
+	}
+	type embedded struct {
+		Base
+		Rev int `dynamo:"version"`
+	}
+	if _, err := NewRepository[noHash](nil, "t", nil); err == nil {
+		t.Fatal("expected an error without a hash key")
+	}
+	if _, err := NewRepository[badVersion](nil, "t", nil); err == nil {
+		t.Fatal("expected an error for a string version")
+	}
+	if _, err := NewRepository[badOption](nil, "t", nil); err == nil {
+		t.Fatal("expected an error for an unknown option")
+	}
+	if _, err := NewRepository[string](nil, "t", nil); err == nil {
+		t.Fatal("expected an error for a non-struct type")
+	}
+	e, err := NewRepository[embedded](nil, "t", nil)
+	if err != nil || e.schema.hash != "id" || e.schema.versionName != "Rev" {
+		t.Fatalf("embedded schema = %+v, %v", e, err)
+	}
+}
+
+func TestCRUD(t *testing.T) {
+	ctx := context.Background()
+	table := newFakeTable()
+	r, _ := NewRepository[order](table, "orders", nil)
+
+	o := &order{TenantID: "t1", OrderID: "o1", Status: "open", CreatedAt: time.Unix(1700000000, 0).UTC()}
+	if err := r.Create(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	if o.Version != 1 {
+		t.Fatalf("version after Create = %d", o.Version)
+	}
+	dup := &order{TenantID: "t1", OrderID: "o1"}
+	if err := r.Create(ctx, dup); !errors.Is(err, ErrConflict) || errs.KindOf(err) != errs.Conflict {
+		t.Fatalf("Create of an existing item = %v", err)
+	}
+	if dup.Version != 0 {
+		t.Fatalf("failed Create left version %d", dup.Version)
+	}
+
+	got, err := r.Get(ctx, Key{Hash: "t1", Range: "o1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != *o {
+		t.Fatalf("Get = %+v, want %+v", got, o)
+	}
+	if _, err := r.Get(ctx, Key{Hash: "t1", Range: "missing"}); !errors.Is(err, ErrNotFound) || !errs.Is(err, errs.NotFound) {
+		t.Fatalf("Get of a missing item = %v", err)
+	}
+	if _, err := r.Get(ctx, Key{Hash: "t1"}); err == nil {
+		t.Fatal("expected an error without the range key")
+	}
+
+	// two writers read version 1; the second write conflicts
+	stale := *got
+	got.Note = "first"
+	if err := r.Put(ctx, got); err != nil || got.Version != 2 {
+		t.Fatalf("Put = %v, version %d", err, got.Version)
+	}
+	stale.Note = "second"
+	if err := r.Put(ctx, &stale); !errors.Is(err, ErrConflict) || stale.Version != 1 {
+		t.Fatalf("stale Put = %v, version %d", err, stale.Version)
+	}
+	if err := r.Delete(ctx, &stale); !errors.Is(err, ErrConflict) {
+		t.Fatalf("stale Delete = %v", err)
+	}
+	if err := r.Delete(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Get(ctx, Key{Hash: "t1", Range: "o1"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete = %v", err)
+	}
+
+	// version 0 puts only create
+	fresh := &order{TenantID: "t1", OrderID: "o2"}
+	if err := r.Put(ctx, fresh); err != nil || fresh.Version != 1 {
+		t.Fatalf("Put of a new item = %v, version %d", err, fresh.Version)
+	}
+	if err := r.Put(ctx, &order{TenantID: "t1", OrderID: "o2"}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("version 0 Put over an existing item = %v", err)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	ctx := context.Background()
+	table := newFakeTable()
+	r, _ := NewRepository[order](table, "orders", &RepositoryOptions{ConsistentRead: true})
+	base := time.Unix(1700000000, 0).UTC()
+	for i, status := range []string{"open", "open", "closed", "open", "open"} {
+		o := &order{TenantID: "t1", OrderID: fmt.Sprintf("o%d", i), Status: status, CreatedAt: base.Add(time.Duration(i) * time.Hour)}
+		if err := r.Create(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = r.Create(ctx, &order{TenantID: "t2", OrderID: "o0", Status: "open", CreatedAt: base})
+
+	page, err := r.Query(ctx, &Query{Hash: "t1", Range: BeginsWith("o"), Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 3 || page.Items[0].OrderID != "o0" || page.LastKey == nil {
+		t.Fatalf("first page = %+v", page)
+	}
+	if !aws.ToBool(table.lastQuery.ConsistentRead) || table.lastQuery.IndexName != nil {
+		t.Fatalf("table query = %+v", table.lastQuery)
+	}
+	page, _ = r.Query(ctx, &Query{Hash: "t1", Limit: 3, StartKey: page.LastKey})
+	if len(page.Items) != 2 || page.Items[0].OrderID != "o3" || page.LastKey != nil {
+		t.Fatalf("second page = %+v", page)
+	}
+
+	open, err := r.QueryAll(ctx, &Query{
+		Index:      "by-status",
+		Hash:       "open",
+		Range:      Between(base.Add(time.Hour), base.Add(4*time.Hour)),
+		Limit:      2,
+		Descending: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, o := range open {
+		ids = append(ids, o.OrderID)
+	}
+	if strings.Join(ids, ",") != "o4,o3,o1" {
+		t.Fatalf("open orders = %v", ids)
+	}
+	if aws.ToString(table.lastQuery.IndexName) != "by-status" || table.lastQuery.ConsistentRead != nil {
+		t.Fatalf("index query = %+v", table.lastQuery)
+	}
+
+	page, _ = r.Query(ctx, &Query{Index: "by-created", Hash: "t1", Range: Equal(base)})
+	if len(page.Items) != 1 || page.Items[0].OrderID != "o0" || !aws.ToBool(table.lastQuery.ConsistentRead) {
+		t.Fatalf("local index page = %+v", page)
+	}
+
+	if _, err := r.Query(ctx, &Query{Index: "missing", Hash: "x"}); err == nil {
+		t.Fatal("expected an error for an unknown index")
+	}
+	if _, err := r.Query(ctx, &Query{}); err == nil {
+		t.Fatal("expected an error without a hash key")
+	}
+}
+
+func TestBatch(t *testing.T) {
+	ctx := context.Background()
+	table := newFakeTable()
+	clk := clock.NewFake(time.Unix(1700000000, 0))
+	r, _ := NewRepository[order](table, "orders", &RepositoryOptions{BatchAttempts: 3, Clock: clk})
+
+	items := make([]order, 60)
+	keys := make([]Key, len(items))
+	for i := range items {
+		items[i] = order{TenantID: "t1", OrderID: fmt.Sprintf("o%02d", i)}
+		keys[i] = Key{Hash: "t1", Range: items[i].OrderID}
+	}
+
+	// unprocessed items are retried after the backoff
+	table.unprocessed = 5
+	done := make(chan error)
+	go func() { done <- r.BatchPut(ctx, items) }()
+	clk.BlockUntil(1)
+	clk.Advance(time.Minute)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if len(table.items) != 60 || table.batchCalls != 4 {
+		t.Fatalf("items = %d, calls = %d", len(table.items), table.batchCalls)
+	}
+
+	if err := r.BatchDelete(ctx, keys[:30]); err != nil {
+		t.Fatal(err)
+	}
+	if len(table.items) != 30 {
+		t.Fatalf("items after BatchDelete = %d", len(table.items))
+	}
+
+	// writes still unprocessed after BatchAttempts fail the batch
+	table.unprocessed = 1000
+	go func() { done <- r.BatchDelete(ctx, keys[30:]) }()
+	for range 2 {
+		clk.BlockUntil(1)
+		clk.Advance(time.Minute)
+	}
+	if err := <-done; err == nil || !strings.Contains(err.Error(), "30 of 30 writes") {
+		t.Fatalf("BatchDelete = %v", err)
+	}
+}
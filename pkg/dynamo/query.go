@@ -0,0 +1,156 @@
+package dynamo
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// Condition is a range key condition for Query
+type Condition struct {
+	expr   string
+	values []any
+}
+
+// Equal matches range keys equal to v
+func Equal(v any) *Condition { return &Condition{expr: "#range = :r0", values: []any{v}} }
+
+// LessThan matches range keys below v
+func LessThan(v any) *Condition { return &Condition{expr: "#range < :r0", values: []any{v}} }
+
+// LessOrEqual matches range keys up to v
+func LessOrEqual(v any) *Condition { return &Condition{expr: "#range <= :r0", values: []any{v}} }
+
+// GreaterThan matches range keys above v
+func GreaterThan(v any) *Condition { return &Condition{expr: "#range > :r0", values: []any{v}} }
+
+// GreaterOrEqual matches range keys from v
+func GreaterOrEqual(v any) *Condition { return &Condition{expr: "#range >= :r0", values: []any{v}} }
+
+// Between matches range keys from lo to hi inclusive
+func Between(lo, hi any) *Condition {
+	return &Condition{expr: "#range BETWEEN :r0 AND :r1", values: []any{lo, hi}}
+}
+
+// BeginsWith matches string range keys starting with prefix
+func BeginsWith(prefix string) *Condition {
+	return &Condition{expr: "begins_with(#range, :r0)", values: []any{prefix}}
+}
+
+// Query reads the items of one partition of the table or an index
+type Query struct {
+	// Index is a secondary index declared on T, or empty for the table
+	Index string
+	// Hash is the partition key value (required)
+	Hash any
+	// Range limits the range keys matched (default: all)
+	Range *Condition
+	// Limit is the maximum number of items read per page (default: up to 1MB)
+	Limit int32
+	// Descending returns items in descending range key order
+	Descending bool
+	// StartKey continues from a previous page's LastKey, e.g. decoded with
+	// cursor.Codec.DecodeDynamoDBKey
+	StartKey map[string]types.AttributeValue
+}
+
+// Page is a page of query results
+type Page[T any] struct {
+	Items []T
+	// LastKey continues the query as Query.StartKey; nil on the last page.
+	// Encode it for clients with cursor.Codec.EncodeDynamoDBKey.
+	LastKey map[string]types.AttributeValue
+}
+
+// Query returns a page of items matching q
+func (r *Repository[T]) Query(ctx context.Context, q *Query) (*Page[T], error) {
+	input, err := r.queryInput(q)
+	if err != nil {
+		return nil, err
+	}
+	out, err := r.client.Query(ctx, input)
+	if err != nil {
+		return nil, errs.Errorf("dynamo: failed to query %s: %w", r.table, err)
+	}
+	page := &Page[T]{Items: make([]T, 0, len(out.Items)), LastKey: out.LastEvaluatedKey}
+	if err := attributevalue.UnmarshalListOfMaps(out.Items, &page.Items); err != nil {
+		return nil, errs.Errorf("dynamo: failed to unmarshal items from %s: %w", r.table, err)
+	}
+	return page, nil
+}
+
+// QueryAll returns every item matching q, reading all pages; q.Limit sets the
+// page size
+func (r *Repository[T]) QueryAll(ctx context.Context, q *Query) ([]T, error) {
+	next := *q
+	var items []T
+	for {
+		page, err := r.Query(ctx, &next)
+		if err != nil {
+			return items, err
+		}
+		items = append(items, page.Items...)
+		if len(page.LastKey) == 0 {
+			return items, nil
+		}
+		next.StartKey = page.LastKey
+	}
+}
+
+func (r *Repository[T]) queryInput(q *Query) (*dynamodb.QueryInput, error) {
+	hashName, rangeName, err := r.schema.keyNames(q.Index)
+	if err != nil {
+		return nil, err
+	}
+	if hashName == "" || q.Hash == nil {
+		return nil, errs.Newf(errs.Internal, "dynamo: query of %s requires a hash key", r.table)
+	}
+	hash, err := attributevalue.Marshal(q.Hash)
+	if err != nil {
+		return nil, errs.Errorf("dynamo: failed to marshal %s: %w", hashName, err)
+	}
+
+	expr := "#hash = :hash"
+	names := map[string]string{"#hash": hashName}
+	values := map[string]types.AttributeValue{":hash": hash}
+	if q.Range != nil {
+		if rangeName == "" {
+			return nil, errs.Newf(errs.Internal, "dynamo: %s has no range key", q.Index)
+		}
+		expr += " AND " + q.Range.expr
+		names["#range"] = rangeName
+		for i, v := range q.Range.values {
+			av, err := attributevalue.Marshal(v)
+			if err != nil {
+				return nil, errs.Errorf("dynamo: failed to marshal %s: %w", rangeName, err)
+			}
+			values[":r"+strconv.Itoa(i)] = av
+		}
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(r.table),
+		KeyConditionExpression:    aws.String(expr),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+		ScanIndexForward:          aws.Bool(!q.Descending),
+		ExclusiveStartKey:         q.StartKey,
+	}
+	if q.Index != "" {
+		input.IndexName = aws.String(q.Index)
+	}
+	if q.Limit > 0 {
+		input.Limit = aws.Int32(q.Limit)
+	}
+	// global secondary indexes, those with their own hash key, do not
+	// support consistent reads
+	if r.opts.ConsistentRead && (q.Index == "" || r.schema.indexes[q.Index].hash == "") {
+		input.ConsistentRead = aws.Bool(true)
+	}
+	return input, nil
+}
@@ -0,0 +1,281 @@
+// Package dynamo provides Repository, a typed data access layer over a
+// DynamoDB table. The key schema is declared with dynamo struct tags next to
+// the usual dynamodbav tags:
+//
+//	type Order struct {
+//		TenantID  string    `dynamodbav:"tenant_id" dynamo:"hash"`
+//		OrderID   string    `dynamodbav:"order_id" dynamo:"range"`
+//		Status    string    `dynamodbav:"status" dynamo:"hash=by-status"`
+//		CreatedAt time.Time `dynamodbav:"created_at" dynamo:"range=by-status,range=by-created"`
+//		Version   int64     `dynamodbav:"version" dynamo:"version"`
+//	}
+//
+//	orders, _ := dynamo.NewRepository[Order](aws.GetDynamoDBClient(), "orders", nil)
+//	order, err := orders.Get(ctx, dynamo.Key{Hash: tenantID, Range: orderID})
+//	page, err := orders.Query(ctx, &dynamo.Query{Index: "by-status", Hash: "open", Limit: 50})
+//
+// hash and range declare the table's keys; hash=index and range=index those
+// of a secondary index, where an index with only a range key is a local
+// secondary index. A version field enables optimistic locking: Put and
+// Delete fail with ErrConflict when the item changed since it was read.
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/bdlilley/easygo/pkg/clock"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/retry"
+)
+
+var (
+	// ErrNotFound is returned by Get when the item does not exist
+	ErrNotFound = errs.New(errs.NotFound, "dynamo: item not found")
+	// ErrConflict is returned when a write's condition fails: Create of an
+	// existing item, or Put or Delete of an item whose version changed
+	ErrConflict = errs.New(errs.Conflict, "dynamo: item was modified concurrently")
+)
+
+// batchWriteMaxItems is the BatchWriteItem limit on requests per call
+const batchWriteMaxItems = 25
+
+// API is the subset of the DynamoDB client used by Repository
+type API interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+}
+
+type RepositoryOptions struct {
+	// ConsistentRead makes Get and table queries strongly consistent
+	ConsistentRead bool
+	// BatchAttempts is the number of BatchWriteItem calls made for a batch
+	// while DynamoDB returns unprocessed items (default: 8)
+	BatchAttempts int
+	// BatchBackoff spaces retries of unprocessed items (default: 50ms to 5s)
+	BatchBackoff retry.Backoff
+	// Clock times the batch backoff (default: clock.Real)
+	Clock clock.Clock
+}
+
+// Repository reads and writes items of type T in a table
+type Repository[T any] struct {
+	client API
+	table  string
+	schema *schema
+	opts   RepositoryOptions
+}
+
+// NewRepository creates a repository for T, a struct with dynamo key tags,
+// e.g. with EGAwsClient.GetDynamoDBClient(); opts may be nil
+func NewRepository[T any](client API, table string, opts *RepositoryOptions) (*Repository[T], error) {
+	s, err := parseSchema(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, err
+	}
+	o := RepositoryOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.BatchAttempts <= 0 {
+		o.BatchAttempts = 8
+	}
+	if o.BatchBackoff.Initial <= 0 {
+		o.BatchBackoff.Initial = 50 * time.Millisecond
+	}
+	if o.BatchBackoff.Max <= 0 {
+		o.BatchBackoff.Max = 5 * time.Second
+	}
+	if o.Clock == nil {
+		o.Clock = clock.Real()
+	}
+	return &Repository[T]{client: client, table: table, schema: s, opts: o}, nil
+}
+
+// Key identifies an item by its hash and, for tables with one, range key
+// values, marshaled like the struct fields they match
+type Key struct {
+	Hash  any
+	Range any
+}
+
+// key marshals k for the table
+func (r *Repository[T]) key(k Key) (map[string]types.AttributeValue, error) {
+	key := map[string]types.AttributeValue{}
+	hash, err := attributevalue.Marshal(k.Hash)
+	if err != nil {
+		return nil, errs.Errorf("dynamo: failed to marshal %s: %w", r.schema.hash, err)
+	}
+	key[r.schema.hash] = hash
+	if r.schema.rng != "" {
+		if k.Range == nil {
+			return nil, errs.Newf(errs.Internal, "dynamo: %s requires a range key", r.table)
+		}
+		rng, err := attributevalue.Marshal(k.Range)
+		if err != nil {
+			return nil, errs.Errorf("dynamo: failed to marshal %s: %w", r.schema.rng, err)
+		}
+		key[r.schema.rng] = rng
+	}
+	return key, nil
+}
+
+// itemKey returns the table key attributes of a marshaled item
+func (r *Repository[T]) itemKey(item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	key := map[string]types.AttributeValue{r.schema.hash: item[r.schema.hash]}
+	if r.schema.rng != "" {
+		key[r.schema.rng] = item[r.schema.rng]
+	}
+	return key
+}
+
+// Get returns the item with key, or ErrNotFound
+func (r *Repository[T]) Get(ctx context.Context, key Key) (*T, error) {
+	k, err := r.key(key)
+	if err != nil {
+		return nil, err
+	}
+	out, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(r.table),
+		Key:            k,
+		ConsistentRead: aws.Bool(r.opts.ConsistentRead),
+	})
+	if err != nil {
+		return nil, errs.Errorf("dynamo: failed to get item from %s: %w", r.table, err)
+	}
+	if out.Item == nil {
+		return nil, errs.Errorf("%s %v: %w", r.table, key.Hash, ErrNotFound)
+	}
+	v := new(T)
+	if err := attributevalue.UnmarshalMap(out.Item, v); err != nil {
+		return nil, errs.Errorf("dynamo: failed to unmarshal item from %s: %w", r.table, err)
+	}
+	return v, nil
+}
+
+// Create writes item, failing with ErrConflict when an item with its key
+// exists. A versioned item is created with version 1.
+func (r *Repository[T]) Create(ctx context.Context, item *T) error {
+	version := r.version(item)
+	initial := version != nil && version.Int() == 0
+	if initial {
+		version.Set(1)
+	}
+	err := r.put(ctx, item, "attribute_not_exists(#hash)", map[string]string{"#hash": r.schema.hash}, nil)
+	if err != nil && initial {
+		version.Set(0)
+	}
+	return err
+}
+
+// Put writes item, replacing any item with its key. A versioned item is
+// written only if the stored version still equals item's, or if no item
+// exists when item's version is 0, and item's version is incremented;
+// otherwise Put fails with ErrConflict.
+func (r *Repository[T]) Put(ctx context.Context, item *T) error {
+	version := r.version(item)
+	if version == nil {
+		return r.put(ctx, item, "", nil, nil)
+	}
+	current := version.Int()
+	version.Set(current + 1)
+	var err error
+	if current == 0 {
+		err = r.put(ctx, item, "attribute_not_exists(#hash)", map[string]string{"#hash": r.schema.hash}, nil)
+	} else {
+		err = r.put(ctx, item, "#version = :version", map[string]string{"#version": r.schema.versionName},
+			map[string]types.AttributeValue{":version": &types.AttributeValueMemberN{Value: strconv.FormatInt(current, 10)}})
+	}
+	if err != nil {
+		version.Set(current)
+	}
+	return err
+}
+
+func (r *Repository[T]) put(ctx context.Context, item *T, condition string, names map[string]string, values map[string]types.AttributeValue) error {
+	av, err := attributevalue.MarshalMap(item)
+	if err != nil {
+		return errs.Errorf("dynamo: failed to marshal item for %s: %w", r.table, err)
+	}
+	input := &dynamodb.PutItemInput{TableName: aws.String(r.table), Item: av}
+	if condition != "" {
+		input.ConditionExpression = aws.String(condition)
+		input.ExpressionAttributeNames = names
+		input.ExpressionAttributeValues = values
+	}
+	_, err = r.client.PutItem(ctx, input)
+	if isConditionFailed(err) {
+		return errs.Errorf("%s: %w", r.table, ErrConflict)
+	}
+	if err != nil {
+		return errs.Errorf("dynamo: failed to put item in %s: %w", r.table, err)
+	}
+	return nil
+}
+
+// Delete deletes item by its key. A versioned item with a nonzero version is
+// deleted only if the stored version still equals item's, failing with
+// ErrConflict otherwise. Deleting a missing unversioned item is not an error.
+func (r *Repository[T]) Delete(ctx context.Context, item *T) error {
+	av, err := attributevalue.MarshalMap(item)
+	if err != nil {
+		return errs.Errorf("dynamo: failed to marshal item for %s: %w", r.table, err)
+	}
+	input := &dynamodb.DeleteItemInput{TableName: aws.String(r.table), Key: r.itemKey(av)}
+	if version := r.version(item); version != nil && version.Int() != 0 {
+		input.ConditionExpression = aws.String("#version = :version")
+		input.ExpressionAttributeNames = map[string]string{"#version": r.schema.versionName}
+		input.ExpressionAttributeValues = map[string]types.AttributeValue{":version": av[r.schema.versionName]}
+	}
+	_, err = r.client.DeleteItem(ctx, input)
+	if isConditionFailed(err) {
+		return errs.Errorf("%s: %w", r.table, ErrConflict)
+	}
+	if err != nil {
+		return errs.Errorf("dynamo: failed to delete item from %s: %w", r.table, err)
+	}
+	return nil
+}
+
+// version returns item's version field, or nil when T is not versioned
+func (r *Repository[T]) version(item *T) *versionField {
+	if r.schema.version == nil {
+		return nil
+	}
+	return &versionField{v: reflect.ValueOf(item).Elem().FieldByIndex(r.schema.version)}
+}
+
+// versionField is a signed or unsigned integer version field
+type versionField struct {
+	v reflect.Value
+}
+
+func (f *versionField) Int() int64 {
+	if f.v.CanInt() {
+		return f.v.Int()
+	}
+	return int64(f.v.Uint())
+}
+
+func (f *versionField) Set(n int64) {
+	if f.v.CanInt() {
+		f.v.SetInt(n)
+	} else {
+		f.v.SetUint(uint64(n))
+	}
+}
+
+func isConditionFailed(err error) bool {
+	var condErr *types.ConditionalCheckFailedException
+	return errors.As(err, &condErr)
+}
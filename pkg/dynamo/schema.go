@@ -0,0 +1,150 @@
+package dynamo
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// schema is the key schema declared by a struct's dynamo tags
+type schema struct {
+	hash    string
+	rng     string
+	indexes map[string]*indexKeys
+	// version is the index path of the version field, or nil
+	version []int
+	// versionName is the version attribute name
+	versionName string
+}
+
+// indexKeys are the key attributes of a secondary index; a local secondary
+// index has no hash attribute of its own
+type indexKeys struct {
+	hash string
+	rng  string
+}
+
+// parseSchema reads the dynamo tags of t, a struct type. Attribute names
+// follow the dynamodbav tags, as attributevalue.MarshalMap does.
+func parseSchema(t reflect.Type) (*schema, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, errs.Newf(errs.Internal, "dynamo: %s is not a struct", t)
+	}
+	s := &schema{indexes: map[string]*indexKeys{}}
+	if err := s.parseFields(t, nil); err != nil {
+		return nil, err
+	}
+	if s.hash == "" {
+		return nil, errs.Newf(errs.Internal, `dynamo: %s has no field tagged dynamo:"hash"`, t)
+	}
+	for name, idx := range s.indexes {
+		if idx.hash == "" && idx.rng == "" {
+			return nil, errs.Newf(errs.Internal, "dynamo: index %s of %s has no key attributes", name, t)
+		}
+	}
+	return s, nil
+}
+
+func (s *schema) parseFields(t reflect.Type, path []int) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, skip := attributeName(f)
+		if skip {
+			continue
+		}
+		index := append(append([]int(nil), path...), i)
+		// untagged embedded structs are flattened into the item
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && f.Tag.Get("dynamodbav") == "" {
+			if err := s.parseFields(f.Type, index); err != nil {
+				return err
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("dynamo")
+		if tag == "" {
+			continue
+		}
+		for _, opt := range strings.Split(tag, ",") {
+			role, indexName, _ := strings.Cut(strings.TrimSpace(opt), "=")
+			if err := s.setRole(f, name, role, indexName, index); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *schema) setRole(f reflect.StructField, name, role, indexName string, index []int) error {
+	set := func(dst *string) error {
+		if *dst != "" {
+			return errs.Newf(errs.Internal, "dynamo: %s and %s are both tagged %s", *dst, name, role)
+		}
+		*dst = name
+		return nil
+	}
+	idx := func() *indexKeys {
+		if s.indexes[indexName] == nil {
+			s.indexes[indexName] = &indexKeys{}
+		}
+		return s.indexes[indexName]
+	}
+	switch {
+	case role == "hash" && indexName == "":
+		return set(&s.hash)
+	case role == "range" && indexName == "":
+		return set(&s.rng)
+	case role == "hash":
+		return set(&idx().hash)
+	case role == "range":
+		return set(&idx().rng)
+	case role == "version" && indexName == "":
+		switch f.Type.Kind() {
+		case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		default:
+			return errs.Newf(errs.Internal, "dynamo: version field %s must be an integer, not %s", f.Name, f.Type)
+		}
+		if s.version != nil {
+			return errs.Newf(errs.Internal, "dynamo: %s and %s are both tagged version", s.versionName, name)
+		}
+		s.version = index
+		s.versionName = name
+		return nil
+	default:
+		return errs.Newf(errs.Internal, "dynamo: invalid tag option %q on %s", role, f.Name)
+	}
+}
+
+// attributeName returns the attribute a field marshals to, and whether the
+// field is skipped
+func attributeName(f reflect.StructField) (string, bool) {
+	name, _, _ := strings.Cut(f.Tag.Get("dynamodbav"), ",")
+	switch name {
+	case "-":
+		return "", true
+	case "":
+		return f.Name, false
+	default:
+		return name, false
+	}
+}
+
+// keyNames returns the hash and range attributes of index, or of the table
+// when index is empty
+func (s *schema) keyNames(index string) (string, string, error) {
+	if index == "" {
+		return s.hash, s.rng, nil
+	}
+	idx, ok := s.indexes[index]
+	if !ok {
+		return "", "", errs.Newf(errs.Internal, "dynamo: unknown index %s", index)
+	}
+	if idx.hash == "" {
+		// local secondary indexes share the table's partition key
+		return s.hash, idx.rng, nil
+	}
+	return idx.hash, idx.rng, nil
+}
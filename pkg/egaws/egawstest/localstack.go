@@ -0,0 +1,212 @@
+package egawstest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/bdlilley/easygo"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/localstack"
+	"gopkg.in/yaml.v3"
+)
+
+// LocalStackEndpointEnv names an already running LocalStack, e.g. a CI service
+// container, that StartLocalStack uses instead of starting its own
+const LocalStackEndpointEnv = "EASYGO_LOCALSTACK_ENDPOINT"
+
+const defaultLocalStackImage = "localstack/localstack:4"
+
+type LocalStackOptions struct {
+	// Image is the LocalStack image (default: localstack/localstack:4)
+	Image string
+	// Region is used by the client (default: us-east-1)
+	Region string
+	// Fixtures are seeded before StartLocalStack returns
+	Fixtures *Fixtures
+	// Env is passed to the container, e.g. SERVICES to start fewer services
+	Env map[string]string
+}
+
+// LocalStack is a running LocalStack with an EGAwsClient pointed at it
+type LocalStack struct {
+	// Endpoint is the LocalStack URL, e.g. http://127.0.0.1:32768
+	Endpoint string
+	Region   string
+	Client   *easygo.EGAwsClient
+}
+
+// StartLocalStack starts LocalStack in a container, or uses the one at
+// $EASYGO_LOCALSTACK_ENDPOINT, and seeds opts.Fixtures. The container is
+// removed when the test ends. The test is skipped in -short mode and when
+// neither Docker nor $EASYGO_LOCALSTACK_ENDPOINT is available.
+//
+// Containers are not shared between tests; start one in TestMain, or a
+// parent test with subtests, and give each test its own resource names.
+func StartLocalStack(tb testing.TB, opts *LocalStackOptions) *LocalStack {
+	tb.Helper()
+	if testing.Short() {
+		tb.Skip("skipping LocalStack test in short mode")
+	}
+	o := LocalStackOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Image == "" {
+		o.Image = defaultLocalStackImage
+	}
+	if o.Region == "" {
+		o.Region = "us-east-1"
+	}
+	ctx := context.Background()
+
+	endpoint := os.Getenv(LocalStackEndpointEnv)
+	if endpoint == "" {
+		if err := dockerHealth(ctx); err != nil {
+			tb.Skipf("skipping LocalStack test, Docker is not available: %v", err)
+		}
+		env := map[string]string{"AWS_DEFAULT_REGION": o.Region}
+		for k, v := range o.Env {
+			env[k] = v
+		}
+		ctr, err := localstack.Run(ctx, o.Image, testcontainers.WithEnv(env))
+		testcontainers.CleanupContainer(tb, ctr)
+		if err != nil {
+			tb.Fatalf("failed to start LocalStack: %v", err)
+		}
+		endpoint, err = ctr.PortEndpoint(ctx, "4566/tcp", "http")
+		if err != nil {
+			tb.Fatalf("failed to get LocalStack endpoint: %v", err)
+		}
+	}
+
+	client, err := easygo.NewAwsClient(ctx, &easygo.NewEGAwsClientArgs{
+		Region:                  o.Region,
+		Credentials:             credentials.NewStaticCredentialsProvider("test", "test", ""),
+		EndpointURL:             endpoint,
+		S3UsePathStyle:          true,
+		SkipCallerIdentityCheck: true,
+	})
+	if err != nil {
+		tb.Fatalf("failed to create LocalStack client: %v", err)
+	}
+	ls := &LocalStack{Endpoint: endpoint, Region: o.Region, Client: client}
+	if o.Fixtures != nil {
+		if err := ls.Seed(ctx, o.Fixtures); err != nil {
+			tb.Fatalf("failed to seed LocalStack: %v", err)
+		}
+	}
+	return ls
+}
+
+// dockerHealth reports whether a Docker provider is reachable; testcontainers
+// panics rather than failing in some environments without one
+func dockerHealth(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errs.Newf(errs.Internal, "%v", r)
+		}
+	}()
+	provider, err := testcontainers.ProviderDocker.GetProvider()
+	if err != nil {
+		return err
+	}
+	defer provider.Close()
+	return provider.Health(ctx)
+}
+
+// Fixtures are resources created in LocalStack before a test runs
+type Fixtures struct {
+	// Secrets maps secret names to values; values that are not strings are
+	// stored as JSON
+	Secrets map[string]any `json:"secrets" yaml:"secrets"`
+	// Parameters maps SSM parameter names to String values
+	Parameters map[string]string `json:"parameters" yaml:"parameters"`
+	// SecureParameters maps SSM parameter names to SecureString values
+	SecureParameters map[string]string `json:"secureParameters" yaml:"secureParameters"`
+	// Buckets maps bucket names to their objects' contents by key; a bucket
+	// with no objects is created empty
+	Buckets map[string]map[string]string `json:"buckets" yaml:"buckets"`
+}
+
+// LoadFixtures reads Fixtures from a YAML or JSON file
+func LoadFixtures(path string) (*Fixtures, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errs.Errorf("failed to read %s: %w", path, err)
+	}
+	f := &Fixtures{}
+	// YAML is a superset of JSON, so one decoder reads both
+	if err := yaml.Unmarshal(b, f); err != nil {
+		return nil, errs.Errorf("failed to parse %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Seed creates the fixtures' resources, overwriting secrets, parameters and
+// objects that already exist
+func (l *LocalStack) Seed(ctx context.Context, f *Fixtures) error {
+	secrets := l.Client.GetSecretsClient()
+	for name, value := range f.Secrets {
+		s, ok := value.(string)
+		if !ok {
+			b, err := json.Marshal(value)
+			if err != nil {
+				return errs.Errorf("failed to marshal secret %s: %w", name, err)
+			}
+			s = string(b)
+		}
+		_, err := secrets.CreateSecret(ctx, &secretsmanager.CreateSecretInput{Name: aws.String(name), SecretString: aws.String(s)})
+		if exists := (*types.ResourceExistsException)(nil); errors.As(err, &exists) {
+			_, err = secrets.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{SecretId: aws.String(name), SecretString: aws.String(s)})
+		}
+		if err != nil {
+			return errs.Errorf("failed to create secret %s: %w", name, err)
+		}
+	}
+
+	params := l.Client.GetSSMClient()
+	for typ, values := range map[ssmtypes.ParameterType]map[string]string{
+		ssmtypes.ParameterTypeString:       f.Parameters,
+		ssmtypes.ParameterTypeSecureString: f.SecureParameters,
+	} {
+		for name, value := range values {
+			_, err := params.PutParameter(ctx, &ssm.PutParameterInput{Name: aws.String(name), Value: aws.String(value), Type: typ, Overwrite: aws.Bool(true)})
+			if err != nil {
+				return errs.Errorf("failed to put parameter %s: %w", name, err)
+			}
+		}
+	}
+
+	buckets := l.Client.GetS3Client()
+	for bucket, objects := range f.Buckets {
+		input := &s3.CreateBucketInput{Bucket: aws.String(bucket)}
+		// us-east-1 rejects an explicit location constraint
+		if l.Region != "us-east-1" {
+			input.CreateBucketConfiguration = &s3types.CreateBucketConfiguration{LocationConstraint: s3types.BucketLocationConstraint(l.Region)}
+		}
+		_, err := buckets.CreateBucket(ctx, input)
+		if owned := (*s3types.BucketAlreadyOwnedByYou)(nil); err != nil && !errors.As(err, &owned) {
+			return errs.Errorf("failed to create bucket %s: %w", bucket, err)
+		}
+		for key, body := range objects {
+			_, err := buckets.PutObject(ctx, &s3.PutObjectInput{Bucket: aws.String(bucket), Key: aws.String(key), Body: strings.NewReader(body)})
+			if err != nil {
+				return errs.Errorf("failed to put s3://%s/%s: %w", bucket, key, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package egawstest
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const fixturesYAML = `
+secrets:
+  app/token: abc
+  app/db:
+    username: admin
+    port: 5432
+parameters:
+  /app/region: eu-west-1
+secureParameters:
+  /app/key: s3cr3t
+buckets:
+  uploads:
+    docs/readme.txt: hello
+  empty: {}
+`
+
+func TestLocalStack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures.yaml")
+	if err := os.WriteFile(path, []byte(fixturesYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fixtures, err := LoadFixtures(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures.Secrets) != 2 || fixtures.Parameters["/app/region"] != "eu-west-1" || len(fixtures.Buckets["empty"]) != 0 {
+		t.Fatalf("got fixtures %+v", fixtures)
+	}
+
+	ls := StartLocalStack(t, &LocalStackOptions{Fixtures: fixtures})
+	ctx := context.Background()
+
+	var db struct {
+		Username string `json:"username"`
+		Port     int    `json:"port"`
+	}
+	if err := ls.Client.GetLatestJsonSecretValue(ctx, "app/db", &db); err != nil || db.Username != "admin" || db.Port != 5432 {
+		t.Fatalf("got %+v %v", db, err)
+	}
+	if v, err := ls.Client.GetParameterValue(ctx, "/app/key"); err != nil || v != "s3cr3t" {
+		t.Fatalf("got %q %v", v, err)
+	}
+	out, err := ls.Client.GetS3Client().GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String("uploads"), Key: aws.String("docs/readme.txt")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Body.Close()
+	if b, _ := io.ReadAll(out.Body); string(b) != "hello" {
+		t.Fatalf("got object %q", b)
+	}
+
+	// seeding again overwrites rather than failing on existing resources
+	if err := ls.Seed(ctx, &Fixtures{Secrets: map[string]any{"app/token": "def"}, Buckets: map[string]map[string]string{"empty": nil}}); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := ls.Client.GetLatestSecretString(ctx, "app/token"); v != "def" {
+		t.Fatalf("got %q after reseeding", v)
+	}
+}
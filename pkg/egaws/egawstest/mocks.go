@@ -0,0 +1,116 @@
+package egawstest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/bdlilley/easygo"
+)
+
+// IdentityProvider is a fake easygo.IdentityProvider returning a fixed identity or Err
+type IdentityProvider struct {
+	Account string
+	Arn     string
+	UserID  string
+	Err     error
+}
+
+var _ easygo.IdentityProvider = (*IdentityProvider)(nil)
+
+func (p *IdentityProvider) GetCallerIdentity(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+	if p.Err != nil {
+		return nil, p.Err
+	}
+	return &sts.GetCallerIdentityOutput{
+		Account: aws.String(p.Account),
+		Arn:     aws.String(p.Arn),
+		UserId:  aws.String(p.UserID),
+	}, nil
+}
+
+// EventPublisher is a fake easygo.EventPublisher that records published events
+type EventPublisher struct {
+	// Err is returned from every call when set; events are not recorded
+	Err error
+
+	mu     sync.Mutex
+	events []easygo.EventBridgeEvent
+}
+
+var _ easygo.EventPublisher = (*EventPublisher)(nil)
+
+func (p *EventPublisher) PutEvent(ctx context.Context, busName, source, detailType string, detail any) error {
+	_, err := p.PutEvents(ctx, []easygo.EventBridgeEvent{{
+		BusName:    busName,
+		Source:     source,
+		DetailType: detailType,
+		Detail:     detail,
+	}})
+	return err
+}
+
+func (p *EventPublisher) PutEvents(ctx context.Context, events []easygo.EventBridgeEvent) ([]string, error) {
+	if p.Err != nil {
+		return nil, p.Err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ids := make([]string, len(events))
+	for i := range events {
+		ids[i] = fmt.Sprintf("event-%d", len(p.events)+1)
+		p.events = append(p.events, events[i])
+	}
+	return ids, nil
+}
+
+// Events returns the events published so far
+func (p *EventPublisher) Events() []easygo.EventBridgeEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]easygo.EventBridgeEvent{}, p.events...)
+}
+
+// ParameterStore is a fake easygo.ParameterReader backed by a map. Missing
+// parameters return a *types.ParameterNotFound like SSM.
+type ParameterStore struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+var _ easygo.ParameterReader = (*ParameterStore)(nil)
+
+// SetParameter sets the value of name
+func (p *ParameterStore) SetParameter(name, value string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.values == nil {
+		p.values = map[string]string{}
+	}
+	p.values[name] = value
+}
+
+func (p *ParameterStore) GetParameterValue(ctx context.Context, name string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	v, ok := p.values[name]
+	if !ok {
+		return "", &ssmtypes.ParameterNotFound{Message: aws.String(fmt.Sprintf("parameter %s not found", name))}
+	}
+	return v, nil
+}
+
+// HealthChecker is a fake easygo.HealthChecker returning Err
+type HealthChecker struct {
+	Err error
+}
+
+var _ easygo.HealthChecker = (*HealthChecker)(nil)
+
+func (h *HealthChecker) HealthCheck(ctx context.Context) error {
+	return h.Err
+}
@@ -0,0 +1,170 @@
+// Package egawstest provides in-memory fakes of the easygo AWS client interfaces
+// for unit testing code that depends on them, and a LocalStack harness for
+// integration tests against real AWS APIs.
+package egawstest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/bdlilley/easygo"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+type secretVersion struct {
+	id     string
+	value  []byte
+	stages []string
+}
+
+// SecretsStore is an in-memory easygo.SecretsReadWriter. It tracks versions and
+// staging labels like Secrets Manager: a new version takes AWSCURRENT and the
+// previous current version becomes AWSPREVIOUS. Missing secrets return a
+// *types.ResourceNotFoundException so AWS error classification works as in production.
+type SecretsStore struct {
+	mu      sync.Mutex
+	secrets map[string][]*secretVersion
+	nextID  int
+}
+
+var _ easygo.SecretsReadWriter = (*SecretsStore)(nil)
+
+// NewSecretsStore returns an empty SecretsStore
+func NewSecretsStore() *SecretsStore {
+	return &SecretsStore{secrets: map[string][]*secretVersion{}}
+}
+
+// SetSecret writes value as the current version of name
+func (s *SecretsStore) SetSecret(name, value string) {
+	s.put(name, []byte(value), "", nil)
+}
+
+// SetJSONSecret marshals value and writes it as the current version of name
+func (s *SecretsStore) SetJSONSecret(name string, value any) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return errs.Errorf("failed to marshal secret value: %w", err)
+	}
+	s.put(name, b, "", nil)
+	return nil
+}
+
+// Delete removes name and all of its versions
+func (s *SecretsStore) Delete(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.secrets, name)
+}
+
+func (s *SecretsStore) GetLatestJsonSecretValue(ctx context.Context, secretNameOrArn string, result any, opts ...easygo.SecretValueOption) error {
+	b, err := s.GetLatestSecretBytes(ctx, secretNameOrArn, opts...)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, result); err != nil {
+		return errs.Errorf("failed to unmarshal byte value: %w", err)
+	}
+	return nil
+}
+
+func (s *SecretsStore) GetLatestSecretString(ctx context.Context, secretNameOrArn string, opts ...easygo.SecretValueOption) (string, error) {
+	b, err := s.GetLatestSecretBytes(ctx, secretNameOrArn, opts...)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (s *SecretsStore) GetLatestSecretBytes(ctx context.Context, secretNameOrArn string, opts ...easygo.SecretValueOption) ([]byte, error) {
+	input := &secretsmanager.GetSecretValueInput{SecretId: aws.String(secretNameOrArn)}
+	for _, opt := range opts {
+		opt(input)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions, ok := s.secrets[secretNameOrArn]
+	if !ok {
+		return nil, notFound(fmt.Sprintf("secret %s not found", secretNameOrArn))
+	}
+
+	stage := aws.ToString(input.VersionStage)
+	if stage == "" && input.VersionId == nil {
+		stage = easygo.SecretVersionStageCurrent
+	}
+	for _, v := range versions {
+		if input.VersionId != nil && v.id != *input.VersionId {
+			continue
+		}
+		if stage != "" && !slices.Contains(v.stages, stage) {
+			continue
+		}
+		return slices.Clone(v.value), nil
+	}
+
+	return nil, notFound(fmt.Sprintf("secret %s has no matching version", secretNameOrArn))
+}
+
+func (s *SecretsStore) PutJsonSecretValue(ctx context.Context, secretNameOrArn string, value any, opts ...easygo.PutSecretOption) (string, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", errs.Errorf("failed to marshal secret value: %w", err)
+	}
+	return s.PutSecretString(ctx, secretNameOrArn, string(b), opts...)
+}
+
+func (s *SecretsStore) PutSecretString(ctx context.Context, secretNameOrArn string, value string, opts ...easygo.PutSecretOption) (string, error) {
+	o := easygo.ResolvePutSecretOptions(opts...)
+	return s.put(secretNameOrArn, []byte(value), o.ClientRequestToken, o.VersionStages), nil
+}
+
+func (s *SecretsStore) put(name string, value []byte, versionID string, stages []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if versionID == "" {
+		s.nextID++
+		versionID = fmt.Sprintf("version-%d", s.nextID)
+	}
+	if len(stages) == 0 {
+		stages = []string{easygo.SecretVersionStageCurrent}
+	}
+
+	versions := s.secrets[name]
+	for _, stage := range stages {
+		for _, v := range versions {
+			if !slices.Contains(v.stages, stage) {
+				continue
+			}
+			v.stages = slices.DeleteFunc(v.stages, func(st string) bool { return st == stage })
+			if stage == easygo.SecretVersionStageCurrent {
+				// the old current version becomes the only previous version
+				for _, other := range versions {
+					other.stages = slices.DeleteFunc(other.stages, func(st string) bool {
+						return st == easygo.SecretVersionStagePrevious
+					})
+				}
+				v.stages = append(v.stages, easygo.SecretVersionStagePrevious)
+			}
+		}
+	}
+
+	s.secrets[name] = append([]*secretVersion{{
+		id:     versionID,
+		value:  slices.Clone(value),
+		stages: slices.Clone(stages),
+	}}, versions...)
+
+	return versionID
+}
+
+func notFound(message string) error {
+	return &types.ResourceNotFoundException{Message: aws.String(message)}
+}
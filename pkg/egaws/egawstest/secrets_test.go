@@ -0,0 +1,65 @@
+package egawstest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bdlilley/easygo"
+	egerrors "github.com/bdlilley/easygo/pkg/egaws/errors"
+)
+
+func TestSecretsStoreVersions(t *testing.T) {
+	ctx := context.Background()
+	store := NewSecretsStore()
+	store.SetSecret("db", "v1")
+
+	v2, err := store.PutSecretString(ctx, "db", "v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := store.GetLatestSecretString(ctx, "db")
+	if err != nil || current != "v2" {
+		t.Fatalf("current = %q, %v; want v2", current, err)
+	}
+	previous, err := store.GetLatestSecretString(ctx, "db", easygo.WithVersionStage(easygo.SecretVersionStagePrevious))
+	if err != nil || previous != "v1" {
+		t.Fatalf("previous = %q, %v; want v1", previous, err)
+	}
+	byID, err := store.GetLatestSecretString(ctx, "db", easygo.WithVersionID(v2))
+	if err != nil || byID != "v2" {
+		t.Fatalf("by version id = %q, %v; want v2", byID, err)
+	}
+
+	if _, err := store.PutSecretString(ctx, "db", "v3", easygo.WithPutVersionStages(easygo.SecretVersionStagePending)); err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := store.GetLatestSecretString(ctx, "db"); current != "v2" {
+		t.Fatalf("pending version replaced current: got %q", current)
+	}
+
+	_, err = store.GetLatestSecretString(ctx, "missing")
+	if !egerrors.IsNotFound(err) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
+func TestSecretsStoreJSON(t *testing.T) {
+	ctx := context.Background()
+	store := NewSecretsStore()
+
+	type creds struct {
+		Username string `json:"username"`
+	}
+	if _, err := store.PutJsonSecretValue(ctx, "creds", creds{Username: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got creds
+	if err := store.GetLatestJsonSecretValue(ctx, "creds", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Username != "admin" {
+		t.Fatalf("username = %q, want admin", got.Username)
+	}
+}
@@ -0,0 +1,119 @@
+// Package errors classifies errors returned by AWS SDK clients so retry and
+// alerting logic can branch on error kinds instead of matching error text.
+// Import it with an alias to avoid shadowing the standard library:
+//
+//	import egerrors "github.com/bdlilley/easygo/pkg/egaws/errors"
+package errors
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+)
+
+var notFoundCodes = map[string]struct{}{
+	"ResourceNotFoundException":               {},
+	"NotFound":                                {},
+	"NotFoundException":                       {},
+	"NoSuchKey":                               {},
+	"NoSuchBucket":                            {},
+	"NoSuchEntity":                            {},
+	"NoSuchHostedZone":                        {},
+	"ParameterNotFound":                       {},
+	"RepositoryNotFoundException":             {},
+	"ImageNotFoundException":                  {},
+	"QueueDoesNotExist":                       {},
+	"AWS.SimpleQueueService.NonExistentQueue": {},
+}
+
+var accessDeniedCodes = map[string]struct{}{
+	"AccessDenied":                {},
+	"AccessDeniedException":       {},
+	"UnauthorizedOperation":       {},
+	"UnauthorizedException":       {},
+	"UnrecognizedClientException": {},
+	"AuthorizationError":          {},
+	"InvalidClientTokenId":        {},
+	"SignatureDoesNotMatch":       {},
+}
+
+var expiredCredentialsCodes = map[string]struct{}{
+	"ExpiredToken":          {},
+	"ExpiredTokenException": {},
+	"RequestExpired":        {},
+	"TokenRefreshRequired":  {},
+}
+
+// ErrorCode returns the AWS API error code of err, or "" if err is not an API error
+func ErrorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return ""
+}
+
+// HTTPStatusCode returns the HTTP status code of the response that produced err, or 0
+func HTTPStatusCode(err error) int {
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.HTTPStatusCode()
+	}
+	return 0
+}
+
+// RequestID returns the AWS request ID of the response that produced err, or ""
+func RequestID(err error) string {
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.ServiceRequestID()
+	}
+	return ""
+}
+
+// IsThrottled reports whether err is a throttling or rate limit error
+func IsThrottled(err error) bool {
+	if _, ok := retry.DefaultThrottleErrorCodes[ErrorCode(err)]; ok {
+		return true
+	}
+	return HTTPStatusCode(err) == http.StatusTooManyRequests
+}
+
+// IsNotFound reports whether err means the requested resource does not exist
+func IsNotFound(err error) bool {
+	if _, ok := notFoundCodes[ErrorCode(err)]; ok {
+		return true
+	}
+	return HTTPStatusCode(err) == http.StatusNotFound
+}
+
+// IsAccessDenied reports whether err is an authorization failure
+func IsAccessDenied(err error) bool {
+	_, ok := accessDeniedCodes[ErrorCode(err)]
+	return ok
+}
+
+// IsExpiredCredentials reports whether err was caused by expired credentials
+func IsExpiredCredentials(err error) bool {
+	_, ok := expiredCredentialsCodes[ErrorCode(err)]
+	return ok
+}
+
+// IsRetryable reports whether the SDK's standard retryer would consider err retryable
+func IsRetryable(err error) bool {
+	for _, r := range retry.DefaultRetryables {
+		if r.IsErrorRetryable(err).Bool() {
+			return true
+		}
+	}
+	return false
+}
+
+// IsClientFault reports whether AWS attributed err to the caller (e.g. invalid parameters)
+func IsClientFault(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorFault() == smithy.FaultClient
+}
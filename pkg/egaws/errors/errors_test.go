@@ -0,0 +1,49 @@
+package errors
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+func responseError(status int, requestID string, err error) error {
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+			Err:      err,
+		},
+		RequestID: requestID,
+	}
+}
+
+func TestClassification(t *testing.T) {
+	throttled := responseError(400, "req-1", &smithy.GenericAPIError{Code: "ThrottlingException"})
+	notFound := fmt.Errorf("get secret: %w", responseError(400, "req-2", &smithy.GenericAPIError{Code: "ResourceNotFoundException"}))
+	denied := responseError(403, "req-3", &smithy.GenericAPIError{Code: "AccessDeniedException", Fault: smithy.FaultClient})
+
+	if !IsThrottled(throttled) || IsThrottled(notFound) {
+		t.Error("IsThrottled misclassified")
+	}
+	if !IsNotFound(notFound) || IsNotFound(denied) {
+		t.Error("IsNotFound misclassified")
+	}
+	if !IsAccessDenied(denied) || IsAccessDenied(throttled) {
+		t.Error("IsAccessDenied misclassified")
+	}
+	if !IsClientFault(denied) {
+		t.Error("IsClientFault misclassified")
+	}
+	if got := RequestID(notFound); got != "req-2" {
+		t.Errorf("RequestID = %q, want req-2", got)
+	}
+	if got := HTTPStatusCode(denied); got != 403 {
+		t.Errorf("HTTPStatusCode = %d, want 403", got)
+	}
+	if RequestID(fmt.Errorf("plain")) != "" || ErrorCode(nil) != "" {
+		t.Error("expected empty values for non-AWS errors")
+	}
+}
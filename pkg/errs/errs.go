@@ -0,0 +1,250 @@
+// Package errs classifies application errors by Kind so transports can map
+// them without matching error text: httpserver turns them into problem+json
+// responses with the kind's status, and logs include the stack captured when
+// the error was created or last wrapped.
+//
+//	var ErrOrderNotFound = errs.New(errs.NotFound, "order not found")
+//
+//	if err := db.Get(ctx, id, &order); err != nil {
+//		return errs.Wrap(err, errs.Internal, "failed to load order")
+//	}
+//
+// Errorf adds context without changing the kind, the way eris.Wrapf does:
+//
+//	return errs.Errorf("order %s: %w", id, ErrOrderNotFound)
+//
+// Messages of client error kinds are shown to clients, so keep internal
+// details in the wrapped cause.
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+)
+
+// Kind classifies an error
+type Kind int
+
+const (
+	// Unknown is the kind of errors not created by this package
+	Unknown Kind = iota
+	NotFound
+	InvalidArgument
+	Unauthenticated
+	PermissionDenied
+	Conflict
+	Throttled
+	Unavailable
+	Internal
+)
+
+var kindNames = map[Kind]string{
+	Unknown:          "unknown",
+	NotFound:         "not_found",
+	InvalidArgument:  "invalid_argument",
+	Unauthenticated:  "unauthenticated",
+	PermissionDenied: "permission_denied",
+	Conflict:         "conflict",
+	Throttled:        "throttled",
+	Unavailable:      "unavailable",
+	Internal:         "internal",
+}
+
+var kindStatuses = map[Kind]int{
+	NotFound:         http.StatusNotFound,
+	InvalidArgument:  http.StatusBadRequest,
+	Unauthenticated:  http.StatusUnauthorized,
+	PermissionDenied: http.StatusForbidden,
+	Conflict:         http.StatusConflict,
+	Throttled:        http.StatusTooManyRequests,
+	Unavailable:      http.StatusServiceUnavailable,
+}
+
+// String returns the kind's snake_case name, used as the problem code
+func (k Kind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return kindNames[Unknown]
+}
+
+// HTTPStatus returns the response status for the kind; Unknown and Internal
+// are 500
+func (k Kind) HTTPStatus() int {
+	if status, ok := kindStatuses[k]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is an error with a Kind, a message and the stack where it was created
+type Error struct {
+	Kind Kind
+	// Message describes the error; it is shown to clients for kinds with a
+	// 4xx status
+	Message string
+	// Err is the wrapped cause, if any
+	Err   error
+	stack []uintptr
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Err == nil:
+		return e.Message
+	case e.Message == "":
+		return e.Err.Error()
+	default:
+		return e.Message + ": " + e.Err.Error()
+	}
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// StackFrames returns the program counters captured when e was created, the
+// same method eris errors and Sentry use
+func (e *Error) StackFrames() []uintptr {
+	return e.stack
+}
+
+// Format prints the error for %s and %v, and the stack as well for %+v
+func (e *Error) Format(s fmt.State, verb rune) {
+	_, _ = io.WriteString(s, e.Error())
+	if verb == 'v' && s.Flag('+') {
+		_, _ = io.WriteString(s, "\n"+StackTrace(e))
+	}
+}
+
+// New returns an error of kind with msg
+func New(kind Kind, msg string) error {
+	return &Error{Kind: kind, Message: msg, stack: callers(3)}
+}
+
+// Newf is New with a formatted message
+func Newf(kind Kind, format string, args ...any) error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...), stack: callers(3)}
+}
+
+// Errorf formats an error like fmt.Errorf and captures the stack. It is the
+// replacement for eris.Wrapf: wrap a cause with %w and the result keeps the
+// cause's kind and client message, so context added here is never shown to
+// clients.
+//
+//	return errs.Errorf("failed to read %s: %w", key, err)
+func Errorf(format string, args ...any) error {
+	return &Error{Err: fmt.Errorf(format, args...), stack: callers(3)}
+}
+
+// Wrap returns err with kind and msg, or nil when err is nil. Unknown keeps
+// the kind of err. The stack is captured where Wrap is called.
+func Wrap(err error, kind Kind, msg string) error {
+	if err == nil {
+		return nil
+	}
+	return wrap(err, kind, msg)
+}
+
+// Wrapf is Wrap with a formatted message
+func Wrapf(err error, kind Kind, format string, args ...any) error {
+	if err == nil {
+		return nil
+	}
+	return wrap(err, kind, fmt.Sprintf(format, args...))
+}
+
+func wrap(err error, kind Kind, msg string) error {
+	e := &Error{Kind: kind, Message: msg, Err: err}
+	if e.Kind == Unknown {
+		e.Kind = KindOf(err)
+	}
+	e.stack = callers(4)
+	return e
+}
+
+// KindOf returns the kind of the outermost *Error in err's chain, or Unknown
+func KindOf(err error) Kind {
+	for err != nil {
+		var e *Error
+		if !errors.As(err, &e) {
+			return Unknown
+		}
+		if e.Kind != Unknown {
+			return e.Kind
+		}
+		err = e.Err
+	}
+	return Unknown
+}
+
+// Is reports whether err has kind
+func Is(err error, kind Kind) bool {
+	return KindOf(err) == kind
+}
+
+// Message returns the message of the outermost *Error in err's chain that
+// has one, e.g. for a client-facing detail
+func Message(err error) string {
+	for err != nil {
+		var e *Error
+		if !errors.As(err, &e) {
+			return ""
+		}
+		if e.Message != "" {
+			return e.Message
+		}
+		err = e.Err
+	}
+	return ""
+}
+
+// stackFramer is implemented by errors carrying a stack, including *Error
+// and eris errors
+type stackFramer interface {
+	StackFrames() []uintptr
+}
+
+// Stack returns the outermost stack captured in err's chain, or nil. Sentinel
+// errors created with New at package level carry the init stack, so the
+// outermost stack, where the error was last wrapped, is the one that points
+// at the failing call.
+func Stack(err error) []uintptr {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if s, ok := err.(stackFramer); ok && len(s.StackFrames()) > 0 {
+			return s.StackFrames()
+		}
+	}
+	return nil
+}
+
+// StackTrace formats Stack(err) one "function file:line" frame per line, or
+// returns "" when err carries no stack
+func StackTrace(err error) string {
+	stack := Stack(err)
+	if len(stack) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	frames := runtime.CallersFrames(stack)
+	for {
+		f, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// callers captures the stack above the skip frames, which include
+// runtime.Callers and callers itself
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(skip, pcs)
+	return pcs[:n]
+}
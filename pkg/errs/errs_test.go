@@ -0,0 +1,67 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rotisserie/eris"
+)
+
+var errMissing = New(NotFound, "order not found")
+
+func TestKinds(t *testing.T) {
+	cause := errors.New("connection refused")
+	wrapped := Wrap(cause, Unavailable, "orders database unavailable")
+	layered := fmt.Errorf("handler: %w", Wrap(eris.Wrap(errMissing, "loading order 42"), Unknown, ""))
+
+	for name, tc := range map[string]struct {
+		err     error
+		kind    Kind
+		status  int
+		message string
+	}{
+		"new":        {errMissing, NotFound, http.StatusNotFound, "order not found"},
+		"wrapped":    {wrapped, Unavailable, http.StatusServiceUnavailable, "orders database unavailable"},
+		"layered":    {layered, NotFound, http.StatusNotFound, "order not found"},
+		"foreign":    {cause, Unknown, http.StatusInternalServerError, ""},
+		"formatted":  {Newf(InvalidArgument, "page size %d exceeds %d", 500, 100), InvalidArgument, http.StatusBadRequest, "page size 500 exceeds 100"},
+		"context":    {Errorf("order %d: %w", 42, errMissing), NotFound, http.StatusNotFound, "order not found"},
+		"overridden": {Wrap(errMissing, Internal, "inconsistent index"), Internal, http.StatusInternalServerError, "inconsistent index"},
+	} {
+		if KindOf(tc.err) != tc.kind || KindOf(tc.err).HTTPStatus() != tc.status || Message(tc.err) != tc.message {
+			t.Errorf("%s: got %s/%d/%q", name, KindOf(tc.err), KindOf(tc.err).HTTPStatus(), Message(tc.err))
+		}
+	}
+	if !errors.Is(wrapped, cause) || !errors.Is(layered, errMissing) || !Is(layered, NotFound) {
+		t.Fatal("errors.Is does not see through the wrapping")
+	}
+	if got := wrapped.Error(); got != "orders database unavailable: connection refused" {
+		t.Fatalf("got %q", got)
+	}
+	if Wrap(nil, Internal, "x") != nil || Wrapf(nil, Internal, "%d", 1) != nil {
+		t.Fatal("wrapping nil should return nil")
+	}
+}
+
+var errSentinel = New(NotFound, "disk not found")
+
+func TestStack(t *testing.T) {
+	err := Wrap(errors.New("boom"), Internal, "failed")
+	if trace := StackTrace(err); !strings.Contains(trace, "errs.TestStack") {
+		t.Fatalf("stack does not start at the caller:\n%s", trace)
+	}
+	if got := fmt.Sprintf("%+v", err); !strings.HasPrefix(got, "failed: boom\n") || !strings.Contains(got, "errs_test.go") {
+		t.Fatalf("got %q", got)
+	}
+
+	// wrapping a sentinel reports where it was wrapped, not the init stack
+	if trace := StackTrace(Wrap(errSentinel, Internal, "write failed")); !strings.Contains(trace, "errs.TestStack") {
+		t.Fatalf("stack does not start at the wrap:\n%s", trace)
+	}
+	if trace := StackTrace(errSentinel); strings.Contains(trace, "errs.TestStack") {
+		t.Fatalf("sentinel has a caller stack:\n%s", trace)
+	}
+}
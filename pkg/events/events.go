@@ -0,0 +1,270 @@
+// Package events is an in-process publish/subscribe bus with typed topics,
+// for decoupling modules within one service. Subscribers receive events
+// synchronously in the publisher's goroutine or asynchronously from a
+// buffered queue; a panicking subscriber does not affect the others, and
+// Close drains queued events before shutdown.
+package events
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+)
+
+// ErrClosed is returned when publishing or subscribing after Close
+var ErrClosed = errs.New(errs.Unavailable, "events: bus is closed")
+
+// Mode is how a subscriber receives events
+type Mode int
+
+const (
+	// Sync subscribers run in the publisher's goroutine before Publish
+	// returns, and their errors are returned by Publish
+	Sync Mode = iota
+	// Async subscribers run in their own goroutine from a buffered queue;
+	// Publish blocks only while the queue is full, and errors go to the
+	// bus ErrorHandler
+	Async
+)
+
+type NewBusArgs struct {
+	// ErrorHandler receives errors and panics from async subscribers
+	// (default: log them to Logger)
+	ErrorHandler func(topic string, err error)
+	// Logger logs async subscriber errors at error without an ErrorHandler
+	// (default: logging.Noop)
+	Logger logging.Logger
+}
+
+// Bus delivers events published to its topics. It is safe for concurrent use.
+type Bus struct {
+	errorHandler func(topic string, err error)
+
+	// mu guards topic subscriber lists; it is never held while sending to
+	// async queues, so Close and unsubscribe do not wait on full queues
+	mu     sync.RWMutex
+	closed bool
+	// closers stop async subscribers, keyed by subscriber
+	closers map[any]func()
+	workers sync.WaitGroup
+}
+
+// NewBus creates a Bus; args may be nil
+func NewBus(args *NewBusArgs) *Bus {
+	if args == nil {
+		args = &NewBusArgs{}
+	}
+	b := &Bus{errorHandler: args.ErrorHandler, closers: map[any]func(){}}
+	if b.errorHandler == nil {
+		logger := args.Logger
+		if logger == nil {
+			logger = logging.Noop()
+		}
+		b.errorHandler = func(topic string, err error) {
+			logger.WithError(err).WithField("topic", topic).Error("event subscriber failed")
+		}
+	}
+	return b
+}
+
+// Close stops accepting events and waits until async subscribers have
+// handled every queued event or ctx is done. Use it as an app component's
+// stop function: app.Func(nil, bus.Close).
+func (b *Bus) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		for _, stop := range b.closers {
+			stop()
+		}
+		clear(b.closers)
+	}
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errs.Errorf("events: timed out draining subscribers: %w", ctx.Err())
+	}
+}
+
+// Handler receives events of type T
+type Handler[T any] func(ctx context.Context, event T) error
+
+type SubscribeOptions struct {
+	// Mode is Sync or Async (default: Sync)
+	Mode Mode
+	// Buffer is the queue length of Async subscribers (default: 64)
+	Buffer int
+}
+
+// Topic publishes events of type T to its subscribers. Declare topics once
+// and share them between the publishing and subscribing modules:
+//
+//	var OrderPlaced = events.NewTopic[Order](bus, "order.placed")
+type Topic[T any] struct {
+	bus  *Bus
+	name string
+
+	// subs is replaced rather than modified, under bus.mu
+	subs []*subscriber[T]
+}
+
+type subscriber[T any] struct {
+	handler Handler[T]
+	// queue is nil for Sync subscribers
+	queue chan envelope[T]
+	// done is closed when the subscriber is removed or the bus closes; the
+	// worker then drains queue and exits. queue itself is never closed, so
+	// publishers racing with Close cannot send on a closed channel.
+	done chan struct{}
+}
+
+type envelope[T any] struct {
+	ctx   context.Context
+	event T
+}
+
+// NewTopic creates a topic named name on bus
+func NewTopic[T any](bus *Bus, name string) *Topic[T] {
+	return &Topic[T]{bus: bus, name: name}
+}
+
+// Name returns the topic name
+func (t *Topic[T]) Name() string {
+	return t.name
+}
+
+// Subscribe adds handler to the topic; opts may be nil. Call unsubscribe to
+// remove it, after which an Async handler finishes its queued events.
+func (t *Topic[T]) Subscribe(handler Handler[T], opts *SubscribeOptions) (unsubscribe func(), err error) {
+	if opts == nil {
+		opts = &SubscribeOptions{}
+	}
+	s := &subscriber[T]{handler: handler}
+
+	b := t.bus
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+
+	if opts.Mode == Async {
+		size := opts.Buffer
+		if size <= 0 {
+			size = 64
+		}
+		s.queue = make(chan envelope[T], size)
+		s.done = make(chan struct{})
+		b.closers[s] = func() { close(s.done) }
+		b.workers.Add(1)
+		go t.run(s)
+	}
+	t.subs = append(slices.Clip(t.subs), s)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			t.subs = slices.DeleteFunc(slices.Clone(t.subs), func(o *subscriber[T]) bool { return o == s })
+			if stop, ok := b.closers[s]; ok {
+				stop()
+				delete(b.closers, s)
+			}
+		})
+	}, nil
+}
+
+// Publish delivers event to every subscriber. Sync subscribers run first
+// and their joined errors are returned; Async subscribers receive event on
+// their queue, with ctx values but not its cancellation.
+func (t *Topic[T]) Publish(ctx context.Context, event T) error {
+	b := t.bus
+	b.mu.RLock()
+	closed, subs := b.closed, t.subs
+	b.mu.RUnlock()
+	if closed {
+		return ErrClosed
+	}
+
+	// sync handlers run without the lock so they may publish and subscribe
+	var failures []error
+	for _, s := range subs {
+		if s.queue != nil {
+			continue
+		}
+		if err := t.handle(ctx, s, event); err != nil {
+			failures = append(failures, err)
+		}
+	}
+
+	env := envelope[T]{ctx: context.WithoutCancel(ctx), event: event}
+	for _, s := range subs {
+		if s.queue == nil {
+			continue
+		}
+		select {
+		case <-s.done:
+			continue
+		default:
+		}
+		select {
+		case s.queue <- env:
+		case <-s.done:
+			// unsubscribed or closed while the queue was full
+		case <-ctx.Done():
+			failures = append(failures, errs.Errorf("events: topic %s: subscriber queue full: %w", t.name, ctx.Err()))
+			return errors.Join(failures...)
+		}
+	}
+	return errors.Join(failures...)
+}
+
+// run handles queued events until the subscriber is stopped, then handles
+// the events still queued
+func (t *Topic[T]) run(s *subscriber[T]) {
+	defer t.bus.workers.Done()
+	for {
+		select {
+		case env := <-s.queue:
+			t.deliver(s, env)
+		case <-s.done:
+			for {
+				select {
+				case env := <-s.queue:
+					t.deliver(s, env)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (t *Topic[T]) deliver(s *subscriber[T], env envelope[T]) {
+	if err := t.handle(env.ctx, s, env.event); err != nil {
+		t.bus.errorHandler(t.name, err)
+	}
+}
+
+// handle calls the subscriber, converting a panic into an error
+func (t *Topic[T]) handle(ctx context.Context, s *subscriber[T], event T) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errs.Newf(errs.Internal, "events: panic handling %s: %v", t.name, r)
+		}
+	}()
+	return s.handler(ctx, event)
+}
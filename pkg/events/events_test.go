@@ -0,0 +1,159 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type orderPlaced struct {
+	ID string
+}
+
+func TestSyncDelivery(t *testing.T) {
+	bus := NewBus(nil)
+	topic := NewTopic[orderPlaced](bus, "order.placed")
+
+	var got []string
+	if _, err := topic.Subscribe(func(_ context.Context, e orderPlaced) error {
+		got = append(got, e.ID)
+		return nil
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+	boom := errors.New("boom")
+	unsubscribe, _ := topic.Subscribe(func(context.Context, orderPlaced) error { return boom }, nil)
+	if _, err := topic.Subscribe(func(context.Context, orderPlaced) error { panic("bad subscriber") }, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	err := topic.Publish(context.Background(), orderPlaced{ID: "1"})
+	if !errors.Is(err, boom) {
+		t.Fatalf("got %v, want boom and the panic joined", err)
+	}
+	if len(got) != 1 || got[0] != "1" {
+		t.Fatalf("got %v", got)
+	}
+
+	unsubscribe()
+	if err := topic.Publish(context.Background(), orderPlaced{ID: "2"}); errors.Is(err, boom) {
+		t.Fatal("unsubscribed handler still called")
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %v, want the other subscriber to still receive events", got)
+	}
+}
+
+func TestAsyncDrainsOnClose(t *testing.T) {
+	var mu sync.Mutex
+	var handlerErrs []error
+	bus := NewBus(&NewBusArgs{ErrorHandler: func(_ string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		handlerErrs = append(handlerErrs, err)
+	}})
+	topic := NewTopic[int](bus, "numbers")
+
+	var sum atomic.Int64
+	release := make(chan struct{})
+	if _, err := topic.Subscribe(func(_ context.Context, n int) error {
+		<-release
+		if n == 3 {
+			panic("three")
+		}
+		sum.Add(int64(n))
+		return nil
+	}, &SubscribeOptions{Mode: Async, Buffer: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	for n := 1; n <= 5; n++ {
+		if err := topic.Publish(context.Background(), n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sum.Load() != 0 {
+		t.Fatal("async subscriber ran in the publisher's goroutine")
+	}
+	close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := bus.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if sum.Load() != 12 {
+		t.Fatalf("got sum %d, want 12 from the drained events", sum.Load())
+	}
+	if len(handlerErrs) != 1 {
+		t.Fatalf("got %v, want the panic reported", handlerErrs)
+	}
+	if err := topic.Publish(context.Background(), 6); !errors.Is(err, ErrClosed) {
+		t.Fatalf("got %v, want ErrClosed", err)
+	}
+}
+
+func TestAsyncFullQueueRespectsContext(t *testing.T) {
+	bus := NewBus(nil)
+	topic := NewTopic[int](bus, "numbers")
+	block := make(chan struct{})
+	defer close(block)
+	if _, err := topic.Subscribe(func(context.Context, int) error {
+		<-block
+		return nil
+	}, &SubscribeOptions{Mode: Async, Buffer: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var err error
+	for i := 0; i < 3 && err == nil; i++ {
+		err = topic.Publish(ctx, i)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want deadline exceeded once the queue is full", err)
+	}
+}
+
+func TestCloseWithPublisherBlocked(t *testing.T) {
+	bus := NewBus(nil)
+	topic := NewTopic[int](bus, "numbers")
+	block := make(chan struct{})
+	started := make(chan struct{}, 3)
+	if _, err := topic.Subscribe(func(context.Context, int) error {
+		started <- struct{}{}
+		<-block
+		return nil
+	}, &SubscribeOptions{Mode: Async, Buffer: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the handler holds one event and the queue another, so this publish
+	// blocks on the full queue
+	_ = topic.Publish(context.Background(), 1)
+	<-started
+	_ = topic.Publish(context.Background(), 2)
+	published := make(chan error)
+	go func() { published <- topic.Publish(context.Background(), 3) }()
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := bus.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Close = %v, want deadline exceeded while the handler is stuck", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("Close took %s past its deadline", d)
+	}
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("Publish still blocked after Close")
+	}
+	close(block)
+}
@@ -0,0 +1,161 @@
+package featureflags
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/bdlilley/easygo"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+)
+
+// Document is the JSON format read by DocumentProvider:
+//
+//	{
+//	  "flags": {
+//	    "new-checkout": {
+//	      "value": false,
+//	      "rules": [{"match": {"plan": ["enterprise"]}, "value": true}]
+//	    },
+//	    "page-size": {"value": 50}
+//	  }
+//	}
+type Document struct {
+	Flags map[string]Flag `json:"flags"`
+}
+
+// Source fetches the raw flag document
+type Source func(ctx context.Context) ([]byte, error)
+
+// S3GetObjectAPI is the subset of the S3 client used by S3Source
+type S3GetObjectAPI interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// S3Source reads the document from an S3 object
+func S3Source(client S3GetObjectAPI, bucket, key string) Source {
+	return func(ctx context.Context) ([]byte, error) {
+		out, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+		if err != nil {
+			return nil, errs.Errorf("failed to get s3://%s/%s: %w", bucket, key, err)
+		}
+		defer out.Body.Close()
+		b, err := io.ReadAll(out.Body)
+		if err != nil {
+			return nil, errs.Errorf("failed to read s3://%s/%s: %w", bucket, key, err)
+		}
+		return b, nil
+	}
+}
+
+// SecretSource reads the document from a Secrets Manager secret
+func SecretSource(secrets easygo.SecretsReader, name string) Source {
+	return func(ctx context.Context) ([]byte, error) {
+		return secrets.GetLatestSecretBytes(ctx, name)
+	}
+}
+
+type NewDocumentProviderArgs struct {
+	// Source fetches the document (required)
+	Source Source
+	// Interval is how often the document is fetched again (default: 30s)
+	Interval time.Duration
+	// ErrorHandler receives failed refreshes; the previous document is kept
+	// (default: log them to Logger)
+	ErrorHandler func(error)
+	// Logger logs failed refreshes at error without an ErrorHandler (default: logging.Noop)
+	Logger logging.Logger
+}
+
+// DocumentProvider serves flags from a JSON Document that is polled for changes
+type DocumentProvider struct {
+	source  Source
+	errorFn func(error)
+	doc     atomic.Pointer[Document]
+
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// NewDocumentProvider fetches the initial document, failing if it cannot be
+// read, and starts polling
+func NewDocumentProvider(ctx context.Context, args *NewDocumentProviderArgs) (*DocumentProvider, error) {
+	if args == nil || args.Source == nil {
+		return nil, errs.New(errs.Internal, "Source is required")
+	}
+	p := &DocumentProvider{
+		source:  args.Source,
+		errorFn: args.ErrorHandler,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if p.errorFn == nil {
+		logger := args.Logger
+		if logger == nil {
+			logger = logging.Noop()
+		}
+		p.errorFn = func(err error) {
+			logger.WithError(err).Error("feature flags refresh failed")
+		}
+	}
+	if err := p.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	interval := args.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	go p.run(interval)
+	return p, nil
+}
+
+func (p *DocumentProvider) Flag(ctx context.Context, key string) (Flag, bool) {
+	f, ok := p.doc.Load().Flags[key]
+	return f, ok
+}
+
+// Refresh fetches the document now
+func (p *DocumentProvider) Refresh(ctx context.Context) error {
+	b, err := p.source(ctx)
+	if err != nil {
+		return err
+	}
+	doc := &Document{}
+	if err := json.Unmarshal(b, doc); err != nil {
+		return errs.Errorf("invalid feature flag document: %w", err)
+	}
+	p.doc.Store(doc)
+	return nil
+}
+
+// Close stops polling
+func (p *DocumentProvider) Close() {
+	p.stopOnce.Do(func() { close(p.stop) })
+	<-p.stopped
+}
+
+func (p *DocumentProvider) run(interval time.Duration) {
+	defer close(p.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			if err := p.Refresh(ctx); err != nil {
+				p.errorFn(err)
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package featureflags
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// DefaultEnvPrefix is the EnvProvider prefix when none is given
+const DefaultEnvPrefix = "FEATURE_"
+
+// EnvProvider reads flags from environment variables named prefix plus the
+// upper-cased key with non-alphanumerics replaced by underscores, e.g.
+// FEATURE_NEW_CHECKOUT for "new-checkout". Env flags have no targeting rules.
+type EnvProvider struct {
+	prefix string
+	lookup func(string) (string, bool)
+}
+
+// NewEnvProvider creates an EnvProvider; an empty prefix uses DefaultEnvPrefix
+func NewEnvProvider(prefix string) *EnvProvider {
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+	return &EnvProvider{prefix: prefix, lookup: os.LookupEnv}
+}
+
+func (p *EnvProvider) Flag(ctx context.Context, key string) (Flag, bool) {
+	v, ok := p.lookup(p.prefix + envName(key))
+	if !ok {
+		return Flag{}, false
+	}
+	return Flag{Value: v}, true
+}
+
+func envName(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, key)
+}
@@ -0,0 +1,160 @@
+// Package featureflags evaluates feature flags from pluggable providers, with
+// per-request targeting on attributes stored in the context.
+package featureflags
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Flag is a flag definition. Rules are evaluated in order and the first
+// matching rule's value wins; Value is used when no rule matches.
+type Flag struct {
+	Value any    `json:"value"`
+	Rules []Rule `json:"rules,omitempty"`
+}
+
+// Rule matches when every attribute in Match has one of the listed values
+type Rule struct {
+	Match map[string][]string `json:"match"`
+	Value any                 `json:"value"`
+}
+
+// Provider looks up flag definitions
+type Provider interface {
+	Flag(ctx context.Context, key string) (Flag, bool)
+}
+
+// Attributes describe the subject of a request for targeting, e.g. user ID,
+// tenant or plan
+type Attributes map[string]string
+
+type attributesKey struct{}
+
+// WithAttributes returns a copy of ctx carrying attrs, merged over any
+// attributes already in ctx
+func WithAttributes(ctx context.Context, attrs Attributes) context.Context {
+	merged := Attributes{}
+	for k, v := range AttributesFromContext(ctx) {
+		merged[k] = v
+	}
+	for k, v := range attrs {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, attributesKey{}, merged)
+}
+
+// AttributesFromContext returns the attributes stored by WithAttributes, or nil
+func AttributesFromContext(ctx context.Context) Attributes {
+	attrs, _ := ctx.Value(attributesKey{}).(Attributes)
+	return attrs
+}
+
+// evaluate returns the flag's value for attrs
+func (f Flag) evaluate(attrs Attributes) any {
+	for _, rule := range f.Rules {
+		if rule.matches(attrs) {
+			return rule.Value
+		}
+	}
+	return f.Value
+}
+
+func (r Rule) matches(attrs Attributes) bool {
+	for name, values := range r.Match {
+		v, ok := attrs[name]
+		if !ok || !slices.Contains(values, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Client evaluates flags against its providers in order; the first provider
+// that defines a flag decides its value
+type Client struct {
+	providers []Provider
+}
+
+// New creates a client, e.g. New(NewEnvProvider(""), documentProvider) so
+// environment variables override the shared document
+func New(providers ...Provider) *Client {
+	return &Client{providers: providers}
+}
+
+// Value returns the raw value of key for the attributes in ctx
+func (c *Client) Value(ctx context.Context, key string) (any, bool) {
+	attrs := AttributesFromContext(ctx)
+	for _, p := range c.providers {
+		if flag, ok := p.Flag(ctx, key); ok {
+			return flag.evaluate(attrs), true
+		}
+	}
+	return nil, false
+}
+
+// Bool returns key as a bool, or def when it is undefined or not a bool.
+// Strings are parsed with strconv.ParseBool.
+func (c *Client) Bool(ctx context.Context, key string, def bool) bool {
+	v, ok := c.Value(ctx, key)
+	if !ok {
+		return def
+	}
+	switch v := v.(type) {
+	case bool:
+		return v
+	case string:
+		if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+// String returns key as a string, or def when it is undefined. Non-string
+// values are formatted with fmt.
+func (c *Client) String(ctx context.Context, key string, def string) string {
+	v, ok := c.Value(ctx, key)
+	if !ok || v == nil {
+		return def
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// Int returns key as an int, or def when it is undefined or not a whole number
+func (c *Client) Int(ctx context.Context, key string, def int) int {
+	v, ok := c.Value(ctx, key)
+	if !ok {
+		return def
+	}
+	switch v := v.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		if v == math.Trunc(v) {
+			return int(v)
+		}
+	case string:
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// StaticProvider serves a fixed set of flags, e.g. in tests
+type StaticProvider map[string]Flag
+
+func (p StaticProvider) Flag(ctx context.Context, key string) (Flag, bool) {
+	f, ok := p[key]
+	return f, ok
+}
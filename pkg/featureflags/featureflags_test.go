@@ -0,0 +1,57 @@
+package featureflags
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bdlilley/easygo/pkg/egaws/egawstest"
+)
+
+func TestTargetingAndPrecedence(t *testing.T) {
+	ctx := context.Background()
+	secrets := egawstest.NewSecretsStore()
+	secrets.SetSecret("flags", `{"flags": {
+		"new-checkout": {"value": false, "rules": [{"match": {"plan": ["enterprise"]}, "value": true}]},
+		"page-size": {"value": 50},
+		"banner": {"value": "hello"}
+	}}`)
+	doc, err := NewDocumentProvider(ctx, &NewDocumentProviderArgs{Source: SecretSource(secrets, "flags")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	env := NewEnvProvider("")
+	env.lookup = func(name string) (string, bool) {
+		if name == "FEATURE_PAGE_SIZE" {
+			return "25", true
+		}
+		return "", false
+	}
+	flags := New(env, doc)
+
+	if flags.Bool(ctx, "new-checkout", true) {
+		t.Fatal("expected default value without matching attributes")
+	}
+	enterprise := WithAttributes(ctx, Attributes{"plan": "enterprise", "user": "u1"})
+	if !flags.Bool(enterprise, "new-checkout", false) {
+		t.Fatal("expected rule to match enterprise plan")
+	}
+	if got := flags.Int(ctx, "page-size", 10); got != 25 {
+		t.Fatalf("expected env override 25, got %d", got)
+	}
+	if got := flags.String(ctx, "banner", ""); got != "hello" {
+		t.Fatalf("expected hello, got %q", got)
+	}
+	if got := flags.Int(ctx, "missing", 7); got != 7 {
+		t.Fatalf("expected default 7, got %d", got)
+	}
+
+	secrets.SetSecret("flags", `{"flags": {"banner": {"value": "updated"}}}`)
+	if err := doc.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := flags.String(ctx, "banner", ""); got != "updated" {
+		t.Fatalf("expected refreshed value, got %q", got)
+	}
+}
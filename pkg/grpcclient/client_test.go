@@ -0,0 +1,87 @@
+package grpcclient
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/grpcserver"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// startFlakyServer serves the health service, failing the first failures calls with Unavailable
+func startFlakyServer(t *testing.T, failures int32) (string, *atomic.Int32) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := &atomic.Int32{}
+	logger, _ := logging.NewTestLogger()
+	s, err := grpcserver.NewEasyGoGRPCServer(&grpcserver.NewEasyGoGRPCServerArgs{
+		Listener: l,
+		Logger:   logger,
+		UnaryInterceptors: []grpc.UnaryServerInterceptor{
+			func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				if calls.Add(1) <= failures {
+					return nil, status.Error(codes.Unavailable, "try again")
+				}
+				return handler(ctx, req)
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(l)
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+	return l.Addr().String(), calls
+}
+
+func TestRetryIdempotentMethods(t *testing.T) {
+	addr, calls := startFlakyServer(t, 2)
+	conn, err := NewClient(&NewClientArgs{
+		Target:            addr,
+		Insecure:          true,
+		IdempotentMethods: []string{"/grpc.health.v1.Health/"},
+		InitialBackoff:    time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("expected SERVING, got %s", resp.Status)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("expected 3 calls, got %d", n)
+	}
+}
+
+func TestNoRetryForOtherMethods(t *testing.T) {
+	addr, calls := startFlakyServer(t, 1)
+	conn, err := NewClient(&NewClientArgs{Target: addr, Insecure: true, InitialBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected Unavailable, got %v", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("expected 1 call, got %d", n)
+	}
+}
@@ -0,0 +1,133 @@
+package grpcclient
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/bdlilley/easygo/pkg/retry"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func loggingUnaryInterceptor(logger logging.Logger) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		logCall(ctx, logger, method, "unary", err, time.Since(start))
+		return err
+	}
+}
+
+// loggingStreamInterceptor logs when a stream is opened; the stream's
+// messages and final status are not observed
+func loggingStreamInterceptor(logger logging.Logger) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		logCall(ctx, logger, method, "stream", err, time.Since(start))
+		return cs, err
+	}
+}
+
+func logCall(ctx context.Context, logger logging.Logger, method, kind string, err error, elapsed time.Duration) {
+	entry := logging.WithTrace(ctx, logger).WithFields(logrus.Fields{
+		"grpc_method": method,
+		"grpc_type":   kind,
+		"grpc_code":   status.Code(err).String(),
+		"elapsed":     elapsed.String(),
+	})
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+	entry.Debug("gRPC call completed")
+}
+
+// retrier retries unary calls to idempotent methods that fail with a retryable code
+type retrier struct {
+	methods       []string
+	codes         []codes.Code
+	maxRetries    int
+	backoff       retry.Backoff
+	perTryTimeout time.Duration
+	logger        logging.Logger
+}
+
+func newRetrier(args *NewClientArgs, logger logging.Logger) *retrier {
+	r := &retrier{
+		methods:       args.IdempotentMethods,
+		codes:         args.RetryableCodes,
+		maxRetries:    args.MaxRetries,
+		backoff:       retry.Backoff{Initial: args.InitialBackoff, Max: args.MaxBackoff},
+		perTryTimeout: args.PerTryTimeout,
+		logger:        logger,
+	}
+	if r.maxRetries == 0 {
+		r.maxRetries = 3
+	}
+	if len(r.codes) == 0 {
+		r.codes = []codes.Code{codes.Unavailable, codes.ResourceExhausted}
+	}
+	if r.backoff.Max <= 0 {
+		r.backoff.Max = 5 * time.Second
+	}
+	return r
+}
+
+// idempotent reports whether method matches IdempotentMethods
+func (r *retrier) idempotent(method string) bool {
+	for _, m := range r.methods {
+		if m == method || (strings.HasSuffix(m, "/") && strings.HasPrefix(method, m)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *retrier) intercept(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if r.maxRetries < 0 || !r.idempotent(method) {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := r.try(ctx, method, req, reply, cc, invoker, opts...)
+		if err == nil || attempt >= r.maxRetries || ctx.Err() != nil || !r.retryable(err) {
+			return err
+		}
+
+		delay := r.backoff.Delay(attempt)
+		logging.WithTrace(ctx, r.logger).WithFields(logrus.Fields{
+			"grpc_method": method,
+			"grpc_code":   status.Code(err).String(),
+			"attempt":     attempt + 1,
+			"delay":       delay.String(),
+		}).Warn("retrying gRPC call")
+
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		}
+	}
+}
+
+// retryable reports whether err has a retryable code or is a per-try timeout
+func (r *retrier) retryable(err error) bool {
+	code := status.Code(err)
+	return slices.Contains(r.codes, code) || (r.perTryTimeout > 0 && code == codes.DeadlineExceeded)
+}
+
+func (r *retrier) try(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if r.perTryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.perTryTimeout)
+		defer cancel()
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
@@ -0,0 +1,132 @@
+package grpcserver
+
+import (
+	"context"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// PanicHandler receives panics recovered from handlers
+type PanicHandler func(ctx context.Context, method string, recovered any, stack []byte)
+
+// healthMethodPrefix identifies health check calls, which are not logged
+const healthMethodPrefix = "/grpc.health.v1.Health/"
+
+// contextLoggerUnaryInterceptor stores a logger with the trace IDs in the call
+// context; handlers get it with logging.FromContext(ctx)
+func contextLoggerUnaryInterceptor(logger *logrus.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(logging.WithContext(ctx, logging.WithTrace(ctx, logger)), req)
+	}
+}
+
+func contextLoggerStreamInterceptor(logger *logrus.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		return handler(srv, &contextStream{ServerStream: ss, ctx: logging.WithContext(ctx, logging.WithTrace(ctx, logger))})
+	}
+}
+
+// contextStream replaces the context of a server stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func loggingUnaryInterceptor(logger *logrus.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, logger, info.FullMethod, "unary", err, time.Since(start))
+		return resp, err
+	}
+}
+
+func loggingStreamInterceptor(logger *logrus.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(ss.Context(), logger, info.FullMethod, "stream", err, time.Since(start))
+		return err
+	}
+}
+
+// logCall logs a completed call at error level for server-side failures and
+// info level otherwise
+func logCall(ctx context.Context, logger *logrus.Logger, method, kind string, err error, elapsed time.Duration) {
+	if strings.HasPrefix(method, healthMethodPrefix) {
+		return
+	}
+	code := status.Code(err)
+	fields := logrus.Fields{
+		"grpc_method": method,
+		"grpc_type":   kind,
+		"grpc_code":   code.String(),
+		"elapsed":     elapsed.String(),
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		fields["remote_ip"] = p.Addr.String()
+	}
+	entry := logging.WithTrace(ctx, logger).WithFields(fields)
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		entry.Error("gRPC call completed")
+	default:
+		entry.Info("gRPC call completed")
+	}
+}
+
+// recoveryUnaryInterceptor turns a panic into an Internal error, logging the
+// stack and reporting it to handler when set
+func recoveryUnaryInterceptor(logger *logrus.Logger, onPanic PanicHandler) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = handlePanic(ctx, logger, onPanic, info.FullMethod, rec)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+func recoveryStreamInterceptor(logger *logrus.Logger, onPanic PanicHandler) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = handlePanic(ss.Context(), logger, onPanic, info.FullMethod, rec)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func handlePanic(ctx context.Context, logger *logrus.Logger, onPanic PanicHandler, method string, rec any) error {
+	stack := debug.Stack()
+	logging.WithTrace(ctx, logger).WithFields(logrus.Fields{
+		"panic":       rec,
+		"stack":       string(stack),
+		"grpc_method": method,
+	}).Error("gRPC handler panic")
+	if onPanic != nil {
+		func() {
+			defer func() { _ = recover() }()
+			onPanic(ctx, method, rec, stack)
+		}()
+	}
+	return status.Error(codes.Internal, "internal server error")
+}
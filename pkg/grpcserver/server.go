@@ -0,0 +1,293 @@
+// Package grpcserver builds gRPC servers with the same conventions as
+// httpserver: structured request logging, panic recovery, OpenTelemetry
+// tracing, health checks, and graceful shutdown on SIGINT or SIGTERM.
+package grpcserver
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/health"
+	"github.com/bdlilley/easygo/pkg/runtimeinfo"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+type EasyGoGRPCServer struct {
+	// GRPC is the underlying server; register services on it before serving
+	GRPC *grpc.Server
+
+	logger          *logrus.Logger
+	addr            string
+	listener        net.Listener
+	tls             bool
+	health          *grpchealth.Server
+	healthRegistry  *health.Registry
+	shutdownTimeout time.Duration
+	shutdownOnce    sync.Once
+}
+
+type NewEasyGoGRPCServerArgs struct {
+	// Logger defaults to JSON logs tagged with runtimeinfo fields
+	Logger *logrus.Logger
+	Port   int
+	// Listener serves on a pre-built listener instead of Port
+	Listener net.Listener
+	// ShutdownTimeout is the grace period for in-flight calls when Run receives
+	// a stop signal; calls still running afterwards are cancelled (default: 30s)
+	ShutdownTimeout time.Duration
+	// TLSCertFile and TLSKeyFile serve TLS with a certificate and key from disk
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSConfig is the base TLS configuration; setting it enables TLS without
+	// cert files. Set ClientCAs and ClientAuth on it for mTLS.
+	TLSConfig *tls.Config
+	// Tracing enables an OpenTelemetry span per call; trace and span IDs are
+	// added to the call log fields
+	Tracing *TracingConfig
+	// DisableHealthService stops grpc.health.v1.Health from being registered
+	DisableHealthService bool
+	// HealthRegistry serves grpc.health.v1.Health from a shared registry, e.g.
+	// the one also served by httpserver, instead of the built-in health server
+	HealthRegistry *health.Registry
+	// DisableReflection stops the server reflection service from being registered
+	DisableReflection bool
+	// DisableCallLogging stops the per-call log line; the context logger is
+	// still available to handlers
+	DisableCallLogging bool
+	// PanicHandler is called with panics recovered from handlers, e.g. to
+	// forward them to an error tracker
+	PanicHandler PanicHandler
+	// UnaryInterceptors and StreamInterceptors run after the built-in
+	// logging and recovery interceptors, in order
+	UnaryInterceptors  []grpc.UnaryServerInterceptor
+	StreamInterceptors []grpc.StreamServerInterceptor
+	// ServerOptions are passed to grpc.NewServer after the built-in options
+	ServerOptions []grpc.ServerOption
+}
+
+// TracingConfig enables an OpenTelemetry span per call
+type TracingConfig struct {
+	// TracerProvider defaults to the global provider
+	TracerProvider trace.TracerProvider
+	// Propagators extract trace context from incoming metadata (default: the
+	// global propagator)
+	Propagators propagation.TextMapPropagator
+}
+
+func NewEasyGoGRPCServer(args *NewEasyGoGRPCServerArgs) (*EasyGoGRPCServer, error) {
+	if args == nil {
+		args = &NewEasyGoGRPCServerArgs{}
+	}
+	if args.Logger == nil {
+		args.Logger = logrus.New()
+		args.Logger.SetFormatter(&logrus.JSONFormatter{})
+		runtimeinfo.InstallFields(args.Logger)
+	}
+
+	unary := []grpc.UnaryServerInterceptor{
+		contextLoggerUnaryInterceptor(args.Logger),
+	}
+	stream := []grpc.StreamServerInterceptor{
+		contextLoggerStreamInterceptor(args.Logger),
+	}
+	if !args.DisableCallLogging {
+		unary = append(unary, loggingUnaryInterceptor(args.Logger))
+		stream = append(stream, loggingStreamInterceptor(args.Logger))
+	}
+	unary = append(unary, recoveryUnaryInterceptor(args.Logger, args.PanicHandler))
+	stream = append(stream, recoveryStreamInterceptor(args.Logger, args.PanicHandler))
+	unary = append(unary, args.UnaryInterceptors...)
+	stream = append(stream, args.StreamInterceptors...)
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}
+	// Tracing runs as a stats handler, ahead of the interceptors, so the call
+	// logger can read the span context
+	if args.Tracing != nil {
+		var topts []otelgrpc.Option
+		if args.Tracing.TracerProvider != nil {
+			topts = append(topts, otelgrpc.WithTracerProvider(args.Tracing.TracerProvider))
+		}
+		if args.Tracing.Propagators != nil {
+			topts = append(topts, otelgrpc.WithPropagators(args.Tracing.Propagators))
+		}
+		opts = append(opts, grpc.StatsHandler(otelgrpc.NewServerHandler(topts...)))
+	}
+
+	tlsConfig, err := loadTLSConfig(args)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	opts = append(opts, args.ServerOptions...)
+
+	s := &EasyGoGRPCServer{
+		GRPC:            grpc.NewServer(opts...),
+		logger:          args.Logger,
+		addr:            fmt.Sprintf(":%d", args.Port),
+		listener:        args.Listener,
+		tls:             tlsConfig != nil,
+		healthRegistry:  args.HealthRegistry,
+		shutdownTimeout: args.ShutdownTimeout,
+	}
+	if s.shutdownTimeout <= 0 {
+		s.shutdownTimeout = 30 * time.Second
+	}
+	switch {
+	case args.DisableHealthService:
+	case args.HealthRegistry != nil:
+		healthpb.RegisterHealthServer(s.GRPC, args.HealthRegistry.GRPCService())
+	default:
+		s.health = grpchealth.NewServer()
+		healthpb.RegisterHealthServer(s.GRPC, s.health)
+	}
+	if !args.DisableReflection {
+		reflection.Register(s.GRPC)
+	}
+	return s, nil
+}
+
+// loadTLSConfig returns the server TLS configuration, or nil when TLS is disabled
+func loadTLSConfig(args *NewEasyGoGRPCServerArgs) (*tls.Config, error) {
+	if (args.TLSCertFile == "") != (args.TLSKeyFile == "") {
+		return nil, errs.New(errs.Internal, "TLSCertFile and TLSKeyFile must be set together")
+	}
+	if args.TLSConfig == nil && args.TLSCertFile == "" {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{}
+	if args.TLSConfig != nil {
+		cfg = args.TLSConfig.Clone()
+	}
+	if args.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(args.TLSCertFile, args.TLSKeyFile)
+		if err != nil {
+			return nil, errs.Errorf("failed to load TLS key pair: %w", err)
+		}
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+	return cfg, nil
+}
+
+// Health returns the health service so services can report their status with
+// SetServingStatus, or nil when DisableHealthService or HealthRegistry is set.
+// The overall status ("") is SERVING until shutdown starts.
+func (s *EasyGoGRPCServer) Health() *grpchealth.Server {
+	return s.health
+}
+
+// listen returns the configured listener or a TCP listener on the server port
+func (s *EasyGoGRPCServer) listen() (net.Listener, error) {
+	if s.listener != nil {
+		return s.listener, nil
+	}
+	l, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return nil, errs.Errorf("failed to listen on %s: %w", s.addr, err)
+	}
+	return l, nil
+}
+
+// ListenAndServe serves on the configured listener or port until Shutdown
+func (s *EasyGoGRPCServer) ListenAndServe() error {
+	l, err := s.listen()
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve serves on l until Shutdown
+func (s *EasyGoGRPCServer) Serve(l net.Listener) error {
+	err := s.GRPC.Serve(l)
+	if errors.Is(err, grpc.ErrServerStopped) {
+		return nil
+	}
+	return err
+}
+
+// Run serves until ctx is cancelled or the process receives SIGINT or SIGTERM,
+// then shuts down gracefully within the configured ShutdownTimeout. It returns
+// nil after a clean shutdown.
+func (s *EasyGoGRPCServer) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	l, err := s.listen()
+	if err != nil {
+		return err
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		s.logger.WithField("addr", l.Addr().String()).Info("gRPC server listening")
+		serveErr <- s.Serve(l)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+		s.logger.Info("gRPC server shutting down")
+		return s.shutdownWithTimeout()
+	}
+}
+
+func (s *EasyGoGRPCServer) shutdownWithTimeout() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	return s.Shutdown(ctx)
+}
+
+// Shutdown marks the server (or its HealthRegistry) NOT_SERVING, stops accepting calls and waits for
+// in-flight calls to finish. When ctx is done first the remaining calls are
+// cancelled and ctx's error is returned.
+func (s *EasyGoGRPCServer) Shutdown(ctx context.Context) error {
+	var err error
+	s.shutdownOnce.Do(func() {
+		if s.health != nil {
+			s.health.Shutdown()
+		}
+		if s.healthRegistry != nil {
+			s.healthRegistry.Shutdown()
+		}
+
+		stopped := make(chan struct{})
+		go func() {
+			s.GRPC.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			s.GRPC.Stop()
+			<-stopped
+			err = ctx.Err()
+		}
+	})
+	return err
+}
@@ -0,0 +1,134 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/httpserver"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func listenLocal(t *testing.T) net.Listener {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func dial(t *testing.T, addr string) healthpb.HealthClient {
+	t.Helper()
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestHealthAndShutdown(t *testing.T) {
+	l := listenLocal(t)
+	logger, _ := logging.NewTestLogger()
+	s, err := NewEasyGoGRPCServer(&NewEasyGoGRPCServerArgs{Listener: l, Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(l)
+
+	client := dial(t, l.Addr().String())
+	resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("expected SERVING, got %s", resp.Status)
+	}
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPanicRecovery(t *testing.T) {
+	l := listenLocal(t)
+	logger, recorder := logging.NewTestLogger()
+	var recovered any
+	s, err := NewEasyGoGRPCServer(&NewEasyGoGRPCServerArgs{
+		Listener: l,
+		Logger:   logger,
+		PanicHandler: func(ctx context.Context, method string, rec any, stack []byte) {
+			recovered = rec
+		},
+		UnaryInterceptors: []grpc.UnaryServerInterceptor{
+			func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				panic("boom")
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(l)
+	defer s.Shutdown(context.Background())
+
+	_, err = dial(t, l.Addr().String()).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("expected Internal, got %v", err)
+	}
+	if recovered != "boom" {
+		t.Fatalf("expected panic handler to receive boom, got %v", recovered)
+	}
+	if !recorder.HasEntryContaining("gRPC handler panic") {
+		t.Fatal("expected panic to be logged")
+	}
+}
+
+func TestRunWithHTTP(t *testing.T) {
+	l := listenLocal(t)
+	logger, _ := logging.NewTestLogger()
+	s, err := NewEasyGoGRPCServer(&NewEasyGoGRPCServerArgs{Listener: l, Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := httpserver.NewEasyGoHTTPServer(&httpserver.NewEasyGoHTTPServerArgs{Logger: logger})
+	h.Chi.Get("/hello", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.RunWithHTTP(ctx, h) }()
+
+	addr := l.Addr().String()
+	if _, err := dial(t, addr).Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get("http://" + addr + "/hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunWithHTTP did not return")
+	}
+}
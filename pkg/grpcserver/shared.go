@@ -0,0 +1,94 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os/signal"
+	"syscall"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/httpserver"
+	"github.com/soheilhy/cmux"
+)
+
+// SharedListener splits one listener between gRPC and HTTP. Connections that
+// open with HTTP/2 and a gRPC content type go to GRPC; everything else goes to
+// HTTP. The split happens before TLS, so a shared port serves cleartext gRPC
+// (h2c) and should sit behind a proxy that terminates TLS.
+type SharedListener struct {
+	GRPC net.Listener
+	HTTP net.Listener
+
+	mux cmux.CMux
+}
+
+// NewSharedListener splits l; call Serve to start routing connections
+func NewSharedListener(l net.Listener) *SharedListener {
+	m := cmux.New(l)
+	return &SharedListener{
+		GRPC: m.MatchWithWriters(cmux.HTTP2MatchHeaderFieldSendSettings("content-type", "application/grpc")),
+		HTTP: m.Match(cmux.Any()),
+		mux:  m,
+	}
+}
+
+// Serve routes connections until the listener is closed
+func (s *SharedListener) Serve() error {
+	err := s.mux.Serve()
+	if err == nil || errors.Is(err, net.ErrClosed) || errors.Is(err, cmux.ErrListenerClosed) || errors.Is(err, cmux.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Close closes the underlying listener
+func (s *SharedListener) Close() {
+	s.mux.Close()
+}
+
+// RunWithHTTP serves s and httpServer on s's port until ctx is cancelled or
+// the process receives SIGINT or SIGTERM, then shuts both down gracefully.
+// Neither server may be configured for TLS; see SharedListener.
+func (s *EasyGoGRPCServer) RunWithHTTP(ctx context.Context, httpServer *httpserver.EasyGoHTTPServer) error {
+	if s.tls {
+		return errs.New(errs.Internal, "a shared gRPC and HTTP port does not support TLS")
+	}
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	l, err := s.listen()
+	if err != nil {
+		return err
+	}
+	shared := NewSharedListener(l)
+
+	serveErr := make(chan error, 3)
+	go func() {
+		serveErr <- s.Serve(shared.GRPC)
+	}()
+	go func() {
+		if err := httpServer.Serve(shared.HTTP); !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+	}()
+	go func() {
+		s.logger.WithField("addr", l.Addr().String()).Info("gRPC and HTTP server listening")
+		serveErr <- shared.Serve()
+	}()
+
+	var errs []error
+	select {
+	case err := <-serveErr:
+		errs = append(errs, err)
+	case <-ctx.Done():
+		s.logger.Info("gRPC and HTTP server shutting down")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	errs = append(errs, s.Shutdown(shutdownCtx), httpServer.Shutdown(shutdownCtx))
+	shared.Close()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// watchInterval is how often Watch re-evaluates the checks
+const watchInterval = 5 * time.Second
+
+// GRPCService returns a grpc.health.v1.Health implementation backed by the
+// registry. The empty service name reports the aggregate; any other name
+// reports the check registered under it.
+func (r *Registry) GRPCService() healthpb.HealthServer {
+	return &grpcService{registry: r}
+}
+
+type grpcService struct {
+	healthpb.UnimplementedHealthServer
+	registry *Registry
+}
+
+func (s *grpcService) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	st, err := s.status(ctx, req.GetService())
+	if err != nil {
+		return nil, err
+	}
+	return &healthpb.HealthCheckResponse{Status: st}, nil
+}
+
+func (s *grpcService) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	ctx := stream.Context()
+	last := healthpb.HealthCheckResponse_UNKNOWN
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		st, err := s.status(ctx, req.GetService())
+		if status.Code(err) == codes.NotFound {
+			st = healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		} else if err != nil {
+			return err
+		}
+		if st != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: st}); err != nil {
+				return err
+			}
+			last = st
+		}
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *grpcService) status(ctx context.Context, service string) (healthpb.HealthCheckResponse_ServingStatus, error) {
+	if s.registry.ShuttingDown() {
+		return healthpb.HealthCheckResponse_NOT_SERVING, nil
+	}
+	if service == "" {
+		if s.registry.Check(ctx).Healthy() {
+			return healthpb.HealthCheckResponse_SERVING, nil
+		}
+		return healthpb.HealthCheckResponse_NOT_SERVING, nil
+	}
+
+	fn, ok := s.registry.Checks()[service]
+	if !ok {
+		return healthpb.HealthCheckResponse_SERVICE_UNKNOWN, status.Errorf(codes.NotFound, "unknown service %s", service)
+	}
+	if fn(ctx) != nil {
+		return healthpb.HealthCheckResponse_NOT_SERVING, nil
+	}
+	return healthpb.HealthCheckResponse_SERVING, nil
+}
@@ -0,0 +1,233 @@
+// Package health is a registry of named health checks shared by every server
+// in a process, so HTTP readiness endpoints, the gRPC health service and
+// direct Check calls agree on the process's status.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+const defaultTimeout = 5 * time.Second
+
+// Statuses reported for checks and the aggregate
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+	// StatusShuttingDown is reported without running checks after Shutdown
+	StatusShuttingDown = "shutting down"
+)
+
+// ErrShuttingDown is returned by Err after Shutdown
+var ErrShuttingDown = errs.New(errs.Unavailable, "shutting down")
+
+// CheckFunc reports a component as unhealthy by returning an error
+type CheckFunc func(ctx context.Context) error
+
+type Options struct {
+	// Timeout bounds each check that does not set its own (default: 5s)
+	Timeout time.Duration
+}
+
+type CheckOptions struct {
+	// Interval reuses a result for this long before running the check again,
+	// for checks that are expensive or rate limited (default: run every time)
+	Interval time.Duration
+	// Timeout bounds the check (default: the registry Timeout)
+	Timeout time.Duration
+}
+
+// Result is the outcome of a single check
+type Result struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Report is the aggregate status; it is the JSON body served by Handler
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Healthy reports whether every check passed
+func (r *Report) Healthy() bool {
+	return r.Status == StatusOK
+}
+
+type check struct {
+	fn       CheckFunc
+	interval time.Duration
+	timeout  time.Duration
+
+	mu   sync.Mutex
+	last *Result
+	err  error
+}
+
+// Registry holds named checks. It is safe for concurrent use.
+type Registry struct {
+	timeout  time.Duration
+	mu       sync.RWMutex
+	checks   map[string]*check
+	shutdown atomic.Bool
+}
+
+// NewRegistry creates an empty registry; opts may be nil
+func NewRegistry(opts *Options) *Registry {
+	r := &Registry{timeout: defaultTimeout, checks: map[string]*check{}}
+	if opts != nil && opts.Timeout > 0 {
+		r.timeout = opts.Timeout
+	}
+	return r
+}
+
+// Register adds or replaces the check called name; opts may be nil
+func (r *Registry) Register(name string, fn CheckFunc, opts *CheckOptions) {
+	c := &check{fn: fn, timeout: r.timeout}
+	if opts != nil {
+		c.interval = opts.Interval
+		if opts.Timeout > 0 {
+			c.timeout = opts.Timeout
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[name] = c
+}
+
+// Unregister removes the check called name
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.checks, name)
+}
+
+// Shutdown makes the registry report StatusShuttingDown from now on, so load
+// balancers stop routing traffic while the process drains
+func (r *Registry) Shutdown() {
+	r.shutdown.Store(true)
+}
+
+// ShuttingDown reports whether Shutdown was called
+func (r *Registry) ShuttingDown() bool {
+	return r.shutdown.Load()
+}
+
+// Check runs all checks concurrently, reusing cached results within their interval
+func (r *Registry) Check(ctx context.Context) *Report {
+	if r.ShuttingDown() {
+		return &Report{Status: StatusShuttingDown}
+	}
+
+	r.mu.RLock()
+	names := make([]string, 0, len(r.checks))
+	checks := make([]*check, 0, len(r.checks))
+	for name := range r.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		checks = append(checks, r.checks[name])
+	}
+	r.mu.RUnlock()
+
+	report := &Report{Status: StatusOK}
+	if len(checks) == 0 {
+		return report
+	}
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = c.run(ctx)
+		}()
+	}
+	wg.Wait()
+
+	report.Checks = make(map[string]Result, len(names))
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// Err runs all checks and returns an error naming the failed ones, or nil
+func (r *Registry) Err(ctx context.Context) error {
+	report := r.Check(ctx)
+	if report.Status == StatusShuttingDown {
+		return ErrShuttingDown
+	}
+	var failures []error
+	for name, result := range report.Checks {
+		if result.Status != StatusOK {
+			failures = append(failures, errs.Newf(errs.Internal, "%s: %s", name, result.Error))
+		}
+	}
+	return errors.Join(failures...)
+}
+
+// Checks returns the registered checks as functions that honor their
+// interval and timeout, e.g. to serve them from another health endpoint
+func (r *Registry) Checks() map[string]CheckFunc {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fns := make(map[string]CheckFunc, len(r.checks))
+	for name, c := range r.checks {
+		fns[name] = func(ctx context.Context) error {
+			_, err := c.run(ctx)
+			return err
+		}
+	}
+	return fns
+}
+
+// run returns the cached result when it is fresh, or runs the check
+func (c *check) run(ctx context.Context) (Result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.last != nil && c.interval > 0 && time.Since(c.last.CheckedAt) < c.interval {
+		return *c.last, c.err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	start := time.Now()
+	err := c.fn(ctx)
+	result := Result{Status: StatusOK, Duration: time.Since(start).String(), CheckedAt: start}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	c.last, c.err = &result, err
+	return result, err
+}
+
+// Handler serves the report as JSON with 200 when healthy and 503 otherwise
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := r.Check(req.Context())
+		status := http.StatusOK
+		if !report.Healthy() {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
@@ -0,0 +1,64 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	r := NewRegistry(nil)
+	var calls atomic.Int32
+	var failing atomic.Bool
+	r.Register("db", func(ctx context.Context) error {
+		calls.Add(1)
+		if failing.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	}, &CheckOptions{Interval: time.Hour})
+
+	if err := r.Err(ctx); err != nil {
+		t.Fatal(err)
+	}
+	failing.Store(true)
+	if !r.Check(ctx).Healthy() {
+		t.Fatal("expected cached healthy result within interval")
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("expected 1 call, got %d", n)
+	}
+
+	r.Register("queue", func(ctx context.Context) error { return errors.New("unreachable") }, nil)
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+
+	svc := r.GRPCService()
+	resp, err := svc.Check(ctx, &healthpb.HealthCheckRequest{Service: "db"})
+	if err != nil || resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("expected db SERVING, got %v %v", resp, err)
+	}
+	resp, err = svc.Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil || resp.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("expected aggregate NOT_SERVING, got %v %v", resp, err)
+	}
+	if _, err := svc.Check(ctx, &healthpb.HealthCheckRequest{Service: "missing"}); err == nil {
+		t.Fatal("expected NotFound for unknown service")
+	}
+
+	r.Unregister("queue")
+	r.Shutdown()
+	if !errors.Is(r.Err(ctx), ErrShuttingDown) {
+		t.Fatal("expected ErrShuttingDown after Shutdown")
+	}
+}
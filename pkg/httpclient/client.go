@@ -0,0 +1,273 @@
+// Package httpclient is an opinionated HTTP client for outbound calls: retries
+// with exponential backoff and jitter, per-try timeouts, an idempotency-aware
+// retry policy, structured logging and typed JSON helpers.
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/breaker"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/bdlilley/easygo/pkg/ratelimit"
+	"github.com/bdlilley/easygo/pkg/requestmeta"
+	"github.com/bdlilley/easygo/pkg/retry"
+	"github.com/sirupsen/logrus"
+)
+
+// IdempotencyKeyHeader marks a non-idempotent request as safe to retry
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// RetryPolicy decides whether an attempt should be retried. resp is nil when
+// err is set. It is only consulted for requests that are safe to retry.
+type RetryPolicy func(req *http.Request, resp *http.Response, err error) bool
+
+type NewClientArgs struct {
+	// BaseURL is prepended to request paths that are not absolute URLs
+	BaseURL string
+	// Transport sends requests (default: http.DefaultTransport)
+	Transport http.RoundTripper
+	// CheckRedirect is the http.Client redirect policy (default: follow up to
+	// 10 redirects)
+	CheckRedirect func(req *http.Request, via []*http.Request) error
+	// Breaker guards each attempt; while it is open requests fail with
+	// breaker.ErrOpen and are not retried
+	Breaker *breaker.Breaker
+	// RateLimiter is waited on before each attempt, keyed by the request's host
+	RateLimiter ratelimit.Limiter
+	// Header is added to every request unless the request sets the same key
+	Header http.Header
+	// PropagateMeta sets the requestmeta of each request's context as headers.
+	// Enable it only for calls to internal services, as it forwards user IDs.
+	PropagateMeta bool
+	// PerTryTimeout bounds each attempt (default: 10s, negative disables)
+	PerTryTimeout time.Duration
+	// MaxRetries is the number of retries after the first attempt (default: 3, negative disables)
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry, doubled per attempt (default: 100ms)
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between retries, including Retry-After (default: 5s)
+	MaxBackoff time.Duration
+	// RetryPolicy overrides DefaultRetryPolicy
+	RetryPolicy RetryPolicy
+	// Logger logs each attempt at debug and retries at warn (default: logging.Noop)
+	Logger logging.Logger
+}
+
+// Client sends requests with retries. It is safe for concurrent use.
+type Client struct {
+	baseURL        string
+	http           *http.Client
+	header         http.Header
+	propagateMeta  bool
+	limiter        ratelimit.Limiter
+	perTryTimeout  time.Duration
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	retryPolicy    RetryPolicy
+	logger         logging.Logger
+}
+
+// NewClient creates a Client; args may be nil
+func NewClient(args *NewClientArgs) *Client {
+	if args == nil {
+		args = &NewClientArgs{}
+	}
+	transport := args.Transport
+	if args.Breaker != nil {
+		transport = breaker.Transport(args.Breaker, transport)
+	}
+	c := &Client{
+		baseURL:        strings.TrimRight(args.BaseURL, "/"),
+		http:           &http.Client{Transport: transport, CheckRedirect: args.CheckRedirect},
+		header:         args.Header,
+		propagateMeta:  args.PropagateMeta,
+		limiter:        args.RateLimiter,
+		perTryTimeout:  args.PerTryTimeout,
+		maxRetries:     args.MaxRetries,
+		initialBackoff: args.InitialBackoff,
+		maxBackoff:     args.MaxBackoff,
+		retryPolicy:    args.RetryPolicy,
+		logger:         args.Logger,
+	}
+	if c.perTryTimeout == 0 {
+		c.perTryTimeout = 10 * time.Second
+	}
+	if c.maxRetries == 0 {
+		c.maxRetries = 3
+	}
+	if c.initialBackoff <= 0 {
+		c.initialBackoff = 100 * time.Millisecond
+	}
+	if c.maxBackoff <= 0 {
+		c.maxBackoff = 5 * time.Second
+	}
+	if c.retryPolicy == nil {
+		c.retryPolicy = DefaultRetryPolicy
+	}
+	if c.logger == nil {
+		c.logger = logging.Noop()
+	}
+	return c
+}
+
+// DefaultRetryPolicy retries connection errors and 429, 502, 503 and 504
+// responses. Errors caused by the request's own context or an open circuit
+// breaker are not retried.
+func DefaultRetryPolicy(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return req.Context().Err() == nil && !errors.Is(err, breaker.ErrOpen)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Retryable reports whether req may be sent more than once: its method is
+// idempotent or it carries an Idempotency-Key, and its body can be replayed
+func Retryable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get(IdempotencyKeyHeader) != ""
+}
+
+// NewRequest creates a request for path, resolved against BaseURL
+func (c *Client) NewRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.resolve(path), body)
+	if err != nil {
+		return nil, errs.Errorf("failed to create %s %s request: %w", method, path, err)
+	}
+	return req, nil
+}
+
+func (c *Client) resolve(path string) string {
+	if c.baseURL == "" || strings.Contains(path, "://") {
+		return path
+	}
+	return c.baseURL + "/" + strings.TrimLeft(path, "/")
+}
+
+// Do sends req, retrying according to the retry policy. The returned response
+// is the last attempt's; non-2xx statuses are not errors.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	for k, v := range c.header {
+		if _, ok := req.Header[k]; !ok {
+			req.Header[k] = v
+		}
+	}
+	if c.propagateMeta {
+		requestmeta.FromContext(req.Context()).InjectHeader(req.Header)
+	}
+
+	retries := 0
+	if c.maxRetries > 0 && Retryable(req) {
+		retries = c.maxRetries
+	}
+	log := logging.WithTrace(req.Context(), c.logger).WithFields(logrus.Fields{
+		"method": req.Method,
+		"host":   req.URL.Host,
+		"path":   req.URL.Path,
+	})
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, errs.Errorf("failed to rewind request body: %w", err)
+			}
+			req.Body = body
+		}
+
+		if c.limiter != nil {
+			if err := ratelimit.Wait(req.Context(), c.limiter, req.URL.Host); err != nil {
+				return nil, errs.Errorf("%s %s rate limited: %w", req.Method, req.URL.Redacted(), err)
+			}
+		}
+
+		start := time.Now()
+		resp, err := c.send(req)
+		fields := logrus.Fields{"attempt": attempt + 1, "elapsed": time.Since(start)}
+		if resp != nil {
+			fields["status"] = resp.StatusCode
+		}
+		if err != nil {
+			fields["error"] = err.Error()
+		}
+		log.WithFields(fields).Debug("HTTP request attempt")
+
+		if attempt >= retries || !c.retryPolicy(req, resp, err) {
+			if err != nil {
+				return nil, errs.Errorf("%s %s failed after %d attempts: %w", req.Method, req.URL.Redacted(), attempt+1, err)
+			}
+			return resp, nil
+		}
+
+		wait := c.backoff(attempt, resp)
+		log.WithFields(fields).WithField("retry_in", wait).Warn("retrying HTTP request")
+		if resp != nil {
+			// drain so the connection can be reused
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-req.Context().Done():
+			t.Stop()
+			return nil, errs.Errorf("%s %s canceled while retrying: %w", req.Method, req.URL.Redacted(), req.Context().Err())
+		}
+	}
+}
+
+// send performs one attempt bounded by the per-try timeout. The timeout keeps
+// running while the caller reads the body and is released when it is closed.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if c.perTryTimeout < 0 {
+		return c.http.Do(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), c.perTryTimeout)
+	resp, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// backoff returns the delay before retry attempt+1: Retry-After when the
+// server sent one, otherwise exponential backoff with equal jitter
+func (c *Client) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			return min(time.Duration(secs)*time.Second, c.maxBackoff)
+		}
+	}
+	return retry.Backoff{Initial: c.initialBackoff, Max: c.maxBackoff}.Delay(attempt)
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,57 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"ok"}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(&NewClientArgs{BaseURL: srv.URL, InitialBackoff: time.Millisecond})
+	ctx := context.Background()
+
+	got, err := GetJSON[struct{ Name string }](ctx, c, "/thing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "ok" || calls.Load() != 3 {
+		t.Fatalf("got %+v after %d calls, want ok after 3", got, calls.Load())
+	}
+
+	// POST without an idempotency key is not retried
+	calls.Store(0)
+	_, err = PostJSON[map[string]string, struct{}](ctx, c, "/thing", map[string]string{"a": "b"})
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("err = %v, want 503 StatusError", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("POST sent %d times, want 1", calls.Load())
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	post, _ := http.NewRequest(http.MethodPost, "http://example.com", nil)
+	if Retryable(post) {
+		t.Error("POST should not be retryable")
+	}
+	post.Header.Set(IdempotencyKeyHeader, "k")
+	if !Retryable(post) {
+		t.Error("POST with idempotency key should be retryable")
+	}
+}
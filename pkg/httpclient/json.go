@@ -0,0 +1,95 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// maxErrorBodyBytes bounds the response body kept in a StatusError
+const maxErrorBodyBytes = 64 << 10
+
+// StatusError is returned by the JSON helpers for non-2xx responses
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	// Body is the start of the response body, e.g. a problem+json document
+	Body []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s: unexpected status %d", e.Method, e.URL, e.StatusCode)
+}
+
+// GetJSON sends a GET request for path and decodes the JSON response into T
+func GetJSON[T any](ctx context.Context, c *Client, path string) (T, error) {
+	return DoJSON[T](ctx, c, http.MethodGet, path, nil)
+}
+
+// PostJSON sends body as JSON to path and decodes the JSON response into Resp
+func PostJSON[Req, Resp any](ctx context.Context, c *Client, path string, body Req) (Resp, error) {
+	return DoJSON[Resp](ctx, c, http.MethodPost, path, body)
+}
+
+// PutJSON sends body as JSON to path with PUT and decodes the JSON response into Resp
+func PutJSON[Req, Resp any](ctx context.Context, c *Client, path string, body Req) (Resp, error) {
+	return DoJSON[Resp](ctx, c, http.MethodPut, path, body)
+}
+
+// DoJSON sends a request with body encoded as JSON (none when body is nil) and
+// decodes a 2xx response into T. A 204 or empty body leaves T as its zero value.
+// Non-2xx responses return a *StatusError.
+func DoJSON[T any](ctx context.Context, c *Client, method, path string, body any) (T, error) {
+	var result T
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return result, errs.Errorf("failed to marshal request body: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := c.NewRequest(ctx, method, path, reader)
+	if err != nil {
+		return result, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		return result, &StatusError{
+			Method:     method,
+			URL:        req.URL.Redacted(),
+			StatusCode: resp.StatusCode,
+			Body:       data,
+		}
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return result, errs.Errorf("failed to read %s %s response: %w", method, req.URL.Redacted(), err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return result, nil
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return result, errs.Errorf("failed to decode %s %s response: %w", method, req.URL.Redacted(), err)
+	}
+	return result, nil
+}
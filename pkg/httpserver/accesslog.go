@@ -0,0 +1,146 @@
+package httpserver
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultAccessLogSkipPaths are not logged unless AccessLogConfig.SkipPaths is set
+var defaultAccessLogSkipPaths = []string{"/", "/healthz", "/readyz"}
+
+// AccessLogConfig controls which requests the access log records
+type AccessLogConfig struct {
+	// SkipPaths are exact paths never logged (default: /, /healthz and /readyz).
+	// Set to an empty, non-nil slice to log every path.
+	SkipPaths []string
+	// SkipPrefixes are path prefixes never logged, e.g. "/static/"
+	SkipPrefixes []string
+	// SuccessSampleRate is the fraction of responses below 400 that are logged,
+	// e.g. 0.01 logs 1% of successes; errors are always logged. Zero logs all.
+	SuccessSampleRate float64
+	// TrustedProxies resolves the logged remote IP from X-Forwarded-For
+	TrustedProxies *TrustedProxies
+	// Fields limits the logged fields to this allowlist; status and elapsed
+	// are always logged. Available: method, route, path, query, remote_ip,
+	// user_agent, request_id, trace_id, span_id, content_length, bytes, proto.
+	Fields []string
+	// QueryValues logs query parameter values. By default they are replaced
+	// with [REDACTED], as they often carry OIDC codes, URL signatures and API
+	// tokens, and only the parameter names are logged.
+	QueryValues bool
+}
+
+type accessLogFilter struct {
+	skipPaths    map[string]bool
+	skipPrefixes []string
+	sampleRate   float64
+	proxies      *TrustedProxies
+	// fields is nil when every field is logged
+	fields      map[string]bool
+	queryValues bool
+}
+
+func newAccessLogFilter(cfg *AccessLogConfig) *accessLogFilter {
+	c := AccessLogConfig{}
+	if cfg != nil {
+		c = *cfg
+	}
+	if c.SkipPaths == nil {
+		c.SkipPaths = defaultAccessLogSkipPaths
+	}
+	if c.SuccessSampleRate <= 0 || c.SuccessSampleRate > 1 {
+		c.SuccessSampleRate = 1
+	}
+
+	f := &accessLogFilter{
+		skipPaths:    make(map[string]bool, len(c.SkipPaths)),
+		skipPrefixes: c.SkipPrefixes,
+		sampleRate:   c.SuccessSampleRate,
+		proxies:      c.TrustedProxies,
+		queryValues:  c.QueryValues,
+	}
+	if c.Fields != nil {
+		f.fields = map[string]bool{"status": true, "elapsed": true}
+		for _, name := range c.Fields {
+			f.fields[name] = true
+		}
+	}
+	for _, p := range c.SkipPaths {
+		f.skipPaths[p] = true
+	}
+	return f
+}
+
+// skip reports whether requests for path are never logged
+func (f *accessLogFilter) skip(path string) bool {
+	if f.skipPaths[path] {
+		return true
+	}
+	for _, prefix := range f.skipPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// sampled reports whether a response with status should be logged
+func (f *accessLogFilter) sampled(status int) bool {
+	return status >= 400 || f.sampleRate >= 1 || rand.Float64() < f.sampleRate
+}
+
+// requestFields returns the access log fields for a completed request
+func (f *accessLogFilter) requestFields(r *http.Request, status, bytes int, elapsed time.Duration) logrus.Fields {
+	fields := logrus.Fields{
+		"status":         status,
+		"bytes":          bytes,
+		"elapsed":        elapsed,
+		"method":         r.Method,
+		"path":           r.URL.Path,
+		"remote_ip":      f.proxies.ClientIP(r),
+		"user_agent":     r.UserAgent(),
+		"content_length": r.ContentLength,
+		"proto":          r.Proto,
+	}
+	if r.URL.RawQuery != "" {
+		fields["query"] = r.URL.RawQuery
+		if !f.queryValues {
+			fields["query"] = redactQuery(r.URL.RawQuery)
+		}
+	}
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		fields["route"] = rctx.RoutePattern()
+	}
+	if id := RequestIDFromContext(r.Context()); id != "" {
+		fields["request_id"] = id
+	}
+	for k, v := range logging.TraceFields(r.Context()) {
+		fields[k] = v
+	}
+
+	if f.fields != nil {
+		for name := range fields {
+			if !f.fields[name] {
+				delete(fields, name)
+			}
+		}
+	}
+	return fields
+}
+
+// redactQuery replaces the values of a raw query, keeping its parameter names
+func redactQuery(raw string) string {
+	params := strings.Split(raw, "&")
+	for i, p := range params {
+		if name, _, ok := strings.Cut(p, "="); ok {
+			params[i] = name + "=[REDACTED]"
+		}
+	}
+	return strings.Join(params, "&")
+}
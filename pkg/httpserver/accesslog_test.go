@@ -0,0 +1,21 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccessLogQuery(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/callback?code=abc123&state=xyz&debug", nil)
+
+	fields := newAccessLogFilter(nil).requestFields(r, 200, 0, 0)
+	if got, want := fields["query"], "code=[REDACTED]&state=[REDACTED]&debug"; got != want {
+		t.Fatalf("query = %v, want %v", got, want)
+	}
+
+	fields = newAccessLogFilter(&AccessLogConfig{QueryValues: true}).requestFields(r, 200, 0, 0)
+	if got, want := fields["query"], "code=abc123&state=xyz&debug"; got != want {
+		t.Fatalf("query = %v, want %v with QueryValues", got, want)
+	}
+}
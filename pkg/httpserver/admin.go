@@ -0,0 +1,69 @@
+package httpserver
+
+import (
+	"net/http"
+
+	"github.com/bdlilley/easygo/pkg/buildinfo"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/bdlilley/easygo/pkg/profiling"
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// AdminConfig configures the admin listener. It hosts /healthz, /readyz,
+// /maintenance, /version, the metrics endpoint and the debug endpoints (unless
+// those set their own Port), keeping them off the public application port.
+type AdminConfig struct {
+	Port int
+	// RuntimeConfig, when set, is served as JSON at GET /config, e.g. the
+	// effective configuration with secrets redacted
+	RuntimeConfig func() any
+	// LogLevels, when set, serves GET and PUT /log-level to change log
+	// verbosity at runtime
+	LogLevels *logging.LevelManager
+	// Profiler, when set, serves GET and PUT /profiling to turn continuous
+	// profiling on and off at runtime
+	Profiler *profiling.Profiler
+	// Middleware runs on every admin request, e.g. an IPFilter restricting
+	// the admin port to internal ranges
+	Middleware []func(http.Handler) http.Handler
+}
+
+// newAdminRouter creates the admin router. The admin port is not access logged,
+// but it shares request IDs and panic recovery with the main router.
+func newAdminRouter(cfg *AdminConfig, logger *logrus.Logger, sink ErrorSink, requestIDHeader string) *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(requestIDMiddleware(requestIDHeader))
+	r.Use(recovererMiddleware(logger, sink))
+	r.Use(cfg.Middleware...)
+	return r
+}
+
+func configureAdmin(s *EasyGoHTTPServer, r *chi.Mux, cfg *AdminConfig) {
+	maintenanceRoutes(s, r)
+	r.Method(http.MethodGet, "/version", buildinfo.Handler())
+	if cfg.RuntimeConfig != nil {
+		r.Get("/config", func(w http.ResponseWriter, _ *http.Request) {
+			JSON(w, http.StatusOK, cfg.RuntimeConfig())
+		})
+	}
+	if cfg.LogLevels != nil {
+		r.Method(http.MethodGet, "/log-level", cfg.LogLevels.Handler())
+		r.Method(http.MethodPut, "/log-level", cfg.LogLevels.Handler())
+	}
+	if cfg.Profiler != nil {
+		r.Method(http.MethodGet, "/profiling", cfg.Profiler.Handler())
+		r.Method(http.MethodPut, "/profiling", cfg.Profiler.Handler())
+	}
+	s.admin = r
+	s.auxServers = append(s.auxServers, newAuxServer("admin", cfg.Port, r))
+}
+
+// AdminRouter returns the admin listener's router for adding operational
+// endpoints, or nil when no admin listener is configured
+func (s *EasyGoHTTPServer) AdminRouter() chi.Router {
+	if s.admin == nil {
+		return nil
+	}
+	return s.admin
+}
@@ -0,0 +1,169 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bdlilley/easygo"
+	"github.com/bdlilley/easygo/pkg/clock"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+)
+
+// DefaultAPIKeyHeader is the header checked by APIKeyAuthenticator
+const DefaultAPIKeyHeader = "X-API-Key"
+
+// apiKeyMissReloadInterval limits reloads triggered by unknown keys, so a key
+// rotated in before the next refresh is accepted without letting bad keys
+// hammer Secrets Manager
+const apiKeyMissReloadInterval = 30 * time.Second
+
+// APIKeyAuthConfig configures API key authentication. The secret is a JSON
+// object mapping each caller's identity to its key:
+//
+//	{"billing-service": "k3y...", "reports-cron": "s3cr3t..."}
+type APIKeyAuthConfig struct {
+	Secrets  easygo.SecretsReader
+	SecretID string
+	// Header carries the key (default: X-API-Key)
+	Header string
+	// RefreshInterval is how often the secret is reloaded (default: 5m)
+	RefreshInterval time.Duration
+	// Logger logs failed reloads at error (default: logging.Noop)
+	Logger logging.Logger
+	// Clock times refreshes and miss reloads (default: clock.Real)
+	Clock clock.Clock
+}
+
+// APIKeyAuthenticator validates API keys loaded from a Secrets Manager secret
+type APIKeyAuthenticator struct {
+	cfg APIKeyAuthConfig
+
+	// reloadMu serializes reloads triggered by unknown keys
+	reloadMu sync.Mutex
+	mu       sync.RWMutex
+	keys     map[[sha256.Size]byte]string
+	loadedAt time.Time
+}
+
+type apiKeyIdentityKey struct{}
+
+// NewAPIKeyAuthenticator loads the keys and reloads them in the background
+// every RefreshInterval until ctx is cancelled
+func NewAPIKeyAuthenticator(ctx context.Context, cfg *APIKeyAuthConfig) (*APIKeyAuthenticator, error) {
+	a := &APIKeyAuthenticator{cfg: *cfg}
+	if a.cfg.Secrets == nil || a.cfg.SecretID == "" {
+		return nil, errs.New(errs.Internal, "Secrets and SecretID are required")
+	}
+	if a.cfg.Header == "" {
+		a.cfg.Header = DefaultAPIKeyHeader
+	}
+	if a.cfg.RefreshInterval <= 0 {
+		a.cfg.RefreshInterval = 5 * time.Minute
+	}
+	if a.cfg.Logger == nil {
+		a.cfg.Logger = logging.Noop()
+	}
+	if a.cfg.Clock == nil {
+		a.cfg.Clock = clock.Real()
+	}
+
+	if err := a.Reload(ctx); err != nil {
+		return nil, err
+	}
+	go a.refreshLoop(ctx)
+
+	return a, nil
+}
+
+// Reload fetches the current keys from Secrets Manager
+func (a *APIKeyAuthenticator) Reload(ctx context.Context) error {
+	var byIdentity map[string]string
+	if err := a.cfg.Secrets.GetLatestJsonSecretValue(ctx, a.cfg.SecretID, &byIdentity); err != nil {
+		return errs.Errorf("failed to load API keys from %s: %w", a.cfg.SecretID, err)
+	}
+
+	keys := make(map[[sha256.Size]byte]string, len(byIdentity))
+	for identity, key := range byIdentity {
+		if key == "" {
+			continue
+		}
+		keys[sha256.Sum256([]byte(key))] = identity
+	}
+
+	a.mu.Lock()
+	a.keys = keys
+	a.loadedAt = a.cfg.Clock.Now()
+	a.mu.Unlock()
+	return nil
+}
+
+func (a *APIKeyAuthenticator) refreshLoop(ctx context.Context) {
+	ticker := a.cfg.Clock.NewTicker(a.cfg.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if err := a.Reload(ctx); err != nil {
+				a.cfg.Logger.WithError(err).Error("failed to reload API keys")
+			}
+		}
+	}
+}
+
+// Authenticate returns the identity for key. Keys are compared by SHA-256
+// digest so lookups do not leak key contents through timing.
+func (a *APIKeyAuthenticator) Authenticate(ctx context.Context, key string) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(key))
+
+	a.mu.RLock()
+	identity, ok := a.keys[sum]
+	stale := a.cfg.Clock.Since(a.loadedAt) > apiKeyMissReloadInterval
+	a.mu.RUnlock()
+	if ok || !stale {
+		return identity, ok
+	}
+
+	a.reloadMu.Lock()
+	a.mu.RLock()
+	stale = a.cfg.Clock.Since(a.loadedAt) > apiKeyMissReloadInterval
+	a.mu.RUnlock()
+	if stale {
+		if err := a.Reload(ctx); err != nil {
+			a.cfg.Logger.WithError(err).Error("failed to reload API keys")
+		}
+	}
+	a.reloadMu.Unlock()
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	identity, ok = a.keys[sum]
+	return identity, ok
+}
+
+// Middleware rejects requests without a valid API key with 401 and stores the
+// key's identity in the request context
+func (a *APIKeyAuthenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := a.Authenticate(r.Context(), r.Header.Get(a.cfg.Header))
+		if !ok {
+			writeProblem(w, r, http.StatusUnauthorized, "invalid API key")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyIdentityKey{}, identity)))
+	})
+}
+
+// APIKeyIdentityFromContext returns the identity of the API key validated by APIKeyAuthenticator.Middleware
+func APIKeyIdentityFromContext(ctx context.Context) (string, bool) {
+	identity, ok := ctx.Value(apiKeyIdentityKey{}).(string)
+	return identity, ok
+}
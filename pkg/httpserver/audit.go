@@ -0,0 +1,103 @@
+package httpserver
+
+import (
+	"net/http"
+
+	"github.com/bdlilley/easygo/pkg/audit"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// AuditConfig configures the Audit middleware
+type AuditConfig struct {
+	// Logger records the events (default: audit.Default())
+	Logger *audit.Logger
+	// Methods are the audited request methods (default: POST, PUT, PATCH, DELETE)
+	Methods []string
+	// Actor identifies the caller (default: the JWT subject, API key identity
+	// or client certificate common name, else "anonymous")
+	Actor func(r *http.Request) string
+	// Resource names the target (default: the request path)
+	Resource func(r *http.Request) string
+}
+
+// Audit returns middleware that records an audit event for each mutating
+// request once it completes. The action is "METHOD route pattern" and the
+// outcome follows the status: denied for 401 and 403, failure for other
+// errors. Mount it after authentication so the actor is known.
+func Audit(cfg *AuditConfig) func(http.Handler) http.Handler {
+	c := AuditConfig{}
+	if cfg != nil {
+		c = *cfg
+	}
+	if c.Methods == nil {
+		c.Methods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	methods := map[string]bool{}
+	for _, m := range c.Methods {
+		methods[m] = true
+	}
+	if c.Actor == nil {
+		c.Actor = defaultAuditActor
+	}
+	if c.Resource == nil {
+		c.Resource = func(r *http.Request) string { return r.URL.Path }
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !methods[r.Method] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			outcome := audit.OutcomeSuccess
+			switch {
+			case status == http.StatusUnauthorized || status == http.StatusForbidden:
+				outcome = audit.OutcomeDenied
+			case status >= 400:
+				outcome = audit.OutcomeFailure
+			}
+			action := r.Method + " " + r.URL.Path
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				action = r.Method + " " + rctx.RoutePattern()
+			}
+			metadata := map[string]any{
+				"status":     status,
+				"remote_ip":  ClientIP(r),
+				"user_agent": r.UserAgent(),
+			}
+			if id := RequestIDFromContext(r.Context()); id != "" {
+				metadata["request_id"] = id
+			}
+
+			log := audit.Log
+			if c.Logger != nil {
+				log = c.Logger.Log
+			}
+			if err := log(r.Context(), c.Actor(r), action, c.Resource(r), outcome, metadata); err != nil {
+				logging.FromContext(r.Context()).WithError(err).Error("failed to record audit event")
+			}
+		})
+	}
+}
+
+func defaultAuditActor(r *http.Request) string {
+	if sub := SubjectFromContext(r.Context()); sub != "" {
+		return sub
+	}
+	if id, ok := APIKeyIdentityFromContext(r.Context()); ok && id != "" {
+		return id
+	}
+	if id, ok := ClientIdentityFromContext(r.Context()); ok && id.Subject.CommonName != "" {
+		return id.Subject.CommonName
+	}
+	return "anonymous"
+}
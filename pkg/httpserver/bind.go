@@ -0,0 +1,155 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// defaultBindMaxBytes is the body limit Bind applies when WithMaxBodyBytes is not given
+const defaultBindMaxBytes = 1 << 20
+
+// BindError is returned by Bind; Status is the HTTP status the error maps to
+type BindError struct {
+	Status  int
+	Message string
+	// Fields lists the failed rules when validation fails
+	Fields []FieldError
+	Err    error
+}
+
+func (e *BindError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+type bindOptions struct {
+	maxBytes              int64
+	disallowUnknownFields bool
+	skipValidation        bool
+}
+
+// BindOption configures Bind
+type BindOption func(*bindOptions)
+
+// WithMaxBodyBytes limits the body Bind reads (default: 1MB)
+func WithMaxBodyBytes(n int64) BindOption {
+	return func(o *bindOptions) {
+		o.maxBytes = n
+	}
+}
+
+// WithDisallowUnknownFields rejects bodies with fields not present in T
+func WithDisallowUnknownFields() BindOption {
+	return func(o *bindOptions) {
+		o.disallowUnknownFields = true
+	}
+}
+
+// WithoutValidation skips struct-tag validation
+func WithoutValidation() BindOption {
+	return func(o *bindOptions) {
+		o.skipValidation = true
+	}
+}
+
+// Bind decodes a JSON request body into a T and validates its validate struct
+// tags. It requires a JSON Content-Type, limits the body size and rejects
+// trailing data. Errors are *BindError; pass them to WriteBindError for a
+// consistent response.
+func Bind[T any](r *http.Request, opts ...BindOption) (T, error) {
+	var v T
+	o := bindOptions{maxBytes: defaultBindMaxBytes}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if !isJSONContentType(r.Header.Get("Content-Type")) {
+		return v, &BindError{Status: http.StatusUnsupportedMediaType, Message: "Content-Type must be application/json"}
+	}
+	if r.Body == nil || r.Body == http.NoBody {
+		return v, &BindError{Status: http.StatusBadRequest, Message: "request body is empty"}
+	}
+
+	body := io.LimitReader(r.Body, o.maxBytes+1)
+	data, err := io.ReadAll(body)
+	if err != nil {
+		if IsRequestBodyTooLarge(err) {
+			return v, &BindError{Status: http.StatusRequestEntityTooLarge, Message: "request body too large", Err: err}
+		}
+		return v, &BindError{Status: http.StatusBadRequest, Message: "failed to read request body", Err: err}
+	}
+	if int64(len(data)) > o.maxBytes {
+		return v, &BindError{Status: http.StatusRequestEntityTooLarge, Message: "request body too large"}
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return v, &BindError{Status: http.StatusBadRequest, Message: "request body is empty"}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if o.disallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(&v); err != nil {
+		return v, decodeError(err)
+	}
+	if dec.More() {
+		return v, &BindError{Status: http.StatusBadRequest, Message: "request body must contain a single JSON value"}
+	}
+	if !o.skipValidation {
+		if err := Validate(v); err != nil {
+			return v, err
+		}
+	}
+
+	return v, nil
+}
+
+func decodeError(err error) *BindError {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return &BindError{Status: http.StatusBadRequest, Message: fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset), Err: err}
+	case errors.As(err, &typeErr):
+		return &BindError{Status: http.StatusBadRequest, Message: fmt.Sprintf("invalid value for field %q", typeErr.Field), Err: err}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &BindError{Status: http.StatusBadRequest, Message: "malformed JSON", Err: err}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return &BindError{Status: http.StatusBadRequest, Message: strings.TrimPrefix(err.Error(), "json: ")}
+	}
+	return &BindError{Status: http.StatusBadRequest, Message: "invalid request body", Err: err}
+}
+
+func isJSONContentType(ct string) bool {
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// JSON writes v as a JSON response with status
+func JSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if v != nil {
+		_ = json.NewEncoder(w).Encode(v)
+	}
+}
+
+// WriteBindError writes the problem response for an error returned by Bind
+func WriteBindError(w http.ResponseWriter, r *http.Request, err error) {
+	WriteProblem(w, r, ProblemFromError(err))
+}
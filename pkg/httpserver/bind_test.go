@@ -0,0 +1,56 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBind(t *testing.T) {
+	type payload struct {
+		Name  string `json:"name"`
+		Count int    `json:"count" validate:"gte=0"`
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		opts        []BindOption
+		wantStatus  int
+	}{
+		{"valid", "application/json", `{"name":"a","count":1}`, nil, 0},
+		{"charset", "application/json; charset=utf-8", `{"name":"a"}`, nil, 0},
+		{"wrong content type", "text/plain", `{"name":"a"}`, nil, http.StatusUnsupportedMediaType},
+		{"empty", "application/json", ``, nil, http.StatusBadRequest},
+		{"malformed", "application/json", `{"name":`, nil, http.StatusBadRequest},
+		{"wrong type", "application/json", `{"count":"x"}`, nil, http.StatusBadRequest},
+		{"trailing data", "application/json", `{} {}`, nil, http.StatusBadRequest},
+		{"unknown field allowed", "application/json", `{"other":1}`, nil, 0},
+		{"unknown field", "application/json", `{"other":1}`, []BindOption{WithDisallowUnknownFields()}, http.StatusBadRequest},
+		{"validation", "application/json", `{"name":"a","count":-1}`, nil, http.StatusUnprocessableEntity},
+		{"too large", "application/json", `{"name":"abcdefghij"}`, []BindOption{WithMaxBodyBytes(8)}, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+
+			_, err := Bind[payload](r, tt.opts...)
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			be, ok := err.(*BindError)
+			if !ok {
+				t.Fatalf("expected *BindError, got %v", err)
+			}
+			if be.Status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%v)", be.Status, tt.wantStatus, be)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+)
+
+// defaultMaxRequestBodyBytes is the request body limit applied when
+// MaxRequestBodyBytes is zero
+const defaultMaxRequestBodyBytes = 10 << 20
+
+// LimitRequestBody returns middleware that rejects bodies larger than n bytes.
+// Requests declaring a larger Content-Length get 413 immediately; otherwise
+// reads past the limit fail with *http.MaxBytesError, which handlers can
+// detect with IsRequestBodyTooLarge. On a route group it can only tighten the
+// server-wide limit; to allow larger bodies on some routes, disable the
+// server-wide limit and apply LimitRequestBody per group instead.
+func LimitRequestBody(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				writeProblem(w, r, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = http.MaxBytesReader(w, r.Body, n)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// IsRequestBodyTooLarge reports whether err came from reading past a LimitRequestBody limit
+func IsRequestBodyTooLarge(err error) bool {
+	var mbe *http.MaxBytesError
+	return errors.As(err, &mbe)
+}
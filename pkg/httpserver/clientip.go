@@ -0,0 +1,96 @@
+package httpserver
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// TrustedProxies resolves client IPs for requests that arrive through known
+// proxies. A nil *TrustedProxies trusts no proxy and uses RemoteAddr.
+type TrustedProxies struct {
+	prefixes []netip.Prefix
+}
+
+// ParseTrustedProxies parses proxy addresses, as CIDRs ("10.0.0.0/8") or single IPs
+func ParseTrustedProxies(cidrs ...string) (*TrustedProxies, error) {
+	t := &TrustedProxies{}
+	for _, c := range cidrs {
+		p, err := parsePrefix(c)
+		if err != nil {
+			return nil, err
+		}
+		t.prefixes = append(t.prefixes, p)
+	}
+	return t, nil
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, errs.Errorf("invalid CIDR %q: %w", s, err)
+		}
+		return p.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, errs.Errorf("invalid IP %q: %w", s, err)
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+func (t *TrustedProxies) trusted(addr netip.Addr) bool {
+	if t == nil {
+		return false
+	}
+	for _, p := range t.prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the client address. When the peer is a trusted proxy,
+// X-Forwarded-For is walked from the right, skipping trusted hops, so clients
+// cannot spoof their address by sending the header themselves.
+func (t *TrustedProxies) ClientIP(r *http.Request) string {
+	peer := remoteAddr(r)
+	addr, err := netip.ParseAddr(peer)
+	if err != nil || !t.trusted(addr.Unmap()) {
+		return peer
+	}
+
+	hops := r.Header.Values("X-Forwarded-For")
+	forwarded := strings.Split(strings.Join(hops, ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if hop == "" {
+			continue
+		}
+		hopAddr, err := netip.ParseAddr(hop)
+		if err != nil {
+			// a malformed entry ends the trustworthy part of the chain
+			return peer
+		}
+		if !t.trusted(hopAddr.Unmap()) {
+			return hopAddr.Unmap().String()
+		}
+		peer = hopAddr.Unmap().String()
+	}
+	return peer
+}
+
+// remoteAddr returns the host part of RemoteAddr
+func remoteAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
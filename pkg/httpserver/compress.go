@@ -0,0 +1,250 @@
+package httpserver
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// CompressionConfig configures response compression
+type CompressionConfig struct {
+	// MinSize is the smallest response body compressed, in bytes (default: 1024)
+	MinSize int
+	// ContentTypes are the media types compressed (default: common text, JSON,
+	// JavaScript, XML and SVG types)
+	ContentTypes []string
+	// Level is the gzip compression level (default: gzip.DefaultCompression)
+	Level int
+	// EnableBrotli prefers brotli for clients that accept it
+	EnableBrotli bool
+}
+
+var defaultCompressibleTypes = []string{
+	"text/html", "text/plain", "text/css", "text/csv", "text/javascript", "text/xml",
+	"application/javascript", "application/json", "application/problem+json",
+	"application/xml", "image/svg+xml",
+}
+
+// Compress returns middleware that gzip (or brotli) encodes responses whose
+// content type is compressible and whose body reaches MinSize. Responses that
+// already set Content-Encoding are left alone.
+func Compress(cfg *CompressionConfig) func(http.Handler) http.Handler {
+	minSize := cfg.MinSize
+	if minSize <= 0 {
+		minSize = 1024
+	}
+	level := cfg.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	types := map[string]bool{}
+	list := cfg.ContentTypes
+	if len(list) == 0 {
+		list = defaultCompressibleTypes
+	}
+	for _, t := range list {
+		types[strings.ToLower(t)] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), cfg.EnableBrotli)
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				encoding:       encoding,
+				level:          level,
+				minSize:        minSize,
+				types:          types,
+			}
+			defer cw.Close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding picks br or gzip from an Accept-Encoding header
+func negotiateEncoding(header string, brotliEnabled bool) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				continue
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	switch {
+	case brotliEnabled && accepted["br"]:
+		return "br"
+	case accepted["gzip"]:
+		return "gzip"
+	}
+	return ""
+}
+
+// compressWriter buffers the start of the body until it knows whether the
+// response is worth compressing
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	level    int
+	minSize  int
+	types    map[string]bool
+
+	status      int
+	buf         bytes.Buffer
+	decided     bool
+	encoder     io.WriteCloser
+	wroteHeader bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status != 0 {
+		return
+	}
+	// informational responses are sent immediately
+	if status < 200 {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.status = status
+	if status == http.StatusNoContent || status == http.StatusNotModified || cw.Header().Get("Content-Encoding") != "" {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if cw.decided {
+		if cw.encoder != nil {
+			return cw.encoder.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf.Write(b)
+	if cw.buf.Len() >= cw.minSize {
+		if err := cw.decide(cw.compressible()); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+func (cw *compressWriter) compressible() bool {
+	if cw.Header().Get("Content-Encoding") != "" {
+		return false
+	}
+	ct := cw.Header().Get("Content-Type")
+	if ct == "" {
+		ct = http.DetectContentType(cw.buf.Bytes())
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	return cw.types[strings.ToLower(mediaType)]
+}
+
+// decide writes the header and buffered bytes, starting the encoder when compress is true
+func (cw *compressWriter) decide(compress bool) error {
+	if cw.decided {
+		return nil
+	}
+	cw.decided = true
+
+	if compress {
+		h := cw.Header()
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		if cw.encoding == "br" {
+			cw.encoder = brotli.NewWriterLevel(cw.ResponseWriter, brotli.DefaultCompression)
+		} else {
+			gz, err := gzip.NewWriterLevel(cw.ResponseWriter, cw.level)
+			if err != nil {
+				return errs.Errorf("failed to create gzip writer: %w", err)
+			}
+			cw.encoder = gz
+		}
+	}
+
+	cw.writeHeader()
+	if cw.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if cw.encoder != nil {
+		_, err = cw.encoder.Write(cw.buf.Bytes())
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf.Bytes())
+	}
+	cw.buf.Reset()
+	return err
+}
+
+func (cw *compressWriter) writeHeader() {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+// Flush sends buffered data; a response flushed before MinSize is reached is
+// compressed if its content type allows, since streaming responses keep growing
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		_ = cw.decide(cw.compressible())
+	}
+	if f, ok := cw.encoder.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Close writes a short, uncompressed body or finishes the compressed stream
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if cw.status == 0 {
+			// the handler wrote nothing
+			return nil
+		}
+		return cw.decide(false)
+	}
+	if cw.encoder != nil {
+		return cw.encoder.Close()
+	}
+	return nil
+}
+
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(cw.ResponseWriter).Hijack()
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,69 @@
+package httpserver
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"hello":"world"}`, 100)
+	handler := Compress(&CompressionConfig{MinSize: 64})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+		_, _ = io.WriteString(w, r.URL.Query().Get("body"))
+	}))
+
+	tests := []struct {
+		name, body, contentType, wantEncoding string
+	}{
+		{"large json", large, "application/json", "gzip"},
+		{"small json", `{"a":1}`, "application/json", ""},
+		{"image", large, "image/png", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?type="+tt.contentType+"&body="+tt.body, nil)
+			req.Header.Set("Accept-Encoding", "gzip, deflate")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			body := rec.Body.Bytes()
+			if tt.wantEncoding == "gzip" {
+				zr, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if body, err = io.ReadAll(zr); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if string(body) != tt.body {
+				t.Fatalf("body mismatch: got %d bytes, want %d", len(body), len(tt.body))
+			}
+		})
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		brotli bool
+		want   string
+	}{
+		{"gzip, br", true, "br"},
+		{"gzip, br", false, "gzip"},
+		{"br;q=0, gzip", true, "gzip"},
+		{"identity", true, ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header, tt.brotli); got != tt.want {
+			t.Errorf("negotiateEncoding(%q, %v) = %q, want %q", tt.header, tt.brotli, got, tt.want)
+		}
+	}
+}
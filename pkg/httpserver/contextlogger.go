@@ -0,0 +1,23 @@
+package httpserver
+
+import (
+	"net/http"
+
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// contextLoggerMiddleware stores a logger with the request ID and trace IDs in
+// the request context; handlers get it with logging.FromContext(r.Context())
+func contextLoggerMiddleware(logger *logrus.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			entry := logging.WithTrace(r.Context(), logger)
+			if id := RequestIDFromContext(r.Context()); id != "" {
+				entry = entry.WithField("request_id", id)
+			}
+			ctx := logging.WithContext(r.Context(), entry)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
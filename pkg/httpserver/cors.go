@@ -0,0 +1,45 @@
+package httpserver
+
+import (
+	"net/http"
+
+	"github.com/go-chi/cors"
+)
+
+// CORSConfig configures cross-origin resource sharing
+type CORSConfig struct {
+	// AllowedOrigins may contain "*" or wildcards such as "https://*.example.com"
+	AllowedOrigins []string
+	// AllowedMethods defaults to GET, POST, PUT, PATCH, DELETE and OPTIONS
+	AllowedMethods []string
+	// AllowedHeaders defaults to Accept, Authorization, Content-Type and the request ID header
+	AllowedHeaders []string
+	// ExposedHeaders are readable by browser scripts; the request ID header is always exposed
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long, in seconds, browsers may cache preflight responses
+	MaxAge int
+}
+
+func corsMiddleware(cfg *CORSConfig, requestIDHeader string) func(http.Handler) http.Handler {
+	methods := cfg.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{
+			http.MethodGet, http.MethodPost, http.MethodPut,
+			http.MethodPatch, http.MethodDelete, http.MethodOptions,
+		}
+	}
+	headers := cfg.AllowedHeaders
+	if len(headers) == 0 {
+		headers = []string{"Accept", "Authorization", "Content-Type", requestIDHeader}
+	}
+
+	return cors.Handler(cors.Options{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   methods,
+		AllowedHeaders:   headers,
+		ExposedHeaders:   append([]string{requestIDHeader}, cfg.ExposedHeaders...),
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge,
+	})
+}
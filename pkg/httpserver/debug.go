@@ -0,0 +1,63 @@
+package httpserver
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// DebugConfig enables the net/http/pprof and expvar handlers under /debug/
+type DebugConfig struct {
+	// Authorize rejects requests with 403 when it returns false. Without it,
+	// the endpoints only answer on a private listener, Port or the Admin port;
+	// on the application port they reject every request.
+	Authorize func(r *http.Request) bool
+	// Port serves the debug endpoints on a separate listener instead of the main router
+	Port int
+}
+
+// debugRoutes registers /debug/pprof/* and /debug/vars on r; public routes
+// without an Authorize func deny every request
+func debugRoutes(r chi.Router, cfg *DebugConfig, public bool) {
+	authorize := cfg.Authorize
+	if authorize == nil && public {
+		authorize = func(*http.Request) bool { return false }
+	}
+	if authorize != nil {
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if !authorize(req) {
+					writeProblem(w, req, http.StatusForbidden, "debug endpoints are not authorized")
+					return
+				}
+				next.ServeHTTP(w, req)
+			})
+		})
+	}
+
+	r.HandleFunc("/debug/pprof/", pprof.Index)
+	r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	r.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	r.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	// named profiles such as heap, goroutine and allocs
+	r.HandleFunc("/debug/pprof/{name}", pprof.Index)
+	r.Method(http.MethodGet, "/debug/vars", expvar.Handler())
+}
+
+// configureDebug adds the debug routes to routes, the application router
+// unless admin is set, or returns a separate server for them when cfg.Port is
+// set
+func configureDebug(routes chi.Router, cfg *DebugConfig, admin bool) *auxServer {
+	if cfg.Port == 0 {
+		routes.Group(func(g chi.Router) {
+			debugRoutes(g, cfg, !admin)
+		})
+		return nil
+	}
+	mux := chi.NewRouter()
+	debugRoutes(mux, cfg, false)
+	return newAuxServer("debug", cfg.Port, mux)
+}
@@ -0,0 +1,38 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestDebugAuthorization(t *testing.T) {
+	status := func(cfg *DebugConfig, admin bool, header string) int {
+		r := chi.NewRouter()
+		configureDebug(r, cfg, admin)
+		req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+		req.Header.Set("X-Debug", header)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	authorize := func(r *http.Request) bool { return r.Header.Get("X-Debug") == "yes" }
+
+	for name, tc := range map[string]struct {
+		cfg    *DebugConfig
+		admin  bool
+		header string
+		want   int
+	}{
+		"public without Authorize": {&DebugConfig{}, false, "yes", http.StatusForbidden},
+		"admin without Authorize":  {&DebugConfig{}, true, "", http.StatusOK},
+		"authorized":               {&DebugConfig{Authorize: authorize}, false, "yes", http.StatusOK},
+		"unauthorized":             {&DebugConfig{Authorize: authorize}, true, "no", http.StatusForbidden},
+	} {
+		if got := status(tc.cfg, tc.admin, tc.header); got != tc.want {
+			t.Errorf("%s: got %d, want %d", name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DrainResult reports how a drain went
+type DrainResult struct {
+	// InFlight is the number of requests active when draining started
+	InFlight int64
+	// CutOff is the number of requests still active at the deadline, which
+	// were terminated by closing their connections
+	CutOff   int64
+	Duration time.Duration
+}
+
+// drainState tracks in-flight requests and whether the server is draining
+type drainState struct {
+	inFlight atomic.Int64
+	draining atomic.Bool
+	// delay is how long readiness fails before the listener closes
+	delay time.Duration
+}
+
+// track counts in-flight requests. Upgraded connections such as WebSockets are
+// not counted; they are closed by their own shutdown hooks.
+func (d *drainState) track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isUpgrade(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		d.inFlight.Add(1)
+		defer d.inFlight.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isUpgrade reports whether r asks to upgrade its connection. Connection is a
+// comma-separated token list; browsers send e.g. "keep-alive, Upgrade".
+func isUpgrade(r *http.Request) bool {
+	for _, v := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// InFlight returns the number of requests currently being handled
+func (s *EasyGoHTTPServer) InFlight() int64 {
+	return s.drain.inFlight.Load()
+}
+
+// Draining reports whether the server has started draining
+func (s *EasyGoHTTPServer) Draining() bool {
+	return s.drain.draining.Load()
+}
+
+// Drain fails readiness immediately, waits DrainDelay so load balancers stop
+// sending new requests, then stops accepting connections and waits for active
+// requests until ctx is done. Requests still running at the deadline are cut
+// off by closing their connections. Shutdown drains before running hooks.
+func (s *EasyGoHTTPServer) Drain(ctx context.Context) (*DrainResult, error) {
+	start := time.Now()
+	s.drain.draining.Store(true)
+	s.health.readiness.Shutdown()
+	res := &DrainResult{InFlight: s.InFlight()}
+	s.logger.WithField("inFlight", res.InFlight).Info("HTTP server draining")
+
+	if s.drain.delay > 0 {
+		select {
+		case <-time.After(s.drain.delay):
+		case <-ctx.Done():
+		}
+	}
+
+	err := s.server.Shutdown(ctx)
+	if err != nil {
+		res.CutOff = s.InFlight()
+		_ = s.server.Close()
+		s.logger.WithField("cutOff", res.CutOff).Warn("drain deadline reached, closed active connections")
+	}
+	res.Duration = time.Since(start)
+	return res, err
+}
@@ -0,0 +1,147 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// drainServer serves a /slow route that blocks until release is closed
+func drainServer(t *testing.T, delay time.Duration) (s *EasyGoHTTPServer, base string, entered chan struct{}, release chan struct{}) {
+	t.Helper()
+	l := localListener(t)
+	s = NewEasyGoHTTPServer(&NewEasyGoHTTPServerArgs{Listener: l, DrainDelay: delay})
+	entered, release = make(chan struct{}, 4), make(chan struct{})
+	s.Chi.Get("/slow", func(w http.ResponseWriter, _ *http.Request) {
+		entered <- struct{}{}
+		<-release
+	})
+	go func() { _ = s.Serve(l) }()
+	return s, "http://" + l.Addr().String(), entered, release
+}
+
+// slowRequest sends GET /slow with the given Connection header
+func slowRequest(t *testing.T, url, connection string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url+"/slow", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if connection != "" {
+		req.Header.Set("Connection", connection)
+	}
+	go func() {
+		if resp, err := http.DefaultClient.Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}()
+}
+
+func readyCode(s *EasyGoHTTPServer) int {
+	w := httptest.NewRecorder()
+	s.ReadinessHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	return w.Code
+}
+
+func TestDrain(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	s, base, entered, release := drainServer(t, delay)
+	slowRequest(t, base, "")
+	<-entered
+	if s.InFlight() != 1 {
+		t.Fatalf("got %d in flight, want 1", s.InFlight())
+	}
+	if code := readyCode(s); code != http.StatusOK {
+		t.Fatalf("readiness is %d before draining", code)
+	}
+
+	type drained struct {
+		res *DrainResult
+		err error
+	}
+	done := make(chan drained, 1)
+	go func() {
+		res, err := s.Drain(context.Background())
+		done <- drained{res, err}
+	}()
+	// readiness fails for the whole delay, while the request is still running
+	for !s.Draining() {
+		time.Sleep(time.Millisecond)
+	}
+	if code := readyCode(s); code != http.StatusServiceUnavailable {
+		t.Fatalf("readiness is %d while draining, want 503", code)
+	}
+	close(release)
+
+	d := <-done
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
+	if d.res.InFlight != 1 || d.res.CutOff != 0 {
+		t.Fatalf("got %+v, want 1 in flight and none cut off", d.res)
+	}
+	if d.res.Duration < delay {
+		t.Fatalf("drained in %v, want at least the %v delay", d.res.Duration, delay)
+	}
+}
+
+func TestDrainCutOff(t *testing.T) {
+	s, base, entered, release := drainServer(t, 0)
+	defer close(release)
+	slowRequest(t, base, "")
+	slowRequest(t, base, "")
+	// upgrade requests are not counted, however the token list is written
+	slowRequest(t, base, "keep-alive, Upgrade")
+	for range 3 {
+		<-entered
+	}
+	if s.InFlight() != 2 {
+		t.Fatalf("got %d in flight, want 2", s.InFlight())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	res, err := s.Drain(ctx)
+	if err == nil {
+		t.Fatal("expected the deadline's error")
+	}
+	if res.InFlight != 2 || res.CutOff != 2 {
+		t.Fatalf("got %+v, want 2 in flight and 2 cut off", res)
+	}
+}
+
+func TestIsUpgrade(t *testing.T) {
+	for connection, want := range map[string]bool{
+		"Upgrade":             true,
+		"upgrade":             true,
+		"keep-alive, Upgrade": true,
+		"Upgrade,keep-alive":  true,
+		"keep-alive":          false,
+		"":                    false,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Connection", connection)
+		if got := isUpgrade(r); got != want {
+			t.Errorf("isUpgrade(%q) = %v, want %v", connection, got, want)
+		}
+	}
+}
+
+func TestRecovererUpgrade(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	h := recovererMiddleware(logger, nil)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("socket closed")
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	r.Header.Set("Connection", "keep-alive, Upgrade")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Body.Len() != 0 {
+		t.Fatalf("wrote %q to an upgraded connection", w.Body.String())
+	}
+}
@@ -0,0 +1,167 @@
+package httpserver
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/bdlilley/easygo/pkg/runtimeinfo"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultEnvPort is used when PORT is unset
+const defaultEnvPort = 8080
+
+// NewEasyGoHTTPServerFromEnv builds a server from NewEasyGoHTTPServerArgsFromEnv
+func NewEasyGoHTTPServerFromEnv(opts ...Option) (*EasyGoHTTPServer, error) {
+	args, err := NewEasyGoHTTPServerArgsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewEasyGoHTTPServer(args, opts...), nil
+}
+
+// NewEasyGoHTTPServerArgsFromEnv reads server settings from the environment so
+// they can be adjusted before constructing the server. Unset variables keep
+// the NewEasyGoHTTPServerArgs defaults.
+//
+//	PORT                       listen port (default: 8080)
+//	UNIX_SOCKET                listen on a unix socket path instead of PORT
+//	ADMIN_PORT                 serve health, metrics and debug endpoints on this port
+//	METRICS_ENABLED            true to serve Prometheus metrics at /metrics
+//	METRICS_RUNTIME_LABELS     true to label them with runtimeinfo labels
+//	HTTP_READ_TIMEOUT          durations such as 30s or 2m
+//	HTTP_READ_HEADER_TIMEOUT
+//	HTTP_WRITE_TIMEOUT
+//	HTTP_IDLE_TIMEOUT
+//	HTTP_SHUTDOWN_TIMEOUT
+//	HTTP_MAX_HEADER_BYTES      integer byte counts
+//	HTTP_MAX_BODY_BYTES
+//	TLS_CERT_FILE              serve HTTPS with this certificate and key
+//	TLS_KEY_FILE
+//	TLS_CLIENT_CA_FILE         require client certificates signed by this CA bundle
+//	CORS_ALLOWED_ORIGINS       comma separated origins; enables CORS
+//	CORS_ALLOW_CREDENTIALS     true to allow credentialed requests
+//	LOG_LEVEL                  logrus level (default: info)
+//	LOG_FORMAT                 json (default), text, or ecs (Elastic Common Schema/Datadog)
+//
+// Logs are tagged with runtimeinfo fields.
+func NewEasyGoHTTPServerArgsFromEnv() (*NewEasyGoHTTPServerArgs, error) {
+	e := &envReader{}
+	args := &NewEasyGoHTTPServerArgs{
+		Port:                e.int("PORT", defaultEnvPort),
+		UnixSocket:          os.Getenv("UNIX_SOCKET"),
+		ReadTimeout:         e.duration("HTTP_READ_TIMEOUT"),
+		ReadHeaderTimeout:   e.duration("HTTP_READ_HEADER_TIMEOUT"),
+		WriteTimeout:        e.duration("HTTP_WRITE_TIMEOUT"),
+		IdleTimeout:         e.duration("HTTP_IDLE_TIMEOUT"),
+		ShutdownTimeout:     e.duration("HTTP_SHUTDOWN_TIMEOUT"),
+		MaxHeaderBytes:      e.int("HTTP_MAX_HEADER_BYTES", 0),
+		MaxRequestBodyBytes: int64(e.int("HTTP_MAX_BODY_BYTES", 0)),
+		TLSCertFile:         os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:          os.Getenv("TLS_KEY_FILE"),
+	}
+
+	if port := e.int("ADMIN_PORT", 0); port > 0 {
+		args.Admin = &AdminConfig{Port: port}
+	}
+	if e.bool("METRICS_ENABLED") {
+		args.Metrics = &MetricsConfig{RuntimeLabels: e.bool("METRICS_RUNTIME_LABELS")}
+	}
+	if origins := os.Getenv("CORS_ALLOWED_ORIGINS"); origins != "" {
+		args.CORS = &CORSConfig{AllowCredentials: e.bool("CORS_ALLOW_CREDENTIALS")}
+		for _, o := range strings.Split(origins, ",") {
+			if o = strings.TrimSpace(o); o != "" {
+				args.CORS.AllowedOrigins = append(args.CORS.AllowedOrigins, o)
+			}
+		}
+	}
+
+	logger := logrus.New()
+	switch format := os.Getenv("LOG_FORMAT"); format {
+	case "", "json":
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	case "text":
+		logger.SetFormatter(&logrus.TextFormatter{DisableColors: true})
+	case "ecs":
+		logger.SetFormatter(&logging.ECSFormatter{})
+	default:
+		e.fail("LOG_FORMAT", errs.Newf(errs.Internal, "unknown format %q", format))
+	}
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		lvl, err := logrus.ParseLevel(level)
+		if err != nil {
+			e.fail("LOG_LEVEL", err)
+		}
+		logger.SetLevel(lvl)
+	}
+	runtimeinfo.InstallFields(logger)
+	args.Logger = logger
+
+	if e.err != nil {
+		return nil, e.err
+	}
+	if (args.TLSCertFile == "") != (args.TLSKeyFile == "") {
+		return nil, errs.New(errs.Internal, "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if caFile := os.Getenv("TLS_CLIENT_CA_FILE"); caFile != "" {
+		pool, err := LoadCertPool(caFile)
+		if err != nil {
+			return nil, errs.Errorf("invalid TLS_CLIENT_CA_FILE: %w", err)
+		}
+		args.ClientCert = &ClientCertConfig{ClientCAs: pool}
+	}
+	return args, nil
+}
+
+// envReader parses environment variables, keeping the first error
+type envReader struct {
+	err error
+}
+
+func (e *envReader) fail(name string, err error) {
+	if e.err == nil {
+		e.err = errs.Errorf("invalid %s: %w", name, err)
+	}
+}
+
+func (e *envReader) int(name string, def int) int {
+	v, ok := os.LookupEnv(name)
+	if !ok || v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		e.fail(name, err)
+		return def
+	}
+	return n
+}
+
+func (e *envReader) duration(name string) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		e.fail(name, err)
+		return 0
+	}
+	return d
+}
+
+func (e *envReader) bool(name string) bool {
+	v := os.Getenv(name)
+	if v == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		e.fail(name, err)
+	}
+	return b
+}
@@ -0,0 +1,168 @@
+package httpserver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETagConfig configures the ETag middleware
+type ETagConfig struct {
+	// Weak generates weak validators (W/"...") instead of strong ones
+	Weak bool
+	// MaxBodyBytes is the largest response buffered to compute an ETag
+	// (default: 1MB); larger and flushed responses are sent unmodified
+	MaxBodyBytes int
+}
+
+// ETag returns middleware for conditional GET and HEAD requests. It hashes 200
+// responses into an ETag unless the handler sets one, and answers 304 Not
+// Modified when If-None-Match matches, or when there is no If-None-Match and
+// If-Modified-Since is not before the handler's Last-Modified header.
+func ETag(cfg *ETagConfig) func(http.Handler) http.Handler {
+	c := ETagConfig{}
+	if cfg != nil {
+		c = *cfg
+	}
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = 1 << 20
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ew := &etagWriter{ResponseWriter: w, max: c.MaxBodyBytes}
+			next.ServeHTTP(ew, r)
+			if ew.passthrough {
+				return
+			}
+
+			status := ew.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			h := w.Header()
+			if status == http.StatusOK {
+				tag := h.Get("ETag")
+				if tag == "" && ew.buf.Len() > 0 {
+					sum := sha256.Sum256(ew.buf.Bytes())
+					tag = `"` + hex.EncodeToString(sum[:16]) + `"`
+					if c.Weak {
+						tag = "W/" + tag
+					}
+					h.Set("ETag", tag)
+				}
+				if notModified(r, tag, h.Get("Last-Modified")) {
+					h.Del("Content-Type")
+					h.Del("Content-Length")
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+			}
+
+			w.WriteHeader(status)
+			_, _ = w.Write(ew.buf.Bytes())
+		})
+	}
+}
+
+// notModified evaluates If-None-Match, falling back to If-Modified-Since
+func notModified(r *http.Request, etag, lastModified string) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if etag == "" {
+			return false
+		}
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || weakETagMatch(candidate, etag) {
+				return true
+			}
+		}
+		return false
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified == "" {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// weakETagMatch compares ETags ignoring the weak prefix, as If-None-Match requires
+func weakETagMatch(a, b string) bool {
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
+
+// etagWriter buffers a response until it completes, or stops buffering when
+// the response grows past max or is flushed
+type etagWriter struct {
+	http.ResponseWriter
+	max         int
+	status      int
+	buf         bytes.Buffer
+	passthrough bool
+}
+
+func (ew *etagWriter) WriteHeader(status int) {
+	if ew.passthrough {
+		ew.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if status < 200 {
+		ew.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if ew.status == 0 {
+		ew.status = status
+	}
+}
+
+func (ew *etagWriter) Write(b []byte) (int, error) {
+	if ew.passthrough {
+		return ew.ResponseWriter.Write(b)
+	}
+	if ew.buf.Len()+len(b) > ew.max {
+		if err := ew.startPassthrough(); err != nil {
+			return 0, err
+		}
+		return ew.ResponseWriter.Write(b)
+	}
+	return ew.buf.Write(b)
+}
+
+func (ew *etagWriter) startPassthrough() error {
+	ew.passthrough = true
+	if ew.status == 0 {
+		ew.status = http.StatusOK
+	}
+	ew.ResponseWriter.WriteHeader(ew.status)
+	_, err := ew.ResponseWriter.Write(ew.buf.Bytes())
+	ew.buf.Reset()
+	return err
+}
+
+func (ew *etagWriter) Flush() {
+	if !ew.passthrough {
+		_ = ew.startPassthrough()
+	}
+	_ = http.NewResponseController(ew.ResponseWriter).Flush()
+}
+
+func (ew *etagWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
@@ -0,0 +1,77 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/health"
+)
+
+// HealthCheckFunc reports a component as unhealthy by returning an error
+type HealthCheckFunc func(ctx context.Context) error
+
+// HealthCheckResult is the outcome of a single named check
+type HealthCheckResult = health.Result
+
+// HealthResponse is the JSON body served by /healthz and /readyz
+type HealthResponse = health.Report
+
+// healthStatusDraining is reported by /readyz during a drain
+const healthStatusDraining = "draining"
+
+// healthRegistry holds the server's checks in pkg/health registries. The
+// readiness registry is the shared HealthRegistry when one is set, so checks
+// added with AddReadinessCheck are also served by grpcserver and Check calls.
+type healthRegistry struct {
+	liveness  *health.Registry
+	readiness *health.Registry
+}
+
+func newHealthRegistry(timeout time.Duration, shared *health.Registry) *healthRegistry {
+	h := &healthRegistry{
+		liveness:  health.NewRegistry(&health.Options{Timeout: timeout}),
+		readiness: shared,
+	}
+	if h.readiness == nil {
+		h.readiness = health.NewRegistry(&health.Options{Timeout: timeout})
+	}
+	return h
+}
+
+// AddLivenessCheck registers a check served by /healthz. Liveness checks should
+// only fail when the process is broken and needs a restart.
+func (s *EasyGoHTTPServer) AddLivenessCheck(name string, fn HealthCheckFunc) {
+	s.health.liveness.Register(name, health.CheckFunc(fn), nil)
+}
+
+// AddReadinessCheck registers a check served by /readyz. Readiness checks fail
+// while the server should not receive traffic, e.g. a dependency is unreachable.
+// With a HealthRegistry the check is registered there; register on it directly
+// to set health.CheckOptions.
+func (s *EasyGoHTTPServer) AddReadinessCheck(name string, fn HealthCheckFunc) {
+	s.health.readiness.Register(name, health.CheckFunc(fn), nil)
+}
+
+// LivenessHandler serves the aggregated liveness status
+func (s *EasyGoHTTPServer) LivenessHandler() http.Handler {
+	return s.health.liveness.Handler()
+}
+
+// ReadinessHandler serves the aggregated readiness status. It fails without
+// running checks once the server is draining or the HealthRegistry is
+// shutting down.
+func (s *EasyGoHTTPServer) ReadinessHandler() http.Handler {
+	checks := s.health.readiness.Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Draining() {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(&HealthResponse{Status: healthStatusDraining})
+			return
+		}
+		checks.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,52 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/health"
+)
+
+func TestReadinessSharedRegistry(t *testing.T) {
+	shared := health.NewRegistry(nil)
+	calls := 0
+	shared.Register("cached", func(context.Context) error {
+		calls++
+		return nil
+	}, &health.CheckOptions{Interval: time.Hour})
+	s := NewEasyGoHTTPServer(&NewEasyGoHTTPServerArgs{HealthRegistry: shared})
+	s.AddReadinessCheck("db", func(context.Context) error { return errors.New("down") })
+
+	// checks added to the server are visible to other users of the registry
+	if err := shared.Err(context.Background()); err == nil {
+		t.Fatal("the server's readiness check is not in the shared registry")
+	}
+
+	ready := func() (int, *HealthResponse) {
+		w := httptest.NewRecorder()
+		s.ReadinessHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var resp HealthResponse
+		_ = json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, &resp
+	}
+	if code, resp := ready(); code != http.StatusServiceUnavailable || resp.Checks["db"].Status != health.StatusFail || resp.Checks["cached"].Status != health.StatusOK {
+		t.Fatalf("got %d %+v", code, resp)
+	}
+	if calls != 1 {
+		t.Fatalf("the cached check ran %d times, want the interval honored", calls)
+	}
+
+	shared.Unregister("db")
+	if code, _ := ready(); code != http.StatusOK {
+		t.Fatalf("got %d", code)
+	}
+	shared.Shutdown()
+	if code, resp := ready(); code != http.StatusServiceUnavailable || resp.Status != health.StatusShuttingDown {
+		t.Fatalf("got %d %+v while the registry is shutting down", code, resp)
+	}
+}
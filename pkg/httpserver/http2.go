@@ -0,0 +1,23 @@
+package httpserver
+
+import "net/http"
+
+const defaultHTTP2MaxConcurrentStreams = 250
+
+// configureHTTP2 sets the protocols and HTTP/2 parameters on server from args
+func configureHTTP2(server *http.Server, args *NewEasyGoHTTPServerArgs) {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(!args.DisableHTTP2)
+	protocols.SetUnencryptedHTTP2(args.EnableH2C)
+	server.Protocols = protocols
+
+	cfg := &http.HTTP2Config{}
+	if args.HTTP2 != nil {
+		*cfg = *args.HTTP2
+	}
+	if cfg.MaxConcurrentStreams == 0 {
+		cfg.MaxConcurrentStreams = defaultHTTP2MaxConcurrentStreams
+	}
+	server.HTTP2 = cfg
+}
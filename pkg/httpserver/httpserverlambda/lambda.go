@@ -0,0 +1,388 @@
+// Package httpserverlambda serves an EasyGoHTTPServer's router from AWS
+// Lambda, so the same application runs as a container or a function. It
+// accepts API Gateway REST (and HTTP API payload format 1.0), HTTP API payload
+// format 2.0 and ALB target group events, converting each to an
+// *http.Request and the handler's response back to the event's format.
+package httpserverlambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"maps"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/httpserver"
+)
+
+type Options struct {
+	// StripPrefix is removed from request paths, e.g. a REST API stage or a
+	// custom domain base path mapping
+	StripPrefix string
+	// DeadlineMargin ends request contexts this long before the invocation
+	// deadline, leaving time to return the response (default: 500ms)
+	DeadlineMargin time.Duration
+	// RequestIDHeader is set from the event's request ID when the request has
+	// none (default: httpserver.DefaultRequestIDHeader)
+	RequestIDHeader string
+}
+
+// Handler is a lambda.Handler serving HTTP events with an http.Handler
+type Handler struct {
+	handler http.Handler
+	opts    Options
+}
+
+var _ lambda.Handler = (*Handler)(nil)
+
+// New creates a Handler for h, e.g. EasyGoHTTPServer.GetHttpServer().Handler
+func New(h http.Handler, opts *Options) *Handler {
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.DeadlineMargin <= 0 {
+		o.DeadlineMargin = 500 * time.Millisecond
+	}
+	if o.RequestIDHeader == "" {
+		o.RequestIDHeader = httpserver.DefaultRequestIDHeader
+	}
+	o.StripPrefix = strings.TrimRight(o.StripPrefix, "/")
+	return &Handler{handler: h, opts: o}
+}
+
+// Start runs s's OnStart hooks and serves Lambda invocations with its full
+// middleware stack, running its OnShutdown hooks when Lambda sends SIGTERM.
+// It only returns when a start hook fails.
+func Start(s *httpserver.EasyGoHTTPServer, opts *Options) error {
+	if err := s.RunStartHooks(context.Background()); err != nil {
+		return err
+	}
+	h := New(s.GetHttpServer().Handler, opts)
+	lambda.StartWithOptions(h, lambda.WithEnableSIGTERM(func() {
+		// Lambda allows 500ms after SIGTERM
+		ctx, cancel := context.WithTimeout(context.Background(), 450*time.Millisecond)
+		defer cancel()
+		_ = s.Shutdown(ctx)
+	}))
+	return nil
+}
+
+type eventKey struct{}
+
+// EventFromContext returns the event a request was built from: an
+// *events.APIGatewayProxyRequest, *events.APIGatewayV2HTTPRequest or
+// *events.ALBTargetGroupRequest, e.g. to read authorizer claims
+func EventFromContext(ctx context.Context) (any, bool) {
+	ev := ctx.Value(eventKey{})
+	return ev, ev != nil
+}
+
+// probe identifies the event type
+type probe struct {
+	Version        string `json:"version"`
+	HTTPMethod     string `json:"httpMethod"`
+	RequestContext struct {
+		ELB json.RawMessage `json:"elb"`
+	} `json:"requestContext"`
+}
+
+// Invoke serves one event
+func (h *Handler) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	var p probe
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, errs.Errorf("failed to decode event: %w", err)
+	}
+	switch {
+	case len(p.RequestContext.ELB) > 0:
+		ev := &events.ALBTargetGroupRequest{}
+		if err := json.Unmarshal(payload, ev); err != nil {
+			return nil, errs.Errorf("failed to decode ALB event: %w", err)
+		}
+		return h.serveALB(ctx, ev)
+	case p.Version == "2.0":
+		ev := &events.APIGatewayV2HTTPRequest{}
+		if err := json.Unmarshal(payload, ev); err != nil {
+			return nil, errs.Errorf("failed to decode API Gateway v2 event: %w", err)
+		}
+		return h.serveV2(ctx, ev)
+	case p.HTTPMethod != "":
+		ev := &events.APIGatewayProxyRequest{}
+		if err := json.Unmarshal(payload, ev); err != nil {
+			return nil, errs.Errorf("failed to decode API Gateway event: %w", err)
+		}
+		return h.serveREST(ctx, ev)
+	}
+	return nil, errs.New(errs.Internal, "unsupported event: not an API Gateway or ALB request")
+}
+
+func (h *Handler) serveREST(ctx context.Context, ev *events.APIGatewayProxyRequest) ([]byte, error) {
+	header := multiHeader(ev.Headers, ev.MultiValueHeaders)
+	query := url.Values{}
+	for k, v := range ev.QueryStringParameters {
+		query.Set(k, v)
+	}
+	for k, v := range ev.MultiValueQueryStringParameters {
+		query[k] = v
+	}
+	w, err := h.serve(ctx, ev, &request{
+		method:    ev.HTTPMethod,
+		path:      ev.Path,
+		rawQuery:  query.Encode(),
+		header:    header,
+		body:      ev.Body,
+		base64:    ev.IsBase64Encoded,
+		sourceIP:  ev.RequestContext.Identity.SourceIP,
+		host:      ev.RequestContext.DomainName,
+		requestID: ev.RequestContext.RequestID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	body, isBase64 := w.encodedBody()
+	return json.Marshal(events.APIGatewayProxyResponse{
+		StatusCode:        w.status,
+		MultiValueHeaders: w.header,
+		Body:              body,
+		IsBase64Encoded:   isBase64,
+	})
+}
+
+func (h *Handler) serveV2(ctx context.Context, ev *events.APIGatewayV2HTTPRequest) ([]byte, error) {
+	header := multiHeader(ev.Headers, nil)
+	if len(ev.Cookies) > 0 {
+		header.Set("Cookie", strings.Join(ev.Cookies, "; "))
+	}
+	w, err := h.serve(ctx, ev, &request{
+		method:    ev.RequestContext.HTTP.Method,
+		path:      ev.RawPath,
+		rawQuery:  ev.RawQueryString,
+		header:    header,
+		body:      ev.Body,
+		base64:    ev.IsBase64Encoded,
+		sourceIP:  ev.RequestContext.HTTP.SourceIP,
+		host:      ev.RequestContext.DomainName,
+		requestID: ev.RequestContext.RequestID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	body, isBase64 := w.encodedBody()
+	resp := events.APIGatewayV2HTTPResponse{
+		StatusCode:      w.status,
+		Headers:         map[string]string{},
+		Body:            body,
+		IsBase64Encoded: isBase64,
+		Cookies:         w.header.Values("Set-Cookie"),
+	}
+	for k, v := range w.header {
+		if k != "Set-Cookie" {
+			resp.Headers[k] = strings.Join(v, ",")
+		}
+	}
+	return json.Marshal(resp)
+}
+
+func (h *Handler) serveALB(ctx context.Context, ev *events.ALBTargetGroupRequest) ([]byte, error) {
+	multi := ev.MultiValueHeaders != nil
+	header := multiHeader(ev.Headers, ev.MultiValueHeaders)
+	// ALB passes query strings as the client sent them, still encoded
+	var pairs []string
+	if ev.MultiValueQueryStringParameters != nil {
+		for _, k := range slices.Sorted(maps.Keys(ev.MultiValueQueryStringParameters)) {
+			for _, v := range ev.MultiValueQueryStringParameters[k] {
+				pairs = append(pairs, k+"="+v)
+			}
+		}
+	} else {
+		for _, k := range slices.Sorted(maps.Keys(ev.QueryStringParameters)) {
+			pairs = append(pairs, k+"="+ev.QueryStringParameters[k])
+		}
+	}
+	w, err := h.serve(ctx, ev, &request{
+		method:   ev.HTTPMethod,
+		path:     ev.Path,
+		rawQuery: strings.Join(pairs, "&"),
+		header:   header,
+		body:     ev.Body,
+		base64:   ev.IsBase64Encoded,
+		sourceIP: firstForwardedFor(header),
+	})
+	if err != nil {
+		return nil, err
+	}
+	body, isBase64 := w.encodedBody()
+	resp := events.ALBTargetGroupResponse{
+		StatusCode:        w.status,
+		StatusDescription: strconv.Itoa(w.status) + " " + http.StatusText(w.status),
+		Body:              body,
+		IsBase64Encoded:   isBase64,
+	}
+	// the response must use the same header format as the request
+	if multi {
+		resp.MultiValueHeaders = w.header
+	} else {
+		resp.Headers = map[string]string{}
+		for k, v := range w.header {
+			resp.Headers[k] = v[len(v)-1]
+		}
+	}
+	return json.Marshal(resp)
+}
+
+// request is the part of an event needed to build an *http.Request
+type request struct {
+	method, path, rawQuery string
+	header                 http.Header
+	body                   string
+	base64                 bool
+	sourceIP, host         string
+	requestID              string
+}
+
+func (h *Handler) serve(ctx context.Context, ev any, in *request) (*responseWriter, error) {
+	body := []byte(in.body)
+	if in.base64 {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(in.body); err != nil {
+			return nil, errs.Errorf("failed to decode base64 request body: %w", err)
+		}
+	}
+
+	path := in.path
+	if h.opts.StripPrefix != "" {
+		if rest, ok := strings.CutPrefix(path, h.opts.StripPrefix); ok && (rest == "" || rest[0] == '/') {
+			path = rest
+		}
+	}
+	if path == "" {
+		path = "/"
+	}
+	target := path
+	if in.rawQuery != "" {
+		target += "?" + in.rawQuery
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline.Add(-h.opts.DeadlineMargin))
+		defer cancel()
+	}
+	ctx = context.WithValue(ctx, eventKey{}, ev)
+
+	r, err := http.NewRequestWithContext(ctx, in.method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, errs.Errorf("invalid request %s %s: %w", in.method, target, err)
+	}
+	r.RequestURI = target
+	r.Header = in.header
+	r.Host = in.header.Get("Host")
+	if r.Host == "" {
+		r.Host = in.host
+	}
+	if in.sourceIP != "" {
+		r.RemoteAddr = net.JoinHostPort(in.sourceIP, "0")
+	}
+	requestID := in.requestID
+	if lc, ok := lambdacontext.FromContext(ctx); ok && requestID == "" {
+		requestID = lc.AwsRequestID
+	}
+	if requestID != "" && r.Header.Get(h.opts.RequestIDHeader) == "" {
+		r.Header.Set(h.opts.RequestIDHeader, requestID)
+	}
+
+	w := &responseWriter{header: http.Header{}}
+	h.handler.ServeHTTP(w, r)
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w, nil
+}
+
+// multiHeader merges single and multi value event headers
+func multiHeader(single map[string]string, multi map[string][]string) http.Header {
+	h := make(http.Header, len(single)+len(multi))
+	for k, v := range single {
+		h.Set(k, v)
+	}
+	for k, vs := range multi {
+		k = http.CanonicalHeaderKey(k)
+		h[k] = append([]string(nil), vs...)
+	}
+	return h
+}
+
+func firstForwardedFor(h http.Header) string {
+	ip, _, _ := strings.Cut(h.Get("X-Forwarded-For"), ",")
+	return strings.TrimSpace(ip)
+}
+
+// responseWriter buffers the handler's response
+type responseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 && status >= 200 {
+		w.status = status
+	}
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.header.Get("Content-Type") == "" && w.body.Len() == 0 && len(b) > 0 {
+		w.header.Set("Content-Type", http.DetectContentType(b))
+	}
+	return w.body.Write(b)
+}
+
+// Flush is a no-op; Lambda responses are sent when the handler returns
+func (w *responseWriter) Flush() {}
+
+// encodedBody returns the body, base64 encoded unless it is uncompressed text
+func (w *responseWriter) encodedBody() (string, bool) {
+	b := w.body.Bytes()
+	if len(b) == 0 {
+		return "", false
+	}
+	if w.header.Get("Content-Encoding") == "" && isText(w.header.Get("Content-Type")) && utf8.Valid(b) {
+		return string(b), false
+	}
+	return base64.StdEncoding.EncodeToString(b), true
+}
+
+func isText(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mt, "text/"),
+		strings.HasSuffix(mt, "+json"), strings.HasSuffix(mt, "+xml"),
+		mt == "application/json", mt == "application/xml", mt == "application/javascript",
+		mt == "application/x-www-form-urlencoded":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package httpserverlambda
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/bdlilley/easygo/pkg/httpserver"
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestHandler() *Handler {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	s := httpserver.NewEasyGoHTTPServer(&httpserver.NewEasyGoHTTPServerArgs{Logger: logger})
+	s.Chi.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, remaining := r.Context().Deadline()
+		http.SetCookie(w, &http.Cookie{Name: "a", Value: "1"})
+		http.SetCookie(w, &http.Cookie{Name: "b", Value: "2"})
+		httpserver.JSON(w, http.StatusOK, map[string]any{
+			"id":         chi.URLParam(r, "id"),
+			"q":          r.URL.Query()["q"],
+			"request_id": httpserver.RequestIDFromContext(r.Context()),
+			"ip":         httpserver.ClientIP(r),
+			"deadline":   remaining,
+		})
+	})
+	s.Chi.Post("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(body)
+	})
+	return New(s.GetHttpServer().Handler, &Options{StripPrefix: "/prod"})
+}
+
+func invoke[T any](t *testing.T, h *Handler, ctx context.Context, event any) T {
+	t.Helper()
+	payload, _ := json.Marshal(event)
+	out, err := h.Invoke(ctx, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp T
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestREST(t *testing.T) {
+	h := newTestHandler()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	resp := invoke[events.APIGatewayProxyResponse](t, h, ctx, events.APIGatewayProxyRequest{
+		HTTPMethod:                      http.MethodGet,
+		Path:                            "/prod/users/42",
+		MultiValueQueryStringParameters: map[string][]string{"q": {"a b", "c"}},
+		RequestContext: events.APIGatewayProxyRequestContext{
+			RequestID: "apigw-1",
+			Identity:  events.APIGatewayRequestIdentity{SourceIP: "203.0.113.9"},
+		},
+	})
+	var body map[string]any
+	if err := json.Unmarshal([]byte(resp.Body), &body); err != nil || resp.StatusCode != http.StatusOK || resp.IsBase64Encoded {
+		t.Fatalf("got %+v", resp)
+	}
+	if body["id"] != "42" || len(body["q"].([]any)) != 2 || body["request_id"] != "apigw-1" || body["ip"] != "203.0.113.9" || body["deadline"] != true {
+		t.Fatalf("got %v", body)
+	}
+	if got := resp.MultiValueHeaders["Set-Cookie"]; len(got) != 2 {
+		t.Fatalf("got cookies %v", got)
+	}
+}
+
+func TestV2Binary(t *testing.T) {
+	h := newTestHandler()
+	data := []byte{0xff, 0x00, 0x10}
+	resp := invoke[events.APIGatewayV2HTTPResponse](t, h, context.Background(), events.APIGatewayV2HTTPRequest{
+		Version:         "2.0",
+		RawPath:         "/echo",
+		Body:            base64.StdEncoding.EncodeToString(data),
+		IsBase64Encoded: true,
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodPost},
+		},
+	})
+	got, _ := base64.StdEncoding.DecodeString(resp.Body)
+	if resp.StatusCode != http.StatusOK || !resp.IsBase64Encoded || string(got) != string(data) {
+		t.Fatalf("got %+v", resp)
+	}
+
+	resp = invoke[events.APIGatewayV2HTTPResponse](t, h, context.Background(), events.APIGatewayV2HTTPRequest{
+		Version:        "2.0",
+		RawPath:        "/users/7",
+		RawQueryString: "q=x",
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodGet},
+		},
+	})
+	if resp.StatusCode != http.StatusOK || len(resp.Cookies) != 2 || resp.Headers["Content-Type"] == "" {
+		t.Fatalf("got %+v", resp)
+	}
+}
+
+func TestALB(t *testing.T) {
+	h := newTestHandler()
+	resp := invoke[events.ALBTargetGroupResponse](t, h, context.Background(), events.ALBTargetGroupRequest{
+		HTTPMethod:            http.MethodGet,
+		Path:                  "/missing",
+		QueryStringParameters: map[string]string{"q": "a%20b"},
+		Headers:               map[string]string{"x-forwarded-for": "198.51.100.1"},
+		RequestContext:        events.ALBTargetGroupRequestContext{ELB: events.ELBContext{TargetGroupArn: "arn"}},
+	})
+	if resp.StatusCode != http.StatusNotFound || resp.StatusDescription != "404 Not Found" || resp.Headers == nil || resp.MultiValueHeaders != nil {
+		t.Fatalf("got %+v", resp)
+	}
+
+	if _, err := h.Invoke(context.Background(), []byte(`{"detail-type":"Scheduled Event"}`)); err == nil {
+		t.Fatal("expected an error for a non-HTTP event")
+	}
+}
@@ -0,0 +1,245 @@
+// Package httpservertest runs an EasyGoHTTPServer on an httptest.Server, so
+// handler tests go through the real middleware stack (request IDs, logging,
+// recovery, body limits, problem responses) rather than a bare router.
+package httpservertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bdlilley/easygo/pkg/httpserver"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// Server is an EasyGoHTTPServer listening on a local httptest.Server
+type Server struct {
+	*httptest.Server
+	// HTTP is the server under test; register routes on HTTP.Chi
+	HTTP *httpserver.EasyGoHTTPServer
+	// Admin serves the admin router when args.Admin is set, otherwise nil
+	Admin *httptest.Server
+	// Logs records everything the server logs, e.g. access log entries
+	Logs *test.Hook
+
+	t testing.TB
+}
+
+// NewTestServer creates the server from args and starts it; it is closed when
+// the test ends. Port, Listener and TLS settings in args are ignored. When
+// args.Logger is nil, logs are discarded apart from being recorded in Logs.
+func NewTestServer(t testing.TB, args *httpserver.NewEasyGoHTTPServerArgs, opts ...httpserver.Option) *Server {
+	t.Helper()
+	a := httpserver.NewEasyGoHTTPServerArgs{}
+	if args != nil {
+		a = *args
+	}
+	if a.Logger == nil {
+		a.Logger = logrus.New()
+		a.Logger.SetOutput(io.Discard)
+	}
+	logs := test.NewLocal(a.Logger)
+
+	s := &Server{HTTP: httpserver.NewEasyGoHTTPServer(&a, opts...), Logs: logs, t: t}
+	s.Server = httptest.NewServer(s.HTTP.GetHttpServer().Handler)
+	t.Cleanup(s.Server.Close)
+	if admin := s.HTTP.AdminRouter(); admin != nil {
+		s.Admin = httptest.NewServer(admin)
+		t.Cleanup(s.Admin.Close)
+	}
+	return s
+}
+
+// Request starts building a request to path on the application listener
+func (s *Server) Request(method, path string) *RequestBuilder {
+	return newRequest(s.t, s.Client(), method, s.URL+path)
+}
+
+// AdminRequest starts building a request to path on the admin listener
+func (s *Server) AdminRequest(method, path string) *RequestBuilder {
+	if s.Admin == nil {
+		s.t.Fatal("httpservertest: the server has no admin listener")
+	}
+	return newRequest(s.t, s.Admin.Client(), method, s.Admin.URL+path)
+}
+
+// Get is Request(http.MethodGet, path).Do()
+func (s *Server) Get(path string) *Response {
+	return s.Request(http.MethodGet, path).Do()
+}
+
+// RequestBuilder builds and sends one request; errors fail the test when the
+// request is sent
+type RequestBuilder struct {
+	t      testing.TB
+	client *http.Client
+	req    *http.Request
+	query  url.Values
+	err    error
+}
+
+func newRequest(t testing.TB, client *http.Client, method, target string) *RequestBuilder {
+	req, err := http.NewRequest(method, target, nil)
+	b := &RequestBuilder{t: t, client: client, req: req, err: err}
+	if err == nil {
+		b.query = req.URL.Query()
+	}
+	return b
+}
+
+// Header sets a request header
+func (b *RequestBuilder) Header(key, value string) *RequestBuilder {
+	if b.err == nil {
+		b.req.Header.Set(key, value)
+	}
+	return b
+}
+
+// Query adds a query parameter
+func (b *RequestBuilder) Query(key, value string) *RequestBuilder {
+	if b.err == nil {
+		b.query.Add(key, value)
+	}
+	return b
+}
+
+// BearerToken sets the Authorization header
+func (b *RequestBuilder) BearerToken(token string) *RequestBuilder {
+	return b.Header("Authorization", "Bearer "+token)
+}
+
+// Body sets the request body and its content type
+func (b *RequestBuilder) Body(body []byte, contentType string) *RequestBuilder {
+	if b.err == nil {
+		b.req.Body = io.NopCloser(bytes.NewReader(body))
+		b.req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+		b.req.ContentLength = int64(len(body))
+		b.req.Header.Set("Content-Type", contentType)
+	}
+	return b
+}
+
+// JSON marshals v as the request body
+func (b *RequestBuilder) JSON(v any) *RequestBuilder {
+	body, err := json.Marshal(v)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	return b.Body(body, "application/json")
+}
+
+// Do sends the request and reads the whole response
+func (b *RequestBuilder) Do() *Response {
+	b.t.Helper()
+	if b.err != nil {
+		b.t.Fatalf("httpservertest: failed to build request: %v", b.err)
+	}
+	b.req.URL.RawQuery = b.query.Encode()
+	resp, err := b.client.Do(b.req)
+	if err != nil {
+		b.t.Fatalf("httpservertest: %s %s: %v", b.req.Method, b.req.URL, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		b.t.Fatalf("httpservertest: failed to read response body: %v", err)
+	}
+	return &Response{Response: resp, Body: body, t: b.t}
+}
+
+// Response is a received response with its body read. The Expect methods
+// report mismatches with t.Errorf and return the response for chaining.
+type Response struct {
+	*http.Response
+	Body []byte
+
+	t testing.TB
+}
+
+// ExpectStatus checks the status code
+func (r *Response) ExpectStatus(status int) *Response {
+	r.t.Helper()
+	if r.StatusCode != status {
+		r.t.Errorf("%s %s: got status %d, want %d; body: %s", r.Request.Method, r.Request.URL.Path, r.StatusCode, status, r.Body)
+	}
+	return r
+}
+
+// ExpectHeader checks a response header
+func (r *Response) ExpectHeader(key, value string) *Response {
+	r.t.Helper()
+	if got := r.Header.Get(key); got != value {
+		r.t.Errorf("%s %s: got header %s %q, want %q", r.Request.Method, r.Request.URL.Path, key, got, value)
+	}
+	return r
+}
+
+// ExpectJSON checks that the body is JSON equal to want, which may be a
+// string of JSON or a value to marshal; key order and whitespace are ignored
+func (r *Response) ExpectJSON(want any) *Response {
+	r.t.Helper()
+	wantJSON, ok := want.(string)
+	if !ok {
+		b, err := json.Marshal(want)
+		if err != nil {
+			r.t.Fatalf("httpservertest: failed to marshal expected JSON: %v", err)
+		}
+		wantJSON = string(b)
+	}
+	var got, exp any
+	if err := json.Unmarshal([]byte(wantJSON), &exp); err != nil {
+		r.t.Fatalf("httpservertest: invalid expected JSON: %v", err)
+	}
+	if err := json.Unmarshal(r.Body, &got); err != nil {
+		r.t.Errorf("%s %s: body is not JSON: %v; body: %s", r.Request.Method, r.Request.URL.Path, err, r.Body)
+		return r
+	}
+	if !reflect.DeepEqual(got, exp) {
+		r.t.Errorf("%s %s: got JSON\n%s\nwant\n%s", r.Request.Method, r.Request.URL.Path, indent(r.Body), indent([]byte(wantJSON)))
+	}
+	return r
+}
+
+// ExpectProblem checks for a problem response with status and, when code is
+// not empty, the problem code
+func (r *Response) ExpectProblem(status int, code string) *Response {
+	r.t.Helper()
+	r.ExpectStatus(status)
+	if ct := r.Header.Get("Content-Type"); !strings.HasPrefix(ct, httpserver.ProblemContentType) {
+		r.t.Errorf("%s %s: got Content-Type %q, want %s", r.Request.Method, r.Request.URL.Path, ct, httpserver.ProblemContentType)
+		return r
+	}
+	p := httpserver.Problem{}
+	if err := json.Unmarshal(r.Body, &p); err != nil {
+		r.t.Errorf("%s %s: invalid problem body: %v", r.Request.Method, r.Request.URL.Path, err)
+	} else if code != "" && p.Code != code {
+		r.t.Errorf("%s %s: got problem code %q, want %q", r.Request.Method, r.Request.URL.Path, p.Code, code)
+	}
+	return r
+}
+
+// DecodeJSON unmarshals the body into v, failing the test on error
+func (r *Response) DecodeJSON(v any) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		r.t.Fatalf("%s %s: failed to decode JSON: %v; body: %s", r.Request.Method, r.Request.URL.Path, err, r.Body)
+	}
+	return r
+}
+
+// indent formats JSON for failure messages, falling back to the raw bytes
+func indent(b []byte) string {
+	var out bytes.Buffer
+	if err := json.Indent(&out, b, "", "  "); err != nil {
+		return string(b)
+	}
+	return out.String()
+}
@@ -0,0 +1,52 @@
+package httpservertest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/bdlilley/easygo/pkg/httpserver"
+)
+
+func TestServer(t *testing.T) {
+	s := NewTestServer(t, &httpserver.NewEasyGoHTTPServerArgs{Admin: &httpserver.AdminConfig{}})
+	s.HTTP.Chi.Post("/orders", s.HTTP.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		body, err := httpserver.Bind[struct {
+			Item string `json:"item"`
+		}](r)
+		if err != nil {
+			return err
+		}
+		httpserver.JSON(w, http.StatusCreated, map[string]any{"item": body.Item, "sort": r.URL.Query().Get("sort")})
+		return nil
+	}))
+	s.HTTP.Chi.Get("/panic", func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	})
+
+	s.Request(http.MethodPost, "/orders").
+		JSON(map[string]string{"item": "book"}).
+		Query("sort", "asc").
+		Header("X-Request-ID", "req-1").
+		Do().
+		ExpectStatus(http.StatusCreated).
+		ExpectHeader("X-Request-ID", "req-1").
+		ExpectJSON(`{"sort": "asc", "item": "book"}`)
+
+	var p httpserver.Problem
+	s.Get("/panic").ExpectProblem(http.StatusInternalServerError, "").DecodeJSON(&p)
+	if p.Instance != "/panic" {
+		t.Fatalf("got problem %+v", p)
+	}
+	s.AdminRequest(http.MethodGet, "/healthz").Do().ExpectStatus(http.StatusOK)
+	s.AdminRequest(http.MethodGet, "/version").Do().ExpectStatus(http.StatusOK).ExpectHeader("Content-Type", "application/json")
+
+	logged := false
+	for _, e := range s.Logs.AllEntries() {
+		if e.Data["path"] == "/orders" {
+			logged = true
+		}
+	}
+	if !logged {
+		t.Fatal("expected an access log entry for /orders")
+	}
+}
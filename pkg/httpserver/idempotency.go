@@ -0,0 +1,222 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/idempotency"
+)
+
+// DefaultIdempotencyHeader carries the client's idempotency key
+const DefaultIdempotencyHeader = "Idempotency-Key"
+
+// idempotencyUnstoredHeaders describe the request or connection that sent a
+// response rather than the response itself, so replays do not repeat them
+var idempotencyUnstoredHeaders = []string{"Date", "Set-Cookie", DefaultRequestIDHeader, "Traceparent", "Tracestate"}
+
+// IdempotencyConfig configures the Idempotency middleware
+type IdempotencyConfig struct {
+	// Store holds the responses, e.g. an idempotency.DynamoDBStore to
+	// deduplicate retries across replicas (default: an in-memory store local
+	// to this process)
+	Store idempotency.Store
+	// TTL is how long responses are replayed (default: 24h)
+	TTL time.Duration
+	// LockTTL is how long an in-progress request holds its key, after which
+	// it is assumed lost and a retry runs again (default: 1m)
+	LockTTL time.Duration
+	// Header carries the key (default: Idempotency-Key)
+	Header string
+	// Methods are the methods keys apply to (default: POST and PATCH)
+	Methods []string
+	// Required rejects requests with those methods that have no key
+	Required bool
+	// Scope namespaces keys, e.g. by the authenticated principal, so clients
+	// cannot replay each other's responses (default: none)
+	Scope func(r *http.Request) string
+	// MaxBodyBytes is the largest response stored (default: 1MB); larger and
+	// flushed responses are not stored and retries run again
+	MaxBodyBytes int
+}
+
+// Idempotency returns middleware that makes retries of a request with the
+// same idempotency key safe. The first request runs and its response is
+// stored; retries replay it with an Idempotent-Replayed: true header. A retry
+// while the first request is in progress gets 409, and reusing a key for a
+// different method, path, query or body gets 422. Responses with 5xx and 429
+// statuses are not stored, so those retries run again. Store errors fail
+// closed with 503. Replays keep the headers already set on the retry's
+// response, such as its request ID, and never repeat Set-Cookie or Date.
+func Idempotency(cfg *IdempotencyConfig) func(http.Handler) http.Handler {
+	c := IdempotencyConfig{}
+	if cfg != nil {
+		c = *cfg
+	}
+	if c.Store == nil {
+		c.Store = idempotency.NewMemoryStore()
+	}
+	if c.TTL <= 0 {
+		c.TTL = 24 * time.Hour
+	}
+	if c.LockTTL <= 0 {
+		c.LockTTL = time.Minute
+	}
+	if c.Header == "" {
+		c.Header = DefaultIdempotencyHeader
+	}
+	if len(c.Methods) == 0 {
+		c.Methods = []string{http.MethodPost, http.MethodPatch}
+	}
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = 1 << 20
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !slices.Contains(c.Methods, r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key := r.Header.Get(c.Header)
+			if key == "" {
+				if c.Required {
+					WriteProblem(w, r, NewProblem(http.StatusBadRequest, c.Header+" header is required").WithCode("idempotency_key_required"))
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > 255 {
+				WriteProblem(w, r, NewProblem(http.StatusBadRequest, c.Header+" is longer than 255 characters").WithCode("idempotency_key_invalid"))
+				return
+			}
+			if c.Scope != nil {
+				key = c.Scope(r) + "/" + key
+			}
+
+			fingerprint, err := requestFingerprint(r)
+			if err != nil {
+				WriteProblem(w, r, ProblemFromError(err))
+				return
+			}
+			rec, claimed, err := c.Store.Claim(r.Context(), key, fingerprint, c.LockTTL)
+			if err != nil {
+				writeProblem(w, r, http.StatusServiceUnavailable, "idempotency store unavailable")
+				return
+			}
+			if !claimed {
+				switch {
+				case rec.Fingerprint != fingerprint:
+					WriteProblem(w, r, NewProblem(http.StatusUnprocessableEntity, c.Header+" was used with a different request").WithCode("idempotency_key_reused"))
+				case rec.InProgress():
+					WriteProblem(w, r, NewProblem(http.StatusConflict, "a request with this "+c.Header+" is in progress").WithCode("idempotency_in_progress"))
+				default:
+					replay(w, rec)
+				}
+				return
+			}
+
+			// a detached context stores the outcome even if the client went away
+			ctx := context.WithoutCancel(r.Context())
+			iw := &idempotencyWriter{ResponseWriter: w, max: c.MaxBodyBytes}
+			stored := false
+			defer func() {
+				if !stored {
+					_ = c.Store.Release(ctx, key)
+				}
+			}()
+			next.ServeHTTP(iw, r)
+
+			status := iw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if iw.overflow || status >= 500 || status == http.StatusTooManyRequests {
+				return
+			}
+			header := w.Header().Clone()
+			for _, name := range idempotencyUnstoredHeaders {
+				header.Del(name)
+			}
+			err = c.Store.Complete(ctx, key, &idempotency.Record{Fingerprint: fingerprint, Status: status, Header: header, Body: iw.buf.Bytes()}, c.TTL)
+			stored = err == nil
+		})
+	}
+}
+
+// requestFingerprint hashes the parts of r a retry must repeat, restoring
+// the body for the handler
+func requestFingerprint(r *http.Request) (string, error) {
+	h := sha256.New()
+	_, _ = io.WriteString(h, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery+"\n")
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return "", err
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		h.Write(body)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func replay(w http.ResponseWriter, rec *idempotency.Record) {
+	h := w.Header()
+	for k, v := range rec.Header {
+		if _, ok := h[k]; !ok {
+			h[k] = v
+		}
+	}
+	h.Set("Idempotent-Replayed", "true")
+	w.WriteHeader(rec.Status)
+	_, _ = w.Write(rec.Body)
+}
+
+// idempotencyWriter copies the response while passing it through, until it
+// grows past max or is flushed
+type idempotencyWriter struct {
+	http.ResponseWriter
+	max      int
+	status   int
+	buf      bytes.Buffer
+	overflow bool
+}
+
+func (iw *idempotencyWriter) WriteHeader(status int) {
+	if iw.status == 0 && status >= 200 {
+		iw.status = status
+	}
+	iw.ResponseWriter.WriteHeader(status)
+}
+
+func (iw *idempotencyWriter) Write(b []byte) (int, error) {
+	if iw.status == 0 {
+		iw.status = http.StatusOK
+	}
+	if !iw.overflow {
+		if iw.buf.Len()+len(b) > iw.max {
+			iw.overflow = true
+			iw.buf.Reset()
+		} else {
+			iw.buf.Write(b)
+		}
+	}
+	return iw.ResponseWriter.Write(b)
+}
+
+func (iw *idempotencyWriter) Flush() {
+	iw.overflow = true
+	iw.buf.Reset()
+	_ = http.NewResponseController(iw.ResponseWriter).Flush()
+}
+
+func (iw *idempotencyWriter) Unwrap() http.ResponseWriter {
+	return iw.ResponseWriter
+}
@@ -0,0 +1,87 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIdempotency(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	handler := Idempotency(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("X-Charge", strconv.Itoa(int(n)))
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: strconv.Itoa(int(n))})
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"charge":` + strconv.Itoa(int(n)) + `}`))
+	}))
+	requests := 0
+	do := func(path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(DefaultIdempotencyHeader, key)
+		}
+		// as set by the request ID middleware before the handler runs
+		requests++
+		w := httptest.NewRecorder()
+		w.Header().Set(DefaultRequestIDHeader, "req-"+strconv.Itoa(requests))
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	first := do("/charges", "k1", `{"amount":5}`)
+	retry := do("/charges", "k1", `{"amount":5}`)
+	if first.Code != http.StatusCreated || retry.Code != http.StatusCreated || retry.Body.String() != `{"charge":1}` ||
+		retry.Header().Get("X-Charge") != "1" || retry.Header().Get("Idempotent-Replayed") != "true" || calls.Load() != 1 {
+		t.Fatalf("got %d %s then %d %s %v after %d calls", first.Code, first.Body, retry.Code, retry.Body, retry.Header(), calls.Load())
+	}
+	// the replay keeps its own request ID and does not repeat the cookie
+	if id := retry.Header().Get(DefaultRequestIDHeader); id != "req-2" {
+		t.Fatalf("replayed request ID %q, want req-2", id)
+	}
+	if cookie := retry.Header().Get("Set-Cookie"); cookie != "" {
+		t.Fatalf("replayed Set-Cookie %q", cookie)
+	}
+	if w := do("/charges", "k1", `{"amount":6}`); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("got %d for a reused key", w.Code)
+	}
+	if w := do("/charges", "", `{}`); w.Code != http.StatusCreated || calls.Load() != 2 {
+		t.Fatalf("got %d without a key", w.Code)
+	}
+
+	// server errors are not stored, so the retry runs again
+	do("/fail", "k2", "")
+	do("/fail", "k2", "")
+	if calls.Load() != 4 {
+		t.Fatalf("got %d calls, want failed requests to be retried", calls.Load())
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		do("/slow", "k3", "")
+	}()
+	// wait for the first request to claim the key
+	for calls.Load() != 5 {
+		time.Sleep(time.Millisecond)
+	}
+	if w := do("/slow", "k3", ""); w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "idempotency_in_progress") {
+		t.Fatalf("got %d %s for a concurrent duplicate", w.Code, w.Body)
+	}
+	close(release)
+	wg.Wait()
+}
@@ -0,0 +1,57 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/netip"
+)
+
+// IPFilterConfig configures the IPFilter middleware. Entries are CIDRs
+// ("10.0.0.0/8") or single IPs.
+type IPFilterConfig struct {
+	// Allow, when non-empty, rejects clients outside these ranges
+	Allow []string
+	// Deny rejects clients in these ranges; it takes precedence over Allow
+	Deny []string
+	// TrustedProxies resolves the client IP from X-Forwarded-For; without it
+	// the peer address is checked
+	TrustedProxies *TrustedProxies
+}
+
+// IPFilter returns middleware that answers 403 for clients denied by cfg or
+// whose address cannot be parsed
+func IPFilter(cfg *IPFilterConfig) (func(http.Handler) http.Handler, error) {
+	c := IPFilterConfig{}
+	if cfg != nil {
+		c = *cfg
+	}
+	allow, err := ParseTrustedProxies(c.Allow...)
+	if err != nil {
+		return nil, err
+	}
+	deny, err := ParseTrustedProxies(c.Deny...)
+	if err != nil {
+		return nil, err
+	}
+
+	permitted := func(ip string) bool {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return false
+		}
+		addr = addr.Unmap()
+		if deny.trusted(addr) {
+			return false
+		}
+		return len(allow.prefixes) == 0 || allow.trusted(addr)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !permitted(c.TrustedProxies.ClientIP(r)) {
+				writeProblem(w, r, http.StatusForbidden, "client address is not allowed")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
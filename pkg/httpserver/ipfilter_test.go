@@ -0,0 +1,52 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPFilter(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mw, err := IPFilter(&IPFilterConfig{
+		Allow:          []string{"192.168.0.0/16"},
+		Deny:           []string{"192.168.1.0/24"},
+		TrustedProxies: proxies,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+
+	tests := []struct {
+		remote, xff string
+		want        int
+	}{
+		{"192.168.2.5:1234", "", http.StatusOK},
+		{"192.168.1.5:1234", "", http.StatusForbidden},
+		{"172.16.0.1:1234", "", http.StatusForbidden},
+		{"10.0.0.1:1234", "192.168.2.5", http.StatusOK},
+		// untrusted peers cannot spoof X-Forwarded-For
+		{"172.16.0.1:1234", "192.168.2.5", http.StatusForbidden},
+		{"10.0.0.1:1234", "192.168.2.5, 172.16.0.1", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tt.remote
+		if tt.xff != "" {
+			r.Header.Set("X-Forwarded-For", tt.xff)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s via %q: status = %d, want %d", tt.remote, tt.xff, w.Code, tt.want)
+		}
+	}
+
+	if _, err := IPFilter(&IPFilterConfig{Allow: []string{"not-an-ip"}}); err == nil {
+		t.Error("expected error for invalid CIDR")
+	}
+}
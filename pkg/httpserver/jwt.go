@@ -0,0 +1,168 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MicahParks/keyfunc/v3"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// JWTAuthConfig configures Bearer token validation
+type JWTAuthConfig struct {
+	// JWKSURL is the JSON Web Key Set used to verify signatures, e.g. an identity
+	// provider's /.well-known/jwks.json. Keys are refreshed every RefreshInterval
+	// and when a token references an unknown kid, so key rotation is picked up.
+	JWKSURL string
+	// Keyfunc supplies verification keys directly instead of JWKSURL
+	Keyfunc jwt.Keyfunc
+	// RefreshInterval is how often the JWKS is reloaded (default: 1h)
+	RefreshInterval time.Duration
+	// Issuer is required to match the iss claim when set
+	Issuer string
+	// Audience requires the aud claim to contain one of these values when set
+	Audience []string
+	// Algorithms are the accepted signing algorithms (default: RS256, RS384,
+	// RS512, PS256, ES256, ES384, EdDSA)
+	Algorithms []string
+	// Leeway allows for clock skew when validating exp, nbf and iat
+	Leeway time.Duration
+	// Logger receives JWKS refresh errors
+	Logger logrus.FieldLogger
+}
+
+// JWTAuthenticator validates Bearer tokens
+type JWTAuthenticator struct {
+	keyfunc jwt.Keyfunc
+	parser  *jwt.Parser
+}
+
+type jwtClaimsKey struct{}
+
+// NewJWTAuthenticator creates an authenticator; ctx bounds the background JWKS
+// refresh, which stops when it is cancelled
+func NewJWTAuthenticator(ctx context.Context, cfg *JWTAuthConfig) (*JWTAuthenticator, error) {
+	kf := cfg.Keyfunc
+	if kf == nil {
+		if cfg.JWKSURL == "" {
+			return nil, errs.New(errs.Internal, "either JWKSURL or Keyfunc is required")
+		}
+		override := keyfunc.Override{RefreshInterval: cfg.RefreshInterval}
+		if cfg.Logger != nil {
+			override.RefreshErrorHandlerFunc = func(u string) func(context.Context, error) {
+				return func(_ context.Context, err error) {
+					cfg.Logger.WithError(err).WithField("url", u).Error("failed to refresh JWKS")
+				}
+			}
+		}
+		jwks, err := keyfunc.NewDefaultOverrideCtx(ctx, []string{cfg.JWKSURL}, override)
+		if err != nil {
+			return nil, errs.Errorf("failed to load JWKS from %s: %w", cfg.JWKSURL, err)
+		}
+		kf = jwks.KeyfuncCtx(ctx)
+	}
+
+	algs := cfg.Algorithms
+	if len(algs) == 0 {
+		algs = []string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384", "EdDSA"}
+	}
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(algs),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if len(cfg.Audience) > 0 {
+		opts = append(opts, jwt.WithAudience(cfg.Audience...))
+	}
+
+	return &JWTAuthenticator{keyfunc: kf, parser: jwt.NewParser(opts...)}, nil
+}
+
+// Validate verifies a token's signature and registered claims and returns its claims
+func (a *JWTAuthenticator) Validate(tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(tokenString, claims, a.keyfunc); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Middleware rejects requests without a valid Bearer token with 401 and stores
+// the token's claims in the request context
+func (a *JWTAuthenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer`)
+			writeProblem(w, r, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		claims, err := a.Validate(token)
+		if err != nil {
+			msg := "invalid token"
+			if errors.Is(err, jwt.ErrTokenExpired) {
+				msg = "token expired"
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeProblem(w, r, http.StatusUnauthorized, msg)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), jwtClaimsKey{}, claims)))
+	})
+}
+
+// ClaimsFromContext returns the claims of the token validated by JWTAuthenticator.Middleware
+func ClaimsFromContext(ctx context.Context) (jwt.MapClaims, bool) {
+	claims, ok := ctx.Value(jwtClaimsKey{}).(jwt.MapClaims)
+	return claims, ok
+}
+
+// SubjectFromContext returns the sub claim of the validated token, or ""
+func SubjectFromContext(ctx context.Context) string {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	sub, _ := claims.GetSubject()
+	return sub
+}
+
+// ScopesFromContext returns the space separated scope claim (or scp list) of the validated token
+func ScopesFromContext(ctx context.Context) []string {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if scope, ok := claims["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+	if list, ok := claims["scp"].([]any); ok {
+		scopes := make([]string, 0, len(list))
+		for _, s := range list {
+			if str, ok := s.(string); ok {
+				scopes = append(scopes, str)
+			}
+		}
+		return scopes
+	}
+	return nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	h := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(h, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
@@ -0,0 +1,150 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// localJWKS serves the public halves of its keys as a JWK Set
+type localJWKS struct {
+	mu   sync.Mutex
+	keys map[string]*rsa.PrivateKey
+}
+
+func (j *localJWKS) add(t *testing.T, kid string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.keys[kid] = key
+}
+
+func (j *localJWKS) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var keys []map[string]string
+	for kid, key := range j.keys {
+		keys = append(keys, map[string]string{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": kid,
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+}
+
+func (j *localJWKS) sign(t *testing.T, kid string, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestJWTAuthenticatorMiddleware(t *testing.T) {
+	jwks := &localJWKS{keys: map[string]*rsa.PrivateKey{}}
+	jwks.add(t, "key-1")
+	srv := httptest.NewServer(jwks)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	auth, err := NewJWTAuthenticator(ctx, &JWTAuthConfig{
+		JWKSURL:  srv.URL,
+		Issuer:   "https://auth.example.com",
+		Audience: []string{"orders"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := auth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(SubjectFromContext(r.Context())))
+	}))
+	do := func(authorization string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	claims := func(mutate func(jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub": "user-1",
+			"iss": "https://auth.example.com",
+			"aud": "orders",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+		if mutate != nil {
+			mutate(c)
+		}
+		return c
+	}
+	key1 := jwks.keys["key-1"]
+
+	w := do("Bearer " + jwks.sign(t, "key-1", key1, claims(nil)))
+	if w.Code != http.StatusOK || w.Body.String() != "user-1" {
+		t.Fatalf("valid token: got %d %q", w.Code, w.Body.String())
+	}
+
+	for name, tc := range map[string]struct {
+		authorization string
+		challenge     string
+	}{
+		"missing":      {"", `Bearer`},
+		"not bearer":   {"Basic dXNlcjpwYXNz", `Bearer`},
+		"empty bearer": {"Bearer ", `Bearer`},
+		"malformed":    {"Bearer not.a.jwt", `Bearer error="invalid_token"`},
+		"wrong audience": {"Bearer " + jwks.sign(t, "key-1", key1, claims(func(c jwt.MapClaims) { c["aud"] = "billing" })),
+			`Bearer error="invalid_token"`},
+		"wrong issuer": {"Bearer " + jwks.sign(t, "key-1", key1, claims(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" })),
+			`Bearer error="invalid_token"`},
+		"expired": {"Bearer " + jwks.sign(t, "key-1", key1, claims(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() })),
+			`Bearer error="invalid_token"`},
+		"no expiry": {"Bearer " + jwks.sign(t, "key-1", key1, claims(func(c jwt.MapClaims) { delete(c, "exp") })),
+			`Bearer error="invalid_token"`},
+	} {
+		w := do(tc.authorization)
+		if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != tc.challenge {
+			t.Errorf("%s: got %d with challenge %q", name, w.Code, w.Header().Get("WWW-Authenticate"))
+		}
+	}
+
+	// a kid missing from the cached set triggers a refresh, picking up rotated keys
+	jwks.add(t, "key-2")
+	if w := do("Bearer " + jwks.sign(t, "key-2", jwks.keys["key-2"], claims(nil))); w.Code != http.StatusOK {
+		t.Fatalf("rotated key: got %d", w.Code)
+	}
+	// keys the JWKS does not publish are rejected, even when the kid is known
+	stranger, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kid := range []string{"key-3", "key-1"} {
+		if w := do("Bearer " + jwks.sign(t, kid, stranger, claims(nil))); w.Code != http.StatusUnauthorized {
+			t.Fatalf("unknown key with kid %s: got %d", kid, w.Code)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// Lifecycle hooks run in this order:
+//
+//  1. Run binds the listener and starts serving
+//  2. OnStart hooks, in registration order, sharing StartTimeout
+//  3. on a stop signal, Shutdown stops the listeners and drains requests
+//  4. OnShutdown hooks, in reverse registration order, sharing the rest of ShutdownTimeout
+//
+// OnPanic hooks run in registration order whenever a handler panics.
+type lifecycleHooks struct {
+	mu       sync.Mutex
+	start    []func(ctx context.Context) error
+	shutdown []func(ctx context.Context) error
+	panic    []ErrorSink
+}
+
+// OnStart registers a hook run by Run once the listener is bound and serving,
+// e.g. to warm caches before readiness checks pass. A failing hook stops the
+// server and Run returns its error.
+func (s *EasyGoHTTPServer) OnStart(fn func(ctx context.Context) error) {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+	s.hooks.start = append(s.hooks.start, fn)
+}
+
+// OnShutdown registers a hook run by Shutdown after the server stops accepting
+// requests and in-flight requests have drained. Hooks run in reverse
+// registration order so resources are released in the opposite order they
+// were acquired.
+func (s *EasyGoHTTPServer) OnShutdown(fn func(ctx context.Context) error) {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+	s.hooks.shutdown = append(s.hooks.shutdown, fn)
+}
+
+// OnPanic registers a hook called with panics recovered from handlers, after
+// the panic is logged and before the 500 response is written
+func (s *EasyGoHTTPServer) OnPanic(fn ErrorSink) {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+	s.hooks.panic = append(s.hooks.panic, fn)
+}
+
+func (h *lifecycleHooks) snapshot() (start, shutdown []func(ctx context.Context) error, panics []ErrorSink) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]func(ctx context.Context) error{}, h.start...),
+		append([]func(ctx context.Context) error{}, h.shutdown...),
+		append([]ErrorSink{}, h.panic...)
+}
+
+// reportPanic calls the panic hooks; a hook that panics itself does not stop the others
+func (h *lifecycleHooks) reportPanic(r *http.Request, recovered any, stack []byte) {
+	_, _, panics := h.snapshot()
+	for _, fn := range panics {
+		func() {
+			defer func() { _ = recover() }()
+			fn(r, recovered, stack)
+		}()
+	}
+}
+
+// RunStartHooks runs the OnStart hooks for hosts that serve the router
+// without Run or Start, such as the httpserverlambda adapter
+func (s *EasyGoHTTPServer) RunStartHooks(ctx context.Context) error {
+	return s.runStartHooks(ctx)
+}
+
+// runStartHooks runs the start hooks in order, stopping at the first error
+func (s *EasyGoHTTPServer) runStartHooks(ctx context.Context) error {
+	start, _, _ := s.hooks.snapshot()
+	if len(start) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.startTimeout)
+	defer cancel()
+	for i, fn := range start {
+		if err := fn(ctx); err != nil {
+			return fmt.Errorf("start hook %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Run serves until ctx is cancelled or the process receives SIGINT or SIGTERM,
+// then shuts down gracefully within the configured ShutdownTimeout. It returns
+// nil after a clean shutdown.
+func (s *EasyGoHTTPServer) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	l, err := s.listen()
+	if err != nil {
+		return err
+	}
+
+	serveErr := s.serveAll(l)
+
+	starting := make(chan error, 1)
+	go func() {
+		starting <- s.runStartHooks(ctx)
+	}()
+
+	for {
+		select {
+		case err := <-starting:
+			if err == nil {
+				starting = nil
+				continue
+			}
+			s.logger.WithError(err).Error("start hook failed")
+			return errors.Join(err, s.shutdownWithTimeout())
+		case err := <-serveErr:
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			// stop whichever listener is still running
+			return errors.Join(err, s.shutdownWithTimeout())
+		case <-ctx.Done():
+			s.logger.Info("HTTP server shutting down")
+			return s.shutdownWithTimeout()
+		}
+	}
+}
+
+// Start binds the listeners, serves, and runs the OnStart hooks, returning
+// nil once Shutdown stops the server. Unlike Run it does not handle signals
+// or shut down on its own, for running the server under a supervisor such as
+// pkg/app. A failing start hook is returned after the server is shut down.
+func (s *EasyGoHTTPServer) Start(ctx context.Context) error {
+	l, err := s.listen()
+	if err != nil {
+		return err
+	}
+
+	serveErr := s.serveAll(l)
+
+	if err := s.runStartHooks(ctx); err != nil {
+		s.logger.WithError(err).Error("start hook failed")
+		return errors.Join(err, s.shutdownWithTimeout())
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// serveAll serves on l and the auxiliary listeners, reporting their errors
+func (s *EasyGoHTTPServer) serveAll(l net.Listener) <-chan error {
+	serveErr := make(chan error, 1+len(s.auxServers))
+	for _, aux := range s.auxServers {
+		go func() {
+			s.logger.WithField("addr", aux.server.Addr).Infof("%s server listening", aux.name)
+			if err := aux.server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				serveErr <- fmt.Errorf("%s server: %w", aux.name, err)
+			}
+		}()
+	}
+	go func() {
+		s.logger.WithField("addr", l.Addr().String()).Info("HTTP server listening")
+		serveErr <- s.Serve(l)
+	}()
+	return serveErr
+}
+
+func (s *EasyGoHTTPServer) shutdownWithTimeout() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	return s.Shutdown(ctx)
+}
+
+// Shutdown drains the server (see Drain), stops the auxiliary listeners and
+// then runs the registered shutdown hooks. Calling it more than once returns
+// the result of the first call.
+func (s *EasyGoHTTPServer) Shutdown(ctx context.Context) error {
+	s.shutdownOnce.Do(func() {
+		var errs []error
+		if _, err := s.Drain(ctx); err != nil {
+			errs = append(errs, err)
+		}
+		for _, aux := range s.auxServers {
+			if err := aux.server.Shutdown(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		_, hooks, _ := s.hooks.snapshot()
+
+		for i := len(hooks) - 1; i >= 0; i-- {
+			if err := hooks[i](ctx); err != nil {
+				s.logger.WithError(err).Error("shutdown hook failed")
+				errs = append(errs, err)
+			}
+		}
+
+		s.shutdownErr = errors.Join(errs...)
+	})
+	return s.shutdownErr
+}
@@ -0,0 +1,164 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// localListener listens on a free loopback port
+func localListener(t *testing.T) net.Listener {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+// freePort returns a loopback port that was free a moment ago
+func freePort(t *testing.T) int {
+	t.Helper()
+	l := localListener(t)
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// hookOrder records the order hooks ran in
+type hookOrder struct {
+	mu  sync.Mutex
+	ran []string
+}
+
+func (h *hookOrder) hook(name string) func(context.Context) error {
+	return func(context.Context) error {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.ran = append(h.ran, name)
+		return nil
+	}
+}
+
+func (h *hookOrder) get() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.ran)
+}
+
+func TestRunLifecycle(t *testing.T) {
+	metricsPort := freePort(t)
+	l := localListener(t)
+	s := NewEasyGoHTTPServer(&NewEasyGoHTTPServerArgs{
+		Listener: l,
+		Metrics:  &MetricsConfig{Port: metricsPort, Registerer: prometheus.NewRegistry(), Gatherer: prometheus.NewRegistry()},
+	})
+	s.Chi.Get("/ping", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("pong")) })
+
+	order := &hookOrder{}
+	started := make(chan struct{})
+	s.OnStart(order.hook("start 1"))
+	s.OnStart(order.hook("start 2"))
+	s.OnStart(func(context.Context) error { close(started); return nil })
+	s.OnShutdown(order.hook("shutdown 1"))
+	s.OnShutdown(order.hook("shutdown 2"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	<-started
+
+	base := "http://" + l.Addr().String()
+	resp, err := http.Get(base + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	// the auxiliary listener binds in the background
+	metricsURL := fmt.Sprintf("http://127.0.0.1:%d/metrics", metricsPort)
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		resp, err = http.Get(metricsURL)
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run returned %v after ctx was canceled", err)
+	}
+	want := []string{"start 1", "start 2", "shutdown 2", "shutdown 1"}
+	if got := order.get(); !slices.Equal(got, want) {
+		t.Fatalf("hooks ran %v, want %v", got, want)
+	}
+	if _, err := http.Get(base + "/ping"); err == nil {
+		t.Fatal("the main listener still serves after Run returned")
+	}
+	if _, err := http.Get(metricsURL); err == nil {
+		t.Fatal("the metrics listener still serves after Run returned")
+	}
+
+	// shutting down again returns the first result without rerunning hooks
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := order.get(); len(got) != len(want) {
+		t.Fatalf("hooks ran %v after a second Shutdown", got)
+	}
+}
+
+func TestRunSignal(t *testing.T) {
+	s := NewEasyGoHTTPServer(&NewEasyGoHTTPServerArgs{Listener: localListener(t)})
+	started := make(chan struct{})
+	s.OnStart(func(context.Context) error { close(started); return nil })
+	shutdown := make(chan struct{})
+	s.OnShutdown(func(context.Context) error { close(shutdown); return nil })
+
+	done := make(chan error, 1)
+	go func() { done <- s.Run(context.Background()) }()
+	// Run handles signals from before the start hooks run
+	<-started
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run returned %v after SIGTERM", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after SIGTERM")
+	}
+	select {
+	case <-shutdown:
+	default:
+		t.Fatal("shutdown hooks did not run")
+	}
+}
+
+func TestRunStartHookFailure(t *testing.T) {
+	s := NewEasyGoHTTPServer(&NewEasyGoHTTPServerArgs{Listener: localListener(t)})
+	failed := errors.New("cache warmup failed")
+	s.OnStart(func(context.Context) error { return failed })
+	shutdown := false
+	s.OnShutdown(func(context.Context) error { shutdown = true; return nil })
+
+	if err := s.Run(context.Background()); !errors.Is(err, failed) {
+		t.Fatalf("got %v, want the start hook's error", err)
+	}
+	if !shutdown {
+		t.Fatal("shutdown hooks did not run after the failed start")
+	}
+}
@@ -0,0 +1,82 @@
+package httpserver
+
+import (
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// defaultUnixSocketMode lets the owner and group connect to the socket
+const defaultUnixSocketMode fs.FileMode = 0o660
+
+// systemdListenFDsStart is the first file descriptor passed by systemd socket activation
+const systemdListenFDsStart = 3
+
+// listen returns the configured listener, a unix socket, or a TCP listener on
+// the server address, in that order of preference
+func (s *EasyGoHTTPServer) listen() (net.Listener, error) {
+	if s.listener != nil {
+		return s.listener, nil
+	}
+	if s.unixSocket == "" {
+		l, err := net.Listen("tcp", s.server.Addr)
+		if err != nil {
+			return nil, errs.Errorf("failed to listen on %s: %w", s.server.Addr, err)
+		}
+		return l, nil
+	}
+
+	// remove a socket left behind by a previous process, but never a regular file
+	if info, err := os.Lstat(s.unixSocket); err == nil {
+		if info.Mode()&fs.ModeSocket == 0 {
+			return nil, errs.Newf(errs.Internal, "%s exists and is not a socket", s.unixSocket)
+		}
+		if err := os.Remove(s.unixSocket); err != nil {
+			return nil, errs.Errorf("failed to remove stale socket %s: %w", s.unixSocket, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, errs.Errorf("failed to stat %s: %w", s.unixSocket, err)
+	}
+
+	l, err := net.Listen("unix", s.unixSocket)
+	if err != nil {
+		return nil, errs.Errorf("failed to listen on %s: %w", s.unixSocket, err)
+	}
+	if err := os.Chmod(s.unixSocket, s.unixSocketMode); err != nil {
+		l.Close()
+		return nil, errs.Errorf("failed to set permissions on %s: %w", s.unixSocket, err)
+	}
+	return l, nil
+}
+
+// Serve serves on l, using TLS when the server is configured for it
+func (s *EasyGoHTTPServer) Serve(l net.Listener) error {
+	if s.tls {
+		return s.server.ServeTLS(l, s.tlsCertFile, s.tlsKeyFile)
+	}
+	return s.server.Serve(l)
+}
+
+// SystemdListener returns the first socket passed by systemd socket activation
+// (LISTEN_FDS), for use as NewEasyGoHTTPServerArgs.Listener
+func SystemdListener() (net.Listener, error) {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, errs.New(errs.Internal, "no sockets passed by systemd for this process")
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, errs.New(errs.Internal, "no sockets passed by systemd for this process")
+	}
+
+	f := os.NewFile(uintptr(systemdListenFDsStart), "systemd-socket")
+	defer f.Close()
+	l, err := net.FileListener(f)
+	if err != nil {
+		return nil, errs.Errorf("failed to use systemd socket: %w", err)
+	}
+	return l, nil
+}
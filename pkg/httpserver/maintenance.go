@@ -0,0 +1,106 @@
+package httpserver
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultMaintenanceRetryAfter is the Retry-After sent when none is given
+const defaultMaintenanceRetryAfter = time.Minute
+
+// MaintenanceStatus describes the server's maintenance mode
+type MaintenanceStatus struct {
+	Enabled bool `json:"enabled"`
+	// RetryAfter is sent to clients as the Retry-After header
+	RetryAfter time.Duration `json:"retryAfter,omitempty"`
+	Message    string        `json:"message,omitempty"`
+	Since      time.Time     `json:"since,omitzero"`
+}
+
+// maintenanceState holds the current maintenance status; nil means disabled
+type maintenanceState struct {
+	status atomic.Pointer[MaintenanceStatus]
+}
+
+// middleware answers 503 with Retry-After while maintenance mode is enabled
+func (m *maintenanceState) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		st := m.status.Load()
+		if st == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		detail := st.Message
+		if detail == "" {
+			detail = "the service is undergoing maintenance"
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(st.RetryAfter))))
+		WriteProblem(w, r, NewProblem(http.StatusServiceUnavailable, detail).WithCode("maintenance"))
+	})
+}
+
+// EnableMaintenance makes application routes answer 503 with Retry-After
+// (default: 1m) and message as the problem detail. Health, metrics and admin
+// endpoints keep working.
+func (s *EasyGoHTTPServer) EnableMaintenance(retryAfter time.Duration, message string) {
+	if retryAfter <= 0 {
+		retryAfter = defaultMaintenanceRetryAfter
+	}
+	s.maintenance.status.Store(&MaintenanceStatus{
+		Enabled:    true,
+		RetryAfter: retryAfter,
+		Message:    message,
+		Since:      time.Now(),
+	})
+	s.logger.WithField("message", message).Warn("maintenance mode enabled")
+}
+
+// DisableMaintenance resumes serving application routes
+func (s *EasyGoHTTPServer) DisableMaintenance() {
+	if s.maintenance.status.Swap(nil) != nil {
+		s.logger.Info("maintenance mode disabled")
+	}
+}
+
+// Maintenance returns the current maintenance status
+func (s *EasyGoHTTPServer) Maintenance() MaintenanceStatus {
+	if st := s.maintenance.status.Load(); st != nil {
+		return *st
+	}
+	return MaintenanceStatus{}
+}
+
+// maintenanceRequest is the body accepted by PUT /maintenance
+type maintenanceRequest struct {
+	RetryAfterSeconds int    `json:"retryAfterSeconds" validate:"gte=0"`
+	Message           string `json:"message"`
+}
+
+// maintenanceRoutes serves GET, PUT (enable) and DELETE (disable) /maintenance
+func maintenanceRoutes(s *EasyGoHTTPServer, r chi.Router) {
+	r.Route("/maintenance", func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, _ *http.Request) {
+			JSON(w, http.StatusOK, s.Maintenance())
+		})
+		r.Put("/", func(w http.ResponseWriter, r *http.Request) {
+			req := maintenanceRequest{}
+			if r.ContentLength != 0 {
+				var err error
+				if req, err = Bind[maintenanceRequest](r); err != nil {
+					WriteBindError(w, r, err)
+					return
+				}
+			}
+			s.EnableMaintenance(time.Duration(req.RetryAfterSeconds)*time.Second, req.Message)
+			JSON(w, http.StatusOK, s.Maintenance())
+		})
+		r.Delete("/", func(w http.ResponseWriter, _ *http.Request) {
+			s.DisableMaintenance()
+			JSON(w, http.StatusOK, s.Maintenance())
+		})
+	})
+}
@@ -0,0 +1,78 @@
+package httpserver
+
+import (
+	"net/http"
+
+	"github.com/bdlilley/easygo/pkg/metrics"
+	"github.com/bdlilley/easygo/pkg/runtimeinfo"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const defaultMetricsPath = "/metrics"
+
+// MetricsConfig enables Prometheus request metrics and the /metrics endpoint
+type MetricsConfig struct {
+	// Provider records the request metrics (default: a Prometheus provider
+	// on Registerer with Namespace and RuntimeLabels)
+	Provider metrics.Provider
+	// Registerer and Gatherer default to the prometheus default registry
+	Registerer prometheus.Registerer
+	Gatherer   prometheus.Gatherer
+	// Namespace prefixes metric names, e.g. "myapp" gives myapp_http_requests_total
+	Namespace string
+	// RuntimeLabels labels the default provider's metrics with
+	// runtimeinfo.Get().Labels(). It is off by default since metrics with
+	// the same name on Registerer must then carry the labels too.
+	RuntimeLabels bool
+	// Buckets are the request duration histogram buckets (default: prometheus.DefBuckets)
+	Buckets []float64
+	// Path serves the metrics handler (default: /metrics)
+	Path string
+	// Port serves metrics on a separate listener instead of the main router, so
+	// they are not exposed publicly; Run starts and stops it with the server
+	Port int
+}
+
+// metricsMiddleware records request metrics through cfg.Provider, or a
+// Prometheus provider on cfg.Registerer, labeled with the chi route pattern
+// rather than the raw path to keep label cardinality bounded
+func metricsMiddleware(cfg *MetricsConfig) func(http.Handler) http.Handler {
+	p := cfg.Provider
+	if p == nil {
+		opts := &metrics.PrometheusOptions{Registerer: cfg.Registerer, Namespace: cfg.Namespace}
+		if cfg.RuntimeLabels {
+			opts.ConstLabels = runtimeinfo.Get().Labels()
+		}
+		p = metrics.NewPrometheus(opts)
+	}
+	buckets := cfg.Buckets
+	if buckets == nil {
+		buckets = prometheus.DefBuckets
+	}
+	return metrics.HTTPMiddleware(p, &metrics.HTTPMiddlewareOptions{Buckets: buckets})
+}
+
+// configureMetrics mounts the metrics handler on routes, returning a separate
+// server for it instead when cfg.Port is set
+func configureMetrics(routes chi.Router, cfg *MetricsConfig) *auxServer {
+	gatherer := cfg.Gatherer
+	if gatherer == nil {
+		gatherer = prometheus.DefaultGatherer
+	}
+	handler := promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})
+	path := cfg.Path
+	if path == "" {
+		path = defaultMetricsPath
+	}
+
+	if cfg.Port == 0 {
+		routes.Method(http.MethodGet, path, handler)
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+	return newAuxServer("metrics", cfg.Port, mux)
+}
@@ -0,0 +1,86 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bdlilley/easygo/pkg/metrics"
+	"github.com/bdlilley/easygo/pkg/runtimeinfo"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsSharedRegistry(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	r := chi.NewRouter()
+	// the server's metrics and metrics.HTTPMiddleware share their families
+	r.Use(metricsMiddleware(&MetricsConfig{Registerer: reg}))
+	r.Use(metrics.HTTPMiddleware(metrics.NewPrometheus(&metrics.PrometheusOptions{Registerer: reg}), nil))
+	r.Use(metricsMiddleware(&MetricsConfig{Registerer: reg}))
+	r.Get("/ping", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("pong")) })
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ping", nil))
+	if n := testutil.CollectAndCount(reg, "http_requests_total", "http_response_size_bytes", "http_requests_in_flight"); n != 3 {
+		t.Fatalf("got %d series, want 3", n)
+	}
+}
+
+func TestMetricsRuntimeLabels(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	r := chi.NewRouter()
+	r.Use(metricsMiddleware(&MetricsConfig{Registerer: reg, RuntimeLabels: true}))
+	r.Get("/ping", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("pong")) })
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := runtimeinfo.Get().Platform
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			found := false
+			for _, l := range m.GetLabel() {
+				found = found || l.GetName() == "platform" && l.GetValue() == want
+			}
+			if !found {
+				t.Fatalf("%s has labels %v, want platform=%s", f.GetName(), m.GetLabel(), want)
+			}
+		}
+	}
+}
+
+func TestMetricsCountMiddlewareResponses(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	s := NewEasyGoHTTPServer(&NewEasyGoHTTPServerArgs{
+		Metrics:   &MetricsConfig{Registerer: reg, Gatherer: reg},
+		RateLimit: &RateLimitConfig{Rate: 1, Burst: 2},
+	})
+	s.Chi.Get("/panic", func(http.ResponseWriter, *http.Request) { panic("boom") })
+	for range 3 {
+		s.GetHttpServer().Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]float64{}
+	for _, f := range families {
+		if f.GetName() != "http_requests_total" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "status" {
+					counts[l.GetValue()] += m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	if counts["500"] != 2 || counts["429"] != 1 {
+		t.Fatalf("got requests by status %v, want 2 recovered panics and 1 rate limited", counts)
+	}
+}
@@ -0,0 +1,122 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// ClientCertConfig configures client certificate (mTLS) authentication. It
+// applies to every request on the TLS listener, including health endpoints
+// unless they are served on the admin port.
+type ClientCertConfig struct {
+	// ClientCAs verifies client certificates (required); see LoadCertPool
+	ClientCAs *x509.CertPool
+	// Optional verifies certificates when presented instead of requiring them;
+	// handlers check ClientIdentityFromContext
+	Optional bool
+	// CheckRevocation is called during the handshake with the verified leaf and
+	// its issuer chain, e.g. to consult a CRL or OCSP responder; an error fails
+	// the handshake
+	CheckRevocation func(leaf *x509.Certificate, chain []*x509.Certificate) error
+	// Authorize is called for each request with a verified certificate, e.g. to
+	// allow specific SANs; an error responds 403
+	Authorize func(r *http.Request, id *ClientIdentity) error
+}
+
+// ClientIdentity is the verified client certificate of a request
+type ClientIdentity struct {
+	Subject        pkix.Name
+	DNSNames       []string
+	EmailAddresses []string
+	URIs           []*url.URL
+	// SerialNumber is the certificate serial in decimal
+	SerialNumber string
+	Certificate  *x509.Certificate
+	// Chain is the verified chain from the leaf to the root
+	Chain []*x509.Certificate
+}
+
+type clientIdentityKey struct{}
+
+// ClientIdentityFromContext returns the request's verified client certificate
+func ClientIdentityFromContext(ctx context.Context) (*ClientIdentity, bool) {
+	id, ok := ctx.Value(clientIdentityKey{}).(*ClientIdentity)
+	return id, ok
+}
+
+// LoadCertPool reads PEM encoded CA certificates from files into a pool
+func LoadCertPool(files ...string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, errs.Errorf("failed to read CA bundle %s: %w", f, err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, errs.Newf(errs.Internal, "no certificates found in %s", f)
+		}
+	}
+	return pool, nil
+}
+
+// configureClientCerts requires and verifies client certificates on cfg
+func configureClientCerts(tlsCfg *tls.Config, cfg *ClientCertConfig) {
+	tlsCfg.ClientCAs = cfg.ClientCAs
+	tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+	if cfg.Optional {
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	if cfg.CheckRevocation != nil {
+		tlsCfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			for _, chain := range cs.VerifiedChains {
+				if err := cfg.CheckRevocation(chain[0], chain[1:]); err != nil {
+					return errs.Errorf("client certificate rejected: %w", err)
+				}
+			}
+			return nil
+		}
+	}
+}
+
+// clientCertMiddleware stores the verified client identity in the request
+// context and applies the Authorize callback
+func clientCertMiddleware(cfg *ClientCertConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+				if !cfg.Optional {
+					writeProblem(w, r, http.StatusUnauthorized, "client certificate required")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			chain := r.TLS.VerifiedChains[0]
+			leaf := chain[0]
+			id := &ClientIdentity{
+				Subject:        leaf.Subject,
+				DNSNames:       leaf.DNSNames,
+				EmailAddresses: leaf.EmailAddresses,
+				URIs:           leaf.URIs,
+				SerialNumber:   leaf.SerialNumber.String(),
+				Certificate:    leaf,
+				Chain:          chain,
+			}
+			if cfg.Authorize != nil {
+				if err := cfg.Authorize(r, id); err != nil {
+					writeProblem(w, r, http.StatusForbidden, "client certificate not authorized")
+					return
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIdentityKey{}, id)))
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package httpserver
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Option customizes NewEasyGoHTTPServer beyond NewEasyGoHTTPServerArgs
+type Option func(*serverOptions)
+
+type serverOptions struct {
+	middleware      []func(http.Handler) http.Handler
+	noDefaultLogger bool
+	routerConfigs   []func(r chi.Router)
+}
+
+// WithMiddleware installs middleware outermost, ahead of the built-in request
+// ID, tracing, logging and recovery middleware, in the order given
+func WithMiddleware(mw ...func(http.Handler) http.Handler) Option {
+	return func(o *serverOptions) {
+		o.middleware = append(o.middleware, mw...)
+	}
+}
+
+// WithoutDefaultLogger skips the built-in request logger, e.g. to install a
+// different access logger with WithMiddleware or WithRouterConfig
+func WithoutDefaultLogger() Option {
+	return func(o *serverOptions) {
+		o.noDefaultLogger = true
+	}
+}
+
+// WithRouterConfig runs fn on the root router after the built-in middleware is
+// installed and before any route is registered, so fn may add middleware that
+// runs inside the defaults, or set NotFound and MethodNotAllowed handlers
+func WithRouterConfig(fn func(r chi.Router)) Option {
+	return func(o *serverOptions) {
+		o.routerConfigs = append(o.routerConfigs, fn)
+	}
+}
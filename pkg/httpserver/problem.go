@@ -0,0 +1,184 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/sirupsen/logrus"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem responses
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details error. Return it from a
+// HandlerFunc, or panic with it, to send it as the response.
+type Problem struct {
+	// Type is a URI identifying the problem type (default: about:blank)
+	Type   string `json:"type,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Instance identifies this occurrence, e.g. the request path
+	Instance string `json:"instance,omitempty"`
+	// Code is a stable, machine readable error code for clients
+	Code string `json:"code,omitempty"`
+	// Extensions are additional members serialized alongside the standard ones
+	Extensions map[string]any `json:"-"`
+}
+
+// NewProblem creates a problem with the standard title for status
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Title + ": " + p.Detail
+	}
+	return p.Title
+}
+
+// WithCode sets the machine readable error code
+func (p *Problem) WithCode(code string) *Problem {
+	p.Code = code
+	return p
+}
+
+// WithExtension adds an extension member
+func (p *Problem) WithExtension(key string, value any) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = map[string]any{}
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	b, err := json.Marshal((*problem)(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return b, err
+	}
+
+	members := map[string]any{}
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+	// standard members take precedence over extensions of the same name
+	if err := json.Unmarshal(b, &members); err != nil {
+		return nil, err
+	}
+	return json.Marshal(members)
+}
+
+// WriteProblem writes p as an application/problem+json response, adding the
+// request ID as the requestId extension
+func WriteProblem(w http.ResponseWriter, r *http.Request, p *Problem) {
+	if id := RequestIDFromContext(r.Context()); id != "" {
+		if _, ok := p.Extensions["requestId"]; !ok {
+			p = p.clone().WithExtension("requestId", id)
+		}
+	}
+	if p.Instance == "" {
+		p = p.clone()
+		p.Instance = r.URL.Path
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Del("Content-Length")
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// clone copies p so shared problem values are not mutated per request
+func (p *Problem) clone() *Problem {
+	c := *p
+	if p.Extensions != nil {
+		c.Extensions = make(map[string]any, len(p.Extensions))
+		for k, v := range p.Extensions {
+			c.Extensions[k] = v
+		}
+	}
+	return &c
+}
+
+// writeProblem writes a problem response with the standard title for status
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	WriteProblem(w, r, NewProblem(status, detail))
+}
+
+// ProblemFromError converts err to a Problem: a *Problem anywhere in the chain
+// is used as is, bind errors keep their status, errs kinds map to their
+// status with the kind as the code, and anything else becomes a generic 500
+// so internal details are not leaked to clients. Only client error kinds
+// expose their message.
+func ProblemFromError(err error) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		return p
+	}
+	var be *BindError
+	if errors.As(err, &be) {
+		p := NewProblem(be.Status, be.Message)
+		if len(be.Fields) > 0 {
+			p.WithCode("validation_failed").WithExtension("errors", be.Fields)
+		}
+		return p
+	}
+	if IsRequestBodyTooLarge(err) {
+		return NewProblem(http.StatusRequestEntityTooLarge, "request body too large")
+	}
+	if kind := errs.KindOf(err); kind != errs.Unknown {
+		status := kind.HTTPStatus()
+		detail := strings.ToLower(http.StatusText(status))
+		if status < http.StatusInternalServerError && errs.Message(err) != "" {
+			detail = errs.Message(err)
+		}
+		return NewProblem(status, detail).WithCode(kind.String())
+	}
+	return NewProblem(http.StatusInternalServerError, "internal server error")
+}
+
+// HandlerFunc is an http handler that returns an error
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// ErrorHandler adapts HandlerFuncs into http.Handlers, writing returned errors
+// as problem responses and logging server errors
+type ErrorHandler struct {
+	Logger logrus.FieldLogger
+}
+
+// Handle adapts h, writing an error it returns with ProblemFromError
+func (e *ErrorHandler) Handle(h HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := h(w, r)
+		if err == nil {
+			return
+		}
+		p := ProblemFromError(err)
+		if p.Status >= http.StatusInternalServerError && e.Logger != nil {
+			fields := logrus.Fields{
+				"request_id": RequestIDFromContext(r.Context()),
+				"method":     r.Method,
+				"path":       r.URL.Path,
+			}
+			if stack := errs.StackTrace(err); stack != "" {
+				fields["stack"] = stack
+			}
+			e.Logger.WithError(err).WithFields(fields).Error("HTTP handler error")
+		}
+		WriteProblem(w, r, p)
+	}
+}
+
+// Handle adapts h using the server's logger for server errors
+func (s *EasyGoHTTPServer) Handle(h HandlerFunc) http.HandlerFunc {
+	return (&ErrorHandler{Logger: s.logger}).Handle(h)
+}
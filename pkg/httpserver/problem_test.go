@@ -0,0 +1,37 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/rotisserie/eris"
+)
+
+func TestProblemFromErrs(t *testing.T) {
+	h := (&ErrorHandler{}).Handle
+	for name, tc := range map[string]struct {
+		err    error
+		status int
+		code   string
+		detail string
+	}{
+		"not found": {eris.Wrap(errs.New(errs.NotFound, "order not found"), "loading order"), http.StatusNotFound, "not_found", "order not found"},
+		"throttled": {errs.New(errs.Throttled, "too many exports"), http.StatusTooManyRequests, "throttled", "too many exports"},
+		"internal":  {errs.Wrap(errors.New("pq: connection reset"), errs.Internal, "query failed"), http.StatusInternalServerError, "internal", "internal server error"},
+		"plain":     {errors.New("pq: connection reset"), http.StatusInternalServerError, "", "internal server error"},
+	} {
+		w := httptest.NewRecorder()
+		h(func(http.ResponseWriter, *http.Request) error { return tc.err }).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+		var p Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != tc.status || p.Code != tc.code || p.Detail != tc.detail {
+			t.Errorf("%s: got %d %+v", name, w.Code, p)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// ProxyOptions configures a reverse proxy built by Proxy
+type ProxyOptions struct {
+	// StripPrefix is removed from the request path before forwarding
+	StripPrefix string
+	// Rewrite maps the (stripped) request path to the upstream path
+	Rewrite func(path string) string
+	// PreserveHost forwards the client's Host header instead of the target's
+	PreserveHost bool
+	// SetHeaders are set on every upstream request
+	SetHeaders map[string]string
+	// RemoveHeaders are removed from upstream requests, e.g. "Cookie"
+	RemoveHeaders []string
+	// Timeout bounds waiting for the upstream response headers (default: 30s)
+	Timeout time.Duration
+	// Transport defaults to a clone of http.DefaultTransport
+	Transport http.RoundTripper
+	// ModifyResponse can change or reject upstream responses
+	ModifyResponse func(*http.Response) error
+}
+
+// Proxy returns a reverse proxy to targetURL. It sets X-Forwarded-For,
+// X-Forwarded-Host and X-Forwarded-Proto, forwards the request ID, and turns
+// upstream failures into 502 (or 504 on timeout) problem responses.
+func Proxy(targetURL string, opts *ProxyOptions) (http.Handler, error) {
+	target, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, errs.Errorf("invalid proxy target %s: %w", targetURL, err)
+	}
+	if target.Scheme == "" || target.Host == "" {
+		return nil, errs.Newf(errs.Internal, "proxy target %s must be an absolute URL", targetURL)
+	}
+
+	o := ProxyOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 30 * time.Second
+	}
+	transport := o.Transport
+	if transport == nil {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.ResponseHeaderTimeout = o.Timeout
+		transport = t
+	}
+
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			path := pr.Out.URL.Path
+			if o.StripPrefix != "" {
+				path = strings.TrimPrefix(path, strings.TrimSuffix(o.StripPrefix, "/"))
+				if !strings.HasPrefix(path, "/") {
+					path = "/" + path
+				}
+			}
+			if o.Rewrite != nil {
+				path = o.Rewrite(path)
+			}
+			pr.Out.URL.Path = path
+			pr.Out.URL.RawPath = ""
+
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			if o.PreserveHost {
+				pr.Out.Host = pr.In.Host
+			}
+			if id := RequestIDFromContext(pr.In.Context()); id != "" {
+				pr.Out.Header.Set(DefaultRequestIDHeader, id)
+			}
+			for _, h := range o.RemoveHeaders {
+				pr.Out.Header.Del(h)
+			}
+			for k, v := range o.SetHeaders {
+				pr.Out.Header.Set(k, v)
+			}
+		},
+		Transport:      transport,
+		ModifyResponse: o.ModifyResponse,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if errors.Is(err, context.Canceled) {
+				// the client went away; there is nobody to respond to
+				return
+			}
+			var netErr net.Error
+			if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+				writeProblem(w, r, http.StatusGatewayTimeout, "upstream timed out")
+				return
+			}
+			writeProblem(w, r, http.StatusBadGateway, "upstream unavailable")
+		},
+	}, nil
+}
@@ -0,0 +1,84 @@
+package httpserver
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/ratelimit"
+)
+
+// RateLimitConfig configures token-bucket rate limiting
+type RateLimitConfig struct {
+	// Rate is the sustained number of requests per second allowed per key;
+	// it is required unless Limiter is set
+	Rate float64
+	// Burst is the bucket size (default: max(1, Rate))
+	Burst int
+	// KeyFunc groups requests into buckets (default: ClientIP). Returning ""
+	// skips rate limiting for the request.
+	KeyFunc func(r *http.Request) string
+	// Store holds the buckets, e.g. a ratelimit.RedisStore to rate limit
+	// across replicas (default: in memory, local to this process)
+	Store ratelimit.Store
+	// Limiter replaces Rate, Burst and Store with a ratelimit.Limiter, e.g. a
+	// sliding window
+	Limiter ratelimit.Limiter
+}
+
+// RateLimit returns token-bucket rate limiting middleware. It sets the
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers and
+// responds 429 with Retry-After when a bucket is empty. Store errors fail open.
+// It panics when cfg has neither a positive Rate nor a Limiter.
+func RateLimit(cfg *RateLimitConfig) func(http.Handler) http.Handler {
+	keyFunc := cfg.KeyFunc
+	if keyFunc == nil {
+		keyFunc = ClientIP
+	}
+	limiter := cfg.Limiter
+	if limiter == nil {
+		bucket, err := ratelimit.NewTokenBucket(&ratelimit.NewTokenBucketArgs{Rate: cfg.Rate, Burst: cfg.Burst, Store: cfg.Store})
+		if err != nil {
+			panic("httpserver: RateLimitConfig needs a positive Rate or a Limiter")
+		}
+		limiter = bucket
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := keyFunc(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			res, err := ratelimit.Allow(r.Context(), limiter, key)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+			if !res.Allowed {
+				h.Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(res.RetryAfter))))
+				writeProblem(w, r, http.StatusTooManyRequests, "rate limit exceeded")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP returns the host part of the request's RemoteAddr. Behind proxies,
+// use TrustedProxies.ClientIP as the KeyFunc instead.
+func ClientIP(r *http.Request) string {
+	return remoteAddr(r)
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
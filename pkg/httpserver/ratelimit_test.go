@@ -0,0 +1,31 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimit(t *testing.T) {
+	h := RateLimit(&RateLimitConfig{Rate: 1, Burst: 2})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	serve := func(remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := serve("10.0.0.1:1234"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: got %d", i, w.Code)
+		}
+	}
+	w := serve("10.0.0.1:1234")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" || w.Header().Get("X-RateLimit-Limit") != "2" {
+		t.Fatalf("got %d %v, want the bucket to be empty", w.Code, w.Header())
+	}
+	if w := serve("10.0.0.2:1234"); w.Code != http.StatusOK {
+		t.Fatalf("got %d, want a separate bucket per client", w.Code)
+	}
+}
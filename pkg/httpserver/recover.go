@@ -0,0 +1,56 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrorSink receives recovered panics, e.g. to forward them to an error tracker
+type ErrorSink func(r *http.Request, recovered any, stack []byte)
+
+// recovererMiddleware turns a panic into a problem response, logging the stack
+// and reporting it to sink when set. Panicking with a *Problem sends that
+// problem; any other value sends a 500. http.ErrAbortHandler is re-raised so
+// the server aborts the response as intended.
+func recovererMiddleware(logger *logrus.Logger, sink ErrorSink) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(rec)
+				}
+
+				stack := debug.Stack()
+				logger.WithFields(logrus.Fields{
+					"panic":      rec,
+					"stack":      string(stack),
+					"request_id": RequestIDFromContext(r.Context()),
+					"method":     r.Method,
+					"path":       r.URL.Path,
+				}).Error("HTTP handler panic")
+				if sink != nil {
+					sink(r, rec, stack)
+				}
+
+				// upgraded connections have no response to write
+				if isUpgrade(r) {
+					return
+				}
+				if err, ok := rec.(error); ok {
+					WriteProblem(w, r, ProblemFromError(err))
+					return
+				}
+				writeProblem(w, r, http.StatusInternalServerError, "internal server error")
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// DefaultRequestIDHeader is the header read and written by the request ID middleware
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds incoming request IDs so clients cannot bloat logs
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the ID assigned to the current request, or "" outside a request
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestIDMiddleware honors a well-formed incoming request ID or generates one,
+// stores it in the request context, and echoes it in the response header
+func requestIDMiddleware(header string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(header)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(header, id)
+
+			ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+			// also set chi's key so middleware.GetReqID works
+			ctx = context.WithValue(ctx, middleware.RequestIDKey, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -1,12 +1,18 @@
 package httpserver
 
 import (
+	"crypto/tls"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"sync"
 	"time"
 
+	"github.com/bdlilley/easygo/pkg/health"
+	"github.com/bdlilley/easygo/pkg/runtimeinfo"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/sirupsen/logrus"
@@ -14,27 +20,184 @@ import (
 
 type EasyGoHTTPServer struct {
 	server *http.Server
-	Chi    *chi.Mux
+	// Chi is the application router. It is mounted under the root router that
+	// holds the built-in middleware and operational endpoints, so middleware may
+	// be added with Chi.Use until the first application route is registered.
+	Chi *chi.Mux
+
+	logger          *logrus.Logger
+	shutdownTimeout time.Duration
+
+	health     *healthRegistry
+	auxServers []*auxServer
+	admin      *chi.Mux
+
+	listener       net.Listener
+	unixSocket     string
+	unixSocketMode os.FileMode
+
+	tls         bool
+	tlsCertFile string
+	tlsKeyFile  string
+
+	hooks        *lifecycleHooks
+	drain        *drainState
+	maintenance  *maintenanceState
+	startTimeout time.Duration
+	shutdownOnce sync.Once
+	shutdownErr  error
 }
 
 func (s *EasyGoHTTPServer) ListenAndServe() error {
-	return s.server.ListenAndServe()
+	l, err := s.listen()
+	if err != nil {
+		return err
+	}
+	return s.server.Serve(l)
 }
 
 type NewEasyGoHTTPServerArgs struct {
+	// Logger defaults to JSON logs tagged with runtimeinfo fields
 	Logger *logrus.Logger
 	Port   int
+	// Listener serves on a pre-built listener, e.g. from SystemdListener, instead of Port
+	Listener net.Listener
+	// UnixSocket serves on a unix domain socket at this path instead of Port
+	UnixSocket string
+	// UnixSocketMode is the socket file's permissions (default: 0660)
+	UnixSocketMode os.FileMode
+	// ShutdownTimeout is the grace period for in-flight requests and shutdown hooks
+	// when Run receives a stop signal (default: 30s)
+	ShutdownTimeout time.Duration
+	// TLSCertFile and TLSKeyFile serve HTTPS with a certificate and key from disk
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSConfig is the base TLS configuration; setting it (with Certificates or
+	// GetCertificate populated) enables HTTPS without cert files
+	TLSConfig *tls.Config
+	// ClientCert requires and verifies client certificates (mTLS) over TLS
+	ClientCert *ClientCertConfig
+	// AutoCert obtains and renews certificates automatically via ACME (Let's Encrypt)
+	AutoCert *AutoCertConfig
+	// DisableHTTP2 serves only HTTP/1.1 over TLS (HTTP/2 is negotiated by default)
+	DisableHTTP2 bool
+	// EnableH2C serves cleartext HTTP/2 (prior knowledge) alongside HTTP/1.1, for
+	// running behind proxies such as Envoy or an ALB that speak h2c to the backend
+	EnableH2C bool
+	// HTTP2 tunes the HTTP/2 server; zero fields use Go's defaults except
+	// MaxConcurrentStreams, which defaults to 250. Idle connections are closed
+	// after the server IdleTimeout.
+	HTTP2 *http.HTTP2Config
+	// DisableHealthEndpoints stops /healthz and /readyz from being mounted
+	DisableHealthEndpoints bool
+	// HealthCheckTimeout bounds each registered health check (default: 5s)
+	HealthCheckTimeout time.Duration
+	// HealthRegistry holds the /readyz checks, e.g. a registry shared with
+	// grpcserver; AddReadinessCheck registers on it, and it is marked shutting
+	// down when the server drains (default: a registry of the server's own)
+	HealthRegistry *health.Registry
+	// Metrics enables Prometheus request metrics and the /metrics endpoint
+	Metrics *MetricsConfig
+	// Tracing enables an OpenTelemetry span per request; trace and span IDs are
+	// added to the request log fields
+	Tracing *TracingConfig
+
+	// Timeouts and header limits; zero uses the default shown, a negative value
+	// disables the limit. Long-lived responses such as streams should extend their
+	// deadline with http.ResponseController rather than disabling WriteTimeout.
+	ReadTimeout       time.Duration // default: 60s
+	ReadHeaderTimeout time.Duration // default: 10s
+	WriteTimeout      time.Duration // default: 60s
+	IdleTimeout       time.Duration // default: 120s
+	MaxHeaderBytes    int           // default: 1MB
+
+	// RequestIDHeader is read for an incoming request ID and set on responses
+	// (default: X-Request-ID)
+	RequestIDHeader string
+	// ErrorSink is called with panics recovered from handlers; it is the first
+	// OnPanic hook
+	ErrorSink ErrorSink
+	// DrainDelay is how long /readyz fails before the listener closes on
+	// shutdown, giving load balancers time to stop routing new requests,
+	// e.g. 5s on Kubernetes. It counts against ShutdownTimeout.
+	DrainDelay time.Duration
+	// StartTimeout bounds the OnStart hooks (default: 30s)
+	StartTimeout time.Duration
+	// CORS mounts cross-origin resource sharing handling when set
+	CORS *CORSConfig
+	// RateLimit applies token-bucket rate limiting to every route; use the
+	// RateLimit middleware directly to limit individual route groups
+	RateLimit *RateLimitConfig
+	// Idempotency replays stored responses for retried POST and PATCH
+	// requests carrying an Idempotency-Key header; use the Idempotency
+	// middleware directly to apply it to individual route groups
+	Idempotency *IdempotencyConfig
+	// Compression enables gzip/brotli response compression. It is mounted inside
+	// the request logger, so logged sizes are uncompressed.
+	Compression *CompressionConfig
+	// MaxRequestBodyBytes limits request bodies server-wide (default: 10MB, negative
+	// disables); use LimitRequestBody to set a different limit on a route group
+	MaxRequestBodyBytes int64
+	// Debug mounts pprof and expvar handlers under /debug/; on the
+	// application port they need DebugConfig.Authorize
+	Debug *DebugConfig
+	// Admin serves health, metrics and debug endpoints on a separate port
+	// instead of the application port
+	Admin *AdminConfig
+	// AccessLog configures skipped paths and sampling for the request log
+	AccessLog *AccessLogConfig
+}
+
+// auxServer is an additional listener, such as a private metrics port, that is
+// started and stopped with the main server
+type auxServer struct {
+	name   string
+	server *http.Server
+}
+
+func newAuxServer(name string, port int, handler http.Handler) *auxServer {
+	return &auxServer{
+		name: name,
+		server: &http.Server{
+			Addr:              fmt.Sprintf(":%d", port),
+			Handler:           handler,
+			ErrorLog:          log.New(io.Discard, "", 0),
+			ReadHeaderTimeout: defaultReadHeaderTimeout,
+			IdleTimeout:       defaultIdleTimeout,
+		},
+	}
 }
 
-// customLogFormatter skips logging for health check endpoints
+// Server timeout and header limit defaults
+const (
+	defaultReadTimeout       = 60 * time.Second
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultWriteTimeout      = 60 * time.Second
+	defaultIdleTimeout       = 120 * time.Second
+	defaultMaxHeaderBytes    = 1 << 20
+)
+
+// withDefault returns def for zero, zero (no limit) for negative, and v otherwise
+func withDefault[T time.Duration | int | int64](v, def T) T {
+	switch {
+	case v == 0:
+		return def
+	case v < 0:
+		return 0
+	}
+	return v
+}
+
+// customLogFormatter skips logging for health check endpoints and other configured paths
 type customLogFormatter struct {
 	Logger  *logrus.Logger
 	NoColor bool
+	filter  *accessLogFilter
 }
 
 func (l *customLogFormatter) NewLogEntry(r *http.Request) middleware.LogEntry {
 	// Skip logging for health check endpoints
-	if r.URL.Path == "/healthz" || r.URL.Path == "/" {
+	if l.filter.skip(r.URL.Path) {
 		return &noopLogEntry{}
 	}
 
@@ -42,6 +205,8 @@ func (l *customLogFormatter) NewLogEntry(r *http.Request) middleware.LogEntry {
 	return &defaultLogEntry{
 		Logger:  l.Logger,
 		NoColor: l.NoColor,
+		filter:  l.filter,
+		request: r,
 	}
 }
 
@@ -60,20 +225,22 @@ func (e *noopLogEntry) Panic(v interface{}, stack []byte) {
 type defaultLogEntry struct {
 	Logger  *logrus.Logger
 	NoColor bool
+	filter  *accessLogFilter
+	request *http.Request
 }
 
 func (e *defaultLogEntry) Write(status, bytes int, header http.Header, elapsed time.Duration, extra interface{}) {
-	e.Logger.WithFields(logrus.Fields{
-		"status":  status,
-		"bytes":   bytes,
-		"elapsed": elapsed,
-	}).Info("HTTP request completed")
+	if !e.filter.sampled(status) {
+		return
+	}
+	e.Logger.WithFields(e.filter.requestFields(e.request, status, bytes, elapsed)).Info("HTTP request completed")
 }
 
 func (e *defaultLogEntry) Panic(v interface{}, stack []byte) {
 	e.Logger.WithFields(logrus.Fields{
-		"panic": v,
-		"stack": string(stack),
+		"panic":      v,
+		"stack":      string(stack),
+		"request_id": RequestIDFromContext(e.request.Context()),
 	}).Error("HTTP request panic")
 }
 
@@ -81,27 +248,153 @@ func (s *EasyGoHTTPServer) GetHttpServer() *http.Server {
 	return s.server
 }
 
-func NewEasyGoHTTPServer(args *NewEasyGoHTTPServerArgs) *EasyGoHTTPServer {
+func NewEasyGoHTTPServer(args *NewEasyGoHTTPServerArgs, opts ...Option) *EasyGoHTTPServer {
+	if args == nil {
+		args = &NewEasyGoHTTPServerArgs{}
+	}
+	o := &serverOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
 	if args.Logger == nil {
 		args.Logger = logrus.New()
 		args.Logger.SetFormatter(&logrus.JSONFormatter{})
+		runtimeinfo.InstallFields(args.Logger)
 	}
 
+	requestIDHeader := args.RequestIDHeader
+	if requestIDHeader == "" {
+		requestIDHeader = DefaultRequestIDHeader
+	}
+
+	drain := &drainState{delay: args.DrainDelay}
+
 	r := chi.NewRouter()
+	r.Use(drain.track)
+	r.Use(o.middleware...)
+	r.Use(requestIDMiddleware(requestIDHeader))
+	// Tracing runs before the request logger so it can read the span context
+	if args.Tracing != nil {
+		r.Use(tracingMiddleware(args.Tracing))
+	}
+	// Metrics wrap the middleware below so rate limited, rejected and
+	// recovered responses are counted too
+	if args.Metrics != nil {
+		r.Use(metricsMiddleware(args.Metrics))
+	}
+	r.Use(contextLoggerMiddleware(args.Logger))
 	// Create a custom logger that skips health check endpoints
-	r.Use(middleware.RequestLogger(&customLogFormatter{
-		Logger:  args.Logger,
-		NoColor: true,
-	}))
+	if !o.noDefaultLogger {
+		r.Use(middleware.RequestLogger(&customLogFormatter{
+			Logger:  args.Logger,
+			NoColor: true,
+			filter:  newAccessLogFilter(args.AccessLog),
+		}))
+	}
+	hooks := &lifecycleHooks{}
+	if args.ErrorSink != nil {
+		hooks.panic = append(hooks.panic, args.ErrorSink)
+	}
+	r.Use(recovererMiddleware(args.Logger, hooks.reportPanic))
+	if args.ClientCert != nil {
+		r.Use(clientCertMiddleware(args.ClientCert))
+	}
+	if args.CORS != nil {
+		r.Use(corsMiddleware(args.CORS, requestIDHeader))
+	}
+	if args.RateLimit != nil {
+		r.Use(RateLimit(args.RateLimit))
+	}
+	if limit := withDefault(args.MaxRequestBodyBytes, defaultMaxRequestBodyBytes); limit > 0 {
+		r.Use(LimitRequestBody(limit))
+	}
+	if args.Compression != nil {
+		r.Use(Compress(args.Compression))
+	}
+	// inside compression, so stored responses are uncompressed and replays are
+	// encoded for each client, and inside the body limit, which bounds the
+	// body read for the fingerprint
+	if args.Idempotency != nil {
+		r.Use(Idempotency(args.Idempotency))
+	}
+	for _, fn := range o.routerConfigs {
+		fn(r)
+	}
+
+	// operational endpoints go on the admin router when there is one
+	var auxServers []*auxServer
+	var admin *chi.Mux
+	operational := chi.Router(r)
+	if args.Admin != nil {
+		admin = newAdminRouter(args.Admin, args.Logger, hooks.reportPanic, requestIDHeader)
+		operational = admin
+	}
+
+	if args.Metrics != nil {
+		if aux := configureMetrics(operational, args.Metrics); aux != nil {
+			auxServers = append(auxServers, aux)
+		}
+	}
+	if args.Debug != nil {
+		if aux := configureDebug(operational, args.Debug, admin != nil); aux != nil {
+			auxServers = append(auxServers, aux)
+		}
+	}
+
+	maintenance := &maintenanceState{}
+	app := chi.NewRouter()
+	app.Use(maintenance.middleware)
+	r.Mount("/", app)
 
 	server := &http.Server{
-		Addr:     fmt.Sprintf(":%d", args.Port),
-		Handler:  r,
-		ErrorLog: log.New(io.Discard, "", 0), // Disable default logging
+		Addr:              fmt.Sprintf(":%d", args.Port),
+		Handler:           r,
+		ErrorLog:          log.New(io.Discard, "", 0), // Disable default logging
+		ReadTimeout:       withDefault(args.ReadTimeout, defaultReadTimeout),
+		ReadHeaderTimeout: withDefault(args.ReadHeaderTimeout, defaultReadHeaderTimeout),
+		WriteTimeout:      withDefault(args.WriteTimeout, defaultWriteTimeout),
+		IdleTimeout:       withDefault(args.IdleTimeout, defaultIdleTimeout),
+		MaxHeaderBytes:    withDefault(args.MaxHeaderBytes, defaultMaxHeaderBytes),
+	}
+
+	tlsEnabled := configureTLS(server, args)
+	configureHTTP2(server, args)
+
+	if args.UnixSocketMode == 0 {
+		args.UnixSocketMode = defaultUnixSocketMode
 	}
 
-	return &EasyGoHTTPServer{
-		server: server,
-		Chi:    r,
+	shutdownTimeout := args.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 30 * time.Second
 	}
+
+	s := &EasyGoHTTPServer{
+		server:          server,
+		Chi:             app,
+		logger:          args.Logger,
+		shutdownTimeout: shutdownTimeout,
+		hooks:           hooks,
+		drain:           drain,
+		maintenance:     maintenance,
+		startTimeout:    withDefault(args.StartTimeout, 30*time.Second),
+		health:          newHealthRegistry(args.HealthCheckTimeout, args.HealthRegistry),
+		auxServers:      auxServers,
+		listener:        args.Listener,
+		unixSocket:      args.UnixSocket,
+		unixSocketMode:  args.UnixSocketMode,
+		tls:             tlsEnabled,
+		tlsCertFile:     args.TLSCertFile,
+		tlsKeyFile:      args.TLSKeyFile,
+	}
+
+	if !args.DisableHealthEndpoints {
+		operational.Method(http.MethodGet, "/healthz", s.LivenessHandler())
+		operational.Method(http.MethodGet, "/readyz", s.ReadinessHandler())
+	}
+	if admin != nil {
+		configureAdmin(s, admin, args.Admin)
+	}
+
+	return s
 }
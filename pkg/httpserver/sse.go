@@ -0,0 +1,175 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// SSEOptions configures a server-sent events stream
+type SSEOptions struct {
+	// HeartbeatInterval is how often a comment is sent to keep proxies from
+	// closing an idle stream (default: 15s, negative disables)
+	HeartbeatInterval time.Duration
+	// Retry tells clients how long to wait before reconnecting
+	Retry time.Duration
+}
+
+// SSEStream writes server-sent events to a client
+type SSEStream struct {
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+	ctx context.Context
+
+	mu     sync.Mutex
+	stop   chan struct{}
+	closed bool
+}
+
+// NewSSEStream starts a text/event-stream response. The server write timeout
+// is lifted for the stream; it ends when the client disconnects (Done) or the
+// handler returns. Call Close (typically deferred) before the handler returns
+// to stop the heartbeat.
+func NewSSEStream(w http.ResponseWriter, r *http.Request, opts *SSEOptions) (*SSEStream, error) {
+	o := SSEOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.HeartbeatInterval == 0 {
+		o.HeartbeatInterval = 15 * time.Second
+	}
+
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return nil, errs.Errorf("failed to clear write deadline: %w", err)
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	// disable response buffering in nginx
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	s := &SSEStream{w: w, rc: rc, ctx: r.Context(), stop: make(chan struct{})}
+	if o.Retry > 0 {
+		if err := s.write(fmt.Sprintf("retry: %d\n\n", o.Retry.Milliseconds())); err != nil {
+			return nil, err
+		}
+	} else if err := s.flush(); err != nil {
+		return nil, err
+	}
+	if o.HeartbeatInterval > 0 {
+		go s.heartbeat(o.HeartbeatInterval)
+	}
+
+	return s, nil
+}
+
+// LastEventID returns the ID of the last event a reconnecting client received
+func LastEventID(r *http.Request) string {
+	return r.Header.Get("Last-Event-ID")
+}
+
+// Done is closed when the client disconnects
+func (s *SSEStream) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Send writes an event. Strings and byte slices are sent as is; other values
+// are marshaled to JSON. An empty event name sends an unnamed "message" event.
+func (s *SSEStream) Send(event string, data any) error {
+	return s.SendWithID("", event, data)
+}
+
+// SendWithID writes an event with an ID clients echo back in Last-Event-ID on reconnect
+func (s *SSEStream) SendWithID(id, event string, data any) error {
+	var payload string
+	switch v := data.(type) {
+	case string:
+		payload = v
+	case []byte:
+		payload = string(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return errs.Errorf("failed to marshal event data: %w", err)
+		}
+		payload = string(b)
+	}
+
+	var b strings.Builder
+	if id != "" {
+		fmt.Fprintf(&b, "id: %s\n", sanitizeSSEField(id))
+	}
+	if event != "" {
+		fmt.Fprintf(&b, "event: %s\n", sanitizeSSEField(event))
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(payload, "\r\n", "\n"), "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+
+	return s.write(b.String())
+}
+
+// Close stops the heartbeat; further sends fail
+func (s *SSEStream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.stop)
+	}
+}
+
+func (s *SSEStream) heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.write(": ping\n\n"); err != nil {
+				return
+			}
+		case <-s.stop:
+			return
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *SSEStream) write(msg string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errs.New(errs.Internal, "event stream is closed")
+	}
+	if err := s.ctx.Err(); err != nil {
+		return errs.Errorf("client disconnected: %w", err)
+	}
+	if _, err := fmt.Fprint(s.w, msg); err != nil {
+		return errs.Errorf("failed to write event: %w", err)
+	}
+	return s.rc.Flush()
+}
+
+func (s *SSEStream) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rc.Flush()
+}
+
+// sanitizeSSEField strips newlines, which would end the field early
+func sanitizeSSEField(v string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(v)
+}
@@ -0,0 +1,83 @@
+package httpserver
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Cache-Control values for static content
+const (
+	staticCacheControl    = "public, max-age=3600"
+	immutableCacheControl = "public, max-age=31536000, immutable"
+	noCacheControl        = "no-cache"
+)
+
+// hashedAssetPattern matches fingerprinted names such as app.3f2a9c1b.js or
+// chunk-5KQ2ZJ7M.css produced by bundlers, which are safe to cache forever
+var hashedAssetPattern = regexp.MustCompile(`[.-][0-9A-Za-z_]{8,}\.[a-z0-9]+$`)
+
+// ServeStatic serves fsys under prefix, e.g. ServeStatic("/assets", assetsFS).
+// Fingerprinted files are cached as immutable, HTML is revalidated on every
+// request and everything else is cached for an hour. Use fs.Sub to serve a
+// subdirectory of an embed.FS.
+func (s *EasyGoHTTPServer) ServeStatic(prefix string, fsys fs.FS) {
+	prefix = "/" + strings.Trim(prefix, "/")
+	handler := staticHandler(fsys)
+	if prefix == "/" {
+		s.Chi.Handle("/*", handler)
+		return
+	}
+	s.Chi.Handle(prefix+"/*", http.StripPrefix(prefix, handler))
+}
+
+// ServeSPA serves a single-page app from fsys at the root. Requests for paths
+// that are not files fall back to indexFile (default: index.html) so
+// client-side history routing works; routes registered on the router take
+// precedence.
+func (s *EasyGoHTTPServer) ServeSPA(fsys fs.FS, indexFile string) {
+	if indexFile == "" {
+		indexFile = "index.html"
+	}
+	files := staticHandler(fsys)
+
+	s.Chi.Handle("/*", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+		if name != "" {
+			if info, err := fs.Stat(fsys, name); err == nil && !info.IsDir() {
+				files.ServeHTTP(w, r)
+				return
+			} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				writeProblem(w, r, http.StatusInternalServerError, "internal server error")
+				return
+			}
+			// missing asset files are real 404s, not app routes
+			if path.Ext(name) != "" && !strings.HasSuffix(name, ".html") {
+				http.NotFound(w, r)
+				return
+			}
+		}
+
+		w.Header().Set("Cache-Control", noCacheControl)
+		http.ServeFileFS(w, r, fsys, indexFile)
+	}))
+}
+
+func staticHandler(fsys fs.FS) http.Handler {
+	fileServer := http.FileServerFS(fsys)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Base(r.URL.Path)
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/") || strings.HasSuffix(name, ".html"):
+			w.Header().Set("Cache-Control", noCacheControl)
+		case hashedAssetPattern.MatchString(name):
+			w.Header().Set("Cache-Control", immutableCacheControl)
+		default:
+			w.Header().Set("Cache-Control", staticCacheControl)
+		}
+		fileServer.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,305 @@
+package httpserver
+
+import (
+	"bytes"
+	"errors"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/sirupsen/logrus"
+)
+
+// Template directories and names recognised by Renderer
+const (
+	layoutsDir          = "layouts"
+	partialsDir         = "partials"
+	contentTemplate     = "content"
+	defaultLayout       = "layouts/base"
+	defaultErrorPage    = "error"
+	htmlTemplateExt     = ".html"
+	textTemplateExt     = ".txt"
+	htmlTemplateContent = "text/html; charset=utf-8"
+	textTemplateContent = "text/plain; charset=utf-8"
+)
+
+type NewRendererArgs struct {
+	// FS holds the templates, typically an embed.FS; see Renderer for the
+	// layout (required unless DevDir is set)
+	FS fs.FS
+	// DevDir reads templates from this directory instead of FS and reparses
+	// them on every render, so edits show without a restart. Set it only in
+	// development, e.g. from an environment variable.
+	DevDir string
+	// Layout is the layout pages that define "content" render in
+	// (default: layouts/base)
+	Layout string
+	// ErrorPage is the page Error renders, and Render falls back to when a
+	// template fails, with ErrorPageData (default: error)
+	ErrorPage string
+	// Funcs are available to every template
+	Funcs map[string]any
+	// Logger logs template failures (default: not logged)
+	Logger logrus.FieldLogger
+}
+
+// ErrorPageData is passed to the error page
+type ErrorPageData struct {
+	Status    int
+	Title     string
+	Message   string
+	RequestID string
+}
+
+// Renderer renders pages from a directory of templates:
+//
+//	layouts/base.html   layouts; base executes {{block "content" .}}{{end}}
+//	partials/nav.html   partials, included with {{template "partials/nav" .}}
+//	users/index.html    pages, rendered with Render(w, 200, "users/index", data)
+//	error.html          the error page
+//
+// Templates are named by their path without the extension. Pages that
+// {{define "content"}} render inside the layout; other pages render on their
+// own, e.g. fragments for htmx. .html files use html/template and .txt files
+// use text/template, each with the layouts and partials of the same kind.
+type Renderer struct {
+	args *NewRendererArgs
+
+	mu    sync.Mutex
+	pages map[string]*renderPage
+}
+
+type renderPage struct {
+	execute     func(w io.Writer, name string, data any) error
+	contentType string
+	// entry is the template executed for the page: the layout or the page
+	entry string
+}
+
+// NewRenderer parses the templates, returning an error for invalid ones
+func NewRenderer(args *NewRendererArgs) (*Renderer, error) {
+	if args == nil || (args.FS == nil && args.DevDir == "") {
+		return nil, errs.New(errs.Internal, "renderer: FS or DevDir is required")
+	}
+	a := *args
+	if a.Layout == "" {
+		a.Layout = defaultLayout
+	}
+	if a.ErrorPage == "" {
+		a.ErrorPage = defaultErrorPage
+	}
+	if a.DevDir != "" {
+		a.FS = os.DirFS(a.DevDir)
+	}
+	r := &Renderer{args: &a}
+	pages, err := r.parse()
+	if err != nil {
+		return nil, err
+	}
+	r.pages = pages
+	return r, nil
+}
+
+// Render renders page name with data and writes it with status. The page is
+// rendered to a buffer first, so a failing template writes the error page
+// instead of a partial response.
+func (rd *Renderer) Render(w http.ResponseWriter, status int, name string, data any) {
+	body, contentType, err := rd.execute(name, data)
+	if err != nil {
+		rd.log(err, name)
+		rd.writeError(w, "", http.StatusInternalServerError, "")
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// Error renders the error page for status, e.g. from NotFound handlers.
+// message defaults to the status text.
+func (rd *Renderer) Error(w http.ResponseWriter, r *http.Request, status int, message string) {
+	rd.writeError(w, RequestIDFromContext(r.Context()), status, message)
+}
+
+func (rd *Renderer) writeError(w http.ResponseWriter, requestID string, status int, message string) {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	data := &ErrorPageData{Status: status, Title: http.StatusText(status), Message: message, RequestID: requestID}
+	body, contentType, err := rd.execute(rd.args.ErrorPage, data)
+	if err != nil {
+		if !errors.Is(err, errNoPage) {
+			rd.log(err, rd.args.ErrorPage)
+		}
+		http.Error(w, message, status)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+var errNoPage = errs.New(errs.NotFound, "template not found")
+
+func (rd *Renderer) execute(name string, data any) ([]byte, string, error) {
+	pages, err := rd.current()
+	if err != nil {
+		return nil, "", err
+	}
+	p, ok := pages[name]
+	if !ok {
+		return nil, "", errs.Errorf("page %q: %w", name, errNoPage)
+	}
+	var buf bytes.Buffer
+	if err := p.execute(&buf, p.entry, data); err != nil {
+		return nil, "", errs.Errorf("failed to render %s: %w", name, err)
+	}
+	return buf.Bytes(), p.contentType, nil
+}
+
+// current returns the parsed pages, reparsing them in dev mode
+func (rd *Renderer) current() (map[string]*renderPage, error) {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	if rd.args.DevDir != "" {
+		pages, err := rd.parse()
+		if err != nil {
+			return nil, err
+		}
+		rd.pages = pages
+	}
+	return rd.pages, nil
+}
+
+func (rd *Renderer) log(err error, name string) {
+	if rd.args.Logger != nil {
+		rd.args.Logger.WithError(err).WithField("template", name).Error("failed to render template")
+	}
+}
+
+// templateFile is a template source named by its path without extension
+type templateFile struct {
+	name string
+	text string
+}
+
+// parse reads every template and builds a template set per page
+func (rd *Renderer) parse() (map[string]*renderPage, error) {
+	shared := map[string][]templateFile{}
+	pageFiles := map[string][]templateFile{}
+	err := fs.WalkDir(rd.args.FS, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		ext := path.Ext(p)
+		if ext != htmlTemplateExt && ext != textTemplateExt {
+			return nil
+		}
+		b, err := fs.ReadFile(rd.args.FS, p)
+		if err != nil {
+			return errs.Errorf("failed to read template %s: %w", p, err)
+		}
+		f := templateFile{name: strings.TrimSuffix(p, ext), text: string(b)}
+		if strings.HasPrefix(p, layoutsDir+"/") || strings.HasPrefix(p, partialsDir+"/") {
+			shared[ext] = append(shared[ext], f)
+		} else {
+			pageFiles[ext] = append(pageFiles[ext], f)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errs.Errorf("failed to load templates: %w", err)
+	}
+
+	pages := map[string]*renderPage{}
+	for _, f := range pageFiles[htmlTemplateExt] {
+		p, err := rd.parseHTML(shared[htmlTemplateExt], f)
+		if err != nil {
+			return nil, err
+		}
+		pages[f.name] = p
+	}
+	for _, f := range pageFiles[textTemplateExt] {
+		if _, ok := pages[f.name]; ok {
+			return nil, errs.Newf(errs.Internal, "template %s exists as both %s and %s", f.name, htmlTemplateExt, textTemplateExt)
+		}
+		p, err := rd.parseText(shared[textTemplateExt], f)
+		if err != nil {
+			return nil, err
+		}
+		pages[f.name] = p
+	}
+	return pages, nil
+}
+
+func (rd *Renderer) parseHTML(shared []templateFile, page templateFile) (*renderPage, error) {
+	t := htmltemplate.New(page.name).Funcs(rd.args.Funcs)
+	parse := func(f templateFile) error {
+		tt := t
+		if f.name != page.name {
+			tt = t.New(f.name)
+		}
+		_, err := tt.Parse(f.text)
+		return err
+	}
+	lookup := func(name string) any {
+		if tt := t.Lookup(name); tt != nil {
+			return tt.Tree
+		}
+		return nil
+	}
+	entry, err := rd.parsePage(shared, page, parse, lookup)
+	if err != nil {
+		return nil, err
+	}
+	return &renderPage{execute: t.ExecuteTemplate, contentType: htmlTemplateContent, entry: entry}, nil
+}
+
+func (rd *Renderer) parseText(shared []templateFile, page templateFile) (*renderPage, error) {
+	t := texttemplate.New(page.name).Funcs(rd.args.Funcs)
+	parse := func(f templateFile) error {
+		tt := t
+		if f.name != page.name {
+			tt = t.New(f.name)
+		}
+		_, err := tt.Parse(f.text)
+		return err
+	}
+	lookup := func(name string) any {
+		if tt := t.Lookup(name); tt != nil {
+			return tt.Tree
+		}
+		return nil
+	}
+	entry, err := rd.parsePage(shared, page, parse, lookup)
+	if err != nil {
+		return nil, err
+	}
+	return &renderPage{execute: t.ExecuteTemplate, contentType: textTemplateContent, entry: entry}, nil
+}
+
+// parsePage parses the layouts and partials, then page, and returns the
+// template to execute: the layout when page defines its content block
+func (rd *Renderer) parsePage(shared []templateFile, page templateFile, parse func(templateFile) error, lookup func(name string) any) (string, error) {
+	for _, f := range shared {
+		if err := parse(f); err != nil {
+			return "", errs.Errorf("failed to parse template %s: %w", f.name, err)
+		}
+	}
+	// layouts may declare a default content block, which pages replace
+	layoutContent := lookup(contentTemplate)
+	if err := parse(page); err != nil {
+		return "", errs.Errorf("failed to parse template %s: %w", page.name, err)
+	}
+	if content := lookup(contentTemplate); content != nil && content != layoutContent && lookup(rd.args.Layout) != nil {
+		return rd.args.Layout, nil
+	}
+	return page.name, nil
+}
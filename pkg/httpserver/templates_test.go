@@ -0,0 +1,75 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var testTemplates = fstest.MapFS{
+	"layouts/base.html": {Data: []byte(`<title>{{block "title" .}}App{{end}}</title>{{template "partials/nav" .}}<main>{{block "content" .}}{{end}}</main>`)},
+	"partials/nav.html": {Data: []byte(`<nav>{{.User}}</nav>`)},
+	"users/index.html":  {Data: []byte(`{{define "title"}}Users{{end}}{{define "content"}}<p>{{shout .User}}</p>{{end}}`)},
+	"users/row.html":    {Data: []byte(`<tr><td>{{.User}}</td></tr>`)},
+	"broken.html":       {Data: []byte(`{{define "content"}}{{template "partials/missing" .}}{{end}}`)},
+	"error.html":        {Data: []byte(`<h1>{{.Status}} {{.Title}}</h1><p>{{.Message}}</p>`)},
+	"report.txt":        {Data: []byte(`Hello {{.User}} & co`)},
+}
+
+func TestRenderer(t *testing.T) {
+	rd, err := NewRenderer(&NewRendererArgs{
+		FS:    testTemplates,
+		Funcs: map[string]any{"shout": strings.ToUpper},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := map[string]string{"User": "<ann>"}
+
+	for _, tc := range []struct {
+		name, want, contentType string
+		status                  int
+	}{
+		{"users/index", `<title>Users</title><nav>&lt;ann&gt;</nav><main><p>&lt;ANN&gt;</p></main>`, "text/html; charset=utf-8", 200},
+		{"users/row", `<tr><td>&lt;ann&gt;</td></tr>`, "text/html; charset=utf-8", 200},
+		{"report", `Hello <ann> & co`, "text/plain; charset=utf-8", 200},
+		{"broken", `<h1>500 Internal Server Error</h1><p>Internal Server Error</p>`, "text/html; charset=utf-8", 500},
+		{"missing", `<h1>500 Internal Server Error</h1><p>Internal Server Error</p>`, "text/html; charset=utf-8", 500},
+	} {
+		rec := httptest.NewRecorder()
+		rd.Render(rec, http.StatusOK, tc.name, data)
+		if rec.Code != tc.status || rec.Body.String() != tc.want || rec.Header().Get("Content-Type") != tc.contentType {
+			t.Errorf("%s: got %d %q %q", tc.name, rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	rd.Error(rec, httptest.NewRequest(http.MethodGet, "/nope", nil), http.StatusNotFound, "no such page")
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "no such page") {
+		t.Fatalf("got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRendererDevReload(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "hello.html")
+	if err := os.WriteFile(page, []byte(`v1`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rd, err := NewRenderer(&NewRendererArgs{DevDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(page, []byte(`v2`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	rd.Render(rec, http.StatusOK, "hello", nil)
+	if rec.Body.String() != "v2" {
+		t.Fatalf("got %q, want the edited template", rec.Body.String())
+	}
+}
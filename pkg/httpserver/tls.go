@@ -0,0 +1,78 @@
+package httpserver
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// AutoCertConfig configures automatic certificates via ACME
+type AutoCertConfig struct {
+	// Hosts is the allowlist of host names certificates may be requested for (required)
+	Hosts []string
+	// CacheDir stores issued certificates across restarts; without it every
+	// restart requests new certificates and quickly hits rate limits
+	CacheDir string
+	// Email is the optional contact address for the ACME account
+	Email string
+	// DirectoryURL overrides the ACME directory, e.g. the Let's Encrypt staging URL
+	DirectoryURL string
+}
+
+// configureTLS sets up server.TLSConfig from args and reports whether TLS is enabled
+func configureTLS(server *http.Server, args *NewEasyGoHTTPServerArgs) bool {
+	enabled := false
+	if args.TLSConfig != nil {
+		server.TLSConfig = args.TLSConfig.Clone()
+		enabled = true
+	}
+	if args.TLSCertFile != "" && args.TLSKeyFile != "" {
+		enabled = true
+	}
+
+	if args.AutoCert != nil {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(args.AutoCert.Hosts...),
+			Email:      args.AutoCert.Email,
+		}
+		if args.AutoCert.CacheDir != "" {
+			manager.Cache = autocert.DirCache(args.AutoCert.CacheDir)
+		}
+		if args.AutoCert.DirectoryURL != "" {
+			manager.Client = &acme.Client{DirectoryURL: args.AutoCert.DirectoryURL}
+		}
+
+		autoCfg := manager.TLSConfig()
+		if server.TLSConfig != nil {
+			server.TLSConfig.GetCertificate = autoCfg.GetCertificate
+			server.TLSConfig.NextProtos = append(server.TLSConfig.NextProtos, autoCfg.NextProtos...)
+		} else {
+			server.TLSConfig = autoCfg
+		}
+		enabled = true
+	}
+
+	if enabled && server.TLSConfig == nil {
+		server.TLSConfig = &tls.Config{}
+	}
+	if server.TLSConfig != nil && server.TLSConfig.MinVersion == 0 {
+		server.TLSConfig.MinVersion = tls.VersionTLS12
+	}
+	if enabled && args.ClientCert != nil {
+		configureClientCerts(server.TLSConfig, args.ClientCert)
+	}
+
+	return enabled
+}
+
+// ListenAndServeTLS serves HTTPS using the configured certificate files, TLSConfig, or AutoCert
+func (s *EasyGoHTTPServer) ListenAndServeTLS() error {
+	l, err := s.listen()
+	if err != nil {
+		return err
+	}
+	return s.server.ServeTLS(l, s.tlsCertFile, s.tlsKeyFile)
+}
@@ -0,0 +1,53 @@
+package httpserver
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingConfig enables an OpenTelemetry span per request
+type TracingConfig struct {
+	// TracerProvider defaults to the global provider
+	TracerProvider trace.TracerProvider
+	// Propagators extract trace context from incoming headers (default: the
+	// global propagator, usually W3C tracecontext and baggage)
+	Propagators propagation.TextMapPropagator
+	// ServerName is recorded as the server.address span attribute
+	ServerName string
+}
+
+// tracingMiddleware starts a span per request and renames it to
+// "METHOD /route/{pattern}" once chi has matched the route
+func tracingMiddleware(cfg *TracingConfig) func(http.Handler) http.Handler {
+	var opts []otelhttp.Option
+	if cfg.TracerProvider != nil {
+		opts = append(opts, otelhttp.WithTracerProvider(cfg.TracerProvider))
+	}
+	if cfg.Propagators != nil {
+		opts = append(opts, otelhttp.WithPropagators(cfg.Propagators))
+	}
+	opts = append(opts, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method
+	}))
+	if cfg.ServerName != "" {
+		opts = append(opts, otelhttp.WithServerName(cfg.ServerName))
+	}
+
+	return func(next http.Handler) http.Handler {
+		named := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				span := trace.SpanFromContext(r.Context())
+				span.SetName(r.Method + " " + rctx.RoutePattern())
+				span.SetAttributes(attribute.String("http.route", rctx.RoutePattern()))
+			}
+		})
+		return otelhttp.NewHandler(named, "http.server", opts...)
+	}
+}
@@ -0,0 +1,46 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bdlilley/easygo/pkg/validate"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes one failed validation rule
+type FieldError = validate.FieldError
+
+// RegisterValidation adds a custom validate tag used by Bind, e.g.
+//
+//	httpserver.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+//		return slugPattern.MatchString(fl.Field().String())
+//	})
+//
+// Register validations during initialization, before the server handles
+// requests. Tags are shared with pkg/validate.
+func RegisterValidation(tag string, fn validator.Func) error {
+	return validate.Register(tag, fn)
+}
+
+// Validator returns the validator used by Bind for advanced configuration
+// such as struct-level validations or aliases
+func Validator() *validator.Validate {
+	return validate.Engine()
+}
+
+// Validate checks v with validate.Struct: its validate struct tags, including
+// the AWS tags from pkg/validate, then its Validate method if it has one. Failures
+// return a 422 *BindError with field details. Values that are not structs
+// are not validated.
+func Validate(v any) error {
+	err := validate.Struct(v)
+	if err == nil {
+		return nil
+	}
+	var verrs validate.Errors
+	if !errors.As(err, &verrs) {
+		return &BindError{Status: http.StatusUnprocessableEntity, Message: "validation failed", Err: err}
+	}
+	return &BindError{Status: http.StatusUnprocessableEntity, Message: "validation failed", Fields: verrs}
+}
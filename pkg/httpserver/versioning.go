@@ -0,0 +1,141 @@
+package httpserver
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// APIVersion declares the routes of one API version
+type APIVersion struct {
+	// Name is the path segment, e.g. "v1"
+	Name string
+	// Routes registers the version's routes relative to the version prefix
+	Routes func(r chi.Router)
+	// Middleware runs only for this version
+	Middleware []func(http.Handler) http.Handler
+
+	// Deprecated adds a Deprecation header to every response of the version;
+	// DeprecatedAt is reported as the deprecation date when set
+	Deprecated   bool
+	DeprecatedAt time.Time
+	// Sunset is when the version will be removed, sent as the Sunset header
+	Sunset time.Time
+	// DocsURL is linked from deprecated responses with rel="deprecation"
+	DocsURL string
+}
+
+// APIVersionsConfig mounts a set of versions under a common prefix
+type APIVersionsConfig struct {
+	// Prefix is the base path, e.g. "/api"
+	Prefix   string
+	Versions []APIVersion
+	// Default serves unversioned requests under Prefix that do not ask for a
+	// version in the Accept header (default: the last version)
+	Default string
+}
+
+type apiVersionKey struct{}
+
+// APIVersionFromContext returns the name of the API version serving the request
+func APIVersionFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(apiVersionKey{}).(string)
+	return v
+}
+
+// vendorVersionPattern matches media types such as application/vnd.acme.v2+json
+var vendorVersionPattern = regexp.MustCompile(`^application/vnd\.[^.]+(?:\.[^.]+)*\.(v[0-9]+)\+json$`)
+
+// APIVersions mounts each version at Prefix/<name>. Requests to Prefix without
+// a version segment are routed by the Accept header, either a vendor media type
+// (application/vnd.acme.v2+json) or a version parameter (application/json;
+// version=2), falling back to the default version.
+func (s *EasyGoHTTPServer) APIVersions(cfg *APIVersionsConfig) {
+	prefix := "/" + strings.Trim(cfg.Prefix, "/")
+	if prefix == "/" {
+		prefix = ""
+	}
+
+	routers := make(map[string]http.Handler, len(cfg.Versions))
+	for _, v := range cfg.Versions {
+		vr := chi.NewRouter()
+		vr.Use(apiVersionMiddleware(v))
+		vr.Use(v.Middleware...)
+		if v.Routes != nil {
+			v.Routes(vr)
+		}
+		routers[v.Name] = vr
+		s.Chi.Mount(prefix+"/"+v.Name, vr)
+	}
+
+	def := cfg.Default
+	if def == "" && len(cfg.Versions) > 0 {
+		def = cfg.Versions[len(cfg.Versions)-1].Name
+	}
+	if prefix == "" || routers[def] == nil {
+		return
+	}
+
+	s.Chi.Mount(prefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := negotiateAPIVersion(r.Header.Get("Accept"))
+		if name == "" {
+			name = def
+		}
+		h, ok := routers[name]
+		if !ok {
+			writeProblem(w, r, http.StatusNotAcceptable, fmt.Sprintf("API version %q is not supported", name))
+			return
+		}
+		h.ServeHTTP(w, r)
+	}))
+}
+
+// negotiateAPIVersion extracts a version name such as "v2" from an Accept header
+func negotiateAPIVersion(accept string) string {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if m := vendorVersionPattern.FindStringSubmatch(mediaType); m != nil {
+			return m[1]
+		}
+		if v := params["version"]; v != "" {
+			if !strings.HasPrefix(v, "v") {
+				v = "v" + v
+			}
+			return v
+		}
+	}
+	return ""
+}
+
+// apiVersionMiddleware records the version in the context and adds RFC 9745
+// Deprecation and RFC 8594 Sunset headers
+func apiVersionMiddleware(v APIVersion) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			if v.Deprecated {
+				if v.DeprecatedAt.IsZero() {
+					h.Set("Deprecation", "true")
+				} else {
+					h.Set("Deprecation", fmt.Sprintf("@%d", v.DeprecatedAt.Unix()))
+				}
+				if v.DocsURL != "" {
+					h.Add("Link", fmt.Sprintf(`<%s>; rel="deprecation"`, v.DocsURL))
+				}
+			}
+			if !v.Sunset.IsZero() {
+				h.Set("Sunset", v.Sunset.UTC().Format(http.TimeFormat))
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiVersionKey{}, v.Name)))
+		})
+	}
+}
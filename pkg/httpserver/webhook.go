@@ -0,0 +1,292 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/bdlilley/easygo/pkg/webhooks"
+)
+
+// ErrWebhookSignature is returned by verifiers for a missing or wrong signature
+var ErrWebhookSignature = errs.New(errs.Unauthenticated, "invalid webhook signature")
+
+// ErrWebhookTimestamp is returned by verifiers for a signed timestamp outside
+// the tolerance, which usually means a replayed request
+var ErrWebhookTimestamp = errs.New(errs.Unauthenticated, "webhook timestamp outside tolerance")
+
+// defaultWebhookTolerance is how far signed timestamps may be from now
+const defaultWebhookTolerance = 5 * time.Minute
+
+// WebhookVerifier checks the signature of a webhook request over its raw
+// body, returning an error wrapping ErrWebhookSignature or ErrWebhookTimestamp
+type WebhookVerifier func(r *http.Request, body []byte) error
+
+// HMACWebhookConfig configures a generic HMAC webhook verifier
+type HMACWebhookConfig struct {
+	// Secrets are tried in order, so secrets can be rotated (required, none
+	// empty)
+	Secrets []string
+	// Header carries the signature (required)
+	Header string
+	// Prefix precedes the signature in Header, e.g. "sha256="
+	Prefix string
+	// Hash is the HMAC hash (default: sha256.New)
+	Hash func() hash.Hash
+	// Base64 decodes signatures as standard base64 instead of hex
+	Base64 bool
+	// TimestampHeader carries a Unix timestamp that is signed as
+	// "<timestamp>.<body>"; without it only the body is signed
+	TimestampHeader string
+	// Tolerance bounds the timestamp's distance from now (default: 5m)
+	Tolerance time.Duration
+}
+
+// HMACWebhook verifies a signature over the body, or over the timestamp and
+// body, in a single header. It panics without Header or Secrets.
+func HMACWebhook(cfg *HMACWebhookConfig) WebhookVerifier {
+	if cfg == nil || cfg.Header == "" {
+		panic("httpserver: HMACWebhook needs a config with a Header")
+	}
+	checkWebhookSecrets(cfg.Secrets)
+	c := *cfg
+	if c.Hash == nil {
+		c.Hash = sha256.New
+	}
+	if c.Tolerance <= 0 {
+		c.Tolerance = defaultWebhookTolerance
+	}
+	return func(r *http.Request, body []byte) error {
+		sig, ok := strings.CutPrefix(r.Header.Get(c.Header), c.Prefix)
+		if !ok || sig == "" {
+			return errs.Errorf("missing %s header: %w", c.Header, ErrWebhookSignature)
+		}
+		var mac []byte
+		var err error
+		if c.Base64 {
+			mac, err = base64.StdEncoding.DecodeString(sig)
+		} else {
+			mac, err = hex.DecodeString(sig)
+		}
+		if err != nil {
+			return errs.Errorf("malformed %s header: %w", c.Header, ErrWebhookSignature)
+		}
+		payload := body
+		if c.TimestampHeader != "" {
+			ts := r.Header.Get(c.TimestampHeader)
+			if err := checkWebhookTimestamp(ts, c.Tolerance); err != nil {
+				return err
+			}
+			payload = append([]byte(ts+"."), body...)
+		}
+		if !validHMAC(c.Hash, c.Secrets, payload, mac) {
+			return ErrWebhookSignature
+		}
+		return nil
+	}
+}
+
+// GitHubWebhook verifies GitHub's X-Hub-Signature-256 header. This and the
+// other verifiers below panic without secrets or with an empty one.
+func GitHubWebhook(secrets []string) WebhookVerifier {
+	return HMACWebhook(&HMACWebhookConfig{Secrets: secrets, Header: "X-Hub-Signature-256", Prefix: "sha256="})
+}
+
+// SlackWebhook verifies Slack's X-Slack-Signature header, signed with the
+// app's signing secret over "v0:<timestamp>:<body>"
+func SlackWebhook(secrets []string, tolerance time.Duration) WebhookVerifier {
+	checkWebhookSecrets(secrets)
+	if tolerance <= 0 {
+		tolerance = defaultWebhookTolerance
+	}
+	return func(r *http.Request, body []byte) error {
+		sig, ok := strings.CutPrefix(r.Header.Get("X-Slack-Signature"), "v0=")
+		mac, err := hex.DecodeString(sig)
+		if !ok || err != nil || len(mac) == 0 {
+			return errs.Errorf("missing or malformed X-Slack-Signature header: %w", ErrWebhookSignature)
+		}
+		ts := r.Header.Get("X-Slack-Request-Timestamp")
+		if err := checkWebhookTimestamp(ts, tolerance); err != nil {
+			return err
+		}
+		payload := append([]byte("v0:"+ts+":"), body...)
+		if !validHMAC(sha256.New, secrets, payload, mac) {
+			return ErrWebhookSignature
+		}
+		return nil
+	}
+}
+
+// StripeWebhook verifies Stripe's Stripe-Signature header, t=<timestamp>
+// with v1 signatures over "<timestamp>.<body>", with the endpoint's secret
+func StripeWebhook(secrets []string, tolerance time.Duration) WebhookVerifier {
+	return signatureListWebhook("Stripe-Signature", secrets, tolerance)
+}
+
+// EasyGoWebhook verifies the Webhook-Signature header of deliveries from a
+// webhooks.Sender, which uses the same scheme as Stripe
+func EasyGoWebhook(secrets []string, tolerance time.Duration) WebhookVerifier {
+	return signatureListWebhook(webhooks.SignatureHeader, secrets, tolerance)
+}
+
+// signatureListWebhook verifies a "t=<timestamp>,v1=<hex>,v1=<hex>" header
+func signatureListWebhook(header string, secrets []string, tolerance time.Duration) WebhookVerifier {
+	checkWebhookSecrets(secrets)
+	if tolerance <= 0 {
+		tolerance = defaultWebhookTolerance
+	}
+	return func(r *http.Request, body []byte) error {
+		var ts string
+		var macs [][]byte
+		for _, part := range strings.Split(r.Header.Get(header), ",") {
+			k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch k {
+			case "t":
+				ts = v
+			case "v1":
+				if mac, err := hex.DecodeString(v); err == nil {
+					macs = append(macs, mac)
+				}
+			}
+		}
+		if ts == "" || len(macs) == 0 {
+			return errs.Errorf("missing or malformed %s header: %w", header, ErrWebhookSignature)
+		}
+		if err := checkWebhookTimestamp(ts, tolerance); err != nil {
+			return err
+		}
+		payload := append([]byte(ts+"."), body...)
+		for _, mac := range macs {
+			if validHMAC(sha256.New, secrets, payload, mac) {
+				return nil
+			}
+		}
+		return ErrWebhookSignature
+	}
+}
+
+// checkWebhookSecrets panics unless there is a secret and none is empty; a
+// verifier without one would reject every request, and an empty HMAC key lets
+// anyone sign requests
+func checkWebhookSecrets(secrets []string) {
+	if len(secrets) == 0 {
+		panic("httpserver: webhook verifier needs at least one secret")
+	}
+	if slices.Contains(secrets, "") {
+		panic("httpserver: webhook secrets must not be empty")
+	}
+}
+
+// validHMAC reports whether mac is the HMAC of payload under any of secrets
+func validHMAC(h func() hash.Hash, secrets []string, payload, mac []byte) bool {
+	for _, secret := range secrets {
+		m := hmac.New(h, []byte(secret))
+		m.Write(payload)
+		if hmac.Equal(m.Sum(nil), mac) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkWebhookTimestamp(ts string, tolerance time.Duration) error {
+	secs, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errs.Errorf("missing or malformed webhook timestamp: %w", ErrWebhookSignature)
+	}
+	if d := time.Since(time.Unix(secs, 0)); d > tolerance || d < -tolerance {
+		return ErrWebhookTimestamp
+	}
+	return nil
+}
+
+// WebhookConfig configures the VerifyWebhook middleware
+type WebhookConfig struct {
+	// Verifier checks each request, e.g. GitHubWebhook(secrets) (required)
+	Verifier WebhookVerifier
+	// MaxBodyBytes bounds the body read for verification (default: 1MB)
+	MaxBodyBytes int64
+}
+
+type webhookBodyKey struct{}
+
+// VerifyWebhook returns middleware that reads the request body, verifies its
+// signature and responds 401 with code webhook_signature_invalid when it does
+// not match. Verified requests get the body back, so handlers can read it
+// again, and the raw bytes are available from WebhookBody and WebhookPayload.
+// It panics without a Verifier.
+func VerifyWebhook(cfg *WebhookConfig) func(http.Handler) http.Handler {
+	if cfg == nil || cfg.Verifier == nil {
+		panic("httpserver: VerifyWebhook needs a WebhookConfig with a Verifier")
+	}
+	maxBytes := cfg.MaxBodyBytes
+	if maxBytes <= 0 {
+		maxBytes = 1 << 20
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body []byte
+			if r.Body != nil {
+				data, err := io.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+				if err != nil && !IsRequestBodyTooLarge(err) {
+					writeProblem(w, r, http.StatusBadRequest, "failed to read request body")
+					return
+				}
+				if err != nil || int64(len(data)) > maxBytes {
+					writeProblem(w, r, http.StatusRequestEntityTooLarge, "request body too large")
+					return
+				}
+				r.Body.Close()
+				body = data
+			}
+
+			if err := cfg.Verifier(r, body); err != nil {
+				logging.FromContext(r.Context()).WithError(err).Debug("webhook rejected")
+				detail := "invalid webhook signature"
+				if errors.Is(err, ErrWebhookTimestamp) {
+					detail = "webhook timestamp outside tolerance"
+				}
+				WriteProblem(w, r, NewProblem(http.StatusUnauthorized, detail).WithCode("webhook_signature_invalid"))
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), webhookBodyKey{}, body)))
+		})
+	}
+}
+
+// WebhookBody returns the raw body verified by VerifyWebhook
+func WebhookBody(ctx context.Context) ([]byte, bool) {
+	body, ok := ctx.Value(webhookBodyKey{}).([]byte)
+	return body, ok
+}
+
+// WebhookPayload decodes the JSON body verified by VerifyWebhook into a T and
+// returns it with the raw bytes, e.g. to store the exact payload received.
+// Errors are *BindError, for WriteBindError.
+func WebhookPayload[T any](r *http.Request) (T, []byte, error) {
+	var v T
+	body, ok := WebhookBody(r.Context())
+	if !ok {
+		return v, nil, &BindError{Status: http.StatusInternalServerError, Message: "webhook was not verified"}
+	}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return v, body, decodeError(err)
+	}
+	return v, body, nil
+}
@@ -0,0 +1,110 @@
+package httpserver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/webhooks"
+)
+
+func hmacHex(secret, payload string) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(payload))
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	type event struct {
+		ID string `json:"id"`
+	}
+	var gotRaw string
+	var gotEvent event
+	handler := func(v WebhookVerifier) http.Handler {
+		return VerifyWebhook(&WebhookConfig{Verifier: v})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ev, raw, err := WebhookPayload[event](r)
+			if err != nil {
+				WriteBindError(w, r, err)
+				return
+			}
+			gotEvent, gotRaw = ev, string(raw)
+		}))
+	}
+	body := `{"id":"evt_1"}`
+	now := time.Now()
+	ts := strconv.FormatInt(now.Unix(), 10)
+	stale := strconv.FormatInt(now.Add(-time.Hour).Unix(), 10)
+
+	for name, tc := range map[string]struct {
+		verifier WebhookVerifier
+		header   http.Header
+		want     int
+	}{
+		"github":              {GitHubWebhook([]string{"old", "gh"}), http.Header{"X-Hub-Signature-256": {"sha256=" + hmacHex("gh", body)}}, http.StatusOK},
+		"github wrong secret": {GitHubWebhook([]string{"other"}), http.Header{"X-Hub-Signature-256": {"sha256=" + hmacHex("gh", body)}}, http.StatusUnauthorized},
+		"github missing":      {GitHubWebhook([]string{"gh"}), http.Header{}, http.StatusUnauthorized},
+		"slack": {SlackWebhook([]string{"sl"}, 0), http.Header{
+			"X-Slack-Request-Timestamp": {ts},
+			"X-Slack-Signature":         {"v0=" + hmacHex("sl", "v0:"+ts+":"+body)},
+		}, http.StatusOK},
+		"slack stale": {SlackWebhook([]string{"sl"}, 0), http.Header{
+			"X-Slack-Request-Timestamp": {stale},
+			"X-Slack-Signature":         {"v0=" + hmacHex("sl", "v0:"+stale+":"+body)},
+		}, http.StatusUnauthorized},
+		"stripe": {StripeWebhook([]string{"whsec"}, 0), http.Header{
+			"Stripe-Signature": {"t=" + ts + ",v1=" + hmacHex("other", ts+"."+body) + ",v1=" + hmacHex("whsec", ts+"."+body)},
+		}, http.StatusOK},
+		"easygo": {EasyGoWebhook([]string{"s"}, 0), http.Header{
+			webhooks.SignatureHeader: {webhooks.SignatureHeaderValue([]string{"s"}, now, []byte(body))},
+		}, http.StatusOK},
+		"generic with timestamp": {HMACWebhook(&HMACWebhookConfig{Secrets: []string{"g"}, Header: "X-Signature", TimestampHeader: "X-Timestamp"}), http.Header{
+			"X-Timestamp": {ts},
+			"X-Signature": {hmacHex("g", ts+"."+body)},
+		}, http.StatusOK},
+	} {
+		gotRaw, gotEvent = "", event{}
+		r := httptest.NewRequest(http.MethodPost, "/hooks", strings.NewReader(body))
+		for k, v := range tc.header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		handler(tc.verifier).ServeHTTP(w, r)
+		if w.Code != tc.want {
+			t.Errorf("%s: got %d %s, want %d", name, w.Code, w.Body, tc.want)
+			continue
+		}
+		if tc.want == http.StatusOK && (gotRaw != body || gotEvent.ID != "evt_1") {
+			t.Errorf("%s: got %q %+v", name, gotRaw, gotEvent)
+		}
+		if tc.want == http.StatusUnauthorized && !strings.Contains(w.Body.String(), "webhook_signature_invalid") {
+			t.Errorf("%s: got %s", name, w.Body)
+		}
+	}
+}
+
+func TestWebhookConfigValidation(t *testing.T) {
+	for name, build := range map[string]func(){
+		"nil HMAC config": func() { HMACWebhook(nil) },
+		"no header":       func() { HMACWebhook(&HMACWebhookConfig{Secrets: []string{"s"}}) },
+		"no secrets":      func() { GitHubWebhook(nil) },
+		"empty secret":    func() { StripeWebhook([]string{"s", ""}, 0) },
+		"empty slack":     func() { SlackWebhook([]string{""}, 0) },
+		"nil config":      func() { VerifyWebhook(nil) },
+		"no verifier":     func() { VerifyWebhook(&WebhookConfig{}) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic", name)
+				}
+			}()
+			build()
+		}()
+	}
+}
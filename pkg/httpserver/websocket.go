@@ -0,0 +1,298 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket errors returned by WebSocketConn.Send
+var (
+	ErrWebSocketClosed         = errs.New(errs.Unavailable, "websocket connection is closed")
+	ErrWebSocketSendBufferFull = errs.New(errs.Unavailable, "websocket send buffer is full")
+)
+
+// WebSocketConfig configures a WebSocket endpoint
+type WebSocketConfig struct {
+	// CheckOrigin accepts or rejects the upgrade; nil allows only same-origin requests
+	CheckOrigin func(r *http.Request) bool
+	// ReadLimit is the maximum incoming message size in bytes (default: 64KB)
+	ReadLimit int64
+	// PingInterval is how often pings are sent (default: 30s); the connection is
+	// closed when no pong arrives within twice this interval
+	PingInterval time.Duration
+	// WriteTimeout bounds each write (default: 10s)
+	WriteTimeout time.Duration
+	// SendBuffer is the number of outgoing messages queued per connection (default: 256)
+	SendBuffer int
+
+	// OnConnect runs after the upgrade; returning an error closes the connection
+	OnConnect func(c *WebSocketConn) error
+	// OnMessage runs for every text or binary message, in order, on the connection's read loop
+	OnMessage func(c *WebSocketConn, messageType int, data []byte)
+	// OnClose runs once the connection is closed
+	OnClose func(c *WebSocketConn)
+}
+
+// WebSocketHub tracks the open connections of an endpoint
+type WebSocketHub struct {
+	mu    sync.RWMutex
+	conns map[*WebSocketConn]struct{}
+}
+
+// Broadcast queues a text message on every open connection, skipping
+// connections whose send buffer is full
+func (h *WebSocketHub) Broadcast(data []byte) {
+	for _, c := range h.Conns() {
+		_ = c.Send(websocket.TextMessage, data)
+	}
+}
+
+// BroadcastJSON broadcasts v marshaled to JSON
+func (h *WebSocketHub) BroadcastJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errs.Errorf("failed to marshal websocket message: %w", err)
+	}
+	h.Broadcast(data)
+	return nil
+}
+
+// Conns returns a snapshot of the open connections
+func (h *WebSocketHub) Conns() []*WebSocketConn {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	conns := make([]*WebSocketConn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+// Len returns the number of open connections
+func (h *WebSocketHub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.conns)
+}
+
+// CloseAll sends a close frame to every connection and waits for them to
+// close or ctx to be done
+func (h *WebSocketHub) CloseAll(ctx context.Context, code int, reason string) error {
+	conns := h.Conns()
+	for _, c := range conns {
+		c.Close(code, reason)
+	}
+	for _, c := range conns {
+		select {
+		case <-c.closed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (h *WebSocketHub) add(c *WebSocketConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.conns[c] = struct{}{}
+}
+
+func (h *WebSocketHub) remove(c *WebSocketConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.conns, c)
+}
+
+type wsMessage struct {
+	messageType int
+	data        []byte
+}
+
+// WebSocketConn is an upgraded connection with its own read and write loops
+type WebSocketConn struct {
+	// Request is the upgrade request
+	Request *http.Request
+
+	conn    *websocket.Conn
+	cfg     *WebSocketConfig
+	send    chan wsMessage
+	ctx     context.Context
+	cancel  context.CancelFunc
+	closing chan websocket.CloseError
+	// closed is closed once the underlying connection has been closed
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// Context is cancelled when the connection closes
+func (c *WebSocketConn) Context() context.Context {
+	return c.ctx
+}
+
+// Send queues a message without blocking
+func (c *WebSocketConn) Send(messageType int, data []byte) error {
+	select {
+	case <-c.ctx.Done():
+		return ErrWebSocketClosed
+	default:
+	}
+	select {
+	case c.send <- wsMessage{messageType: messageType, data: data}:
+		return nil
+	case <-c.ctx.Done():
+		return ErrWebSocketClosed
+	default:
+		return ErrWebSocketSendBufferFull
+	}
+}
+
+// SendJSON queues v marshaled to JSON as a text message
+func (c *WebSocketConn) SendJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errs.Errorf("failed to marshal websocket message: %w", err)
+	}
+	return c.Send(websocket.TextMessage, data)
+}
+
+// Close sends a close frame with code and reason and closes the connection
+func (c *WebSocketConn) Close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closing <- websocket.CloseError{Code: code, Text: reason}
+		c.cancel()
+	})
+}
+
+// HandleWebSocket mounts a WebSocket endpoint at pattern and returns its hub.
+// Open connections are closed with 1001 (going away) when the server shuts down.
+func (s *EasyGoHTTPServer) HandleWebSocket(pattern string, cfg *WebSocketConfig) *WebSocketHub {
+	hub := NewWebSocketHub()
+	s.Chi.Handle(pattern, WebSocketHandler(hub, cfg))
+	s.OnShutdown(func(ctx context.Context) error {
+		return hub.CloseAll(ctx, websocket.CloseGoingAway, "server shutting down")
+	})
+	return hub
+}
+
+// NewWebSocketHub creates an empty hub for use with WebSocketHandler
+func NewWebSocketHub() *WebSocketHub {
+	return &WebSocketHub{conns: map[*WebSocketConn]struct{}{}}
+}
+
+// WebSocketHandler upgrades requests and registers the connections with hub
+func WebSocketHandler(hub *WebSocketHub, cfg *WebSocketConfig) http.Handler {
+	c := *cfg
+	if c.ReadLimit <= 0 {
+		c.ReadLimit = 64 << 10
+	}
+	if c.PingInterval <= 0 {
+		c.PingInterval = 30 * time.Second
+	}
+	if c.WriteTimeout <= 0 {
+		c.WriteTimeout = 10 * time.Second
+	}
+	if c.SendBuffer <= 0 {
+		c.SendBuffer = 256
+	}
+	upgrader := websocket.Upgrader{CheckOrigin: c.CheckOrigin}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the upgrader writes its own error response
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+		conn := &WebSocketConn{
+			Request: r,
+			conn:    ws,
+			cfg:     &c,
+			send:    make(chan wsMessage, c.SendBuffer),
+			ctx:     ctx,
+			cancel:  cancel,
+			closing: make(chan websocket.CloseError, 1),
+			closed:  make(chan struct{}),
+		}
+		hub.add(conn)
+
+		go conn.writePump(hub)
+		if c.OnConnect != nil {
+			if err := c.OnConnect(conn); err != nil {
+				conn.Close(websocket.CloseInternalServerErr, "")
+			}
+		}
+		conn.readPump()
+	})
+}
+
+// readPump reads messages until the connection fails or is closed
+func (c *WebSocketConn) readPump() {
+	pongWait := 2 * c.cfg.PingInterval
+	c.conn.SetReadLimit(c.cfg.ReadLimit)
+	_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		messageType, data, err := c.conn.ReadMessage()
+		if err != nil {
+			code := websocket.CloseNormalClosure
+			if ce, ok := err.(*websocket.CloseError); ok {
+				code = ce.Code
+			} else if errors.Is(err, websocket.ErrReadLimit) {
+				code = websocket.CloseMessageTooBig
+			}
+			c.Close(code, "")
+			return
+		}
+		if c.cfg.OnMessage != nil {
+			c.cfg.OnMessage(c, messageType, data)
+		}
+	}
+}
+
+// writePump is the only writer to the connection; it sends queued messages and
+// pings, and owns closing the underlying connection
+func (c *WebSocketConn) writePump(hub *WebSocketHub) {
+	ticker := time.NewTicker(c.cfg.PingInterval)
+	defer func() {
+		ticker.Stop()
+		_ = c.conn.Close()
+		hub.remove(c)
+		close(c.closed)
+		if c.cfg.OnClose != nil {
+			c.cfg.OnClose(c)
+		}
+	}()
+
+	for {
+		select {
+		case msg := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(c.cfg.WriteTimeout))
+			if err := c.conn.WriteMessage(msg.messageType, msg.data); err != nil {
+				c.Close(websocket.CloseAbnormalClosure, "")
+			}
+		case <-ticker.C:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(c.cfg.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.Close(websocket.CloseAbnormalClosure, "")
+			}
+		case ce := <-c.closing:
+			if ce.Code != websocket.CloseAbnormalClosure {
+				_ = c.conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(ce.Code, ce.Text), time.Now().Add(c.cfg.WriteTimeout))
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// DynamoDBAPI is the subset of the DynamoDB client used by DynamoDBStore
+type DynamoDBAPI interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+}
+
+type DynamoDBStoreOptions struct {
+	// KeyAttribute is the table's partition key, a string (default: key)
+	KeyAttribute string
+}
+
+// DynamoDBStore is a Store in a DynamoDB table. Claims are conditional puts,
+// and records keep their expiry in the ttl attribute; enable the table's TTL
+// on it to remove old keys.
+type DynamoDBStore struct {
+	client DynamoDBAPI
+	table  string
+	opts   DynamoDBStoreOptions
+	now    func() time.Time
+}
+
+var _ Store = (*DynamoDBStore)(nil)
+
+// NewDynamoDBStore creates a store for items in table, e.g. with
+// EGAwsClient.GetDynamoDBClient()
+func NewDynamoDBStore(client DynamoDBAPI, table string, opts *DynamoDBStoreOptions) *DynamoDBStore {
+	o := DynamoDBStoreOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.KeyAttribute == "" {
+		o.KeyAttribute = "key"
+	}
+	return &DynamoDBStore{client: client, table: table, opts: o, now: time.Now}
+}
+
+func (s *DynamoDBStore) itemKey(key string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{s.opts.KeyAttribute: &types.AttributeValueMemberS{Value: key}}
+}
+
+func (s *DynamoDBStore) Claim(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Record, bool, error) {
+	now := s.now()
+	item := s.itemKey(key)
+	item["fingerprint"] = &types.AttributeValueMemberS{Value: fingerprint}
+	item["status"] = &types.AttributeValueMemberN{Value: "0"}
+	item["ttl"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(ttl+time.Second-1).Unix(), 10)}
+	_, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item:      item,
+		// TTL deletion lags, so expired items are treated as absent
+		ConditionExpression:                 aws.String("attribute_not_exists(#key) OR #ttl <= :now"),
+		ExpressionAttributeNames:            map[string]string{"#key": s.opts.KeyAttribute, "#ttl": "ttl"},
+		ExpressionAttributeValues:           map[string]types.AttributeValue{":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)}},
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	var condErr *types.ConditionalCheckFailedException
+	if !errors.As(err, &condErr) {
+		if err != nil {
+			return nil, false, errs.Errorf("failed to claim idempotency key %s: %w", key, err)
+		}
+		return nil, true, nil
+	}
+
+	existing := condErr.Item
+	if existing == nil {
+		out, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{TableName: aws.String(s.table), Key: s.itemKey(key), ConsistentRead: aws.Bool(true)})
+		if err != nil {
+			return nil, false, errs.Errorf("failed to get idempotency key %s: %w", key, err)
+		}
+		if out.Item == nil {
+			return nil, false, errs.Newf(errs.Internal, "idempotency key %s was released while claiming it", key)
+		}
+		existing = out.Item
+	}
+	rec, err := decodeItem(existing)
+	if err != nil {
+		return nil, false, errs.Errorf("invalid idempotency record %s: %w", key, err)
+	}
+	return rec, false, nil
+}
+
+func (s *DynamoDBStore) Complete(ctx context.Context, key string, rec *Record, ttl time.Duration) error {
+	header, err := json.Marshal(rec.Header)
+	if err != nil {
+		return errs.Errorf("failed to marshal response header: %w", err)
+	}
+	item := s.itemKey(key)
+	item["fingerprint"] = &types.AttributeValueMemberS{Value: rec.Fingerprint}
+	item["status"] = &types.AttributeValueMemberN{Value: strconv.Itoa(rec.Status)}
+	item["header"] = &types.AttributeValueMemberB{Value: header}
+	item["body"] = &types.AttributeValueMemberB{Value: rec.Body}
+	item["ttl"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(s.now().Add(ttl).Unix(), 10)}
+	if _, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String(s.table), Item: item}); err != nil {
+		return errs.Errorf("failed to store idempotency key %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *DynamoDBStore) Release(ctx context.Context, key string) error {
+	if _, err := s.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{TableName: aws.String(s.table), Key: s.itemKey(key)}); err != nil {
+		return errs.Errorf("failed to release idempotency key %s: %w", key, err)
+	}
+	return nil
+}
+
+func decodeItem(item map[string]types.AttributeValue) (*Record, error) {
+	rec := &Record{}
+	if v, ok := item["fingerprint"].(*types.AttributeValueMemberS); ok {
+		rec.Fingerprint = v.Value
+	}
+	if v, ok := item["status"].(*types.AttributeValueMemberN); ok {
+		rec.Status, _ = strconv.Atoi(v.Value)
+	}
+	if v, ok := item["body"].(*types.AttributeValueMemberB); ok {
+		rec.Body = v.Value
+	}
+	if v, ok := item["header"].(*types.AttributeValueMemberB); ok {
+		rec.Header = map[string][]string{}
+		if err := json.Unmarshal(v.Value, &rec.Header); err != nil {
+			return nil, err
+		}
+	}
+	return rec, nil
+}
@@ -0,0 +1,67 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// fakeDynamoDB evaluates the claim condition on a map of items
+type fakeDynamoDB struct {
+	mu    sync.Mutex
+	items map[string]map[string]types.AttributeValue
+}
+
+func (f *fakeDynamoDB) GetItem(_ context.Context, in *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &dynamodb.GetItemOutput{Item: f.items[in.Key["key"].(*types.AttributeValueMemberS).Value]}, nil
+}
+
+func (f *fakeDynamoDB) PutItem(_ context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := in.Item["key"].(*types.AttributeValueMemberS).Value
+	if old, ok := f.items[key]; ok && in.ConditionExpression != nil {
+		now := in.ExpressionAttributeValues[":now"].(*types.AttributeValueMemberN).Value
+		if ttl := old["ttl"].(*types.AttributeValueMemberN).Value; ttl > now {
+			return nil, &types.ConditionalCheckFailedException{Item: old}
+		}
+	}
+	f.items[key] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) DeleteItem(_ context.Context, in *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.items, in.Key["key"].(*types.AttributeValueMemberS).Value)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func TestDynamoDBStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewDynamoDBStore(&fakeDynamoDB{items: map[string]map[string]types.AttributeValue{}}, "idempotency", nil)
+	if _, claimed, err := s.Claim(ctx, "k", "fp", time.Minute); err != nil || !claimed {
+		t.Fatalf("got %v %v", claimed, err)
+	}
+	rec, claimed, err := s.Claim(ctx, "k", "fp", time.Minute)
+	if err != nil || claimed || !rec.InProgress() || rec.Fingerprint != "fp" {
+		t.Fatalf("got %+v %v %v", rec, claimed, err)
+	}
+	if err := s.Complete(ctx, "k", &Record{Fingerprint: "fp", Status: 201, Header: map[string][]string{"X-A": {"b"}}, Body: []byte("ok")}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	rec, _, _ = s.Claim(ctx, "k", "fp", time.Minute)
+	if rec.Status != 201 || len(rec.Header["X-A"]) != 1 || rec.Header["X-A"][0] != "b" || string(rec.Body) != "ok" {
+		t.Fatalf("got %+v", rec)
+	}
+	_ = s.Release(ctx, "k")
+	if _, claimed, _ := s.Claim(ctx, "k", "fp", time.Minute); !claimed {
+		t.Fatal("expected a released key to be claimable")
+	}
+}
@@ -0,0 +1,100 @@
+// Package idempotency stores the outcome of operations by idempotency key so
+// retries of an operation run it once. httpserver.Idempotency replays stored
+// HTTP responses with it, and outbox.Deduplicate drops redelivered messages.
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Record is the state stored for an idempotency key
+type Record struct {
+	// Fingerprint identifies the request the key was first used with
+	Fingerprint string
+	// Status is the outcome, e.g. an HTTP response status, or 0 while the
+	// first request is in progress
+	Status int
+	// Header and Body hold the stored response, e.g. HTTP headers and body
+	Header map[string][]string
+	Body   []byte
+}
+
+// InProgress reports whether the first request with the key has not finished
+func (r *Record) InProgress() bool {
+	return r.Status == 0
+}
+
+// Store holds idempotency records. Implement it over a shared store such as
+// DynamoDB to deduplicate retries across replicas.
+type Store interface {
+	// Claim stores an in-progress record for key that expires after ttl.
+	// When key already has a record that has not expired, Claim returns it
+	// and false instead.
+	Claim(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Record, bool, error)
+	// Complete replaces key's record with the finished outcome, kept for ttl
+	Complete(ctx context.Context, key string, rec *Record, ttl time.Duration) error
+	// Release deletes key's record so the request can be retried
+	Release(ctx context.Context, key string) error
+}
+
+type memoryEntry struct {
+	rec     Record
+	expires time.Time
+}
+
+// MemoryStore is a Store local to the process
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]*memoryEntry{}, now: time.Now}
+}
+
+func (s *MemoryStore) Claim(_ context.Context, key, fingerprint string, ttl time.Duration) (*Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+	if e, ok := s.entries[key]; ok && now.Before(e.expires) {
+		rec := e.rec
+		return &rec, false, nil
+	}
+	s.entries[key] = &memoryEntry{rec: Record{Fingerprint: fingerprint}, expires: now.Add(ttl)}
+	return nil, true, nil
+}
+
+func (s *MemoryStore) Complete(_ context.Context, key string, rec *Record, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = &memoryEntry{rec: *rec, expires: s.now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// sweep drops expired records at most once a minute to bound memory
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Package ids generates time-sortable identifiers: UUIDv7s, ULIDs, KSUIDs and
+// typed prefixed IDs such as usr_01HZX3V9Q4M8K2W6C7R5T0NBJD. Generators take
+// a clock and entropy source so tests can produce deterministic IDs.
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	mrand "math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+	"github.com/segmentio/ksuid"
+)
+
+type NewGeneratorArgs struct {
+	// Now is the clock IDs embed (default: time.Now)
+	Now func() time.Time
+	// Entropy supplies the random bits (default: crypto/rand)
+	Entropy io.Reader
+}
+
+// Generator creates IDs. It is safe for concurrent use.
+type Generator struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	entropy io.Reader
+	// monotonic keeps ULIDs from the same millisecond in order
+	monotonic *ulid.MonotonicEntropy
+}
+
+// NewGenerator creates a Generator; args may be nil
+func NewGenerator(args *NewGeneratorArgs) *Generator {
+	if args == nil {
+		args = &NewGeneratorArgs{}
+	}
+	g := &Generator{now: args.Now, entropy: args.Entropy}
+	if g.now == nil {
+		g.now = time.Now
+	}
+	if g.entropy == nil {
+		g.entropy = rand.Reader
+	}
+	g.monotonic = ulid.Monotonic(g.entropy, 0)
+	return g
+}
+
+// NewDeterministic returns a Generator for tests that yields the same IDs for
+// the same seed: its clock starts at start and advances one millisecond per
+// ID, and its entropy is a seeded ChaCha8 stream
+func NewDeterministic(seed uint64, start time.Time) *Generator {
+	var key [32]byte
+	binary.BigEndian.PutUint64(key[:], seed)
+	var mu sync.Mutex
+	next := start
+	return NewGenerator(&NewGeneratorArgs{
+		Now: func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			t := next
+			next = next.Add(time.Millisecond)
+			return t
+		},
+		Entropy: mrand.NewChaCha8(key),
+	})
+}
+
+var (
+	defaultMu        sync.RWMutex
+	defaultGenerator = NewGenerator(nil)
+)
+
+// Default returns the Generator used by the package-level functions
+func Default() *Generator {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultGenerator
+}
+
+// SetDefault replaces the Generator used by the package-level functions and
+// returns a function restoring the previous one, e.g. in tests:
+//
+//	defer ids.SetDefault(ids.NewDeterministic(1, time.Unix(0, 0)))()
+func SetDefault(g *Generator) (restore func()) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	prev := defaultGenerator
+	defaultGenerator = g
+	return func() {
+		defaultMu.Lock()
+		defer defaultMu.Unlock()
+		defaultGenerator = prev
+	}
+}
+
+// UUIDv7 returns a version 7 UUID, ordered by its millisecond timestamp
+func (g *Generator) UUIDv7() uuid.UUID {
+	ms := uint64(g.now().UnixMilli())
+	var id uuid.UUID
+	g.read(id[6:])
+	id[0] = byte(ms >> 40)
+	id[1] = byte(ms >> 32)
+	id[2] = byte(ms >> 24)
+	id[3] = byte(ms >> 16)
+	id[4] = byte(ms >> 8)
+	id[5] = byte(ms)
+	id[6] = id[6]&0x0f | 0x70 // version 7
+	id[8] = id[8]&0x3f | 0x80 // RFC 4122 variant
+	return id
+}
+
+// ULID returns a ULID; ULIDs from one Generator are strictly increasing,
+// including within the same millisecond
+func (g *Generator) ULID() ulid.ULID {
+	ms := ulid.Timestamp(g.now())
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	id, err := ulid.New(ms, g.monotonic)
+	if err != nil {
+		// the monotonic counter overflowed within one millisecond; start afresh
+		g.monotonic = ulid.Monotonic(g.entropy, 0)
+		id = ulid.MustNew(ms, g.monotonic)
+	}
+	return id
+}
+
+// KSUID returns a KSUID, ordered by its second resolution timestamp
+func (g *Generator) KSUID() ksuid.KSUID {
+	t := g.now()
+	payload := make([]byte, 16)
+	g.read(payload)
+	id, err := ksuid.FromParts(t, payload)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+func (g *Generator) read(b []byte) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, err := io.ReadFull(g.entropy, b); err != nil {
+		panic("ids: failed to read entropy: " + err.Error())
+	}
+}
+
+// NewUUIDv7 returns a UUIDv7 from the default Generator
+func NewUUIDv7() uuid.UUID {
+	return Default().UUIDv7()
+}
+
+// NewULID returns a ULID from the default Generator
+func NewULID() ulid.ULID {
+	return Default().ULID()
+}
+
+// NewKSUID returns a KSUID from the default Generator
+func NewKSUID() ksuid.KSUID {
+	return Default().KSUID()
+}
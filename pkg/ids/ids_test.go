@@ -0,0 +1,96 @@
+package ids
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+type user struct{}
+
+func (user) Prefix() string { return "usr" }
+
+type order struct{}
+
+func (order) Prefix() string { return "ord" }
+
+func TestDeterministic(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	a, b := NewDeterministic(7, start), NewDeterministic(7, start)
+	for range 3 {
+		if a.ULID() != b.ULID() || a.UUIDv7() != b.UUIDv7() || a.KSUID() != b.KSUID() {
+			t.Fatal("expected identical IDs for the same seed")
+		}
+	}
+	if NewDeterministic(8, start).ULID() == NewDeterministic(7, start).ULID() {
+		t.Fatal("expected different IDs for different seeds")
+	}
+
+	u := NewDeterministic(1, start).UUIDv7()
+	if u.Version() != 7 || u.Variant().String() != "RFC4122" {
+		t.Fatalf("unexpected uuid %s version %d variant %s", u, u.Version(), u.Variant())
+	}
+}
+
+func TestSortable(t *testing.T) {
+	g := NewGenerator(&NewGeneratorArgs{Now: func() time.Time { return time.UnixMilli(1700000000000) }})
+	var ulids, uuids []string
+	for range 100 {
+		ulids = append(ulids, g.ULID().String())
+	}
+	if !slices.IsSorted(ulids) {
+		t.Fatal("ULIDs within a millisecond should be increasing")
+	}
+
+	clock := time.UnixMilli(1700000000000)
+	g = NewGenerator(&NewGeneratorArgs{Now: func() time.Time { clock = clock.Add(time.Millisecond); return clock }})
+	for range 100 {
+		uuids = append(uuids, g.UUIDv7().String())
+	}
+	if !slices.IsSorted(uuids) {
+		t.Fatal("UUIDv7s should sort by time")
+	}
+}
+
+func TestPrefixedID(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	defer SetDefault(NewDeterministic(1, start))()
+
+	id := New[user]()
+	s := id.String()
+	if len(s) != 30 || s[:4] != "usr_" || !id.Time().Equal(start) {
+		t.Fatalf("unexpected id %s at %v", s, id.Time())
+	}
+	parsed, err := Parse[user](s)
+	if err != nil || parsed != id {
+		t.Fatalf("round trip failed: %v", err)
+	}
+	if _, err := Parse[order](s); !errors.Is(err, ErrInvalidID) {
+		t.Fatalf("expected prefix mismatch, got %v", err)
+	}
+	if Valid[user]("usr_nope") {
+		t.Fatal("expected malformed id to be invalid")
+	}
+
+	var body struct {
+		ID    ID[user] `json:"id"`
+		Owner ID[user] `json:"owner"`
+	}
+	if err := json.Unmarshal([]byte(`{"id":"`+s+`","owner":""}`), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.ID != id || !body.Owner.IsZero() {
+		t.Fatalf("unexpected body %+v", body)
+	}
+	if err := json.Unmarshal([]byte(`{"id":"ord_`+s[4:]+`"}`), &body); err == nil {
+		t.Fatal("expected mistyped id to fail")
+	}
+
+	var scanned ID[user]
+	v, _ := id.Value()
+	if err := scanned.Scan(v); err != nil || scanned != id {
+		t.Fatalf("scan failed: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package ids
+
+import (
+	"database/sql/driver"
+	"strings"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/oklog/ulid/v2"
+)
+
+// ErrInvalidID is returned when parsing a malformed or mistyped prefixed ID
+var ErrInvalidID = errs.New(errs.InvalidArgument, "invalid id")
+
+// Kind names the prefix of a typed ID. Declare one per entity:
+//
+//	type User struct{}
+//
+//	func (User) Prefix() string { return "usr" }
+//
+//	type UserID = ids.ID[User]
+//
+//	id := ids.New[User]() // usr_01HZX3V9Q4M8K2W6C7R5T0NBJD
+type Kind interface {
+	Prefix() string
+}
+
+// ID is a ULID tagged with its kind's prefix, so IDs of different entities
+// cannot be mixed up at compile time or when parsed. The zero ID is empty and
+// encodes as an empty string. IDs marshal as text, so they work as JSON
+// strings, and implement sql.Scanner and driver.Valuer.
+type ID[K Kind] struct {
+	ulid ulid.ULID
+}
+
+// New returns a new ID of kind K from the default Generator
+func New[K Kind]() ID[K] {
+	return NewWith[K](Default())
+}
+
+// NewWith returns a new ID of kind K from g
+func NewWith[K Kind](g *Generator) ID[K] {
+	return ID[K]{ulid: g.ULID()}
+}
+
+// Parse parses an ID of kind K, rejecting IDs with another prefix
+func Parse[K Kind](s string) (ID[K], error) {
+	var k K
+	u, err := ParsePrefixed(k.Prefix(), s)
+	if err != nil {
+		return ID[K]{}, err
+	}
+	return ID[K]{ulid: u}, nil
+}
+
+// MustParse is Parse that panics on invalid IDs, for constants in tests
+func MustParse[K Kind](s string) ID[K] {
+	id, err := Parse[K](s)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// Valid reports whether s is an ID of kind K
+func Valid[K Kind](s string) bool {
+	_, err := Parse[K](s)
+	return err == nil
+}
+
+func (id ID[K]) String() string {
+	if id.IsZero() {
+		return ""
+	}
+	var k K
+	return k.Prefix() + "_" + id.ulid.String()
+}
+
+// IsZero reports whether id is the zero ID
+func (id ID[K]) IsZero() bool {
+	return id.ulid == (ulid.ULID{})
+}
+
+// ULID returns the ID without its prefix
+func (id ID[K]) ULID() ulid.ULID {
+	return id.ulid
+}
+
+// Time returns when the ID was generated, to the millisecond
+func (id ID[K]) Time() time.Time {
+	return id.ulid.Timestamp()
+}
+
+func (id ID[K]) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+func (id *ID[K]) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		*id = ID[K]{}
+		return nil
+	}
+	parsed, err := Parse[K](string(b))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+func (id ID[K]) Value() (driver.Value, error) {
+	if id.IsZero() {
+		return nil, nil
+	}
+	return id.String(), nil
+}
+
+func (id *ID[K]) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*id = ID[K]{}
+		return nil
+	case string:
+		return id.UnmarshalText([]byte(v))
+	case []byte:
+		return id.UnmarshalText(v)
+	}
+	return errs.Errorf("cannot scan %T into an id: %w", src, ErrInvalidID)
+}
+
+// Prefixed returns a new ULID from the default Generator with prefix, for
+// prefixes chosen at runtime; prefer ID for fixed entity types
+func Prefixed(prefix string) string {
+	return prefix + "_" + NewULID().String()
+}
+
+// ParsePrefixed parses s as prefix, an underscore and a ULID
+func ParsePrefixed(prefix, s string) (ulid.ULID, error) {
+	rest, ok := strings.CutPrefix(s, prefix+"_")
+	if !ok {
+		return ulid.ULID{}, errs.Errorf("%q does not have prefix %s_: %w", s, prefix, ErrInvalidID)
+	}
+	u, err := ulid.ParseStrict(rest)
+	if err != nil {
+		return ulid.ULID{}, errs.Errorf("%q: %v: %w", s, err, ErrInvalidID)
+	}
+	return u, nil
+}
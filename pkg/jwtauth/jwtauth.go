@@ -0,0 +1,364 @@
+// Package jwtauth issues signed JWTs. Signing keys are loaded from PEM
+// private keys, Secrets Manager or any secrets.Provider, or stay in KMS with
+// asymmetric KMS signing. An Issuer fills in the standard claims, rotates
+// keys while still publishing the previous ones under their kid, and serves
+// a JWKS so httpserver.JWTAuthenticator, in this or another service, can
+// verify its tokens.
+package jwtauth
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/ids"
+	"github.com/bdlilley/easygo/pkg/secrets"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWKSPath is where JWKS handlers are conventionally mounted
+const JWKSPath = "/.well-known/jwks.json"
+
+// Signer signs tokens with one key
+type Signer interface {
+	// Algorithm is the JWS alg, e.g. RS256
+	Algorithm() string
+	// KeyID is the kid header of signed tokens
+	KeyID() string
+	// Sign returns the signature of the JWS signing input
+	Sign(ctx context.Context, signingInput string) ([]byte, error)
+	// PublicKey is published in the JWKS; nil for HMAC keys, which are
+	// never published
+	PublicKey() crypto.PublicKey
+	// VerificationKey verifies tokens signed by this signer
+	VerificationKey() any
+}
+
+type NewKeySignerArgs struct {
+	// Key is a crypto.Signer such as *rsa.PrivateKey, *ecdsa.PrivateKey or
+	// ed25519.PrivateKey, or a []byte HMAC secret (required)
+	Key any
+	// Algorithm is the JWS alg (default: RS256 for RSA, ES256, ES384 or
+	// ES512 for EC keys by curve, EdDSA for Ed25519 and HS256 for HMAC)
+	Algorithm string
+	// KeyID is the kid header (default: the RFC 7638 thumbprint of the public
+	// key; required for HMAC secrets)
+	KeyID string
+}
+
+// KeySigner signs with a key held in memory
+type KeySigner struct {
+	method jwt.SigningMethod
+	key    any
+	keyID  string
+}
+
+var _ Signer = (*KeySigner)(nil)
+
+// NewKeySigner creates a KeySigner
+func NewKeySigner(args *NewKeySignerArgs) (*KeySigner, error) {
+	if args == nil || args.Key == nil {
+		return nil, errs.New(errs.Internal, "jwtauth: Key is required")
+	}
+	alg := args.Algorithm
+	if alg == "" {
+		var err error
+		if alg, err = defaultAlgorithm(args.Key); err != nil {
+			return nil, err
+		}
+	}
+	method := jwt.GetSigningMethod(alg)
+	if method == nil || alg == "none" {
+		return nil, errs.Newf(errs.Internal, "jwtauth: unsupported algorithm %s", alg)
+	}
+	s := &KeySigner{method: method, key: args.Key, keyID: args.KeyID}
+	if s.keyID == "" {
+		pub := s.PublicKey()
+		if pub == nil {
+			return nil, errs.New(errs.Internal, "jwtauth: KeyID is required for HMAC secrets")
+		}
+		kid, err := Thumbprint(pub)
+		if err != nil {
+			return nil, err
+		}
+		s.keyID = kid
+	}
+	// sign once so mismatched keys and algorithms fail here, not per token
+	if _, err := s.Sign(context.Background(), "probe"); err != nil {
+		return nil, errs.Errorf("jwtauth: key cannot sign %s: %w", alg, err)
+	}
+	return s, nil
+}
+
+// SignerFromSecret loads a signing key from a secret, e.g. with
+// secrets.NewSecretsManager(awsClient, nil). The secret holds a PEM private
+// key, or the raw secret when args.Algorithm is an HMAC algorithm; args.Key
+// is ignored and args may be nil.
+func SignerFromSecret(ctx context.Context, provider secrets.Provider, name string, args *NewKeySignerArgs) (*KeySigner, error) {
+	value, err := provider.Get(ctx, name)
+	if err != nil {
+		return nil, errs.Errorf("failed to load signing key %s: %w", name, err)
+	}
+	a := NewKeySignerArgs{}
+	if args != nil {
+		a = *args
+	}
+	if strings.HasPrefix(a.Algorithm, "HS") {
+		a.Key = []byte(value)
+	} else {
+		if a.Key, err = ParsePrivateKeyPEM([]byte(value)); err != nil {
+			return nil, errs.Errorf("invalid signing key %s: %w", name, err)
+		}
+	}
+	return NewKeySigner(&a)
+}
+
+func (s *KeySigner) Algorithm() string { return s.method.Alg() }
+func (s *KeySigner) KeyID() string     { return s.keyID }
+
+func (s *KeySigner) Sign(_ context.Context, signingInput string) ([]byte, error) {
+	return s.method.Sign(signingInput, s.key)
+}
+
+func (s *KeySigner) PublicKey() crypto.PublicKey {
+	if k, ok := s.key.(crypto.Signer); ok {
+		return k.Public()
+	}
+	return nil
+}
+
+func (s *KeySigner) VerificationKey() any {
+	if pub := s.PublicKey(); pub != nil {
+		return pub
+	}
+	return s.key
+}
+
+// Claims are the claims of an issued token. Registered claims left empty
+// are filled in by Issuer.Issue.
+type Claims struct {
+	jwt.RegisteredClaims
+	// Scope is the space separated scope claim
+	Scope string `json:"scope,omitempty"`
+	// Extra are private claims merged into the token; they never override
+	// the registered claims or scope
+	Extra map[string]any `json:"-"`
+}
+
+// NewClaims returns claims for subject with the given scopes
+func NewClaims(subject string, scopes ...string) *Claims {
+	return &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: subject},
+		Scope:            strings.Join(scopes, " "),
+	}
+}
+
+// With adds a private claim and returns c
+func (c *Claims) With(name string, value any) *Claims {
+	if c.Extra == nil {
+		c.Extra = map[string]any{}
+	}
+	c.Extra[name] = value
+	return c
+}
+
+func (c Claims) MarshalJSON() ([]byte, error) {
+	type claims Claims
+	b, err := json.Marshal(claims(c))
+	if err != nil || len(c.Extra) == 0 {
+		return b, err
+	}
+	members := map[string]any{}
+	for k, v := range c.Extra {
+		members[k] = v
+	}
+	if err := json.Unmarshal(b, &members); err != nil {
+		return nil, err
+	}
+	return json.Marshal(members)
+}
+
+type NewIssuerArgs struct {
+	// Signer signs new tokens (required)
+	Signer Signer
+	// Issuer is the iss claim, usually the service's base URL
+	Issuer string
+	// Audience is the default aud claim
+	Audience []string
+	// TTL is the default token lifetime (default: 15m)
+	TTL time.Duration
+	// KeepPrevious is how many rotated out signers stay in the JWKS so
+	// tokens they signed still verify (default: 1)
+	KeepPrevious int
+	// Now is the clock for issued-at and expiry (default: time.Now)
+	Now func() time.Time
+}
+
+// Issuer mints tokens. It is safe for concurrent use.
+type Issuer struct {
+	issuer       string
+	audience     []string
+	ttl          time.Duration
+	keepPrevious int
+	now          func() time.Time
+
+	mu       sync.RWMutex
+	current  Signer
+	previous []Signer
+}
+
+// NewIssuer creates an Issuer
+func NewIssuer(args *NewIssuerArgs) (*Issuer, error) {
+	if args == nil || args.Signer == nil {
+		return nil, errs.New(errs.Internal, "jwtauth: Signer is required")
+	}
+	i := &Issuer{
+		issuer:       args.Issuer,
+		audience:     args.Audience,
+		ttl:          args.TTL,
+		keepPrevious: args.KeepPrevious,
+		now:          args.Now,
+		current:      args.Signer,
+	}
+	if i.ttl <= 0 {
+		i.ttl = 15 * time.Minute
+	}
+	if i.keepPrevious <= 0 {
+		i.keepPrevious = 1
+	}
+	if i.now == nil {
+		i.now = time.Now
+	}
+	return i, nil
+}
+
+// Rotate signs new tokens with next. The current signer is kept for
+// verification and in the JWKS until KeepPrevious newer rotations push it out.
+func (i *Issuer) Rotate(next Signer) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if next.KeyID() == i.current.KeyID() {
+		i.current = next
+		return
+	}
+	i.previous = slices.DeleteFunc(i.previous, func(s Signer) bool { return s.KeyID() == next.KeyID() })
+	i.previous = append([]Signer{i.current}, i.previous...)
+	if len(i.previous) > i.keepPrevious {
+		i.previous = i.previous[:i.keepPrevious]
+	}
+	i.current = next
+}
+
+// signers returns the current signer followed by the previous ones
+func (i *Issuer) signers() []Signer {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return append([]Signer{i.current}, i.previous...)
+}
+
+// Issue fills in claims left empty, iss, aud, iat, nbf, exp and a ULID jti,
+// and returns the signed token
+func (i *Issuer) Issue(ctx context.Context, claims *Claims) (string, error) {
+	c := *claims
+	now := i.now().Truncate(time.Second)
+	if c.Issuer == "" {
+		c.Issuer = i.issuer
+	}
+	if len(c.Audience) == 0 {
+		c.Audience = i.audience
+	}
+	if c.IssuedAt == nil {
+		c.IssuedAt = jwt.NewNumericDate(now)
+	}
+	if c.NotBefore == nil {
+		c.NotBefore = jwt.NewNumericDate(now)
+	}
+	if c.ExpiresAt == nil {
+		c.ExpiresAt = jwt.NewNumericDate(now.Add(i.ttl))
+	}
+	if c.ID == "" {
+		c.ID = ids.NewULID().String()
+	}
+	return i.Sign(ctx, c)
+}
+
+// Sign signs claims as is with the current signer
+func (i *Issuer) Sign(ctx context.Context, claims jwt.Claims) (string, error) {
+	i.mu.RLock()
+	signer := i.current
+	i.mu.RUnlock()
+
+	method := jwt.GetSigningMethod(signer.Algorithm())
+	if method == nil {
+		return "", errs.Newf(errs.Internal, "jwtauth: unsupported algorithm %s", signer.Algorithm())
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = signer.KeyID()
+	input, err := token.SigningString()
+	if err != nil {
+		return "", errs.Errorf("failed to encode token: %w", err)
+	}
+	sig, err := signer.Sign(ctx, input)
+	if err != nil {
+		return "", errs.Errorf("failed to sign token with %s: %w", signer.KeyID(), err)
+	}
+	return input + "." + token.EncodeSegment(sig), nil
+}
+
+// Keyfunc verifies tokens from this Issuer by kid, e.g. as
+// httpserver.JWTAuthConfig.Keyfunc in the issuing service itself
+func (i *Issuer) Keyfunc() jwt.Keyfunc {
+	return func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		for _, s := range i.signers() {
+			if s.KeyID() == kid {
+				if t.Method.Alg() != s.Algorithm() {
+					return nil, errs.Newf(errs.Internal, "token alg %s does not match key %s", t.Method.Alg(), kid)
+				}
+				return s.VerificationKey(), nil
+			}
+		}
+		return nil, errs.Newf(errs.Internal, "unknown key %q", kid)
+	}
+}
+
+// JWKS returns the public keys of the current and previous signers
+func (i *Issuer) JWKS() (*JWKSet, error) {
+	set := &JWKSet{Keys: []JWK{}}
+	for _, s := range i.signers() {
+		pub := s.PublicKey()
+		if pub == nil {
+			continue
+		}
+		jwk, err := publicJWK(pub)
+		if err != nil {
+			return nil, err
+		}
+		jwk.KeyID, jwk.Algorithm, jwk.Use = s.KeyID(), s.Algorithm(), "sig"
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set, nil
+}
+
+// JWKSHandler serves the JWKS, conventionally at JWKSPath:
+//
+//	server.Chi.Method(http.MethodGet, jwtauth.JWKSPath, issuer.JWKSHandler())
+func (i *Issuer) JWKSHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		set, err := i.JWKS()
+		if err != nil {
+			http.Error(w, "failed to build JWKS", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		// verifiers refetch on unknown kids, so a short cache is safe across rotations
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		_ = json.NewEncoder(w).Encode(set)
+	})
+}
@@ -0,0 +1,182 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/bdlilley/easygo/pkg/httpserver"
+	"github.com/bdlilley/easygo/pkg/secrets"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newRSASigner(t *testing.T) *KeySigner {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewKeySigner(&NewKeySignerArgs{Key: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestIssueAndVerify(t *testing.T) {
+	ctx := context.Background()
+	issuer, err := NewIssuer(&NewIssuerArgs{
+		Signer:   newRSASigner(t),
+		Issuer:   "https://auth.example.com",
+		Audience: []string{"orders"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := issuer.Issue(ctx, NewClaims("user-1", "orders:read", "orders:write").With("tenant", "acme"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auth, err := httpserver.NewJWTAuthenticator(ctx, &httpserver.JWTAuthConfig{
+		Keyfunc:  issuer.Keyfunc(),
+		Issuer:   "https://auth.example.com",
+		Audience: []string{"orders"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := auth.Validate(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["sub"] != "user-1" || claims["scope"] != "orders:read orders:write" || claims["tenant"] != "acme" || claims["jti"] == "" {
+		t.Fatalf("unexpected claims %v", claims)
+	}
+
+	// rotated out keys still verify and stay published until pushed out
+	old := issuer.signers()[0]
+	issuer.Rotate(newRSASigner(t))
+	if _, err := auth.Validate(token); err != nil {
+		t.Fatalf("token from the previous key: %v", err)
+	}
+	set, err := issuer.JWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Keys) != 2 || set.Keys[1].KeyID != old.KeyID() || set.Keys[0].Algorithm != "RS256" {
+		t.Fatalf("unexpected JWKS %+v", set)
+	}
+	issuer.Rotate(newRSASigner(t))
+	if _, err := auth.Validate(token); err == nil {
+		t.Fatal("expected a token from a retired key to fail")
+	}
+}
+
+func TestJWKSHandlerVerifiesRemotely(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("JWT_SIGNING_KEY", string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})))
+	signer, err := SignerFromSecret(ctx, secrets.NewEnv("JWT_", nil), "signing-key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer.Algorithm() != "ES256" {
+		t.Fatalf("got %s, want ES256 for a P-256 key", signer.Algorithm())
+	}
+	issuer, _ := NewIssuer(&NewIssuerArgs{Signer: signer, TTL: time.Minute})
+
+	srv := httptest.NewServer(issuer.JWKSHandler())
+	defer srv.Close()
+	auth, err := httpserver.NewJWTAuthenticator(ctx, &httpserver.JWTAuthConfig{JWKSURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := issuer.Issue(ctx, NewClaims("svc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.Validate(token); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fakeKMS signs with a local EC key the way KMS does, returning DER signatures
+type fakeKMS struct {
+	key *ecdsa.PrivateKey
+}
+
+func (f *fakeKMS) GetPublicKey(_ context.Context, in *kms.GetPublicKeyInput, _ ...func(*kms.Options)) (*kms.GetPublicKeyOutput, error) {
+	der, err := x509.MarshalPKIXPublicKey(&f.key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &kms.GetPublicKeyOutput{
+		KeyId:             aws.String("arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"),
+		KeyUsage:          types.KeyUsageTypeSignVerify,
+		PublicKey:         der,
+		SigningAlgorithms: []types.SigningAlgorithmSpec{types.SigningAlgorithmSpecEcdsaSha256},
+	}, nil
+}
+
+func (f *fakeKMS) Sign(_ context.Context, in *kms.SignInput, _ ...func(*kms.Options)) (*kms.SignOutput, error) {
+	sig, err := ecdsa.SignASN1(rand.Reader, f.key, in.Message)
+	if err != nil {
+		return nil, err
+	}
+	return &kms.SignOutput{Signature: sig}, nil
+}
+
+func TestKMSSigner(t *testing.T) {
+	ctx := context.Background()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := NewKMSSigner(ctx, &NewKMSSignerArgs{Client: &fakeKMS{key: key}, KeyID: "alias/jwt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer.KeyID() != "1234abcd-12ab-34cd-56ef-1234567890ab" || signer.Algorithm() != "ES256" {
+		t.Fatalf("got kid %s alg %s", signer.KeyID(), signer.Algorithm())
+	}
+	issuer, _ := NewIssuer(&NewIssuerArgs{Signer: signer})
+	token, err := issuer.Issue(ctx, NewClaims("user-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := jwt.Parse(token, func(*jwt.Token) (any, error) { return &key.PublicKey, nil }, jwt.WithValidMethods([]string{"ES256"}))
+	if err != nil || !parsed.Valid {
+		t.Fatalf("KMS signed token did not verify: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	issuer.JWKSHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, JWKSPath, nil))
+	var set JWKSet
+	if err := json.Unmarshal(rec.Body.Bytes(), &set); err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Keys) != 1 || set.Keys[0].KeyType != "EC" || set.Keys[0].Curve != "P-256" {
+		t.Fatalf("unexpected JWKS %s", rec.Body.String())
+	}
+}
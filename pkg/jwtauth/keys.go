@@ -0,0 +1,141 @@
+package jwtauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// ParsePrivateKeyPEM parses a PEM encoded PKCS #8, PKCS #1 RSA or SEC 1 EC
+// private key
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errs.New(errs.Internal, "jwtauth: no PEM block found")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errs.Newf(errs.Internal, "jwtauth: unsupported private key type %T", key)
+		}
+		return signer, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, errs.Newf(errs.Internal, "jwtauth: unsupported %s PEM block", block.Type)
+}
+
+// JWK is a public JSON Web Key as published in a JWKS
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	// N and E are RSA members
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Curve, X and Y are EC and OKP members
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// JWKSet is a JSON Web Key Set
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// publicJWK converts an RSA, ECDSA or Ed25519 public key
+func publicJWK(pub crypto.PublicKey) (JWK, error) {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return JWK{KeyType: "RSA", N: b64(k.N.Bytes()), E: b64(big.NewInt(int64(k.E)).Bytes())}, nil
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		ecdh, err := k.ECDH()
+		if err != nil {
+			return JWK{}, errs.Errorf("jwtauth: invalid EC public key: %w", err)
+		}
+		// uncompressed point: 0x04 || X || Y
+		point := ecdh.Bytes()
+		return JWK{KeyType: "EC", Curve: k.Curve.Params().Name, X: b64(point[1 : 1+size]), Y: b64(point[1+size:])}, nil
+	case ed25519.PublicKey:
+		return JWK{KeyType: "OKP", Curve: "Ed25519", X: b64(k)}, nil
+	}
+	return JWK{}, errs.Newf(errs.Internal, "jwtauth: unsupported public key type %T", pub)
+}
+
+// Thumbprint returns the RFC 7638 SHA-256 thumbprint of pub, the default
+// kid of asymmetric signers
+func Thumbprint(pub crypto.PublicKey) (string, error) {
+	jwk, err := publicJWK(pub)
+	if err != nil {
+		return "", err
+	}
+	// the required members in lexicographic order
+	var members any
+	switch jwk.KeyType {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Curve, jwk.KeyType, jwk.X, jwk.Y}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Curve, jwk.KeyType, jwk.X}
+	}
+	b, err := json.Marshal(members)
+	if err != nil {
+		return "", errs.Errorf("jwtauth: failed to marshal thumbprint: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// defaultAlgorithm picks the usual JWS algorithm for a key
+func defaultAlgorithm(key any) (string, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return "RS256", nil
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return "ES256", nil
+		case elliptic.P384():
+			return "ES384", nil
+		case elliptic.P521():
+			return "ES512", nil
+		}
+	case ed25519.PrivateKey:
+		return "EdDSA", nil
+	case []byte:
+		return "HS256", nil
+	}
+	return "", errs.Newf(errs.Internal, "jwtauth: unsupported signing key type %T", key)
+}
@@ -0,0 +1,152 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// KMSAPI is the subset of the KMS client used by KMSSigner
+type KMSAPI interface {
+	GetPublicKey(ctx context.Context, params *kms.GetPublicKeyInput, optFns ...func(*kms.Options)) (*kms.GetPublicKeyOutput, error)
+	Sign(ctx context.Context, params *kms.SignInput, optFns ...func(*kms.Options)) (*kms.SignOutput, error)
+}
+
+// kmsAlgorithms maps KMS signing algorithms to JWS algorithms, in order of
+// preference
+var kmsAlgorithms = []struct {
+	kms  types.SigningAlgorithmSpec
+	jws  string
+	hash crypto.Hash
+}{
+	{types.SigningAlgorithmSpecEcdsaSha256, "ES256", crypto.SHA256},
+	{types.SigningAlgorithmSpecEcdsaSha384, "ES384", crypto.SHA384},
+	{types.SigningAlgorithmSpecEcdsaSha512, "ES512", crypto.SHA512},
+	{types.SigningAlgorithmSpecRsassaPkcs1V15Sha256, "RS256", crypto.SHA256},
+	{types.SigningAlgorithmSpecRsassaPkcs1V15Sha384, "RS384", crypto.SHA384},
+	{types.SigningAlgorithmSpecRsassaPkcs1V15Sha512, "RS512", crypto.SHA512},
+	{types.SigningAlgorithmSpecRsassaPssSha256, "PS256", crypto.SHA256},
+	{types.SigningAlgorithmSpecRsassaPssSha384, "PS384", crypto.SHA384},
+	{types.SigningAlgorithmSpecRsassaPssSha512, "PS512", crypto.SHA512},
+}
+
+type NewKMSSignerArgs struct {
+	// Client is typically EGAwsClient.GetKMSClient() (required)
+	Client KMSAPI
+	// KeyID is the ID, ARN or alias of an asymmetric SIGN_VERIFY key (required)
+	KeyID string
+	// Algorithm is the JWS alg (default: the first the key supports of
+	// ES256, ES384, ES512, RS256, RS384, RS512, PS256, PS384 and PS512)
+	Algorithm string
+	// KID is the kid header (default: the key's ID)
+	KID string
+}
+
+// KMSSigner signs with an asymmetric KMS key, so the private key never
+// leaves KMS. Each token costs one KMS Sign call.
+type KMSSigner struct {
+	client    KMSAPI
+	keyID     string
+	kid       string
+	algorithm string
+	kmsAlg    types.SigningAlgorithmSpec
+	hash      crypto.Hash
+	public    crypto.PublicKey
+	// sigSize is the size of each half of a raw ECDSA signature
+	sigSize int
+}
+
+var _ Signer = (*KMSSigner)(nil)
+
+// NewKMSSigner loads the public key of args.KeyID and creates a KMSSigner
+func NewKMSSigner(ctx context.Context, args *NewKMSSignerArgs) (*KMSSigner, error) {
+	if args == nil || args.Client == nil || args.KeyID == "" {
+		return nil, errs.New(errs.Internal, "jwtauth: KMS Client and KeyID are required")
+	}
+	out, err := args.Client.GetPublicKey(ctx, &kms.GetPublicKeyInput{KeyId: aws.String(args.KeyID)})
+	if err != nil {
+		return nil, errs.Errorf("failed to get public key of %s: %w", args.KeyID, err)
+	}
+	if out.KeyUsage != types.KeyUsageTypeSignVerify {
+		return nil, errs.Newf(errs.Internal, "jwtauth: KMS key %s is not a signing key", args.KeyID)
+	}
+	pub, err := x509.ParsePKIXPublicKey(out.PublicKey)
+	if err != nil {
+		return nil, errs.Errorf("invalid public key of %s: %w", args.KeyID, err)
+	}
+
+	s := &KMSSigner{client: args.Client, keyID: aws.ToString(out.KeyId), kid: args.KID, public: pub}
+	for _, a := range kmsAlgorithms {
+		if !slices.Contains(out.SigningAlgorithms, a.kms) || (args.Algorithm != "" && args.Algorithm != a.jws) {
+			continue
+		}
+		s.algorithm, s.kmsAlg, s.hash = a.jws, a.kms, a.hash
+		break
+	}
+	if s.algorithm == "" {
+		return nil, errs.Newf(errs.Internal, "jwtauth: KMS key %s supports no matching JWS algorithm", args.KeyID)
+	}
+	if ec, ok := pub.(*ecdsa.PublicKey); ok {
+		s.sigSize = (ec.Curve.Params().BitSize + 7) / 8
+	}
+	if s.kid == "" {
+		s.kid = keyIDFromARN(s.keyID)
+	}
+	return s, nil
+}
+
+// keyIDFromARN returns the key ID of a key ARN so account details are not
+// published in kid headers
+func keyIDFromARN(id string) string {
+	if parsed, err := arn.Parse(id); err == nil {
+		return strings.TrimPrefix(parsed.Resource, "key/")
+	}
+	return id
+}
+
+func (s *KMSSigner) Algorithm() string           { return s.algorithm }
+func (s *KMSSigner) KeyID() string               { return s.kid }
+func (s *KMSSigner) PublicKey() crypto.PublicKey { return s.public }
+func (s *KMSSigner) VerificationKey() any        { return s.public }
+
+func (s *KMSSigner) Sign(ctx context.Context, signingInput string) ([]byte, error) {
+	h := s.hash.New()
+	h.Write([]byte(signingInput))
+	out, err := s.client.Sign(ctx, &kms.SignInput{
+		KeyId:            aws.String(s.keyID),
+		Message:          h.Sum(nil),
+		MessageType:      types.MessageTypeDigest,
+		SigningAlgorithm: s.kmsAlg,
+	})
+	if err != nil {
+		return nil, errs.Errorf("failed to sign with %s: %w", s.keyID, err)
+	}
+	if s.sigSize == 0 {
+		return out.Signature, nil
+	}
+	return ecdsaRaw(out.Signature, s.sigSize)
+}
+
+// ecdsaRaw converts KMS's ASN.1 DER ECDSA signature to the fixed size
+// R || S form JWS requires
+func ecdsaRaw(der []byte, size int) ([]byte, error) {
+	var sig struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, errs.Errorf("invalid ECDSA signature from KMS: %w", err)
+	}
+	raw := make([]byte, 2*size)
+	sig.R.FillBytes(raw[:size])
+	sig.S.FillBytes(raw[size:])
+	return raw, nil
+}
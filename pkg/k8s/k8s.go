@@ -0,0 +1,144 @@
+// Package k8s builds Kubernetes clients from in-cluster configuration or a
+// kubeconfig, with logging through logging.Logger, client-side rate limits
+// and helpers for reading Secrets and ConfigMaps like the AWS secret helpers.
+package k8s
+
+import (
+	"os"
+	"strings"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// serviceAccountNamespaceFile holds the pod's namespace when running in a cluster
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+type NewClientArgs struct {
+	// Kubeconfig is used outside a cluster (default: $KUBECONFIG or ~/.kube/config)
+	Kubeconfig string
+	// Context selects a kubeconfig context (default: the current context)
+	Context string
+	// Namespace is the default namespace for the namespace helpers (default:
+	// the pod's namespace in a cluster, else the kubeconfig context's, else "default")
+	Namespace string
+	// QPS and Burst are the client-side rate limits (default: 50 and 100)
+	QPS   float32
+	Burst int
+	// UserAgent identifies the client to the API server
+	UserAgent string
+	// Scheme registers the types known to the controller-runtime client
+	// (default: the client-go built-in types)
+	Scheme *runtime.Scheme
+	// Logger receives client-go and controller-runtime logs. Both libraries log
+	// through process-wide loggers, so setting it replaces them globally.
+	Logger logging.Logger
+}
+
+// Client bundles a typed clientset and a controller-runtime client sharing one config
+type Client struct {
+	Config    *rest.Config
+	Clientset kubernetes.Interface
+	// Client is the controller-runtime client for typed and unstructured objects
+	Client client.Client
+
+	namespace string
+}
+
+// NewClient builds clients from in-cluster configuration when running in a
+// pod, or from the kubeconfig otherwise
+func NewClient(args *NewClientArgs) (*Client, error) {
+	if args == nil {
+		args = &NewClientArgs{}
+	}
+	if args.Logger != nil {
+		logger := NewLogr(args.Logger)
+		klog.SetLogger(logger)
+		ctrllog.SetLogger(logger)
+	}
+
+	cfg, namespace, err := restConfig(args)
+	if err != nil {
+		return nil, err
+	}
+	cfg.QPS = args.QPS
+	if cfg.QPS <= 0 {
+		cfg.QPS = 50
+	}
+	cfg.Burst = args.Burst
+	if cfg.Burst <= 0 {
+		cfg.Burst = 100
+	}
+	if args.UserAgent != "" {
+		cfg.UserAgent = args.UserAgent
+	}
+	if args.Namespace != "" {
+		namespace = args.Namespace
+	}
+
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, errs.Errorf("failed to create clientset: %w", err)
+	}
+	scheme := args.Scheme
+	if scheme == nil {
+		scheme = clientgoscheme.Scheme
+	}
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, errs.Errorf("failed to create controller-runtime client: %w", err)
+	}
+	return &Client{Config: cfg, Clientset: clientset, Client: c, namespace: namespace}, nil
+}
+
+// restConfig returns the REST config and the namespace it implies
+func restConfig(args *NewClientArgs) (*rest.Config, string, error) {
+	if args.Kubeconfig == "" && args.Context == "" {
+		if cfg, err := rest.InClusterConfig(); err == nil {
+			return cfg, inClusterNamespace(), nil
+		}
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if args.Kubeconfig != "" {
+		rules.ExplicitPath = args.Kubeconfig
+	}
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: args.Context})
+	cfg, err := loader.ClientConfig()
+	if err != nil {
+		return nil, "", errs.Errorf("failed to load kubeconfig: %w", err)
+	}
+	namespace, _, err := loader.Namespace()
+	if err != nil || namespace == "" {
+		namespace = "default"
+	}
+	return cfg, namespace, nil
+}
+
+func inClusterNamespace() string {
+	b, err := os.ReadFile(serviceAccountNamespaceFile)
+	if ns := strings.TrimSpace(string(b)); err == nil && ns != "" {
+		return ns
+	}
+	return "default"
+}
+
+// Namespace returns the default namespace used when a helper is called with ""
+func (c *Client) Namespace() string {
+	return c.namespace
+}
+
+func (c *Client) ns(namespace string) string {
+	if namespace == "" {
+		return c.namespace
+	}
+	return namespace
+}
@@ -0,0 +1,81 @@
+package k8s
+
+import (
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/go-logr/logr"
+	"github.com/sirupsen/logrus"
+)
+
+// NewLogr adapts logger to logr for libraries such as client-go and
+// controller-runtime. logr V-levels 0 and 1 map to info and debug, higher
+// levels to trace.
+func NewLogr(logger logging.Logger) logr.Logger {
+	return logr.New(&logrSink{logger: logger})
+}
+
+type logrSink struct {
+	logger logging.Logger
+	name   string
+	fields logrus.Fields
+}
+
+func (s *logrSink) Init(logr.RuntimeInfo) {}
+
+func (s *logrSink) Enabled(level int) bool {
+	l, ok := s.logger.(interface{ IsLevelEnabled(logrus.Level) bool })
+	return !ok || l.IsLevelEnabled(logrLevel(level))
+}
+
+func (s *logrSink) entry(keysAndValues []any) *logrus.Entry {
+	entry := s.logger.WithFields(s.fields)
+	if s.name != "" {
+		entry = entry.WithField("logger", s.name)
+	}
+	return entry.WithFields(keyValues(keysAndValues))
+}
+
+func (s *logrSink) Info(level int, msg string, keysAndValues ...any) {
+	s.entry(keysAndValues).Log(logrLevel(level), msg)
+}
+
+func (s *logrSink) Error(err error, msg string, keysAndValues ...any) {
+	s.entry(keysAndValues).WithError(err).Error(msg)
+}
+
+func (s *logrSink) WithValues(keysAndValues ...any) logr.LogSink {
+	fields := logrus.Fields{}
+	for k, v := range s.fields {
+		fields[k] = v
+	}
+	for k, v := range keyValues(keysAndValues) {
+		fields[k] = v
+	}
+	return &logrSink{logger: s.logger, name: s.name, fields: fields}
+}
+
+func (s *logrSink) WithName(name string) logr.LogSink {
+	if s.name != "" {
+		name = s.name + "." + name
+	}
+	return &logrSink{logger: s.logger, name: name, fields: s.fields}
+}
+
+func logrLevel(level int) logrus.Level {
+	switch {
+	case level <= 0:
+		return logrus.InfoLevel
+	case level == 1:
+		return logrus.DebugLevel
+	}
+	return logrus.TraceLevel
+}
+
+func keyValues(kv []any) logrus.Fields {
+	fields := logrus.Fields{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		if k, ok := kv[i].(string); ok {
+			fields[k] = kv[i+1]
+		}
+	}
+	return fields
+}
@@ -0,0 +1,117 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/bdlilley/easygo/pkg/config"
+	"github.com/bdlilley/easygo/pkg/errs"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// secretData returns a Secret's data decoded to strings
+func (c *Client) secretData(ctx context.Context, namespace, name string) (map[string]string, error) {
+	secret, err := c.Clientset.CoreV1().Secrets(c.ns(namespace)).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errs.Errorf("failed to get secret %s/%s: %w", c.ns(namespace), name, err)
+	}
+	data := make(map[string]string, len(secret.Data))
+	for k, v := range secret.Data {
+		data[k] = string(v)
+	}
+	return data, nil
+}
+
+func (c *Client) configMapData(ctx context.Context, namespace, name string) (map[string]string, error) {
+	cm, err := c.Clientset.CoreV1().ConfigMaps(c.ns(namespace)).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errs.Errorf("failed to get config map %s/%s: %w", c.ns(namespace), name, err)
+	}
+	data := make(map[string]string, len(cm.Data)+len(cm.BinaryData))
+	for k, v := range cm.BinaryData {
+		data[k] = string(v)
+	}
+	for k, v := range cm.Data {
+		data[k] = v
+	}
+	return data, nil
+}
+
+func value(data map[string]string, kind, name, key string) (string, error) {
+	v, ok := data[key]
+	if !ok {
+		return "", errs.Newf(errs.Internal, "%s %s has no key %s", kind, name, key)
+	}
+	return v, nil
+}
+
+// GetSecretString returns one key of a Secret; an empty namespace uses the client's
+func (c *Client) GetSecretString(ctx context.Context, namespace, name, key string) (string, error) {
+	data, err := c.secretData(ctx, namespace, name)
+	if err != nil {
+		return "", err
+	}
+	return value(data, "secret", name, key)
+}
+
+// GetJSONSecret unmarshals the JSON stored in one key of a Secret into result
+func (c *Client) GetJSONSecret(ctx context.Context, namespace, name, key string, result any) error {
+	v, err := c.GetSecretString(ctx, namespace, name, key)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(v), result); err != nil {
+		return errs.Errorf("failed to unmarshal secret %s key %s: %w", name, key, err)
+	}
+	return nil
+}
+
+// DecodeSecret fills the struct result from a Secret's keys, matching fields
+// by their env tag with the same parsing, default and required rules as
+// pkg/config
+func (c *Client) DecodeSecret(ctx context.Context, namespace, name string, result any) error {
+	data, err := c.secretData(ctx, namespace, name)
+	if err != nil {
+		return err
+	}
+	return decode(ctx, data, result)
+}
+
+// GetConfigMapString returns one key of a ConfigMap; an empty namespace uses the client's
+func (c *Client) GetConfigMapString(ctx context.Context, namespace, name, key string) (string, error) {
+	data, err := c.configMapData(ctx, namespace, name)
+	if err != nil {
+		return "", err
+	}
+	return value(data, "config map", name, key)
+}
+
+// GetJSONConfigMap unmarshals the JSON stored in one key of a ConfigMap into result
+func (c *Client) GetJSONConfigMap(ctx context.Context, namespace, name, key string, result any) error {
+	v, err := c.GetConfigMapString(ctx, namespace, name, key)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(v), result); err != nil {
+		return errs.Errorf("failed to unmarshal config map %s key %s: %w", name, key, err)
+	}
+	return nil
+}
+
+// DecodeConfigMap fills the struct result from a ConfigMap's keys like DecodeSecret
+func (c *Client) DecodeConfigMap(ctx context.Context, namespace, name string, result any) error {
+	data, err := c.configMapData(ctx, namespace, name)
+	if err != nil {
+		return err
+	}
+	return decode(ctx, data, result)
+}
+
+func decode(ctx context.Context, data map[string]string, result any) error {
+	return config.LoadContext(ctx, result, &config.LoadArgs{
+		LookupEnv: func(key string) (string, bool) {
+			v, ok := data[key]
+			return v, ok
+		},
+	})
+}
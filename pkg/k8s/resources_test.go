@@ -0,0 +1,54 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDecodeSecret(t *testing.T) {
+	ctx := context.Background()
+	c := &Client{
+		namespace: "apps",
+		Clientset: fake.NewClientset(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "apps"},
+				Data: map[string][]byte{
+					"DB_HOST":  []byte("db.internal"),
+					"DB_PORT":  []byte("6432"),
+					"settings": []byte(`{"pool": 10}`),
+				},
+			},
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "apps"},
+				Data:       map[string]string{"MODE": "batch"},
+			},
+		),
+	}
+
+	var db struct {
+		Host string `env:"DB_HOST" required:"true"`
+		Port int    `env:"DB_PORT" default:"5432"`
+		User string `env:"DB_USER" default:"app"`
+	}
+	if err := c.DecodeSecret(ctx, "", "db", &db); err != nil {
+		t.Fatal(err)
+	}
+	if db.Host != "db.internal" || db.Port != 6432 || db.User != "app" {
+		t.Fatalf("unexpected %+v", db)
+	}
+
+	var settings struct{ Pool int }
+	if err := c.GetJSONSecret(ctx, "apps", "db", "settings", &settings); err != nil || settings.Pool != 10 {
+		t.Fatalf("unexpected %+v %v", settings, err)
+	}
+	if mode, err := c.GetConfigMapString(ctx, "", "app", "MODE"); err != nil || mode != "batch" {
+		t.Fatalf("unexpected %q %v", mode, err)
+	}
+	if _, err := c.GetSecretString(ctx, "", "db", "missing"); err == nil {
+		t.Fatal("expected error for missing key")
+	}
+}
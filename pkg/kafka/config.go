@@ -0,0 +1,189 @@
+// Package kafka wraps franz-go with JSON producers, a consumer group runner
+// that processes each partition in its own goroutine, and SASL/TLS
+// configuration with credentials from Secrets Manager or AWS MSK IAM.
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"strings"
+
+	"github.com/bdlilley/easygo"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+	saslaws "github.com/twmb/franz-go/pkg/sasl/aws"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+// SASL mechanisms
+const (
+	MechanismPlain       = "PLAIN"
+	MechanismSCRAMSHA256 = "SCRAM-SHA-256"
+	MechanismSCRAMSHA512 = "SCRAM-SHA-512"
+	MechanismAWSMSKIAM   = "AWS_MSK_IAM"
+)
+
+// ClientConfig holds the connection settings shared by producers and consumers
+type ClientConfig struct {
+	// Brokers are the seed broker addresses (required)
+	Brokers  []string
+	ClientID string
+	// TLS enables TLS 1.2+ with system roots
+	TLS bool
+	// TLSConfig is the TLS configuration; setting it enables TLS
+	TLSConfig *tls.Config
+	// SASL authenticates connections; SASL over plaintext is rejected unless
+	// AllowPlaintextSASL is set
+	SASL               *SASLConfig
+	AllowPlaintextSASL bool
+	// Logger receives client logs at warn and above, and errors without an
+	// ErrorHandler (default: logging.Noop)
+	Logger logging.Logger
+	// Options are appended to the built-in client options
+	Options []kgo.Opt
+}
+
+// SASLConfig configures SASL authentication
+type SASLConfig struct {
+	// Mechanism is one of the Mechanism constants (required)
+	Mechanism string
+	Username  string
+	Password  string
+	// Secret names a Secrets Manager JSON secret with "username" and
+	// "password" keys, read once when the client is created. Requires Secrets.
+	Secret  string
+	Secrets easygo.SecretsReader
+	// AwsClient signs AWS_MSK_IAM authentication with its credentials
+	AwsClient *easygo.EGAwsClient
+}
+
+// options converts the config to franz-go client options
+func (cfg *ClientConfig) options(ctx context.Context) ([]kgo.Opt, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, errs.New(errs.Internal, "Brokers is required")
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = logging.Noop()
+	}
+
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(cfg.Brokers...),
+		kgo.WithLogger(&kgoLogger{logger: logger}),
+	}
+	if cfg.ClientID != "" {
+		opts = append(opts, kgo.ClientID(cfg.ClientID))
+	}
+
+	tlsConfig := cfg.TLSConfig
+	if tlsConfig != nil {
+		tlsConfig = tlsConfig.Clone()
+	} else if cfg.TLS {
+		tlsConfig = &tls.Config{}
+	}
+	if tlsConfig != nil {
+		if tlsConfig.MinVersion == 0 {
+			tlsConfig.MinVersion = tls.VersionTLS12
+		}
+		opts = append(opts, kgo.DialTLSConfig(tlsConfig))
+	}
+
+	if cfg.SASL != nil {
+		if tlsConfig == nil && !cfg.AllowPlaintextSASL {
+			return nil, errs.New(errs.Internal, "SASL requires TLS; set AllowPlaintextSASL to send credentials in plaintext")
+		}
+		mechanism, err := cfg.SASL.mechanism(ctx)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, kgo.SASL(mechanism))
+	}
+	return append(opts, cfg.Options...), nil
+}
+
+func (s *SASLConfig) mechanism(ctx context.Context) (sasl.Mechanism, error) {
+	if s.Mechanism == MechanismAWSMSKIAM {
+		if s.AwsClient == nil {
+			return nil, errs.New(errs.Internal, "AWS_MSK_IAM requires AwsClient")
+		}
+		provider := s.AwsClient.GetConfig().Credentials
+		return saslaws.ManagedStreamingIAM(func(ctx context.Context) (saslaws.Auth, error) {
+			creds, err := provider.Retrieve(ctx)
+			if err != nil {
+				return saslaws.Auth{}, errs.Errorf("failed to retrieve AWS credentials: %w", err)
+			}
+			return saslaws.Auth{
+				AccessKey:    creds.AccessKeyID,
+				SecretKey:    creds.SecretAccessKey,
+				SessionToken: creds.SessionToken,
+			}, nil
+		}), nil
+	}
+
+	username, password := s.Username, s.Password
+	if s.Secret != "" {
+		if s.Secrets == nil {
+			return nil, errs.New(errs.Internal, "SASL Secret requires Secrets")
+		}
+		var creds struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}
+		if err := s.Secrets.GetLatestJsonSecretValue(ctx, s.Secret, &creds); err != nil {
+			return nil, errs.Errorf("failed to read SASL credentials from %s: %w", s.Secret, err)
+		}
+		username, password = creds.Username, creds.Password
+	}
+
+	switch strings.ToUpper(s.Mechanism) {
+	case MechanismPlain:
+		return plain.Auth{User: username, Pass: password}.AsMechanism(), nil
+	case MechanismSCRAMSHA256:
+		return scram.Auth{User: username, Pass: password}.AsSha256Mechanism(), nil
+	case MechanismSCRAMSHA512:
+		return scram.Auth{User: username, Pass: password}.AsSha512Mechanism(), nil
+	}
+	return nil, errs.Newf(errs.Internal, "unsupported SASL mechanism %q", s.Mechanism)
+}
+
+// kgoLogger forwards franz-go logs at warn and above
+type kgoLogger struct {
+	logger logging.Logger
+}
+
+func (l *kgoLogger) Level() kgo.LogLevel {
+	return kgo.LogLevelWarn
+}
+
+func (l *kgoLogger) Log(level kgo.LogLevel, msg string, keyvals ...any) {
+	entry := l.logger.WithField("component", "kafka")
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if k, ok := keyvals[i].(string); ok {
+			entry = entry.WithField(k, keyvals[i+1])
+		}
+	}
+	if level == kgo.LogLevelError {
+		entry.Error(msg)
+		return
+	}
+	entry.Warn(msg)
+}
+
+// marshal encodes values as JSON; []byte and json.RawMessage are sent as-is
+func marshal(value any) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case json.RawMessage:
+		return v, nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, errs.Errorf("failed to marshal record value: %w", err)
+	}
+	return b, nil
+}
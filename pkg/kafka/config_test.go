@@ -0,0 +1,51 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bdlilley/easygo/pkg/egaws/egawstest"
+)
+
+func TestSASLOptions(t *testing.T) {
+	ctx := context.Background()
+	secrets := egawstest.NewSecretsStore()
+	if err := secrets.SetJSONSecret("kafka", map[string]string{"username": "svc", "password": "pw"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &ClientConfig{
+		Brokers: []string{"localhost:9092"},
+		SASL:    &SASLConfig{Mechanism: MechanismSCRAMSHA512, Secret: "kafka", Secrets: secrets},
+	}
+	if _, err := cfg.options(ctx); err == nil {
+		t.Fatal("expected SASL without TLS to be rejected")
+	}
+	cfg.TLS = true
+	if _, err := cfg.options(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.SASL.Mechanism = "GSSAPI"
+	if _, err := cfg.options(ctx); err == nil {
+		t.Fatal("expected unsupported mechanism error")
+	}
+	cfg.SASL = &SASLConfig{Mechanism: MechanismAWSMSKIAM}
+	if _, err := cfg.options(ctx); err == nil {
+		t.Fatal("expected AWS_MSK_IAM without AwsClient to be rejected")
+	}
+	if _, err := (&ClientConfig{}).options(ctx); err == nil {
+		t.Fatal("expected missing brokers error")
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	b, err := marshal(map[string]int{"n": 1})
+	if err != nil || string(b) != `{"n":1}` {
+		t.Fatalf("unexpected %s %v", b, err)
+	}
+	b, err = marshal([]byte("raw"))
+	if err != nil || string(b) != "raw" {
+		t.Fatalf("unexpected %s %v", b, err)
+	}
+}
@@ -0,0 +1,283 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/sirupsen/logrus"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// CommitStrategy controls when consumed offsets are committed. Every strategy
+// is at-least-once: a record is only committed after its handler returns.
+type CommitStrategy int
+
+const (
+	// CommitPeriodic marks handled records and commits them in the background
+	// every AutoCommitInterval and when partitions are revoked
+	CommitPeriodic CommitStrategy = iota
+	// CommitEachBatch commits synchronously after each fetched batch of a partition
+	CommitEachBatch
+	// CommitEachRecord commits synchronously after every record; slowest,
+	// but at most one record is redelivered after a crash
+	CommitEachRecord
+)
+
+// Handler processes one record. A returned error goes to the consumer's
+// ErrorHandler and the record is still committed; retry inside the handler
+// (e.g. with retry.Run) or forward to a dead letter topic to avoid data loss.
+type Handler func(ctx context.Context, record *kgo.Record) error
+
+type NewConsumerArgs struct {
+	ClientConfig
+	// Group is the consumer group (required)
+	Group string
+	// Topics are consumed by the group (required)
+	Topics []string
+	// Handler processes records (required). Records of one partition are
+	// handled in order; partitions are handled concurrently.
+	Handler Handler
+	// Commit is the commit strategy (default: CommitPeriodic)
+	Commit CommitStrategy
+	// AutoCommitInterval is the CommitPeriodic interval (default: 5s)
+	AutoCommitInterval time.Duration
+	// StartFromOldest consumes from the earliest offset when the group has no
+	// committed offset (default: latest)
+	StartFromOldest bool
+	// MaxPollRecords bounds each poll (default: 1000)
+	MaxPollRecords int
+	// ErrorHandler receives handler, fetch and commit errors (default: log
+	// them to Logger at error)
+	ErrorHandler func(record *kgo.Record, err error)
+}
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// Consumer runs a consumer group, handling each assigned partition in its own
+// goroutine. On rebalance the revoked partitions finish their in-flight
+// records and commit before the partitions are released.
+type Consumer struct {
+	client         *kgo.Client
+	handler        Handler
+	commit         CommitStrategy
+	maxPollRecords int
+	errorHandler   func(record *kgo.Record, err error)
+	logger         logging.Logger
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	mu      sync.Mutex
+	workers map[topicPartition]*partitionWorker
+}
+
+// NewConsumer creates a consumer; call Run to start consuming
+func NewConsumer(ctx context.Context, args *NewConsumerArgs) (*Consumer, error) {
+	if args == nil || args.Group == "" || len(args.Topics) == 0 || args.Handler == nil {
+		return nil, errs.New(errs.Internal, "Group, Topics and Handler are required")
+	}
+	opts, err := args.ClientConfig.options(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Consumer{
+		handler:        args.Handler,
+		commit:         args.Commit,
+		maxPollRecords: args.MaxPollRecords,
+		errorHandler:   args.ErrorHandler,
+		logger:         args.Logger,
+		workers:        map[topicPartition]*partitionWorker{},
+	}
+	if c.maxPollRecords <= 0 {
+		c.maxPollRecords = 1000
+	}
+	if c.logger == nil {
+		c.logger = logging.Noop()
+	}
+	if c.errorHandler == nil {
+		c.errorHandler = func(record *kgo.Record, err error) {
+			log := c.logger.WithError(err)
+			if record != nil {
+				log = log.WithFields(logrus.Fields{"topic": record.Topic, "partition": record.Partition, "offset": record.Offset})
+			}
+			log.Error("kafka consumer error")
+		}
+	}
+
+	opts = append(opts,
+		kgo.ConsumerGroup(args.Group),
+		kgo.ConsumeTopics(args.Topics...),
+		kgo.BlockRebalanceOnPoll(),
+		kgo.OnPartitionsAssigned(c.assigned),
+		kgo.OnPartitionsRevoked(c.revoked),
+		kgo.OnPartitionsLost(c.lost),
+	)
+	if args.StartFromOldest {
+		opts = append(opts, kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()))
+	}
+	if c.commit == CommitPeriodic {
+		opts = append(opts, kgo.AutoCommitMarks())
+		if args.AutoCommitInterval > 0 {
+			opts = append(opts, kgo.AutoCommitInterval(args.AutoCommitInterval))
+		}
+	} else {
+		opts = append(opts, kgo.DisableAutoCommit())
+	}
+
+	c.client, err = kgo.NewClient(opts...)
+	if err != nil {
+		return nil, errs.Errorf("failed to create kafka client: %w", err)
+	}
+	return c, nil
+}
+
+// Client returns the underlying franz-go client
+func (c *Consumer) Client() *kgo.Client {
+	return c.client
+}
+
+// Run consumes until ctx is cancelled, then waits for in-flight records,
+// commits, leaves the group and closes the client. It returns nil after a
+// clean shutdown.
+func (c *Consumer) Run(ctx context.Context) error {
+	c.mu.Lock()
+	c.ctx, c.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	c.mu.Unlock()
+	defer c.cancel()
+
+	for {
+		fetches := c.client.PollRecords(ctx, c.maxPollRecords)
+		if fetches.IsClientClosed() || ctx.Err() != nil {
+			break
+		}
+		fetches.EachError(func(topic string, partition int32, err error) {
+			c.errorHandler(nil, errs.Errorf("fetch from %s[%d] failed: %w", topic, partition, err))
+		})
+		fetches.EachPartition(func(p kgo.FetchTopicPartition) {
+			if len(p.Records) == 0 {
+				return
+			}
+			c.mu.Lock()
+			w := c.workers[topicPartition{p.Topic, p.Partition}]
+			c.mu.Unlock()
+			if w != nil {
+				w.records <- p.Records
+			}
+		})
+		c.client.AllowRebalance()
+	}
+
+	// leaving the group revokes every partition, which drains the workers and commits
+	c.client.CloseAllowingRebalance()
+	return nil
+}
+
+func (c *Consumer) assigned(_ context.Context, _ *kgo.Client, assigned map[string][]int32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for topic, partitions := range assigned {
+		for _, partition := range partitions {
+			w := &partitionWorker{
+				consumer: c,
+				records:  make(chan []*kgo.Record, 4),
+				done:     make(chan struct{}),
+			}
+			c.workers[topicPartition{topic, partition}] = w
+			go w.run(c.ctx)
+		}
+	}
+}
+
+// revoked drains the revoked partitions' workers and commits their offsets
+func (c *Consumer) revoked(ctx context.Context, cl *kgo.Client, revoked map[string][]int32) {
+	c.stopWorkers(revoked)
+	if c.commit == CommitPeriodic {
+		if err := cl.CommitMarkedOffsets(context.WithoutCancel(ctx)); err != nil {
+			c.errorHandler(nil, errs.Errorf("failed to commit revoked partitions: %w", err))
+		}
+	}
+}
+
+// lost stops the lost partitions' workers; their offsets can no longer be committed
+func (c *Consumer) lost(_ context.Context, _ *kgo.Client, lost map[string][]int32) {
+	c.stopWorkers(lost)
+}
+
+func (c *Consumer) stopWorkers(partitions map[string][]int32) {
+	var stopping []*partitionWorker
+	c.mu.Lock()
+	for topic, ps := range partitions {
+		for _, partition := range ps {
+			tp := topicPartition{topic, partition}
+			if w, ok := c.workers[tp]; ok {
+				delete(c.workers, tp)
+				close(w.records)
+				stopping = append(stopping, w)
+			}
+		}
+	}
+	c.mu.Unlock()
+	for _, w := range stopping {
+		<-w.done
+	}
+}
+
+// partitionWorker handles the records of one partition in order
+type partitionWorker struct {
+	consumer *Consumer
+	records  chan []*kgo.Record
+	done     chan struct{}
+}
+
+func (w *partitionWorker) run(ctx context.Context) {
+	defer close(w.done)
+	c := w.consumer
+	for batch := range w.records {
+		for _, r := range batch {
+			if err := w.handle(ctx, r); err != nil {
+				c.errorHandler(r, err)
+			}
+			switch c.commit {
+			case CommitPeriodic:
+				c.client.MarkCommitRecords(r)
+			case CommitEachRecord:
+				w.commitRecords(ctx, r)
+			}
+		}
+		if c.commit == CommitEachBatch {
+			w.commitRecords(ctx, batch[len(batch)-1])
+		}
+	}
+}
+
+// handle calls the handler, turning a panic into an error
+func (w *partitionWorker) handle(ctx context.Context, r *kgo.Record) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = errs.Newf(errs.Internal, "handler panic: %v", rec)
+		}
+	}()
+	start := time.Now()
+	err = w.consumer.handler(ctx, r)
+	logging.WithTrace(ctx, w.consumer.logger).WithFields(logrus.Fields{
+		"topic":     r.Topic,
+		"partition": r.Partition,
+		"offset":    r.Offset,
+		"elapsed":   time.Since(start).String(),
+	}).Debug("handled kafka record")
+	return err
+}
+
+func (w *partitionWorker) commitRecords(ctx context.Context, r *kgo.Record) {
+	err := w.consumer.client.CommitRecords(ctx, r)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		w.consumer.errorHandler(r, errs.Errorf("commit failed: %w", err))
+	}
+}
@@ -0,0 +1,203 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/retry"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/network"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// kafkaBrokersEnv names an already running broker, e.g. a CI service
+// container, used instead of starting one
+const kafkaBrokersEnv = "EASYGO_KAFKA_BROKERS"
+
+// startKafka returns the brokers of a single-node Kafka in a container, or
+// those in $EASYGO_KAFKA_BROKERS. The test is skipped in -short mode and when
+// neither Docker nor $EASYGO_KAFKA_BROKERS is available.
+func startKafka(t *testing.T) []string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping Kafka test in short mode")
+	}
+	if brokers := os.Getenv(kafkaBrokersEnv); brokers != "" {
+		return strings.Split(brokers, ",")
+	}
+	ctx := context.Background()
+	if err := dockerHealth(ctx); err != nil {
+		t.Skipf("skipping Kafka test, Docker is not available: %v", err)
+	}
+
+	// the broker advertises the host port, so it is chosen before starting
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+	l.Close()
+
+	ctr, err := testcontainers.Run(ctx, "apache/kafka:3.9.1",
+		testcontainers.WithExposedPorts("9092/tcp"),
+		testcontainers.WithHostConfigModifier(func(hc *container.HostConfig) {
+			hc.PortBindings = network.PortMap{network.MustParsePort("9092/tcp"): {{HostPort: port}}}
+		}),
+		testcontainers.WithEnv(map[string]string{
+			"KAFKA_LISTENERS":                        "PLAINTEXT://:9092,CONTROLLER://:9093",
+			"KAFKA_ADVERTISED_LISTENERS":             "PLAINTEXT://127.0.0.1:" + port,
+			"KAFKA_OFFSETS_TOPIC_NUM_PARTITIONS":     "1",
+			"KAFKA_GROUP_INITIAL_REBALANCE_DELAY_MS": "0",
+		}),
+		testcontainers.WithWaitStrategy(wait.ForListeningPort("9092/tcp")),
+	)
+	testcontainers.CleanupContainer(t, ctr)
+	if err != nil {
+		t.Fatalf("failed to start Kafka: %v", err)
+	}
+	return []string{"127.0.0.1:" + port}
+}
+
+// dockerHealth reports whether a Docker provider is reachable; testcontainers
+// panics rather than failing in some environments without one
+func dockerHealth(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	provider, err := testcontainers.ProviderDocker.GetProvider()
+	if err != nil {
+		return err
+	}
+	defer provider.Close()
+	return provider.Health(ctx)
+}
+
+func newTestProducer(t *testing.T, brokers []string, topic string, errorHandler func(*kgo.Record, error)) *Producer {
+	t.Helper()
+	p, err := NewProducer(context.Background(), &NewProducerArgs{
+		ClientConfig: ClientConfig{Brokers: brokers, Options: []kgo.Opt{kgo.AllowAutoTopicCreation()}},
+		Topic:        topic,
+		ErrorHandler: errorHandler,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = p.Close(context.Background()) })
+	return p
+}
+
+// consume runs a consumer in group until want records were handled
+func consume(t *testing.T, brokers []string, topic, group string, want int, handler Handler) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var mu sync.Mutex
+	handled := 0
+	c, err := NewConsumer(ctx, &NewConsumerArgs{
+		ClientConfig:    ClientConfig{Brokers: brokers},
+		Group:           group,
+		Topics:          []string{topic},
+		Commit:          CommitEachRecord,
+		StartFromOldest: true,
+		Handler: func(ctx context.Context, r *kgo.Record) error {
+			err := handler(ctx, r)
+			mu.Lock()
+			defer mu.Unlock()
+			if handled++; handled == want {
+				cancel()
+			}
+			return err
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Fatalf("timed out after handling %d of %d records", handled, want)
+	}
+}
+
+func TestConsumer(t *testing.T) {
+	brokers := startKafka(t)
+	ctx := context.Background()
+	p := newTestProducer(t, brokers, "orders", nil)
+	for _, id := range []string{"a", "b", "c"} {
+		if err := p.Send(ctx, id, map[string]string{"id": id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the handler retries a failing record before moving on
+	attempts := map[string]int{}
+	consume(t, brokers, "orders", "billing", 3, func(ctx context.Context, r *kgo.Record) error {
+		return retry.Run(ctx, func(context.Context) error {
+			attempts[string(r.Key)]++
+			if string(r.Key) == "b" && attempts["b"] < 2 {
+				return errors.New("not yet")
+			}
+			return nil
+		}, &retry.Options{Backoff: retry.Backoff{Initial: time.Millisecond}})
+	})
+	if attempts["a"] != 1 || attempts["b"] != 2 || attempts["c"] != 1 {
+		t.Fatalf("got attempts %v", attempts)
+	}
+
+	// committed records are not redelivered to the group
+	if err := p.Send(ctx, "d", map[string]string{"id": "d"}); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	consume(t, brokers, "orders", "billing", 1, func(_ context.Context, r *kgo.Record) error {
+		keys = append(keys, string(r.Key))
+		return nil
+	})
+	if len(keys) != 1 || keys[0] != "d" {
+		t.Fatalf("got %v after the commit, want only d", keys)
+	}
+}
+
+func TestProducerDeliveryErrors(t *testing.T) {
+	brokers := startKafka(t)
+	ctx := context.Background()
+
+	failed := make(chan error, 1)
+	p := newTestProducer(t, brokers, "events", func(_ *kgo.Record, err error) { failed <- err })
+	if err := p.SendAsync(ctx, "ok", map[string]string{"id": "ok"}); err != nil {
+		t.Fatal(err)
+	}
+	// larger than the client's 1MB batch limit, so delivery fails
+	if err := p.SendAsync(ctx, "big", strings.Repeat("x", 2<<20)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-failed:
+		if !errors.Is(err, kerr.MessageTooLarge) {
+			t.Fatalf("got %v, want MESSAGE_TOO_LARGE", err)
+		}
+	default:
+		t.Fatal("the failed delivery was not reported")
+	}
+	if err := p.SendAsync(ctx, "x", make(chan int)); err == nil {
+		t.Fatal("expected an encoding error to be returned")
+	}
+}
@@ -0,0 +1,122 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+type NewProducerArgs struct {
+	ClientConfig
+	// Topic is the default topic for Send and SendAsync (required)
+	Topic string
+	// Key derives a record key from the value when Send is called with an
+	// empty key, e.g. the entity ID so its events stay ordered on one partition.
+	// Without it records with no key are spread across partitions.
+	Key func(value any) string
+	// ErrorHandler receives SendAsync delivery failures (default: log them to
+	// Logger at error)
+	ErrorHandler func(record *kgo.Record, err error)
+}
+
+// Producer sends JSON records to a topic. It is safe for concurrent use.
+type Producer struct {
+	client       *kgo.Client
+	topic        string
+	key          func(value any) string
+	errorHandler func(record *kgo.Record, err error)
+}
+
+// NewProducer creates a producer; call Close to flush buffered records
+func NewProducer(ctx context.Context, args *NewProducerArgs) (*Producer, error) {
+	if args == nil || args.Topic == "" {
+		return nil, errs.New(errs.Internal, "Topic is required")
+	}
+	opts, err := args.ClientConfig.options(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts = append([]kgo.Opt{kgo.DefaultProduceTopic(args.Topic)}, opts...)
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, errs.Errorf("failed to create kafka client: %w", err)
+	}
+
+	p := &Producer{
+		client:       client,
+		topic:        args.Topic,
+		key:          args.Key,
+		errorHandler: args.ErrorHandler,
+	}
+	if p.errorHandler == nil {
+		logger := args.Logger
+		if logger == nil {
+			logger = logging.Noop()
+		}
+		p.errorHandler = func(record *kgo.Record, err error) {
+			logger.WithError(err).WithField("topic", record.Topic).Error("failed to deliver kafka record")
+		}
+	}
+	return p, nil
+}
+
+// Client returns the underlying franz-go client
+func (p *Producer) Client() *kgo.Client {
+	return p.client
+}
+
+func (p *Producer) record(key string, value any) (*kgo.Record, error) {
+	b, err := marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" && p.key != nil {
+		key = p.key(value)
+	}
+	r := &kgo.Record{Topic: p.topic, Value: b}
+	if key != "" {
+		r.Key = []byte(key)
+	}
+	return r, nil
+}
+
+// Send encodes value as JSON and waits until the record is acknowledged
+func (p *Producer) Send(ctx context.Context, key string, value any) error {
+	r, err := p.record(key, value)
+	if err != nil {
+		return err
+	}
+	if err := p.client.ProduceSync(ctx, r).FirstErr(); err != nil {
+		return errs.Errorf("failed to produce to %s: %w", p.topic, err)
+	}
+	return nil
+}
+
+// SendAsync encodes value as JSON and buffers it for delivery; delivery
+// failures go to ErrorHandler. An encoding error is returned immediately.
+func (p *Producer) SendAsync(ctx context.Context, key string, value any) error {
+	r, err := p.record(key, value)
+	if err != nil {
+		return err
+	}
+	p.client.Produce(ctx, r, func(r *kgo.Record, err error) {
+		if err != nil {
+			p.errorHandler(r, err)
+		}
+	})
+	return nil
+}
+
+// Flush waits until buffered records are delivered or ctx is done
+func (p *Producer) Flush(ctx context.Context) error {
+	return p.client.Flush(ctx)
+}
+
+// Close flushes buffered records and closes the client
+func (p *Producer) Close(ctx context.Context) error {
+	err := p.client.Flush(ctx)
+	p.client.Close()
+	return err
+}
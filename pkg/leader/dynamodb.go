@@ -0,0 +1,25 @@
+package leader
+
+import (
+	"context"
+
+	"github.com/bdlilley/easygo"
+)
+
+// NewDynamoDBBackend holds leadership as a lock in locker's table, keyed by
+// the election name. The lease duration and owner come from the locker's options.
+func NewDynamoDBBackend(locker *easygo.DynamoDBLocker) Backend {
+	return &dynamoDBBackend{locker: locker}
+}
+
+type dynamoDBBackend struct {
+	locker *easygo.DynamoDBLocker
+}
+
+func (b *dynamoDBBackend) Campaign(ctx context.Context, name string) (Lease, error) {
+	lock, err := b.locker.Acquire(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
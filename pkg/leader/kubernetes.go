@@ -0,0 +1,129 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+type NewKubernetesBackendArgs struct {
+	// Clientset manages Lease objects, e.g. k8s.Client.Clientset (required)
+	Clientset kubernetes.Interface
+	// Namespace holds the Lease objects, e.g. k8s.Client.Namespace() (required)
+	Namespace string
+	// Identity is this process's holder identity (default: hostname plus a random suffix)
+	Identity string
+	// LeaseDuration is how long followers wait before taking over an unrenewed lease (default: 15s)
+	LeaseDuration time.Duration
+	// RenewDeadline is how long the leader retries renewal before giving up (default: 10s)
+	RenewDeadline time.Duration
+	// RetryPeriod is the interval between acquire and renew attempts (default: 2s)
+	RetryPeriod time.Duration
+}
+
+// NewKubernetesBackend holds leadership with coordination.k8s.io Leases named
+// after the election
+func NewKubernetesBackend(args *NewKubernetesBackendArgs) (Backend, error) {
+	if args == nil || args.Clientset == nil || args.Namespace == "" {
+		return nil, errs.New(errs.Internal, "leader: Clientset and Namespace are required")
+	}
+	b := &kubernetesBackend{NewKubernetesBackendArgs: *args}
+	if b.Identity == "" {
+		b.Identity = defaultIdentity()
+	}
+	if b.LeaseDuration <= 0 {
+		b.LeaseDuration = 15 * time.Second
+	}
+	if b.RenewDeadline <= 0 {
+		b.RenewDeadline = 10 * time.Second
+	}
+	if b.RetryPeriod <= 0 {
+		b.RetryPeriod = 2 * time.Second
+	}
+	return b, nil
+}
+
+type kubernetesBackend struct {
+	NewKubernetesBackendArgs
+}
+
+func (b *kubernetesBackend) Campaign(ctx context.Context, name string) (Lease, error) {
+	acquired := make(chan struct{})
+	lease := &kubernetesLease{lost: make(chan struct{}), done: make(chan struct{})}
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	lease.cancel = cancel
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Name: name, Namespace: b.Namespace},
+			Client:     b.Clientset.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: b.Identity},
+		},
+		LeaseDuration:   b.LeaseDuration,
+		RenewDeadline:   b.RenewDeadline,
+		RetryPeriod:     b.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) { close(acquired) },
+			OnStoppedLeading: lease.stopped,
+		},
+	})
+	if err != nil {
+		cancel()
+		return nil, errs.Errorf("invalid leader election config for %s: %w", name, err)
+	}
+
+	go func() {
+		defer close(lease.done)
+		elector.Run(runCtx)
+	}()
+
+	select {
+	case <-acquired:
+		return lease, nil
+	case <-ctx.Done():
+		_ = lease.Release(context.Background())
+		return nil, ctx.Err()
+	}
+}
+
+// kubernetesLease is leadership held by a running LeaderElector
+type kubernetesLease struct {
+	cancel   context.CancelFunc
+	released atomic.Bool
+	lost     chan struct{}
+	lostOnce sync.Once
+	done     chan struct{}
+}
+
+func (l *kubernetesLease) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// stopped is called when the elector exits, which is a loss unless Release stopped it
+func (l *kubernetesLease) stopped() {
+	if !l.released.Load() {
+		l.lostOnce.Do(func() { close(l.lost) })
+	}
+}
+
+// Release stops the elector, which clears the Lease holder so a follower can
+// take over without waiting for it to expire
+func (l *kubernetesLease) Release(ctx context.Context) error {
+	l.released.Store(true)
+	l.cancel()
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
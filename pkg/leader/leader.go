@@ -0,0 +1,239 @@
+// Package leader elects a single leader among replicas so work such as the
+// scheduler or backfill jobs runs in one process at a time. Leadership is
+// held through a Kubernetes Lease or a DynamoDB lock.
+package leader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/bdlilley/easygo/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Backend campaigns for leadership of a named election
+type Backend interface {
+	// Campaign blocks until leadership of name is acquired or ctx is done
+	Campaign(ctx context.Context, name string) (Lease, error)
+}
+
+// Lease is held leadership
+type Lease interface {
+	// Lost is closed when leadership could not be renewed
+	Lost() <-chan struct{}
+	// Release stops renewal and gives up leadership
+	Release(ctx context.Context) error
+}
+
+type NewElectorArgs struct {
+	// Backend holds leadership (required)
+	Backend Backend
+	// Name identifies the election; replicas running the same work use the same name (required)
+	Name string
+	// OnElected is called when this process becomes leader, before the work starts
+	OnElected func(ctx context.Context)
+	// OnDemoted is called after the work stops and leadership is released or lost
+	OnDemoted func(lost bool)
+	// RetryInterval is how long to wait after a failed campaign (default: 5s)
+	RetryInterval time.Duration
+	// ReleaseTimeout bounds releasing leadership after the work stops (default: 10s)
+	ReleaseTimeout time.Duration
+	// Registerer registers the election metrics (default: prometheus.DefaultRegisterer)
+	Registerer prometheus.Registerer
+	// Namespace prefixes metric names, e.g. "myapp" gives myapp_leader_is_leader
+	Namespace string
+	// Logger logs leadership changes (default: logging.Noop)
+	Logger logging.Logger
+}
+
+// Elector runs work only while this process is the leader
+type Elector struct {
+	backend        Backend
+	name           string
+	onElected      func(ctx context.Context)
+	onDemoted      func(lost bool)
+	retryInterval  time.Duration
+	releaseTimeout time.Duration
+	metrics        *electionMetrics
+	logger         logging.Logger
+
+	leader atomic.Bool
+}
+
+// New creates an Elector
+func New(args *NewElectorArgs) (*Elector, error) {
+	if args == nil || args.Backend == nil || args.Name == "" {
+		return nil, errs.New(errs.Internal, "leader: Backend and Name are required")
+	}
+	e := &Elector{
+		backend:        args.Backend,
+		name:           args.Name,
+		onElected:      args.OnElected,
+		onDemoted:      args.OnDemoted,
+		retryInterval:  args.RetryInterval,
+		releaseTimeout: args.ReleaseTimeout,
+		logger:         args.Logger,
+	}
+	if e.retryInterval <= 0 {
+		e.retryInterval = 5 * time.Second
+	}
+	if e.releaseTimeout <= 0 {
+		e.releaseTimeout = 10 * time.Second
+	}
+	if e.logger == nil {
+		e.logger = logging.Noop()
+	}
+	reg := args.Registerer
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	e.metrics = newElectionMetrics(reg, args.Namespace)
+	e.metrics.isLeader.WithLabelValues(e.name).Set(0)
+	return e, nil
+}
+
+// IsLeader reports whether this process currently holds leadership
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// RunWhenLeader campaigns for leadership and runs fn while it is held. fn's
+// context is cancelled when leadership is lost, after which the elector
+// campaigns again and runs fn anew once re-elected. RunWhenLeader returns fn's
+// result when fn returns on its own, or nil once ctx is done.
+func (e *Elector) RunWhenLeader(ctx context.Context, fn func(ctx context.Context) error) error {
+	logger := logging.WithTrace(ctx, e.logger).WithField("election", e.name)
+	for {
+		lease, err := e.backend.Campaign(ctx, e.name)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			logger.WithError(err).Error("leader campaign failed")
+			t := time.NewTimer(e.retryInterval)
+			select {
+			case <-t.C:
+				continue
+			case <-ctx.Done():
+				t.Stop()
+				return nil
+			}
+		}
+
+		if ctx.Err() != nil {
+			e.release(ctx, logger, lease)
+			return nil
+		}
+
+		lost, err := e.lead(ctx, logger, lease, fn)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if lost {
+			logger.Warn("leadership lost")
+			continue
+		}
+		return err
+	}
+}
+
+// lead runs fn while lease is held and reports whether it was lost
+func (e *Elector) lead(ctx context.Context, logger logging.Logger, lease Lease, fn func(ctx context.Context) error) (lost bool, err error) {
+	leadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-lease.Lost():
+			cancel()
+		case <-leadCtx.Done():
+		}
+	}()
+
+	e.leader.Store(true)
+	e.metrics.isLeader.WithLabelValues(e.name).Set(1)
+	e.metrics.elected.WithLabelValues(e.name).Inc()
+	logger.Info("elected leader")
+	if e.onElected != nil {
+		e.onElected(leadCtx)
+	}
+
+	err = e.run(leadCtx, fn)
+	cancel()
+
+	select {
+	case <-lease.Lost():
+		lost = true
+	default:
+		e.release(ctx, logger, lease)
+	}
+
+	e.leader.Store(false)
+	e.metrics.isLeader.WithLabelValues(e.name).Set(0)
+	if lost {
+		e.metrics.lost.WithLabelValues(e.name).Inc()
+	}
+	logger.WithField("lost", lost).Info("stepped down as leader")
+	if e.onDemoted != nil {
+		e.onDemoted(lost)
+	}
+	return lost, err
+}
+
+func (e *Elector) release(ctx context.Context, logger logging.Logger, lease Lease) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), e.releaseTimeout)
+	defer cancel()
+	if err := lease.Release(ctx); err != nil {
+		logger.WithError(err).Warn("failed to release leadership")
+	}
+}
+
+// run calls fn, converting a panic into an error so leadership is still released
+func (e *Elector) run(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errs.Newf(errs.Internal, "leader: panic in %s: %v", e.name, r)
+		}
+	}()
+	return fn(ctx)
+}
+
+type electionMetrics struct {
+	isLeader *prometheus.GaugeVec
+	elected  *prometheus.CounterVec
+	lost     *prometheus.CounterVec
+}
+
+func newElectionMetrics(reg prometheus.Registerer, namespace string) *electionMetrics {
+	labels := []string{"election"}
+	return &electionMetrics{
+		isLeader: metrics.RegisterCollector(reg, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "leader_is_leader",
+			Help:      "1 while this process holds leadership of the election.",
+		}, labels)),
+		elected: metrics.RegisterCollector(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "leader_elected_total",
+			Help:      "Times this process became leader.",
+		}, labels)),
+		lost: metrics.RegisterCollector(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "leader_lost_total",
+			Help:      "Times this process lost leadership before its work finished.",
+		}, labels)),
+	}
+}
+
+// defaultIdentity is the hostname plus a random suffix, unique per process
+func defaultIdentity() string {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return host + "-" + hex.EncodeToString(suffix)
+}
@@ -0,0 +1,208 @@
+package leader
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// memoryBackend grants leadership to one lease at a time
+type memoryBackend struct {
+	mu     sync.Mutex
+	holder *memoryLease
+}
+
+type memoryLease struct {
+	backend *memoryBackend
+	lost    chan struct{}
+}
+
+func (b *memoryBackend) Campaign(ctx context.Context, name string) (Lease, error) {
+	for {
+		b.mu.Lock()
+		if b.holder == nil {
+			b.holder = &memoryLease{backend: b, lost: make(chan struct{})}
+			l := b.holder
+			b.mu.Unlock()
+			return l, nil
+		}
+		b.mu.Unlock()
+		select {
+		case <-time.After(time.Millisecond):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// revoke simulates a failed renewal; the lease is free once expire is called
+func (b *memoryBackend) revoke() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	close(b.holder.lost)
+}
+
+func (b *memoryBackend) expire() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.holder = nil
+}
+
+func (l *memoryLease) Lost() <-chan struct{} {
+	return l.lost
+}
+
+func (l *memoryLease) Release(ctx context.Context) error {
+	l.backend.mu.Lock()
+	defer l.backend.mu.Unlock()
+	if l.backend.holder == l {
+		l.backend.holder = nil
+	}
+	return nil
+}
+
+func TestRunWhenLeader(t *testing.T) {
+	backend := &memoryBackend{}
+	reg := prometheus.NewRegistry()
+	var running, runs atomic.Int32
+	var demotions []bool
+	var mu sync.Mutex
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	electors := make([]*Elector, 2)
+	for i := range electors {
+		e, err := New(&NewElectorArgs{
+			Backend:    backend,
+			Name:       "jobs",
+			Registerer: reg,
+			OnDemoted: func(lost bool) {
+				mu.Lock()
+				demotions = append(demotions, lost)
+				mu.Unlock()
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		electors[i] = e
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = e.RunWhenLeader(ctx, func(ctx context.Context) error {
+				if running.Add(1) > 1 {
+					t.Error("two leaders running at once")
+				}
+				runs.Add(1)
+				<-ctx.Done()
+				running.Add(-1)
+				return ctx.Err()
+			})
+		}()
+	}
+
+	waitFor(t, func() bool { return runs.Load() == 1 })
+	backend.revoke()
+	waitFor(t, func() bool { return running.Load() == 0 })
+	backend.expire()
+	waitFor(t, func() bool { return runs.Load() == 2 })
+	leaders := 0
+	for _, e := range electors {
+		if e.IsLeader() {
+			leaders++
+		}
+	}
+	if leaders != 1 {
+		t.Fatalf("expected one leader, got %d", leaders)
+	}
+
+	cancel()
+	wg.Wait()
+	if n := testutil.ToFloat64(electors[0].metrics.lost.WithLabelValues("jobs")); n != 1 {
+		t.Fatalf("expected one lost leadership, got %v", n)
+	}
+	if n := testutil.ToFloat64(electors[0].metrics.isLeader.WithLabelValues("jobs")); n != 0 {
+		t.Fatalf("expected no leader after shutdown, got %v", n)
+	}
+	if len(demotions) < 2 || !demotions[0] || slices.Contains(demotions[1:], true) {
+		t.Fatalf("unexpected demotions %v", demotions)
+	}
+}
+
+func TestRunWhenLeaderReturnsResult(t *testing.T) {
+	e, err := New(&NewElectorArgs{Backend: &memoryBackend{}, Name: "once", Registerer: prometheus.NewRegistry()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.RunWhenLeader(context.Background(), func(ctx context.Context) error {
+		return context.DeadlineExceeded
+	}); err != context.DeadlineExceeded {
+		t.Fatalf("expected fn's error, got %v", err)
+	}
+}
+
+func TestKubernetesBackend(t *testing.T) {
+	clientset := fake.NewClientset()
+	newBackend := func(id string) Backend {
+		b, err := NewKubernetesBackend(&NewKubernetesBackendArgs{
+			Clientset:     clientset,
+			Namespace:     "default",
+			Identity:      id,
+			LeaseDuration: 2 * time.Second,
+			RenewDeadline: time.Second,
+			RetryPeriod:   50 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	ctx := context.Background()
+	lease, err := newBackend("a").Campaign(ctx, "jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	if _, err := newBackend("b").Campaign(waitCtx, "jobs"); err == nil {
+		t.Fatal("expected campaign to wait while the lease is held")
+	}
+
+	if err := lease.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-lease.Lost():
+		t.Fatal("released lease reported as lost")
+	default:
+	}
+
+	waitCtx, cancel = context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	next, err := newBackend("b").Campaign(waitCtx, "jobs")
+	if err != nil {
+		t.Fatalf("expected released lease to be acquired: %v", err)
+	}
+	_ = next.Release(ctx)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
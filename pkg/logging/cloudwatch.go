@@ -0,0 +1,328 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/bdlilley/easygo/pkg/batcher"
+	egerrors "github.com/bdlilley/easygo/pkg/egaws/errors"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/sirupsen/logrus"
+)
+
+// CloudWatch Logs PutLogEvents limits
+const (
+	cloudWatchMaxBatchEvents = 10000
+	cloudWatchMaxBatchBytes  = 1048576
+	cloudWatchEventOverhead  = 26
+	cloudWatchMaxEventBytes  = 262144 - cloudWatchEventOverhead
+	cloudWatchMaxBatchSpan   = 24 * time.Hour
+)
+
+// cloudWatchMaxAttempts bounds the flushes an event is sent in before it is
+// dropped, so events failing for long do not crowd out new ones
+const cloudWatchMaxAttempts = 5
+
+// CloudWatchLogsAPI is the subset of the CloudWatch Logs client used by CloudWatchHook
+type CloudWatchLogsAPI interface {
+	PutLogEvents(ctx context.Context, params *cloudwatchlogs.PutLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutLogEventsOutput, error)
+	CreateLogGroup(ctx context.Context, params *cloudwatchlogs.CreateLogGroupInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogGroupOutput, error)
+	CreateLogStream(ctx context.Context, params *cloudwatchlogs.CreateLogStreamInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogStreamOutput, error)
+}
+
+type NewCloudWatchHookArgs struct {
+	// Config is the AWS config used to build the CloudWatch Logs client,
+	// typically EGAwsClient.GetConfig()
+	Config aws.Config
+	// Client overrides the CloudWatch Logs client built from Config
+	Client        CloudWatchLogsAPI
+	LogGroupName  string
+	LogStreamName string
+	// DisableAutoCreate disables creation of the log group and stream when they do not exist
+	DisableAutoCreate bool
+	// Formatter formats entries passed to Fire (default: logrus.JSONFormatter)
+	Formatter logrus.Formatter
+	// Levels limits the levels the hook fires for (default: logrus.AllLevels)
+	Levels []logrus.Level
+	// BatchSize is the number of buffered events that triggers a flush (default: 1000, max: 10000)
+	BatchSize int
+	// FlushInterval is the maximum time events wait in the buffer (default: 5s)
+	FlushInterval time.Duration
+	// MaxBufferSize bounds the number of buffered events; new events are dropped when full (default: 10000)
+	MaxBufferSize int
+	// ErrorHandler receives errors from background flushes (default: log them
+	// to Logger)
+	ErrorHandler func(error)
+	// Logger logs failed flushes at error without an ErrorHandler, and should
+	// not ship to this hook (default: logging.Noop)
+	Logger Logger
+}
+
+type cloudWatchEvent struct {
+	message   string
+	timestamp int64
+	attempts  int
+}
+
+func (ev cloudWatchEvent) size() int {
+	return len(ev.message) + cloudWatchEventOverhead
+}
+
+// CloudWatchHook is a logrus hook and io.Writer that batches log entries and ships them to CloudWatch Logs
+type CloudWatchHook struct {
+	client        CloudWatchLogsAPI
+	logGroupName  string
+	logStreamName string
+	autoCreate    bool
+	formatter     logrus.Formatter
+	levels        []logrus.Level
+	errorHandler  func(error)
+
+	batcher *batcher.Batcher[cloudWatchEvent]
+	dropped atomic.Int64
+
+	// flushMu serializes PutLogEvents calls so the sequence token stays consistent
+	flushMu       sync.Mutex
+	sequenceToken *string
+}
+
+// NewCloudWatchHook creates a CloudWatchHook and starts its background flusher.
+// Call Close to flush remaining events and stop the flusher.
+func NewCloudWatchHook(args *NewCloudWatchHookArgs) (*CloudWatchHook, error) {
+	if args.LogGroupName == "" || args.LogStreamName == "" {
+		return nil, errs.New(errs.Internal, "LogGroupName and LogStreamName are required")
+	}
+
+	h := &CloudWatchHook{
+		client:        args.Client,
+		logGroupName:  args.LogGroupName,
+		logStreamName: args.LogStreamName,
+		autoCreate:    !args.DisableAutoCreate,
+		formatter:     args.Formatter,
+		levels:        args.Levels,
+		errorHandler:  args.ErrorHandler,
+	}
+	if h.client == nil {
+		h.client = cloudwatchlogs.NewFromConfig(args.Config)
+	}
+	if h.formatter == nil {
+		h.formatter = &logrus.JSONFormatter{}
+	}
+	if len(h.levels) == 0 {
+		h.levels = logrus.AllLevels
+	}
+	if h.errorHandler == nil {
+		logger := args.Logger
+		if logger == nil {
+			logger = Noop()
+		}
+		h.errorHandler = func(err error) {
+			logger.WithError(err).Error("failed to flush CloudWatch log events")
+		}
+	}
+
+	batchSize := args.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	if batchSize > cloudWatchMaxBatchEvents {
+		batchSize = cloudWatchMaxBatchEvents
+	}
+	maxBufferSize := args.MaxBufferSize
+	if maxBufferSize <= 0 {
+		maxBufferSize = 10000
+	}
+	flushInterval := args.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = 5 * time.Second
+	}
+	h.batcher = batcher.New(h.flush, &batcher.Options[cloudWatchEvent]{
+		MaxItems:     batchSize,
+		MaxBytes:     cloudWatchMaxBatchBytes,
+		Size:         cloudWatchEvent.size,
+		MaxAge:       flushInterval,
+		QueueSize:    maxBufferSize,
+		ErrorHandler: func(_ []cloudWatchEvent, err error) { h.errorHandler(err) },
+	})
+
+	return h, nil
+}
+
+// Levels implements logrus.Hook
+func (h *CloudWatchHook) Levels() []logrus.Level {
+	return h.levels
+}
+
+// Fire implements logrus.Hook
+func (h *CloudWatchHook) Fire(entry *logrus.Entry) error {
+	b, err := h.formatter.Format(entry)
+	if err != nil {
+		return errs.Errorf("failed to format log entry: %w", err)
+	}
+	h.enqueue(string(b), entry.Time)
+	return nil
+}
+
+// Write implements io.Writer so the hook can be used as a logger output
+func (h *CloudWatchHook) Write(p []byte) (int, error) {
+	h.enqueue(string(p), time.Now())
+	return len(p), nil
+}
+
+// Dropped returns the number of events dropped because the buffer was full,
+// CloudWatch Logs rejected them, or their flushes kept failing
+func (h *CloudWatchHook) Dropped() int64 {
+	return h.dropped.Load()
+}
+
+// Flush sends all buffered events to CloudWatch Logs
+func (h *CloudWatchHook) Flush(ctx context.Context) error {
+	return h.batcher.Flush(ctx)
+}
+
+// Close stops the background flusher and flushes remaining events
+func (h *CloudWatchHook) Close(ctx context.Context) error {
+	return h.batcher.Close(ctx)
+}
+
+func (h *CloudWatchHook) enqueue(message string, t time.Time) {
+	h.add(cloudWatchEvent{message: truncateUTF8(message, cloudWatchMaxEventBytes), timestamp: t.UnixMilli()})
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a rune, since
+// CloudWatch Logs rejects events that are not valid UTF-8
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// add buffers ev without blocking the logger, dropping it when the buffer is full
+func (h *CloudWatchHook) add(ev cloudWatchEvent) {
+	if err := h.batcher.TryAdd(ev); err != nil {
+		h.dropped.Add(1)
+	}
+}
+
+// flush sends a batch in timestamp order, split into PutLogEvents calls that
+// span at most 24 hours. Events not sent after a retryable error are requeued
+// for the next flush, up to cloudWatchMaxAttempts; others are dropped.
+func (h *CloudWatchHook) flush(ctx context.Context, batch []cloudWatchEvent) error {
+	sort.SliceStable(batch, func(i, j int) bool {
+		return batch[i].timestamp < batch[j].timestamp
+	})
+	for len(batch) > 0 {
+		n := 1
+		for n < len(batch) && time.Duration(batch[n].timestamp-batch[0].timestamp)*time.Millisecond <= cloudWatchMaxBatchSpan {
+			n++
+		}
+		if err := h.put(ctx, batch[:n]); err != nil {
+			retryable := egerrors.IsRetryable(err)
+			for _, ev := range batch {
+				if ev.attempts++; retryable && ev.attempts < cloudWatchMaxAttempts {
+					h.add(ev)
+				} else {
+					h.dropped.Add(1)
+				}
+			}
+			return err
+		}
+		batch = batch[n:]
+	}
+	return nil
+}
+
+func (h *CloudWatchHook) put(ctx context.Context, batch []cloudWatchEvent) error {
+	h.flushMu.Lock()
+	defer h.flushMu.Unlock()
+
+	events := make([]types.InputLogEvent, len(batch))
+	for i, ev := range batch {
+		events[i] = types.InputLogEvent{
+			Message:   aws.String(ev.message),
+			Timestamp: aws.Int64(ev.timestamp),
+		}
+	}
+
+	created := false
+	for attempt := 0; attempt < 3; attempt++ {
+		output, err := h.client.PutLogEvents(ctx, &cloudwatchlogs.PutLogEventsInput{
+			LogGroupName:  aws.String(h.logGroupName),
+			LogStreamName: aws.String(h.logStreamName),
+			LogEvents:     events,
+			SequenceToken: h.sequenceToken,
+		})
+		if err == nil {
+			h.sequenceToken = output.NextSequenceToken
+			h.dropped.Add(int64(rejectedLogEvents(output.RejectedLogEventsInfo, len(events))))
+			return nil
+		}
+
+		var invalidToken *types.InvalidSequenceTokenException
+		var alreadyAccepted *types.DataAlreadyAcceptedException
+		var notFound *types.ResourceNotFoundException
+		switch {
+		case errors.As(err, &invalidToken):
+			h.sequenceToken = invalidToken.ExpectedSequenceToken
+		case errors.As(err, &alreadyAccepted):
+			h.sequenceToken = alreadyAccepted.ExpectedSequenceToken
+			return nil
+		case errors.As(err, &notFound) && h.autoCreate && !created:
+			if err := h.createDestination(ctx); err != nil {
+				return err
+			}
+			created = true
+			h.sequenceToken = nil
+		default:
+			return errs.Errorf("failed to put log events: %w", err)
+		}
+	}
+
+	return errs.New(errs.Internal, "failed to put log events: retries exhausted")
+}
+
+// rejectedLogEvents counts the events of a request of n that CloudWatch Logs
+// accepted the request without storing, being too old, expired or too new
+func rejectedLogEvents(info *types.RejectedLogEventsInfo, n int) int {
+	if info == nil {
+		return 0
+	}
+	old := max(aws.ToInt32(info.TooOldLogEventEndIndex), aws.ToInt32(info.ExpiredLogEventEndIndex))
+	if info.TooNewLogEventStartIndex != nil {
+		return min(int(old), n) + n - int(aws.ToInt32(info.TooNewLogEventStartIndex))
+	}
+	return min(int(old), n)
+}
+
+func (h *CloudWatchHook) createDestination(ctx context.Context) error {
+	var exists *types.ResourceAlreadyExistsException
+
+	_, err := h.client.CreateLogGroup(ctx, &cloudwatchlogs.CreateLogGroupInput{
+		LogGroupName: aws.String(h.logGroupName),
+	})
+	if err != nil && !errors.As(err, &exists) {
+		return errs.Errorf("failed to create log group: %w", err)
+	}
+
+	_, err = h.client.CreateLogStream(ctx, &cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(h.logGroupName),
+		LogStreamName: aws.String(h.logStreamName),
+	})
+	if err != nil && !errors.As(err, &exists) {
+		return errs.Errorf("failed to create log stream: %w", err)
+	}
+
+	return nil
+}
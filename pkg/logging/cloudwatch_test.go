@@ -0,0 +1,186 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/smithy-go"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+type fakeCloudWatchLogs struct {
+	mu    sync.Mutex
+	calls [][]string
+}
+
+func (f *fakeCloudWatchLogs) PutLogEvents(_ context.Context, in *cloudwatchlogs.PutLogEventsInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutLogEventsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var msgs []string
+	for _, ev := range in.LogEvents {
+		msgs = append(msgs, aws.ToString(ev.Message))
+	}
+	f.calls = append(f.calls, msgs)
+	return &cloudwatchlogs.PutLogEventsOutput{}, nil
+}
+
+func (f *fakeCloudWatchLogs) CreateLogGroup(context.Context, *cloudwatchlogs.CreateLogGroupInput, ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogGroupOutput, error) {
+	return &cloudwatchlogs.CreateLogGroupOutput{}, nil
+}
+
+func (f *fakeCloudWatchLogs) CreateLogStream(context.Context, *cloudwatchlogs.CreateLogStreamInput, ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogStreamOutput, error) {
+	return &cloudwatchlogs.CreateLogStreamOutput{}, nil
+}
+
+func TestCloudWatchHook(t *testing.T) {
+	client := &fakeCloudWatchLogs{}
+	h, err := NewCloudWatchHook(&NewCloudWatchHookArgs{
+		Client:        client,
+		LogGroupName:  "group",
+		LogStreamName: "stream",
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	h.enqueue("c", now)
+	h.enqueue("a", now.Add(-48*time.Hour))
+	h.enqueue("b", now.Add(-time.Hour))
+	if err := h.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// events are sorted and split so no call spans more than 24 hours
+	if len(client.calls) != 2 || len(client.calls[0]) != 1 || client.calls[0][0] != "a" ||
+		len(client.calls[1]) != 2 || client.calls[1][0] != "b" || client.calls[1][1] != "c" {
+		t.Fatalf("calls = %v", client.calls)
+	}
+
+	h.enqueue("late", now)
+	if h.Dropped() != 1 {
+		t.Fatalf("dropped = %d, want 1 after Close", h.Dropped())
+	}
+}
+
+type failingCloudWatchLogs struct{ fakeCloudWatchLogs }
+
+func (f *failingCloudWatchLogs) PutLogEvents(context.Context, *cloudwatchlogs.PutLogEventsInput, ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutLogEventsOutput, error) {
+	return nil, errors.New("throttled")
+}
+
+func TestCloudWatchHookOversizedEvent(t *testing.T) {
+	client := &fakeCloudWatchLogs{}
+	h, err := NewCloudWatchHook(&NewCloudWatchHookArgs{
+		Client:        client,
+		LogGroupName:  "group",
+		LogStreamName: "stream",
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the limit falls inside a multi-byte rune, which is dropped whole
+	h.enqueue(strings.Repeat("a", cloudWatchMaxEventBytes-1)+"é", time.Now())
+	if err := h.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	got := client.calls[0][0]
+	if len(got) != cloudWatchMaxEventBytes-1 || !utf8.ValidString(got) {
+		t.Fatalf("got %d bytes, valid UTF-8 %v", len(got), utf8.ValidString(got))
+	}
+}
+
+func TestCloudWatchHookLogsFlushErrors(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	h, err := NewCloudWatchHook(&NewCloudWatchHookArgs{
+		Client:        &failingCloudWatchLogs{},
+		LogGroupName:  "group",
+		LogStreamName: "stream",
+		FlushInterval: 10 * time.Millisecond,
+		Logger:        logger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close(context.Background())
+
+	h.enqueue("a", time.Now())
+	deadline := time.Now().Add(5 * time.Second)
+	for hook.LastEntry() == nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if e := hook.LastEntry(); e == nil || e.Level != logrus.ErrorLevel {
+		t.Fatalf("got %v, want the flush error logged at error", e)
+	}
+}
+
+// erroringCloudWatchLogs fails every PutLogEvents call with err
+type erroringCloudWatchLogs struct {
+	fakeCloudWatchLogs
+	err error
+}
+
+func (f *erroringCloudWatchLogs) PutLogEvents(_ context.Context, in *cloudwatchlogs.PutLogEventsInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutLogEventsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, nil)
+	return nil, f.err
+}
+
+func TestCloudWatchHookFailedEvents(t *testing.T) {
+	ctx := context.Background()
+	newHook := func(client CloudWatchLogsAPI) *CloudWatchHook {
+		h, err := NewCloudWatchHook(&NewCloudWatchHookArgs{
+			Client:            client,
+			LogGroupName:      "group",
+			LogStreamName:     "stream",
+			FlushInterval:     time.Hour,
+			DisableAutoCreate: true,
+			ErrorHandler:      func(error) {},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	// permanent errors drop the events at once
+	denied := &erroringCloudWatchLogs{err: &smithy.GenericAPIError{Code: "AccessDeniedException"}}
+	h := newHook(denied)
+	h.enqueue("a", time.Now())
+	_ = h.Flush(ctx)
+	_ = h.Flush(ctx)
+	if len(denied.calls) != 1 || h.Dropped() != 1 {
+		t.Fatalf("got %d calls and %d dropped, want the event dropped after one call", len(denied.calls), h.Dropped())
+	}
+	_ = h.Close(ctx)
+
+	// retryable errors requeue the events up to cloudWatchMaxAttempts
+	throttled := &erroringCloudWatchLogs{err: &smithy.GenericAPIError{Code: "ThrottlingException"}}
+	h = newHook(throttled)
+	h.enqueue("a", time.Now())
+	for range cloudWatchMaxAttempts + 2 {
+		_ = h.Flush(ctx)
+	}
+	if len(throttled.calls) != cloudWatchMaxAttempts || h.Dropped() != 1 {
+		t.Fatalf("got %d calls and %d dropped, want %d calls", len(throttled.calls), h.Dropped(), cloudWatchMaxAttempts)
+	}
+	_ = h.Close(ctx)
+
+	// events CloudWatch Logs accepts without storing count as dropped
+	if n := rejectedLogEvents(&types.RejectedLogEventsInfo{TooOldLogEventEndIndex: aws.Int32(2), TooNewLogEventStartIndex: aws.Int32(4)}, 5); n != 3 {
+		t.Fatalf("got %d rejected events, want 3", n)
+	}
+}
@@ -0,0 +1,23 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+type loggerKey struct{}
+
+// WithContext returns a copy of ctx carrying logger
+func WithContext(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger stored by WithContext, or the logrus standard
+// logger when there is none, so it is always safe to call
+func FromContext(ctx context.Context) Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(Logger); ok && logger != nil {
+		return logger
+	}
+	return logrus.StandardLogger()
+}
@@ -0,0 +1,106 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/sirupsen/logrus"
+)
+
+// ecsVersion is the Elastic Common Schema version the formatter follows
+const ecsVersion = "8.11.0"
+
+// ECSFormatter is a logrus formatter that writes JSON using Elastic Common
+// Schema field names (@timestamp, log.level, message, trace.id), which Datadog
+// also understands. Entry fields are written as is, except trace_id/span_id,
+// which become trace.id/span.id, and errors, which become error.message.
+type ECSFormatter struct {
+	// ServiceName, ServiceVersion and Environment are added to every entry
+	// when set
+	ServiceName    string
+	ServiceVersion string
+	Environment    string
+	// Datadog adds dd.trace_id/dd.span_id (in Datadog's decimal format),
+	// dd.service, dd.env, dd.version and status so logs link to APM traces
+	Datadog bool
+}
+
+// Format implements logrus.Formatter
+func (f *ECSFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	data := make(logrus.Fields, len(entry.Data)+8)
+	for k, v := range entry.Data {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		data[k] = v
+	}
+	if errMsg, ok := data[logrus.ErrorKey]; ok {
+		delete(data, logrus.ErrorKey)
+		data["error.message"] = errMsg
+	}
+
+	traceID, spanID := data[TraceIDField], data[SpanIDField]
+	if traceID == nil {
+		ctxFields := TraceFields(entry.Context)
+		traceID, spanID = ctxFields[TraceIDField], ctxFields[SpanIDField]
+	}
+	delete(data, TraceIDField)
+	delete(data, SpanIDField)
+	if traceID != nil {
+		data["trace.id"] = traceID
+		data["span.id"] = spanID
+	}
+
+	data["@timestamp"] = entry.Time.UTC().Format(time.RFC3339Nano)
+	data["log.level"] = entry.Level.String()
+	data["message"] = entry.Message
+	data["ecs.version"] = ecsVersion
+	setIfNotEmpty(data, "service.name", f.ServiceName)
+	setIfNotEmpty(data, "service.version", f.ServiceVersion)
+	setIfNotEmpty(data, "service.environment", f.Environment)
+
+	if f.Datadog {
+		data["status"] = entry.Level.String()
+		setIfNotEmpty(data, "dd.service", f.ServiceName)
+		setIfNotEmpty(data, "dd.version", f.ServiceVersion)
+		setIfNotEmpty(data, "dd.env", f.Environment)
+		if id, ok := traceID.(string); ok {
+			setIfNotEmpty(data, "dd.trace_id", datadogID(id))
+		}
+		if id, ok := spanID.(string); ok {
+			setIfNotEmpty(data, "dd.span_id", datadogID(id))
+		}
+	}
+
+	b := entry.Buffer
+	if b == nil {
+		b = &bytes.Buffer{}
+	}
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(data); err != nil {
+		return nil, errs.Errorf("failed to marshal log entry: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+// datadogID converts a hex OpenTelemetry ID to Datadog's decimal form of its
+// low 64 bits
+func datadogID(hexID string) string {
+	raw, err := hex.DecodeString(hexID)
+	if err != nil || len(raw) < 8 {
+		return ""
+	}
+	return strconv.FormatUint(binary.BigEndian.Uint64(raw[len(raw)-8:]), 10)
+}
+
+func setIfNotEmpty(data logrus.Fields, key, value string) {
+	if value != "" {
+		data[key] = value
+	}
+}
@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestECSFormatter(t *testing.T) {
+	f := &ECSFormatter{ServiceName: "orders", Datadog: true}
+	entry := &logrus.Entry{
+		Time:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Level:   logrus.WarnLevel,
+		Message: "slow query",
+		Data: logrus.Fields{
+			"error":      errors.New("timeout"),
+			TraceIDField: "4bf92f3577b34da6a3ce929d0e0e4736",
+			SpanIDField:  "00f067aa0ba902b7",
+		},
+	}
+	b, err := f.Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := map[string]any{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"@timestamp":    "2024-01-02T03:04:05Z",
+		"log.level":     "warning",
+		"message":       "slow query",
+		"error.message": "timeout",
+		"trace.id":      "4bf92f3577b34da6a3ce929d0e0e4736",
+		"dd.trace_id":   "11803532876627986230",
+		"dd.span_id":    "67667974448284343",
+		"service.name":  "orders",
+	}
+	for k, v := range want {
+		if out[k] != v {
+			t.Errorf("%s = %v, want %v", k, out[k], v)
+		}
+	}
+}
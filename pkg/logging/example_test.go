@@ -2,6 +2,8 @@ package logging_test
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 	"os"
 
 	"github.com/bdlilley/easygo"
@@ -88,3 +90,30 @@ func ExampleLogger_customImplementation() {
 func someFunction() error {
 	return nil
 }
+
+// ExampleNewSlogLogger demonstrates routing a Logger through log/slog
+func ExampleNewSlogLogger() {
+	handler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	var logger logging.Logger = logging.NewSlogLogger(slog.New(handler))
+
+	logger.WithField("user_id", 42).Info("user logged in")
+	logger.Debug("dropped by the handler's level")
+	// Output: level=INFO msg="user logged in" user_id=42
+}
+
+// ExampleNewTestLogger demonstrates asserting on log output in tests
+func ExampleNewTestLogger() {
+	logger, rec := logging.NewTestLogger()
+
+	logger.WithField("table", "orders").Warn("slow query")
+
+	fmt.Println(rec.HasEntryContaining("slow"), len(rec.EntriesAtLevel(logrus.WarnLevel)))
+	// Output: true 1
+}
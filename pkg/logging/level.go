@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultLevelEnv is the variable NewLevelManager reads when envVar is empty
+const DefaultLevelEnv = "LOG_LEVEL"
+
+// LevelManager sets the level of a group of loggers at runtime
+type LevelManager struct {
+	mu      sync.Mutex
+	level   logrus.Level
+	loggers []*logrus.Logger
+}
+
+// NewLevelManager creates a manager for loggers. The initial level is read from
+// envVar (default: LOG_LEVEL) when set, otherwise it is the first logger's level
+// (info when there are no loggers).
+func NewLevelManager(envVar string, loggers ...*logrus.Logger) (*LevelManager, error) {
+	if envVar == "" {
+		envVar = DefaultLevelEnv
+	}
+	m := &LevelManager{level: logrus.InfoLevel}
+	if len(loggers) > 0 {
+		m.level = loggers[0].GetLevel()
+	}
+	if v := os.Getenv(envVar); v != "" {
+		lvl, err := logrus.ParseLevel(v)
+		if err != nil {
+			return nil, errs.Errorf("invalid %s: %w", envVar, err)
+		}
+		m.level = lvl
+	}
+	for _, l := range loggers {
+		m.Register(l)
+	}
+	return m, nil
+}
+
+// Register adds l to the managed loggers and sets it to the current level
+func (m *LevelManager) Register(l *logrus.Logger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l.SetLevel(m.level)
+	m.loggers = append(m.loggers, l)
+}
+
+// Level returns the current level
+func (m *LevelManager) Level() logrus.Level {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.level
+}
+
+// SetLevel changes the level of every managed logger
+func (m *LevelManager) SetLevel(lvl logrus.Level) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.level = lvl
+	for _, l := range m.loggers {
+		l.SetLevel(lvl)
+	}
+}
+
+// SetLevelString parses and sets a level name such as "debug"
+func (m *LevelManager) SetLevelString(level string) error {
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return errs.Errorf("invalid log level %q: %w", level, err)
+	}
+	m.SetLevel(lvl)
+	return nil
+}
+
+type levelBody struct {
+	Level string `json:"level"`
+}
+
+// Handler serves the level as JSON: GET returns {"level":"info"} and PUT sets
+// it from the same body. Mount it on an admin listener, not a public one.
+func (m *LevelManager) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			body := levelBody{}
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&body); err != nil {
+				http.Error(w, "invalid JSON body", http.StatusBadRequest)
+				return
+			}
+			if err := m.SetLevelString(body.Level); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(levelBody{Level: m.Level().String()})
+	})
+}
@@ -0,0 +1,35 @@
+package logging
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestLevelManager(t *testing.T) {
+	t.Setenv("TEST_LOG_LEVEL", "warn")
+	a, b := logrus.New(), logrus.New()
+	m, err := NewLevelManager("TEST_LOG_LEVEL", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Register(b)
+	if a.GetLevel() != logrus.WarnLevel || b.GetLevel() != logrus.WarnLevel {
+		t.Fatalf("levels = %s, %s, want warn", a.GetLevel(), b.GetLevel())
+	}
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/log-level", strings.NewReader(`{"level":"debug"}`)))
+	if w.Code != http.StatusOK || b.GetLevel() != logrus.DebugLevel {
+		t.Fatalf("PUT: status %d, level %s", w.Code, b.GetLevel())
+	}
+
+	w = httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/log-level", strings.NewReader(`{"level":"loud"}`)))
+	if w.Code != http.StatusBadRequest || m.Level() != logrus.DebugLevel {
+		t.Fatalf("invalid PUT: status %d, level %s", w.Code, m.Level())
+	}
+}
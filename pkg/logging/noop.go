@@ -0,0 +1,18 @@
+package logging
+
+import (
+	"io"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Noop returns a Logger that discards every entry. Entries below the panic
+// level are not even built; Panic still panics and Fatal still exits, as
+// callers of those levels rely on it.
+func Noop() Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetFormatter(discardFormatter{})
+	logger.SetLevel(logrus.PanicLevel)
+	return logger
+}
@@ -0,0 +1,153 @@
+package logging
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultRedactedFields are the field name fragments redacted by default
+var DefaultRedactedFields = []string{"password", "passwd", "token", "authorization", "secret", "apikey", "cookie", "credential"}
+
+// DefaultRedactionPatterns match AWS access key IDs and JWTs
+var DefaultRedactionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+}
+
+type RedactionConfig struct {
+	// Fields are matched against field names ignoring case, '-' and '_': a
+	// field is redacted when its name contains one of them (default: DefaultRedactedFields)
+	Fields []string
+	// Patterns are masked wherever they appear in the message and string
+	// values (default: DefaultRedactionPatterns)
+	Patterns []*regexp.Regexp
+	// Replacement replaces redacted values (default: "[REDACTED]")
+	Replacement string
+}
+
+// RedactionHook is a logrus hook that redacts sensitive fields and masks
+// sensitive values before entries are written. Use InstallRedaction so it runs
+// before other hooks such as CloudWatchHook.
+type RedactionHook struct {
+	fields      []string
+	patterns    []*regexp.Regexp
+	replacement string
+}
+
+// NewRedactionHook creates a RedactionHook; cfg may be nil
+func NewRedactionHook(cfg *RedactionConfig) *RedactionHook {
+	c := RedactionConfig{}
+	if cfg != nil {
+		c = *cfg
+	}
+	if c.Fields == nil {
+		c.Fields = DefaultRedactedFields
+	}
+	if c.Patterns == nil {
+		c.Patterns = DefaultRedactionPatterns
+	}
+	if c.Replacement == "" {
+		c.Replacement = "[REDACTED]"
+	}
+	h := &RedactionHook{patterns: c.Patterns, replacement: c.Replacement}
+	for _, f := range c.Fields {
+		h.fields = append(h.fields, normalizeFieldName(f))
+	}
+	return h
+}
+
+// InstallRedaction adds a RedactionHook to l ahead of its existing hooks and returns it
+func InstallRedaction(l *logrus.Logger, cfg *RedactionConfig) *RedactionHook {
+	h := NewRedactionHook(cfg)
+	hooks := logrus.LevelHooks{}
+	hooks.Add(h)
+	for lvl, existing := range l.Hooks {
+		hooks[lvl] = append(hooks[lvl], existing...)
+	}
+	l.ReplaceHooks(hooks)
+	return h
+}
+
+// Levels implements logrus.Hook
+func (h *RedactionHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook
+func (h *RedactionHook) Fire(entry *logrus.Entry) error {
+	entry.Message = h.mask(entry.Message)
+	for k, v := range entry.Data {
+		entry.Data[k] = h.redact(k, v)
+	}
+	return nil
+}
+
+// Redact returns v with sensitive values masked, as if logged under key
+func (h *RedactionHook) Redact(key string, v any) any {
+	return h.redact(key, v)
+}
+
+func (h *RedactionHook) redact(key string, v any) any {
+	if h.sensitive(key) {
+		return h.replacement
+	}
+	switch val := v.(type) {
+	case string:
+		return h.mask(val)
+	case error:
+		if masked := h.mask(val.Error()); masked != val.Error() {
+			return masked
+		}
+		return val
+	case fmt.Stringer:
+		return h.mask(val.String())
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, nested := range val {
+			out[k] = h.redact(k, nested)
+		}
+		return out
+	case map[string]string:
+		out := make(map[string]string, len(val))
+		for k, nested := range val {
+			if h.sensitive(k) {
+				out[k] = h.replacement
+			} else {
+				out[k] = h.mask(nested)
+			}
+		}
+		return out
+	case []string:
+		out := make([]string, len(val))
+		for i, s := range val {
+			out[i] = h.mask(s)
+		}
+		return out
+	}
+	return v
+}
+
+func (h *RedactionHook) sensitive(key string) bool {
+	name := normalizeFieldName(key)
+	for _, f := range h.fields {
+		if strings.Contains(name, f) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *RedactionHook) mask(s string) string {
+	for _, p := range h.patterns {
+		s = p.ReplaceAllString(s, h.replacement)
+	}
+	return s
+}
+
+func normalizeFieldName(name string) string {
+	name = strings.ToLower(name)
+	return strings.NewReplacer("_", "", "-", "").Replace(name)
+}
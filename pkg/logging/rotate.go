@@ -0,0 +1,273 @@
+package logging
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// rotatedTimeFormat is the timestamp inserted into rotated file names
+const rotatedTimeFormat = "20060102T150405.000"
+
+type RotatingFileConfig struct {
+	// Filename is the active log file; rotated files are written next to it
+	// as name-<timestamp>.ext (required)
+	Filename string
+	// MaxSizeBytes rotates the file before a write would exceed it (default: 100MB)
+	MaxSizeBytes int64
+	// MaxAge rotates the file once it has been open this long (default: never)
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files kept (default: keep all)
+	MaxBackups int
+	// Retention deletes rotated files older than this (default: keep all)
+	Retention time.Duration
+	// Compress gzips rotated files
+	Compress bool
+	// Mode is the permission of new files (default: 0640)
+	Mode os.FileMode
+	// ErrorHandler receives errors from background compression and cleanup
+	// (default: log them to Logger)
+	ErrorHandler func(error)
+	// Logger logs background errors at error without an ErrorHandler; it may
+	// write to this file (default: Noop)
+	Logger Logger
+}
+
+// RotatingFile is an io.Writer, usable as a logrus output, that writes to a
+// file and rotates it by size and age
+type RotatingFile struct {
+	cfg RotatingFileConfig
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	// millMu serializes background compression and cleanup
+	millMu sync.Mutex
+	mills  sync.WaitGroup
+}
+
+// NewRotatingFile opens or creates cfg.Filename for appending
+func NewRotatingFile(cfg *RotatingFileConfig) (*RotatingFile, error) {
+	if cfg == nil || cfg.Filename == "" {
+		return nil, errs.New(errs.Internal, "Filename is required")
+	}
+	f := &RotatingFile{cfg: *cfg}
+	if f.cfg.MaxSizeBytes <= 0 {
+		f.cfg.MaxSizeBytes = 100 << 20
+	}
+	if f.cfg.Mode == 0 {
+		f.cfg.Mode = 0o640
+	}
+	if f.cfg.ErrorHandler == nil {
+		logger := f.cfg.Logger
+		if logger == nil {
+			logger = Noop()
+		}
+		f.cfg.ErrorHandler = func(err error) {
+			logger.WithError(err).WithField("file", f.cfg.Filename).Error("rotating log file failed")
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(f.cfg.Filename), 0o755); err != nil {
+		return nil, errs.Errorf("failed to create log directory for %s: %w", f.cfg.Filename, err)
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.cfg.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, f.cfg.Mode)
+	if err != nil {
+		return errs.Errorf("failed to open %s: %w", f.cfg.Filename, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return errs.Errorf("failed to stat %s: %w", f.cfg.Filename, err)
+	}
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = time.Now()
+	return nil
+}
+
+// Write implements io.Writer, rotating first when p would exceed MaxSizeBytes
+// or the file is older than MaxAge
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	tooBig := f.size > 0 && f.size+int64(len(p)) > f.cfg.MaxSizeBytes
+	tooOld := f.cfg.MaxAge > 0 && time.Since(f.openedAt) >= f.cfg.MaxAge
+	if tooBig || tooOld {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, renames it with a timestamp and opens a new one
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	return f.rotate()
+}
+
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return errs.Errorf("failed to close %s: %w", f.cfg.Filename, err)
+	}
+	f.file = nil
+	if err := os.Rename(f.cfg.Filename, f.backupName(time.Now())); err != nil {
+		return errs.Errorf("failed to rotate %s: %w", f.cfg.Filename, err)
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	f.mills.Add(1)
+	go func() {
+		defer f.mills.Done()
+		f.mill()
+	}()
+	return nil
+}
+
+// Close closes the file and waits for background compression and cleanup
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+	f.mills.Wait()
+	return err
+}
+
+// backupName returns an unused rotated file name for t, moving forward a
+// millisecond at a time when rotations collide
+func (f *RotatingFile) backupName(t time.Time) string {
+	dir, prefix, ext := f.nameParts()
+	for {
+		name := filepath.Join(dir, prefix+t.UTC().Format(rotatedTimeFormat)+ext)
+		_, err := os.Stat(name)
+		_, gzErr := os.Stat(name + ".gz")
+		if os.IsNotExist(err) && os.IsNotExist(gzErr) {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func (f *RotatingFile) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(f.cfg.Filename)
+	base := filepath.Base(f.cfg.Filename)
+	ext = filepath.Ext(base)
+	return dir, strings.TrimSuffix(base, ext) + "-", ext
+}
+
+type rotatedFile struct {
+	path string
+	time time.Time
+}
+
+// rotatedFiles lists rotated files, newest first
+func (f *RotatingFile) rotatedFiles() ([]rotatedFile, error) {
+	dir, prefix, ext := f.nameParts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errs.Errorf("failed to list %s: %w", dir, err)
+	}
+	var files []rotatedFile
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz"), ext)
+		t, err := time.Parse(rotatedTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		files = append(files, rotatedFile{path: filepath.Join(dir, name), time: t})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].time.After(files[j].time) })
+	return files, nil
+}
+
+// mill compresses rotated files and removes those beyond the retention limits
+func (f *RotatingFile) mill() {
+	f.millMu.Lock()
+	defer f.millMu.Unlock()
+
+	files, err := f.rotatedFiles()
+	if err != nil {
+		f.cfg.ErrorHandler(err)
+		return
+	}
+	for i, rf := range files {
+		expired := f.cfg.Retention > 0 && time.Since(rf.time) > f.cfg.Retention
+		if (f.cfg.MaxBackups > 0 && i >= f.cfg.MaxBackups) || expired {
+			if err := os.Remove(rf.path); err != nil && !os.IsNotExist(err) {
+				f.cfg.ErrorHandler(errs.Errorf("failed to remove %s: %w", rf.path, err))
+			}
+			continue
+		}
+		if f.cfg.Compress && !strings.HasSuffix(rf.path, ".gz") {
+			if err := compressFile(rf.path, f.cfg.Mode); err != nil {
+				f.cfg.ErrorHandler(err)
+			}
+		}
+	}
+}
+
+// compressFile gzips path to path.gz and removes the original
+func compressFile(path string, mode os.FileMode) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return errs.Errorf("failed to open %s: %w", path, err)
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return errs.Errorf("failed to create %s.gz: %w", path, err)
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return errs.Errorf("failed to compress %s: %w", path, err)
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return errs.Errorf("failed to compress %s: %w", path, err)
+	}
+	if err := dst.Close(); err != nil {
+		return errs.Errorf("failed to compress %s: %w", path, err)
+	}
+	return os.Remove(path)
+}
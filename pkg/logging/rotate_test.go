@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewRotatingFile(&RotatingFileConfig{
+		Filename:     filepath.Join(dir, "app.log"),
+		MaxSizeBytes: 10,
+		MaxBackups:   2,
+		Compress:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	active, _ := os.ReadFile(filepath.Join(dir, "app.log"))
+	if string(active) != "fourth\n" {
+		t.Errorf("active file = %q", active)
+	}
+	files, _ := f.rotatedFiles()
+	if len(files) > 2 {
+		t.Errorf("kept %d rotated files, want at most 2", len(files))
+	}
+	for _, rf := range files {
+		if !strings.HasSuffix(rf.path, ".gz") {
+			t.Errorf("%s was not compressed", rf.path)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package logging
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type SamplingConfig struct {
+	// First is how many identical entries are logged per Interval before
+	// sampling starts (default: 10)
+	First int
+	// Thereafter logs every Mth identical entry after First (default: 100,
+	// negative drops them all)
+	Thereafter int
+	// Interval is the window identical entries are counted in (default: 1s)
+	Interval time.Duration
+}
+
+// sampleCounter counts one kind of entry within the current window
+type sampleCounter struct {
+	windowStart time.Time
+	count       int
+	suppressed  int
+	level       logrus.Level
+	message     string
+}
+
+type samplingHook struct {
+	next Logger
+	cfg  SamplingConfig
+	now  func() time.Time
+
+	mu        sync.Mutex
+	counters  map[string]*sampleCounter
+	lastSweep time.Time
+}
+
+// NewSampledLogger returns a Logger that forwards entries to next, limiting
+// repeats of the same level and message: the first First per Interval are
+// logged, then one in Thereafter. When a window ends with entries suppressed,
+// a warning with the suppressed count is logged once another entry arrives.
+// Fatal and panic entries are never sampled.
+func NewSampledLogger(next Logger, cfg *SamplingConfig) *logrus.Logger {
+	c := SamplingConfig{}
+	if cfg != nil {
+		c = *cfg
+	}
+	if c.First <= 0 {
+		c.First = 10
+	}
+	if c.Thereafter == 0 {
+		c.Thereafter = 100
+	}
+	if c.Interval <= 0 {
+		c.Interval = time.Second
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetFormatter(discardFormatter{})
+	logger.SetLevel(logrus.TraceLevel)
+	if l, ok := next.(*logrus.Logger); ok {
+		logger.SetLevel(l.GetLevel())
+	}
+	logger.AddHook(&samplingHook{
+		next:     next,
+		cfg:      c,
+		now:      time.Now,
+		counters: map[string]*sampleCounter{},
+	})
+	return logger
+}
+
+func (h *samplingHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *samplingHook) Fire(entry *logrus.Entry) error {
+	if entry.Level <= logrus.FatalLevel {
+		h.forward(entry.Level, entry.Data, entry.Message)
+		return nil
+	}
+
+	allowed, summaries := h.sample(entry.Level, entry.Message)
+	for _, s := range summaries {
+		h.next.WithFields(logrus.Fields{
+			"message":    s.message,
+			"level":      s.level.String(),
+			"suppressed": s.suppressed,
+		}).Warn("suppressed repeated log entries")
+	}
+	if allowed {
+		h.forward(entry.Level, entry.Data, entry.Message)
+	}
+	return nil
+}
+
+func (h *samplingHook) forward(level logrus.Level, data logrus.Fields, message string) {
+	h.next.WithFields(data).Log(level, message)
+}
+
+// sample reports whether an entry is logged and returns the counters of
+// finished windows that suppressed entries
+func (h *samplingHook) sample(level logrus.Level, message string) (bool, []sampleCounter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	var summaries []sampleCounter
+	if now.Sub(h.lastSweep) >= h.cfg.Interval {
+		for key, c := range h.counters {
+			if now.Sub(c.windowStart) >= h.cfg.Interval {
+				if c.suppressed > 0 {
+					summaries = append(summaries, *c)
+				}
+				delete(h.counters, key)
+			}
+		}
+		h.lastSweep = now
+	}
+
+	key := level.String() + "\x00" + message
+	c, ok := h.counters[key]
+	if !ok || now.Sub(c.windowStart) >= h.cfg.Interval {
+		if ok && c.suppressed > 0 {
+			summaries = append(summaries, *c)
+		}
+		c = &sampleCounter{windowStart: now, level: level, message: message}
+		h.counters[key] = c
+	}
+
+	c.count++
+	if c.count <= h.cfg.First {
+		return true, summaries
+	}
+	if h.cfg.Thereafter > 0 && (c.count-h.cfg.First)%h.cfg.Thereafter == 0 {
+		return true, summaries
+	}
+	c.suppressed++
+	return false, summaries
+}
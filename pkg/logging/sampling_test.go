@@ -0,0 +1,33 @@
+package logging
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestSampledLogger(t *testing.T) {
+	next, hook := test.NewNullLogger()
+	l := NewSampledLogger(next, &SamplingConfig{First: 2, Thereafter: 3, Interval: time.Minute})
+	now := time.Unix(1000, 0)
+	sampler := l.Hooks[logrus.InfoLevel][0].(*samplingHook)
+	sampler.now = func() time.Time { return now }
+
+	for i := 0; i < 8; i++ {
+		l.WithField("attempt", i).Error("connection refused")
+	}
+	// 2 logged, then every 3rd of the remaining 6
+	if got := len(hook.AllEntries()); got != 4 {
+		t.Fatalf("logged %d entries, want 4", got)
+	}
+
+	now = now.Add(time.Minute)
+	l.Error("connection refused")
+	entries := hook.AllEntries()
+	summary := entries[len(entries)-2]
+	if summary.Data["suppressed"] != 4 || entries[len(entries)-1].Message != "connection refused" {
+		t.Fatalf("summary = %v, last = %q", summary.Data, entries[len(entries)-1].Message)
+	}
+}
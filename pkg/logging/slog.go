@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/sirupsen/logrus"
+)
+
+// NewSlogLogger returns a Logger that writes every entry to l, with logrus
+// fields as slog attributes. Levels are filtered by l's handler.
+func NewSlogLogger(l *slog.Logger) *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetFormatter(discardFormatter{})
+	logger.AddHook(&slogHook{logger: l})
+
+	// skip building entries for levels the handler drops
+	logger.SetLevel(logrus.ErrorLevel)
+	for _, lvl := range []logrus.Level{logrus.TraceLevel, logrus.DebugLevel, logrus.InfoLevel, logrus.WarnLevel} {
+		if l.Enabled(context.Background(), slogLevel(lvl)) {
+			logger.SetLevel(lvl)
+			break
+		}
+	}
+	return logger
+}
+
+// slogLevel maps logrus levels to slog; trace is below debug and fatal and
+// panic are above error
+func slogLevel(lvl logrus.Level) slog.Level {
+	switch lvl {
+	case logrus.TraceLevel:
+		return slog.LevelDebug - 4
+	case logrus.DebugLevel:
+		return slog.LevelDebug
+	case logrus.InfoLevel:
+		return slog.LevelInfo
+	case logrus.WarnLevel:
+		return slog.LevelWarn
+	case logrus.ErrorLevel:
+		return slog.LevelError
+	default:
+		return slog.LevelError + 4
+	}
+}
+
+type slogHook struct {
+	logger *slog.Logger
+}
+
+func (h *slogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *slogHook) Fire(entry *logrus.Entry) error {
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	attrs := make([]slog.Attr, 0, len(entry.Data))
+	for k, v := range entry.Data {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		attrs = append(attrs, slog.Any(k, v))
+	}
+	h.logger.LogAttrs(ctx, slogLevel(entry.Level), entry.Message, attrs...)
+	return nil
+}
+
+// discardFormatter skips formatting for loggers whose hooks do the writing
+type discardFormatter struct{}
+
+func (discardFormatter) Format(*logrus.Entry) ([]byte, error) {
+	return nil, nil
+}
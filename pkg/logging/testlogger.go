@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"io"
+	"maps"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TestEntry is an entry captured by a TestRecorder
+type TestEntry struct {
+	Time    time.Time
+	Level   logrus.Level
+	Message string
+	Fields  logrus.Fields
+}
+
+// TestRecorder captures entries logged through a test logger
+type TestRecorder struct {
+	mu      sync.Mutex
+	entries []TestEntry
+}
+
+// NewTestLogger returns a logger that records every entry, at all levels,
+// instead of writing it, and the recorder to assert on
+func NewTestLogger() (*logrus.Logger, *TestRecorder) {
+	rec := &TestRecorder{}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetFormatter(discardFormatter{})
+	logger.SetLevel(logrus.TraceLevel)
+	logger.ExitFunc = func(int) {}
+	logger.AddHook(rec)
+	return logger, rec
+}
+
+// Levels implements logrus.Hook
+func (r *TestRecorder) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook
+func (r *TestRecorder) Fire(entry *logrus.Entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, TestEntry{
+		Time:    entry.Time,
+		Level:   entry.Level,
+		Message: entry.Message,
+		Fields:  maps.Clone(entry.Data),
+	})
+	return nil
+}
+
+// Entries returns the captured entries in order
+func (r *TestRecorder) Entries() []TestEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]TestEntry(nil), r.entries...)
+}
+
+// EntriesAtLevel returns the captured entries logged at lvl
+func (r *TestRecorder) EntriesAtLevel(lvl logrus.Level) []TestEntry {
+	var out []TestEntry
+	for _, e := range r.Entries() {
+		if e.Level == lvl {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// HasEntryContaining reports whether any entry's message contains substr
+func (r *TestRecorder) HasEntryContaining(substr string) bool {
+	for _, e := range r.Entries() {
+		if strings.Contains(e.Message, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasEntryWithField reports whether any entry has field key set to value
+func (r *TestRecorder) HasEntryWithField(key string, value any) bool {
+	for _, e := range r.Entries() {
+		if v, ok := e.Fields[key]; ok && reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Last returns the most recent entry, or false when nothing was logged
+func (r *TestRecorder) Last() (TestEntry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) == 0 {
+		return TestEntry{}, false
+	}
+	return r.entries[len(r.entries)-1], true
+}
+
+// Reset discards the captured entries
+func (r *TestRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
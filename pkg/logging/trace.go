@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Field names for OpenTelemetry correlation
+const (
+	TraceIDField = "trace_id"
+	SpanIDField  = "span_id"
+)
+
+// TraceFields returns the trace_id and span_id of the span in ctx, or nil when
+// there is no valid span
+func TraceFields(ctx context.Context) logrus.Fields {
+	if ctx == nil {
+		return nil
+	}
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	return logrus.Fields{
+		TraceIDField: sc.TraceID().String(),
+		SpanIDField:  sc.SpanID().String(),
+	}
+}
+
+// WithTrace returns an entry for logger that carries ctx and its trace fields.
+// Use it when the logger may not have a TraceHook installed.
+func WithTrace(ctx context.Context, logger Logger) *logrus.Entry {
+	return logger.WithFields(TraceFields(ctx)).WithContext(ctx)
+}
+
+// TraceHook adds trace_id and span_id to entries logged with a context holding
+// a valid span, e.g. log.WithContext(ctx).Info(...)
+type TraceHook struct{}
+
+// Levels implements logrus.Hook
+func (TraceHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook
+func (TraceHook) Fire(entry *logrus.Entry) error {
+	for k, v := range TraceFields(entry.Context) {
+		if _, ok := entry.Data[k]; !ok {
+			entry.Data[k] = v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package zapadapter_test
+
+import (
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/bdlilley/easygo/pkg/logging/zapadapter"
+	"go.uber.org/zap"
+)
+
+// ExampleNew demonstrates routing a Logger through zap
+func ExampleNew() {
+	var logger logging.Logger = zapadapter.New(zap.NewExample())
+
+	logger.WithField("user_id", 42).Info("user logged in")
+	// Output: {"level":"info","msg":"user logged in","user_id":42}
+}
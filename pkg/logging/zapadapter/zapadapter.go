@@ -0,0 +1,81 @@
+// Package zapadapter writes logging.Logger entries through a zap logger, so
+// services can keep zap while using the easygo AWS and HTTP helpers.
+package zapadapter
+
+import (
+	"io"
+
+	"github.com/sirupsen/logrus"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// New returns a logging.Logger that writes every entry to l, with logrus fields
+// as zap fields. Levels are filtered by l's core.
+func New(l *zap.Logger) *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetFormatter(discardFormatter{})
+	logger.AddHook(&zapHook{logger: l})
+
+	// skip building entries for levels the core drops
+	logger.SetLevel(logrus.ErrorLevel)
+	for _, lvl := range []logrus.Level{logrus.TraceLevel, logrus.DebugLevel, logrus.InfoLevel, logrus.WarnLevel} {
+		if l.Core().Enabled(zapLevel(lvl)) {
+			logger.SetLevel(lvl)
+			break
+		}
+	}
+	return logger
+}
+
+// NewSugared returns a logging.Logger that writes through s
+func NewSugared(s *zap.SugaredLogger) *logrus.Logger {
+	return New(s.Desugar())
+}
+
+// zapLevel maps logrus levels to zap. zap has no trace level, so trace is
+// logged at debug. Fatal and panic are logged at error because logrus exits
+// or panics itself after running hooks.
+func zapLevel(lvl logrus.Level) zapcore.Level {
+	switch lvl {
+	case logrus.TraceLevel, logrus.DebugLevel:
+		return zapcore.DebugLevel
+	case logrus.InfoLevel:
+		return zapcore.InfoLevel
+	case logrus.WarnLevel:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
+
+type zapHook struct {
+	logger *zap.Logger
+}
+
+func (h *zapHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *zapHook) Fire(entry *logrus.Entry) error {
+	fields := make([]zap.Field, 0, len(entry.Data))
+	for k, v := range entry.Data {
+		if err, ok := v.(error); ok {
+			fields = append(fields, zap.NamedError(k, err))
+			continue
+		}
+		fields = append(fields, zap.Any(k, v))
+	}
+	if ce := h.logger.Check(zapLevel(entry.Level), entry.Message); ce != nil {
+		ce.Write(fields...)
+	}
+	return nil
+}
+
+// discardFormatter skips formatting since the hook does the writing
+type discardFormatter struct{}
+
+func (discardFormatter) Format(*logrus.Entry) ([]byte, error) {
+	return nil, nil
+}
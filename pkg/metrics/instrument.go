@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go/middleware"
+	egerrors "github.com/bdlilley/easygo/pkg/egaws/errors"
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+type awsMetrics struct {
+	calls     Counter
+	duration  Histogram
+	throttles Counter
+}
+
+// AWSMiddleware records aws_api_calls_total and
+// aws_api_call_duration_seconds by service and operation, and
+// aws_api_throttles_total for every throttled attempt including retried
+// ones. Add it to aws.Config.APIOptions, or set NewEGAwsClientArgs.Metrics.
+func AWSMiddleware(p Provider) func(*middleware.Stack) error {
+	m := &awsMetrics{
+		calls:     p.Counter("aws_api_calls_total", "Total AWS API calls.", "service", "operation", "result"),
+		duration:  p.Histogram("aws_api_call_duration_seconds", "AWS API call latency in seconds, including retries.", nil, "service", "operation"),
+		throttles: p.Counter("aws_api_throttles_total", "Total throttled AWS API call attempts.", "service", "operation"),
+	}
+	return func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("EasyGoMetrics",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				tags := Tags{"service": awsmiddleware.GetServiceID(ctx), "operation": awsmiddleware.GetOperationName(ctx)}
+				timer := NewTimer(m.duration.With(tags))
+				out, metadata, err := next.HandleInitialize(ctx, in)
+				timer.ObserveDuration()
+
+				result := "success"
+				if err != nil {
+					result = "error"
+				}
+				m.calls.With(tags).With(Tags{"result": result}).Inc()
+				if results, ok := retry.GetAttemptResults(metadata); ok {
+					for _, attempt := range results.Results {
+						if attempt.Err != nil && egerrors.IsThrottled(attempt.Err) {
+							m.throttles.With(tags).Inc()
+						}
+					}
+				} else if egerrors.IsThrottled(err) {
+					m.throttles.With(tags).Inc()
+				}
+				return out, metadata, err
+			}), middleware.Before)
+	}
+}
+
+type HTTPMiddlewareOptions struct {
+	// Buckets are the request duration histogram buckets (default: the
+	// backend's defaults)
+	Buckets []float64
+}
+
+// HTTPMiddleware records http_requests_total, http_request_duration_seconds
+// and http_response_size_bytes by method, chi route pattern and status, and
+// http_requests_in_flight. httpserver.MetricsConfig uses it, so the families
+// are shared when both are enabled on one registry. opts may be nil.
+func HTTPMiddleware(p Provider, opts *HTTPMiddlewareOptions) func(http.Handler) http.Handler {
+	if opts == nil {
+		opts = &HTTPMiddlewareOptions{}
+	}
+	requests := p.Counter("http_requests_total", "Total HTTP requests handled.", "method", "route", "status")
+	duration := p.Histogram("http_request_duration_seconds", "HTTP request latency in seconds.", opts.Buckets, "method", "route", "status")
+	responseSize := p.Histogram("http_response_size_bytes", "HTTP response body size in bytes.", httpResponseSizeBuckets, "method", "route", "status")
+	inFlight := p.Gauge("http_requests_in_flight", "HTTP requests currently being handled.")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inFlight.Add(1)
+			defer inFlight.Add(-1)
+
+			start := time.Now()
+			ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			// the route pattern keeps label cardinality bounded
+			route := "unmatched"
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			tags := Tags{"method": r.Method, "route": route, "status": strconv.Itoa(status)}
+			requests.With(tags).Inc()
+			duration.With(tags).Observe(time.Since(start).Seconds())
+			responseSize.With(tags).Observe(float64(ww.BytesWritten()))
+		})
+	}
+}
+
+// httpResponseSizeBuckets run from 100B to 100MB
+var httpResponseSizeBuckets = []float64{100, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8}
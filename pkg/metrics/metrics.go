@@ -0,0 +1,94 @@
+// Package metrics is a small Counter, Gauge, Histogram and Timer API with
+// Prometheus and no-op backends, so libraries can emit metrics without
+// binding applications to one backend. AWSMiddleware and HTTPMiddleware
+// instrument AWS API calls and HTTP servers through any Provider.
+package metrics
+
+import (
+	"time"
+)
+
+// Tags label a metric, e.g. Tags{"queue": "orders"}. Tags not declared when
+// the metric was created are ignored and missing ones are empty.
+type Tags map[string]string
+
+// Counter is a value that only increases
+type Counter interface {
+	// With returns the counter with tags added
+	With(tags Tags) Counter
+	Inc()
+	Add(delta float64)
+}
+
+// Gauge is a value that goes up and down
+type Gauge interface {
+	// With returns the gauge with tags added
+	With(tags Tags) Gauge
+	Set(value float64)
+	Add(delta float64)
+}
+
+// Histogram records the distribution of observed values
+type Histogram interface {
+	// With returns the histogram with tags added
+	With(tags Tags) Histogram
+	Observe(value float64)
+}
+
+// Provider creates metrics; tagNames declares the tags a metric accepts.
+// Creating a metric that already exists returns the existing one.
+type Provider interface {
+	Counter(name, help string, tagNames ...string) Counter
+	Gauge(name, help string, tagNames ...string) Gauge
+	// Histogram records values in buckets (default: the backend's defaults)
+	Histogram(name, help string, buckets []float64, tagNames ...string) Histogram
+}
+
+// Timer observes elapsed seconds into a Histogram:
+//
+//	defer metrics.NewTimer(latency.With(metrics.Tags{"job": name})).ObserveDuration()
+type Timer struct {
+	histogram Histogram
+	start     time.Time
+}
+
+// NewTimer starts a timer
+func NewTimer(h Histogram) *Timer {
+	return &Timer{histogram: h, start: time.Now()}
+}
+
+// ObserveDuration records the time since the timer started and returns it
+func (t *Timer) ObserveDuration() time.Duration {
+	d := time.Since(t.start)
+	t.histogram.Observe(d.Seconds())
+	return d
+}
+
+// Noop returns a Provider whose metrics discard every value, the default
+// for libraries that accept an optional Provider
+func Noop() Provider {
+	return noop{}
+}
+
+type noop struct{}
+
+func (noop) Counter(string, string, ...string) Counter                { return noopCounter{} }
+func (noop) Gauge(string, string, ...string) Gauge                    { return noopGauge{} }
+func (noop) Histogram(string, string, []float64, ...string) Histogram { return noopHistogram{} }
+
+type noopCounter struct{}
+
+func (c noopCounter) With(Tags) Counter { return c }
+func (noopCounter) Inc()                {}
+func (noopCounter) Add(float64)         {}
+
+type noopGauge struct{}
+
+func (g noopGauge) With(Tags) Gauge { return g }
+func (noopGauge) Set(float64)       {}
+func (noopGauge) Add(float64)       {}
+
+type noopHistogram struct{}
+
+func (h noopHistogram) With(Tags) Histogram { return h }
+func (noopHistogram) Observe(float64)       {}
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPrometheus(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	p := NewPrometheus(&PrometheusOptions{Registerer: reg, Namespace: "app"})
+
+	jobs := p.Counter("jobs_total", "Jobs run.", "job", "result")
+	jobs.With(Tags{"job": "sync"}).With(Tags{"result": "ok", "undeclared": "x"}).Add(2)
+	// creating the same metric again shares the collector
+	p.Counter("jobs_total", "Jobs run.", "job", "result").With(Tags{"job": "sync", "result": "ok"}).Inc()
+
+	depth := p.Gauge("queue_depth", "Queue depth.")
+	depth.Set(5)
+	depth.Add(-2)
+
+	latency := p.Histogram("job_seconds", "Job latency.", []float64{1}, "job")
+	NewTimer(latency.With(Tags{"job": "sync"})).ObserveDuration()
+
+	if got := testutil.ToFloat64(jobs.(*promCounter).vec.WithLabelValues("sync", "ok")); got != 3 {
+		t.Fatalf("got %v jobs, want 3", got)
+	}
+	if got := testutil.ToFloat64(depth.(*promGauge).vec.WithLabelValues()); got != 3 {
+		t.Fatalf("got depth %v, want 3", got)
+	}
+	if n := testutil.CollectAndCount(reg, "app_job_seconds"); n != 1 {
+		t.Fatalf("got %d histogram series, want 1", n)
+	}
+
+	// the no-op provider accepts the same calls
+	Noop().Counter("x", "x", "a").With(Tags{"a": "b"}).Inc()
+}
+
+func TestAWSMiddleware(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	stack := middleware.NewStack("test", func() any { return nil })
+	if err := AWSMiddleware(NewPrometheus(&PrometheusOptions{Registerer: reg}))(stack); err != nil {
+		t.Fatal(err)
+	}
+	throttled := middleware.HandlerFunc(func(context.Context, any) (any, middleware.Metadata, error) {
+		return nil, middleware.Metadata{}, &smithy.GenericAPIError{Code: "ThrottlingException"}
+	})
+	if _, _, err := middleware.DecorateHandler(throttled, stack).Handle(context.Background(), nil); err == nil {
+		t.Fatal("expected the handler error")
+	}
+
+	expected := `
+# HELP aws_api_throttles_total Total throttled AWS API call attempts.
+# TYPE aws_api_throttles_total counter
+aws_api_throttles_total{operation="",service=""} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "aws_api_throttles_total"); err != nil {
+		t.Fatal(err)
+	}
+	if n := testutil.CollectAndCount(reg, "aws_api_calls_total"); n != 1 {
+		t.Fatalf("got %d call series, want 1", n)
+	}
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	r := chi.NewRouter()
+	r.Use(HTTPMiddleware(NewPrometheus(&PrometheusOptions{Registerer: reg}), nil))
+	r.Get("/users/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	for _, id := range []string{"1", "2"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/"+id, nil))
+	}
+
+	expected := `
+# HELP http_requests_total Total HTTP requests handled.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",route="/users/{id}",status="204"} 2
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "http_requests_total"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type PrometheusOptions struct {
+	// Registerer registers the metrics (default: prometheus.DefaultRegisterer)
+	Registerer prometheus.Registerer
+	// Namespace prefixes metric names, e.g. "myapp" gives myapp_jobs_total
+	Namespace string
+	// ConstLabels are added to every metric, e.g. runtimeinfo.Get().Labels()
+	ConstLabels map[string]string
+}
+
+// NewPrometheus returns a Provider that registers Prometheus collectors;
+// opts may be nil
+func NewPrometheus(opts *PrometheusOptions) Provider {
+	if opts == nil {
+		opts = &PrometheusOptions{}
+	}
+	p := &promProvider{reg: opts.Registerer, namespace: opts.Namespace}
+	if p.reg == nil {
+		p.reg = prometheus.DefaultRegisterer
+	}
+	if len(opts.ConstLabels) > 0 {
+		p.reg = prometheus.WrapRegistererWith(opts.ConstLabels, p.reg)
+	}
+	return p
+}
+
+type promProvider struct {
+	reg       prometheus.Registerer
+	namespace string
+}
+
+func (p *promProvider) Counter(name, help string, tagNames ...string) Counter {
+	vec := RegisterCollector(p.reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: p.namespace,
+		Name:      name,
+		Help:      help,
+	}, tagNames))
+	return &promCounter{vec: vec, labels: withTags(tagNames, nil, nil)}
+}
+
+func (p *promProvider) Gauge(name, help string, tagNames ...string) Gauge {
+	vec := RegisterCollector(p.reg, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: p.namespace,
+		Name:      name,
+		Help:      help,
+	}, tagNames))
+	return &promGauge{vec: vec, labels: withTags(tagNames, nil, nil)}
+}
+
+func (p *promProvider) Histogram(name, help string, buckets []float64, tagNames ...string) Histogram {
+	vec := RegisterCollector(p.reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: p.namespace,
+		Name:      name,
+		Help:      help,
+		Buckets:   buckets,
+	}, tagNames))
+	return &promHistogram{vec: vec, labels: withTags(tagNames, nil, nil)}
+}
+
+// withTags returns labels with tags merged in, keeping only declared names
+// so Prometheus never rejects the label set
+func withTags(names []string, labels prometheus.Labels, tags Tags) prometheus.Labels {
+	merged := make(prometheus.Labels, len(names))
+	if labels == nil {
+		for _, name := range names {
+			merged[name] = ""
+		}
+	} else {
+		maps.Copy(merged, labels)
+	}
+	for k, v := range tags {
+		if _, ok := merged[k]; ok {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+type promCounter struct {
+	vec    *prometheus.CounterVec
+	labels prometheus.Labels
+}
+
+func (c *promCounter) With(tags Tags) Counter {
+	return &promCounter{vec: c.vec, labels: withTags(nil, c.labels, tags)}
+}
+
+func (c *promCounter) Inc()              { c.vec.With(c.labels).Inc() }
+func (c *promCounter) Add(delta float64) { c.vec.With(c.labels).Add(delta) }
+
+type promGauge struct {
+	vec    *prometheus.GaugeVec
+	labels prometheus.Labels
+}
+
+func (g *promGauge) With(tags Tags) Gauge {
+	return &promGauge{vec: g.vec, labels: withTags(nil, g.labels, tags)}
+}
+
+func (g *promGauge) Set(value float64) { g.vec.With(g.labels).Set(value) }
+func (g *promGauge) Add(delta float64) { g.vec.With(g.labels).Add(delta) }
+
+type promHistogram struct {
+	vec    *prometheus.HistogramVec
+	labels prometheus.Labels
+}
+
+func (h *promHistogram) With(tags Tags) Histogram {
+	return &promHistogram{vec: h.vec, labels: withTags(nil, h.labels, tags)}
+}
+
+func (h *promHistogram) Observe(value float64) { h.vec.With(h.labels).Observe(value) }
+
+// RegisterCollector registers c, or returns the collector already registered
+// under the same name so metrics can be created more than once. It panics
+// when another collector with a different type or labels holds the name.
+func RegisterCollector[C prometheus.Collector](reg prometheus.Registerer, c C) C {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(C); ok {
+				return existing
+			}
+		}
+		panic(fmt.Sprintf("failed to register metric: %v", err))
+	}
+	return c
+}
@@ -0,0 +1,127 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/bdlilley/easygo"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// snsMaxSubjectLength is the SNS limit for email subjects
+const snsMaxSubjectLength = 100
+
+// SNSPublishAPI is the subset of the SNS client used by SNSNotifier
+type SNSPublishAPI interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+// SNSNotifier publishes notifications to an SNS topic as plain text, with
+// severity and source message attributes for subscription filter policies
+type SNSNotifier struct {
+	client   SNSPublishAPI
+	topicArn string
+}
+
+var _ Notifier = (*SNSNotifier)(nil)
+
+// NewSNSNotifier creates a notifier for topicArn, e.g. with EGAwsClient.GetSNSClient()
+func NewSNSNotifier(client SNSPublishAPI, topicArn string) *SNSNotifier {
+	return &SNSNotifier{client: client, topicArn: topicArn}
+}
+
+func (s *SNSNotifier) Notify(ctx context.Context, n *Notification) error {
+	n = withDefaults(n)
+	subj := subject(n)
+	if len(subj) > snsMaxSubjectLength {
+		subj = subj[:snsMaxSubjectLength-3] + "..."
+	}
+	attrs := map[string]types.MessageAttributeValue{
+		"severity": {DataType: aws.String("String"), StringValue: aws.String(n.Severity.String())},
+	}
+	if n.Source != "" {
+		attrs["source"] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(n.Source)}
+	}
+	_, err := s.client.Publish(ctx, &sns.PublishInput{
+		TopicArn:          aws.String(s.topicArn),
+		Subject:           aws.String(subj),
+		Message:           aws.String(plainText(n)),
+		MessageAttributes: attrs,
+	})
+	if err != nil {
+		return errs.Errorf("failed to publish notification to %s: %w", s.topicArn, err)
+	}
+	return nil
+}
+
+// EmailSender sends email; implemented by easygo.EGAwsClient
+type EmailSender interface {
+	SendEmailMessage(ctx context.Context, msg *easygo.EmailMessage) (string, error)
+}
+
+type EmailNotifierOptions struct {
+	// From is the verified SES sender address (required)
+	From string
+	// To are the recipients (required)
+	To []string
+	// SubjectPrefix is prepended to subjects, e.g. "[prod]"
+	SubjectPrefix string
+}
+
+// EmailNotifier sends notifications as HTML and plain text email through SES
+type EmailNotifier struct {
+	sender EmailSender
+	opts   EmailNotifierOptions
+}
+
+var _ Notifier = (*EmailNotifier)(nil)
+
+// NewEmailNotifier creates an email notifier sending with sender
+func NewEmailNotifier(sender EmailSender, opts *EmailNotifierOptions) (*EmailNotifier, error) {
+	if opts == nil || opts.From == "" || len(opts.To) == 0 {
+		return nil, errs.New(errs.Internal, "notify: From and To are required")
+	}
+	return &EmailNotifier{sender: sender, opts: *opts}, nil
+}
+
+func (e *EmailNotifier) Notify(ctx context.Context, n *Notification) error {
+	n = withDefaults(n)
+	subj := subject(n)
+	if e.opts.SubjectPrefix != "" {
+		subj = e.opts.SubjectPrefix + " " + subj
+	}
+	_, err := e.sender.SendEmailMessage(ctx, &easygo.EmailMessage{
+		From:     e.opts.From,
+		To:       e.opts.To,
+		Subject:  subj,
+		HTMLBody: htmlBody(n),
+		TextBody: plainText(n),
+	})
+	if err != nil {
+		return errs.Errorf("failed to send notification email: %w", err)
+	}
+	return nil
+}
+
+func htmlBody(n *Notification) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<h2 style=\"color:%s\">%s</h2>", severityColor(n.Severity), html.EscapeString(subject(n)))
+	if n.Body != "" {
+		fmt.Fprintf(&b, "<p style=\"white-space:pre-wrap\">%s</p>", html.EscapeString(n.Body))
+	}
+	if len(n.Fields) > 0 {
+		b.WriteString("<table>")
+		for _, k := range sortedFields(n) {
+			fmt.Fprintf(&b, "<tr><th align=\"left\">%s</th><td>%s</td></tr>", html.EscapeString(k), html.EscapeString(n.Fields[k]))
+		}
+		b.WriteString("</table>")
+	}
+	fmt.Fprintf(&b, "<p><small>%s</small></p>", n.Time.UTC().Format(time.RFC3339))
+	return b.String()
+}
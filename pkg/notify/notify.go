@@ -0,0 +1,203 @@
+// Package notify sends alerts from jobs and servers to SNS topics, SES email
+// and Slack or Teams webhooks, with severity routing and text/template
+// rendering of notifications.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// Severity orders notifications for routing
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+	Critical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	case Critical:
+		return "critical"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity parses a severity name such as "warning"
+func ParseSeverity(name string) (Severity, error) {
+	for s := Info; s <= Critical; s++ {
+		if strings.EqualFold(name, s.String()) {
+			return s, nil
+		}
+	}
+	return Info, errs.Newf(errs.Internal, "unknown severity %q", name)
+}
+
+// Notification is an alert to deliver
+type Notification struct {
+	Severity Severity
+	Title    string
+	Body     string
+	// Fields are key facts shown as a list, e.g. job name or error count
+	Fields map[string]string
+	// Source identifies the sender, e.g. the service or job name
+	Source string
+	// Time defaults to when the notification is sent
+	Time time.Time
+}
+
+// Notifier delivers notifications
+type Notifier interface {
+	Notify(ctx context.Context, n *Notification) error
+}
+
+// NotifierFunc adapts a function to a Notifier
+type NotifierFunc func(ctx context.Context, n *Notification) error
+
+func (f NotifierFunc) Notify(ctx context.Context, n *Notification) error {
+	return f(ctx, n)
+}
+
+// Route sends notifications at or above MinSeverity to Notifier
+type Route struct {
+	MinSeverity Severity
+	Notifier    Notifier
+}
+
+// Router delivers each notification to every route it matches, e.g. all
+// notifications to Slack and only critical ones to a paging SNS topic
+type Router struct {
+	routes []Route
+}
+
+var _ Notifier = (*Router)(nil)
+
+// NewRouter creates a Router for routes
+func NewRouter(routes ...Route) *Router {
+	return &Router{routes: routes}
+}
+
+// Notify sends n to every matching route, continuing past failed routes and
+// returning their joined errors
+func (r *Router) Notify(ctx context.Context, n *Notification) error {
+	n = withDefaults(n)
+	var errs []error
+	for _, route := range r.routes {
+		if n.Severity < route.MinSeverity {
+			continue
+		}
+		if err := route.Notifier.Notify(ctx, n); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Template renders notifications from text/template title and body templates
+type Template struct {
+	Severity Severity
+	title    *template.Template
+	body     *template.Template
+}
+
+// NewTemplate parses title and body templates for notifications of severity
+func NewTemplate(severity Severity, title, body string) (*Template, error) {
+	t := &Template{Severity: severity}
+	var err error
+	if t.title, err = template.New("title").Option("missingkey=error").Parse(title); err != nil {
+		return nil, errs.Errorf("invalid title template: %w", err)
+	}
+	if t.body, err = template.New("body").Option("missingkey=error").Parse(body); err != nil {
+		return nil, errs.Errorf("invalid body template: %w", err)
+	}
+	return t, nil
+}
+
+// MustTemplate is NewTemplate that panics on invalid templates, for package-level templates
+func MustTemplate(severity Severity, title, body string) *Template {
+	t, err := NewTemplate(severity, title, body)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Render executes the templates with data
+func (t *Template) Render(data any) (*Notification, error) {
+	var title, body strings.Builder
+	if err := t.title.Execute(&title, data); err != nil {
+		return nil, errs.Errorf("failed to render title: %w", err)
+	}
+	if err := t.body.Execute(&body, data); err != nil {
+		return nil, errs.Errorf("failed to render body: %w", err)
+	}
+	return &Notification{Severity: t.Severity, Title: title.String(), Body: body.String()}, nil
+}
+
+// Send renders t with data and sends it with notifier
+func (t *Template) Send(ctx context.Context, notifier Notifier, data any) error {
+	n, err := t.Render(data)
+	if err != nil {
+		return err
+	}
+	return notifier.Notify(ctx, n)
+}
+
+// withDefaults returns n with Time set
+func withDefaults(n *Notification) *Notification {
+	if !n.Time.IsZero() {
+		return n
+	}
+	c := *n
+	c.Time = time.Now()
+	return &c
+}
+
+// sortedFields returns the field names in order so output is stable
+func sortedFields(n *Notification) []string {
+	return slices.Sorted(maps.Keys(n.Fields))
+}
+
+// subject is the one-line summary used by email and SNS
+func subject(n *Notification) string {
+	s := fmt.Sprintf("[%s] %s", strings.ToUpper(n.Severity.String()), n.Title)
+	if n.Source != "" {
+		s += " (" + n.Source + ")"
+	}
+	return s
+}
+
+// plainText renders n for text-only destinations
+func plainText(n *Notification) string {
+	var b strings.Builder
+	b.WriteString(subject(n))
+	if n.Body != "" {
+		b.WriteString("\n\n")
+		b.WriteString(n.Body)
+	}
+	if len(n.Fields) > 0 {
+		b.WriteString("\n")
+		for _, k := range sortedFields(n) {
+			fmt.Fprintf(&b, "\n%s: %s", k, n.Fields[k])
+		}
+	}
+	fmt.Fprintf(&b, "\n\n%s", n.Time.UTC().Format(time.RFC3339))
+	return b.String()
+}
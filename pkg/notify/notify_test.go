@@ -0,0 +1,123 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/bdlilley/easygo"
+)
+
+type fakeSNS struct {
+	input *sns.PublishInput
+}
+
+func (f *fakeSNS) Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	f.input = params
+	return &sns.PublishOutput{}, nil
+}
+
+type fakeSender struct {
+	msg *easygo.EmailMessage
+}
+
+func (f *fakeSender) SendEmailMessage(ctx context.Context, msg *easygo.EmailMessage) (string, error) {
+	f.msg = msg
+	return "id", nil
+}
+
+func TestRouter(t *testing.T) {
+	var all, critical []string
+	router := NewRouter(
+		Route{MinSeverity: Info, Notifier: NotifierFunc(func(ctx context.Context, n *Notification) error {
+			all = append(all, n.Title)
+			return nil
+		})},
+		Route{MinSeverity: Critical, Notifier: NotifierFunc(func(ctx context.Context, n *Notification) error {
+			critical = append(critical, n.Title)
+			return nil
+		})},
+	)
+	ctx := context.Background()
+	_ = router.Notify(ctx, &Notification{Severity: Warning, Title: "slow"})
+	_ = router.Notify(ctx, &Notification{Severity: Critical, Title: "down"})
+	if len(all) != 2 || len(critical) != 1 || critical[0] != "down" {
+		t.Fatalf("unexpected routing all=%v critical=%v", all, critical)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	tmpl := MustTemplate(Error, "{{.Job}} failed", "{{.Count}} records rejected")
+	n, err := tmpl.Render(map[string]any{"Job": "backfill", "Count": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Severity != Error || n.Title != "backfill failed" || n.Body != "3 records rejected" {
+		t.Fatalf("unexpected notification %+v", n)
+	}
+	if _, err := tmpl.Render(map[string]any{}); err == nil {
+		t.Fatal("expected missing key error")
+	}
+	if s, err := ParseSeverity("WARNING"); err != nil || s != Warning {
+		t.Fatalf("unexpected severity %v %v", s, err)
+	}
+}
+
+func TestBackends(t *testing.T) {
+	ctx := context.Background()
+	n := &Notification{
+		Severity: Critical,
+		Title:    "backfill failed",
+		Body:     "3 records rejected",
+		Fields:   map[string]string{"job": "backfill", "env": "prod"},
+		Source:   "worker",
+		Time:     time.Unix(1700000000, 0),
+	}
+
+	var payload map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	if err := NewSlackNotifier(srv.URL, nil).Notify(ctx, n); err != nil {
+		t.Fatal(err)
+	}
+	attachment := payload["attachments"].([]any)[0].(map[string]any)
+	if attachment["title"] != "[CRITICAL] backfill failed (worker)" || len(attachment["fields"].([]any)) != 2 {
+		t.Fatalf("unexpected slack payload %v", payload)
+	}
+	if err := NewTeamsNotifier(srv.URL, nil).Notify(ctx, n); err != nil {
+		t.Fatal(err)
+	}
+	if payload["@type"] != "MessageCard" || payload["themeColor"] != "8b0000" {
+		t.Fatalf("unexpected teams payload %v", payload)
+	}
+
+	snsClient := &fakeSNS{}
+	if err := NewSNSNotifier(snsClient, "arn:topic").Notify(ctx, n); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(aws.ToString(snsClient.input.Message), "env: prod\njob: backfill") ||
+		aws.ToString(snsClient.input.MessageAttributes["severity"].StringValue) != "critical" {
+		t.Fatalf("unexpected sns input %+v", snsClient.input)
+	}
+
+	sender := &fakeSender{}
+	email, err := NewEmailNotifier(sender, &EmailNotifierOptions{From: "alerts@example.com", To: []string{"ops@example.com"}, SubjectPrefix: "[prod]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := email.Notify(ctx, n); err != nil {
+		t.Fatal(err)
+	}
+	if sender.msg.Subject != "[prod] [CRITICAL] backfill failed (worker)" || !strings.Contains(sender.msg.HTMLBody, "<th align=\"left\">env</th>") {
+		t.Fatalf("unexpected email %+v", sender.msg)
+	}
+}
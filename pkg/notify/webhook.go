@@ -0,0 +1,144 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/httpclient"
+)
+
+// maxWebhookErrorBytes bounds the response body included in webhook errors
+const maxWebhookErrorBytes = 4 << 10
+
+// severityColor is the accent color for chat messages and email headings
+func severityColor(s Severity) string {
+	switch s {
+	case Warning:
+		return "#d9a400"
+	case Error:
+		return "#d13438"
+	case Critical:
+		return "#8b0000"
+	}
+	return "#2eb67d"
+}
+
+type WebhookOptions struct {
+	// Client sends the webhook requests (default: httpclient.NewClient(nil))
+	Client *httpclient.Client
+}
+
+// WebhookNotifier posts notifications to a chat incoming webhook
+type WebhookNotifier struct {
+	url     string
+	client  *httpclient.Client
+	payload func(n *Notification) any
+}
+
+var _ Notifier = (*WebhookNotifier)(nil)
+
+func newWebhookNotifier(url string, opts *WebhookOptions, payload func(n *Notification) any) *WebhookNotifier {
+	w := &WebhookNotifier{url: url, payload: payload}
+	if opts != nil {
+		w.client = opts.Client
+	}
+	if w.client == nil {
+		w.client = httpclient.NewClient(nil)
+	}
+	return w
+}
+
+// NewSlackNotifier posts notifications to a Slack incoming webhook URL as a
+// message attachment colored by severity
+func NewSlackNotifier(url string, opts *WebhookOptions) *WebhookNotifier {
+	return newWebhookNotifier(url, opts, slackPayload)
+}
+
+// NewTeamsNotifier posts notifications to a Microsoft Teams incoming webhook
+// URL as a message card colored by severity
+func NewTeamsNotifier(url string, opts *WebhookOptions) *WebhookNotifier {
+	return newWebhookNotifier(url, opts, teamsPayload)
+}
+
+func (w *WebhookNotifier) Notify(ctx context.Context, n *Notification) error {
+	body, err := json.Marshal(w.payload(withDefaults(n)))
+	if err != nil {
+		return errs.Errorf("failed to marshal webhook payload: %w", err)
+	}
+	req, err := w.client.NewRequest(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return errs.Errorf("failed to post notification webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookErrorBytes))
+		return errs.Newf(errs.Internal, "notification webhook returned status %d: %s", resp.StatusCode, data)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type slackAttachment struct {
+	Color    string       `json:"color"`
+	Title    string       `json:"title"`
+	Text     string       `json:"text,omitempty"`
+	Fields   []slackField `json:"fields,omitempty"`
+	Footer   string       `json:"footer,omitempty"`
+	Ts       int64        `json:"ts"`
+	Fallback string       `json:"fallback"`
+}
+
+func slackPayload(n *Notification) any {
+	a := slackAttachment{
+		Color:    severityColor(n.Severity),
+		Title:    subject(n),
+		Text:     n.Body,
+		Footer:   n.Source,
+		Ts:       n.Time.Unix(),
+		Fallback: subject(n),
+	}
+	for _, k := range sortedFields(n) {
+		a.Fields = append(a.Fields, slackField{Title: k, Value: n.Fields[k], Short: len(n.Fields[k]) < 40})
+	}
+	return map[string]any{"attachments": []slackAttachment{a}}
+}
+
+type teamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func teamsPayload(n *Notification) any {
+	facts := make([]teamsFact, 0, len(n.Fields))
+	for _, k := range sortedFields(n) {
+		facts = append(facts, teamsFact{Name: k, Value: n.Fields[k]})
+	}
+	section := map[string]any{"facts": facts}
+	if n.Source != "" {
+		section["activitySubtitle"] = n.Source
+	}
+	return map[string]any{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    subject(n),
+		"themeColor": severityColor(n.Severity)[1:],
+		"title":      subject(n),
+		"text":       n.Body,
+		"sections":   []any{section},
+	}
+}
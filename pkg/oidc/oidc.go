@@ -0,0 +1,426 @@
+// Package oidc signs users in to browser apps with an OpenID Connect
+// provider: discovery, the authorization code flow with PKCE, encrypted
+// session cookies with token refresh, and middleware gating routes on a
+// signed-in session.
+//
+//	rp, err := oidc.New(ctx, &oidc.NewRelyingPartyArgs{
+//		Issuer:       "https://login.example.com",
+//		ClientID:     clientID,
+//		ClientSecret: clientSecret,
+//		RedirectURL:  "https://app.example.com/auth/callback",
+//		CookieSecret: cookieSecret,
+//	})
+//	server.Chi.Mount("/auth", rp.Routes())
+//	server.Chi.With(rp.RequireSession).Get("/", home)
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/MicahParks/jwkset"
+	"github.com/MicahParks/keyfunc/v3"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/httpserver"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+const (
+	defaultCookieName = "easygo_session"
+	// flowCookieSuffix names the cookie holding state between login and callback
+	flowCookieSuffix = "_flow"
+	flowTTL          = 10 * time.Minute
+	// refreshMargin refreshes access tokens shortly before they expire
+	refreshMargin = 30 * time.Second
+)
+
+// ProviderMetadata is the subset of the discovery document the relying party uses
+type ProviderMetadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	UserInfoEndpoint      string   `json:"userinfo_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	EndSessionEndpoint    string   `json:"end_session_endpoint"`
+	SigningAlgorithms     []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// Discover fetches issuer's /.well-known/openid-configuration; client may be nil
+func Discover(ctx context.Context, client *http.Client, issuer string) (*ProviderMetadata, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	u := strings.TrimRight(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, errs.Errorf("invalid issuer %s: %w", issuer, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errs.Errorf("failed to fetch %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errs.Newf(errs.Internal, "failed to fetch %s: %s", u, resp.Status)
+	}
+	md := &ProviderMetadata{}
+	if err := json.NewDecoder(resp.Body).Decode(md); err != nil {
+		return nil, errs.Errorf("invalid discovery document from %s: %w", u, err)
+	}
+	if md.Issuer != strings.TrimRight(issuer, "/") && md.Issuer != issuer {
+		return nil, errs.Newf(errs.Internal, "discovery document issuer %s does not match %s", md.Issuer, issuer)
+	}
+	return md, nil
+}
+
+type NewRelyingPartyArgs struct {
+	// Issuer is the provider's issuer URL, used for discovery (required)
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the absolute URL of the callback route, i.e. where
+	// Routes is mounted plus /callback (required)
+	RedirectURL string
+	// Scopes are requested at login (default: openid, profile, email)
+	Scopes []string
+	// CookieSecret encrypts session cookies; at least 32 random bytes shared
+	// by every replica (required)
+	CookieSecret []byte
+	// CookieName names the session cookie (default: easygo_session)
+	CookieName string
+	// InsecureCookies drops the Secure attribute for local http development
+	InsecureCookies bool
+	// SessionTTL ends sessions after this long, even when tokens are
+	// refreshed (default: 12h)
+	SessionTTL time.Duration
+	// LoginPath is where RequireSession sends browsers without a session,
+	// i.e. where Routes is mounted plus /login (default: /auth/login)
+	LoginPath string
+	// PostLoginPath is the default page after login (default: /)
+	PostLoginPath string
+	// PostLogoutURL is where users land after logout, passed to the
+	// provider's end session endpoint when it has one (default: /)
+	PostLogoutURL string
+	// HTTPClient calls the provider (default: http.DefaultClient)
+	HTTPClient *http.Client
+	Logger     logging.Logger
+}
+
+// RelyingParty handles sign-in with one OIDC provider
+type RelyingParty struct {
+	args     NewRelyingPartyArgs
+	metadata *ProviderMetadata
+	oauth    *oauth2.Config
+	cookies  *cookieCodec
+	keyfunc  jwt.Keyfunc
+	parser   *jwt.Parser
+	logger   logging.Logger
+}
+
+// New discovers the provider and loads its signing keys; ctx bounds the
+// background key refresh
+func New(ctx context.Context, args *NewRelyingPartyArgs) (*RelyingParty, error) {
+	if args == nil || args.Issuer == "" || args.ClientID == "" || args.RedirectURL == "" {
+		return nil, errs.New(errs.Internal, "oidc: Issuer, ClientID and RedirectURL are required")
+	}
+	a := *args
+	if len(a.Scopes) == 0 {
+		a.Scopes = []string{"openid", "profile", "email"}
+	}
+	if a.CookieName == "" {
+		a.CookieName = defaultCookieName
+	}
+	if a.SessionTTL <= 0 {
+		a.SessionTTL = 12 * time.Hour
+	}
+	if a.LoginPath == "" {
+		a.LoginPath = "/auth/login"
+	}
+	if a.PostLoginPath == "" {
+		a.PostLoginPath = "/"
+	}
+	if a.PostLogoutURL == "" {
+		a.PostLogoutURL = "/"
+	}
+	if a.HTTPClient == nil {
+		a.HTTPClient = http.DefaultClient
+	}
+	if a.Logger == nil {
+		a.Logger = logging.Noop()
+	}
+
+	cookies, err := newCookieCodec(a.CookieSecret, !a.InsecureCookies)
+	if err != nil {
+		return nil, err
+	}
+	md, err := Discover(ctx, a.HTTPClient, a.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	storage, err := jwkset.NewStorageFromHTTP(md.JWKSURI, jwkset.HTTPClientStorageOptions{
+		Client:          a.HTTPClient,
+		Ctx:             ctx,
+		RefreshInterval: time.Hour,
+		RefreshErrorHandler: func(_ context.Context, err error) {
+			a.Logger.WithError(err).WithField("url", md.JWKSURI).Error("failed to refresh JWKS")
+		},
+	})
+	if err != nil {
+		return nil, errs.Errorf("failed to load JWKS from %s: %w", md.JWKSURI, err)
+	}
+	jwks, err := keyfunc.New(keyfunc.Options{Ctx: ctx, Storage: storage})
+	if err != nil {
+		return nil, errs.Errorf("failed to load JWKS from %s: %w", md.JWKSURI, err)
+	}
+	algs := md.SigningAlgorithms
+	if len(algs) == 0 {
+		algs = []string{"RS256"}
+	}
+
+	return &RelyingParty{
+		args:     a,
+		metadata: md,
+		oauth: &oauth2.Config{
+			ClientID:     a.ClientID,
+			ClientSecret: a.ClientSecret,
+			RedirectURL:  a.RedirectURL,
+			Scopes:       a.Scopes,
+			Endpoint:     oauth2.Endpoint{AuthURL: md.AuthorizationEndpoint, TokenURL: md.TokenEndpoint},
+		},
+		cookies: cookies,
+		keyfunc: jwks.KeyfuncCtx(ctx),
+		parser: jwt.NewParser(
+			jwt.WithValidMethods(algs),
+			jwt.WithIssuer(md.Issuer),
+			jwt.WithAudience(a.ClientID),
+			jwt.WithExpirationRequired(),
+			jwt.WithLeeway(time.Minute),
+		),
+		logger: a.Logger,
+	}, nil
+}
+
+// Metadata returns the discovered provider metadata
+func (rp *RelyingParty) Metadata() *ProviderMetadata {
+	return rp.metadata
+}
+
+// Routes returns the /login, /callback and /logout handlers to mount, e.g.
+// at /auth. Login accepts a return_to query parameter with a local path.
+func (rp *RelyingParty) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/login", rp.login)
+	r.Get("/callback", rp.callback)
+	r.Get("/logout", rp.logout)
+	r.Post("/logout", rp.logout)
+	return r
+}
+
+// flow is kept in a short-lived cookie between login and callback
+type flow struct {
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	ReturnTo string `json:"r"`
+}
+
+func (rp *RelyingParty) login(w http.ResponseWriter, r *http.Request) {
+	f := &flow{
+		State:    randomString(),
+		Nonce:    randomString(),
+		Verifier: oauth2.GenerateVerifier(),
+		ReturnTo: localPath(r.URL.Query().Get("return_to"), rp.args.PostLoginPath),
+	}
+	if err := rp.cookies.write(w, r, rp.args.CookieName+flowCookieSuffix, f, flowTTL); err != nil {
+		rp.fail(w, r, http.StatusInternalServerError, "failed to start login", err)
+		return
+	}
+	http.Redirect(w, r, rp.oauth.AuthCodeURL(f.State,
+		oauth2.S256ChallengeOption(f.Verifier),
+		oauth2.SetAuthURLParam("nonce", f.Nonce),
+	), http.StatusFound)
+}
+
+func (rp *RelyingParty) callback(w http.ResponseWriter, r *http.Request) {
+	flowCookie := rp.args.CookieName + flowCookieSuffix
+	var f flow
+	ok, err := rp.cookies.read(r, flowCookie, &f)
+	rp.cookies.clear(w, r, flowCookie)
+	q := r.URL.Query()
+	switch {
+	case err != nil || !ok:
+		rp.fail(w, r, http.StatusBadRequest, "login expired, please sign in again", err)
+		return
+	case q.Get("state") != f.State:
+		rp.fail(w, r, http.StatusBadRequest, "login state mismatch", nil)
+		return
+	case q.Get("error") != "":
+		rp.fail(w, r, http.StatusUnauthorized, "sign in failed: "+q.Get("error"), errs.New(errs.Internal, q.Get("error_description")))
+		return
+	}
+
+	ctx := rp.clientContext(r.Context())
+	token, err := rp.oauth.Exchange(ctx, q.Get("code"), oauth2.VerifierOption(f.Verifier))
+	if err != nil {
+		rp.fail(w, r, http.StatusBadGateway, "failed to exchange authorization code", err)
+		return
+	}
+	session, err := rp.newSession(token, f.Nonce)
+	if err != nil {
+		rp.fail(w, r, http.StatusUnauthorized, "invalid ID token", err)
+		return
+	}
+	if err := rp.writeSession(w, r, session); err != nil {
+		rp.fail(w, r, http.StatusInternalServerError, "failed to save session", err)
+		return
+	}
+	logging.WithTrace(r.Context(), rp.logger).WithField("subject", session.Subject).Info("user signed in")
+	http.Redirect(w, r, f.ReturnTo, http.StatusFound)
+}
+
+func (rp *RelyingParty) logout(w http.ResponseWriter, r *http.Request) {
+	var session Session
+	ok, _ := rp.cookies.read(r, rp.args.CookieName, &session)
+	rp.cookies.clear(w, r, rp.args.CookieName)
+
+	target := rp.args.PostLogoutURL
+	if rp.metadata.EndSessionEndpoint != "" {
+		q := url.Values{"client_id": {rp.args.ClientID}}
+		if ok && session.IDToken != "" {
+			q.Set("id_token_hint", session.IDToken)
+		}
+		if strings.Contains(rp.args.PostLogoutURL, "://") {
+			q.Set("post_logout_redirect_uri", rp.args.PostLogoutURL)
+		}
+		sep := "?"
+		if strings.Contains(rp.metadata.EndSessionEndpoint, "?") {
+			sep = "&"
+		}
+		target = rp.metadata.EndSessionEndpoint + sep + q.Encode()
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// newSession verifies the ID token of token and builds a session from it
+func (rp *RelyingParty) newSession(token *oauth2.Token, nonce string) (*Session, error) {
+	raw, _ := token.Extra("id_token").(string)
+	if raw == "" {
+		return nil, errs.New(errs.Internal, "token response has no id_token")
+	}
+	claims := jwt.MapClaims{}
+	if _, err := rp.parser.ParseWithClaims(raw, claims, rp.keyfunc); err != nil {
+		return nil, errs.Errorf("failed to verify ID token: %w", err)
+	}
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return nil, errs.New(errs.Internal, "ID token nonce mismatch")
+	}
+	sub, _ := claims.GetSubject()
+	email, _ := claims["email"].(string)
+	name, _ := claims["name"].(string)
+	return &Session{
+		Subject:      sub,
+		Email:        email,
+		Name:         name,
+		Claims:       claims,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		TokenExpiry:  token.Expiry,
+		IDToken:      raw,
+		ExpiresAt:    time.Now().Add(rp.args.SessionTTL),
+	}, nil
+}
+
+func (rp *RelyingParty) writeSession(w http.ResponseWriter, r *http.Request, s *Session) error {
+	return rp.cookies.write(w, r, rp.args.CookieName, s, time.Until(s.ExpiresAt))
+}
+
+// LoadSession returns the request's session, refreshing its access token
+// when it is about to expire. It returns false when there is no valid
+// session.
+func (rp *RelyingParty) LoadSession(w http.ResponseWriter, r *http.Request) (*Session, bool) {
+	var s Session
+	ok, err := rp.cookies.read(r, rp.args.CookieName, &s)
+	if err != nil || !ok || time.Now().After(s.ExpiresAt) {
+		if ok || err != nil {
+			rp.cookies.clear(w, r, rp.args.CookieName)
+		}
+		return nil, false
+	}
+	if s.RefreshToken == "" || s.TokenExpiry.IsZero() || time.Until(s.TokenExpiry) > refreshMargin {
+		return &s, true
+	}
+
+	token, err := rp.oauth.TokenSource(rp.clientContext(r.Context()), &oauth2.Token{
+		RefreshToken: s.RefreshToken,
+		Expiry:       time.Now().Add(-time.Second),
+	}).Token()
+	if err != nil {
+		logging.WithTrace(r.Context(), rp.logger).WithError(err).WithField("subject", s.Subject).Info("failed to refresh session, signing out")
+		rp.cookies.clear(w, r, rp.args.CookieName)
+		return nil, false
+	}
+	s.AccessToken, s.TokenExpiry = token.AccessToken, token.Expiry
+	if token.RefreshToken != "" {
+		s.RefreshToken = token.RefreshToken
+	}
+	if err := rp.writeSession(w, r, &s); err != nil {
+		logging.WithTrace(r.Context(), rp.logger).WithError(err).Warn("failed to save refreshed session")
+	}
+	return &s, true
+}
+
+// RequireSession passes requests with a session to next, with the session
+// in the context. Browser page requests without one are redirected to login
+// and return to the same page; other requests get a 401 problem response.
+func (rp *RelyingParty) RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, ok := rp.LoadSession(w, r)
+		if ok {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionKey{}, session)))
+			return
+		}
+		if (r.Method == http.MethodGet || r.Method == http.MethodHead) && strings.Contains(r.Header.Get("Accept"), "text/html") {
+			http.Redirect(w, r, rp.args.LoginPath+"?"+url.Values{"return_to": {r.URL.RequestURI()}}.Encode(), http.StatusFound)
+			return
+		}
+		httpserver.WriteProblem(w, r, httpserver.NewProblem(http.StatusUnauthorized, "sign in required"))
+	})
+}
+
+// clientContext makes oauth2 use the configured HTTP client
+func (rp *RelyingParty) clientContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, rp.args.HTTPClient)
+}
+
+func (rp *RelyingParty) fail(w http.ResponseWriter, r *http.Request, status int, detail string, err error) {
+	entry := logging.WithTrace(r.Context(), rp.logger)
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+	entry.WithField("status", status).Warn("OIDC " + detail)
+	httpserver.WriteProblem(w, r, httpserver.NewProblem(status, detail))
+}
+
+// localPath returns p when it is a path on this site, so return_to cannot
+// redirect users elsewhere, and def otherwise
+func localPath(p, def string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") || strings.HasPrefix(p, "/\\") {
+		return def
+	}
+	return p
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
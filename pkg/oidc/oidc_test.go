@@ -0,0 +1,249 @@
+package oidc
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/jwtauth"
+	"github.com/go-chi/chi/v5"
+)
+
+// fakeProvider is a minimal OIDC provider issuing tokens with jwtauth
+type fakeProvider struct {
+	*httptest.Server
+	issuer *jwtauth.Issuer
+
+	mu sync.Mutex
+	// codes maps authorization codes to their nonce and PKCE challenge
+	codes     map[string][2]string
+	refreshes int
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := jwtauth.NewKeySigner(&jwtauth.NewKeySignerArgs{Key: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakeProvider{codes: map[string][2]string{}}
+	mux := http.NewServeMux()
+	p.Server = httptest.NewServer(mux)
+	p.issuer, _ = jwtauth.NewIssuer(&jwtauth.NewIssuerArgs{Signer: signer, Issuer: p.URL, Audience: []string{"app"}})
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+			"jwks_uri":               p.URL + "/jwks",
+			"end_session_endpoint":   p.URL + "/logout",
+		})
+	})
+	mux.Handle("/jwks", p.issuer.JWKSHandler())
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("code_challenge_method") != "S256" {
+			http.Error(w, "PKCE required", http.StatusBadRequest)
+			return
+		}
+		p.mu.Lock()
+		p.codes["code-1"] = [2]string{q.Get("nonce"), q.Get("code_challenge")}
+		p.mu.Unlock()
+		http.Redirect(w, r, q.Get("redirect_uri")+"?code=code-1&state="+url.QueryEscape(q.Get("state")), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		resp := map[string]any{"access_token": "refreshed", "token_type": "Bearer", "expires_in": 3600}
+		if r.Form.Get("grant_type") == "refresh_token" {
+			p.mu.Lock()
+			p.refreshes++
+			p.mu.Unlock()
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+		p.mu.Lock()
+		code, ok := p.codes[r.Form.Get("code")]
+		p.mu.Unlock()
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != code[1] {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		idToken, err := p.issuer.Issue(r.Context(), jwtauth.NewClaims("user-1").With("nonce", code[0]).With("email", "ann@example.com"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// an access token that is already due for refresh
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "initial", "token_type": "Bearer", "expires_in": 1,
+			"refresh_token": "rt-1", "id_token": idToken,
+		})
+	})
+	t.Cleanup(p.Close)
+	return p
+}
+
+func TestLoginFlow(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	provider := newFakeProvider(t)
+
+	app := chi.NewRouter()
+	appServer := httptest.NewServer(app)
+	defer appServer.Close()
+	rp, err := New(ctx, &NewRelyingPartyArgs{
+		Issuer:          provider.URL,
+		ClientID:        "app",
+		ClientSecret:    "secret",
+		RedirectURL:     appServer.URL + "/auth/callback",
+		CookieSecret:    bytes.Repeat([]byte("s"), 32),
+		InsecureCookies: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.Mount("/auth", rp.Routes())
+	app.With(rp.RequireSession).Get("/me", func(w http.ResponseWriter, r *http.Request) {
+		s, _ := SessionFromContext(r.Context())
+		w.Write([]byte(s.Subject + " " + s.Email + " " + s.AccessToken))
+	})
+
+	// API requests without a session are rejected
+	resp, err := http.Get(appServer.URL + "/me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("got %d, want 401", resp.StatusCode)
+	}
+
+	// browsers are sent through the provider and back to the page
+	jar, _ := cookiejar.New(nil)
+	browser := &http.Client{Jar: jar}
+	req, _ := http.NewRequest(http.MethodGet, appServer.URL+"/me", nil)
+	req.Header.Set("Accept", "text/html")
+	resp, err = browser.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := new(bytes.Buffer)
+	body.ReadFrom(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || body.String() != "user-1 ann@example.com refreshed" {
+		t.Fatalf("got %d %q", resp.StatusCode, body.String())
+	}
+	if provider.refreshes != 1 {
+		t.Fatalf("got %d refreshes, want the expiring access token refreshed once", provider.refreshes)
+	}
+
+	// logout clears the session and goes to the provider
+	noRedirect := &http.Client{Jar: jar, CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err = noRedirect.Post(appServer.URL+"/auth/logout", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if loc := resp.Header.Get("Location"); !strings.HasPrefix(loc, provider.URL+"/logout?") || !strings.Contains(loc, "id_token_hint=") {
+		t.Fatalf("got logout redirect %q", loc)
+	}
+	resp, err = browser.Get(appServer.URL + "/me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("got %d after logout, want 401", resp.StatusCode)
+	}
+}
+
+func TestCallbackRejectsForgedState(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	provider := newFakeProvider(t)
+	rp, err := New(ctx, &NewRelyingPartyArgs{
+		Issuer:       provider.URL,
+		ClientID:     "app",
+		RedirectURL:  "https://app.example.com/auth/callback",
+		CookieSecret: bytes.Repeat([]byte("s"), 32),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := rp.Routes()
+
+	rec := httptest.NewRecorder()
+	routes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/login?return_to=//evil.example.com", nil))
+	var flowCookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == defaultCookieName+flowCookieSuffix {
+			flowCookie = c
+		}
+	}
+	if flowCookie == nil || !flowCookie.Secure || !flowCookie.HttpOnly {
+		t.Fatalf("got flow cookie %+v", flowCookie)
+	}
+	var f flow
+	if err := rp.cookies.open(flowCookie.Name, flowCookie.Value, &f); err != nil || f.ReturnTo != "/" {
+		t.Fatalf("got %+v %v, want return_to replaced with /", f, err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/callback?code=code-1&state=forged", nil)
+	req.AddCookie(flowCookie)
+	rec = httptest.NewRecorder()
+	routes.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400 for a forged state", rec.Code)
+	}
+}
+
+func TestCookieChunks(t *testing.T) {
+	codec, err := newCookieCodec(bytes.Repeat([]byte("k"), 32), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	big := &Session{Subject: "user-1", IDToken: strings.Repeat("x", 9000), ExpiresAt: time.Now().Add(time.Hour)}
+	rec := httptest.NewRecorder()
+	if err := codec.write(rec, httptest.NewRequest(http.MethodGet, "/", nil), "s", big, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) < 3 {
+		t.Fatalf("got %d cookies, want the session split", len(cookies))
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	var got Session
+	if ok, err := codec.read(req, "s", &got); !ok || err != nil || got.IDToken != big.IDToken {
+		t.Fatalf("got %v %v", ok, err)
+	}
+
+	// a value sealed for one cookie does not open as another
+	sealed, err := codec.seal("s", &Session{Subject: "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := codec.open("other", sealed, &got); err == nil {
+		t.Fatal("expected a cookie moved to another name to be rejected")
+	}
+}
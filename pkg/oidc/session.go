@@ -0,0 +1,171 @@
+package oidc
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// maxCookieValue keeps each cookie under the 4KB browser limit including its
+// name and attributes; larger values are split across numbered cookies
+const maxCookieValue = 3800
+
+// maxCookieChunks bounds how many cookies one value may span
+const maxCookieChunks = 10
+
+// Session is the signed-in user, stored encrypted in a cookie
+type Session struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email,omitempty"`
+	Name    string `json:"name,omitempty"`
+	// Claims are the ID token claims
+	Claims map[string]any `json:"claims,omitempty"`
+
+	AccessToken  string    `json:"at,omitempty"`
+	RefreshToken string    `json:"rt,omitempty"`
+	TokenExpiry  time.Time `json:"exp,omitzero"`
+	// IDToken is sent as the logout hint
+	IDToken string `json:"idt,omitempty"`
+	// ExpiresAt ends the session regardless of token refreshes
+	ExpiresAt time.Time `json:"sexp"`
+}
+
+type sessionKey struct{}
+
+// SessionFromContext returns the session of a request passed by
+// RelyingParty.RequireSession or LoadSession
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	s, ok := ctx.Value(sessionKey{}).(*Session)
+	return s, ok
+}
+
+// cookieCodec seals cookie values with AES-256-GCM, binding each to its
+// cookie name so values cannot be swapped between cookies
+type cookieCodec struct {
+	aead   cipher.AEAD
+	secure bool
+}
+
+func newCookieCodec(secret []byte, secure bool) (*cookieCodec, error) {
+	if len(secret) < 32 {
+		return nil, errs.New(errs.Internal, "oidc: CookieSecret must be at least 32 bytes")
+	}
+	key := sha256.Sum256(secret)
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, errs.Errorf("oidc: failed to create cookie cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errs.Errorf("oidc: failed to create cookie cipher: %w", err)
+	}
+	return &cookieCodec{aead: aead, secure: secure}, nil
+}
+
+func (c *cookieCodec) seal(name string, v any) (string, error) {
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return "", errs.Errorf("failed to marshal cookie: %w", err)
+	}
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plaintext)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errs.Errorf("failed to generate cookie nonce: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(c.aead.Seal(nonce, nonce, plaintext, []byte(name))), nil
+}
+
+func (c *cookieCodec) open(name, value string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(b) < c.aead.NonceSize() {
+		return errs.Newf(errs.Internal, "malformed %s cookie", name)
+	}
+	plaintext, err := c.aead.Open(nil, b[:c.aead.NonceSize()], b[c.aead.NonceSize():], []byte(name))
+	if err != nil {
+		return errs.Newf(errs.Internal, "invalid %s cookie", name)
+	}
+	return json.Unmarshal(plaintext, v)
+}
+
+// write seals v into the cookie name, split across name, name_1, name_2...
+// when it is too large for one cookie, and expires chunks left over from a
+// larger previous value
+func (c *cookieCodec) write(w http.ResponseWriter, r *http.Request, name string, v any, maxAge time.Duration) error {
+	value, err := c.seal(name, v)
+	if err != nil {
+		return err
+	}
+	var chunks []string
+	for len(value) > maxCookieValue {
+		chunks = append(chunks, value[:maxCookieValue])
+		value = value[maxCookieValue:]
+	}
+	chunks = append(chunks, value)
+	if len(chunks) > maxCookieChunks {
+		return errs.Newf(errs.Internal, "%s cookie is too large", name)
+	}
+	for i, chunk := range chunks {
+		http.SetCookie(w, c.cookie(chunkName(name, i), chunk, int(maxAge.Seconds())))
+	}
+	for i := len(chunks); i < maxCookieChunks; i++ {
+		if _, err := r.Cookie(chunkName(name, i)); err != nil {
+			break
+		}
+		http.SetCookie(w, c.cookie(chunkName(name, i), "", -1))
+	}
+	return nil
+}
+
+// read opens the cookie name into v, reporting false when it is absent
+func (c *cookieCodec) read(r *http.Request, name string, v any) (bool, error) {
+	var value strings.Builder
+	for i := range maxCookieChunks {
+		cookie, err := r.Cookie(chunkName(name, i))
+		if err != nil {
+			break
+		}
+		value.WriteString(cookie.Value)
+	}
+	if value.Len() == 0 {
+		return false, nil
+	}
+	return true, c.open(name, value.String(), v)
+}
+
+// clear expires the cookie name and its chunks
+func (c *cookieCodec) clear(w http.ResponseWriter, r *http.Request, name string) {
+	for i := range maxCookieChunks {
+		if _, err := r.Cookie(chunkName(name, i)); err != nil && i > 0 {
+			break
+		}
+		http.SetCookie(w, c.cookie(chunkName(name, i), "", -1))
+	}
+}
+
+func (c *cookieCodec) cookie(name, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   c.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func chunkName(name string, i int) string {
+	if i == 0 {
+		return name
+	}
+	return name + "_" + strconv.Itoa(i)
+}
@@ -0,0 +1,331 @@
+// Package otel sets up OpenTelemetry in one call: tracer and meter
+// providers exporting over OTLP, a resource describing the service, a
+// sampler and propagators, configured from Options and the standard OTEL_*
+// environment variables and installed as the global providers that the
+// httpserver, grpcserver and EGAwsClient instrumentation use by default.
+package otel
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/buildinfo"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/runtimeinfo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+)
+
+// Exporters
+const (
+	ExporterOTLP = "otlp"
+	ExporterNone = "none"
+)
+
+// OTLP protocols
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http/protobuf"
+)
+
+type Options struct {
+	// ServiceName is the service.name resource attribute (default:
+	// OTEL_SERVICE_NAME, then buildinfo's Name)
+	ServiceName string
+	// ServiceVersion is the service.version resource attribute (default:
+	// buildinfo's Version)
+	ServiceVersion string
+	// Environment is the deployment.environment.name resource attribute
+	Environment string
+	// Attributes are added to the resource, after OTEL_RESOURCE_ATTRIBUTES
+	Attributes []attribute.KeyValue
+
+	// Exporter is otlp or none (default: OTEL_TRACES_EXPORTER and
+	// OTEL_METRICS_EXPORTER, then otlp when an OTLP endpoint is configured
+	// and none otherwise)
+	Exporter string
+	// Protocol is grpc or http/protobuf (default: OTEL_EXPORTER_OTLP_PROTOCOL, then grpc)
+	Protocol string
+	// Endpoint is the OTLP collector URL, e.g. http://localhost:4317
+	// (default: OTEL_EXPORTER_OTLP_ENDPOINT and the per-signal variables)
+	Endpoint string
+	// Insecure disables TLS to the collector
+	Insecure bool
+	// Headers are sent with every export, e.g. an API key
+	Headers map[string]string
+
+	// Sampler defaults to OTEL_TRACES_SAMPLER, then sampling every trace not
+	// already sampled out by its parent
+	Sampler sdktrace.Sampler
+	// Propagators default to W3C tracecontext and baggage
+	Propagators propagation.TextMapPropagator
+	// MetricInterval is how often metrics are exported (default: OTEL_METRIC_EXPORT_INTERVAL, then 60s)
+	MetricInterval time.Duration
+	// DisableTraces and DisableMetrics skip setting up that signal
+	DisableTraces  bool
+	DisableMetrics bool
+	// NoGlobal leaves the global providers and propagator untouched
+	NoGlobal bool
+}
+
+// Telemetry holds the configured providers
+type Telemetry struct {
+	TracerProvider trace.TracerProvider
+	MeterProvider  metric.MeterProvider
+	Propagators    propagation.TextMapPropagator
+	Resource       *resource.Resource
+
+	shutdown []func(context.Context) error
+}
+
+// Setup configures tracing and metrics and, unless NoGlobal is set, installs
+// them globally. OTEL_SDK_DISABLED=true sets up no-op providers. Call
+// Shutdown before exiting to flush buffered telemetry:
+//
+//	tel, err := otel.Setup(ctx, &otel.Options{ServiceName: "orders"})
+//	if err != nil {
+//		return err
+//	}
+//	defer tel.Shutdown(context.WithoutCancel(ctx))
+func Setup(ctx context.Context, opts *Options) (*Telemetry, error) {
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Propagators == nil {
+		o.Propagators = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	}
+	t := &Telemetry{
+		TracerProvider: tracenoop.NewTracerProvider(),
+		MeterProvider:  metricnoop.NewMeterProvider(),
+		Propagators:    o.Propagators,
+	}
+	if disabled, _ := strconv.ParseBool(os.Getenv("OTEL_SDK_DISABLED")); disabled {
+		return t, nil
+	}
+
+	res, err := newResource(ctx, &o)
+	if err != nil {
+		return nil, err
+	}
+	t.Resource = res
+
+	if !o.DisableTraces {
+		if err := t.setupTraces(ctx, &o); err != nil {
+			return nil, errors.Join(err, t.Shutdown(ctx))
+		}
+	}
+	if !o.DisableMetrics {
+		if err := t.setupMetrics(ctx, &o); err != nil {
+			return nil, errors.Join(err, t.Shutdown(ctx))
+		}
+	}
+
+	if !o.NoGlobal {
+		otel.SetTracerProvider(t.TracerProvider)
+		otel.SetMeterProvider(t.MeterProvider)
+		otel.SetTextMapPropagator(t.Propagators)
+	}
+	return t, nil
+}
+
+// Shutdown flushes and stops the providers
+func (t *Telemetry) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, fn := range t.shutdown {
+		errs = append(errs, fn(ctx))
+	}
+	t.shutdown = nil
+	return errors.Join(errs...)
+}
+
+func (t *Telemetry) setupTraces(ctx context.Context, o *Options) error {
+	tracerOpts := []sdktrace.TracerProviderOption{sdktrace.WithResource(t.Resource)}
+	if o.Sampler != nil {
+		tracerOpts = append(tracerOpts, sdktrace.WithSampler(o.Sampler))
+	}
+	switch e := exporter(o, "TRACES"); e {
+	case ExporterNone:
+	case ExporterOTLP:
+		var exp sdktrace.SpanExporter
+		var err error
+		if protocol(o, "TRACES") == ProtocolHTTP {
+			exp, err = otlptracehttp.New(ctx, traceHTTPOptions(o)...)
+		} else {
+			exp, err = otlptracegrpc.New(ctx, traceGRPCOptions(o)...)
+		}
+		if err != nil {
+			return errs.Errorf("failed to create OTLP trace exporter: %w", err)
+		}
+		tracerOpts = append(tracerOpts, sdktrace.WithBatcher(exp))
+	default:
+		return errs.Newf(errs.Internal, "otel: unsupported trace exporter %q", e)
+	}
+	tp := sdktrace.NewTracerProvider(tracerOpts...)
+	t.TracerProvider = tp
+	t.shutdown = append(t.shutdown, tp.Shutdown)
+	return nil
+}
+
+func (t *Telemetry) setupMetrics(ctx context.Context, o *Options) error {
+	meterOpts := []sdkmetric.Option{sdkmetric.WithResource(t.Resource)}
+	switch e := exporter(o, "METRICS"); e {
+	case ExporterNone:
+	case ExporterOTLP:
+		var exp sdkmetric.Exporter
+		var err error
+		if protocol(o, "METRICS") == ProtocolHTTP {
+			exp, err = otlpmetrichttp.New(ctx, metricHTTPOptions(o)...)
+		} else {
+			exp, err = otlpmetricgrpc.New(ctx, metricGRPCOptions(o)...)
+		}
+		if err != nil {
+			return errs.Errorf("failed to create OTLP metric exporter: %w", err)
+		}
+		var readerOpts []sdkmetric.PeriodicReaderOption
+		if o.MetricInterval > 0 {
+			readerOpts = append(readerOpts, sdkmetric.WithInterval(o.MetricInterval))
+		}
+		meterOpts = append(meterOpts, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exp, readerOpts...)))
+	default:
+		return errs.Newf(errs.Internal, "otel: unsupported metric exporter %q", e)
+	}
+	mp := sdkmetric.NewMeterProvider(meterOpts...)
+	t.MeterProvider = mp
+	t.shutdown = append(t.shutdown, mp.Shutdown)
+	return nil
+}
+
+// exporter resolves the exporter of signal, TRACES or METRICS
+func exporter(o *Options, signal string) string {
+	if o.Exporter != "" {
+		return o.Exporter
+	}
+	if e := os.Getenv("OTEL_" + signal + "_EXPORTER"); e != "" {
+		return strings.Split(e, ",")[0]
+	}
+	if o.Endpoint != "" || os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_"+signal+"_ENDPOINT") != "" {
+		return ExporterOTLP
+	}
+	return ExporterNone
+}
+
+func protocol(o *Options, signal string) string {
+	if o.Protocol != "" {
+		return o.Protocol
+	}
+	for _, name := range []string{"OTEL_EXPORTER_OTLP_" + signal + "_PROTOCOL", "OTEL_EXPORTER_OTLP_PROTOCOL"} {
+		if p := os.Getenv(name); p != "" {
+			return p
+		}
+	}
+	return ProtocolGRPC
+}
+
+func traceGRPCOptions(o *Options) []otlptracegrpc.Option {
+	var opts []otlptracegrpc.Option
+	if o.Endpoint != "" {
+		opts = append(opts, otlptracegrpc.WithEndpointURL(o.Endpoint))
+	}
+	if o.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	if len(o.Headers) > 0 {
+		opts = append(opts, otlptracegrpc.WithHeaders(o.Headers))
+	}
+	return opts
+}
+
+func traceHTTPOptions(o *Options) []otlptracehttp.Option {
+	var opts []otlptracehttp.Option
+	if o.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpointURL(strings.TrimRight(o.Endpoint, "/")+"/v1/traces"))
+	}
+	if o.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if len(o.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(o.Headers))
+	}
+	return opts
+}
+
+func metricGRPCOptions(o *Options) []otlpmetricgrpc.Option {
+	var opts []otlpmetricgrpc.Option
+	if o.Endpoint != "" {
+		opts = append(opts, otlpmetricgrpc.WithEndpointURL(o.Endpoint))
+	}
+	if o.Insecure {
+		opts = append(opts, otlpmetricgrpc.WithInsecure())
+	}
+	if len(o.Headers) > 0 {
+		opts = append(opts, otlpmetricgrpc.WithHeaders(o.Headers))
+	}
+	return opts
+}
+
+func metricHTTPOptions(o *Options) []otlpmetrichttp.Option {
+	var opts []otlpmetrichttp.Option
+	if o.Endpoint != "" {
+		opts = append(opts, otlpmetrichttp.WithEndpointURL(strings.TrimRight(o.Endpoint, "/")+"/v1/metrics"))
+	}
+	if o.Insecure {
+		opts = append(opts, otlpmetrichttp.WithInsecure())
+	}
+	if len(o.Headers) > 0 {
+		opts = append(opts, otlpmetrichttp.WithHeaders(o.Headers))
+	}
+	return opts
+}
+
+// newResource describes the service. Later sources win: build and runtime
+// info, then OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES, then Options.
+func newResource(ctx context.Context, o *Options) (*resource.Resource, error) {
+	build := buildinfo.Get()
+	defaults := append([]attribute.KeyValue{semconv.ServiceName(build.Name)}, build.Attributes()...)
+	defaults = append(defaults, runtimeinfo.Get().Attributes()...)
+
+	var explicit []attribute.KeyValue
+	if o.ServiceName != "" {
+		explicit = append(explicit, semconv.ServiceName(o.ServiceName))
+	}
+	if o.ServiceVersion != "" {
+		explicit = append(explicit, semconv.ServiceVersion(o.ServiceVersion))
+	}
+	if o.Environment != "" {
+		explicit = append(explicit, semconv.DeploymentEnvironmentNameKey.String(o.Environment))
+	}
+	explicit = append(explicit, o.Attributes...)
+
+	res, err := resource.New(ctx,
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithAttributes(defaults...),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithProcessRuntimeName(),
+		resource.WithProcessRuntimeVersion(),
+		resource.WithFromEnv(),
+		resource.WithAttributes(explicit...),
+	)
+	if err != nil && !errors.Is(err, resource.ErrPartialResource) {
+		return nil, errs.Errorf("failed to build OpenTelemetry resource: %w", err)
+	}
+	return res, nil
+}
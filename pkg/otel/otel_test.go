@@ -0,0 +1,82 @@
+package otel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+func TestSetupExportsOverHTTP(t *testing.T) {
+	var traces, metrics atomic.Int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/traces":
+			traces.Add(1)
+		case "/v1/metrics":
+			metrics.Add(1)
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer collector.Close()
+
+	ctx := context.Background()
+	tel, err := Setup(ctx, &Options{
+		ServiceName: "orders",
+		Environment: "test",
+		Attributes:  []attribute.KeyValue{attribute.String("team", "payments")},
+		Protocol:    ProtocolHTTP,
+		Endpoint:    collector.URL,
+		Insecure:    true,
+		NoGlobal:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs := map[attribute.Key]string{}
+	for _, kv := range tel.Resource.Attributes() {
+		attrs[kv.Key] = kv.Value.Emit()
+	}
+	if attrs[semconv.ServiceNameKey] != "orders" || attrs[semconv.DeploymentEnvironmentNameKey] != "test" || attrs["team"] != "payments" {
+		t.Fatalf("got resource %v", attrs)
+	}
+
+	_, span := tel.TracerProvider.Tracer("test").Start(ctx, "work")
+	span.End()
+	counter, _ := tel.MeterProvider.Meter("test").Int64Counter("jobs")
+	counter.Add(ctx, 1)
+	if err := tel.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if traces.Load() == 0 || metrics.Load() == 0 {
+		t.Fatalf("got %d trace and %d metric exports, want both flushed on shutdown", traces.Load(), metrics.Load())
+	}
+}
+
+func TestSetupWithoutEndpoint(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_TRACES_EXPORTER", "")
+	t.Setenv("OTEL_SERVICE_NAME", "from-env")
+	tel, err := Setup(context.Background(), &Options{NoGlobal: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tel.Shutdown(context.Background())
+	if exporter(&Options{}, "TRACES") != ExporterNone {
+		t.Fatal("expected no exporter without an endpoint")
+	}
+	name, _ := tel.Resource.Set().Value(semconv.ServiceNameKey)
+	if name.AsString() != "from-env" {
+		t.Fatalf("got service.name %q, want OTEL_SERVICE_NAME", name.AsString())
+	}
+
+	t.Setenv("OTEL_SDK_DISABLED", "true")
+	tel, _ = Setup(context.Background(), &Options{NoGlobal: true})
+	if tel.Resource != nil {
+		t.Fatal("expected no-op providers when the SDK is disabled")
+	}
+}
@@ -0,0 +1,250 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/bdlilley/easygo/pkg/clock"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// DynamoDBAPI is the subset of the DynamoDB client used by DynamoDBStore
+type DynamoDBAPI interface {
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+}
+
+// dynamoPending is the value of the pending attribute, the due index's
+// partition key
+const dynamoPending = "1"
+
+type DynamoDBStoreOptions struct {
+	// KeyAttribute is the table's partition key, a string (default: id)
+	KeyAttribute string
+	// DueIndex is a global secondary index with partition key pending (S)
+	// and sort key due (N), projecting all attributes (default: outbox-due).
+	// Only undelivered messages have these attributes, so the index stays
+	// small.
+	DueIndex string
+	// Retention sets the ttl attribute of delivered messages this far ahead;
+	// enable the table's TTL on it to remove them (default: 7 days)
+	Retention time.Duration
+	// Clock decides which messages are due (default: clock.Real)
+	Clock clock.Clock
+}
+
+// DynamoDBStore is a Store in a DynamoDB table. Messages are written with
+// the caller's TransactWriteItems request using TransactItems, and claimed
+// with conditional updates, so any number of relays can share the table.
+type DynamoDBStore struct {
+	client DynamoDBAPI
+	table  string
+	opts   DynamoDBStoreOptions
+}
+
+var _ Store = (*DynamoDBStore)(nil)
+
+// NewDynamoDBStore creates a store for items in table, e.g. with
+// EGAwsClient.GetDynamoDBClient(); opts may be nil
+func NewDynamoDBStore(client DynamoDBAPI, table string, opts *DynamoDBStoreOptions) *DynamoDBStore {
+	o := DynamoDBStoreOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.KeyAttribute == "" {
+		o.KeyAttribute = "id"
+	}
+	if o.DueIndex == "" {
+		o.DueIndex = "outbox-due"
+	}
+	if o.Retention <= 0 {
+		o.Retention = 7 * 24 * time.Hour
+	}
+	if o.Clock == nil {
+		o.Clock = clock.Real()
+	}
+	return &DynamoDBStore{client: client, table: table, opts: o}
+}
+
+// TransactItems returns Put items adding msgs, to include in the
+// TransactWriteItems request that writes the business data, and assigns the
+// messages' IDs
+func (s *DynamoDBStore) TransactItems(msgs ...*Message) ([]types.TransactWriteItem, error) {
+	now := s.opts.Clock.Now()
+	items := make([]types.TransactWriteItem, 0, len(msgs))
+	for _, msg := range msgs {
+		if err := msg.prepare(now); err != nil {
+			return nil, err
+		}
+		item := s.itemKey(msg.ID)
+		item["topic"] = &types.AttributeValueMemberS{Value: msg.Topic}
+		item["payload"] = &types.AttributeValueMemberB{Value: msg.Payload}
+		item["created_at"] = millis(msg.CreatedAt)
+		item["attempts"] = &types.AttributeValueMemberN{Value: "0"}
+		item["claims"] = &types.AttributeValueMemberN{Value: "0"}
+		item["pending"] = &types.AttributeValueMemberS{Value: dynamoPending}
+		item["due"] = millis(now)
+		if msg.Key != "" {
+			item["key"] = &types.AttributeValueMemberS{Value: msg.Key}
+		}
+		if len(msg.Headers) > 0 {
+			headers := make(map[string]types.AttributeValue, len(msg.Headers))
+			for k, v := range msg.Headers {
+				headers[k] = &types.AttributeValueMemberS{Value: v}
+			}
+			item["headers"] = &types.AttributeValueMemberM{Value: headers}
+		}
+		items = append(items, types.TransactWriteItem{Put: &types.Put{TableName: aws.String(s.table), Item: item}})
+	}
+	return items, nil
+}
+
+func (s *DynamoDBStore) itemKey(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{s.opts.KeyAttribute: &types.AttributeValueMemberS{Value: id}}
+}
+
+func millis(t time.Time) *types.AttributeValueMemberN {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(t.UnixMilli(), 10)}
+}
+
+func (s *DynamoDBStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]*Message, error) {
+	now := s.opts.Clock.Now()
+	out, err := s.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:                aws.String(s.table),
+		IndexName:                aws.String(s.opts.DueIndex),
+		KeyConditionExpression:   aws.String("#pending = :pending AND #due <= :now"),
+		ExpressionAttributeNames: map[string]string{"#pending": "pending", "#due": "due"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pending": &types.AttributeValueMemberS{Value: dynamoPending},
+			":now":     millis(now),
+		},
+		Limit: aws.Int32(int32(limit)),
+	})
+	if err != nil {
+		return nil, errs.Errorf("failed to query due outbox messages: %w", err)
+	}
+
+	var msgs []*Message
+	for _, item := range out.Items {
+		key, _ := item[s.opts.KeyAttribute].(*types.AttributeValueMemberS)
+		due, _ := item["due"].(*types.AttributeValueMemberN)
+		if key == nil || due == nil {
+			continue
+		}
+		// the index is eventually consistent, so the claim is conditional on
+		// the table still holding the due time that was read
+		claimed, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:                aws.String(s.table),
+			Key:                      s.itemKey(key.Value),
+			UpdateExpression:         aws.String("SET #due = :lease, claims = claims + :one"),
+			ConditionExpression:      aws.String("#due = :due"),
+			ExpressionAttributeNames: map[string]string{"#due": "due"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":lease": millis(now.Add(lease)),
+				":due":   due,
+				":one":   &types.AttributeValueMemberN{Value: "1"},
+			},
+			ReturnValues: types.ReturnValueAllNew,
+		})
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			continue
+		}
+		if err != nil {
+			return msgs, errs.Errorf("failed to claim outbox message %s: %w", key.Value, err)
+		}
+		msgs = append(msgs, s.decode(claimed.Attributes))
+	}
+	return msgs, nil
+}
+
+func (s *DynamoDBStore) decode(item map[string]types.AttributeValue) *Message {
+	msg := &Message{}
+	str := func(name string) string {
+		if v, ok := item[name].(*types.AttributeValueMemberS); ok {
+			return v.Value
+		}
+		return ""
+	}
+	num := func(name string) int64 {
+		if v, ok := item[name].(*types.AttributeValueMemberN); ok {
+			n, _ := strconv.ParseInt(v.Value, 10, 64)
+			return n
+		}
+		return 0
+	}
+	msg.ID = str(s.opts.KeyAttribute)
+	msg.Topic = str("topic")
+	msg.Key = str("key")
+	if v, ok := item["payload"].(*types.AttributeValueMemberB); ok {
+		msg.Payload = v.Value
+	}
+	if v, ok := item["headers"].(*types.AttributeValueMemberM); ok {
+		msg.Headers = make(map[string]string, len(v.Value))
+		for k, hv := range v.Value {
+			if hs, ok := hv.(*types.AttributeValueMemberS); ok {
+				msg.Headers[k] = hs.Value
+			}
+		}
+	}
+	msg.CreatedAt = time.UnixMilli(num("created_at"))
+	msg.Attempts = int(num("attempts"))
+	msg.claim = strconv.FormatInt(num("claims"), 10)
+	return msg
+}
+
+func (s *DynamoDBStore) Delivered(ctx context.Context, msg *Message) error {
+	now := s.opts.Clock.Now()
+	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                aws.String(s.table),
+		Key:                      s.itemKey(msg.ID),
+		UpdateExpression:         aws.String("SET delivered_at = :now, #ttl = :ttl REMOVE #pending, #due"),
+		ConditionExpression:      aws.String("attribute_exists(#key)"),
+		ExpressionAttributeNames: map[string]string{"#key": s.opts.KeyAttribute, "#pending": "pending", "#due": "due", "#ttl": "ttl"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": millis(now),
+			":ttl": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(s.opts.Retention).Unix(), 10)},
+		},
+	})
+	var condErr *types.ConditionalCheckFailedException
+	if err != nil && !errors.As(err, &condErr) {
+		return errs.Errorf("failed to mark outbox message %s delivered: %w", msg.ID, err)
+	}
+	return nil
+}
+
+func (s *DynamoDBStore) Failed(ctx context.Context, msg *Message, retryAt time.Time, cause error) error {
+	names := map[string]string{"#pending": "pending", "#due": "due"}
+	values := map[string]types.AttributeValue{
+		":claims": &types.AttributeValueMemberN{Value: msg.claim},
+		":error":  &types.AttributeValueMemberS{Value: cause.Error()},
+		":one":    &types.AttributeValueMemberN{Value: "1"},
+	}
+	update := "SET attempts = attempts + :one, last_error = :error"
+	if retryAt.IsZero() {
+		values[":now"] = millis(s.opts.Clock.Now())
+		update += ", failed_at = :now REMOVE #pending, #due"
+	} else {
+		values[":due"] = millis(retryAt)
+		update += ", #due = :due"
+	}
+	// the claims condition skips messages another relay has claimed since
+	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(s.table),
+		Key:                       s.itemKey(msg.ID),
+		UpdateExpression:          aws.String(update),
+		ConditionExpression:       aws.String("claims = :claims AND attribute_exists(#pending)"),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	var condErr *types.ConditionalCheckFailedException
+	if err != nil && !errors.As(err, &condErr) {
+		return errs.Errorf("failed to record failure of outbox message %s: %w", msg.ID, err)
+	}
+	return nil
+}
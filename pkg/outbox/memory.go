@@ -0,0 +1,138 @@
+package outbox
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/clock"
+)
+
+type MemoryStoreOptions struct {
+	// Clock decides which messages are due (default: clock.Real)
+	Clock clock.Clock
+}
+
+// MemoryStore is an in-process Store for tests and local development
+type MemoryStore struct {
+	clock clock.Clock
+
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	claims  int
+}
+
+type memoryEntry struct {
+	msg       Message
+	due       time.Time
+	delivered bool
+	dead      bool
+	lastError string
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty store; opts may be nil
+func NewMemoryStore(opts *MemoryStoreOptions) *MemoryStore {
+	s := &MemoryStore{clock: clock.Real(), entries: map[string]*memoryEntry{}}
+	if opts != nil && opts.Clock != nil {
+		s.clock = opts.Clock
+	}
+	return s
+}
+
+// Add stores msgs, assigning their IDs. Adding an ID that exists is a no-op,
+// so retried writes do not duplicate messages.
+func (s *MemoryStore) Add(_ context.Context, msgs ...*Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now()
+	for _, msg := range msgs {
+		if err := msg.prepare(now); err != nil {
+			return err
+		}
+		if _, ok := s.entries[msg.ID]; !ok {
+			s.entries[msg.ID] = &memoryEntry{msg: *msg, due: now}
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Claim(_ context.Context, limit int, lease time.Duration) ([]*Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now()
+	var due []*memoryEntry
+	for _, e := range s.entries {
+		if !e.delivered && !e.dead && !e.due.After(now) {
+			due = append(due, e)
+		}
+	}
+	slices.SortFunc(due, func(a, b *memoryEntry) int { return a.msg.CreatedAt.Compare(b.msg.CreatedAt) })
+
+	msgs := make([]*Message, 0, min(limit, len(due)))
+	for _, e := range due[:min(limit, len(due))] {
+		s.claims++
+		e.due = now.Add(lease)
+		e.msg.claim = strconv.Itoa(s.claims)
+		msg := e.msg
+		msgs = append(msgs, &msg)
+	}
+	return msgs, nil
+}
+
+func (s *MemoryStore) Delivered(_ context.Context, msg *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[msg.ID]; ok {
+		e.delivered = true
+	}
+	return nil
+}
+
+func (s *MemoryStore) Failed(_ context.Context, msg *Message, retryAt time.Time, cause error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[msg.ID]
+	if !ok || e.delivered || e.msg.claim != msg.claim {
+		// another relay claimed the message after the lease passed
+		return nil
+	}
+	e.msg.Attempts++
+	e.lastError = cause.Error()
+	if retryAt.IsZero() {
+		e.dead = true
+	} else {
+		e.due = retryAt
+	}
+	return nil
+}
+
+// Pending returns the number of messages not yet delivered or given up on
+func (s *MemoryStore) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, e := range s.entries {
+		if !e.delivered && !e.dead {
+			n++
+		}
+	}
+	return n
+}
+
+// Dead returns the messages given up on
+func (s *MemoryStore) Dead() []*Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var dead []*Message
+	for _, e := range s.entries {
+		if e.dead {
+			msg := e.msg
+			dead = append(dead, &msg)
+		}
+	}
+	return dead
+}
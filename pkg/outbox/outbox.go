@@ -0,0 +1,287 @@
+// Package outbox implements the transactional outbox pattern: events are
+// written to an outbox table in the same database transaction as the
+// business data they describe, and a Relay publishes them to SNS, SQS or
+// EventBridge afterwards, so an event is published if and only if the
+// transaction committed.
+//
+//	tx, _ := pool.Begin(ctx)
+//	_, _ = tx.Exec(ctx, "INSERT INTO orders ...")
+//	msg, _ := outbox.NewMessage("orders.created", order)
+//	_ = store.Add(ctx, tx, msg)
+//	_ = tx.Commit(ctx)
+//
+//	relay, _ := outbox.NewRelay(&outbox.NewRelayArgs{Store: store, Publisher: outbox.QueuePublisher(q)})
+//	go relay.Run(ctx)
+//
+// Delivery is at least once: a relay that crashes after publishing but
+// before recording the delivery publishes the message again. Every message
+// carries its ID as an idempotency key (the outbox-id attribute, and the
+// deduplication ID on FIFO destinations) so consumers can drop duplicates,
+// e.g. with Deduplicate.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/clock"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/ids"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/bdlilley/easygo/pkg/retry"
+	"github.com/sirupsen/logrus"
+)
+
+// Attributes set on published messages
+const (
+	// IDAttribute carries Message.ID, the idempotency key
+	IDAttribute = "outbox-id"
+	// TopicAttribute carries Message.Topic
+	TopicAttribute = "outbox-topic"
+)
+
+// Message is an event waiting in the outbox
+type Message struct {
+	// ID identifies the message and is its idempotency key (default: a
+	// prefixed ULID assigned when the message is added)
+	ID string
+	// Topic names the event, e.g. orders.created; Router routes on it and
+	// EventBridgePublisher uses it as the detail type
+	Topic string
+	// Key orders and deduplicates messages on FIFO topics and queues, where
+	// it is the message group ID; leave it empty for standard destinations
+	Key     string
+	Payload []byte
+	// Headers are published as message attributes
+	Headers   map[string]string
+	CreatedAt time.Time
+	// Attempts is the number of failed publish attempts so far
+	Attempts int
+
+	// claim identifies the store's lease on the message
+	claim string
+}
+
+// NewMessage returns a message with v marshaled to JSON as its payload
+func NewMessage(topic string, v any) (*Message, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, errs.Errorf("failed to marshal %s payload: %w", topic, err)
+	}
+	return &Message{Topic: topic, Payload: b}, nil
+}
+
+// prepare assigns the defaults of a message being added at now
+func (m *Message) prepare(now time.Time) error {
+	if m.Topic == "" {
+		return errs.New(errs.Internal, "outbox message Topic is required")
+	}
+	if m.ID == "" {
+		m.ID = ids.Prefixed("obx")
+	}
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = now
+	}
+	return nil
+}
+
+// Store holds outbox messages for a Relay. Stores also provide a way to add
+// messages inside the caller's transaction, which is not part of Store since
+// it depends on the database.
+type Store interface {
+	// Claim leases up to limit messages that are due, oldest first. Claimed
+	// messages are not claimed again until lease has passed.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*Message, error)
+	// Delivered records that msg was published
+	Delivered(ctx context.Context, msg *Message) error
+	// Failed records a failed publish of msg, to be retried at retryAt; a
+	// zero retryAt gives up on the message
+	Failed(ctx context.Context, msg *Message, retryAt time.Time, cause error) error
+}
+
+// Publisher publishes a message to its destination
+type Publisher interface {
+	Publish(ctx context.Context, msg *Message) error
+}
+
+// PublisherFunc adapts a function to a Publisher
+type PublisherFunc func(ctx context.Context, msg *Message) error
+
+func (f PublisherFunc) Publish(ctx context.Context, msg *Message) error {
+	return f(ctx, msg)
+}
+
+// Router publishes each message with the Publisher for its topic, or
+// fallback when no route matches; a nil fallback fails unrouted messages
+func Router(routes map[string]Publisher, fallback Publisher) Publisher {
+	return PublisherFunc(func(ctx context.Context, msg *Message) error {
+		if p, ok := routes[msg.Topic]; ok {
+			return p.Publish(ctx, msg)
+		}
+		if fallback != nil {
+			return fallback.Publish(ctx, msg)
+		}
+		return retry.Permanent(errs.Newf(errs.Internal, "no publisher for topic %s", msg.Topic))
+	})
+}
+
+// attributes returns the message attributes published with msg
+func (m *Message) attributes() map[string]string {
+	attrs := make(map[string]string, len(m.Headers)+2)
+	for k, v := range m.Headers {
+		attrs[k] = v
+	}
+	attrs[IDAttribute] = m.ID
+	attrs[TopicAttribute] = m.Topic
+	return attrs
+}
+
+type NewRelayArgs struct {
+	Store     Store
+	Publisher Publisher
+	// BatchSize is the number of messages claimed at once (default: 100)
+	BatchSize int
+	// Concurrency is the number of messages published at once (default: 10)
+	Concurrency int
+	// PollInterval is the wait between polls that find fewer than BatchSize
+	// messages (default: 1s)
+	PollInterval time.Duration
+	// Lease is how long claimed messages are hidden from other relays; it
+	// must exceed the time to publish a batch (default: 30s)
+	Lease time.Duration
+	// MaxAttempts gives up on a message after this many failed publishes
+	// (default: 10)
+	MaxAttempts int
+	// Backoff spaces retries of a failing message (default: 1s to 5m)
+	Backoff retry.Backoff
+	// Logger logs failed publishes (default: logging.Noop)
+	Logger logging.Logger
+	// Clock times polls and retries (default: clock.Real)
+	Clock clock.Clock
+}
+
+// Relay publishes messages from a Store
+type Relay struct {
+	args NewRelayArgs
+}
+
+// NewRelay creates a relay; call Run to start it
+func NewRelay(args *NewRelayArgs) (*Relay, error) {
+	if args == nil || args.Store == nil || args.Publisher == nil {
+		return nil, errs.New(errs.Internal, "Store and Publisher are required")
+	}
+	a := *args
+	if a.BatchSize <= 0 {
+		a.BatchSize = 100
+	}
+	if a.Concurrency <= 0 {
+		a.Concurrency = 10
+	}
+	if a.PollInterval <= 0 {
+		a.PollInterval = time.Second
+	}
+	if a.Lease <= 0 {
+		a.Lease = 30 * time.Second
+	}
+	if a.MaxAttempts <= 0 {
+		a.MaxAttempts = 10
+	}
+	if a.Backoff.Initial <= 0 {
+		a.Backoff.Initial = time.Second
+	}
+	if a.Backoff.Max <= 0 {
+		a.Backoff.Max = 5 * time.Minute
+	}
+	if a.Logger == nil {
+		a.Logger = logging.Noop()
+	}
+	if a.Clock == nil {
+		a.Clock = clock.Real()
+	}
+	return &Relay{args: a}, nil
+}
+
+// Run relays messages until ctx is done, polling again right away while
+// batches come back full. Store errors are logged and retried after
+// PollInterval.
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		n, err := r.RelayOnce(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			r.args.Logger.WithError(err).Error("failed to relay outbox messages")
+		}
+		if err == nil && n == r.args.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-r.args.Clock.After(r.args.PollInterval):
+		}
+	}
+}
+
+// RelayOnce claims one batch and publishes it, returning the number of
+// messages claimed. It suits scheduled jobs and tests.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	msgs, err := r.args.Store.Claim(ctx, r.args.BatchSize, r.args.Lease)
+	if err != nil {
+		return 0, errs.Errorf("failed to claim outbox messages: %w", err)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures []error
+	)
+	sem := make(chan struct{}, r.args.Concurrency)
+	for _, msg := range msgs {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := r.relay(ctx, msg); err != nil {
+				mu.Lock()
+				failures = append(failures, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(failures) > 0 {
+		return len(msgs), errs.Errorf("failed to record %d outbox deliveries: %w", len(failures), failures[0])
+	}
+	return len(msgs), nil
+}
+
+// relay publishes msg and records the outcome, returning store errors
+func (r *Relay) relay(ctx context.Context, msg *Message) error {
+	err := r.args.Publisher.Publish(ctx, msg)
+	if err == nil {
+		return r.args.Store.Delivered(ctx, msg)
+	}
+
+	attempts := msg.Attempts + 1
+	log := logging.WithTrace(ctx, r.args.Logger).WithFields(logrus.Fields{
+		"outbox_id": msg.ID,
+		"topic":     msg.Topic,
+		"attempt":   attempts,
+		"error":     err.Error(),
+	})
+	var retryAt time.Time
+	if attempts < r.args.MaxAttempts && !retry.IsPermanent(err) {
+		retryAt = r.args.Clock.Now().Add(r.args.Backoff.Delay(attempts - 1))
+		log.Warn("failed to publish outbox message")
+	} else {
+		log.Error("giving up on outbox message")
+	}
+	return r.args.Store.Failed(ctx, msg, retryAt, err)
+}
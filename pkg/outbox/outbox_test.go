@@ -0,0 +1,446 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/bdlilley/easygo/pkg/clock"
+	"github.com/bdlilley/easygo/pkg/idempotency"
+	"github.com/bdlilley/easygo/pkg/pg"
+	"github.com/bdlilley/easygo/pkg/pg/pgtest"
+	"github.com/bdlilley/easygo/pkg/queue"
+	"github.com/bdlilley/easygo/pkg/retry"
+	"github.com/jackc/pgx/v5"
+)
+
+func TestRelay(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Unix(1700000000, 0))
+	store := NewMemoryStore(&MemoryStoreOptions{Clock: clk})
+
+	var mu sync.Mutex
+	published := map[string]int{}
+	flaky := 0
+	pub := PublisherFunc(func(ctx context.Context, msg *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		published[msg.Topic]++
+		switch msg.Topic {
+		case "flaky":
+			flaky++
+			if flaky < 3 {
+				return errors.New("unavailable")
+			}
+		case "poison":
+			return retry.Permanent(errors.New("rejected"))
+		}
+		return nil
+	})
+	relay, err := NewRelay(&NewRelayArgs{
+		Store:       store,
+		Publisher:   pub,
+		MaxAttempts: 5,
+		Backoff:     retry.Backoff{Initial: time.Second, Max: time.Minute, NoJitter: true},
+		Clock:       clk,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, topic := range []string{"ok", "flaky", "poison"} {
+		msg, err := NewMessage(topic, map[string]string{"topic": topic})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Add(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(msg.ID, "obx_") {
+			t.Fatalf("ID = %q", msg.ID)
+		}
+		// adding the same message again is a no-op
+		if err := store.Add(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Add(ctx, &Message{}); err == nil {
+		t.Fatal("expected an error for a message without a topic")
+	}
+
+	if n, err := relay.RelayOnce(ctx); err != nil || n != 3 {
+		t.Fatalf("RelayOnce = %d, %v", n, err)
+	}
+	if store.Pending() != 1 || len(store.Dead()) != 1 || store.Dead()[0].Topic != "poison" {
+		t.Fatalf("pending = %d, dead = %v", store.Pending(), store.Dead())
+	}
+
+	// the flaky message waits for its backoff
+	if n, _ := relay.RelayOnce(ctx); n != 0 {
+		t.Fatalf("claimed %d messages before the backoff", n)
+	}
+	clk.Advance(time.Second)
+	if n, _ := relay.RelayOnce(ctx); n != 1 {
+		t.Fatalf("claimed %d messages after the first backoff", n)
+	}
+	clk.Advance(time.Second)
+	if n, _ := relay.RelayOnce(ctx); n != 0 {
+		t.Fatal("second backoff should double")
+	}
+	clk.Advance(time.Second)
+	if n, _ := relay.RelayOnce(ctx); n != 1 {
+		t.Fatalf("claimed %d messages after the second backoff", n)
+	}
+	if store.Pending() != 0 {
+		t.Fatalf("pending = %d", store.Pending())
+	}
+	if published["ok"] != 1 || published["flaky"] != 3 || published["poison"] != 1 {
+		t.Fatalf("published = %v", published)
+	}
+}
+
+func TestMemoryStoreLease(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Unix(1700000000, 0))
+	store := NewMemoryStore(&MemoryStoreOptions{Clock: clk})
+	if err := store.Add(ctx, &Message{Topic: "t", Payload: []byte("{}")}); err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := store.Claim(ctx, 10, time.Minute)
+	if len(first) != 1 {
+		t.Fatalf("claimed %d", len(first))
+	}
+	if again, _ := store.Claim(ctx, 10, time.Minute); len(again) != 0 {
+		t.Fatal("claimed a leased message")
+	}
+	clk.Advance(time.Minute)
+	second, _ := store.Claim(ctx, 10, time.Minute)
+	if len(second) != 1 {
+		t.Fatal("lease did not expire")
+	}
+
+	// the stale claim's failure is ignored
+	_ = store.Failed(ctx, first[0], time.Time{}, errors.New("late"))
+	if len(store.Dead()) != 0 {
+		t.Fatal("stale claim marked the message dead")
+	}
+	_ = store.Delivered(ctx, second[0])
+	if store.Pending() != 0 {
+		t.Fatal("message still pending")
+	}
+}
+
+func TestRun(t *testing.T) {
+	clk := clock.NewFake(time.Unix(1700000000, 0))
+	store := NewMemoryStore(&MemoryStoreOptions{Clock: clk})
+	delivered := make(chan string, 10)
+	relay, _ := NewRelay(&NewRelayArgs{
+		Store: store,
+		Publisher: PublisherFunc(func(ctx context.Context, msg *Message) error {
+			delivered <- msg.Topic
+			return nil
+		}),
+		Clock: clk,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- relay.Run(ctx) }()
+
+	clk.BlockUntil(1)
+	_ = store.Add(ctx, &Message{Topic: "later", Payload: []byte("{}")})
+	clk.Advance(time.Second)
+	if got := <-delivered; got != "later" {
+		t.Fatalf("delivered %q", got)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run = %v", err)
+	}
+}
+
+func TestRouter(t *testing.T) {
+	var got []string
+	route := func(name string) Publisher {
+		return PublisherFunc(func(ctx context.Context, msg *Message) error {
+			got = append(got, name+":"+msg.Topic)
+			return nil
+		})
+	}
+	ctx := context.Background()
+
+	r := Router(map[string]Publisher{"orders.created": route("sns")}, nil)
+	_ = r.Publish(ctx, &Message{Topic: "orders.created"})
+	if err := r.Publish(ctx, &Message{Topic: "other"}); !retry.IsPermanent(err) {
+		t.Fatalf("unrouted error = %v", err)
+	}
+
+	r = Router(map[string]Publisher{"orders.created": route("sns")}, route("bus"))
+	_ = r.Publish(ctx, &Message{Topic: "other"})
+	if strings.Join(got, ",") != "sns:orders.created,bus:other" {
+		t.Fatalf("got %v", got)
+	}
+}
+
+type captureQueue struct {
+	queue.Queue
+	msgs []*queue.Message
+}
+
+func (q *captureQueue) Publish(_ context.Context, msg *queue.Message) error {
+	q.msgs = append(q.msgs, msg)
+	return nil
+}
+
+func TestQueuePublisher(t *testing.T) {
+	q := &captureQueue{}
+	pub := QueuePublisher(q)
+	ctx := context.Background()
+	_ = pub.Publish(ctx, &Message{ID: "obx_1", Topic: "t", Payload: []byte("a"), Headers: map[string]string{"tenant": "x"}})
+	_ = pub.Publish(ctx, &Message{ID: "obx_2", Topic: "t", Key: "order-1", Payload: []byte("b")})
+
+	first, second := q.msgs[0], q.msgs[1]
+	if first.Attributes[IDAttribute] != "obx_1" || first.Attributes[TopicAttribute] != "t" || first.Attributes["tenant"] != "x" {
+		t.Fatalf("attributes = %v", first.Attributes)
+	}
+	if first.GroupID != "" || second.GroupID != "order-1" || second.DeduplicationID != "obx_2" {
+		t.Fatalf("FIFO fields = %+v, %+v", first, second)
+	}
+}
+
+func TestDeduplicate(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	fail := true
+	h := Deduplicate(func(ctx context.Context, msg *queue.Message) error {
+		calls++
+		if fail {
+			return errors.New("failed")
+		}
+		return nil
+	}, idempotency.NewMemoryStore(), nil)
+
+	msg := &queue.Message{Attributes: map[string]string{IDAttribute: "obx_1"}}
+	if err := h(ctx, msg); err == nil {
+		t.Fatal("expected the handler error")
+	}
+	// a failure releases the ID so the redelivery runs
+	fail = false
+	if err := h(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := h(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatalf("calls = %d", calls)
+	}
+
+	_ = h(ctx, &queue.Message{})
+	_ = h(ctx, &queue.Message{})
+	if calls != 4 {
+		t.Fatalf("messages without an ID were deduplicated: calls = %d", calls)
+	}
+}
+
+func TestDeduplicateInProgress(t *testing.T) {
+	ctx := context.Background()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	store := idempotency.NewMemoryStore()
+	h := Deduplicate(func(ctx context.Context, msg *queue.Message) error {
+		close(started)
+		<-release
+		return nil
+	}, store, nil)
+
+	msg := &queue.Message{Attributes: map[string]string{IDAttribute: "obx_1"}}
+	done := make(chan error)
+	go func() { done <- h(ctx, msg) }()
+	<-started
+	if err := h(ctx, msg); err == nil {
+		t.Fatal("expected a retry while the message is being handled")
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+type fakeDynamoDB struct {
+	query   *dynamodb.QueryInput
+	items   []map[string]types.AttributeValue
+	updates []*dynamodb.UpdateItemInput
+	// fail fails updates of these keys with ConditionalCheckFailed
+	fail map[string]bool
+}
+
+func (f *fakeDynamoDB) Query(_ context.Context, in *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	f.query = in
+	return &dynamodb.QueryOutput{Items: f.items}, nil
+}
+
+func (f *fakeDynamoDB) UpdateItem(_ context.Context, in *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	f.updates = append(f.updates, in)
+	id := in.Key["id"].(*types.AttributeValueMemberS).Value
+	if f.fail[id] {
+		return nil, &types.ConditionalCheckFailedException{}
+	}
+	for _, item := range f.items {
+		if item["id"].(*types.AttributeValueMemberS).Value == id {
+			attrs := map[string]types.AttributeValue{}
+			for k, v := range item {
+				attrs[k] = v
+			}
+			attrs["claims"] = &types.AttributeValueMemberN{Value: "3"}
+			return &dynamodb.UpdateItemOutput{Attributes: attrs}, nil
+		}
+	}
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func TestDynamoDBStore(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.UnixMilli(1700000000000))
+	db := &fakeDynamoDB{fail: map[string]bool{"obx_taken": true}}
+	store := NewDynamoDBStore(db, "outbox", &DynamoDBStoreOptions{Clock: clk})
+
+	items, err := store.TransactItems(&Message{ID: "obx_1", Topic: "orders.created", Key: "o1", Payload: []byte(`{"id":"o1"}`), Headers: map[string]string{"tenant": "x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := items[0].Put.Item
+	if aws.ToString(items[0].Put.TableName) != "outbox" || item["pending"].(*types.AttributeValueMemberS).Value != "1" ||
+		item["due"].(*types.AttributeValueMemberN).Value != "1700000000000" {
+		t.Fatalf("item = %v", item)
+	}
+
+	taken := map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "obx_taken"}, "due": item["due"]}
+	db.items = []map[string]types.AttributeValue{taken, item}
+	msgs, err := store.Claim(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aws.ToString(db.query.IndexName) != "outbox-due" || aws.ToInt32(db.query.Limit) != 10 {
+		t.Fatalf("query = %+v", db.query)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("claimed %d messages", len(msgs))
+	}
+	msg := msgs[0]
+	if msg.ID != "obx_1" || msg.Topic != "orders.created" || msg.Key != "o1" || string(msg.Payload) != `{"id":"o1"}` ||
+		msg.Headers["tenant"] != "x" || !msg.CreatedAt.Equal(clk.Now()) || msg.claim != "3" {
+		t.Fatalf("msg = %+v", msg)
+	}
+
+	_ = store.Failed(ctx, msg, clk.Now().Add(time.Minute), errors.New("unavailable"))
+	retried := db.updates[len(db.updates)-1]
+	if strings.Contains(aws.ToString(retried.UpdateExpression), "REMOVE") ||
+		retried.ExpressionAttributeValues[":claims"].(*types.AttributeValueMemberN).Value != "3" {
+		t.Fatalf("retry update = %s", aws.ToString(retried.UpdateExpression))
+	}
+	_ = store.Failed(ctx, msg, time.Time{}, errors.New("rejected"))
+	dead := db.updates[len(db.updates)-1]
+	if !strings.Contains(aws.ToString(dead.UpdateExpression), "REMOVE #pending, #due") {
+		t.Fatalf("dead update = %s", aws.ToString(dead.UpdateExpression))
+	}
+	if err := store.Delivered(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPostgresStore(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Unix(1700000000, 0))
+	pool, err := pg.NewPool(ctx, &pg.NewPoolArgs{
+		ConnString: pgtest.StartPostgres(t, nil),
+		Migrations: fstest.MapFS{"0001_outbox.sql": {Data: []byte(PostgresSchema("outbox"))}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	store := NewPostgresStore(pool, &PostgresStoreOptions{Clock: clk})
+
+	add := func(commit bool, msgs ...*Message) {
+		t.Helper()
+		err := pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+			if err := store.Add(ctx, tx, msgs...); err != nil {
+				return err
+			}
+			if !commit {
+				return errors.New("rolled back")
+			}
+			return nil
+		})
+		if commit && err != nil {
+			t.Fatal(err)
+		}
+	}
+	a := &Message{Topic: "a", Payload: []byte(`{}`), Headers: map[string]string{"tenant": "x"}}
+	b := &Message{Topic: "b", Payload: []byte(`{}`), CreatedAt: clk.Now().Add(time.Second)}
+	add(true, a, b)
+	add(true, a)
+	add(false, &Message{Topic: "rolled-back", Payload: []byte(`{}`)})
+
+	claimed, err := store.Claim(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claimed) != 2 || claimed[0].ID != a.ID || claimed[0].Headers["tenant"] != "x" || claimed[1].ID != b.ID {
+		t.Fatalf("claimed %+v", claimed)
+	}
+	if again, _ := store.Claim(ctx, 10, time.Minute); len(again) != 0 {
+		t.Fatalf("claimed %d leased messages", len(again))
+	}
+
+	if err := store.Delivered(ctx, claimed[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Failed(ctx, claimed[1], clk.Now().Add(30*time.Second), errors.New("unavailable")); err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(30 * time.Second)
+	retried, err := store.Claim(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(retried) != 1 || retried[0].ID != b.ID || retried[0].Attempts != 1 {
+		t.Fatalf("retried %+v", retried)
+	}
+
+	// a relay whose lease was taken over does not record its failure
+	if err := store.Failed(ctx, claimed[1], clk.Now(), errors.New("stale")); err != nil {
+		t.Fatal(err)
+	}
+	// a zero retryAt gives up on the message
+	if err := store.Failed(ctx, retried[0], time.Time{}, errors.New("rejected")); err != nil {
+		t.Fatal(err)
+	}
+	var attempts int
+	var lastError string
+	var failed bool
+	if err := pool.QueryRow(ctx, "SELECT attempts, last_error, failed_at IS NOT NULL FROM outbox WHERE id = $1", b.ID).Scan(&attempts, &lastError, &failed); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 || lastError != "rejected" || !failed {
+		t.Fatalf("attempts = %d, last_error = %q, failed = %v", attempts, lastError, failed)
+	}
+	clk.Advance(time.Hour)
+	if again, _ := store.Claim(ctx, 10, time.Minute); len(again) != 0 {
+		t.Fatalf("claimed %d delivered or failed messages", len(again))
+	}
+
+	if n, err := store.Prune(ctx, time.Minute); err != nil || n != 1 {
+		t.Fatalf("Prune = %d, %v", n, err)
+	}
+}
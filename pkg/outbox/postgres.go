@@ -0,0 +1,186 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/clock"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// PostgresExecer runs statements; pgx.Tx, *pgx.Conn and *pgxpool.Pool
+// implement it
+type PostgresExecer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// PostgresDB is the part of *pgxpool.Pool (or pg.Pool) used by PostgresStore
+type PostgresDB interface {
+	PostgresExecer
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// PostgresSchema returns the statements creating the outbox table, for a
+// migration. The partial index keeps polling cheap as delivered rows
+// accumulate.
+func PostgresSchema(table string) string {
+	t := quoteTable(table)
+	index := pgx.Identifier{strings.ReplaceAll(table, ".", "_") + "_due_idx"}.Sanitize()
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s (
+	id           text PRIMARY KEY,
+	topic        text NOT NULL,
+	key          text NOT NULL DEFAULT '',
+	payload      bytea NOT NULL,
+	headers      jsonb NOT NULL DEFAULT '{}',
+	created_at   timestamptz NOT NULL,
+	attempts     integer NOT NULL DEFAULT 0,
+	claims       integer NOT NULL DEFAULT 0,
+	due_at       timestamptz,
+	delivered_at timestamptz,
+	failed_at    timestamptz,
+	last_error   text
+);
+CREATE INDEX IF NOT EXISTS %[2]s ON %[1]s (due_at) WHERE due_at IS NOT NULL;
+`, t, index)
+}
+
+type PostgresStoreOptions struct {
+	// Table is the outbox table, optionally schema qualified (default: outbox)
+	Table string
+	// Clock decides which messages are due (default: clock.Real)
+	Clock clock.Clock
+}
+
+// PostgresStore is a Store in a Postgres table created with PostgresSchema.
+// Claims use FOR UPDATE SKIP LOCKED, so any number of relays can share the
+// table.
+type PostgresStore struct {
+	db    PostgresDB
+	table string
+	clock clock.Clock
+}
+
+var _ Store = (*PostgresStore)(nil)
+
+// NewPostgresStore creates a store; opts may be nil
+func NewPostgresStore(db PostgresDB, opts *PostgresStoreOptions) *PostgresStore {
+	o := PostgresStoreOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Table == "" {
+		o.Table = "outbox"
+	}
+	if o.Clock == nil {
+		o.Clock = clock.Real()
+	}
+	return &PostgresStore{db: db, table: quoteTable(o.Table), clock: o.Clock}
+}
+
+// quoteTable quotes a possibly schema qualified table name
+func quoteTable(table string) string {
+	return pgx.Identifier(strings.Split(table, ".")).Sanitize()
+}
+
+// Add inserts msgs with tx, the transaction writing the business data, and
+// assigns their IDs. Messages whose ID exists are skipped, so retried
+// transactions do not duplicate them.
+func (s *PostgresStore) Add(ctx context.Context, tx PostgresExecer, msgs ...*Message) error {
+	now := s.clock.Now()
+	for _, msg := range msgs {
+		if err := msg.prepare(now); err != nil {
+			return err
+		}
+		headers, err := json.Marshal(msg.Headers)
+		if err != nil {
+			return errs.Errorf("failed to marshal headers of outbox message %s: %w", msg.ID, err)
+		}
+		if msg.Headers == nil {
+			headers = []byte("{}")
+		}
+		_, err = tx.Exec(ctx, `INSERT INTO `+s.table+` (id, topic, key, payload, headers, created_at, due_at)
+VALUES ($1, $2, $3, $4, $5, $6, $6) ON CONFLICT (id) DO NOTHING`,
+			msg.ID, msg.Topic, msg.Key, msg.Payload, headers, msg.CreatedAt)
+		if err != nil {
+			return errs.Errorf("failed to add outbox message %s: %w", msg.ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *PostgresStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]*Message, error) {
+	now := s.clock.Now()
+	rows, err := s.db.Query(ctx, `UPDATE `+s.table+` SET due_at = $2, claims = claims + 1
+WHERE id IN (
+	SELECT id FROM `+s.table+` WHERE due_at <= $1 ORDER BY created_at LIMIT $3 FOR UPDATE SKIP LOCKED
+)
+RETURNING id, topic, key, payload, headers, created_at, attempts, claims`, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, errs.Errorf("failed to claim outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var msgs []*Message
+	for rows.Next() {
+		msg := &Message{}
+		var headers []byte
+		var claims int
+		if err := rows.Scan(&msg.ID, &msg.Topic, &msg.Key, &msg.Payload, &headers, &msg.CreatedAt, &msg.Attempts, &claims); err != nil {
+			return nil, errs.Errorf("failed to scan outbox message: %w", err)
+		}
+		if err := json.Unmarshal(headers, &msg.Headers); err != nil {
+			return nil, errs.Errorf("invalid headers on outbox message %s: %w", msg.ID, err)
+		}
+		msg.claim = strconv.Itoa(claims)
+		msgs = append(msgs, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errs.Errorf("failed to claim outbox messages: %w", err)
+	}
+	// RETURNING does not keep the subquery's order
+	slices.SortFunc(msgs, func(a, b *Message) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return msgs, nil
+}
+
+func (s *PostgresStore) Delivered(ctx context.Context, msg *Message) error {
+	_, err := s.db.Exec(ctx, `UPDATE `+s.table+` SET due_at = NULL, delivered_at = $2 WHERE id = $1`, msg.ID, s.clock.Now())
+	if err != nil {
+		return errs.Errorf("failed to mark outbox message %s delivered: %w", msg.ID, err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) Failed(ctx context.Context, msg *Message, retryAt time.Time, cause error) error {
+	var due, failed *time.Time
+	if retryAt.IsZero() {
+		now := s.clock.Now()
+		failed = &now
+	} else {
+		due = &retryAt
+	}
+	claims, _ := strconv.Atoi(msg.claim)
+	// the claims condition skips messages another relay has claimed since
+	_, err := s.db.Exec(ctx, `UPDATE `+s.table+` SET attempts = attempts + 1, last_error = $3, due_at = $4, failed_at = $5
+WHERE id = $1 AND claims = $2 AND delivered_at IS NULL`, msg.ID, claims, cause.Error(), due, failed)
+	if err != nil {
+		return errs.Errorf("failed to record failure of outbox message %s: %w", msg.ID, err)
+	}
+	return nil
+}
+
+// Prune deletes messages delivered more than olderThan ago, returning the
+// number deleted; run it periodically, e.g. from a scheduler job
+func (s *PostgresStore) Prune(ctx context.Context, olderThan time.Duration) (int64, error) {
+	tag, err := s.db.Exec(ctx, `DELETE FROM `+s.table+` WHERE delivered_at < $1`, s.clock.Now().Add(-olderThan))
+	if err != nil {
+		return 0, errs.Errorf("failed to prune outbox messages: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
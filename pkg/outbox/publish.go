@@ -0,0 +1,145 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/bdlilley/easygo"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/idempotency"
+	"github.com/bdlilley/easygo/pkg/queue"
+)
+
+// SNSPublishAPI is the subset of the SNS client used by SNSPublisher
+type SNSPublishAPI interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+// SNSPublisher publishes messages to topicArn, e.g. with
+// EGAwsClient.GetSNSClient(). Messages with a Key are published with it as
+// the message group ID and their ID as the deduplication ID, as FIFO topics
+// require.
+func SNSPublisher(client SNSPublishAPI, topicArn string) Publisher {
+	return PublisherFunc(func(ctx context.Context, msg *Message) error {
+		attrs := map[string]types.MessageAttributeValue{}
+		for k, v := range msg.attributes() {
+			attrs[k] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(v)}
+		}
+		input := &sns.PublishInput{
+			TopicArn:          aws.String(topicArn),
+			Message:           aws.String(string(msg.Payload)),
+			MessageAttributes: attrs,
+		}
+		if msg.Key != "" {
+			input.MessageGroupId = aws.String(msg.Key)
+			input.MessageDeduplicationId = aws.String(msg.ID)
+		}
+		if _, err := client.Publish(ctx, input); err != nil {
+			return errs.Errorf("failed to publish outbox message %s to %s: %w", msg.ID, topicArn, err)
+		}
+		return nil
+	})
+}
+
+// QueuePublisher publishes messages to q, e.g. a queue.SQSQueue. Messages
+// with a Key are published with it as the group ID and their ID as the
+// deduplication ID.
+func QueuePublisher(q queue.Queue) Publisher {
+	return PublisherFunc(func(ctx context.Context, msg *Message) error {
+		qm := &queue.Message{Body: msg.Payload, Attributes: msg.attributes()}
+		if msg.Key != "" {
+			qm.GroupID = msg.Key
+			qm.DeduplicationID = msg.ID
+		}
+		if err := q.Publish(ctx, qm); err != nil {
+			return errs.Errorf("failed to publish outbox message %s: %w", msg.ID, err)
+		}
+		return nil
+	})
+}
+
+// EventBridgePublisher puts messages on busName as events from source, with
+// the topic as the detail type and the JSON payload as the detail, e.g. with
+// an EGAwsClient. EventBridge events carry no attributes, so include the
+// message ID in the payload when consumers need to deduplicate.
+func EventBridgePublisher(client easygo.EventPublisher, busName, source string) Publisher {
+	return PublisherFunc(func(ctx context.Context, msg *Message) error {
+		if !json.Valid(msg.Payload) {
+			return errs.Newf(errs.Internal, "outbox message %s payload is not JSON", msg.ID)
+		}
+		_, err := client.PutEvents(ctx, []easygo.EventBridgeEvent{{
+			BusName:    busName,
+			Source:     source,
+			DetailType: msg.Topic,
+			Detail:     json.RawMessage(msg.Payload),
+		}})
+		if err != nil {
+			return errs.Errorf("failed to put outbox message %s: %w", msg.ID, err)
+		}
+		return nil
+	})
+}
+
+type DeduplicateOptions struct {
+	// TTL is how long handled message IDs are remembered; it must exceed the
+	// time a duplicate can arrive (default: 24h)
+	TTL time.Duration
+	// LockTTL is how long a message being handled holds its ID, after which
+	// the handler is assumed lost and a duplicate runs again (default: 1m)
+	LockTTL time.Duration
+	// RetryDelay delays duplicates that arrive while the message is still
+	// being handled (default: 10s)
+	RetryDelay time.Duration
+}
+
+// Deduplicate returns a queue handler that runs handler once per outbox
+// message ID, using store to remember handled IDs across consumers, e.g. an
+// idempotency.DynamoDBStore. Messages without the outbox-id attribute are
+// always handled. opts may be nil.
+func Deduplicate(handler queue.Handler, store idempotency.Store, opts *DeduplicateOptions) queue.Handler {
+	o := DeduplicateOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.TTL <= 0 {
+		o.TTL = 24 * time.Hour
+	}
+	if o.LockTTL <= 0 {
+		o.LockTTL = time.Minute
+	}
+	if o.RetryDelay <= 0 {
+		o.RetryDelay = 10 * time.Second
+	}
+	return func(ctx context.Context, msg *queue.Message) error {
+		id := msg.Attributes[IDAttribute]
+		if id == "" {
+			return handler(ctx, msg)
+		}
+		key := "outbox:" + id
+		rec, claimed, err := store.Claim(ctx, key, "", o.LockTTL)
+		if err != nil {
+			return errs.Errorf("failed to claim outbox message %s: %w", id, err)
+		}
+		if !claimed {
+			if rec.InProgress() {
+				return queue.RetryAfter(errs.Newf(errs.Internal, "outbox message %s is being handled", id), o.RetryDelay)
+			}
+			return nil
+		}
+		if err := handler(ctx, msg); err != nil {
+			if releaseErr := store.Release(ctx, key); releaseErr != nil {
+				return errs.Errorf("failed to release outbox message %s: %v: %w", id, releaseErr, err)
+			}
+			return err
+		}
+		if err := store.Complete(ctx, key, &idempotency.Record{Status: http.StatusOK}, o.TTL); err != nil {
+			return errs.Errorf("failed to complete outbox message %s: %w", id, err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,122 @@
+package pg
+
+import (
+	"cmp"
+	"context"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/jackc/pgx/v5"
+)
+
+// migrationLockID is the advisory lock held while migrating so replicas
+// starting together apply migrations once
+const migrationLockID = 7245339410582201901
+
+type migration struct {
+	version int64
+	name    string
+	file    string
+}
+
+// Migrate applies the .sql files at the root of fsys that have not been
+// applied yet, in version order. Files are named <version>_<description>.sql,
+// e.g. 0001_create_users.sql; each runs in its own transaction and is recorded
+// in the schema_migrations table. Concurrent callers are serialized with an
+// advisory lock. Typically fsys is an embed.FS sub-directory:
+//
+//	//go:embed migrations/*.sql
+//	var migrations embed.FS
+//	sub, _ := fs.Sub(migrations, "migrations")
+func Migrate(ctx context.Context, pool *Pool, fsys fs.FS, logger logging.Logger) error {
+	if logger == nil {
+		logger = logging.Noop()
+	}
+	migrations, err := readMigrations(fsys)
+	if err != nil {
+		return err
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return errs.Errorf("failed to acquire connection for migrations: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", int64(migrationLockID)); err != nil {
+		return errs.Errorf("failed to take migration lock: %w", err)
+	}
+	defer conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", int64(migrationLockID))
+
+	if _, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`); err != nil {
+		return errs.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	rows, err := conn.Query(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return errs.Errorf("failed to read schema_migrations: %w", err)
+	}
+	applied, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return errs.Errorf("failed to read schema_migrations: %w", err)
+	}
+
+	for _, m := range migrations {
+		if slices.Contains(applied, m.version) {
+			continue
+		}
+		sql, err := fs.ReadFile(fsys, m.file)
+		if err != nil {
+			return errs.Errorf("failed to read migration %s: %w", m.file, err)
+		}
+		err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, string(sql)); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.version, m.name)
+			return err
+		})
+		if err != nil {
+			return errs.Errorf("migration %s failed: %w", m.file, err)
+		}
+		logger.WithField("migration", m.file).Info("applied migration")
+	}
+	return nil
+}
+
+// readMigrations lists the migrations in fsys sorted by version
+func readMigrations(fsys fs.FS) ([]migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, errs.Errorf("failed to list migrations: %w", err)
+	}
+
+	var migrations []migration
+	for _, file := range files {
+		base := strings.TrimSuffix(path.Base(file), ".sql")
+		prefix, name, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return nil, errs.Newf(errs.Internal, "migration %s does not start with a numeric version", file)
+		}
+		migrations = append(migrations, migration{version: version, name: name, file: file})
+	}
+	slices.SortFunc(migrations, func(a, b migration) int {
+		return cmp.Compare(a.version, b.version)
+	})
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].version == migrations[i-1].version {
+			return nil, errs.Newf(errs.Internal, "migrations %s and %s have the same version", migrations[i-1].file, migrations[i].file)
+		}
+	}
+	return migrations, nil
+}
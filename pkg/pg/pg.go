@@ -0,0 +1,182 @@
+// Package pg builds pgx connection pools with production defaults: RDS IAM
+// authentication, embedded SQL migrations applied on startup, health checks
+// and slow query logging.
+package pg
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"time"
+
+	"github.com/bdlilley/easygo"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TokenSource supplies a password for each new connection, e.g. an
+// *easygo.RDSTokenSource generating IAM auth tokens
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+var _ TokenSource = (*easygo.RDSTokenSource)(nil)
+
+type NewPoolArgs struct {
+	// ConnString is a postgres:// URL or key=value DSN; when set, Host, Port,
+	// Database, User, Password and SSLMode are ignored
+	ConnString string
+	Host       string
+	// Port defaults to 5432
+	Port     int
+	Database string
+	User     string
+	Password string
+	// SSLMode defaults to require; use disable for local development
+	SSLMode string
+
+	// AwsClient with IAMAuth authenticates with RDS IAM tokens for User instead of Password
+	AwsClient *easygo.EGAwsClient
+	IAMAuth   bool
+	// TokenSource supplies passwords for new connections, overriding IAMAuth
+	TokenSource TokenSource
+
+	// Pool sizing; zero uses the pgxpool default
+	MaxConns        int32
+	MinConns        int32
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+
+	// Migrations holds SQL migrations applied on startup; see Migrate
+	Migrations fs.FS
+
+	// SlowQueryThreshold logs queries taking at least this long at warn
+	// (default: 500ms, negative disables)
+	SlowQueryThreshold time.Duration
+	// Logger receives slow query and migration logs (default: logging.Noop)
+	Logger logging.Logger
+}
+
+// Pool is a pgx connection pool
+type Pool struct {
+	*pgxpool.Pool
+}
+
+var _ easygo.HealthChecker = (*Pool)(nil)
+
+// NewPool connects a pool, verifies it with a ping and applies args.Migrations
+func NewPool(ctx context.Context, args *NewPoolArgs) (*Pool, error) {
+	if args == nil {
+		return nil, errs.New(errs.Internal, "args are required")
+	}
+	logger := args.Logger
+	if logger == nil {
+		logger = logging.Noop()
+	}
+
+	cfg, err := pgxpool.ParseConfig(connString(args))
+	if err != nil {
+		return nil, errs.Errorf("failed to parse connection config: %w", err)
+	}
+	if args.MaxConns > 0 {
+		cfg.MaxConns = args.MaxConns
+	}
+	if args.MinConns > 0 {
+		cfg.MinConns = args.MinConns
+	}
+	if args.MaxConnLifetime > 0 {
+		cfg.MaxConnLifetime = args.MaxConnLifetime
+	}
+	if args.MaxConnIdleTime > 0 {
+		cfg.MaxConnIdleTime = args.MaxConnIdleTime
+	}
+
+	tokens := args.TokenSource
+	if tokens == nil && args.IAMAuth {
+		if args.AwsClient == nil {
+			return nil, errs.New(errs.Internal, "IAMAuth requires AwsClient")
+		}
+		tokens, err = args.AwsClient.NewRDSTokenSource(&easygo.NewRDSTokenSourceArgs{
+			Host:   cfg.ConnConfig.Host,
+			Port:   int(cfg.ConnConfig.Port),
+			DBUser: cfg.ConnConfig.User,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if tokens != nil {
+		cfg.BeforeConnect = func(ctx context.Context, cc *pgx.ConnConfig) error {
+			token, err := tokens.Token(ctx)
+			if err != nil {
+				return err
+			}
+			cc.Password = token
+			return nil
+		}
+	}
+
+	threshold := args.SlowQueryThreshold
+	if threshold == 0 {
+		threshold = 500 * time.Millisecond
+	}
+	if threshold > 0 {
+		cfg.ConnConfig.Tracer = &slowQueryTracer{threshold: threshold, logger: logger}
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, errs.Errorf("failed to create pool: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, errs.Errorf("failed to connect to database: %w", err)
+	}
+
+	p := &Pool{Pool: pool}
+	if args.Migrations != nil {
+		if err := Migrate(ctx, p, args.Migrations, logger); err != nil {
+			pool.Close()
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func connString(args *NewPoolArgs) string {
+	if args.ConnString != "" {
+		return args.ConnString
+	}
+	port := args.Port
+	if port == 0 {
+		port = 5432
+	}
+	sslMode := args.SSLMode
+	if sslMode == "" {
+		sslMode = "require"
+	}
+	u := &url.URL{
+		Scheme:   "postgres",
+		Host:     fmt.Sprintf("%s:%d", args.Host, port),
+		Path:     "/" + args.Database,
+		RawQuery: url.Values{"sslmode": {sslMode}}.Encode(),
+	}
+	if args.Password != "" {
+		u.User = url.UserPassword(args.User, args.Password)
+	} else if args.User != "" {
+		u.User = url.User(args.User)
+	}
+	return u.String()
+}
+
+// HealthCheck pings the database. It can be registered directly with
+// httpserver's AddReadinessCheck.
+func (p *Pool) HealthCheck(ctx context.Context) error {
+	if err := p.Ping(ctx); err != nil {
+		return errs.Errorf("database ping failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+// Package pgtest starts PostgreSQL for tests of code that needs a real
+// database, such as pg.Migrate and the outbox.
+package pgtest
+
+import (
+	"context"
+	"crypto/rand"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/jackc/pgx/v5"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// PostgresURLEnv names an already running PostgreSQL, e.g. a CI service
+// container, that StartPostgres uses instead of starting its own. The URL's
+// user must be allowed to create databases.
+const PostgresURLEnv = "EASYGO_POSTGRES_URL"
+
+const defaultPostgresImage = "postgres:17-alpine"
+
+type PostgresOptions struct {
+	// Image is the PostgreSQL image (default: postgres:17-alpine)
+	Image string
+}
+
+// StartPostgres starts PostgreSQL in a container, or uses the one at
+// $EASYGO_POSTGRES_URL, and returns the URL of a new empty database on it.
+// The database and container are removed when the test ends. The test is
+// skipped in -short mode and when neither Docker nor $EASYGO_POSTGRES_URL is
+// available.
+func StartPostgres(tb testing.TB, opts *PostgresOptions) string {
+	tb.Helper()
+	if testing.Short() {
+		tb.Skip("skipping PostgreSQL test in short mode")
+	}
+	o := PostgresOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Image == "" {
+		o.Image = defaultPostgresImage
+	}
+	ctx := context.Background()
+
+	server := os.Getenv(PostgresURLEnv)
+	if server == "" {
+		if err := dockerHealth(ctx); err != nil {
+			tb.Skipf("skipping PostgreSQL test, Docker is not available: %v", err)
+		}
+		ctr, err := testcontainers.Run(ctx, o.Image,
+			testcontainers.WithEnv(map[string]string{"POSTGRES_PASSWORD": "postgres"}),
+			testcontainers.WithExposedPorts("5432/tcp"),
+			// the entrypoint restarts the server once after initializing it
+			testcontainers.WithWaitStrategy(wait.ForLog("database system is ready to accept connections").WithOccurrence(2)),
+		)
+		testcontainers.CleanupContainer(tb, ctr)
+		if err != nil {
+			tb.Fatalf("failed to start PostgreSQL: %v", err)
+		}
+		hostPort, err := ctr.PortEndpoint(ctx, "5432/tcp", "")
+		if err != nil {
+			tb.Fatalf("failed to get PostgreSQL endpoint: %v", err)
+		}
+		server = "postgres://postgres:postgres@" + hostPort + "/postgres?sslmode=disable"
+	}
+
+	db, err := createDatabase(ctx, tb, server)
+	if err != nil {
+		tb.Fatalf("failed to create test database: %v", err)
+	}
+	return db
+}
+
+// createDatabase creates a uniquely named database, so tests sharing a server
+// do not see each other's tables, and drops it when the test ends
+func createDatabase(ctx context.Context, tb testing.TB, server string) (string, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", errs.Errorf("invalid %s: %w", PostgresURLEnv, err)
+	}
+	conn, err := pgx.Connect(ctx, server)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close(ctx)
+
+	name := pgx.Identifier{"pgtest_" + strings.ToLower(rand.Text())}.Sanitize()
+	if _, err := conn.Exec(ctx, "CREATE DATABASE "+name); err != nil {
+		return "", err
+	}
+	tb.Cleanup(func() {
+		conn, err := pgx.Connect(ctx, server)
+		if err != nil {
+			tb.Logf("failed to drop test database: %v", err)
+			return
+		}
+		defer conn.Close(ctx)
+		if _, err := conn.Exec(ctx, "DROP DATABASE "+name+" WITH (FORCE)"); err != nil {
+			tb.Logf("failed to drop test database: %v", err)
+		}
+	})
+	u.Path = "/" + strings.Trim(name, `"`)
+	return u.String(), nil
+}
+
+// dockerHealth reports whether a Docker provider is reachable; testcontainers
+// panics rather than failing in some environments without one
+func dockerHealth(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errs.Errorf("%v", r)
+		}
+	}()
+	provider, err := testcontainers.ProviderDocker.GetProvider()
+	if err != nil {
+		return err
+	}
+	defer provider.Close()
+	return provider.Health(ctx)
+}
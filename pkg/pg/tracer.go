@@ -0,0 +1,48 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	sql   string
+	start time.Time
+}
+
+// slowQueryTracer logs queries that take at least threshold. Arguments are not
+// logged since they may hold sensitive values.
+type slowQueryTracer struct {
+	threshold time.Duration
+	logger    logging.Logger
+}
+
+func (t *slowQueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, &queryStart{sql: data.SQL, start: time.Now()})
+}
+
+func (t *slowQueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	qs, ok := ctx.Value(queryStartKey{}).(*queryStart)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(qs.start)
+	if elapsed < t.threshold {
+		return
+	}
+	entry := logging.WithTrace(ctx, t.logger).WithFields(logrus.Fields{
+		"sql":     qs.sql,
+		"elapsed": elapsed.String(),
+		"rows":    data.CommandTag.RowsAffected(),
+	})
+	if data.Err != nil {
+		entry = entry.WithError(data.Err)
+	}
+	entry.Warn("slow query")
+}
@@ -0,0 +1,291 @@
+// Package profiling periodically captures runtime profiles and ships them to
+// a Sink such as S3 or a Pyroscope server, for continuous profiling of
+// long-running services. Capturing can be turned on and off at runtime
+// through Handler, typically on the admin listener.
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"runtime/pprof"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/bdlilley/easygo/pkg/scheduler"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrTooLarge is reported when a profile exceeds Options.MaxBytes; the
+// profile is dropped, since a truncated pprof file cannot be parsed
+var ErrTooLarge = errs.New(errs.InvalidArgument, "profile exceeds size limit")
+
+// Type names a runtime profile
+type Type string
+
+const (
+	CPU       Type = "cpu"
+	Heap      Type = "heap"
+	Allocs    Type = "allocs"
+	Goroutine Type = "goroutine"
+	// Mutex and Block profiles are empty unless the application sets
+	// runtime.SetMutexProfileFraction and runtime.SetBlockProfileRate
+	Mutex Type = "mutex"
+	Block Type = "block"
+)
+
+// Profile is one captured profile
+type Profile struct {
+	Type Type
+	// Start and End bound the sampling period; they are equal for snapshot
+	// profiles such as heap
+	Start time.Time
+	End   time.Time
+	// Data is the gzip-compressed pprof protobuf
+	Data []byte
+	// Labels are Options.Labels, e.g. service and version
+	Labels map[string]string
+}
+
+// Sink receives captured profiles
+type Sink interface {
+	Upload(ctx context.Context, p *Profile) error
+}
+
+type Options struct {
+	// Sink receives the profiles (required)
+	Sink Sink
+	// Types are captured on each run (default: CPU and Heap)
+	Types []Type
+	// Schedule decides when captures run (default: scheduler.Every(time.Minute))
+	Schedule scheduler.Schedule
+	// SampleRate is the probability in (0, 1] that a scheduled capture runs,
+	// so a large fleet can profile a fraction of instances at a time
+	// (default: 1)
+	SampleRate float64
+	// CPUDuration is how long the CPU profile samples for (default: 10s)
+	CPUDuration time.Duration
+	// MaxBytes drops profiles larger than this, compressed (default: 8MiB)
+	MaxBytes int
+	// UploadTimeout bounds each upload (default: 30s)
+	UploadTimeout time.Duration
+	// Disabled starts the profiler turned off until SetEnabled(true)
+	Disabled bool
+	// Labels are attached to every profile
+	Labels map[string]string
+	// Logger logs each capture at debug and, without an ErrorHandler, errors
+	// at error (default: logging.Noop)
+	Logger logging.Logger
+	// ErrorHandler receives capture and upload errors (default: log them to Logger)
+	ErrorHandler func(error)
+}
+
+// Profiler captures profiles on a schedule. Call Run to start it, e.g. with
+// app.Runner(p.Run).
+type Profiler struct {
+	sink          Sink
+	types         []Type
+	schedule      scheduler.Schedule
+	sampleRate    float64
+	cpuDuration   time.Duration
+	maxBytes      int
+	uploadTimeout time.Duration
+	labels        map[string]string
+	logger        logging.Logger
+	errorHandler  func(error)
+
+	enabled atomic.Bool
+}
+
+// New creates a Profiler
+func New(opts *Options) (*Profiler, error) {
+	if opts == nil || opts.Sink == nil {
+		return nil, errs.New(errs.Internal, "profiling: Sink is required")
+	}
+	if opts.SampleRate < 0 || opts.SampleRate > 1 {
+		return nil, errs.Newf(errs.Internal, "profiling: SampleRate %v is not between 0 and 1", opts.SampleRate)
+	}
+	p := &Profiler{
+		sink:          opts.Sink,
+		types:         slices.Clone(opts.Types),
+		schedule:      opts.Schedule,
+		sampleRate:    opts.SampleRate,
+		cpuDuration:   opts.CPUDuration,
+		maxBytes:      opts.MaxBytes,
+		uploadTimeout: opts.UploadTimeout,
+		labels:        opts.Labels,
+		logger:        opts.Logger,
+		errorHandler:  opts.ErrorHandler,
+	}
+	for _, t := range p.types {
+		if t != CPU && pprof.Lookup(string(t)) == nil {
+			return nil, errs.Newf(errs.Internal, "profiling: unknown profile type %q", t)
+		}
+	}
+	if len(p.types) == 0 {
+		p.types = []Type{CPU, Heap}
+	}
+	if p.schedule == nil {
+		p.schedule = scheduler.Every(time.Minute)
+	}
+	if p.sampleRate == 0 {
+		p.sampleRate = 1
+	}
+	if p.cpuDuration <= 0 {
+		p.cpuDuration = 10 * time.Second
+	}
+	if p.maxBytes <= 0 {
+		p.maxBytes = 8 << 20
+	}
+	if p.uploadTimeout <= 0 {
+		p.uploadTimeout = 30 * time.Second
+	}
+	if p.logger == nil {
+		p.logger = logging.Noop()
+	}
+	if p.errorHandler == nil {
+		p.errorHandler = func(err error) { p.logger.WithError(err).Error("profile capture failed") }
+	}
+	p.enabled.Store(!opts.Disabled)
+	return p, nil
+}
+
+// Enabled reports whether scheduled captures run
+func (p *Profiler) Enabled() bool {
+	return p.enabled.Load()
+}
+
+// SetEnabled turns scheduled captures on or off; a capture in progress
+// finishes
+func (p *Profiler) SetEnabled(enabled bool) {
+	p.enabled.Store(enabled)
+}
+
+// Run captures profiles on the schedule until ctx is canceled, and returns nil
+// then. Errors go to the ErrorHandler rather than stopping the loop.
+func (p *Profiler) Run(ctx context.Context) error {
+	for {
+		t := time.NewTimer(time.Until(p.schedule.Next(time.Now())))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil
+		case <-t.C:
+		}
+		if !p.enabled.Load() || (p.sampleRate < 1 && rand.Float64() >= p.sampleRate) {
+			continue
+		}
+		for _, typ := range p.types {
+			if err := p.CaptureAndUpload(ctx, typ); err != nil && ctx.Err() == nil {
+				p.errorHandler(err)
+			}
+		}
+	}
+}
+
+// CaptureAndUpload captures one profile of typ and sends it to the sink,
+// regardless of whether the profiler is enabled
+func (p *Profiler) CaptureAndUpload(ctx context.Context, typ Type) error {
+	prof, err := p.Capture(ctx, typ)
+	if err != nil {
+		return err
+	}
+	uploadCtx, cancel := context.WithTimeout(ctx, p.uploadTimeout)
+	defer cancel()
+	if err := p.sink.Upload(uploadCtx, prof); err != nil {
+		return errs.Errorf("failed to upload %s profile: %w", typ, err)
+	}
+	p.logger.WithFields(logrus.Fields{"type": typ, "bytes": len(prof.Data)}).Debug("uploaded profile")
+	return nil
+}
+
+// Capture records one profile of typ. A CPU profile samples for CPUDuration,
+// or until ctx is canceled, and fails if another CPU profile is running, e.g.
+// one requested through /debug/pprof/profile.
+func (p *Profiler) Capture(ctx context.Context, typ Type) (*Profile, error) {
+	buf := &limitedBuffer{max: p.maxBytes}
+	prof := &Profile{Type: typ, Start: time.Now(), Labels: p.labels}
+	if typ == CPU {
+		if err := pprof.StartCPUProfile(buf); err != nil {
+			return nil, errs.Errorf("failed to start CPU profile: %w", err)
+		}
+		t := time.NewTimer(p.cpuDuration)
+		select {
+		case <-ctx.Done():
+		case <-t.C:
+		}
+		t.Stop()
+		pprof.StopCPUProfile()
+	} else {
+		lookup := pprof.Lookup(string(typ))
+		if lookup == nil {
+			return nil, errs.Newf(errs.Internal, "unknown profile type %q", typ)
+		}
+		if err := lookup.WriteTo(buf, 0); err != nil && !errors.Is(err, ErrTooLarge) {
+			return nil, errs.Errorf("failed to write %s profile: %w", typ, err)
+		}
+	}
+	if buf.exceeded {
+		return nil, errs.Errorf("%s profile is over %d bytes: %w", typ, p.maxBytes, ErrTooLarge)
+	}
+	prof.End = time.Now()
+	if typ != CPU {
+		prof.Start = prof.End
+	}
+	prof.Data = buf.Bytes()
+	return prof, nil
+}
+
+// limitedBuffer stops accepting writes past max, so a runaway profile does
+// not grow without bound
+type limitedBuffer struct {
+	bytes.Buffer
+	max      int
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.exceeded || b.Len()+len(p) > b.max {
+		b.exceeded = true
+		return 0, ErrTooLarge
+	}
+	return b.Buffer.Write(p)
+}
+
+type stateBody struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// Handler serves the profiler's state: GET returns {"enabled": true} and PUT
+// with the same body turns scheduled captures on or off
+func (p *Profiler) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			body := stateBody{}
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&body); err != nil {
+				http.Error(w, "invalid JSON body", http.StatusBadRequest)
+				return
+			}
+			if body.Enabled == nil {
+				http.Error(w, "enabled is required", http.StatusBadRequest)
+				return
+			}
+			p.SetEnabled(*body.Enabled)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		enabled := p.Enabled()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(stateBody{Enabled: &enabled})
+	})
+}
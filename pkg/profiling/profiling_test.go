@@ -0,0 +1,145 @@
+package profiling
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/bdlilley/easygo/pkg/scheduler"
+)
+
+type memorySink struct {
+	mu       sync.Mutex
+	profiles []*Profile
+}
+
+func (s *memorySink) Upload(_ context.Context, p *Profile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.profiles = append(s.profiles, p)
+	return nil
+}
+
+func (s *memorySink) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.profiles)
+}
+
+func TestProfilerRun(t *testing.T) {
+	sink := &memorySink{}
+	p, err := New(&Options{
+		Sink:        sink,
+		Types:       []Type{CPU, Heap, Goroutine},
+		Schedule:    scheduler.Every(10 * time.Millisecond),
+		CPUDuration: 20 * time.Millisecond,
+		Labels:      map[string]string{"service": "orders"},
+		Disabled:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- p.Run(ctx) }()
+
+	time.Sleep(50 * time.Millisecond)
+	if sink.len() != 0 {
+		t.Fatal("captured while disabled")
+	}
+
+	w := httptest.NewRecorder()
+	p.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/profiling", strings.NewReader(`{"enabled":true}`)))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"enabled":true`) {
+		t.Fatalf("got %d %s", w.Code, w.Body)
+	}
+	for deadline := time.Now().Add(5 * time.Second); sink.len() < 3; {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d profiles, want 3", sink.len())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	for i, typ := range []Type{CPU, Heap, Goroutine} {
+		prof := sink.profiles[i]
+		// pprof output is gzip-compressed
+		if prof.Type != typ || len(prof.Data) < 2 || prof.Data[0] != 0x1f || prof.Data[1] != 0x8b || prof.Labels["service"] != "orders" {
+			t.Fatalf("profile %d: got %s with %d bytes", i, prof.Type, len(prof.Data))
+		}
+	}
+	if cpu := sink.profiles[0]; cpu.End.Sub(cpu.Start) < 20*time.Millisecond {
+		t.Fatalf("CPU profile covered %s", cpu.End.Sub(cpu.Start))
+	}
+
+	w = httptest.NewRecorder()
+	p.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/profiling", strings.NewReader(`{}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("got %d for a body without enabled", w.Code)
+	}
+}
+
+func TestSizeLimit(t *testing.T) {
+	p, _ := New(&Options{Sink: &memorySink{}, MaxBytes: 16})
+	if _, err := p.Capture(context.Background(), Goroutine); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("got %v, want ErrTooLarge", err)
+	}
+	if _, err := New(&Options{Sink: &memorySink{}, Types: []Type{"nope"}}); err == nil {
+		t.Fatal("expected an error for an unknown profile type")
+	}
+}
+
+type fakeS3 struct {
+	input *s3.PutObjectInput
+}
+
+func (f *fakeS3) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	f.input = in
+	return &s3.PutObjectOutput{}, nil
+}
+
+func TestSinks(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	prof := &Profile{Type: Heap, Start: start, End: start.Add(10 * time.Second), Data: []byte("pprof"), Labels: map[string]string{"version": "1.2", "env": "prod"}}
+
+	client := &fakeS3{}
+	s3Sink, _ := NewS3Sink(&NewS3SinkArgs{Client: client, Bucket: "profiles", Prefix: "orders", Instance: "host-1"})
+	if err := s3Sink.Upload(ctx, prof); err != nil {
+		t.Fatal(err)
+	}
+	if key := aws.ToString(client.input.Key); key != "orders/heap/2026/03/04/050607-host-1.pb.gz" || client.input.Metadata["env"] != "prod" {
+		t.Fatalf("got key %s metadata %v", key, client.input.Metadata)
+	}
+
+	var query string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("name") + " " + r.URL.Query().Get("from") + " " + r.URL.Query().Get("until") + " " + r.URL.Query().Get("format")
+		body, _ = io.ReadAll(r.Body)
+		if r.Header.Get("X-Scope-OrgID") != "team" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+	pyroscope, _ := NewPyroscopeSink(&NewPyroscopeSinkArgs{URL: srv.URL, Application: "orders", Header: http.Header{"X-Scope-OrgID": {"team"}}})
+	if err := pyroscope.Upload(ctx, prof); err != nil {
+		t.Fatal(err)
+	}
+	if query != "orders{env=prod,version=1.2} 1772600767 1772600777 pprof" || string(body) != "pprof" {
+		t.Fatalf("got query %q body %q", query, body)
+	}
+}
@@ -0,0 +1,154 @@
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/httpclient"
+)
+
+// S3PutObjectAPI is the subset of the S3 client used by S3Sink
+type S3PutObjectAPI interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+type NewS3SinkArgs struct {
+	// Client is typically EGAwsClient.GetS3Client() (required)
+	Client S3PutObjectAPI
+	// Bucket holds the profiles (required)
+	Bucket string
+	// Prefix is prepended to object keys, which are
+	// <prefix>/<type>/YYYY/MM/DD/HHMMSS-<instance>.pb.gz
+	Prefix string
+	// Instance distinguishes processes writing to the same prefix (default: the hostname)
+	Instance string
+}
+
+// S3Sink stores each profile as an object, with its labels as object metadata
+type S3Sink struct {
+	client   S3PutObjectAPI
+	bucket   string
+	prefix   string
+	instance string
+}
+
+var _ Sink = (*S3Sink)(nil)
+
+// NewS3Sink creates an S3Sink
+func NewS3Sink(args *NewS3SinkArgs) (*S3Sink, error) {
+	if args == nil || args.Client == nil || args.Bucket == "" {
+		return nil, errs.New(errs.Internal, "profiling: S3 Client and Bucket are required")
+	}
+	s := &S3Sink{client: args.Client, bucket: args.Bucket, prefix: args.Prefix, instance: args.Instance}
+	if s.instance == "" {
+		s.instance, _ = os.Hostname()
+	}
+	if s.instance == "" {
+		s.instance = "unknown"
+	}
+	return s, nil
+}
+
+func (s *S3Sink) Upload(ctx context.Context, p *Profile) error {
+	start := p.Start.UTC()
+	key := path.Join(s.prefix, string(p.Type), start.Format("2006/01/02"), start.Format("150405")+"-"+s.instance+".pb.gz")
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(p.Data),
+		ContentType: aws.String("application/octet-stream"),
+		Metadata:    p.Labels,
+	})
+	if err != nil {
+		return errs.Errorf("failed to put s3://%s/%s: %w", s.bucket, key, err)
+	}
+	return nil
+}
+
+type NewPyroscopeSinkArgs struct {
+	// URL is the Pyroscope server, e.g. http://pyroscope:4040 (required)
+	URL string
+	// Application names the profiles, e.g. the service name (required)
+	Application string
+	// Client sends the uploads (default: httpclient.NewClient with defaults)
+	Client *httpclient.Client
+	// Header is added to each upload, e.g. Authorization or X-Scope-OrgID
+	Header http.Header
+}
+
+// PyroscopeSink sends profiles to a Pyroscope server's /ingest endpoint in
+// pprof format, with the profile labels as Pyroscope labels
+type PyroscopeSink struct {
+	url         string
+	application string
+	client      *httpclient.Client
+	header      http.Header
+}
+
+var _ Sink = (*PyroscopeSink)(nil)
+
+// NewPyroscopeSink creates a PyroscopeSink
+func NewPyroscopeSink(args *NewPyroscopeSinkArgs) (*PyroscopeSink, error) {
+	if args == nil || args.URL == "" || args.Application == "" {
+		return nil, errs.New(errs.Internal, "profiling: Pyroscope URL and Application are required")
+	}
+	s := &PyroscopeSink{url: strings.TrimRight(args.URL, "/"), application: args.Application, client: args.Client, header: args.Header}
+	if s.client == nil {
+		s.client = httpclient.NewClient(&httpclient.NewClientArgs{})
+	}
+	return s, nil
+}
+
+func (s *PyroscopeSink) Upload(ctx context.Context, p *Profile) error {
+	q := url.Values{
+		"name":   {s.name(p)},
+		"from":   {strconv.FormatInt(p.Start.Unix(), 10)},
+		"until":  {strconv.FormatInt(p.End.Unix(), 10)},
+		"format": {"pprof"},
+	}
+	req, err := s.client.NewRequest(ctx, http.MethodPost, s.url+"/ingest?"+q.Encode(), bytes.NewReader(p.Data))
+	if err != nil {
+		return err
+	}
+	for k, v := range s.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errs.Newf(errs.Internal, "pyroscope ingest returned %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// name formats the application and labels as app{k=v,...}, in key order
+func (s *PyroscopeSink) name(p *Profile) string {
+	var b strings.Builder
+	b.WriteString(s.application)
+	b.WriteByte('{')
+	for i, k := range slices.Sorted(maps.Keys(p.Labels)) {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k + "=" + p.Labels[k])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
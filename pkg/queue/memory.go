@@ -0,0 +1,211 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/logging"
+)
+
+type MemoryQueueOptions struct {
+	// Concurrency is the number of messages each subscriber handles at once (default: 1)
+	Concurrency int
+	// RetryDelay is how long a nacked message waits before redelivery unless
+	// the handler used RetryAfter (default: redeliver immediately)
+	RetryDelay time.Duration
+	// MaxAttempts moves a message to DeadLetters after this many failed
+	// deliveries, like an SQS redrive policy (default: unlimited)
+	MaxAttempts int
+	// ErrorHandler receives handler errors (default: log them to Logger)
+	ErrorHandler func(msg *Message, err error)
+	// Logger logs handler errors at error without an ErrorHandler (default: logging.Noop)
+	Logger logging.Logger
+}
+
+// MemoryQueue is an in-process Queue for tests and local development.
+// Subscribers compete for messages, and nacked messages are redelivered.
+type MemoryQueue struct {
+	opts MemoryQueueOptions
+
+	mu       sync.Mutex
+	ready    []*memoryEntry
+	delayed  int
+	inFlight int
+	dead     []*Message
+	nextID   int
+	// changed is closed and replaced whenever the queue changes
+	changed chan struct{}
+}
+
+type memoryEntry struct {
+	msg      *Message
+	attempts int
+}
+
+var _ Queue = (*MemoryQueue)(nil)
+
+// NewMemoryQueue creates an empty queue; opts may be nil
+func NewMemoryQueue(opts *MemoryQueueOptions) *MemoryQueue {
+	o := MemoryQueueOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 1
+	}
+	if o.Logger == nil {
+		o.Logger = logging.Noop()
+	}
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = logErrors(o.Logger, "memory queue")
+	}
+	return &MemoryQueue{opts: o, changed: make(chan struct{})}
+}
+
+func (q *MemoryQueue) Publish(ctx context.Context, msg *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.nextID++
+	msg.ID = fmt.Sprintf("message-%d", q.nextID)
+	q.ready = append(q.ready, &memoryEntry{msg: cloneMessage(msg)})
+	q.broadcast()
+	return nil
+}
+
+// Subscribe handles messages until ctx is done. Handlers in flight when ctx
+// is done run to completion with a context that is not cancelled.
+func (q *MemoryQueue) Subscribe(ctx context.Context, handler Handler) error {
+	slots := make(chan struct{}, q.opts.Concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil
+		}
+		e, ok := q.next(ctx)
+		if !ok {
+			return nil
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			q.process(context.WithoutCancel(ctx), handler, e)
+		}()
+	}
+}
+
+// Len returns the number of messages waiting for delivery, including nacked
+// messages waiting out their retry delay
+func (q *MemoryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.ready) + q.delayed
+}
+
+// DeadLetters returns the messages that exhausted MaxAttempts
+func (q *MemoryQueue) DeadLetters() []*Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return slices.Clone(q.dead)
+}
+
+// WaitIdle blocks until no messages are waiting or being handled, so tests
+// can assert on the effects of everything published
+func (q *MemoryQueue) WaitIdle(ctx context.Context) error {
+	for {
+		q.mu.Lock()
+		idle := len(q.ready) == 0 && q.delayed == 0 && q.inFlight == 0
+		changed := q.changed
+		q.mu.Unlock()
+		if idle {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// next takes the oldest ready message, waiting until one is published
+func (q *MemoryQueue) next(ctx context.Context) (*memoryEntry, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.ready) > 0 {
+			e := q.ready[0]
+			q.ready = q.ready[1:]
+			e.attempts++
+			q.inFlight++
+			q.mu.Unlock()
+			return e, true
+		}
+		changed := q.changed
+		q.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+func (q *MemoryQueue) process(ctx context.Context, handler Handler, e *memoryEntry) {
+	msg := cloneMessage(e.msg)
+	msg.Attempt = e.attempts
+	err := handle(ctx, handler, msg)
+	if err != nil {
+		q.opts.ErrorHandler(msg, err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.inFlight--
+	defer q.broadcast()
+	if err == nil {
+		return
+	}
+	if q.opts.MaxAttempts > 0 && e.attempts >= q.opts.MaxAttempts {
+		q.dead = append(q.dead, msg)
+		return
+	}
+	delay := q.opts.RetryDelay
+	if d, ok := retryDelay(err); ok {
+		delay = d
+	}
+	if delay <= 0 {
+		q.ready = append(q.ready, e)
+		return
+	}
+	q.delayed++
+	time.AfterFunc(delay, func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		q.delayed--
+		q.ready = append(q.ready, e)
+		q.broadcast()
+	})
+}
+
+// broadcast wakes waiters; q.mu must be held
+func (q *MemoryQueue) broadcast() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+func cloneMessage(msg *Message) *Message {
+	c := *msg
+	c.Body = slices.Clone(msg.Body)
+	c.Attributes = maps.Clone(msg.Attributes)
+	return &c
+}
@@ -0,0 +1,139 @@
+// Package queue is a message queue abstraction with SQS and in-memory
+// backends, so application code can publish and consume without depending on
+// a broker and be tested without one.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/bdlilley/easygo/pkg/ratelimit"
+)
+
+// Message is a queued message
+type Message struct {
+	// ID is assigned by the queue when the message is published
+	ID   string
+	Body []byte
+	// Attributes are string metadata delivered with the message
+	Attributes map[string]string
+	// GroupID orders messages within a group on FIFO queues
+	GroupID string
+	// DeduplicationID suppresses duplicate publishes on FIFO queues
+	DeduplicationID string
+	// Attempt is the delivery attempt starting at 1; set on received messages
+	Attempt int
+}
+
+// Handler processes a received message. Returning nil acknowledges it so it
+// is not delivered again; returning an error negatively acknowledges it so it
+// is redelivered, after a delay when wrapped with RetryAfter.
+type Handler func(ctx context.Context, msg *Message) error
+
+// Queue publishes and consumes messages
+type Queue interface {
+	// Publish sends msg and sets its ID
+	Publish(ctx context.Context, msg *Message) error
+	// Subscribe delivers messages to handler until ctx is done, then waits for
+	// handlers in flight to finish
+	Subscribe(ctx context.Context, handler Handler) error
+}
+
+// PublishJSON marshals v and publishes it with attrs
+func PublishJSON(ctx context.Context, q Queue, v any, attrs map[string]string) (*Message, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, errs.Errorf("failed to marshal message: %w", err)
+	}
+	msg := &Message{Body: b, Attributes: attrs}
+	if err := q.Publish(ctx, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// JSONHandler adapts fn to a Handler that unmarshals message bodies into T.
+// Bodies that fail to unmarshal are acknowledged and reported to onInvalid,
+// since redelivering them can never succeed; onInvalid may be nil.
+func JSONHandler[T any](fn func(ctx context.Context, v T, msg *Message) error, onInvalid func(msg *Message, err error)) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		var v T
+		if err := json.Unmarshal(msg.Body, &v); err != nil {
+			if onInvalid != nil {
+				onInvalid(msg, errs.Errorf("invalid message %s: %w", msg.ID, err))
+			}
+			return nil
+		}
+		return fn(ctx, v, msg)
+	}
+}
+
+// RateLimited returns q with Publish waiting for limiter under key, e.g. to
+// keep a publisher within a downstream consumer's throughput
+func RateLimited(q Queue, limiter ratelimit.Limiter, key string) Queue {
+	return &rateLimitedQueue{Queue: q, limiter: limiter, key: key}
+}
+
+type rateLimitedQueue struct {
+	Queue
+	limiter ratelimit.Limiter
+	key     string
+}
+
+func (q *rateLimitedQueue) Publish(ctx context.Context, msg *Message) error {
+	if err := ratelimit.Wait(ctx, q.limiter, q.key); err != nil {
+		return errs.Errorf("failed to publish to %s: %w", q.key, err)
+	}
+	return q.Queue.Publish(ctx, msg)
+}
+
+type retryAfterError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *retryAfterError) Error() string { return e.err.Error() }
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+// RetryAfter wraps a handler error so the message is redelivered after delay
+func RetryAfter(err error, delay time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &retryAfterError{err: err, delay: delay}
+}
+
+// retryDelay returns the delay requested with RetryAfter
+func retryDelay(err error) (time.Duration, bool) {
+	var r *retryAfterError
+	if errors.As(err, &r) {
+		return r.delay, true
+	}
+	return 0, false
+}
+
+// handle calls handler, converting a panic into an error so the message is nacked
+func handle(ctx context.Context, handler Handler, msg *Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errs.Newf(errs.Internal, "queue: panic handling message %s: %v", msg.ID, r)
+		}
+	}()
+	return handler(ctx, msg)
+}
+
+// logErrors is the default ErrorHandler of the queues; msg is nil for errors
+// not tied to a message
+func logErrors(logger logging.Logger, queue string) func(msg *Message, err error) {
+	return func(msg *Message, err error) {
+		log := logger.WithError(err).WithField("queue", queue)
+		if msg != nil {
+			log = log.WithField("message_id", msg.ID)
+		}
+		log.Error("queue error")
+	}
+}
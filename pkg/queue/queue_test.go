@@ -0,0 +1,173 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/sirupsen/logrus"
+)
+
+type order struct {
+	ID string `json:"id"`
+}
+
+func TestMemoryQueue(t *testing.T) {
+	logger, logs := logging.NewTestLogger()
+	q := NewMemoryQueue(&MemoryQueueOptions{Concurrency: 2, MaxAttempts: 3, Logger: logger})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	handled := map[string]int{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = q.Subscribe(ctx, JSONHandler(func(ctx context.Context, o order, msg *Message) error {
+			mu.Lock()
+			handled[o.ID]++
+			mu.Unlock()
+			switch o.ID {
+			case "flaky":
+				if msg.Attempt < 2 {
+					return RetryAfter(errors.New("not yet"), 10*time.Millisecond)
+				}
+			case "poison":
+				return errors.New("always fails")
+			}
+			return nil
+		}, nil))
+	}()
+
+	for _, id := range []string{"a", "flaky", "poison"} {
+		msg, err := PublishJSON(ctx, q, order{ID: id}, map[string]string{"type": "order"})
+		if err != nil || msg.ID == "" {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+	waitCtx, waitCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer waitCancel()
+	if err := q.WaitIdle(waitCtx); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	<-done
+
+	if handled["a"] != 1 || handled["flaky"] != 2 || handled["poison"] != 3 {
+		t.Fatalf("unexpected deliveries %v", handled)
+	}
+	if len(logs.EntriesAtLevel(logrus.ErrorLevel)) != 4 {
+		t.Fatalf("got %d error logs, want one per failed delivery", len(logs.EntriesAtLevel(logrus.ErrorLevel)))
+	}
+	dead := q.DeadLetters()
+	if len(dead) != 1 || dead[0].Attributes["type"] != "order" || dead[0].Attempt != 3 {
+		t.Fatalf("unexpected dead letters %+v", dead)
+	}
+	if q.Len() != 0 {
+		t.Fatalf("expected empty queue, got %d", q.Len())
+	}
+}
+
+// fakeSQS serves queued messages once and records acknowledgements
+type fakeSQS struct {
+	mu         sync.Mutex
+	queued     []types.Message
+	sent       []*sqs.SendMessageInput
+	deleted    []string
+	visibility map[string]int32
+}
+
+func (f *fakeSQS) SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, params)
+	return &sqs.SendMessageOutput{MessageId: aws.String("sqs-1")}, nil
+}
+
+func (f *fakeSQS) ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	f.mu.Lock()
+	n := min(int(params.MaxNumberOfMessages), len(f.queued))
+	msgs := f.queued[:n]
+	f.queued = f.queued[n:]
+	f.mu.Unlock()
+	if len(msgs) == 0 {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &sqs.ReceiveMessageOutput{Messages: msgs}, nil
+}
+
+func (f *fakeSQS) DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleted = append(f.deleted, aws.ToString(params.ReceiptHandle))
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func (f *fakeSQS) ChangeMessageVisibility(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.visibility[aws.ToString(params.ReceiptHandle)] = params.VisibilityTimeout
+	return &sqs.ChangeMessageVisibilityOutput{}, nil
+}
+
+func TestSQSQueue(t *testing.T) {
+	fake := &fakeSQS{visibility: map[string]int32{}}
+	for _, body := range []string{"ok", "retry"} {
+		fake.queued = append(fake.queued, types.Message{
+			MessageId:         aws.String("id-" + body),
+			ReceiptHandle:     aws.String("rh-" + body),
+			Body:              aws.String(body),
+			Attributes:        map[string]string{"ApproximateReceiveCount": "2"},
+			MessageAttributes: map[string]types.MessageAttributeValue{"tenant": {DataType: aws.String("String"), StringValue: aws.String("t1")}},
+		})
+	}
+	q := NewSQSQueue(fake, "https://sqs.example/q", &SQSQueueOptions{ErrorHandler: func(*Message, error) {}})
+
+	msg := &Message{Body: []byte("hello"), Attributes: map[string]string{"k": "v"}, GroupID: "g"}
+	if err := q.Publish(context.Background(), msg); err != nil || msg.ID != "sqs-1" {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if sent := fake.sent[0]; aws.ToString(sent.MessageGroupId) != "g" || aws.ToString(sent.MessageAttributes["k"].StringValue) != "v" {
+		t.Fatalf("unexpected send input %+v", sent)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		_ = q.Subscribe(ctx, func(ctx context.Context, msg *Message) error {
+			defer wg.Done()
+			if msg.Attempt != 2 || msg.Attributes["tenant"] != "t1" {
+				t.Errorf("unexpected message %+v", msg)
+			}
+			if string(msg.Body) == "retry" {
+				return RetryAfter(errors.New("later"), 30*time.Second)
+			}
+			return nil
+		})
+	}()
+	wg.Wait()
+	cancel()
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	deadline := time.Now().Add(5 * time.Second)
+	for (len(fake.deleted) != 1 || len(fake.visibility) != 1) && time.Now().Before(deadline) {
+		fake.mu.Unlock()
+		time.Sleep(time.Millisecond)
+		fake.mu.Lock()
+	}
+	if len(fake.deleted) != 1 || fake.deleted[0] != "rh-ok" {
+		t.Fatalf("expected ok message deleted, got %v", fake.deleted)
+	}
+	if fake.visibility["rh-retry"] != 30 {
+		t.Fatalf("expected retry visibility of 30s, got %v", fake.visibility)
+	}
+}
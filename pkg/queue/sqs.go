@@ -0,0 +1,225 @@
+package queue
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/bdlilley/easygo/pkg/retry"
+	"github.com/sirupsen/logrus"
+)
+
+// sqsMaxMessages is the ReceiveMessage batch limit
+const sqsMaxMessages = 10
+
+// SQSAPI is the subset of the SQS client used by SQSQueue
+type SQSAPI interface {
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+	ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
+	ChangeMessageVisibility(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error)
+}
+
+type SQSQueueOptions struct {
+	// Concurrency is the number of messages handled at once (default: 10)
+	Concurrency int
+	// WaitTime is the long polling wait per receive (default and max: 20s)
+	WaitTime time.Duration
+	// VisibilityTimeout overrides the queue's visibility timeout for received
+	// messages; it should exceed the longest handler run (default: the queue's)
+	VisibilityTimeout time.Duration
+	// ErrorHandler receives handler, receive and acknowledgement errors; msg is
+	// nil for receive errors (default: log them to Logger)
+	ErrorHandler func(msg *Message, err error)
+	// Logger logs handled messages at debug and, without an ErrorHandler,
+	// errors at error (default: logging.Noop)
+	Logger logging.Logger
+}
+
+// SQSQueue is a Queue backed by an SQS queue. Nacked messages are redelivered
+// when their visibility timeout expires, or after the RetryAfter delay, so the
+// queue's redrive policy moves repeatedly failing messages to a dead letter queue.
+type SQSQueue struct {
+	client   SQSAPI
+	queueURL string
+	opts     SQSQueueOptions
+}
+
+var _ Queue = (*SQSQueue)(nil)
+
+// NewSQSQueue creates a queue for queueURL, e.g. with EGAwsClient.GetSQSClient()
+func NewSQSQueue(client SQSAPI, queueURL string, opts *SQSQueueOptions) *SQSQueue {
+	o := SQSQueueOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 10
+	}
+	if o.WaitTime <= 0 || o.WaitTime > 20*time.Second {
+		o.WaitTime = 20 * time.Second
+	}
+	if o.Logger == nil {
+		o.Logger = logging.Noop()
+	}
+	if o.ErrorHandler == nil {
+		o.ErrorHandler = logErrors(o.Logger, "sqs queue")
+	}
+	return &SQSQueue{client: client, queueURL: queueURL, opts: o}
+}
+
+func (q *SQSQueue) Publish(ctx context.Context, msg *Message) error {
+	input := &sqs.SendMessageInput{
+		QueueUrl:          aws.String(q.queueURL),
+		MessageBody:       aws.String(string(msg.Body)),
+		MessageAttributes: map[string]types.MessageAttributeValue{},
+	}
+	for k, v := range msg.Attributes {
+		input.MessageAttributes[k] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(v)}
+	}
+	if msg.GroupID != "" {
+		input.MessageGroupId = aws.String(msg.GroupID)
+	}
+	if msg.DeduplicationID != "" {
+		input.MessageDeduplicationId = aws.String(msg.DeduplicationID)
+	}
+	out, err := q.client.SendMessage(ctx, input)
+	if err != nil {
+		return errs.Errorf("failed to send message to %s: %w", q.queueURL, err)
+	}
+	msg.ID = aws.ToString(out.MessageId)
+	return nil
+}
+
+// Subscribe long polls the queue and handles up to Concurrency messages at
+// once. Handlers in flight when ctx is done run to completion with a context
+// that is not cancelled.
+func (q *SQSQueue) Subscribe(ctx context.Context, handler Handler) error {
+	slots := make(chan struct{}, q.opts.Concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	backoff := retry.Backoff{Initial: time.Second, Max: 30 * time.Second}
+	failures := 0
+	for {
+		// wait for one free slot, then take as many more as a batch can use
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil
+		}
+		n := 1
+	fill:
+		for n < sqsMaxMessages {
+			select {
+			case slots <- struct{}{}:
+				n++
+			default:
+				break fill
+			}
+		}
+
+		msgs, err := q.receive(ctx, n)
+		for range n - len(msgs) {
+			<-slots
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			q.opts.ErrorHandler(nil, err)
+			t := time.NewTimer(backoff.Delay(failures))
+			failures++
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return nil
+			}
+			continue
+		}
+		failures = 0
+
+		for _, m := range msgs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-slots }()
+				q.process(context.WithoutCancel(ctx), handler, m)
+			}()
+		}
+	}
+}
+
+func (q *SQSQueue) receive(ctx context.Context, n int) ([]types.Message, error) {
+	input := &sqs.ReceiveMessageInput{
+		QueueUrl:                    aws.String(q.queueURL),
+		MaxNumberOfMessages:         int32(n),
+		WaitTimeSeconds:             int32(q.opts.WaitTime / time.Second),
+		MessageAttributeNames:       []string{"All"},
+		MessageSystemAttributeNames: []types.MessageSystemAttributeName{types.MessageSystemAttributeNameApproximateReceiveCount, types.MessageSystemAttributeNameMessageGroupId},
+	}
+	if q.opts.VisibilityTimeout > 0 {
+		input.VisibilityTimeout = int32(q.opts.VisibilityTimeout / time.Second)
+	}
+	out, err := q.client.ReceiveMessage(ctx, input)
+	if err != nil {
+		return nil, errs.Errorf("failed to receive messages from %s: %w", q.queueURL, err)
+	}
+	return out.Messages, nil
+}
+
+func (q *SQSQueue) process(ctx context.Context, handler Handler, m types.Message) {
+	msg := &Message{
+		ID:         aws.ToString(m.MessageId),
+		Body:       []byte(aws.ToString(m.Body)),
+		Attributes: make(map[string]string, len(m.MessageAttributes)),
+		GroupID:    m.Attributes[string(types.MessageSystemAttributeNameMessageGroupId)],
+		Attempt:    1,
+	}
+	for k, v := range m.MessageAttributes {
+		msg.Attributes[k] = aws.ToString(v.StringValue)
+	}
+	if n, err := strconv.Atoi(m.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)]); err == nil {
+		msg.Attempt = n
+	}
+
+	start := time.Now()
+	err := handle(ctx, handler, msg)
+	logging.WithTrace(ctx, q.opts.Logger).WithFields(logrus.Fields{
+		"queue":   q.queueURL,
+		"id":      msg.ID,
+		"attempt": msg.Attempt,
+		"elapsed": time.Since(start),
+		"acked":   err == nil,
+	}).Debug("handled queue message")
+
+	if err != nil {
+		q.opts.ErrorHandler(msg, err)
+		if delay, ok := retryDelay(err); ok {
+			_, visErr := q.client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+				QueueUrl:          aws.String(q.queueURL),
+				ReceiptHandle:     m.ReceiptHandle,
+				VisibilityTimeout: int32(delay / time.Second),
+			})
+			if visErr != nil {
+				q.opts.ErrorHandler(msg, errs.Errorf("failed to change message visibility: %w", visErr))
+			}
+		}
+		return
+	}
+
+	_, err = q.client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(q.queueURL),
+		ReceiptHandle: m.ReceiptHandle,
+	})
+	if err != nil {
+		q.opts.ErrorHandler(msg, errs.Errorf("failed to delete message: %w", err))
+	}
+}
@@ -0,0 +1,35 @@
+package ratelimit
+
+import (
+	"net/http"
+)
+
+// Transport waits for l before each request sent by next, keyed by keyFunc
+// (default: the request's host), so outbound calls stay under a partner's
+// rate limits. httpclient.Client takes a Limiter directly so waiting does not
+// count against its per-try timeout.
+func Transport(l Limiter, keyFunc func(*http.Request) string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if keyFunc == nil {
+		keyFunc = func(r *http.Request) string { return r.URL.Host }
+	}
+	return &transport{limiter: l, keyFunc: keyFunc, next: next}
+}
+
+type transport struct {
+	limiter Limiter
+	keyFunc func(*http.Request) string
+	next    http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := Wait(req.Context(), t.limiter, t.keyFunc(req)); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
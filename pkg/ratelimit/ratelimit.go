@@ -0,0 +1,286 @@
+// Package ratelimit limits how often something happens per key with token
+// bucket or sliding window algorithms. Limiter state lives in a Store, in
+// memory or in Redis or DynamoDB to share limits across replicas, so the same
+// limiters back the httpserver RateLimit middleware, outbound httpclient
+// calls and queue publishers.
+package ratelimit
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// ErrContention is returned when a store update keeps losing to concurrent
+// updates of the same key
+var ErrContention = errs.New(errs.Unavailable, "ratelimit: too many concurrent updates")
+
+// ErrExceedsLimit is returned by Wait when n is more than the limiter can
+// ever allow at once
+var ErrExceedsLimit = errs.New(errs.InvalidArgument, "ratelimit: n exceeds the limit")
+
+// defaultPrefix namespaces limiter keys in shared stores
+const defaultPrefix = "ratelimit:"
+
+// Result is the outcome of an AllowN call
+type Result struct {
+	Allowed bool
+	// Limit is the burst or window limit
+	Limit int
+	// Remaining is how many more events are allowed right now
+	Remaining int
+	// RetryAfter is how long until the events are allowed when not allowed
+	RetryAfter time.Duration
+	// Reset is how long until the limit is fully available again
+	Reset time.Duration
+}
+
+// Limiter decides whether events for a key are allowed
+type Limiter interface {
+	// AllowN records n events for key if they are allowed; events that are
+	// not allowed are not recorded
+	AllowN(ctx context.Context, key string, n int) (Result, error)
+}
+
+// Store holds limiter state per key
+type Store interface {
+	// Update atomically replaces key's state with the state fn returns and
+	// expires it after ttl. state is nil when key has none. fn may be called
+	// more than once when a concurrent update of key wins.
+	Update(ctx context.Context, key string, ttl time.Duration, fn func(state []byte) ([]byte, error)) error
+}
+
+// Allow is AllowN for a single event
+func Allow(ctx context.Context, l Limiter, key string) (Result, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+// Wait blocks until one event for key is allowed or ctx is done
+func Wait(ctx context.Context, l Limiter, key string) error {
+	return WaitN(ctx, l, key, 1)
+}
+
+// WaitN blocks until n events for key are allowed or ctx is done
+func WaitN(ctx context.Context, l Limiter, key string, n int) error {
+	for {
+		res, err := l.AllowN(ctx, key, n)
+		if err != nil {
+			return err
+		}
+		if res.Allowed {
+			return nil
+		}
+		if n > res.Limit {
+			return ErrExceedsLimit
+		}
+		timer := time.NewTimer(max(res.RetryAfter, time.Millisecond))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+type NewTokenBucketArgs struct {
+	// Rate is the sustained number of events per second allowed per key (required)
+	Rate float64
+	// Burst is the bucket size (default: max(1, Rate))
+	Burst int
+	// Store defaults to a new MemoryStore
+	Store Store
+	// Prefix is prepended to keys in the store (default: ratelimit:)
+	Prefix string
+	// Now is the clock (default: time.Now)
+	Now func() time.Time
+}
+
+// TokenBucket allows Burst events at once per key, refilled at Rate per
+// second, smoothing bursty traffic to the sustained rate
+type TokenBucket struct {
+	rate   float64
+	burst  int
+	store  Store
+	prefix string
+	now    func() time.Time
+}
+
+var _ Limiter = (*TokenBucket)(nil)
+
+// NewTokenBucket creates a TokenBucket
+func NewTokenBucket(args *NewTokenBucketArgs) (*TokenBucket, error) {
+	if args == nil || args.Rate <= 0 {
+		return nil, errs.New(errs.Internal, "ratelimit: Rate must be positive")
+	}
+	l := &TokenBucket{rate: args.Rate, burst: args.Burst, store: args.Store, prefix: args.Prefix, now: args.Now}
+	if l.burst <= 0 {
+		l.burst = max(1, int(math.Ceil(l.rate)))
+	}
+	if l.store == nil {
+		l.store = NewMemoryStore()
+	}
+	if l.prefix == "" {
+		l.prefix = defaultPrefix
+	}
+	if l.now == nil {
+		l.now = time.Now
+	}
+	return l, nil
+}
+
+func (l *TokenBucket) AllowN(ctx context.Context, key string, n int) (Result, error) {
+	var res Result
+	// a full bucket carries no state, so it only needs to outlive a refill
+	ttl := time.Duration(float64(l.burst)/l.rate*float64(time.Second)) + time.Second
+	err := l.store.Update(ctx, l.prefix+key, ttl, func(state []byte) ([]byte, error) {
+		now := l.now()
+		tokens, last := float64(l.burst), now
+		if len(state) == 16 {
+			tokens = math.Float64frombits(binary.BigEndian.Uint64(state))
+			last = time.Unix(0, int64(binary.BigEndian.Uint64(state[8:])))
+		}
+		if elapsed := now.Sub(last); elapsed > 0 {
+			tokens = math.Min(float64(l.burst), tokens+elapsed.Seconds()*l.rate)
+		}
+
+		res = Result{Limit: l.burst}
+		if tokens >= float64(n) {
+			tokens -= float64(n)
+			res.Allowed = true
+		} else {
+			res.RetryAfter = l.seconds(float64(n) - tokens)
+		}
+		res.Remaining = int(tokens)
+		res.Reset = l.seconds(float64(l.burst) - tokens)
+
+		next := make([]byte, 16)
+		binary.BigEndian.PutUint64(next, math.Float64bits(tokens))
+		binary.BigEndian.PutUint64(next[8:], uint64(now.UnixNano()))
+		return next, nil
+	})
+	if err != nil {
+		return Result{}, errs.Errorf("failed to rate limit %s: %w", key, err)
+	}
+	return res, nil
+}
+
+// seconds returns how long refilling tokens takes
+func (l *TokenBucket) seconds(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+type NewSlidingWindowArgs struct {
+	// Limit is the number of events allowed per key in any Window (required)
+	Limit int
+	// Window is the length of the sliding window (required)
+	Window time.Duration
+	// Store defaults to a new MemoryStore
+	Store Store
+	// Prefix is prepended to keys in the store (default: ratelimit:)
+	Prefix string
+	// Now is the clock (default: time.Now)
+	Now func() time.Time
+}
+
+// SlidingWindow allows Limit events per key in any Window. It counts events
+// in fixed windows and weights the previous window's count by how much of it
+// the sliding window still overlaps, so it avoids the double bursts fixed
+// windows allow at their boundaries in constant space per key.
+type SlidingWindow struct {
+	limit  int
+	window time.Duration
+	store  Store
+	prefix string
+	now    func() time.Time
+}
+
+var _ Limiter = (*SlidingWindow)(nil)
+
+// NewSlidingWindow creates a SlidingWindow
+func NewSlidingWindow(args *NewSlidingWindowArgs) (*SlidingWindow, error) {
+	if args == nil || args.Limit <= 0 || args.Window <= 0 {
+		return nil, errs.New(errs.Internal, "ratelimit: Limit and Window must be positive")
+	}
+	l := &SlidingWindow{limit: args.Limit, window: args.Window, store: args.Store, prefix: args.Prefix, now: args.Now}
+	if l.store == nil {
+		l.store = NewMemoryStore()
+	}
+	if l.prefix == "" {
+		l.prefix = defaultPrefix
+	}
+	if l.now == nil {
+		l.now = time.Now
+	}
+	return l, nil
+}
+
+func (l *SlidingWindow) AllowN(ctx context.Context, key string, n int) (Result, error) {
+	var res Result
+	err := l.store.Update(ctx, l.prefix+key, 2*l.window, func(state []byte) ([]byte, error) {
+		now := l.now().UnixNano()
+		window := int64(l.window)
+		start := now - now%window
+		var prev, curr int64
+		if len(state) == 24 {
+			switch stateStart := int64(binary.BigEndian.Uint64(state)); start - stateStart {
+			case 0:
+				prev, curr = int64(binary.BigEndian.Uint64(state[8:])), int64(binary.BigEndian.Uint64(state[16:]))
+			case window:
+				prev = int64(binary.BigEndian.Uint64(state[16:]))
+			}
+		}
+
+		elapsed := now - start
+		weight := 1 - float64(elapsed)/float64(window)
+		used := float64(prev)*weight + float64(curr)
+
+		res = Result{Limit: l.limit}
+		if used+float64(n) <= float64(l.limit) {
+			curr += int64(n)
+			used += float64(n)
+			res.Allowed = true
+		} else {
+			res.RetryAfter = l.retryAfter(prev, curr, n, elapsed)
+		}
+		res.Remaining = max(0, int(float64(l.limit)-used))
+		// the previous window stops counting when this one ends and this one
+		// when the next one ends
+		res.Reset = time.Duration(window - elapsed)
+		if curr > 0 {
+			res.Reset += l.window
+		}
+
+		next := make([]byte, 24)
+		binary.BigEndian.PutUint64(next, uint64(start))
+		binary.BigEndian.PutUint64(next[8:], uint64(prev))
+		binary.BigEndian.PutUint64(next[16:], uint64(curr))
+		return next, nil
+	})
+	if err != nil {
+		return Result{}, errs.Errorf("failed to rate limit %s: %w", key, err)
+	}
+	return res, nil
+}
+
+// retryAfter returns how long until the decaying weight of earlier events
+// leaves room for n more
+func (l *SlidingWindow) retryAfter(prev, curr int64, n int, elapsed int64) time.Duration {
+	window := float64(l.window)
+	free := float64(l.limit - n)
+	if float64(curr) <= free && prev > 0 {
+		// prev*(1 - t/window) + curr <= free within this window
+		t := window * (1 - (free-float64(curr))/float64(prev))
+		return time.Duration(math.Ceil(t)) - time.Duration(elapsed)
+	}
+	// this window's events become the previous window's, decaying from the
+	// start of the next one
+	wait := time.Duration(window) - time.Duration(elapsed)
+	if curr > 0 && free >= 0 {
+		wait += time.Duration(math.Ceil(window * math.Max(0, 1-free/float64(curr))))
+	}
+	return wait
+}
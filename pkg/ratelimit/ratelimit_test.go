@@ -0,0 +1,166 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestTokenBucket(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	l, err := NewTokenBucket(&NewTokenBucketArgs{Rate: 1, Burst: 2, Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 2 {
+		if res, _ := Allow(ctx, l, "k"); !res.Allowed {
+			t.Fatalf("event %d: expected allowed", i)
+		}
+	}
+	res, _ := Allow(ctx, l, "k")
+	if res.Allowed || res.RetryAfter != time.Second || res.Reset != 2*time.Second {
+		t.Fatalf("got %+v, want an empty bucket refilling in 1s", res)
+	}
+	if res, _ := Allow(ctx, l, "other"); !res.Allowed {
+		t.Fatal("expected a separate bucket per key")
+	}
+	now = now.Add(time.Second)
+	if res, _ := Allow(ctx, l, "k"); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("after refill: %+v", res)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	l, err := NewSlidingWindow(&NewSlidingWindowArgs{Limit: 10, Window: 10 * time.Second, Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res, _ := l.AllowN(ctx, "k", 10); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("got %+v", res)
+	}
+	res, _ := l.AllowN(ctx, "k", 1)
+	if res.Allowed || res.RetryAfter != 11*time.Second {
+		t.Fatalf("got %+v, want denied until the window slides past one event", res)
+	}
+
+	// halfway through the next window half of the previous window still counts
+	now = now.Add(15 * time.Second)
+	if res, _ := l.AllowN(ctx, "k", 5); !res.Allowed {
+		t.Fatalf("got %+v, want 5 events allowed", res)
+	}
+	res, _ = l.AllowN(ctx, "k", 1)
+	if res.Allowed || res.RetryAfter != time.Second {
+		t.Fatalf("got %+v, want retry once another previous event decays", res)
+	}
+}
+
+func TestWait(t *testing.T) {
+	l, _ := NewTokenBucket(&NewTokenBucketArgs{Rate: 100, Burst: 1})
+	ctx := context.Background()
+	start := time.Now()
+	for range 3 {
+		if err := Wait(ctx, l, "k"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Fatalf("3 events at 100/s took %s", elapsed)
+	}
+	if err := WaitN(ctx, l, "k", 2); !errors.Is(err, ErrExceedsLimit) {
+		t.Fatalf("got %v, want ErrExceedsLimit", err)
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := Wait(canceled, l, "k"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestSharedStores(t *testing.T) {
+	srv := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	stores := map[string]Store{
+		"redis":    NewRedisStore(client),
+		"dynamodb": NewDynamoDBStore(newFakeDynamoDB(), "limits", nil),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			// two replicas share one limit
+			a, _ := NewTokenBucket(&NewTokenBucketArgs{Rate: 0.001, Burst: 20, Store: store})
+			b, _ := NewTokenBucket(&NewTokenBucketArgs{Rate: 0.001, Burst: 20, Store: store})
+
+			var mu sync.Mutex
+			allowed := 0
+			var wg sync.WaitGroup
+			for i := range 30 {
+				l := a
+				if i%2 == 1 {
+					l = b
+				}
+				wg.Go(func() {
+					res, err := Allow(ctx, l, "k")
+					if err != nil {
+						t.Error(err)
+					}
+					if res.Allowed {
+						mu.Lock()
+						allowed++
+						mu.Unlock()
+					}
+				})
+			}
+			wg.Wait()
+			if allowed != 20 {
+				t.Fatalf("got %d allowed, want 20", allowed)
+			}
+		})
+	}
+}
+
+// fakeDynamoDB supports the GetItem and conditional PutItem calls DynamoDBStore makes
+type fakeDynamoDB struct {
+	mu    sync.Mutex
+	items map[string]map[string]types.AttributeValue
+}
+
+func newFakeDynamoDB() *fakeDynamoDB {
+	return &fakeDynamoDB{items: map[string]map[string]types.AttributeValue{}}
+}
+
+func (f *fakeDynamoDB) GetItem(_ context.Context, in *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &dynamodb.GetItemOutput{Item: f.items[in.Key["key"].(*types.AttributeValueMemberS).Value]}, nil
+}
+
+func (f *fakeDynamoDB) PutItem(_ context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := in.Item["key"].(*types.AttributeValueMemberS).Value
+	existing, ok := f.items[key]
+	switch aws.ToString(in.ConditionExpression) {
+	case "attribute_not_exists(#key)":
+		ok = !ok
+	default:
+		ok = ok && numberAttribute(existing["version"]) == numberAttribute(in.ExpressionAttributeValues[":version"])
+	}
+	if !ok {
+		return nil, &types.ConditionalCheckFailedException{Message: aws.String("version mismatch")}
+	}
+	f.items[key] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
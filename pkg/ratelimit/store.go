@@ -0,0 +1,240 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/retry"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// maxUpdateAttempts bounds optimistic update retries under contention
+const maxUpdateAttempts = 10
+
+// contentionBackoff spreads out retries of conflicting updates
+var contentionBackoff = retry.Backoff{Initial: time.Millisecond, Max: 50 * time.Millisecond}
+
+// waitToRetry sleeps before retrying a conflicting update
+func waitToRetry(ctx context.Context, attempt int) error {
+	t := time.NewTimer(contentionBackoff.Delay(attempt))
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// memorySweepInterval is how often expired keys are dropped from a MemoryStore
+const memorySweepInterval = time.Minute
+
+type memoryEntry struct {
+	state   []byte
+	expires time.Time
+}
+
+// MemoryStore is a Store local to the process
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]memoryEntry{}, now: time.Now}
+}
+
+func (s *MemoryStore) Update(_ context.Context, key string, ttl time.Duration, fn func(state []byte) ([]byte, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+	var state []byte
+	if e, ok := s.entries[key]; ok && now.Before(e.expires) {
+		state = e.state
+	}
+	next, err := fn(state)
+	if err != nil {
+		return err
+	}
+	s.entries[key] = memoryEntry{state: next, expires: now.Add(ttl)}
+	return nil
+}
+
+// sweep drops expired keys at most once per interval to bound memory
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memorySweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// RedisStore is a Store in Redis, updated with WATCH and MULTI so replicas
+// sharing the server share limits
+type RedisStore struct {
+	client goredis.UniversalClient
+}
+
+var _ Store = (*RedisStore)(nil)
+
+// NewRedisStore creates a store using client, e.g. a redis.Client
+func NewRedisStore(client goredis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Update(ctx context.Context, key string, ttl time.Duration, fn func(state []byte) ([]byte, error)) error {
+	update := func(tx *goredis.Tx) error {
+		state, err := tx.Get(ctx, key).Bytes()
+		if err != nil && !errors.Is(err, goredis.Nil) {
+			return err
+		}
+		next, err := fn(state)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(p goredis.Pipeliner) error {
+			p.Set(ctx, key, next, ttl)
+			return nil
+		})
+		return err
+	}
+	for attempt := range maxUpdateAttempts {
+		err := s.client.Watch(ctx, update, key)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, goredis.TxFailedErr) {
+			return errs.Errorf("failed to update %s in redis: %w", key, err)
+		}
+		if err := waitToRetry(ctx, attempt); err != nil {
+			return err
+		}
+	}
+	return ErrContention
+}
+
+// DynamoDBAPI is the subset of the DynamoDB client used by DynamoDBStore
+type DynamoDBAPI interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+}
+
+type DynamoDBStoreOptions struct {
+	// KeyAttribute is the table's partition key, a string (default: key)
+	KeyAttribute string
+}
+
+// DynamoDBStore is a Store in a DynamoDB table. Items are updated with a
+// version condition and keep their expiry in the ttl attribute; enable the
+// table's TTL on it to remove idle keys.
+type DynamoDBStore struct {
+	client DynamoDBAPI
+	table  string
+	opts   DynamoDBStoreOptions
+	now    func() time.Time
+}
+
+var _ Store = (*DynamoDBStore)(nil)
+
+// NewDynamoDBStore creates a store for items in table, e.g. with
+// EGAwsClient.GetDynamoDBClient()
+func NewDynamoDBStore(client DynamoDBAPI, table string, opts *DynamoDBStoreOptions) *DynamoDBStore {
+	o := DynamoDBStoreOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.KeyAttribute == "" {
+		o.KeyAttribute = "key"
+	}
+	return &DynamoDBStore{client: client, table: table, opts: o, now: time.Now}
+}
+
+func (s *DynamoDBStore) Update(ctx context.Context, key string, ttl time.Duration, fn func(state []byte) ([]byte, error)) error {
+	itemKey := map[string]types.AttributeValue{s.opts.KeyAttribute: &types.AttributeValueMemberS{Value: key}}
+	for attempt := range maxUpdateAttempts {
+		out, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName:      aws.String(s.table),
+			Key:            itemKey,
+			ConsistentRead: aws.Bool(true),
+		})
+		if err != nil {
+			return errs.Errorf("failed to get %s from dynamodb: %w", key, err)
+		}
+
+		now := s.now()
+		var state []byte
+		version := int64(-1)
+		if out.Item != nil {
+			version = numberAttribute(out.Item["version"])
+			// TTL deletion lags, so expired items are treated as absent
+			if b, ok := out.Item["state"].(*types.AttributeValueMemberB); ok && numberAttribute(out.Item["ttl"]) > now.Unix() {
+				state = b.Value
+			}
+		}
+		next, err := fn(state)
+		if err != nil {
+			return err
+		}
+
+		input := &dynamodb.PutItemInput{
+			TableName: aws.String(s.table),
+			Item: map[string]types.AttributeValue{
+				s.opts.KeyAttribute: itemKey[s.opts.KeyAttribute],
+				"state":             &types.AttributeValueMemberB{Value: next},
+				"version":           &types.AttributeValueMemberN{Value: strconv.FormatInt(version+1, 10)},
+				"ttl":               &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(ttl+time.Second-1).Unix(), 10)},
+			},
+			ExpressionAttributeNames: map[string]string{"#key": s.opts.KeyAttribute},
+		}
+		if version < 0 {
+			input.ConditionExpression = aws.String("attribute_not_exists(#key)")
+		} else {
+			input.ConditionExpression = aws.String("#version = :version")
+			input.ExpressionAttributeNames = map[string]string{"#version": "version"}
+			input.ExpressionAttributeValues = map[string]types.AttributeValue{
+				":version": &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)},
+			}
+		}
+		_, err = s.client.PutItem(ctx, input)
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			if err := waitToRetry(ctx, attempt); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return errs.Errorf("failed to put %s to dynamodb: %w", key, err)
+		}
+		return nil
+	}
+	return ErrContention
+}
+
+// numberAttribute returns the value of a numeric attribute, or 0
+func numberAttribute(v types.AttributeValue) int64 {
+	n, ok := v.(*types.AttributeValueMemberN)
+	if !ok {
+		return 0
+	}
+	i, _ := strconv.ParseInt(n.Value, 10, 64)
+	return i
+}
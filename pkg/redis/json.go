@@ -0,0 +1,39 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// GetJSON reads key and unmarshals it into T. found is false when the key
+// does not exist.
+func GetJSON[T any](ctx context.Context, c goredis.Cmdable, key string) (value T, found bool, err error) {
+	b, err := c.Get(ctx, key).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return value, false, nil
+	}
+	if err != nil {
+		return value, false, errs.Errorf("failed to get %s: %w", key, err)
+	}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return value, false, errs.Errorf("failed to unmarshal %s: %w", key, err)
+	}
+	return value, true, nil
+}
+
+// SetJSON marshals value and stores it at key, expiring after ttl (0 keeps it forever)
+func SetJSON(ctx context.Context, c goredis.Cmdable, key string, value any, ttl time.Duration) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return errs.Errorf("failed to marshal %s: %w", key, err)
+	}
+	if err := c.Set(ctx, key, b, ttl).Err(); err != nil {
+		return errs.Errorf("failed to set %s: %w", key, err)
+	}
+	return nil
+}
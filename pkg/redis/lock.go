@@ -0,0 +1,87 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// ErrLockHeld is returned by TryLock when the key is locked by another holder
+var ErrLockHeld = errs.New(errs.Conflict, "lock is held by another owner")
+
+// ErrLockNotHeld is returned by Extend and Release when the lock expired and
+// may have been taken by another holder
+var ErrLockNotHeld = errs.New(errs.Conflict, "lock is no longer held")
+
+// compare-and-delete and compare-and-extend, so a holder never touches a lock
+// that expired and was taken by someone else
+var (
+	releaseScript = goredis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+	extendScript = goredis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+// Lock is a TTL lock held in a single Redis key. It is not renewed
+// automatically; call Extend before the TTL runs out for long work.
+type Lock struct {
+	client goredis.Scripter
+	key    string
+	token  string
+}
+
+// TryLock sets key with a random token if it does not exist, returning
+// ErrLockHeld when it does
+func TryLock(ctx context.Context, c goredis.UniversalClient, key string, ttl time.Duration) (*Lock, error) {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	token := hex.EncodeToString(b)
+
+	ok, err := c.SetNX(ctx, key, token, ttl).Result()
+	if err != nil {
+		return nil, errs.Errorf("failed to lock %s: %w", key, err)
+	}
+	if !ok {
+		return nil, ErrLockHeld
+	}
+	return &Lock{client: c, key: key, token: token}, nil
+}
+
+// Key returns the locked key
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Extend resets the lock's TTL if it is still held
+func (l *Lock) Extend(ctx context.Context, ttl time.Duration) error {
+	n, err := extendScript.Run(ctx, l.client, []string{l.key}, l.token, ttl.Milliseconds()).Int()
+	if err != nil {
+		return errs.Errorf("failed to extend lock %s: %w", l.key, err)
+	}
+	if n == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Release deletes the lock if it is still held
+func (l *Lock) Release(ctx context.Context) error {
+	n, err := releaseScript.Run(ctx, l.client, []string{l.key}, l.token).Int()
+	if err != nil {
+		return errs.Errorf("failed to release lock %s: %w", l.key, err)
+	}
+	if n == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
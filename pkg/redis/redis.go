@@ -0,0 +1,159 @@
+// Package redis builds go-redis clients with TLS, credentials from Secrets
+// Manager, health checks and logging, plus typed JSON helpers and TTL locks.
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/bdlilley/easygo"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+type NewClientArgs struct {
+	// Addr is host:port (required)
+	Addr     string
+	Username string
+	Password string
+	// PasswordSecret names a Secrets Manager secret holding the password,
+	// either as a plain string or as JSON with "password" and optional
+	// "username" keys, e.g. an ElastiCache RBAC user secret. Requires Secrets.
+	PasswordSecret string
+	Secrets        easygo.SecretsReader
+	DB             int
+
+	// TLS enables TLS 1.2+ with system roots, as required by ElastiCache in-transit encryption
+	TLS bool
+	// TLSConfig is the TLS configuration; setting it enables TLS
+	TLSConfig *tls.Config
+
+	// Pool sizing and timeouts; zero uses the go-redis defaults
+	PoolSize     int
+	MinIdleConns int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// Logger logs failed commands at debug and connection errors at warn (default: logging.Noop)
+	Logger logging.Logger
+}
+
+// Client is a go-redis client
+type Client struct {
+	*goredis.Client
+}
+
+var _ easygo.HealthChecker = (*Client)(nil)
+
+// NewClient creates a client; connections are opened lazily
+func NewClient(ctx context.Context, args *NewClientArgs) (*Client, error) {
+	if args == nil || args.Addr == "" {
+		return nil, errs.New(errs.Internal, "Addr is required")
+	}
+	logger := args.Logger
+	if logger == nil {
+		logger = logging.Noop()
+	}
+
+	username, password := args.Username, args.Password
+	if args.PasswordSecret != "" {
+		if args.Secrets == nil {
+			return nil, errs.New(errs.Internal, "PasswordSecret requires Secrets")
+		}
+		value, err := args.Secrets.GetLatestSecretString(ctx, args.PasswordSecret)
+		if err != nil {
+			return nil, errs.Errorf("failed to read redis password from %s: %w", args.PasswordSecret, err)
+		}
+		username, password = parseCredentials(value, username)
+	}
+
+	opts := &goredis.Options{
+		Addr:         args.Addr,
+		Username:     username,
+		Password:     password,
+		DB:           args.DB,
+		PoolSize:     args.PoolSize,
+		MinIdleConns: args.MinIdleConns,
+		DialTimeout:  args.DialTimeout,
+		ReadTimeout:  args.ReadTimeout,
+		WriteTimeout: args.WriteTimeout,
+	}
+	if args.TLSConfig != nil {
+		opts.TLSConfig = args.TLSConfig.Clone()
+	} else if args.TLS {
+		opts.TLSConfig = &tls.Config{}
+	}
+	if opts.TLSConfig != nil && opts.TLSConfig.MinVersion == 0 {
+		opts.TLSConfig.MinVersion = tls.VersionTLS12
+	}
+
+	client := goredis.NewClient(opts)
+	client.AddHook(&loggingHook{logger: logger})
+	return &Client{Client: client}, nil
+}
+
+// parseCredentials reads a secret holding either a plain password or JSON
+// with password and username keys
+func parseCredentials(value, username string) (string, string) {
+	var creds struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.Unmarshal([]byte(value), &creds); err != nil || creds.Password == "" {
+		return username, value
+	}
+	if creds.Username != "" {
+		username = creds.Username
+	}
+	return username, creds.Password
+}
+
+// HealthCheck pings the server. It can be registered directly with
+// httpserver's AddReadinessCheck.
+func (c *Client) HealthCheck(ctx context.Context) error {
+	if err := c.Ping(ctx).Err(); err != nil {
+		return errs.Errorf("redis ping failed: %w", err)
+	}
+	return nil
+}
+
+// loggingHook logs dial failures and failed commands; missing keys are not failures
+type loggingHook struct {
+	logger logging.Logger
+}
+
+func (h *loggingHook) DialHook(next goredis.DialHook) goredis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			logging.WithTrace(ctx, h.logger).WithError(err).WithField("addr", addr).Warn("redis dial failed")
+		}
+		return conn, err
+	}
+}
+
+func (h *loggingHook) ProcessHook(next goredis.ProcessHook) goredis.ProcessHook {
+	return func(ctx context.Context, cmd goredis.Cmder) error {
+		err := next(ctx, cmd)
+		if err != nil && !errors.Is(err, goredis.Nil) {
+			logging.WithTrace(ctx, h.logger).WithError(err).WithField("command", cmd.Name()).Debug("redis command failed")
+		}
+		return err
+	}
+}
+
+func (h *loggingHook) ProcessPipelineHook(next goredis.ProcessPipelineHook) goredis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []goredis.Cmder) error {
+		err := next(ctx, cmds)
+		if err != nil && !errors.Is(err, goredis.Nil) {
+			logging.WithTrace(ctx, h.logger).WithError(err).WithField("commands", len(cmds)).Debug("redis pipeline failed")
+		}
+		return err
+	}
+}
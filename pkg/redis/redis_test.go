@@ -0,0 +1,80 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bdlilley/easygo/pkg/egaws/egawstest"
+)
+
+func newTestClient(t *testing.T) (*Client, *miniredis.Miniredis) {
+	t.Helper()
+	srv := miniredis.RunT(t)
+	srv.RequireAuth("s3cret")
+
+	secrets := egawstest.NewSecretsStore()
+	if err := secrets.SetJSONSecret("redis", map[string]string{"password": "s3cret"}); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(context.Background(), &NewClientArgs{Addr: srv.Addr(), PasswordSecret: "redis", Secrets: secrets})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c, srv
+}
+
+func TestJSON(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestClient(t)
+	if err := c.HealthCheck(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	type user struct{ Name string }
+	if _, found, err := GetJSON[user](ctx, c, "user:1"); err != nil || found {
+		t.Fatalf("expected missing key, got found=%v err=%v", found, err)
+	}
+	if err := SetJSON(ctx, c, "user:1", user{Name: "ada"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	got, found, err := GetJSON[user](ctx, c, "user:1")
+	if err != nil || !found || got.Name != "ada" {
+		t.Fatalf("unexpected result %+v found=%v err=%v", got, found, err)
+	}
+}
+
+func TestLock(t *testing.T) {
+	ctx := context.Background()
+	c, srv := newTestClient(t)
+
+	lock, err := TryLock(ctx, c, "lock:job", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := TryLock(ctx, c, "lock:job", time.Second); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("expected ErrLockHeld, got %v", err)
+	}
+	if err := lock.Extend(ctx, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// an expired lock taken by another holder is not released by the first
+	lock, err = TryLock(ctx, c, "lock:job", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.FastForward(2 * time.Second)
+	if _, err := TryLock(ctx, c, "lock:job", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Release(ctx); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("expected ErrLockNotHeld, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package requestmeta
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// OutgoingContext returns a copy of ctx whose outgoing gRPC metadata carries
+// the metadata in ctx, keeping keys already set
+func OutgoingContext(ctx context.Context) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	FromContext(ctx).each(func(name, value string) {
+		key := strings.ToLower(name)
+		if len(md.Get(key)) == 0 {
+			md.Set(key, value)
+		}
+	})
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// FromMetadata restores the metadata in incoming gRPC metadata
+func FromMetadata(md metadata.MD) Meta {
+	names := make([]string, 0, len(md))
+	for name := range md {
+		names = append(names, name)
+	}
+	return restore(func(name string) string {
+		if v := md.Get(name); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}, names)
+}
+
+// incomingContext stores the metadata of an incoming call in ctx
+func incomingContext(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return WithMeta(ctx, FromMetadata(md))
+}
+
+// UnaryClientInterceptor propagates the metadata of each call's context
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(OutgoingContext(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor propagates the metadata of each stream's context
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(OutgoingContext(ctx), desc, cc, method, opts...)
+	}
+}
+
+// UnaryServerInterceptor restores the metadata of incoming calls; add it to
+// grpcserver.NewEasyGoGRPCServerArgs.UnaryInterceptors
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(incomingContext(ctx), req)
+	}
+}
+
+// StreamServerInterceptor restores the metadata of incoming streams
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &contextStream{ServerStream: ss, ctx: incomingContext(ss.Context())})
+	}
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
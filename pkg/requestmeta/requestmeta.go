@@ -0,0 +1,225 @@
+// Package requestmeta carries request-scoped metadata, such as the request
+// ID, user, tenant and locale, through contexts and across service calls. It
+// serializes the metadata to HTTP headers and gRPC metadata on outbound calls
+// (httpclient and grpcclient do so with PropagateMeta) and restores it with
+// server middleware and interceptors, so services need no header plumbing.
+//
+// Restored values come from the caller, so only restore them from trusted
+// internal callers; at the edge, set them from authenticated state instead.
+package requestmeta
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/sirupsen/logrus"
+)
+
+// Header names the metadata is propagated as. gRPC uses the lowercase names.
+const (
+	RequestIDHeader = "X-Request-ID"
+	UserIDHeader    = "X-User-ID"
+	TenantHeader    = "X-Tenant-ID"
+	LocaleHeader    = "X-Locale"
+	// BaggagePrefix prefixes a header per Baggage entry
+	BaggagePrefix = "X-Meta-"
+)
+
+const (
+	// maxValueLength bounds restored values so callers cannot bloat contexts and logs
+	maxValueLength = 256
+	// maxBaggage bounds the number of restored Baggage entries
+	maxBaggage = 16
+)
+
+// Meta is the metadata of a request
+type Meta struct {
+	RequestID string
+	UserID    string
+	Tenant    string
+	// Locale is a BCP 47 language tag, e.g. en-US
+	Locale string
+	// Baggage carries other values; keys are lowercase
+	Baggage map[string]string
+}
+
+type metaKey struct{}
+
+// WithMeta returns a copy of ctx carrying m
+func WithMeta(ctx context.Context, m Meta) context.Context {
+	return context.WithValue(ctx, metaKey{}, m)
+}
+
+// FromContext returns the metadata stored by WithMeta. Outside a request it
+// is empty, except that the request ID of chi's RequestID middleware (which
+// httpserver also sets) is used when none was stored.
+func FromContext(ctx context.Context) Meta {
+	m, _ := ctx.Value(metaKey{}).(Meta)
+	if m.RequestID == "" {
+		m.RequestID = middleware.GetReqID(ctx)
+	}
+	return m
+}
+
+// Update returns a copy of ctx with the metadata in ctx changed by fn
+func Update(ctx context.Context, fn func(m *Meta)) context.Context {
+	m := FromContext(ctx)
+	if m.Baggage != nil {
+		baggage := make(map[string]string, len(m.Baggage))
+		for k, v := range m.Baggage {
+			baggage[k] = v
+		}
+		m.Baggage = baggage
+	}
+	fn(&m)
+	return WithMeta(ctx, m)
+}
+
+// Fields returns the non-empty values as log fields
+func (m Meta) Fields() logrus.Fields {
+	fields := logrus.Fields{}
+	for k, v := range map[string]string{"request_id": m.RequestID, "user_id": m.UserID, "tenant_id": m.Tenant, "locale": m.Locale} {
+		if v != "" {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+// each calls fn with the header name and value of every non-empty value
+func (m Meta) each(fn func(name, value string)) {
+	for _, kv := range [...][2]string{
+		{RequestIDHeader, m.RequestID},
+		{UserIDHeader, m.UserID},
+		{TenantHeader, m.Tenant},
+		{LocaleHeader, m.Locale},
+	} {
+		if kv[1] != "" {
+			fn(kv[0], kv[1])
+		}
+	}
+	for k, v := range m.Baggage {
+		if v != "" {
+			fn(BaggagePrefix+k, v)
+		}
+	}
+}
+
+// restore builds Meta from get, which returns the value of a header name, and
+// the headers with BaggagePrefix in names. Invalid values are dropped.
+func restore(get func(name string) string, names []string) Meta {
+	value := func(name string) string {
+		v := strings.TrimSpace(get(name))
+		if !validValue(v) {
+			return ""
+		}
+		return v
+	}
+	m := Meta{
+		RequestID: value(RequestIDHeader),
+		UserID:    value(UserIDHeader),
+		Tenant:    value(TenantHeader),
+		Locale:    value(LocaleHeader),
+	}
+	for _, name := range names {
+		key, ok := cutPrefixFold(name, BaggagePrefix)
+		if !ok || key == "" || len(m.Baggage) >= maxBaggage {
+			continue
+		}
+		if v := value(name); v != "" {
+			if m.Baggage == nil {
+				m.Baggage = map[string]string{}
+			}
+			m.Baggage[strings.ToLower(key)] = v
+		}
+	}
+	return m
+}
+
+func validValue(v string) bool {
+	if len(v) > maxValueLength {
+		return false
+	}
+	for i := 0; i < len(v); i++ {
+		if v[i] < 0x20 || v[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
+
+// InjectHeader sets m's values in h, keeping headers h already has
+func (m Meta) InjectHeader(h http.Header) {
+	m.each(func(name, value string) {
+		if h.Get(name) == "" {
+			h.Set(name, value)
+		}
+	})
+}
+
+// FromHeader restores the metadata in h. Without an X-Locale header the first
+// Accept-Language tag is used.
+func FromHeader(h http.Header) Meta {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	m := restore(h.Get, names)
+	if m.Locale == "" {
+		m.Locale = acceptLanguage(h.Get("Accept-Language"))
+	}
+	return m
+}
+
+// acceptLanguage returns the first tag of an Accept-Language header
+func acceptLanguage(v string) string {
+	tag, _, _ := strings.Cut(v, ",")
+	tag, _, _ = strings.Cut(tag, ";")
+	tag = strings.TrimSpace(tag)
+	if tag == "*" || !validValue(tag) {
+		return ""
+	}
+	return tag
+}
+
+// Middleware restores the metadata of incoming requests into their context.
+// A request ID assigned by earlier middleware, such as httpserver's, wins
+// over the header.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := FromHeader(r.Header)
+		if id := middleware.GetReqID(r.Context()); id != "" {
+			m.RequestID = id
+		}
+		next.ServeHTTP(w, r.WithContext(WithMeta(r.Context(), m)))
+	})
+}
+
+// Transport sets the metadata of each request's context as headers before
+// sending it with next (default: http.DefaultTransport)
+func Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		m := FromContext(req.Context())
+		req = req.Clone(req.Context())
+		m.InjectHeader(req.Header)
+		return next.RoundTrip(req)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
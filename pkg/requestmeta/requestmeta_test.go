@@ -0,0 +1,81 @@
+package requestmeta
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestHTTPRoundTrip(t *testing.T) {
+	want := Meta{RequestID: "req-1", UserID: "u-42", Tenant: "acme", Locale: "de-DE", Baggage: map[string]string{"plan": "pro"}}
+
+	var got Meta
+	server := httptest.NewServer(Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+	})))
+	defer server.Close()
+
+	client := &http.Client{Transport: Transport(nil)}
+	req, _ := http.NewRequestWithContext(WithMeta(context.Background(), want), http.MethodGet, server.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestFromHeader(t *testing.T) {
+	h := http.Header{}
+	h.Set("Accept-Language", "fr-CA,fr;q=0.9")
+	h.Set(UserIDHeader, strings.Repeat("x", maxValueLength+1))
+	h.Set(TenantHeader, "bad\nvalue")
+	if m := FromHeader(h); m.Locale != "fr-CA" || m.UserID != "" || m.Tenant != "" {
+		t.Fatalf("got %+v", m)
+	}
+
+	// a request ID assigned by earlier middleware wins over the header
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(RequestIDHeader, "from-client")
+	r = r.WithContext(context.WithValue(r.Context(), middleware.RequestIDKey, "assigned"))
+	var id string
+	Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = FromContext(r.Context()).RequestID
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	if id != "assigned" {
+		t.Fatalf("got request ID %q", id)
+	}
+}
+
+func TestGRPCRoundTrip(t *testing.T) {
+	want := Meta{RequestID: "req-1", UserID: "u-42", Baggage: map[string]string{"plan": "pro"}}
+	ctx := metadata.AppendToOutgoingContext(WithMeta(context.Background(), want), "x-user-id", "explicit")
+
+	var outgoing metadata.MD
+	err := UnaryClientInterceptor()(ctx, "/svc/M", nil, nil, nil, func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got Meta
+	_, _ = UnaryServerInterceptor()(metadata.NewIncomingContext(context.Background(), outgoing), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ any) (any, error) {
+		got = FromContext(ctx)
+		return nil, nil
+	})
+	want.UserID = "explicit"
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
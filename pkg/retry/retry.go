@@ -0,0 +1,158 @@
+// Package retry calls functions until they succeed, waiting with exponential
+// backoff and jitter between attempts.
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/clock"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// Backoff computes exponential delays with equal jitter: each delay is
+// between half and all of min(Initial * Multiplier^attempt, Max)
+type Backoff struct {
+	// Initial is the delay before the first retry (default: 100ms)
+	Initial time.Duration
+	// Max caps the delay (default: 10s)
+	Max time.Duration
+	// Multiplier grows the delay per attempt (default: 2)
+	Multiplier float64
+	// NoJitter uses the exact exponential delay
+	NoJitter bool
+}
+
+// Delay returns the wait before retry number attempt, starting at 0
+func (b Backoff) Delay(attempt int) time.Duration {
+	initial, maxDelay, mult := b.Initial, b.Max, b.Multiplier
+	if initial <= 0 {
+		initial = 100 * time.Millisecond
+	}
+	if maxDelay <= 0 {
+		maxDelay = 10 * time.Second
+	}
+	if mult < 1 {
+		mult = 2
+	}
+
+	d := float64(initial)
+	for i := 0; i < attempt && d < float64(maxDelay); i++ {
+		d *= mult
+	}
+	delay := min(time.Duration(d), maxDelay)
+	if b.NoJitter || delay <= 1 {
+		return delay
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+type Options struct {
+	// MaxAttempts is the total number of calls, including the first (default: 3)
+	MaxAttempts int
+	// MaxElapsed stops retrying once this much time has passed since the first
+	// call (default: no limit)
+	MaxElapsed time.Duration
+	Backoff    Backoff
+	// Retryable classifies errors (default: everything except context errors
+	// and errors wrapped with Permanent), e.g. egaws/errors.IsRetryable
+	Retryable func(error) bool
+	// Logger logs each failed attempt at debug (default: logging.Noop)
+	Logger logging.Logger
+	// Operation names the call in logs and errors
+	Operation string
+	// Clock times the backoff and MaxElapsed (default: clock.Real)
+	Clock clock.Clock
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so Do returns it without retrying
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was wrapped with Permanent
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, or the
+// attempts, elapsed time or ctx run out. The last error is returned wrapped
+// with the attempt count; errors wrapped with Permanent are unwrapped.
+func Do[T any](ctx context.Context, fn func(ctx context.Context) (T, error), opts *Options) (T, error) {
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 3
+	}
+	if o.Logger == nil {
+		o.Logger = logging.Noop()
+	}
+	if o.Operation == "" {
+		o.Operation = "operation"
+	}
+	if o.Clock == nil {
+		o.Clock = clock.Real()
+	}
+
+	start := o.Clock.Now()
+	for attempt := 1; ; attempt++ {
+		result, err := fn(ctx)
+		if err == nil {
+			return result, nil
+		}
+
+		var p *permanentError
+		if errors.As(err, &p) {
+			return result, p.err
+		}
+		retryable := ctx.Err() == nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+		if retryable && o.Retryable != nil {
+			retryable = o.Retryable(err)
+		}
+		if !retryable {
+			return result, err
+		}
+
+		delay := o.Backoff.Delay(attempt - 1)
+		exhausted := attempt >= o.MaxAttempts || (o.MaxElapsed > 0 && o.Clock.Since(start)+delay > o.MaxElapsed)
+		logging.WithTrace(ctx, o.Logger).WithFields(logrus.Fields{
+			"operation": o.Operation,
+			"attempt":   attempt,
+			"error":     err.Error(),
+		}).Debug("attempt failed")
+		if exhausted {
+			return result, errs.Errorf("%s failed after %d attempts: %w", o.Operation, attempt, err)
+		}
+
+		select {
+		case <-o.Clock.After(delay):
+		case <-ctx.Done():
+			return result, errs.Errorf("%s canceled after %d attempts: %w", o.Operation, attempt, err)
+		}
+	}
+}
+
+// Run is Do for functions without a result
+func Run(ctx context.Context, fn func(ctx context.Context) error, opts *Options) error {
+	_, err := Do(ctx, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	}, opts)
+	return err
+}
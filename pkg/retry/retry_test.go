@@ -0,0 +1,76 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/clock"
+)
+
+func TestDo(t *testing.T) {
+	ctx := context.Background()
+	opts := &Options{MaxAttempts: 4, Backoff: Backoff{Initial: time.Millisecond}}
+
+	calls := 0
+	got, err := Do(ctx, func(context.Context) (int, error) {
+		calls++
+		if calls < 3 {
+			return 0, errors.New("flaky")
+		}
+		return 42, nil
+	}, opts)
+	if err != nil || got != 42 || calls != 3 {
+		t.Fatalf("got %d, %v after %d calls", got, err, calls)
+	}
+
+	calls = 0
+	errBad := errors.New("bad request")
+	err = Run(ctx, func(context.Context) error {
+		calls++
+		return Permanent(errBad)
+	}, opts)
+	if err != errBad || calls != 1 {
+		t.Fatalf("err = %v after %d calls, want unwrapped permanent error after 1", err, calls)
+	}
+
+	calls = 0
+	err = Run(ctx, func(context.Context) error {
+		calls++
+		return errors.New("down")
+	}, opts)
+	if err == nil || calls != 4 {
+		t.Fatalf("err = %v after %d calls, want failure after 4", err, calls)
+	}
+}
+
+func TestMaxElapsed(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	opts := &Options{MaxAttempts: 10, MaxElapsed: 5 * time.Minute, Backoff: Backoff{Initial: time.Minute, NoJitter: true, Multiplier: 1}, Clock: clk}
+	calls := 0
+	errc := make(chan error, 1)
+	go func() {
+		errc <- Run(context.Background(), func(context.Context) error {
+			calls++
+			return errors.New("down")
+		}, opts)
+	}()
+	// calls at 0 to 5 minutes; the sixth would wait past MaxElapsed
+	for range 5 {
+		clk.BlockUntil(1)
+		clk.Advance(time.Minute)
+	}
+	if err := <-errc; err == nil || calls != 6 {
+		t.Fatalf("err = %v after %d calls, want failure after 6", err, calls)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Initial: 100 * time.Millisecond, Max: time.Second, NoJitter: true}
+	for attempt, want := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		if got := b.Delay(attempt); got != want {
+			t.Errorf("Delay(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
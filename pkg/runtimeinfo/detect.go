@@ -0,0 +1,203 @@
+package runtimeinfo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// identityAPI is the part of the EC2 instance metadata client used to read
+// the instance identity document
+type identityAPI interface {
+	GetInstanceIdentityDocument(ctx context.Context, params *imds.GetInstanceIdentityDocumentInput, optFns ...func(*imds.Options)) (*imds.GetInstanceIdentityDocumentOutput, error)
+}
+
+// files checked for an EC2 hypervisor before calling the metadata service,
+// which would otherwise time out on machines outside EC2
+var ec2Markers = []string{
+	"/sys/class/dmi/id/board_vendor",
+	"/sys/class/dmi/id/sys_vendor",
+	"/sys/hypervisor/uuid",
+}
+
+const serviceAccountNamespace = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+var (
+	containerIDPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+	// mountinfo names the container in the runtime's paths for the
+	// container's hostname and resolv.conf, which also works on cgroup v2
+	mountinfoIDPattern = regexp.MustCompile(`/(?:containers|sandboxes)/([0-9a-f]{64})/`)
+)
+
+type detector struct {
+	getenv   func(string) string
+	readFile func(string) ([]byte, error)
+	client   *http.Client
+	identity identityAPI
+}
+
+func newDetector() *detector {
+	return &detector{
+		getenv:   os.Getenv,
+		readFile: os.ReadFile,
+		client:   &http.Client{Timeout: time.Second},
+		identity: imds.New(imds.Options{Retryer: aws.NopRetryer{}}),
+	}
+}
+
+func (d *detector) detect(ctx context.Context) Info {
+	info := Info{Platform: PlatformLocal}
+	switch {
+	case d.getenv("AWS_LAMBDA_FUNCTION_NAME") != "":
+		info.Platform = PlatformLambda
+		info.FunctionName = d.getenv("AWS_LAMBDA_FUNCTION_NAME")
+		info.FunctionVersion = d.getenv("AWS_LAMBDA_FUNCTION_VERSION")
+	case d.getenv("ECS_CONTAINER_METADATA_URI_V4") != "":
+		// metadata errors leave the platform set from the environment
+		info.Platform = PlatformECS
+		_ = d.ecs(ctx, d.getenv("ECS_CONTAINER_METADATA_URI_V4"), &info)
+	case d.getenv("KUBERNETES_SERVICE_HOST") != "":
+		info.Platform = PlatformKubernetes
+		d.kubernetes(&info)
+		if d.onEC2() && d.ec2(ctx, &info) == nil {
+			info.Platform = PlatformEKS
+		}
+	case d.onEC2():
+		if d.ec2(ctx, &info) == nil {
+			info.Platform = PlatformEC2
+		}
+	}
+	if info.Region == "" {
+		info.Region = d.getenv("AWS_REGION")
+	}
+	if info.Region == "" {
+		info.Region = d.getenv("AWS_DEFAULT_REGION")
+	}
+	return info
+}
+
+// ecsTask and ecsContainer are the fields read from the task metadata
+// endpoint v4
+type ecsTask struct {
+	Cluster          string
+	TaskARN          string
+	Family           string
+	AvailabilityZone string
+}
+
+type ecsContainer struct {
+	DockerID string `json:"DockerId"`
+}
+
+func (d *detector) ecs(ctx context.Context, uri string, info *Info) error {
+	var container ecsContainer
+	if err := d.getJSON(ctx, uri, &container); err != nil {
+		return err
+	}
+	info.ContainerID = container.DockerID
+
+	var task ecsTask
+	if err := d.getJSON(ctx, uri+"/task", &task); err != nil {
+		return err
+	}
+	info.Cluster = task.Cluster
+	info.TaskARN = task.TaskARN
+	info.TaskFamily = task.Family
+	info.AvailabilityZone = task.AvailabilityZone
+	if a, err := arn.Parse(task.TaskARN); err == nil {
+		info.Region = a.Region
+		info.AccountID = a.AccountID
+	}
+	return nil
+}
+
+func (d *detector) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return errs.Errorf("failed to create metadata request: %w", err)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return errs.Errorf("failed to get %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errs.Newf(errs.Internal, "got status %d from %s", resp.StatusCode, url)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return errs.Errorf("failed to decode %s: %w", url, err)
+	}
+	return nil
+}
+
+func (d *detector) kubernetes(info *Info) {
+	info.PodName = d.getenv("POD_NAME")
+	if info.PodName == "" {
+		info.PodName = d.getenv("HOSTNAME")
+	}
+	info.Namespace = d.getenv("POD_NAMESPACE")
+	if info.Namespace == "" {
+		if b, err := d.readFile(serviceAccountNamespace); err == nil {
+			info.Namespace = strings.TrimSpace(string(b))
+		}
+	}
+	info.NodeName = d.getenv("NODE_NAME")
+	info.ContainerID = d.containerID()
+}
+
+// containerID reads the container's ID from its cgroup, or from mountinfo
+// when the cgroup is namespaced (cgroup v2)
+func (d *detector) containerID() string {
+	if b, err := d.readFile("/proc/self/cgroup"); err == nil {
+		if ids := containerIDPattern.FindAll(b, -1); len(ids) > 0 {
+			return string(ids[len(ids)-1])
+		}
+	}
+	if b, err := d.readFile("/proc/self/mountinfo"); err == nil {
+		if m := mountinfoIDPattern.FindSubmatch(b); m != nil {
+			return string(m[1])
+		}
+	}
+	return ""
+}
+
+func (d *detector) onEC2() bool {
+	if strings.EqualFold(d.getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		return false
+	}
+	for _, path := range ec2Markers {
+		b, err := d.readFile(path)
+		if err != nil {
+			continue
+		}
+		b = bytes.ToLower(bytes.TrimSpace(b))
+		if bytes.HasPrefix(b, []byte("amazon ec2")) || bytes.HasPrefix(b, []byte("ec2")) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *detector) ec2(ctx context.Context, info *Info) error {
+	out, err := d.identity.GetInstanceIdentityDocument(ctx, &imds.GetInstanceIdentityDocumentInput{})
+	if err != nil {
+		return errs.Errorf("failed to get the instance identity document: %w", err)
+	}
+	doc := out.InstanceIdentityDocument
+	info.Region = doc.Region
+	info.AvailabilityZone = doc.AvailabilityZone
+	info.AccountID = doc.AccountID
+	info.InstanceID = doc.InstanceID
+	info.InstanceType = doc.InstanceType
+	return nil
+}
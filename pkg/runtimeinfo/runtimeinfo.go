@@ -0,0 +1,178 @@
+// Package runtimeinfo detects where the process runs (EC2, ECS, EKS or other
+// Kubernetes, Lambda) and reports the instance, task or pod it runs in, so
+// logs, metrics and traces can be tagged with it and AWS clients can default
+// their region. The default loggers of httpserver, grpcserver and cli carry
+// its fields, httpserver.MetricsConfig.RuntimeLabels adds its labels, and
+// otel resources and EGAwsClient's region default use it. Elsewhere:
+//
+//	runtimeinfo.InstallFields(logger)
+//	metrics.NewPrometheus(&metrics.PrometheusOptions{ConstLabels: runtimeinfo.Get().Labels()})
+//
+// Detection reads environment variables, the ECS task metadata endpoint,
+// Kubernetes downward API variables and cgroup files, and the EC2 instance
+// metadata service when the machine reports an EC2 hypervisor. It runs once
+// and is cached; set AWS_EC2_METADATA_DISABLED=true to skip the metadata
+// service.
+package runtimeinfo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+// Platforms, matching the OpenTelemetry cloud.platform values
+const (
+	PlatformEC2        = "aws_ec2"
+	PlatformECS        = "aws_ecs"
+	PlatformEKS        = "aws_eks"
+	PlatformLambda     = "aws_lambda"
+	PlatformKubernetes = "kubernetes"
+	// PlatformLocal is reported when nothing is detected, e.g. on a laptop
+	PlatformLocal = "local"
+)
+
+// detectTimeout bounds the metadata requests made by Get
+const detectTimeout = 2 * time.Second
+
+// Info describes the environment the process runs in; fields that do not
+// apply to the platform are empty
+type Info struct {
+	Platform         string `json:"platform"`
+	Region           string `json:"region,omitempty"`
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
+	AccountID        string `json:"accountId,omitempty"`
+	// InstanceID and InstanceType describe the EC2 instance, including the
+	// node under an EKS pod when the pod can reach the metadata service
+	InstanceID   string `json:"instanceId,omitempty"`
+	InstanceType string `json:"instanceType,omitempty"`
+	// Cluster, TaskARN and TaskFamily describe the ECS task
+	Cluster    string `json:"cluster,omitempty"`
+	TaskARN    string `json:"taskArn,omitempty"`
+	TaskFamily string `json:"taskFamily,omitempty"`
+	// ContainerID is the container's ID on ECS and Kubernetes
+	ContainerID string `json:"containerId,omitempty"`
+	// PodName, Namespace and NodeName describe the Kubernetes pod; set
+	// POD_NAME, POD_NAMESPACE and NODE_NAME from the downward API for exact
+	// values
+	PodName   string `json:"podName,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	NodeName  string `json:"nodeName,omitempty"`
+	// FunctionName and FunctionVersion describe the Lambda function
+	FunctionName    string `json:"functionName,omitempty"`
+	FunctionVersion string `json:"functionVersion,omitempty"`
+}
+
+var get = sync.OnceValue(func() Info {
+	ctx, cancel := context.WithTimeout(context.Background(), detectTimeout)
+	defer cancel()
+	return Detect(ctx)
+})
+
+// Get returns the detected environment, detecting it on the first call
+func Get() Info {
+	return get()
+}
+
+// Detect inspects the environment without caching; ctx bounds the metadata
+// requests. Most callers want Get.
+func Detect(ctx context.Context) Info {
+	return newDetector().detect(ctx)
+}
+
+// Fields returns the non-empty identifying values as log fields
+func (i Info) Fields() logrus.Fields {
+	fields := logrus.Fields{}
+	for k, v := range map[string]string{
+		"platform":          i.Platform,
+		"region":            i.Region,
+		"availability_zone": i.AvailabilityZone,
+		"instance_id":       i.InstanceID,
+		"task_arn":          i.TaskARN,
+		"container_id":      i.ContainerID,
+		"pod":               i.PodName,
+		"namespace":         i.Namespace,
+		"node":              i.NodeName,
+		"function_name":     i.FunctionName,
+	} {
+		if v != "" {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+// Labels returns low-cardinality metric labels: the platform, region and
+// availability zone. Per-instance values are left to the scraper, which
+// adds them as target labels.
+func (i Info) Labels() map[string]string {
+	labels := map[string]string{"platform": i.Platform}
+	if i.Region != "" {
+		labels["region"] = i.Region
+	}
+	if i.AvailabilityZone != "" {
+		labels["availability_zone"] = i.AvailabilityZone
+	}
+	return labels
+}
+
+// Attributes returns OpenTelemetry resource attributes for the environment,
+// using the cloud, host, aws.ecs, k8s, container and faas conventions
+func (i Info) Attributes() []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	add := func(v string, fn func(string) attribute.KeyValue) {
+		if v != "" {
+			attrs = append(attrs, fn(v))
+		}
+	}
+	switch i.Platform {
+	case PlatformEC2:
+		attrs = append(attrs, semconv.CloudProviderAWS, semconv.CloudPlatformAWSEC2)
+	case PlatformECS:
+		attrs = append(attrs, semconv.CloudProviderAWS, semconv.CloudPlatformAWSECS)
+	case PlatformEKS:
+		attrs = append(attrs, semconv.CloudProviderAWS, semconv.CloudPlatformAWSEKS)
+	case PlatformLambda:
+		attrs = append(attrs, semconv.CloudProviderAWS, semconv.CloudPlatformAWSLambda)
+	}
+	add(i.Region, semconv.CloudRegion)
+	add(i.AvailabilityZone, semconv.CloudAvailabilityZone)
+	add(i.AccountID, semconv.CloudAccountID)
+	add(i.InstanceID, semconv.HostID)
+	add(i.InstanceType, semconv.HostType)
+	add(i.TaskARN, semconv.AWSECSTaskARN)
+	add(i.TaskFamily, semconv.AWSECSTaskFamily)
+	add(i.ContainerID, semconv.ContainerID)
+	add(i.PodName, semconv.K8SPodName)
+	add(i.Namespace, semconv.K8SNamespaceName)
+	add(i.NodeName, semconv.K8SNodeName)
+	add(i.FunctionName, semconv.FaaSName)
+	add(i.FunctionVersion, semconv.FaaSVersion)
+	return attrs
+}
+
+// InstallFields adds Get's fields to every entry l logs that does not set
+// them
+func InstallFields(l *logrus.Logger) {
+	l.AddHook(fieldsHook(Get().Fields()))
+}
+
+// fieldsHook adds runtime fields to entries that do not set them
+type fieldsHook logrus.Fields
+
+func (h fieldsHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h fieldsHook) Fire(entry *logrus.Entry) error {
+	for k, v := range h {
+		if _, ok := entry.Data[k]; !ok {
+			entry.Data[k] = v
+		}
+	}
+	return nil
+}
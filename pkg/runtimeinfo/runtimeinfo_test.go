@@ -0,0 +1,125 @@
+package runtimeinfo
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+type fakeIdentity struct {
+	doc   imds.InstanceIdentityDocument
+	calls int
+}
+
+func (f *fakeIdentity) GetInstanceIdentityDocument(context.Context, *imds.GetInstanceIdentityDocumentInput, ...func(*imds.Options)) (*imds.GetInstanceIdentityDocumentOutput, error) {
+	f.calls++
+	return &imds.GetInstanceIdentityDocumentOutput{InstanceIdentityDocument: f.doc}, nil
+}
+
+func testDetector(env, files map[string]string, identity identityAPI) *detector {
+	return &detector{
+		getenv: func(k string) string { return env[k] },
+		readFile: func(path string) ([]byte, error) {
+			if v, ok := files[path]; ok {
+				return []byte(v), nil
+			}
+			return nil, fs.ErrNotExist
+		},
+		client:   http.DefaultClient,
+		identity: identity,
+	}
+}
+
+func TestDetect(t *testing.T) {
+	const cid = "8f4e9a1b2c3d4e5f60718293a4b5c6d7e8f90123456789abcdef0123456789ab"
+	ec2 := &fakeIdentity{doc: imds.InstanceIdentityDocument{Region: "eu-west-1", AvailabilityZone: "eu-west-1b", InstanceID: "i-123", InstanceType: "m7g.large", AccountID: "111122223333"}}
+	ecs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v4":
+			_, _ = w.Write([]byte(`{"DockerId":"abc123"}`))
+		case "/v4/task":
+			_, _ = w.Write([]byte(`{"Cluster":"prod","TaskARN":"arn:aws:ecs:us-east-2:444455556666:task/prod/0f9e","Family":"orders","AvailabilityZone":"us-east-2a"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ecs.Close()
+
+	for name, tc := range map[string]struct {
+		env, files map[string]string
+		want       Info
+	}{
+		"local": {env: map[string]string{"AWS_REGION": "us-west-2"}, want: Info{Platform: PlatformLocal, Region: "us-west-2"}},
+		"lambda": {
+			env:  map[string]string{"AWS_LAMBDA_FUNCTION_NAME": "orders", "AWS_LAMBDA_FUNCTION_VERSION": "7", "AWS_REGION": "ap-south-1"},
+			want: Info{Platform: PlatformLambda, Region: "ap-south-1", FunctionName: "orders", FunctionVersion: "7"},
+		},
+		"ecs": {
+			env:  map[string]string{"ECS_CONTAINER_METADATA_URI_V4": ecs.URL + "/v4"},
+			want: Info{Platform: PlatformECS, Region: "us-east-2", AvailabilityZone: "us-east-2a", AccountID: "444455556666", Cluster: "prod", TaskARN: "arn:aws:ecs:us-east-2:444455556666:task/prod/0f9e", TaskFamily: "orders", ContainerID: "abc123"},
+		},
+		"ec2": {
+			files: map[string]string{"/sys/class/dmi/id/board_vendor": "Amazon EC2\n"},
+			want:  Info{Platform: PlatformEC2, Region: "eu-west-1", AvailabilityZone: "eu-west-1b", AccountID: "111122223333", InstanceID: "i-123", InstanceType: "m7g.large"},
+		},
+		"ec2 metadata disabled": {
+			env:   map[string]string{"AWS_EC2_METADATA_DISABLED": "true"},
+			files: map[string]string{"/sys/class/dmi/id/board_vendor": "Amazon EC2\n"},
+			want:  Info{Platform: PlatformLocal},
+		},
+		"eks": {
+			env: map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1", "HOSTNAME": "orders-5d9c-x2x", "NODE_NAME": "ip-10-0-1-2"},
+			files: map[string]string{
+				"/sys/hypervisor/uuid":  "ec2e1916-9099-7caf-fd21-012345abcdef",
+				serviceAccountNamespace: "shop\n",
+				"/proc/self/cgroup":     "0::/\n",
+				"/proc/self/mountinfo":  "1 2 0:1 /var/lib/containerd/io.containerd.grpc.v1.cri/sandboxes/" + cid + "/hostname /etc/hostname rw\n",
+			},
+			want: Info{Platform: PlatformEKS, Region: "eu-west-1", AvailabilityZone: "eu-west-1b", AccountID: "111122223333", InstanceID: "i-123", InstanceType: "m7g.large", ContainerID: cid, PodName: "orders-5d9c-x2x", Namespace: "shop", NodeName: "ip-10-0-1-2"},
+		},
+		"kubernetes": {
+			env:   map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1", "POD_NAME": "orders-0", "POD_NAMESPACE": "shop"},
+			files: map[string]string{"/proc/self/cgroup": "12:pids:/kubepods/besteffort/pod1234/" + cid + "\n"},
+			want:  Info{Platform: PlatformKubernetes, ContainerID: cid, PodName: "orders-0", Namespace: "shop"},
+		},
+	} {
+		if got := testDetector(tc.env, tc.files, ec2).detect(context.Background()); got != tc.want {
+			t.Errorf("%s: got %+v, want %+v", name, got, tc.want)
+		}
+	}
+	if ec2.calls != 2 {
+		t.Fatalf("got %d metadata calls, want 2", ec2.calls)
+	}
+}
+
+func TestECSUnavailable(t *testing.T) {
+	d := testDetector(map[string]string{"ECS_CONTAINER_METADATA_URI_V4": "http://127.0.0.1:1/v4", "AWS_REGION": "us-east-1"}, nil, nil)
+	if got := d.detect(context.Background()); got != (Info{Platform: PlatformECS, Region: "us-east-1"}) {
+		t.Fatalf("got %+v", got)
+	}
+	if err := d.ecs(context.Background(), "http://127.0.0.1:1/v4", &Info{}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	info := Info{Platform: PlatformECS, Region: "us-east-2", TaskARN: "arn"}
+	got := attribute.NewSet(info.Attributes()...)
+	for _, kv := range []attribute.KeyValue{semconv.CloudProviderAWS, semconv.CloudPlatformAWSECS, semconv.CloudRegion("us-east-2"), semconv.AWSECSTaskARN("arn")} {
+		if v, ok := got.Value(kv.Key); !ok || v != kv.Value {
+			t.Errorf("missing %v in %v", kv, got.Encoded(attribute.DefaultEncoder()))
+		}
+	}
+	if fields := info.Fields(); len(fields) != 3 || fields["task_arn"] != "arn" {
+		t.Fatalf("got fields %v", fields)
+	}
+	if labels := info.Labels(); len(labels) != 2 || labels["region"] != "us-east-2" {
+		t.Fatalf("got labels %v", labels)
+	}
+}
@@ -0,0 +1,279 @@
+// Package scheduler runs periodic jobs on cron expressions or fixed intervals,
+// with overlap prevention, jitter, per-job timeouts and clean shutdown.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/clock"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// Schedule returns the next activation time after t
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Cron parses a cron expression: five fields (minute to day of week), six
+// with a leading seconds field, or a descriptor such as @hourly or @every 5m.
+// Prefix it with TZ=Area/City to evaluate it in another time zone.
+func Cron(expr string) (Schedule, error) {
+	sched, err := cronParser.Parse(expr)
+	if err != nil {
+		return nil, errs.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+	return sched, nil
+}
+
+// MustCron is Cron that panics on invalid expressions, for package-level jobs
+func MustCron(expr string) Schedule {
+	sched, err := Cron(expr)
+	if err != nil {
+		panic(err)
+	}
+	return sched
+}
+
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// Every runs a job at a fixed interval measured from the previous activation.
+// Add rejects intervals that are not positive.
+func Every(d time.Duration) Schedule {
+	return every(d)
+}
+
+// Job is a periodic task
+type Job struct {
+	// Name identifies the job in logs (required, unique)
+	Name     string
+	Schedule Schedule
+	Func     func(ctx context.Context) error
+	// Timeout bounds each run (default: none)
+	Timeout time.Duration
+	// Jitter delays each run by a random duration up to this value, so
+	// replicas do not run in lockstep
+	Jitter time.Duration
+	// AllowOverlap starts a run even when the previous one has not finished;
+	// by default the activation is skipped
+	AllowOverlap bool
+	// RunOnStart also runs the job when the scheduler starts
+	RunOnStart bool
+}
+
+type NewSchedulerArgs struct {
+	// Logger logs runs at debug, failures at error and skips at warn (default: logging.Noop)
+	Logger logging.Logger
+	// Clock schedules and times runs (default: clock.Real)
+	Clock clock.Clock
+}
+
+// Scheduler runs jobs until stopped
+type Scheduler struct {
+	logger logging.Logger
+	clock  clock.Clock
+
+	mu      sync.Mutex
+	jobs    []*scheduledJob
+	ctx     context.Context
+	cancel  context.CancelFunc
+	started bool
+	stopped bool
+	// stop ends the scheduling loops; running jobs keep ctx until Stop's deadline
+	stop chan struct{}
+
+	loops sync.WaitGroup
+	runs  sync.WaitGroup
+}
+
+type scheduledJob struct {
+	Job
+	mu      sync.Mutex
+	running int
+}
+
+// New creates a Scheduler; args may be nil
+func New(args *NewSchedulerArgs) *Scheduler {
+	if args == nil {
+		args = &NewSchedulerArgs{}
+	}
+	s := &Scheduler{logger: args.Logger, clock: args.Clock, stop: make(chan struct{})}
+	if s.logger == nil {
+		s.logger = logging.Noop()
+	}
+	if s.clock == nil {
+		s.clock = clock.Real()
+	}
+	return s
+}
+
+// Add registers a job. Jobs added after Start are scheduled immediately.
+func (s *Scheduler) Add(job Job) error {
+	if job.Name == "" || job.Schedule == nil || job.Func == nil {
+		return errs.New(errs.Internal, "job Name, Schedule and Func are required")
+	}
+	if d, ok := job.Schedule.(every); ok && d <= 0 {
+		return errs.Newf(errs.Internal, "job %s: Every interval must be positive, got %s", job.Name, time.Duration(d))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return errs.New(errs.Internal, "scheduler is stopped")
+	}
+	for _, j := range s.jobs {
+		if j.Name == job.Name {
+			return errs.Newf(errs.Internal, "job %s is already registered", job.Name)
+		}
+	}
+	j := &scheduledJob{Job: job}
+	s.jobs = append(s.jobs, j)
+	if s.started {
+		s.startLoop(j)
+	}
+	return nil
+}
+
+// Start schedules the registered jobs. Runs use a context derived from ctx.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started || s.stopped {
+		return
+	}
+	s.started = true
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	for _, j := range s.jobs {
+		s.startLoop(j)
+	}
+}
+
+// Stop stops scheduling and waits for running jobs until ctx is done, then
+// cancels their contexts and returns ctx's error
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return nil
+	}
+	s.stopped = true
+	close(s.stop)
+	started := s.started
+	s.mu.Unlock()
+	if !started {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.loops.Wait()
+		s.runs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// startLoop runs j's schedule; s.mu must be held
+func (s *Scheduler) startLoop(j *scheduledJob) {
+	s.loops.Add(1)
+	go func() {
+		defer s.loops.Done()
+		if j.RunOnStart {
+			s.trigger(j)
+		}
+		next := j.Schedule.Next(s.clock.Now())
+		for !next.IsZero() {
+			delay := next.Sub(s.clock.Now())
+			if j.Jitter > 0 {
+				delay += rand.N(j.Jitter)
+			}
+			select {
+			case <-s.clock.After(delay):
+			case <-s.stop:
+				return
+			}
+			s.trigger(j)
+
+			next = j.Schedule.Next(next)
+			if now := s.clock.Now(); next.Before(now) {
+				// activations missed while suspended are not made up
+				next = j.Schedule.Next(now)
+			}
+		}
+	}()
+}
+
+// trigger starts a run of j unless it is still running and overlap is not allowed
+func (s *Scheduler) trigger(j *scheduledJob) {
+	select {
+	case <-s.stop:
+		return
+	default:
+	}
+	log := s.logger.WithField("job", j.Name)
+
+	j.mu.Lock()
+	if j.running > 0 && !j.AllowOverlap {
+		j.mu.Unlock()
+		log.Warn("skipping job run, previous run still in progress")
+		return
+	}
+	j.running++
+	j.mu.Unlock()
+
+	s.runs.Add(1)
+	go func() {
+		defer s.runs.Done()
+		defer func() {
+			j.mu.Lock()
+			j.running--
+			j.mu.Unlock()
+		}()
+
+		start := s.clock.Now()
+		err := s.run(j)
+		log = log.WithField("duration", s.clock.Since(start))
+		if err != nil {
+			log.WithError(err).Error("job run failed")
+			return
+		}
+		log.Debug("job run completed")
+	}()
+}
+
+func (s *Scheduler) run(j *scheduledJob) (err error) {
+	ctx := s.ctx
+	if j.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.Timeout)
+		defer cancel()
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			s.logger.WithFields(logrus.Fields{"job": j.Name, "stack": string(debug.Stack())}).Error("job panicked")
+			err = fmt.Errorf("job panicked: %v", v)
+		}
+	}()
+	return j.Func(ctx)
+}
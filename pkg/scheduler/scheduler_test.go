@@ -0,0 +1,106 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/clock"
+)
+
+func TestCron(t *testing.T) {
+	sched, err := Cron("*/15 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2024, 1, 1, 10, 7, 0, 0, time.UTC)
+	if got := sched.Next(from); !got.Equal(time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC)) {
+		t.Fatalf("Next = %s", got)
+	}
+	if _, err := Cron("not a cron"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestSchedulerSkipsOverlap(t *testing.T) {
+	s := New(nil)
+	var runs atomic.Int32
+	release := make(chan struct{})
+	err := s.Add(Job{
+		Name:       "slow",
+		Schedule:   Every(time.Millisecond),
+		RunOnStart: true,
+		Func: func(ctx context.Context) error {
+			runs.Add(1)
+			<-release
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Start(context.Background())
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if runs.Load() != 1 {
+		t.Fatalf("job ran %d times while still running, want 1", runs.Load())
+	}
+}
+
+func TestSchedulerClock(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := New(&NewSchedulerArgs{Clock: clk})
+	runs := make(chan struct{}, 10)
+	err := s.Add(Job{
+		Name:     "hourly",
+		Schedule: Every(time.Hour),
+		Func: func(context.Context) error {
+			runs <- struct{}{}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Start(context.Background())
+	defer s.Stop(context.Background())
+
+	wait := func() {
+		t.Helper()
+		select {
+		case <-runs:
+		case <-time.After(time.Second):
+			t.Fatal("job did not run")
+		}
+	}
+	clk.BlockUntil(1)
+	clk.Advance(59 * time.Minute)
+	clk.Advance(time.Minute)
+	wait()
+
+	// activations missed while suspended are skipped: one run for 2h, then
+	// the schedule restarts from 7h
+	clk.BlockUntil(1)
+	clk.Advance(6 * time.Hour)
+	wait()
+	clk.BlockUntil(1)
+	clk.Advance(time.Hour)
+	wait()
+	if len(runs) != 0 {
+		t.Fatalf("got %d extra runs", len(runs))
+	}
+}
+
+func TestAddRejectsNonPositiveInterval(t *testing.T) {
+	s := New(nil)
+	for _, d := range []time.Duration{0, -time.Second} {
+		if err := s.Add(Job{Name: "spin", Schedule: Every(d), Func: func(context.Context) error { return nil }}); err == nil {
+			t.Fatalf("Every(%s) was accepted", d)
+		}
+	}
+}
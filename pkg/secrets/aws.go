@@ -0,0 +1,33 @@
+package secrets
+
+import (
+	"context"
+
+	"github.com/bdlilley/easygo"
+	egerrors "github.com/bdlilley/easygo/pkg/egaws/errors"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// NewSecretsManager reads Secrets Manager secrets by name or ARN, typically
+// with an EGAwsClient; opts may be nil
+func NewSecretsManager(reader easygo.SecretsReader, opts *Options) Provider {
+	return newProvider(func(ctx context.Context, name string) (string, error) {
+		value, err := reader.GetLatestSecretString(ctx, name)
+		if egerrors.IsNotFound(err) {
+			return "", errs.Errorf("secret %s: %v: %w", name, err, ErrNotFound)
+		}
+		return value, err
+	}, opts)
+}
+
+// NewSSM reads SSM parameters by name, decrypting SecureStrings, typically
+// with an EGAwsClient; opts may be nil
+func NewSSM(reader easygo.ParameterReader, opts *Options) Provider {
+	return newProvider(func(ctx context.Context, name string) (string, error) {
+		value, err := reader.GetParameterValue(ctx, name)
+		if egerrors.IsNotFound(err) {
+			return "", errs.Errorf("parameter %s: %v: %w", name, err, ErrNotFound)
+		}
+		return value, err
+	}, opts)
+}
@@ -0,0 +1,59 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// NewEnv reads secrets from environment variables. Names are upper cased
+// with every character other than letters and digits replaced by an
+// underscore, then prefixed, so with prefix "APP_" the secret "db/password"
+// is read from APP_DB_PASSWORD. opts may be nil.
+func NewEnv(prefix string, opts *Options) Provider {
+	return newProvider(func(_ context.Context, name string) (string, error) {
+		key := prefix + envName(name)
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			return "", errs.Errorf("environment variable %s is not set: %w", key, ErrNotFound)
+		}
+		return value, nil
+	}, opts)
+}
+
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// NewFiles reads secrets from files under dir, such as Kubernetes secret
+// volumes or Docker secrets, so "db/password" is read from dir/db/password.
+// Trailing newlines are trimmed. opts may be nil.
+func NewFiles(dir string, opts *Options) Provider {
+	root, _ := filepath.Abs(dir)
+	return newProvider(func(_ context.Context, name string) (string, error) {
+		if !fs.ValidPath(name) {
+			return "", errs.Errorf("invalid secret name %q", name)
+		}
+		b, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", errs.Errorf("secret file %s does not exist: %w", name, ErrNotFound)
+		}
+		if err != nil {
+			return "", errs.Errorf("failed to read secret file %s: %w", name, err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	}, opts)
+}
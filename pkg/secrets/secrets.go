@@ -0,0 +1,147 @@
+// Package secrets reads secrets through one Provider interface with AWS
+// Secrets Manager, SSM Parameter Store, environment variable, local file and
+// HashiCorp Vault backends, so application code and config.Load can switch
+// providers per environment, e.g. files in development and Secrets Manager
+// in production.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/clock"
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/logging"
+)
+
+// ErrNotFound is returned when a secret does not exist. A missing secret is a
+// deployment problem rather than a missing resource the client asked for, so
+// it is Internal and served as a 500.
+var ErrNotFound = errs.New(errs.Internal, "secret not found")
+
+// Provider reads secrets by name. Names may end in #key to select a field
+// of a JSON object secret, e.g. "prod/db#password".
+type Provider interface {
+	// Get returns the secret value
+	Get(ctx context.Context, name string) (string, error)
+	// GetJSON unmarshals the secret value into v
+	GetJSON(ctx context.Context, name string, v any) error
+	// Watch calls fn with the current value, then again each time it
+	// changes, until ctx is done. It returns an error only when the first
+	// read fails; later failures go to Options.ErrorHandler.
+	Watch(ctx context.Context, name string, fn func(value string)) error
+}
+
+type Options struct {
+	// WatchInterval is how often Watch reads the secret (default: 5m)
+	WatchInterval time.Duration
+	// ErrorHandler receives failed reads while watching (default: log them to Logger)
+	ErrorHandler func(name string, err error)
+	// Logger logs failed reads at error without an ErrorHandler (default: logging.Noop)
+	Logger logging.Logger
+	// Clock times WatchInterval (default: clock.Real)
+	Clock clock.Clock
+}
+
+// provider implements Provider for a backend's fetch function
+type provider struct {
+	fetch        func(ctx context.Context, name string) (string, error)
+	interval     time.Duration
+	errorHandler func(name string, err error)
+	clock        clock.Clock
+}
+
+func newProvider(fetch func(ctx context.Context, name string) (string, error), opts *Options) *provider {
+	if opts == nil {
+		opts = &Options{}
+	}
+	p := &provider{fetch: fetch, interval: opts.WatchInterval, errorHandler: opts.ErrorHandler, clock: opts.Clock}
+	if p.interval <= 0 {
+		p.interval = 5 * time.Minute
+	}
+	if p.errorHandler == nil {
+		logger := opts.Logger
+		if logger == nil {
+			logger = logging.Noop()
+		}
+		p.errorHandler = func(name string, err error) {
+			logger.WithError(err).WithField("secret", name).Error("failed to read watched secret")
+		}
+	}
+	if p.clock == nil {
+		p.clock = clock.Real()
+	}
+	return p
+}
+
+func (p *provider) Get(ctx context.Context, name string) (string, error) {
+	name, key, _ := strings.Cut(name, "#")
+	value, err := p.fetch(ctx, name)
+	if err != nil || key == "" {
+		return value, err
+	}
+	return jsonKey(value, name, key)
+}
+
+func (p *provider) GetJSON(ctx context.Context, name string, v any) error {
+	value, err := p.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(value), v); err != nil {
+		return errs.Errorf("secret %s is not valid JSON: %w", name, err)
+	}
+	return nil
+}
+
+func (p *provider) Watch(ctx context.Context, name string, fn func(value string)) error {
+	value, err := p.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	fn(value)
+
+	go func() {
+		ticker := p.clock.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+			}
+			next, err := p.Get(ctx, name)
+			if err != nil {
+				if ctx.Err() == nil {
+					p.errorHandler(name, err)
+				}
+				continue
+			}
+			if next != value {
+				value = next
+				fn(value)
+			}
+		}
+	}()
+	return nil
+}
+
+// jsonKey returns the field key of the JSON object secret; non-string values
+// are returned as JSON
+func jsonKey(secret, name, key string) (string, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", errs.Errorf("secret %s is not a JSON object: %w", name, err)
+	}
+	raw, ok := fields[key]
+	if !ok {
+		return "", errs.Errorf("secret %s has no key %q: %w", name, key, ErrNotFound)
+	}
+	var str string
+	if err := json.Unmarshal(raw, &str); err == nil {
+		return str, nil
+	}
+	return string(raw), nil
+}
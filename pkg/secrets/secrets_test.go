@@ -0,0 +1,129 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/clock"
+	"github.com/bdlilley/easygo/pkg/egaws/egawstest"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+func TestBackends(t *testing.T) {
+	ctx := context.Background()
+
+	store := egawstest.NewSecretsStore()
+	store.SetSecret("prod/db", `{"password":"sm-pass","port":5432}`)
+
+	params := &egawstest.ParameterStore{}
+	params.SetParameter("/prod/db/password", "ssm-pass")
+
+	t.Setenv("APP_PROD_DB_PASSWORD", "env-pass")
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "prod"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "prod", "db"), []byte(`{"password":"file-pass"}`+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/v1/kv/data/prod/db" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"data":{"data":{"password":"vault-pass"},"metadata":{"version":3}}}`))
+	}))
+	defer vaultServer.Close()
+	vault, err := NewVault(&NewVaultArgs{Address: vaultServer.URL, Token: "root", Mount: "kv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		provider Provider
+		secret   string
+		want     string
+	}{
+		{"secrets manager", NewSecretsManager(store, nil), "prod/db#password", "sm-pass"},
+		{"secrets manager non-string key", NewSecretsManager(store, nil), "prod/db#port", "5432"},
+		{"ssm", NewSSM(params, nil), "/prod/db/password", "ssm-pass"},
+		{"env", NewEnv("APP_", nil), "prod/db-password", "env-pass"},
+		{"files", NewFiles(dir, nil), "prod/db#password", "file-pass"},
+		{"vault", vault, "prod/db#password", "vault-pass"},
+	} {
+		got, err := tc.provider.Get(ctx, tc.secret)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got %q, %v, want %q", tc.name, got, err, tc.want)
+		}
+	}
+
+	var db struct {
+		Password string `json:"password"`
+	}
+	if err := vault.GetJSON(ctx, "prod/db", &db); err != nil || db.Password != "vault-pass" {
+		t.Fatalf("got %+v, %v", db, err)
+	}
+
+	for name, p := range map[string]Provider{
+		"env":   NewEnv("APP_", nil),
+		"files": NewFiles(dir, nil),
+		"vault": vault,
+	} {
+		if _, err := p.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) || errs.KindOf(err) != errs.Internal {
+			t.Errorf("%s: got %v (%s), want an internal ErrNotFound", name, err, errs.KindOf(err))
+		}
+	}
+	if _, err := NewFiles(dir, nil).Get(ctx, "../etc/passwd"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want paths outside dir rejected", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token")
+	if err := os.WriteFile(path, []byte("v1"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	clk := clock.NewFake(time.Unix(0, 0))
+	p := NewFiles(dir, &Options{WatchInterval: time.Minute, ErrorHandler: func(string, error) {}, Clock: clk})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	values := make(chan string, 10)
+	if err := p.Watch(ctx, "token", func(v string) { values <- v }); err != nil {
+		t.Fatal(err)
+	}
+	if v := <-values; v != "v1" {
+		t.Fatalf("got %q, want v1", v)
+	}
+	if err := os.WriteFile(path, []byte("v2"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	clk.BlockUntil(1)
+	clk.Advance(time.Minute)
+	select {
+	case v := <-values:
+		if v != "v2" {
+			t.Fatalf("got %q, want v2", v)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("change not observed")
+	}
+
+	if err := p.Watch(ctx, "missing", func(string) {}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want ErrNotFound for the first read", err)
+	}
+}
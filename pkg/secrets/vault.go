@@ -0,0 +1,94 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/httpclient"
+)
+
+type NewVaultArgs struct {
+	// Address is the Vault server URL, e.g. https://vault.example.com:8200 (required)
+	Address string
+	// Token authenticates requests (required)
+	Token string
+	// Mount is the KV version 2 secrets engine path (default: secret)
+	Mount string
+	// Namespace is the Vault Enterprise namespace (default: none)
+	Namespace string
+	// Client sends requests (default: an httpclient.Client with retries)
+	Client *httpclient.Client
+	// Options configures Watch; may be nil
+	Options *Options
+}
+
+// vaultResponse is the body of a KV version 2 read
+type vaultResponse struct {
+	Data struct {
+		Data map[string]json.RawMessage `json:"data"`
+	} `json:"data"`
+}
+
+// NewVault reads secrets from a Vault KV version 2 engine. A secret's value
+// is its data as a JSON object, so select fields with #key, e.g.
+// "app/db#password".
+func NewVault(args *NewVaultArgs) (Provider, error) {
+	if args == nil || args.Address == "" || args.Token == "" {
+		return nil, errs.New(errs.Internal, "secrets: Vault Address and Token are required")
+	}
+	mount := strings.Trim(args.Mount, "/")
+	if mount == "" {
+		mount = "secret"
+	}
+	header := http.Header{"X-Vault-Token": {args.Token}}
+	if args.Namespace != "" {
+		header.Set("X-Vault-Namespace", args.Namespace)
+	}
+	client := args.Client
+	if client == nil {
+		client = httpclient.NewClient(&httpclient.NewClientArgs{})
+	}
+	base := strings.TrimRight(args.Address, "/") + "/v1/" + mount + "/data/"
+
+	return newProvider(func(ctx context.Context, name string) (string, error) {
+		req, err := client.NewRequest(ctx, http.MethodGet, base+pathEscape(name), nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header = header.Clone()
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", errs.Errorf("failed to read vault secret %s: %w", name, err)
+		}
+		defer resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			return "", errs.Errorf("vault secret %s: %w", name, ErrNotFound)
+		case resp.StatusCode != http.StatusOK:
+			return "", errs.Errorf("failed to read vault secret %s: %s", name, resp.Status)
+		}
+		var body vaultResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			return "", errs.Errorf("failed to decode vault secret %s: %w", name, err)
+		}
+		data, err := json.Marshal(body.Data.Data)
+		if err != nil {
+			return "", errs.Errorf("failed to encode vault secret %s: %w", name, err)
+		}
+		return string(data), nil
+	}, args.Options), nil
+}
+
+// pathEscape escapes each segment of a slash separated secret path
+func pathEscape(name string) string {
+	segments := strings.Split(strings.Trim(name, "/"), "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,90 @@
+package tenant
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bdlilley/easygo/pkg/ratelimit"
+)
+
+// Config holds a value per tenant, such as a plan's quotas or a tenant's
+// database, with a default for tenants without an override. It is safe for
+// concurrent use, so overrides can be reloaded while serving.
+type Config[T any] struct {
+	mu        sync.RWMutex
+	def       T
+	overrides map[string]T
+}
+
+// NewConfig creates a Config returning def for tenants not in overrides
+func NewConfig[T any](def T, overrides map[string]T) *Config[T] {
+	c := &Config[T]{def: def, overrides: make(map[string]T, len(overrides))}
+	for id, v := range overrides {
+		c.overrides[id] = v
+	}
+	return c
+}
+
+// For returns the value for tenant id
+func (c *Config[T]) For(id string) T {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if v, ok := c.overrides[id]; ok {
+		return v
+	}
+	return c.def
+}
+
+// Get returns the value for the tenant in ctx, or the default
+func (c *Config[T]) Get(ctx context.Context) T {
+	return c.For(ID(ctx))
+}
+
+// Set overrides the value for tenant id
+func (c *Config[T]) Set(id string, v T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.overrides[id] = v
+}
+
+// Delete removes tenant id's override
+func (c *Config[T]) Delete(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.overrides, id)
+}
+
+// Replace swaps all overrides, e.g. after reloading them from config
+func (c *Config[T]) Replace(overrides map[string]T) {
+	m := make(map[string]T, len(overrides))
+	for id, v := range overrides {
+		m[id] = v
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.overrides = m
+}
+
+// Limiter is a ratelimit.Limiter that applies each tenant's own limiter from
+// a Config, and keeps tenants' keys apart so one tenant cannot use up
+// another's limit. The tenant is taken from the context passed to AllowN.
+type Limiter struct {
+	limits *Config[ratelimit.Limiter]
+}
+
+var _ ratelimit.Limiter = (*Limiter)(nil)
+
+// NewLimiter creates a Limiter; a nil limiter for a tenant, including the
+// default, disables rate limiting for it
+func NewLimiter(limits *Config[ratelimit.Limiter]) *Limiter {
+	return &Limiter{limits: limits}
+}
+
+func (l *Limiter) AllowN(ctx context.Context, key string, n int) (ratelimit.Result, error) {
+	id := ID(ctx)
+	limiter := l.limits.For(id)
+	if limiter == nil {
+		return ratelimit.Result{Allowed: true}, nil
+	}
+	return limiter.AllowN(ctx, id+"/"+key, n)
+}
@@ -0,0 +1,187 @@
+// Package tenant carries the tenant of a request through its context. The
+// Middleware resolves the tenant from a header, subdomain or JWT claim, adds
+// it to the context logger and feature flag attributes, and Config and
+// Limiter give other easygo modules per-tenant settings and rate limits.
+package tenant
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/bdlilley/easygo/pkg/featureflags"
+	"github.com/bdlilley/easygo/pkg/httpserver"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/bdlilley/easygo/pkg/requestmeta"
+)
+
+// DefaultHeader carries the tenant ID between services
+const DefaultHeader = requestmeta.TenantHeader
+
+// LogField is the log field and feature flag attribute holding the tenant ID
+const LogField = "tenant_id"
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx carrying the tenant id
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext returns the tenant stored by WithTenant or the Middleware
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(tenantKey{}).(string)
+	return id, ok && id != ""
+}
+
+// ID returns the tenant in ctx, or ""
+func ID(ctx context.Context) string {
+	id, _ := FromContext(ctx)
+	return id
+}
+
+// Resolver finds the tenant of a request, returning "" when the request does
+// not name one
+type Resolver func(r *http.Request) string
+
+// FromHeader resolves the tenant from the request header name. Clients can
+// set any header, so use it only where a trusted proxy or service sets it;
+// resolve other requests FromClaim.
+func FromHeader(name string) Resolver {
+	return func(r *http.Request) string {
+		return strings.TrimSpace(r.Header.Get(name))
+	}
+}
+
+// FromSubdomain resolves the tenant from the label left of baseDomain in the
+// request's host, e.g. acme for acme.example.com with baseDomain example.com.
+// Hosts outside baseDomain and nested subdomains resolve no tenant.
+func FromSubdomain(baseDomain string) Resolver {
+	suffix := "." + strings.ToLower(strings.Trim(baseDomain, "."))
+	return func(r *http.Request) string {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		sub, ok := strings.CutSuffix(strings.ToLower(host), suffix)
+		if !ok || sub == "" || strings.Contains(sub, ".") {
+			return ""
+		}
+		return sub
+	}
+}
+
+// FromClaim resolves the tenant from a string claim of the token validated by
+// httpserver.JWTAuthenticator, so the Middleware must run after it
+func FromClaim(claim string) Resolver {
+	return func(r *http.Request) string {
+		claims, ok := httpserver.ClaimsFromContext(r.Context())
+		if !ok {
+			return ""
+		}
+		id, _ := claims[claim].(string)
+		return id
+	}
+}
+
+// FirstOf tries resolvers in order and returns the first tenant found
+func FirstOf(resolvers ...Resolver) Resolver {
+	return func(r *http.Request) string {
+		for _, resolve := range resolvers {
+			if id := resolve(r); id != "" {
+				return id
+			}
+		}
+		return ""
+	}
+}
+
+// MiddlewareConfig configures the Middleware
+type MiddlewareConfig struct {
+	// Resolver finds the request's tenant (required). There is no default
+	// since the tenant must come from a source the service trusts, e.g.
+	// FromClaim after JWT authentication.
+	Resolver Resolver
+	// Optional passes requests without a tenant through; by default they get
+	// 400 with code tenant_required
+	Optional bool
+	// Validate rejects unknown or disabled tenants; requests it returns an
+	// error for get 403 with code tenant_forbidden
+	Validate func(ctx context.Context, id string) error
+}
+
+// Middleware returns middleware that stores the resolved tenant in the
+// request context with WithTenant and as the requestmeta tenant, and adds it
+// to the context logger and to the feature flag attributes as tenant_id. It
+// panics without a Resolver.
+func Middleware(cfg *MiddlewareConfig) func(http.Handler) http.Handler {
+	if cfg == nil || cfg.Resolver == nil {
+		panic("tenant: MiddlewareConfig needs a Resolver")
+	}
+	c := *cfg
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := c.Resolver(r)
+			if id == "" {
+				if c.Optional {
+					next.ServeHTTP(w, r)
+					return
+				}
+				httpserver.WriteProblem(w, r, httpserver.NewProblem(http.StatusBadRequest, "tenant is required").WithCode("tenant_required"))
+				return
+			}
+			if c.Validate != nil {
+				if err := c.Validate(r.Context(), id); err != nil {
+					logging.FromContext(r.Context()).WithError(err).WithField(LogField, id).Debug("tenant rejected")
+					httpserver.WriteProblem(w, r, httpserver.NewProblem(http.StatusForbidden, "tenant is not allowed").WithCode("tenant_forbidden"))
+					return
+				}
+			}
+
+			ctx := WithTenant(r.Context(), id)
+			ctx = logging.WithContext(ctx, logging.FromContext(ctx).WithField(LogField, id))
+			ctx = featureflags.WithAttributes(ctx, featureflags.Attributes{LogField: id})
+			ctx = requestmeta.Update(ctx, func(m *requestmeta.Meta) { m.Tenant = id })
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// KeyFunc groups requests by tenant, for httpserver.RateLimitConfig and
+// httpserver.IdempotencyConfig.Scope. Requests without a tenant fall back to
+// httpserver.ClientIP.
+func KeyFunc(r *http.Request) string {
+	if id, ok := FromContext(r.Context()); ok {
+		return "tenant:" + id
+	}
+	return httpserver.ClientIP(r)
+}
+
+// Transport sets the tenant in each request's context as header (default:
+// DefaultHeader), so downstream services resolve the same tenant. Use it as
+// httpclient.NewClientArgs.Transport.
+func Transport(header string, next http.RoundTripper) http.RoundTripper {
+	if header == "" {
+		header = DefaultHeader
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		id, ok := FromContext(req.Context())
+		if !ok || req.Header.Get(header) != "" {
+			return next.RoundTrip(req)
+		}
+		req = req.Clone(req.Context())
+		req.Header.Set(header, id)
+		return next.RoundTrip(req)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,111 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bdlilley/easygo/pkg/featureflags"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/bdlilley/easygo/pkg/ratelimit"
+	"github.com/sirupsen/logrus"
+)
+
+func TestResolvers(t *testing.T) {
+	resolve := FirstOf(FromHeader(DefaultHeader), FromSubdomain("example.com"))
+	for host, want := range map[string]string{
+		"acme.example.com":      "acme",
+		"ACME.example.com:8443": "acme",
+		"example.com":           "",
+		"a.b.example.com":       "",
+		"acme.other.com":        "",
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Host = host
+		if got := resolve(r); got != want {
+			t.Errorf("got %q for %s, want %q", got, host, want)
+		}
+	}
+	r := httptest.NewRequest(http.MethodGet, "http://acme.example.com/", nil)
+	r.Header.Set(DefaultHeader, "globex")
+	if got := resolve(r); got != "globex" {
+		t.Fatalf("got %q, want the header to win", got)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var gotTenant string
+	var gotAttrs featureflags.Attributes
+	var gotFields logrus.Fields
+	handler := Middleware(&MiddlewareConfig{
+		Resolver: FromHeader(DefaultHeader),
+		Validate: func(_ context.Context, id string) error {
+			if id == "banned" {
+				return errors.New("suspended")
+			}
+			return nil
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTenant = ID(r.Context())
+		gotAttrs = featureflags.AttributesFromContext(r.Context())
+		gotFields = logging.FromContext(r.Context()).(*logrus.Entry).Data
+	}))
+	do := func(id string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if id != "" {
+			r.Header.Set(DefaultHeader, id)
+		}
+		r = r.WithContext(logging.WithContext(r.Context(), logrus.NewEntry(logrus.New())))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := do("acme"); code != http.StatusOK || gotTenant != "acme" || gotAttrs[LogField] != "acme" || gotFields[LogField] != "acme" {
+		t.Fatalf("got %d %q %v %v", code, gotTenant, gotAttrs, gotFields)
+	}
+	if code := do(""); code != http.StatusBadRequest {
+		t.Fatalf("got %d without a tenant", code)
+	}
+	if code := do("banned"); code != http.StatusForbidden {
+		t.Fatalf("got %d for a rejected tenant", code)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic without a Resolver")
+		}
+	}()
+	Middleware(&MiddlewareConfig{})
+}
+
+func TestLimiter(t *testing.T) {
+	free, _ := ratelimit.NewTokenBucket(&ratelimit.NewTokenBucketArgs{Rate: 1, Burst: 1})
+	paid, _ := ratelimit.NewTokenBucket(&ratelimit.NewTokenBucketArgs{Rate: 1, Burst: 3})
+	l := NewLimiter(NewConfig[ratelimit.Limiter](free, map[string]ratelimit.Limiter{"acme": paid, "internal": nil}))
+
+	allowed := func(id string, n int) int {
+		ctx := WithTenant(context.Background(), id)
+		count := 0
+		for range n {
+			if res, err := ratelimit.Allow(ctx, l, "api"); err == nil && res.Allowed {
+				count++
+			}
+		}
+		return count
+	}
+	if got := allowed("acme", 5); got != 3 {
+		t.Errorf("got %d allowed for acme, want its burst of 3", got)
+	}
+	if got := allowed("globex", 5); got != 1 {
+		t.Errorf("got %d allowed for globex, want the default burst of 1", got)
+	}
+	if got := allowed("initech", 5); got != 1 {
+		t.Errorf("got %d allowed for initech, want a bucket separate from globex", got)
+	}
+	if got := allowed("internal", 5); got != 5 {
+		t.Errorf("got %d allowed for internal, want no limit", got)
+	}
+}
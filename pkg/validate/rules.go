@@ -0,0 +1,100 @@
+package validate
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/google/uuid"
+)
+
+// RuleError is returned by the rule functions
+type RuleError struct {
+	Rule    string
+	Message string
+}
+
+func (e *RuleError) Error() string {
+	return e.Message
+}
+
+type stringRule struct {
+	message string
+	check   func(s string) bool
+}
+
+// stringRules are registered as validate tags and back the rule functions
+var stringRules = map[string]stringRule{
+	"arn": {"must be a valid ARN", func(s string) bool {
+		_, err := arn.Parse(s)
+		return err == nil
+	}},
+	"aws_region":     {"must be a valid AWS region", regionPattern.MatchString},
+	"aws_account_id": {"must be a 12 digit AWS account ID", accountIDPattern.MatchString},
+	"duration": {"must be a duration such as 30s or 5m", func(s string) bool {
+		_, err := time.ParseDuration(s)
+		return err == nil
+	}},
+}
+
+var (
+	regionPattern    = regexp.MustCompile(`^[a-z]{2}(-gov|-iso[a-z]?)?-[a-z]+-\d+$`)
+	accountIDPattern = regexp.MustCompile(`^\d{12}$`)
+)
+
+func checkRule(tag, s string) error {
+	rule := stringRules[tag]
+	if rule.check(s) {
+		return nil
+	}
+	return &RuleError{Rule: tag, Message: rule.message}
+}
+
+// ARN checks that s is an ARN such as arn:aws:sqs:us-east-1:123456789012:jobs
+func ARN(s string) error {
+	return checkRule("arn", s)
+}
+
+// Region checks that s looks like an AWS region such as us-east-1
+func Region(s string) error {
+	return checkRule("aws_region", s)
+}
+
+// AccountID checks that s is a 12 digit AWS account ID
+func AccountID(s string) error {
+	return checkRule("aws_account_id", s)
+}
+
+// Duration checks that s parses with time.ParseDuration
+func Duration(s string) error {
+	return checkRule("duration", s)
+}
+
+// UUID checks that s is a UUID in canonical form
+func UUID(s string) error {
+	if len(s) == 36 {
+		if _, err := uuid.Parse(s); err == nil {
+			return nil
+		}
+	}
+	return &RuleError{Rule: "uuid", Message: "must be a valid UUID"}
+}
+
+// URL checks that s is an absolute URL with a scheme and host
+func URL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return &RuleError{Rule: "url", Message: "must be a valid URL"}
+	}
+	return nil
+}
+
+// CIDR checks that s is an IPv4 or IPv6 CIDR block such as 10.0.0.0/16
+func CIDR(s string) error {
+	if _, _, err := net.ParseCIDR(s); err != nil {
+		return &RuleError{Rule: "cidr", Message: "must be a valid CIDR block"}
+	}
+	return nil
+}
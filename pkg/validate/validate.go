@@ -0,0 +1,261 @@
+// Package validate checks values with validate struct tags and programmatic
+// rules, reporting every failure at once. It adds tags for AWS ARNs, regions
+// and account IDs and for duration strings to the go-playground/validator
+// built-ins such as uuid, url and cidr, and backs httpserver.Bind and
+// config.Load validation.
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes one failed validation rule
+type FieldError struct {
+	// Field is the JSON path of the field, e.g. "address.zip"
+	Field string `json:"field"`
+	// Rule is the failed rule, e.g. "required"
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Errors collects failed rules. Build one with Check, Require and Var, then
+// return Err:
+//
+//	var errs validate.Errors
+//	errs.Require("name", req.Name)
+//	errs.Check("roleArn", validate.ARN(req.RoleArn))
+//	errs.Var("replicas", req.Replicas, "min=1,max=10")
+//	return errs.Err()
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.Field + " " + fe.Message
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// Err returns e as an error, or nil when no rule failed
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Add records a failed rule
+func (e *Errors) Add(field, rule, message string) {
+	*e = append(*e, FieldError{Field: field, Rule: rule, Message: message})
+}
+
+// Check records err against field when it is not nil. Errors from the rule
+// functions in this package keep their rule name; Errors values are merged
+// with their fields nested under field.
+func (e *Errors) Check(field string, err error) {
+	if err == nil {
+		return
+	}
+	var nested Errors
+	if errors.As(err, &nested) {
+		for _, fe := range nested {
+			fe.Field = joinPath(field, fe.Field)
+			*e = append(*e, fe)
+		}
+		return
+	}
+	var re *RuleError
+	if errors.As(err, &re) {
+		e.Add(field, re.Rule, re.Message)
+		return
+	}
+	e.Add(field, "invalid", err.Error())
+}
+
+// Require records a required failure when value is its type's zero value
+func (e *Errors) Require(field string, value any) {
+	if v := reflect.ValueOf(value); !v.IsValid() || v.IsZero() {
+		e.Add(field, "required", "is required")
+	}
+}
+
+// Var checks value against validate tags such as "required,min=1"
+func (e *Errors) Var(field string, value any, tags string) {
+	e.Check(field, Var(value, tags))
+}
+
+// Validator is implemented by types with rules beyond their struct tags;
+// Struct calls Validate after the tags pass and merges returned Errors
+type Validator interface {
+	Validate() error
+}
+
+// engine is shared by Struct and Var; validator.Validate caches struct
+// metadata and is safe for concurrent use
+var engine = newEngine()
+
+// Engine returns the validator used by Struct and Var for advanced
+// configuration such as struct-level rules or aliases
+func Engine() *validator.Validate {
+	return engine
+}
+
+func newEngine() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// report JSON field names rather than Go field names
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			return ""
+		case "":
+			return f.Name
+		}
+		return name
+	})
+	for tag, rule := range stringRules {
+		_ = v.RegisterValidation(tag, func(fl validator.FieldLevel) bool {
+			if fl.Field().Kind() != reflect.String {
+				return false
+			}
+			return rule.check(fl.Field().String())
+		})
+	}
+	return v
+}
+
+// Register adds a custom validate tag, e.g.
+//
+//	validate.Register("slug", func(fl validator.FieldLevel) bool {
+//		return slugPattern.MatchString(fl.Field().String())
+//	})
+//
+// Register tags during initialization, before values are validated.
+func Register(tag string, fn validator.Func) error {
+	return engine.RegisterValidation(tag, fn)
+}
+
+// Struct checks v's validate struct tags, then its Validate method when it
+// implements Validator. It returns Errors listing every failure, or nil.
+// Values that are not structs or pointers to structs are not validated.
+func Struct(v any) error {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs Errors
+	if err := convert(engine.Struct(v), &errs, true); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	if val, ok := asValidator(v); ok {
+		errs.Check("", val.Validate())
+	}
+	return errs.Err()
+}
+
+// asValidator also finds Validate methods with pointer receivers on struct values
+func asValidator(v any) (Validator, bool) {
+	if val, ok := v.(Validator); ok {
+		return val, true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Struct {
+		return nil, false
+	}
+	p := reflect.New(rv.Type())
+	p.Elem().Set(rv)
+	val, ok := p.Interface().(Validator)
+	return val, ok
+}
+
+// Var checks a single value against validate tags, returning Errors with an
+// empty Field on failure
+func Var(value any, tags string) error {
+	var errs Errors
+	if err := convert(engine.Var(value, tags), &errs, false); err != nil {
+		return err
+	}
+	return errs.Err()
+}
+
+// convert appends validator errors to errs; other errors, such as
+// validating an invalid value, are returned as is
+func convert(err error, errs *Errors, namespaced bool) error {
+	if err == nil {
+		return nil
+	}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	for _, fe := range verrs {
+		field := ""
+		if namespaced {
+			field = fieldPath(fe.Namespace())
+		}
+		errs.Add(field, fe.Tag(), fieldMessage(fe))
+	}
+	return nil
+}
+
+// fieldPath drops the root struct name from a validator namespace
+func fieldPath(namespace string) string {
+	if _, rest, ok := strings.Cut(namespace, "."); ok {
+		return rest
+	}
+	return namespace
+}
+
+func joinPath(parent, child string) string {
+	switch {
+	case parent == "":
+		return child
+	case child == "":
+		return parent
+	}
+	return parent + "." + child
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	if rule, ok := stringRules[fe.Tag()]; ok {
+		return rule.message
+	}
+	switch fe.Tag() {
+	case "required", "required_if", "required_unless", "required_with", "required_without":
+		return "is required"
+	case "min", "gte":
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max", "lte":
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "len":
+		return fmt.Sprintf("must have length %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fe.Param())
+	case "email":
+		return "must be a valid email address"
+	case "url", "uri", "http_url":
+		return "must be a valid URL"
+	case "uuid", "uuid4", "uuid7":
+		return "must be a valid UUID"
+	case "cidr", "cidrv4", "cidrv6":
+		return "must be a valid CIDR block"
+	case "ip", "ipv4", "ipv6":
+		return "must be a valid IP address"
+	}
+	if fe.Param() != "" {
+		return fmt.Sprintf("failed %s=%s validation", fe.Tag(), fe.Param())
+	}
+	return fmt.Sprintf("failed %s validation", fe.Tag())
+}
@@ -0,0 +1,88 @@
+package validate
+
+import (
+	"errors"
+	"testing"
+)
+
+type target struct {
+	Name     string `json:"name" validate:"required"`
+	RoleArn  string `json:"roleArn" validate:"omitempty,arn"`
+	Region   string `json:"region" validate:"aws_region"`
+	Account  string `json:"account" validate:"omitempty,aws_account_id"`
+	Interval string `json:"interval" validate:"duration"`
+	Subnet   string `json:"subnet" validate:"omitempty,cidr"`
+	Replicas int    `json:"replicas"`
+}
+
+func (t target) Validate() error {
+	var errs Errors
+	if t.Replicas > 3 && t.Region == "us-east-1" {
+		errs.Add("replicas", "max", "must be at most 3 in us-east-1")
+	}
+	return errs.Err()
+}
+
+func TestStruct(t *testing.T) {
+	err := Struct(&target{RoleArn: "role", Region: "mars-1", Interval: "soon", Subnet: "10.0.0.0"})
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected Errors, got %v", err)
+	}
+	got := map[string]string{}
+	for _, fe := range errs {
+		got[fe.Field] = fe.Rule
+	}
+	want := map[string]string{"name": "required", "roleArn": "arn", "region": "aws_region", "interval": "duration", "subnet": "cidr"}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	for field, rule := range want {
+		if got[field] != rule {
+			t.Fatalf("expected %s to fail %s, got %v", field, rule, errs)
+		}
+	}
+
+	valid := target{Name: "jobs", RoleArn: "arn:aws:iam::123456789012:role/jobs", Region: "us-gov-west-1", Account: "123456789012", Interval: "5m", Subnet: "10.0.0.0/16"}
+	if err := Struct(valid); err != nil {
+		t.Fatal(err)
+	}
+
+	// the Validate method runs once the tags pass
+	valid.Region, valid.Replicas = "us-east-1", 5
+	if err := Struct(valid); err == nil || err.Error() != "validation failed: replicas must be at most 3 in us-east-1" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var errs Errors
+	errs.Require("name", "")
+	errs.Check("queueArn", ARN("arn:aws:sqs:us-east-1:123456789012:jobs"))
+	errs.Check("id", UUID("1234"))
+	errs.Check("endpoint", URL("localhost"))
+	errs.Var("port", 0, "min=1")
+	errs.Check("target", Struct(target{Name: "x", Region: "us-east-1", Interval: "1s"}))
+	errs.Check("target", Struct(target{Region: "us-east-1", Interval: "1s"}))
+	errs.Check("other", errors.New("boom"))
+
+	want := []FieldError{
+		{Field: "name", Rule: "required", Message: "is required"},
+		{Field: "id", Rule: "uuid", Message: "must be a valid UUID"},
+		{Field: "endpoint", Rule: "url", Message: "must be a valid URL"},
+		{Field: "port", Rule: "min", Message: "must be at least 1"},
+		{Field: "target.name", Rule: "required", Message: "is required"},
+		{Field: "other", Rule: "invalid", Message: "boom"},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	for i := range want {
+		if errs[i] != want[i] {
+			t.Fatalf("error %d: got %+v, want %+v", i, errs[i], want[i])
+		}
+	}
+	if (Errors{}).Err() != nil {
+		t.Fatal("expected nil error for no failures")
+	}
+}
@@ -0,0 +1,50 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/queue"
+)
+
+// DeadLetterRecord is the message QueueDeadLetter publishes for a failed
+// delivery. Endpoint secrets are not included.
+type DeadLetterRecord struct {
+	EndpointID string          `json:"endpoint_id"`
+	URL        string          `json:"url"`
+	EventID    string          `json:"event_id"`
+	EventType  string          `json:"event_type"`
+	Body       json.RawMessage `json:"body"`
+	Attempts   int             `json:"attempts"`
+	StatusCode int             `json:"status_code,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	FailedAt   time.Time       `json:"failed_at"`
+}
+
+// QueueDeadLetter publishes failed deliveries to q as DeadLetterRecord JSON
+// with event_type and endpoint_id attributes, e.g. to an SQS queue with
+// queue.NewSQSQueue(aws.GetSQSClient(), url, nil)
+func QueueDeadLetter(q queue.Queue) DeadLetter {
+	return DeadLetterFunc(func(ctx context.Context, d *Delivery) error {
+		rec := DeadLetterRecord{
+			EndpointID: d.Endpoint.key(),
+			URL:        d.Endpoint.URL,
+			EventID:    d.Event.ID,
+			EventType:  d.Event.Type,
+			Body:       d.Body,
+			Attempts:   d.Attempt,
+			StatusCode: d.StatusCode,
+			FailedAt:   time.Now().UTC(),
+		}
+		if d.Err != nil {
+			rec.Error = d.Err.Error()
+		}
+		_, err := queue.PublishJSON(ctx, q, rec, map[string]string{"event_type": rec.EventType, "endpoint_id": rec.EndpointID})
+		if err != nil {
+			return errs.Errorf("failed to publish dead letter for webhook %s: %w", rec.EventID, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,53 @@
+package webhooks
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// ErrBlockedAddress is returned for deliveries to loopback, private,
+// link-local and other non-public addresses, which customer-supplied URLs
+// could otherwise use to reach internal services
+var ErrBlockedAddress = errs.New(errs.InvalidArgument, "webhooks: endpoint address is not public")
+
+// sharedAddressSpace is the carrier-grade NAT range, which IsPrivate omits
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicTransport dials only public addresses. The check runs on the
+// resolved address at dial time, so DNS rebinding and redirects cannot
+// bypass it, and proxies are not used since they would dial for us.
+func publicTransport() *http.Transport {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: dialPublic}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = dialer.DialContext
+	return t
+}
+
+func dialPublic(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return errs.Errorf("%s: %w", address, ErrBlockedAddress)
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil || !publicAddr(ip) {
+		return errs.Errorf("%s: %w", address, ErrBlockedAddress)
+	}
+	return nil
+}
+
+func publicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// noRedirects makes a redirect a failed delivery rather than a request to
+// another URL than the one the customer registered
+func noRedirects(*http.Request, []*http.Request) error {
+	return http.ErrUseLastResponse
+}
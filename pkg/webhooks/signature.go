@@ -0,0 +1,47 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header names set on every delivery
+const (
+	// IDHeader is the event ID, the same for every attempt and endpoint, so
+	// receivers can deduplicate
+	IDHeader = "Webhook-ID"
+	// EventHeader is the event type
+	EventHeader = "Webhook-Event"
+	// TimestampHeader is the Unix time the attempt was signed
+	TimestampHeader = "Webhook-Timestamp"
+	// SignatureHeader is t=<timestamp>,v1=<signature>, with a v1 entry per
+	// endpoint secret so receivers can rotate secrets without downtime
+	SignatureHeader = "Webhook-Signature"
+)
+
+// Sign returns the hex HMAC-SHA256 of "<unix timestamp>.<body>" under secret.
+// Binding the timestamp lets receivers reject replayed deliveries.
+func Sign(secret string, t time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(t.Unix(), 10)))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignatureHeaderValue returns the SignatureHeader value for body signed at t
+// by each of secrets
+func SignatureHeaderValue(secrets []string, t time.Time, body []byte) string {
+	var b strings.Builder
+	b.WriteString("t=")
+	b.WriteString(strconv.FormatInt(t.Unix(), 10))
+	for _, secret := range secrets {
+		b.WriteString(",v1=")
+		b.WriteString(Sign(secret, t, body))
+	}
+	return b.String()
+}
@@ -0,0 +1,477 @@
+// Package webhooks delivers events to customer HTTP endpoints. Each delivery
+// is a signed JSON POST, retried with backoff on network errors, 408, 429 and
+// 5xx responses, and handed to a DeadLetter, such as an SQS queue, once its
+// attempts run out. Concurrency is bounded overall and per endpoint so one
+// slow customer cannot starve the others. Endpoint URLs come from customers,
+// so the default client dials only public addresses and follows no redirects.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+	"github.com/bdlilley/easygo/pkg/httpclient"
+	"github.com/bdlilley/easygo/pkg/ids"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/bdlilley/easygo/pkg/retry"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrClosed is returned by Enqueue after Close
+var ErrClosed = errs.New(errs.Unavailable, "webhooks: sender is closed")
+
+// Endpoint is a customer's webhook receiver
+type Endpoint struct {
+	// ID identifies the endpoint in callbacks and dead letters (default: URL)
+	ID  string
+	URL string
+	// Secrets sign each delivery; list the new secret first while rotating
+	Secrets []string
+	// Header is added to every delivery to the endpoint
+	Header http.Header
+}
+
+func (e *Endpoint) key() string {
+	if e.ID != "" {
+		return e.ID
+	}
+	return e.URL
+}
+
+// Event is a webhook event, delivered as the JSON object
+// {"id", "type", "created_at", "data"}
+type Event struct {
+	// ID deduplicates deliveries at the receiver (default: a new evt_ ID for
+	// each Send or Enqueue; set it to fan one event out under one ID)
+	ID   string
+	Type string
+	// Time is when the event happened (default: now, set on each Send or Enqueue)
+	Time time.Time
+	Data any
+}
+
+// Status is the state of a delivery
+type Status string
+
+const (
+	StatusSucceeded Status = "succeeded"
+	// StatusRetrying is reported after a failed attempt that will be retried
+	StatusRetrying Status = "retrying"
+	// StatusFailed is reported when a delivery gives up and is dead-lettered
+	StatusFailed Status = "failed"
+)
+
+// Delivery is the state of an event's delivery to one endpoint after an
+// attempt
+type Delivery struct {
+	Endpoint *Endpoint
+	Event    *Event
+	// Body is the signed request body
+	Body    []byte
+	Status  Status
+	Attempt int
+	// StatusCode is the attempt's response status, or 0 without a response
+	StatusCode int
+	// Err is why the attempt failed
+	Err      error
+	Duration time.Duration
+	// NextAttempt is when a retrying delivery is attempted again
+	NextAttempt time.Time
+}
+
+// DeadLetter receives deliveries that failed permanently or ran out of
+// attempts, e.g. to replay them later
+type DeadLetter interface {
+	DeadLetter(ctx context.Context, d *Delivery) error
+}
+
+// DeadLetterFunc adapts a function to a DeadLetter
+type DeadLetterFunc func(ctx context.Context, d *Delivery) error
+
+func (f DeadLetterFunc) DeadLetter(ctx context.Context, d *Delivery) error {
+	return f(ctx, d)
+}
+
+type NewSenderArgs struct {
+	// Client sends attempts; it should not retry itself (default: a client
+	// with retries disabled, a 10s timeout, no redirects and only public
+	// addresses dialed). A custom Client is used as is, without that guard.
+	Client *httpclient.Client
+	// AllowPrivateAddresses lets the default Client deliver to loopback and
+	// private addresses, e.g. for local development
+	AllowPrivateAddresses bool
+	// MaxAttempts is the total number of attempts per delivery (default: 6)
+	MaxAttempts int
+	// Backoff spaces attempts (default: 1s doubling up to 5m); Retry-After
+	// responses are honored up to Backoff.Max
+	Backoff retry.Backoff
+	// Concurrency bounds the attempts in flight (default: 20)
+	Concurrency int
+	// EndpointConcurrency bounds the attempts in flight per endpoint (default: 2)
+	EndpointConcurrency int
+	// MaxPending bounds the enqueued deliveries that have not finished;
+	// Enqueue blocks while it is reached (default: 1000)
+	MaxPending int
+	// DeadLetter receives failed deliveries (default: none, they are dropped)
+	DeadLetter DeadLetter
+	// OnStatus is called after every attempt, e.g. to record delivery status
+	// for customers
+	OnStatus func(ctx context.Context, d *Delivery)
+	// UserAgent is sent with every delivery (default: easygo-webhooks)
+	UserAgent string
+	// Logger logs attempts at debug and failed deliveries at warn (default: logging.Noop)
+	Logger logging.Logger
+}
+
+// Sender delivers webhooks. It is safe for concurrent use.
+type Sender struct {
+	client              *httpclient.Client
+	maxAttempts         int
+	backoff             retry.Backoff
+	sem                 chan struct{}
+	pending             chan struct{}
+	endpointConcurrency int
+	deadLetter          DeadLetter
+	onStatus            func(ctx context.Context, d *Delivery)
+	userAgent           string
+	logger              logging.Logger
+	now                 func() time.Time
+
+	mu        sync.Mutex
+	endpoints map[string]*endpointSlot
+	closed    bool
+	wg        sync.WaitGroup
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// endpointSlot bounds one endpoint's attempts, kept while refs are held
+type endpointSlot struct {
+	sem  chan struct{}
+	refs int
+}
+
+// NewSender creates a Sender; args may be nil
+func NewSender(args *NewSenderArgs) *Sender {
+	if args == nil {
+		args = &NewSenderArgs{}
+	}
+	s := &Sender{
+		client:              args.Client,
+		maxAttempts:         args.MaxAttempts,
+		backoff:             args.Backoff,
+		endpointConcurrency: args.EndpointConcurrency,
+		deadLetter:          args.DeadLetter,
+		onStatus:            args.OnStatus,
+		userAgent:           args.UserAgent,
+		logger:              args.Logger,
+		now:                 time.Now,
+		endpoints:           map[string]*endpointSlot{},
+	}
+	if s.client == nil {
+		clientArgs := &httpclient.NewClientArgs{MaxRetries: -1, CheckRedirect: noRedirects}
+		if !args.AllowPrivateAddresses {
+			clientArgs.Transport = publicTransport()
+		}
+		s.client = httpclient.NewClient(clientArgs)
+	}
+	if s.maxAttempts <= 0 {
+		s.maxAttempts = 6
+	}
+	if s.backoff.Initial <= 0 {
+		s.backoff.Initial = time.Second
+	}
+	if s.backoff.Max <= 0 {
+		s.backoff.Max = 5 * time.Minute
+	}
+	concurrency := args.Concurrency
+	if concurrency <= 0 {
+		concurrency = 20
+	}
+	s.sem = make(chan struct{}, concurrency)
+	if s.endpointConcurrency <= 0 {
+		s.endpointConcurrency = 2
+	}
+	maxPending := args.MaxPending
+	if maxPending <= 0 {
+		maxPending = 1000
+	}
+	s.pending = make(chan struct{}, maxPending)
+	if s.userAgent == "" {
+		s.userAgent = "easygo-webhooks"
+	}
+	if s.logger == nil {
+		s.logger = logging.Noop()
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return s
+}
+
+// Send delivers ev to ep, retrying until it succeeds, fails permanently or
+// runs out of attempts, and returns the last attempt. Failed deliveries are
+// dead-lettered and returned with an error. ev is not modified; defaults
+// are set on the copy in Delivery.Event.
+func (s *Sender) Send(ctx context.Context, ep *Endpoint, ev *Event) (*Delivery, error) {
+	return s.send(ctx, ep, s.prepare(ev))
+}
+
+func (s *Sender) send(ctx context.Context, ep *Endpoint, ev *Event) (*Delivery, error) {
+	body, err := s.encode(ev)
+	if err != nil {
+		return nil, err
+	}
+	d := &Delivery{Endpoint: ep, Event: ev, Body: body}
+	log := logging.WithTrace(ctx, s.logger).WithFields(logrus.Fields{
+		"webhook_endpoint": ep.key(),
+		"webhook_event":    ev.ID,
+		"webhook_type":     ev.Type,
+	})
+
+	for {
+		d.Attempt++
+		start := s.now()
+		retryAfter, retryable, err := s.attempt(ctx, d)
+		d.Duration = s.now().Sub(start)
+		d.Err = err
+		d.NextAttempt = time.Time{}
+		switch {
+		case err == nil:
+			d.Status = StatusSucceeded
+		case retryable && d.Attempt < s.maxAttempts && ctx.Err() == nil:
+			d.Status = StatusRetrying
+			wait := s.backoff.Delay(d.Attempt - 1)
+			if retryAfter > 0 {
+				wait = min(retryAfter, s.backoff.Max)
+			}
+			d.NextAttempt = s.now().Add(wait)
+		default:
+			d.Status = StatusFailed
+		}
+		log.WithFields(logrus.Fields{"attempt": d.Attempt, "status": d.Status, "status_code": d.StatusCode}).Debug("webhook attempt completed")
+		s.report(ctx, d)
+
+		switch d.Status {
+		case StatusSucceeded:
+			return d, nil
+		case StatusFailed:
+			log.WithError(err).WithField("attempts", d.Attempt).Warn("webhook delivery failed")
+			return d, s.fail(ctx, d)
+		}
+
+		t := time.NewTimer(d.NextAttempt.Sub(s.now()))
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			d.Status = StatusFailed
+			d.Err = errs.Errorf("canceled after %d attempts: %w", d.Attempt, ctx.Err())
+			s.report(ctx, d)
+			return d, s.fail(ctx, d)
+		}
+	}
+}
+
+// Enqueue delivers ev to ep in the background, keeping ctx's values but not
+// its cancellation. It blocks while MaxPending deliveries are unfinished,
+// until ctx is done. Close waits for enqueued deliveries.
+func (s *Sender) Enqueue(ctx context.Context, ep *Endpoint, ev *Event) error {
+	select {
+	case s.pending <- struct{}{}:
+	case <-s.ctx.Done():
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		<-s.pending
+		return ErrClosed
+	}
+	ev = s.prepare(ev)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() { <-s.pending }()
+		ctx, stop := mergeCancel(context.WithoutCancel(ctx), s.ctx)
+		defer stop()
+		_, _ = s.send(ctx, ep, ev)
+	}()
+	return nil
+}
+
+// Close stops accepting deliveries and waits for enqueued ones to finish.
+// When ctx is done first, the remaining deliveries are canceled, which
+// dead-letters them, and Close waits for that.
+func (s *Sender) Close(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// prepare returns a copy of ev with its ID and Time defaulted, so deliveries
+// never write to the caller's event
+func (s *Sender) prepare(ev *Event) *Event {
+	c := *ev
+	if c.ID == "" {
+		c.ID = ids.Prefixed("evt")
+	}
+	if c.Time.IsZero() {
+		c.Time = s.now()
+	}
+	return &c
+}
+
+func (s *Sender) encode(ev *Event) ([]byte, error) {
+	body, err := json.Marshal(struct {
+		ID        string    `json:"id"`
+		Type      string    `json:"type"`
+		CreatedAt time.Time `json:"created_at"`
+		Data      any       `json:"data"`
+	}{ev.ID, ev.Type, ev.Time.UTC(), ev.Data})
+	if err != nil {
+		return nil, errs.Errorf("failed to marshal webhook event %s: %w", ev.ID, err)
+	}
+	return body, nil
+}
+
+// attempt posts d once, reporting whether a failure is worth retrying and any
+// Retry-After delay
+func (s *Sender) attempt(ctx context.Context, d *Delivery) (time.Duration, bool, error) {
+	d.StatusCode = 0
+	release, err := s.acquire(ctx, d.Endpoint.key())
+	if err != nil {
+		return 0, false, err
+	}
+	defer release()
+
+	req, err := s.client.NewRequest(ctx, http.MethodPost, d.Endpoint.URL, bytes.NewReader(d.Body))
+	if err != nil {
+		return 0, false, err
+	}
+	for k, v := range d.Endpoint.Header {
+		req.Header[k] = v
+	}
+	now := s.now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", s.userAgent)
+	req.Header.Set(IDHeader, d.Event.ID)
+	req.Header.Set(EventHeader, d.Event.Type)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	if len(d.Endpoint.Secrets) > 0 {
+		req.Header.Set(SignatureHeader, SignatureHeaderValue(d.Endpoint.Secrets, now, d.Body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, true, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	d.StatusCode = resp.StatusCode
+
+	switch code := resp.StatusCode; {
+	case code >= 200 && code <= 299:
+		return 0, false, nil
+	case code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500:
+		var retryAfter time.Duration
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			retryAfter = time.Duration(secs) * time.Second
+		}
+		return retryAfter, true, errs.Newf(errs.Internal, "endpoint returned status %d", code)
+	default:
+		return 0, false, errs.Newf(errs.Internal, "endpoint returned status %d", code)
+	}
+}
+
+// acquire takes a slot overall and for the endpoint
+func (s *Sender) acquire(ctx context.Context, endpoint string) (func(), error) {
+	s.mu.Lock()
+	slot, ok := s.endpoints[endpoint]
+	if !ok {
+		slot = &endpointSlot{sem: make(chan struct{}, s.endpointConcurrency)}
+		s.endpoints[endpoint] = slot
+	}
+	slot.refs++
+	s.mu.Unlock()
+	unref := func() {
+		s.mu.Lock()
+		if slot.refs--; slot.refs == 0 {
+			delete(s.endpoints, endpoint)
+		}
+		s.mu.Unlock()
+	}
+
+	select {
+	case slot.sem <- struct{}{}:
+	case <-ctx.Done():
+		unref()
+		return nil, ctx.Err()
+	}
+	select {
+	case s.sem <- struct{}{}:
+	case <-ctx.Done():
+		<-slot.sem
+		unref()
+		return nil, ctx.Err()
+	}
+	return func() {
+		<-s.sem
+		<-slot.sem
+		unref()
+	}, nil
+}
+
+func (s *Sender) report(ctx context.Context, d *Delivery) {
+	if s.onStatus != nil {
+		snapshot := *d
+		s.onStatus(ctx, &snapshot)
+	}
+}
+
+// fail dead-letters d and returns the delivery's error
+func (s *Sender) fail(ctx context.Context, d *Delivery) error {
+	err := errs.Errorf("webhook %s to %s failed after %d attempts: %w", d.Event.ID, d.Endpoint.key(), d.Attempt, d.Err)
+	if s.deadLetter == nil {
+		return err
+	}
+	// dead-letter even when the delivery was canceled by shutdown
+	if dlErr := s.deadLetter.DeadLetter(context.WithoutCancel(ctx), d); dlErr != nil {
+		return errors.Join(err, errs.Errorf("failed to dead-letter webhook: %w", dlErr))
+	}
+	return err
+}
+
+// mergeCancel returns ctx canceled when either ctx or other is done
+func mergeCancel(ctx, other context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(other, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
@@ -0,0 +1,184 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/queue"
+	"github.com/bdlilley/easygo/pkg/retry"
+)
+
+func TestSend(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+		want := "t=" + r.Header.Get(TimestampHeader) + ",v1=" + Sign("new", time.Unix(ts, 0), body) + ",v1=" + Sign("old", time.Unix(ts, 0), body)
+		if r.Header.Get(SignatureHeader) != want || r.Header.Get(IDHeader) != "evt_1" || r.Header.Get(EventHeader) != "order.paid" {
+			t.Errorf("got headers %v for %s", r.Header, body)
+		}
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var statuses []Status
+	s := NewSender(&NewSenderArgs{
+		AllowPrivateAddresses: true,
+		Backoff:               retry.Backoff{Initial: time.Millisecond, Max: time.Millisecond},
+		OnStatus: func(_ context.Context, d *Delivery) {
+			mu.Lock()
+			defer mu.Unlock()
+			statuses = append(statuses, d.Status)
+		},
+	})
+	ep := &Endpoint{URL: server.URL, Secrets: []string{"new", "old"}}
+	d, err := s.Send(context.Background(), ep, &Event{ID: "evt_1", Type: "order.paid", Data: map[string]int{"amount": 5}})
+	if err != nil || d.Attempt != 3 || d.StatusCode != http.StatusNoContent {
+		t.Fatalf("got %+v %v", d, err)
+	}
+	if got := statuses; len(got) != 3 || got[0] != StatusRetrying || got[2] != StatusSucceeded {
+		t.Fatalf("got statuses %v", got)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(d.Body, &body); err != nil || body["id"] != "evt_1" || body["data"].(map[string]any)["amount"] != 5.0 {
+		t.Fatalf("got body %s", d.Body)
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer server.Close()
+
+	dlq := queue.NewMemoryQueue(nil)
+	s := NewSender(&NewSenderArgs{AllowPrivateAddresses: true, DeadLetter: QueueDeadLetter(dlq)})
+	if err := s.Enqueue(context.Background(), &Endpoint{ID: "ep_1", URL: server.URL}, &Event{Type: "order.paid"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("got %d attempts, want a 410 not to be retried", calls.Load())
+	}
+	if err := s.Enqueue(context.Background(), &Endpoint{URL: server.URL}, &Event{}); err != ErrClosed {
+		t.Fatalf("got %v after Close", err)
+	}
+
+	if dlq.Len() != 1 {
+		t.Fatalf("got %d dead letters", dlq.Len())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var rec DeadLetterRecord
+	_ = dlq.Subscribe(ctx, queue.JSONHandler(func(_ context.Context, v DeadLetterRecord, _ *queue.Message) error {
+		rec = v
+		cancel()
+		return nil
+	}, nil))
+	if rec.EndpointID != "ep_1" || rec.StatusCode != http.StatusGone || rec.Attempts != 1 || !strings.HasPrefix(rec.EventID, "evt_") {
+		t.Fatalf("got %+v", rec)
+	}
+}
+
+func TestEndpointConcurrency(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		inFlight.Add(-1)
+	}))
+	defer server.Close()
+
+	s := NewSender(&NewSenderArgs{AllowPrivateAddresses: true, EndpointConcurrency: 2})
+	// one event fanned out is shared by the deliveries but never written to
+	ev := &Event{Type: "ping"}
+	for range 10 {
+		_ = s.Enqueue(context.Background(), &Endpoint{URL: server.URL}, ev)
+	}
+	_ = s.Close(context.Background())
+	if peak.Load() != 2 {
+		t.Fatalf("got %d concurrent deliveries, want 2", peak.Load())
+	}
+	if ev.ID != "" || !ev.Time.IsZero() {
+		t.Fatalf("the caller's event was modified: %+v", ev)
+	}
+}
+
+func TestMaxPending(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	s := NewSender(&NewSenderArgs{AllowPrivateAddresses: true, MaxPending: 1})
+	ep := &Endpoint{URL: server.URL}
+	if err := s.Enqueue(context.Background(), ep, &Event{Type: "ping"}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Enqueue(ctx, ep, &Event{Type: "ping"}); err != context.DeadlineExceeded {
+		t.Fatalf("got %v, want Enqueue to block while a delivery is pending", err)
+	}
+	close(release)
+	if err := s.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrivateAddressesBlocked(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	s := NewSender(&NewSenderArgs{MaxAttempts: 1})
+	if _, err := s.Send(context.Background(), &Endpoint{URL: server.URL}, &Event{Type: "ping"}); !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("got %v, want ErrBlockedAddress", err)
+	}
+	if calls.Load() != 0 {
+		t.Fatal("the loopback endpoint was called")
+	}
+	for addr, public := range map[string]bool{
+		"8.8.8.8": true, "2606:4700::1111": true, "10.0.0.1": false, "169.254.169.254": false,
+		"::1": false, "fd00::1": false, "100.64.0.1": false, "::ffff:127.0.0.1": false,
+	} {
+		if publicAddr(netip.MustParseAddr(addr)) != public {
+			t.Errorf("%s: public = %v", addr, !public)
+		}
+	}
+
+	redirect := httptest.NewServer(http.RedirectHandler(server.URL, http.StatusFound))
+	defer redirect.Close()
+	d, err := NewSender(&NewSenderArgs{AllowPrivateAddresses: true, MaxAttempts: 1}).Send(context.Background(), &Endpoint{URL: redirect.URL}, &Event{Type: "ping"})
+	if err == nil || d.StatusCode != http.StatusFound || calls.Load() != 0 {
+		t.Fatalf("got %v %v, want the redirect not followed", d, err)
+	}
+}
@@ -0,0 +1,94 @@
+package workerpool
+
+import (
+	"context"
+	"sync"
+)
+
+// Result is the outcome of one item processed by Map or Stream
+type Result[R any] struct {
+	// Index is the item's position in the input
+	Index int
+	Value R
+	Err   error
+}
+
+// Map applies fn to every item with bounded concurrency and returns the
+// results in input order. Items not started before ctx is done get ctx's error.
+func Map[T, R any](ctx context.Context, items []T, fn func(ctx context.Context, item T) (R, error), opts *Options) []Result[R] {
+	results := make([]Result[R], len(items))
+	done := make([]bool, len(items))
+	for r := range Stream(ctx, sliceChan(items), fn, opts) {
+		results[r.Index] = r
+		done[r.Index] = true
+	}
+	for i := range results {
+		if !done[i] {
+			results[i] = Result[R]{Index: i, Err: ctx.Err()}
+		}
+	}
+	return results
+}
+
+// Stream applies fn to items read from in with bounded concurrency and sends
+// results in completion order. The returned channel is closed once in is
+// closed, or ctx is done, and every started item has finished.
+func Stream[T, R any](ctx context.Context, in <-chan T, fn func(ctx context.Context, item T) (R, error), opts *Options) <-chan Result[R] {
+	o := opts.withDefaults()
+	out := make(chan Result[R], o.Concurrency)
+
+	type indexed struct {
+		index int
+		item  T
+	}
+	work := make(chan indexed)
+	go func() {
+		defer close(work)
+		for i := 0; ; i++ {
+			select {
+			case item, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case work <- indexed{i, item}:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(o.Concurrency)
+	for w := 0; w < o.Concurrency; w++ {
+		go func() {
+			defer wg.Done()
+			for job := range work {
+				r := Result[R]{Index: job.index}
+				r.Err = run(ctx, o.TaskTimeout, func(ctx context.Context) error {
+					var err error
+					r.Value, err = fn(ctx, job.item)
+					return err
+				})
+				out <- r
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+func sliceChan[T any](items []T) <-chan T {
+	ch := make(chan T, len(items))
+	for _, item := range items {
+		ch <- item
+	}
+	close(ch)
+	return ch
+}
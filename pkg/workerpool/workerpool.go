@@ -0,0 +1,144 @@
+// Package workerpool runs tasks with bounded concurrency, per-task timeouts
+// and panic isolation.
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// ErrPoolClosed is returned by Submit after Close has been called
+var ErrPoolClosed = errs.New(errs.Unavailable, "worker pool is closed")
+
+// PanicError is the error of a task that panicked
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", e.Value)
+}
+
+type Options struct {
+	// Concurrency is the number of tasks run at once (default: GOMAXPROCS)
+	Concurrency int
+	// QueueSize is the number of submitted tasks waiting for a worker before
+	// Submit blocks (default: Concurrency)
+	QueueSize int
+	// TaskTimeout bounds each task's context (default: none)
+	TaskTimeout time.Duration
+}
+
+func (o *Options) withDefaults() Options {
+	c := Options{}
+	if o != nil {
+		c = *o
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = runtime.GOMAXPROCS(0)
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = c.Concurrency
+	}
+	return c
+}
+
+// Task is a unit of work run by a Pool
+type Task func(ctx context.Context) error
+
+// Pool runs submitted tasks on a fixed number of workers. When its context is
+// canceled, queued tasks are skipped with the context's error and running
+// tasks see the cancellation; Close waits for them to return.
+type Pool struct {
+	ctx  context.Context
+	opts Options
+
+	mu     sync.RWMutex
+	closed bool
+	tasks  chan Task
+	wg     sync.WaitGroup
+
+	errMu sync.Mutex
+	errs  []error
+}
+
+// New creates a Pool and starts its workers. Call Close to wait for them.
+func New(ctx context.Context, opts *Options) *Pool {
+	p := &Pool{ctx: ctx, opts: opts.withDefaults()}
+	p.tasks = make(chan Task, p.opts.QueueSize)
+	p.wg.Add(p.opts.Concurrency)
+	for i := 0; i < p.opts.Concurrency; i++ {
+		go p.worker()
+	}
+	return p
+}
+
+// Submit queues task, blocking while the queue is full. It fails when the
+// pool is closed or its context is done.
+func (p *Pool) Submit(task Task) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrPoolClosed
+	}
+	select {
+	case p.tasks <- task:
+		return nil
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	}
+}
+
+// Close stops accepting tasks, waits for queued and running tasks, and
+// returns their errors joined
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.tasks)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+
+	p.errMu.Lock()
+	defer p.errMu.Unlock()
+	return errors.Join(p.errs...)
+}
+
+func (p *Pool) worker() {
+	defer p.wg.Done()
+	for task := range p.tasks {
+		if err := run(p.ctx, p.opts.TaskTimeout, task); err != nil {
+			p.errMu.Lock()
+			p.errs = append(p.errs, err)
+			p.errMu.Unlock()
+		}
+	}
+}
+
+// run calls task with the pool context and timeout, converting panics to a
+// *PanicError. Tasks whose context is already done are not started.
+func run(ctx context.Context, timeout time.Duration, task Task) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return task(ctx)
+}
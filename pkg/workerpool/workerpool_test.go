@@ -0,0 +1,65 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	p := New(context.Background(), &Options{Concurrency: 2})
+	var running, peak atomic.Int32
+	for i := 0; i < 6; i++ {
+		err := p.Submit(func(context.Context) error {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				old := peak.Load()
+				if n <= old || peak.CompareAndSwap(old, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Submit(func(context.Context) error { panic("boom") }); err != nil {
+		t.Fatal(err)
+	}
+
+	err := p.Close()
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "boom" {
+		t.Fatalf("Close() = %v, want PanicError", err)
+	}
+	if peak.Load() > 2 {
+		t.Fatalf("ran %d tasks at once, want at most 2", peak.Load())
+	}
+	if err := p.Submit(func(context.Context) error { return nil }); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("Submit after Close = %v", err)
+	}
+}
+
+func TestMap(t *testing.T) {
+	results := Map(context.Background(), []int{1, 2, 3, 4}, func(_ context.Context, n int) (int, error) {
+		if n == 3 {
+			return 0, errors.New("three")
+		}
+		time.Sleep(time.Duration(5-n) * time.Millisecond)
+		return n * n, nil
+	}, &Options{Concurrency: 4})
+
+	for i, r := range results {
+		if r.Index != i {
+			t.Fatalf("results[%d].Index = %d", i, r.Index)
+		}
+	}
+	if results[1].Value != 4 || results[2].Err == nil || results[3].Value != 16 {
+		t.Fatalf("results = %+v", results)
+	}
+}
@@ -0,0 +1,122 @@
+package easygo
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/rds/auth"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+const (
+	// rdsTokenLifetime is how long RDS accepts an IAM auth token
+	rdsTokenLifetime = 15 * time.Minute
+	// rdsTokenRefreshWindow is how long before expiry a cached token is regenerated
+	rdsTokenRefreshWindow = 5 * time.Minute
+)
+
+type NewRDSTokenSourceArgs struct {
+	// Host is the DB instance or cluster endpoint
+	Host string
+	// Port defaults to 5432
+	Port int
+	// DBUser is the database user mapped to the IAM identity
+	DBUser string
+	// Region defaults to the client's region
+	Region string
+}
+
+// RDSTokenSource generates RDS/Aurora IAM authentication tokens and reuses them
+// until shortly before their 15-minute expiry
+type RDSTokenSource struct {
+	client   *EGAwsClient
+	endpoint string
+	region   string
+	dbUser   string
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// NewRDSTokenSource creates a token source using the client's credentials.
+//
+// With pgx, set the password before each connection:
+//
+//	poolCfg.BeforeConnect = func(ctx context.Context, cc *pgx.ConnConfig) error {
+//		token, err := src.Token(ctx)
+//		cc.Password = token
+//		return err
+//	}
+func (c *EGAwsClient) NewRDSTokenSource(args *NewRDSTokenSourceArgs) (*RDSTokenSource, error) {
+	if args.Host == "" || args.DBUser == "" {
+		return nil, errs.New(errs.Internal, "Host and DBUser are required")
+	}
+
+	port := args.Port
+	if port == 0 {
+		port = 5432
+	}
+	region := args.Region
+	if region == "" {
+		region = c.cfg.Region
+	}
+
+	return &RDSTokenSource{
+		client:   c,
+		endpoint: fmt.Sprintf("%s:%d", args.Host, port),
+		region:   region,
+		dbUser:   args.DBUser,
+	}, nil
+}
+
+// Token returns a valid auth token, generating a new one when the cached token is close to expiry
+func (s *RDSTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Until(s.expiresAt) > rdsTokenRefreshWindow {
+		return s.token, nil
+	}
+
+	issuedAt := time.Now()
+	token, err := auth.BuildAuthToken(ctx, s.endpoint, s.region, s.dbUser, s.client.cfg.Credentials)
+	if err != nil {
+		return "", errs.Errorf("failed to build RDS auth token: %w", err)
+	}
+	s.token = token
+	s.expiresAt = issuedAt.Add(rdsTokenLifetime)
+
+	return token, nil
+}
+
+// Connector returns a database/sql connector that opens each new connection with
+// a fresh token. dsn builds the driver-specific connection string from the token.
+//
+//	db := sql.OpenDB(src.Connector(&pq.Driver{}, func(token string) string {
+//		return fmt.Sprintf("host=%s user=%s password=%s dbname=app sslmode=require", host, user, token)
+//	}))
+func (s *RDSTokenSource) Connector(drv driver.Driver, dsn func(token string) string) driver.Connector {
+	return &rdsConnector{source: s, driver: drv, dsn: dsn}
+}
+
+type rdsConnector struct {
+	source *RDSTokenSource
+	driver driver.Driver
+	dsn    func(token string) string
+}
+
+func (c *rdsConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	token, err := c.source.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.driver.Open(c.dsn(token))
+}
+
+func (c *rdsConnector) Driver() driver.Driver {
+	return c.driver
+}
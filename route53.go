@@ -0,0 +1,99 @@
+package easygo
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+type route53ChangeOptions struct {
+	comment string
+	wait    bool
+	maxWait time.Duration
+}
+
+// Route53ChangeOption configures a record change
+type Route53ChangeOption func(*route53ChangeOptions)
+
+// WithRoute53Comment sets the comment on the change batch
+func WithRoute53Comment(comment string) Route53ChangeOption {
+	return func(o *route53ChangeOptions) {
+		o.comment = comment
+	}
+}
+
+// WithWaitForSync waits up to maxWait for the change to reach INSYNC status
+func WithWaitForSync(maxWait time.Duration) Route53ChangeOption {
+	return func(o *route53ChangeOptions) {
+		o.wait = true
+		o.maxWait = maxWait
+	}
+}
+
+// UpsertRecord creates or replaces the recordType record set name in zoneID with values
+func (c *EGAwsClient) UpsertRecord(ctx context.Context, zoneID, name, recordType string, values []string, ttl int64, opts ...Route53ChangeOption) (*types.ChangeInfo, error) {
+	return c.ChangeRecords(ctx, zoneID, []types.Change{
+		newRoute53Change(types.ChangeActionUpsert, name, recordType, values, ttl),
+	}, opts...)
+}
+
+// DeleteRecord deletes the recordType record set name from zoneID; values and ttl must
+// match the existing record set exactly
+func (c *EGAwsClient) DeleteRecord(ctx context.Context, zoneID, name, recordType string, values []string, ttl int64, opts ...Route53ChangeOption) (*types.ChangeInfo, error) {
+	return c.ChangeRecords(ctx, zoneID, []types.Change{
+		newRoute53Change(types.ChangeActionDelete, name, recordType, values, ttl),
+	}, opts...)
+}
+
+// ChangeRecords submits changes to zoneID as one atomic change batch
+func (c *EGAwsClient) ChangeRecords(ctx context.Context, zoneID string, changes []types.Change, opts ...Route53ChangeOption) (*types.ChangeInfo, error) {
+	o := &route53ChangeOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	batch := &types.ChangeBatch{Changes: changes}
+	if o.comment != "" {
+		batch.Comment = aws.String(o.comment)
+	}
+
+	output, err := c.route53Client.ChangeResourceRecordSets(ctx, &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(zoneID),
+		ChangeBatch:  batch,
+	})
+	if err != nil {
+		return nil, errs.Errorf("failed to change resource record sets: %w", err)
+	}
+
+	if !o.wait {
+		return output.ChangeInfo, nil
+	}
+
+	waiter := route53.NewResourceRecordSetsChangedWaiter(c.route53Client)
+	result, err := waiter.WaitForOutput(ctx, &route53.GetChangeInput{Id: output.ChangeInfo.Id}, o.maxWait)
+	if err != nil {
+		return output.ChangeInfo, errs.Errorf("failed waiting for record change to sync: %w", err)
+	}
+
+	return result.ChangeInfo, nil
+}
+
+func newRoute53Change(action types.ChangeAction, name, recordType string, values []string, ttl int64) types.Change {
+	records := make([]types.ResourceRecord, len(values))
+	for i, v := range values {
+		records[i] = types.ResourceRecord{Value: aws.String(v)}
+	}
+	return types.Change{
+		Action: action,
+		ResourceRecordSet: &types.ResourceRecordSet{
+			Name:            aws.String(name),
+			Type:            types.RRType(recordType),
+			TTL:             aws.Int64(ttl),
+			ResourceRecords: records,
+		},
+	}
+}
@@ -0,0 +1,55 @@
+package easygo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/bdlilley/easygo/pkg/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// apiCallLoggingMiddleware logs each AWS API call at debug level. It runs in the
+// initialize step so duration and retry count cover every attempt of the call.
+func apiCallLoggingMiddleware(logger logging.Logger) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("EasyGoAPICallLogging",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				start := time.Now()
+				out, metadata, err := next.HandleInitialize(ctx, in)
+
+				fields := logrus.Fields{
+					"service":   awsmiddleware.GetServiceID(ctx),
+					"operation": awsmiddleware.GetOperationName(ctx),
+					"duration":  time.Since(start),
+				}
+				if results, ok := retry.GetAttemptResults(metadata); ok && len(results.Results) > 0 {
+					fields["retries"] = len(results.Results) - 1
+				}
+				if requestID, ok := awsmiddleware.GetRequestIDMetadata(metadata); ok {
+					fields["requestId"] = requestID
+				}
+				if resp, ok := awsmiddleware.GetRawResponse(metadata).(*smithyhttp.Response); ok && resp != nil {
+					fields["status"] = resp.StatusCode
+				}
+
+				if err != nil {
+					var respErr *awshttp.ResponseError
+					if errors.As(err, &respErr) {
+						fields["status"] = respErr.HTTPStatusCode()
+						fields["requestId"] = respErr.ServiceRequestID()
+					}
+					logging.WithTrace(ctx, logger).WithFields(fields).WithError(err).Debug("AWS API call failed")
+				} else {
+					logging.WithTrace(ctx, logger).WithFields(fields).Debug("AWS API call completed")
+				}
+
+				return out, metadata, err
+			}), middleware.Before)
+	}
+}
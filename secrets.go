@@ -0,0 +1,172 @@
+package easygo
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// Secrets Manager staging labels
+const (
+	SecretVersionStageCurrent  = "AWSCURRENT"
+	SecretVersionStagePrevious = "AWSPREVIOUS"
+	SecretVersionStagePending  = "AWSPENDING"
+)
+
+// SecretValueOption selects which version of a secret the fetch helpers return
+type SecretValueOption func(*secretsmanager.GetSecretValueInput)
+
+// WithVersionStage selects the secret version attached to stage, e.g. SecretVersionStagePrevious
+func WithVersionStage(stage string) SecretValueOption {
+	return func(input *secretsmanager.GetSecretValueInput) {
+		input.VersionStage = aws.String(stage)
+	}
+}
+
+// WithVersionID selects a specific secret version by its version ID
+func WithVersionID(versionID string) SecretValueOption {
+	return func(input *secretsmanager.GetSecretValueInput) {
+		input.VersionId = aws.String(versionID)
+	}
+}
+
+type GetLatestSecretValueResult struct {
+	ByteValue []byte
+}
+
+type ByteTransformer[T any] struct {
+	ByteValue []byte
+}
+
+// Gets the latest value of secretNameOrArn and unmarshals it into result.
+// opts can select a different version, e.g. WithVersionStage(SecretVersionStagePrevious).
+func (c *EGAwsClient) GetLatestJsonSecretValue(ctx context.Context, secretNameOrArn string, result any, opts ...SecretValueOption) error {
+	byteValue, err := c.GetLatestSecretBytes(ctx, secretNameOrArn, opts...)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(byteValue, result)
+	if err != nil {
+		return errs.Errorf("failed to unmarshal byte value: %w", err)
+	}
+
+	return nil
+}
+
+// Gets the latest value of secretNameOrArn as a string, for opaque values such as PEM keys or tokens
+func (c *EGAwsClient) GetLatestSecretString(ctx context.Context, secretNameOrArn string, opts ...SecretValueOption) (string, error) {
+	byteValue, err := c.GetLatestSecretBytes(ctx, secretNameOrArn, opts...)
+	if err != nil {
+		return "", err
+	}
+	return string(byteValue), nil
+}
+
+// Gets the latest value of secretNameOrArn as bytes; SecretString is preferred over SecretBinary
+func (c *EGAwsClient) GetLatestSecretBytes(ctx context.Context, secretNameOrArn string, opts ...SecretValueOption) ([]byte, error) {
+	input := &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretNameOrArn),
+	}
+	for _, opt := range opts {
+		opt(input)
+	}
+
+	output, err := c.secretsClient.GetSecretValue(ctx, input)
+	if err != nil {
+		return nil, errs.Errorf("failed to get secret value: %w", err)
+	}
+
+	if output.SecretString != nil {
+		return []byte(*output.SecretString), nil
+	} else if output.SecretBinary != nil {
+		return output.SecretBinary, nil
+	}
+	return nil, errs.New(errs.Internal, "secret found but value is empty")
+}
+
+// PutSecretOptions is the resolved set of PutSecretOption values
+type PutSecretOptions struct {
+	Verify             bool
+	ClientRequestToken string
+	VersionStages      []string
+}
+
+// PutSecretOption configures how a new secret version is written
+type PutSecretOption func(*PutSecretOptions)
+
+// ResolvePutSecretOptions applies opts to an empty PutSecretOptions; it lets
+// alternative SecretsWriter implementations honor the same options
+func ResolvePutSecretOptions(opts ...PutSecretOption) *PutSecretOptions {
+	o := &PutSecretOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithReadBackVerification reads the new version back after writing and fails if it differs
+func WithReadBackVerification() PutSecretOption {
+	return func(o *PutSecretOptions) {
+		o.Verify = true
+	}
+}
+
+// WithClientRequestToken sets the idempotency token, which becomes the new version ID
+func WithClientRequestToken(token string) PutSecretOption {
+	return func(o *PutSecretOptions) {
+		o.ClientRequestToken = token
+	}
+}
+
+// WithPutVersionStages attaches stages to the new version instead of the default AWSCURRENT,
+// e.g. SecretVersionStagePending during rotation
+func WithPutVersionStages(stages ...string) PutSecretOption {
+	return func(o *PutSecretOptions) {
+		o.VersionStages = stages
+	}
+}
+
+// Marshals value to JSON and writes it as a new version of secretNameOrArn, returning the version ID
+func (c *EGAwsClient) PutJsonSecretValue(ctx context.Context, secretNameOrArn string, value any, opts ...PutSecretOption) (string, error) {
+	byteValue, err := json.Marshal(value)
+	if err != nil {
+		return "", errs.Errorf("failed to marshal secret value: %w", err)
+	}
+	return c.PutSecretString(ctx, secretNameOrArn, string(byteValue), opts...)
+}
+
+// Writes value as a new SecretString version of secretNameOrArn, returning the version ID
+func (c *EGAwsClient) PutSecretString(ctx context.Context, secretNameOrArn string, value string, opts ...PutSecretOption) (string, error) {
+	o := ResolvePutSecretOptions(opts...)
+
+	input := &secretsmanager.PutSecretValueInput{
+		SecretId:      aws.String(secretNameOrArn),
+		SecretString:  aws.String(value),
+		VersionStages: o.VersionStages,
+	}
+	if o.ClientRequestToken != "" {
+		input.ClientRequestToken = aws.String(o.ClientRequestToken)
+	}
+
+	output, err := c.secretsClient.PutSecretValue(ctx, input)
+	if err != nil {
+		return "", errs.Errorf("failed to put secret value: %w", err)
+	}
+	versionID := aws.ToString(output.VersionId)
+
+	if o.Verify {
+		stored, err := c.GetLatestSecretString(ctx, secretNameOrArn, WithVersionID(versionID))
+		if err != nil {
+			return versionID, errs.Errorf("failed to read back secret value: %w", err)
+		}
+		if stored != value {
+			return versionID, errs.New(errs.Internal, "secret value read back does not match the value written")
+		}
+	}
+
+	return versionID, nil
+}
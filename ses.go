@@ -0,0 +1,216 @@
+package easygo
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// EmailAttachment is a file attached to an EmailMessage
+type EmailAttachment struct {
+	Filename string
+	// ContentType defaults to application/octet-stream
+	ContentType string
+	Data        []byte
+}
+
+// EmailMessage is a message sent by SendEmailMessage. Messages with attachments are
+// sent as raw MIME; others use the SES simple message format.
+type EmailMessage struct {
+	From        string
+	To          []string
+	Cc          []string
+	Bcc         []string
+	ReplyTo     []string
+	Subject     string
+	HTMLBody    string
+	TextBody    string
+	Attachments []EmailAttachment
+}
+
+// SendEmail sends a simple email with an HTML and/or text body and returns the SES message ID
+func (c *EGAwsClient) SendEmail(ctx context.Context, from string, to []string, subject, htmlBody, textBody string) (string, error) {
+	return c.SendEmailMessage(ctx, &EmailMessage{
+		From:     from,
+		To:       to,
+		Subject:  subject,
+		HTMLBody: htmlBody,
+		TextBody: textBody,
+	})
+}
+
+// SendEmailMessage sends msg and returns the SES message ID
+func (c *EGAwsClient) SendEmailMessage(ctx context.Context, msg *EmailMessage) (string, error) {
+	input := &sesv2.SendEmailInput{
+		FromEmailAddress: aws.String(msg.From),
+		Destination: &types.Destination{
+			ToAddresses:  msg.To,
+			CcAddresses:  msg.Cc,
+			BccAddresses: msg.Bcc,
+		},
+		ReplyToAddresses: msg.ReplyTo,
+	}
+
+	if len(msg.Attachments) > 0 {
+		raw, err := buildRawEmail(msg)
+		if err != nil {
+			return "", err
+		}
+		input.Content = &types.EmailContent{Raw: &types.RawMessage{Data: raw}}
+	} else {
+		body := &types.Body{}
+		if msg.HTMLBody != "" {
+			body.Html = &types.Content{Data: aws.String(msg.HTMLBody), Charset: aws.String("UTF-8")}
+		}
+		if msg.TextBody != "" {
+			body.Text = &types.Content{Data: aws.String(msg.TextBody), Charset: aws.String("UTF-8")}
+		}
+		input.Content = &types.EmailContent{Simple: &types.Message{
+			Subject: &types.Content{Data: aws.String(msg.Subject), Charset: aws.String("UTF-8")},
+			Body:    body,
+		}}
+	}
+
+	output, err := c.sesClient.SendEmail(ctx, input)
+	if err != nil {
+		return "", errs.Errorf("failed to send email: %w", err)
+	}
+	return aws.ToString(output.MessageId), nil
+}
+
+// SendTemplatedEmail sends an email using the SES template templateName; templateData
+// is marshaled to JSON as the template replacement data
+func (c *EGAwsClient) SendTemplatedEmail(ctx context.Context, from string, to []string, templateName string, templateData any) (string, error) {
+	data, err := json.Marshal(templateData)
+	if err != nil {
+		return "", errs.Errorf("failed to marshal template data: %w", err)
+	}
+
+	output, err := c.sesClient.SendEmail(ctx, &sesv2.SendEmailInput{
+		FromEmailAddress: aws.String(from),
+		Destination:      &types.Destination{ToAddresses: to},
+		Content: &types.EmailContent{Template: &types.Template{
+			TemplateName: aws.String(templateName),
+			TemplateData: aws.String(string(data)),
+		}},
+	})
+	if err != nil {
+		return "", errs.Errorf("failed to send templated email: %w", err)
+	}
+	return aws.ToString(output.MessageId), nil
+}
+
+// buildRawEmail builds a multipart/mixed MIME message with the bodies in a
+// multipart/alternative part followed by the attachments
+func buildRawEmail(msg *EmailMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	mixed := multipart.NewWriter(&buf)
+
+	writeHeader := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	writeHeader("From", msg.From)
+	if len(msg.To) > 0 {
+		writeHeader("To", strings.Join(msg.To, ", "))
+	}
+	if len(msg.Cc) > 0 {
+		writeHeader("Cc", strings.Join(msg.Cc, ", "))
+	}
+	if len(msg.ReplyTo) > 0 {
+		writeHeader("Reply-To", strings.Join(msg.ReplyTo, ", "))
+	}
+	writeHeader("Subject", mime.QEncoding.Encode("UTF-8", msg.Subject))
+	writeHeader("MIME-Version", "1.0")
+	writeHeader("Content-Type", fmt.Sprintf("multipart/mixed; boundary=%q", mixed.Boundary()))
+	buf.WriteString("\r\n")
+
+	var alt bytes.Buffer
+	altWriter := multipart.NewWriter(&alt)
+	if msg.TextBody != "" {
+		if err := writeQuotedPrintablePart(altWriter, "text/plain; charset=UTF-8", msg.TextBody); err != nil {
+			return nil, err
+		}
+	}
+	if msg.HTMLBody != "" {
+		if err := writeQuotedPrintablePart(altWriter, "text/html; charset=UTF-8", msg.HTMLBody); err != nil {
+			return nil, err
+		}
+	}
+	if err := altWriter.Close(); err != nil {
+		return nil, errs.Errorf("failed to build email body: %w", err)
+	}
+
+	altPart, err := mixed.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {fmt.Sprintf("multipart/alternative; boundary=%q", altWriter.Boundary())},
+	})
+	if err != nil {
+		return nil, errs.Errorf("failed to build email body: %w", err)
+	}
+	if _, err := altPart.Write(alt.Bytes()); err != nil {
+		return nil, errs.Errorf("failed to build email body: %w", err)
+	}
+
+	for _, a := range msg.Attachments {
+		contentType := a.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		part, err := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(contentType, map[string]string{"name": a.Filename})},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, errs.Errorf("failed to attach %s: %w", a.Filename, err)
+		}
+		if err := writeBase64Lines(part, a.Data); err != nil {
+			return nil, errs.Errorf("failed to attach %s: %w", a.Filename, err)
+		}
+	}
+
+	if err := mixed.Close(); err != nil {
+		return nil, errs.Errorf("failed to build email: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintablePart(w *multipart.Writer, contentType, body string) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return errs.Errorf("failed to build email body: %w", err)
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return errs.Errorf("failed to build email body: %w", err)
+	}
+	return qp.Close()
+}
+
+// writeBase64Lines writes data base64 encoded in 76 character lines as required by RFC 2045
+func writeBase64Lines(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		n := min(76, len(encoded))
+		if _, err := fmt.Fprintf(w, "%s\r\n", encoded[:n]); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package easygo
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// GetParameterValue returns the value of the SSM parameter name, decrypting
+// SecureString parameters
+func (c *EGAwsClient) GetParameterValue(ctx context.Context, name string) (string, error) {
+	output, err := c.ssmClient.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", errs.Errorf("failed to get parameter %s: %w", name, err)
+	}
+	if output.Parameter == nil {
+		return "", errs.Newf(errs.Internal, "parameter %s has no value", name)
+	}
+	return aws.ToString(output.Parameter.Value), nil
+}
@@ -0,0 +1,46 @@
+package easygo
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/bdlilley/easygo/pkg/errs"
+)
+
+// GetSessionTokenWithMFA returns temporary credentials for the current IAM user
+// authenticated with an MFA device. serialNumber is the device ARN (or serial number
+// for hardware devices), tokenCode the current code, and duration the session
+// length (0 uses the STS default of 12 hours).
+func (c *EGAwsClient) GetSessionTokenWithMFA(ctx context.Context, serialNumber, tokenCode string, duration time.Duration) (aws.Credentials, error) {
+	input := &sts.GetSessionTokenInput{
+		SerialNumber: aws.String(serialNumber),
+		TokenCode:    aws.String(tokenCode),
+	}
+	if duration > 0 {
+		input.DurationSeconds = aws.Int32(int32(duration.Seconds()))
+	}
+
+	output, err := c.stsClient.GetSessionToken(ctx, input)
+	if err != nil {
+		return aws.Credentials{}, errs.Errorf("failed to get session token: %w", err)
+	}
+
+	return aws.Credentials{
+		AccessKeyID:     aws.ToString(output.Credentials.AccessKeyId),
+		SecretAccessKey: aws.ToString(output.Credentials.SecretAccessKey),
+		SessionToken:    aws.ToString(output.Credentials.SessionToken),
+		Source:          "GetSessionToken",
+		CanExpire:       true,
+		Expires:         aws.ToTime(output.Credentials.Expiration),
+	}, nil
+}
+
+// UseCredentials replaces the client's credentials with creds and rebuilds the
+// service clients. It is not safe to call while other goroutines use the client.
+func (c *EGAwsClient) UseCredentials(creds aws.Credentials) {
+	c.cfg.Credentials = aws.NewCredentialsCache(credentials.StaticCredentialsProvider{Value: creds})
+	c.initClients()
+}